@@ -188,7 +188,7 @@ print_success "Migrations completed"
 print_step 8 "Building application"
 
 echo -e "Compiling..."
-go build -o bin/digiorder cmd/main.go
+go build -o bin/digiorder ./cmd
 print_success "Build completed successfully"
 
 # Step 9: Run tests
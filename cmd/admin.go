@@ -0,0 +1,165 @@
+// cmd/admin.go - create-admin subcommand
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/security"
+)
+
+// adminRoleID matches the admin role seeded by the initial migration
+const adminRoleID = 1
+
+// runCreateAdmin creates an admin account from the console, bypassing the
+// HTTP setup-token flow. With -reset an existing user's password is replaced
+// and the account is promoted to admin.
+func runCreateAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	configPath := configFlag(fs)
+	username := fs.String("username", "admin", "admin username")
+	fullName := fs.String("full-name", "System Administrator", "admin full name")
+	reset := fs.Bool("reset", false, "reset the password if the user already exists")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: digiorder create-admin [flags]")
+		fmt.Fprintln(fs.Output(), "The password is read from ADMIN_PASSWORD or, if unset, from stdin.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if strings.TrimSpace(*username) == "" {
+		return errors.New("-username is required")
+	}
+
+	password, err := readAdminPassword()
+	if err != nil {
+		return err
+	}
+	// HashPassword enforces the default password requirements
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := useFieldKeys(cfg); err != nil {
+		return err
+	}
+
+	database, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	queries := db.New(database)
+
+	existing, err := queries.GetUserByUsername(ctx, *username)
+	switch {
+	case err == nil:
+		if !*reset {
+			return fmt.Errorf("user %q already exists (pass -reset to replace its password)", *username)
+		}
+		if err := queries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			ID:           existing.ID,
+			PasswordHash: hashedPassword,
+		}); err != nil {
+			return fmt.Errorf("failed to reset password: %w", err)
+		}
+		if _, err := queries.UpdateUser(ctx, db.UpdateUserParams{
+			ID:     existing.ID,
+			RoleID: sql.NullInt32{Int32: adminRoleID, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to grant admin role: %w", err)
+		}
+		log.Printf("Password reset for admin %q", *username)
+
+	case errors.Is(err, sql.ErrNoRows):
+		if err := createAdmin(ctx, queries, *username, *fullName, hashedPassword); err != nil {
+			return err
+		}
+		log.Printf("Admin %q created", *username)
+
+	default:
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	// Close the HTTP setup flow so the setup token can no longer be used
+	if _, err := queries.CompleteSystemSetup(ctx, db.CompleteSystemSetupParams{
+		AdminCreated: sql.NullBool{Bool: true, Valid: true},
+		SetupByIp:    sql.NullString{String: "console", Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to mark setup as completed: %w", err)
+	}
+
+	return nil
+}
+
+// createAdmin inserts the first admin with the well-known setup ID, and any
+// further admins with a generated ID
+func createAdmin(ctx context.Context, queries *db.Queries, username, fullName, passwordHash string) error {
+	hasAdmin, err := queries.HasAdminUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check for existing admin: %w", err)
+	}
+
+	name := db.EncryptedString{String: fullName, Valid: fullName != ""}
+	role := sql.NullInt32{Int32: adminRoleID, Valid: true}
+
+	if !hasAdmin {
+		_, err = queries.CreateAdminUser(ctx, db.CreateAdminUserParams{
+			ID:           uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+			Username:     username,
+			FullName:     name,
+			PasswordHash: passwordHash,
+			RoleID:       role,
+		})
+	} else {
+		_, err = queries.CreateUser(ctx, db.CreateUserParams{
+			Username:     username,
+			FullName:     name,
+			PasswordHash: passwordHash,
+			RoleID:       role,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	return nil
+}
+
+// readAdminPassword takes the password from ADMIN_PASSWORD or the first line of stdin
+func readAdminPassword() (string, error) {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Admin password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	return password, nil
+}
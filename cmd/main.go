@@ -3,104 +3,139 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
 	"github.com/jamalkaksouri/DigiOrder/internal/db"
-	"github.com/jamalkaksouri/DigiOrder/internal/server"
+	"github.com/jamalkaksouri/DigiOrder/internal/fieldcrypt"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
 )
 
+const usage = `Usage: digiorder <command> [flags]
+
+Commands:
+  serve          Start the HTTP API server (default)
+  migrate        Apply, roll back or inspect schema migrations
+  seed           Insert reference data (roles, categories, dosage forms, permissions),
+                 and with -demo sample products, users and orders
+  create-admin   Create an admin account or reset an admin password
+
+Run "digiorder <command> -h" for command flags.
+`
+
 func main() {
 	// Setup logger
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Println("Starting DigiOrder v3.0...")
 
-	// Validate environment
-	if err := validateEnvironment(); err != nil {
-		log.Fatal("Environment validation failed:", err)
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
 	}
 
-	// Database connection with retry
-	database, err := connectWithRetry(5, 2*time.Second)
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+	var err error
+	switch command {
+	case "serve":
+		err = runServe(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "seed":
+		err = runSeed(args)
+	case "create-admin":
+		err = runCreateAdmin(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
-	defer func() {
-		if err := database.Close(); err != nil {
-			log.Printf("Error closing database: %v", err)
-		}
-	}()
-
-	log.Println("Database connection established")
 
-	// Create and configure server
-	srv := server.New(database)
-
-	// Start server in goroutine
-	go func() {
-		port := getEnv("SERVER_PORT", "5582")
-		addr := ":" + port
-
-		log.Printf("Server starting on %s", addr)
-		if err := srv.Start(addr); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Server failed to start:", err)
-		}
-	}()
+	if err != nil {
+		log.Fatalf("%s: %v", command, err)
+	}
+}
 
-	// Wait for interrupt signal to gracefully shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+// configFlag registers the -config flag shared by every subcommand
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", getEnv("CONFIG_FILE", ""),
+		"path to a YAML config file (environment variables override it)")
+}
 
-	sig := <-quit
-	log.Printf("Received signal: %v. Shutting down server...", sig)
+// loadConfig reads the configuration file (if any) and the environment
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return cfg, nil
+}
 
-	// Graceful shutdown with timeout
+// useFieldKeys loads the field encryption keys, unwrapping those held by
+// KMS, so encrypted columns are read and written with them
+func useFieldKeys(cfg *config.Config) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	kms := cfg.Encryption.KMS
+	keys, err := fieldcrypt.Load(ctx, cfg.Encryption.Keys, fieldcrypt.KMSConfig{
+		Region:       kms.Region,
+		Endpoint:     kms.Endpoint,
+		AccessKey:    kms.AccessKey,
+		SecretKey:    kms.SecretKey,
+		SessionToken: kms.SessionToken,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load field encryption keys: %w", err)
 	}
-
-	log.Println("Server exited gracefully")
+	fieldcrypt.Use(keys)
+	return nil
 }
 
-func validateEnvironment() error {
-	required := []string{
-		"DB_HOST",
-		"DB_USER",
-		"DB_PASSWORD",
-		"DB_NAME",
-		"JWT_SECRET",
+// useJWTKeys loads the key pair access tokens are signed with when
+// jwt.algorithm is RS256 or EdDSA, and the previous keys still accepted
+func useJWTKeys(cfg *config.Config) error {
+	if cfg.JWT.Algorithm == "HS256" {
+		return middleware.ConfigureJWTKeys(nil, nil)
 	}
 
-	for _, env := range required {
-		if os.Getenv(env) == "" {
-			return fmt.Errorf("required environment variable %s is not set", env)
+	current, err := middleware.LoadSigningKey(cfg.JWT.PrivateKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the JWT signing key: %w", err)
+	}
+	if current.Method.Alg() != cfg.JWT.Algorithm {
+		return fmt.Errorf("jwt.private_key_file holds a %s key, not %s", current.Method.Alg(), cfg.JWT.Algorithm)
+	}
+	previous := make([]*middleware.SigningKey, 0, len(cfg.JWT.PreviousKeyFiles))
+	for _, path := range cfg.JWT.PreviousKeyFiles {
+		key, err := middleware.LoadSigningKey(path)
+		if err != nil {
+			return fmt.Errorf("failed to load a previous JWT key: %w", err)
 		}
+		previous = append(previous, key)
 	}
+	return middleware.ConfigureJWTKeys(current, previous)
+}
 
-	// Validate JWT secret length
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if len(jwtSecret) < 32 {
-		return fmt.Errorf("JWT_SECRET must be at least 32 characters, got %d", len(jwtSecret))
+// openDatabase validates the database settings and connects with retry
+func openDatabase(cfg *config.Config) (*pgxpool.Pool, error) {
+	if err := cfg.Database.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
-
-	return nil
+	return connectWithRetry(cfg, 5, 2*time.Second)
 }
 
-func connectWithRetry(maxRetries int, retryDelay time.Duration) (*sql.DB, error) {
-	var database *sql.DB
+// connectWithRetry connects with the configured credentials, reading
+// rotated ones from their secret store every secrets.refresh_interval
+func connectWithRetry(cfg *config.Config, maxRetries int, retryDelay time.Duration) (*pgxpool.Pool, error) {
+	var database *pgxpool.Pool
 	var err error
 
 	for i := 0; i < maxRetries; i++ {
-		database, err = db.Connect()
+		database, err = db.ConnectRefreshing(cfg.Database, cfg.DatabaseCredentials, cfg.Secrets.RefreshInterval)
 		if err == nil {
 			return database, nil
 		}
@@ -0,0 +1,99 @@
+// cmd/migrate.go - migrate subcommand
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/migrations"
+)
+
+// runMigrate handles "migrate up", "migrate down [-steps N]" and "migrate status"
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := configFlag(fs)
+	steps := fs.Int("steps", 1, "number of migrations to roll back (down only)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: digiorder migrate [-steps N] up|down|status")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	action := "up"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	database, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	migrator, err := db.NewMigrator(database, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s); schema at version %d", applied, migrator.Latest())
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back %d migration(s)", reverted)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version: %d\nlatest:  %d\ndirty:   %t\npending: %d\n",
+			status.Version, status.Latest, status.Dirty, status.Pending)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+
+	return nil
+}
+
+// runMigrations brings the database schema up to the embedded version
+func runMigrations(database *pgxpool.Pool) error {
+	migrator, err := db.NewMigrator(database, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Database schema at version %d (%d migration(s) applied)", migrator.Latest(), applied)
+	return nil
+}
@@ -0,0 +1,92 @@
+// cmd/seed.go - seed subcommand
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/demo"
+)
+
+// runSeed inserts missing reference data; existing rows are not modified.
+// With -demo it also adds sample products, users and orders.
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	configPath := configFlag(fs)
+	withDemo := fs.Bool("demo", false, "also add demo products, a demo_<role> user per role and sample orders")
+	orders := fs.Int("orders", 25, "sample orders to create with -demo")
+	password := fs.String("password", getEnv("DEMO_PASSWORD", demo.DefaultPassword), "password of the demo users")
+	force := fs.Bool("force", false, "allow -demo in production")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *withDemo && cfg.Env == "production" && !*force {
+		return errors.New("refusing to add demo users with a known password in production (pass -force to do it anyway)")
+	}
+	if *withDemo {
+		if err := useFieldKeys(cfg); err != nil {
+			return err
+		}
+	}
+
+	database, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	results, err := db.SeedReferenceData(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		log.Printf("Seeded %-16s %d new row(s)", r.Table, r.Inserted)
+	}
+
+	if !*withDemo {
+		return nil
+	}
+	result, err := seedDemo(ctx, database, demo.Options{Orders: *orders, Password: *password, TaxPercent: cfg.Pricing.TaxPercent})
+	if errors.Is(err, demo.ErrAlreadySeeded) {
+		log.Print("Demo data is already present; nothing added")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Demo data: %d products, %d barcodes, %d users, %d orders with %d items",
+		result.Products, result.Barcodes, result.Users, result.Orders, result.OrderItems)
+	log.Printf("Demo users are named demo_<role>; sign in with the demo password")
+	return nil
+}
+
+// seedDemo adds the demo data in one transaction
+func seedDemo(ctx context.Context, database *pgxpool.Pool, opts demo.Options) (demo.Result, error) {
+	tx, err := database.Begin(ctx)
+	if err != nil {
+		return demo.Result{}, fmt.Errorf("failed to begin demo transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := demo.Seed(ctx, db.New(tx), opts)
+	if err != nil {
+		return demo.Result{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return demo.Result{}, fmt.Errorf("failed to commit demo data: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+// cmd/serve.go - serve subcommand
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/server"
+)
+
+// runServe starts the HTTP server and blocks until SIGINT/SIGTERM
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := configFlag(fs)
+	skipMigrations := fs.Bool("skip-migrations", false,
+		"do not apply embedded schema migrations on startup (overrides database.auto_migrate)")
+	fs.Parse(args)
+
+	log.Println("Starting DigiOrder v3.0...")
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if err := useFieldKeys(cfg); err != nil {
+		return err
+	}
+	if err := useJWTKeys(cfg); err != nil {
+		return err
+	}
+
+	// Database connection with retry
+	database, err := connectWithRetry(cfg, 5, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	log.Println("Database connection established")
+
+	// Apply embedded schema migrations unless disabled
+	if *skipMigrations || !cfg.Database.AutoMigrate {
+		log.Println("Skipping schema migrations (disabled)")
+	} else if err := runMigrations(database); err != nil {
+		return fmt.Errorf("schema migration failed: %w", err)
+	}
+
+	// Create and configure server
+	srv := server.New(database, cfg)
+
+	// Fail fast on a broken schema or missing seed data instead of
+	// serving 500s at runtime
+	selfTestCtx, cancelSelfTest := context.WithTimeout(context.Background(), 30*time.Second)
+	report := srv.RunSelfTest(selfTestCtx)
+	cancelSelfTest()
+	if !report.Passed {
+		log.Printf("Startup self-test failed:\n%s", report)
+		return fmt.Errorf("startup self-test failed")
+	}
+	log.Printf("Startup self-test passed:\n%s", report)
+
+	// Start server in goroutine
+	go func() {
+		addr := cfg.Server.Address()
+
+		log.Printf("Server starting on %s", addr)
+		if err := srv.Start(addr); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start:", err)
+		}
+	}()
+
+	// SIGHUP reloads rate limits, CORS origins, log level and maintenance
+	// mode from the config file without dropping connections
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			result, err := srv.ReloadConfig()
+			if err != nil {
+				log.Printf("Configuration reload failed: %v", err)
+				continue
+			}
+			log.Printf("Configuration reloaded (applied: %v, restart required: %v)",
+				result.Applied, result.RestartRequired)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-quit
+	signal.Stop(hup)
+	log.Printf("Received signal: %v. Shutting down server...", sig)
+
+	// Graceful shutdown with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	log.Println("Server exited gracefully")
+	return nil
+}
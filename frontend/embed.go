@@ -0,0 +1,11 @@
+// Package frontend embeds the built single-page app into the binary so the
+// server can serve it without a web server in front.
+package frontend
+
+import "embed"
+
+// FS holds dist/, the output of the frontend build. Without a build it
+// holds only a placeholder, and the server has no embedded app to serve.
+//
+//go:embed all:dist
+var FS embed.FS
@@ -0,0 +1,348 @@
+// internal/anomaly/analyzer.go - Suspicious logins found in the login log
+package anomaly
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Kinds of security events. The analyzer finds the login anomalies;
+// refresh token reuse is recorded by the API when a rotated refresh token
+// is presented again.
+const (
+	KindImpossibleTravel  = "impossible_travel"
+	KindNewDevice         = "new_device"
+	KindUnusualHour       = "unusual_hour"
+	KindRefreshTokenReuse = "refresh_token_reuse"
+)
+
+// Kinds lists every kind of security event
+var Kinds = []string{KindImpossibleTravel, KindNewDevice, KindUnusualHour, KindRefreshTokenReuse}
+
+// batchSize is how many logins one transaction claims
+const batchSize = 100
+
+var findingsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "security_events_total",
+		Help: "Login anomalies found by kind",
+	},
+	[]string{"kind"},
+)
+
+// TxFunc runs fn inside a database transaction
+type TxFunc func(ctx context.Context, fn func(q db.Querier) error) error
+
+// Config controls the analyzer; see config.AnomalyConfig
+type Config struct {
+	Interval          time.Duration // 0 disables the analyzer
+	Location          *time.Location
+	MaxTravelSpeedKmh float64
+	MinTravelKm       float64
+	MinLogins         int
+	History           time.Duration
+}
+
+// Analyzer looks at every successful login once, comparing it with the
+// user's earlier logins. Logins are claimed with SKIP LOCKED, so several
+// instances can run one each.
+type Analyzer struct {
+	withTx    TxFunc
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+	onFinding func(db.SecurityEvent)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAnalyzer creates an analyzer. Call Start to begin analyzing logins.
+func NewAnalyzer(withTx TxFunc, config Config, logger *logging.Logger) *Analyzer {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &Analyzer{
+		withTx: withTx,
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if config.Interval > 0 {
+		a.heartbeat = middleware.NewHeartbeat("login_anomalies", config.Interval)
+	}
+	return a
+}
+
+// OnFinding registers a callback invoked for every security event once it
+// is stored, e.g. to alert administrators. Call it before Start.
+func (a *Analyzer) OnFinding(fn func(db.SecurityEvent)) {
+	a.onFinding = fn
+}
+
+// Start launches the loop that analyzes new logins
+func (a *Analyzer) Start() {
+	if a.heartbeat == nil {
+		return
+	}
+	a.wg.Add(1)
+	go a.loop()
+}
+
+// Stop ends the loop, waiting for a run in progress until ctx expires
+func (a *Analyzer) Stop(ctx context.Context) error {
+	a.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the loop is running, or nil when disabled
+func (a *Analyzer) Heartbeat() *middleware.Heartbeat {
+	return a.heartbeat
+}
+
+func (a *Analyzer) loop() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		a.analyzeNew()
+		a.heartbeat.Beat()
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// analyzeNew analyzes claimed batches of logins until none are left
+func (a *Analyzer) analyzeNew() {
+	for a.ctx.Err() == nil {
+		n, err := a.analyzeBatch(a.ctx)
+		if err != nil {
+			a.logger.Error("Failed to analyze logins", err, nil)
+			return
+		}
+		if n < batchSize {
+			return
+		}
+	}
+}
+
+// analyzeBatch claims a batch of logins and stores what it finds in them.
+// Claims and findings commit together.
+func (a *Analyzer) analyzeBatch(ctx context.Context) (int, error) {
+	var claimed int
+	var findings []db.SecurityEvent
+	err := a.withTx(ctx, func(q db.Querier) error {
+		findings = findings[:0]
+		logins, err := q.ClaimUnanalyzedLogins(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		claimed = len(logins)
+		sort.Slice(logins, func(i, j int) bool {
+			return logins[i].AttemptTime.Time.Before(logins[j].AttemptTime.Time)
+		})
+
+		for _, login := range logins {
+			found, err := a.Analyze(ctx, q, login)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, found...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range findings {
+		findingsTotal.WithLabelValues(event.Kind).Inc()
+		if a.onFinding != nil {
+			a.onFinding(event)
+		}
+	}
+	return claimed, nil
+}
+
+// Analyze compares a successful login with the user's earlier ones and
+// stores a security event for each anomaly
+func (a *Analyzer) Analyze(ctx context.Context, q db.Querier, login db.LoginAttemptsLog) ([]db.SecurityEvent, error) {
+	checks := []func(context.Context, db.Querier, db.LoginAttemptsLog) (string, any, error){
+		a.impossibleTravel,
+		a.newDevice,
+		a.unusualHour,
+	}
+
+	var events []db.SecurityEvent
+	for _, check := range checks {
+		kind, details, err := check(ctx, q, login)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "" {
+			continue
+		}
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return nil, err
+		}
+		event, err := q.CreateSecurityEvent(ctx, db.CreateSecurityEventParams{
+			Kind:           kind,
+			Username:       login.Username,
+			LoginAttemptID: uuid.NullUUID{UUID: login.ID, Valid: true},
+			IpAddress:      login.IpAddress,
+			Details:        raw,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// loginPlace is where and when a login happened
+type loginPlace struct {
+	IP      string    `json:"ip"`
+	Country string    `json:"country,omitempty"`
+	City    string    `json:"city,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+func placeOf(login db.LoginAttemptsLog) loginPlace {
+	return loginPlace{
+		IP:      login.IpAddress,
+		Country: login.Country.String,
+		City:    login.City.String,
+		Time:    login.AttemptTime.Time,
+	}
+}
+
+// impossibleTravel flags a login too far from the user's previous one to
+// have got there in time
+func (a *Analyzer) impossibleTravel(ctx context.Context, q db.Querier, login db.LoginAttemptsLog) (string, any, error) {
+	if !login.Latitude.Valid || !login.Longitude.Valid {
+		return "", nil, nil
+	}
+	prev, err := q.GetPreviousLogin(ctx, db.GetPreviousLoginParams{
+		Username: login.Username,
+		Before:   login.AttemptTime.Time,
+		ID:       login.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !prev.Latitude.Valid || !prev.Longitude.Valid {
+		return "", nil, nil
+	}
+
+	km := Distance(prev.Latitude.Float64, prev.Longitude.Float64, login.Latitude.Float64, login.Longitude.Float64)
+	if km < a.config.MinTravelKm {
+		return "", nil, nil
+	}
+	hours := login.AttemptTime.Time.Sub(prev.AttemptTime.Time).Hours()
+	if hours > 0 && km/hours <= a.config.MaxTravelSpeedKmh {
+		return "", nil, nil
+	}
+
+	details := map[string]any{
+		"from":        placeOf(prev),
+		"to":          placeOf(login),
+		"distance_km": math.Round(km),
+		"hours":       math.Round(hours*100) / 100,
+	}
+	if hours > 0 {
+		details["speed_kmh"] = math.Round(km / hours)
+	}
+	return KindImpossibleTravel, details, nil
+}
+
+// newDevice flags a login from a device the user has not logged in from
+// before. A user's first fingerprinted login is not flagged.
+func (a *Analyzer) newDevice(ctx context.Context, q db.Querier, login db.LoginAttemptsLog) (string, any, error) {
+	fingerprint := login.DeviceFingerprint.String
+	if fingerprint == "" {
+		return "", nil, nil
+	}
+	counts, err := q.CountLoginFingerprints(ctx, db.CountLoginFingerprintsParams{
+		Fingerprint: fingerprint,
+		Username:    login.Username,
+		Before:      login.AttemptTime.Time,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if counts.Fingerprinted == 0 || counts.Matching > 0 {
+		return "", nil, nil
+	}
+	return KindNewDevice, map[string]any{
+		"fingerprint":  fingerprint,
+		"user_agent":   login.UserAgent.String,
+		"known_logins": counts.Fingerprinted,
+	}, nil
+}
+
+// unusualHour flags a login at an hour of the day the user has not logged
+// in at, give or take an hour, once there are enough logins to tell
+func (a *Analyzer) unusualHour(ctx context.Context, q db.Querier, login db.LoginAttemptsLog) (string, any, error) {
+	if a.config.MinLogins <= 0 {
+		return "", nil, nil
+	}
+	at := login.AttemptTime.Time
+	hour := at.In(a.config.Location).Hour()
+	counts, err := q.CountLoginsNearHour(ctx, db.CountLoginsNearHourParams{
+		Timezone: a.config.Location.String(),
+		Hour:     int32(hour),
+		Username: login.Username,
+		Since:    at.Add(-a.config.History),
+		Before:   at,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if counts.Logins < int64(a.config.MinLogins) || counts.NearHour > 0 {
+		return "", nil, nil
+	}
+	return KindUnusualHour, map[string]any{
+		"hour":     hour,
+		"timezone": a.config.Location.String(),
+		"logins":   counts.Logins,
+	}, nil
+}
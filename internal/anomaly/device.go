@@ -0,0 +1,55 @@
+// internal/anomaly/device.go - Device fingerprints and distances
+package anomaly
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0
+
+// Fingerprint identifies the browser or app a login comes from by its
+// user agent and preferred languages. It is empty when both are.
+func Fingerprint(userAgent, acceptLanguage string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	acceptLanguage = strings.ToLower(strings.TrimSpace(acceptLanguage))
+	if userAgent == "" && acceptLanguage == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userAgent + "\n" + acceptLanguage))
+	return hex.EncodeToString(sum[:8])
+}
+
+// DeviceInfo is the device_info of a login attempt: its fingerprint and
+// the languages it was made from
+func DeviceInfo(userAgent, acceptLanguage string) db.EncryptedJSON {
+	fingerprint := Fingerprint(userAgent, acceptLanguage)
+	if fingerprint == "" {
+		return db.EncryptedJSON{}
+	}
+	raw, err := json.Marshal(map[string]string{
+		"fingerprint":     fingerprint,
+		"accept_language": acceptLanguage,
+	})
+	if err != nil {
+		return db.EncryptedJSON{}
+	}
+	return db.EncryptedJSON{RawMessage: raw, Valid: true}
+}
+
+// Distance is the great-circle distance in kilometres between two points
+// given in degrees
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
@@ -0,0 +1,288 @@
+// internal/audit/audit.go - Buffered audit log writer
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+)
+
+// How long a synchronous write, or the final flush on Stop, may take
+const writeTimeout = 10 * time.Second
+
+// Entry is one audit log entry
+type Entry struct {
+	UserID     uuid.UUID // uuid.Nil, for system actions, is stored as NULL
+	Action     string
+	EntityType string
+	EntityID   string
+	OldValues  map[string]any // nil is stored as NULL
+	NewValues  map[string]any // nil is stored as NULL
+	IPAddress  string
+	UserAgent  string
+}
+
+// Config controls the queue of entries waiting to be written
+type Config struct {
+	BufferSize    int // 0 writes every entry as it is logged
+	BatchSize     int
+	FlushInterval time.Duration
+
+	// Written, when set, is called with every entry once it is stored
+	Written func(ctx context.Context, entry Entry)
+}
+
+// Writer stores audit entries. Log queues an entry and returns at once; a
+// worker writes the queue in batches of BatchSize, or every FlushInterval
+// when fewer are waiting. An entry that finds the queue full is dropped
+// and counted, so a slow database never holds up requests. Write stores
+// an entry before returning, for the ones that must not be lost.
+type Writer struct {
+	queries   db.Querier
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	queue chan Entry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWriter creates a writer. Call Start to begin writing queued entries.
+func NewWriter(queries db.Querier, config Config, logger *logging.Logger) *Writer {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer{
+		queries: queries,
+		config:  config,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if config.BufferSize > 0 {
+		w.queue = make(chan Entry, config.BufferSize)
+		w.heartbeat = middleware.NewHeartbeat("audit", config.FlushInterval)
+	}
+	return w
+}
+
+// Log queues entry to be written. Without a queue, or once the writer is
+// stopped, it is written in the background right away.
+func (w *Writer) Log(entry Entry) {
+	if w.queue == nil || w.ctx.Err() != nil {
+		go w.Write(entry)
+		return
+	}
+	select {
+	case w.queue <- entry:
+	default:
+		middleware.RecordAuditEntryDropped("queue_full")
+		w.logger.Warn("Audit queue full, entry dropped", map[string]any{
+			"action":      entry.Action,
+			"entity_type": entry.EntityType,
+			"entity_id":   entry.EntityID,
+		})
+	}
+}
+
+// Write stores entry before returning. It is written outside the
+// request's tenant scope, as the queued entries are. A failure is logged
+// and counted as a dropped entry as well as returned.
+func (w *Writer) Write(entry Entry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := w.insert(ctx, entry); err != nil {
+		w.failed(err, entry)
+		return err
+	}
+	w.written(ctx, entry)
+	return nil
+}
+
+// Start launches the worker that writes queued entries
+func (w *Writer) Start() {
+	if w.queue == nil {
+		return
+	}
+	w.wg.Add(1)
+	go w.loop()
+}
+
+// Stop ends the worker, writing the entries still queued unless ctx
+// expires first
+func (w *Writer) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the worker is running, or nil without a queue
+func (w *Writer) Heartbeat() *middleware.Heartbeat {
+	return w.heartbeat
+}
+
+func (w *Writer) loop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, w.config.BatchSize)
+	for {
+		select {
+		case <-w.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+			w.drain(ctx, batch)
+			cancel()
+			return
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+			if len(batch) < w.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			w.heartbeat.Beat()
+		}
+		batch = w.flush(w.ctx, batch)
+	}
+}
+
+// drain writes batch and every entry left in the queue
+func (w *Writer) drain(ctx context.Context, batch []Entry) {
+	for {
+		select {
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+			if len(batch) == w.config.BatchSize {
+				batch = w.flush(ctx, batch)
+			}
+		default:
+			w.flush(ctx, batch)
+			return
+		}
+	}
+}
+
+// flush writes batch with one statement and returns it emptied. When the
+// statement fails the entries are written one by one, so only the ones at
+// fault, such as those of a user deleted meanwhile, are lost.
+func (w *Writer) flush(ctx context.Context, batch []Entry) []Entry {
+	if len(batch) == 0 {
+		return batch
+	}
+	arg := db.CreateAuditLogsParams{
+		UserIds:     make([]uuid.UUID, len(batch)),
+		Actions:     make([]string, len(batch)),
+		EntityTypes: make([]string, len(batch)),
+		EntityIds:   make([]string, len(batch)),
+		OldValues:   make([]string, len(batch)),
+		NewValues:   make([]string, len(batch)),
+		IpAddresses: make([]string, len(batch)),
+		UserAgents:  make([]string, len(batch)),
+	}
+	for i, entry := range batch {
+		arg.UserIds[i] = entry.UserID
+		arg.Actions[i] = entry.Action
+		arg.EntityTypes[i] = entry.EntityType
+		arg.EntityIds[i] = entry.EntityID
+		arg.OldValues[i] = string(values(entry.OldValues))
+		arg.NewValues[i] = string(values(entry.NewValues))
+		arg.IpAddresses[i] = entry.IPAddress
+		arg.UserAgents[i] = entry.UserAgent
+	}
+
+	// Array parameters bypass the column types, so addresses and user
+	// agents are encrypted here
+	err := encryptAll(arg.IpAddresses, arg.UserAgents)
+	if err == nil {
+		err = w.queries.CreateAuditLogs(ctx, arg)
+	}
+	if err != nil {
+		for _, entry := range batch {
+			if err := w.insert(ctx, entry); err != nil {
+				w.failed(err, entry)
+				continue
+			}
+			w.written(ctx, entry)
+		}
+	} else {
+		for _, entry := range batch {
+			w.written(ctx, entry)
+		}
+	}
+	return batch[:0]
+}
+
+func (w *Writer) insert(ctx context.Context, entry Entry) error {
+	_, err := w.queries.CreateAuditLog(ctx, db.CreateAuditLogParams{
+		UserID:     uuid.NullUUID{UUID: entry.UserID, Valid: entry.UserID != uuid.Nil},
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		OldValues:  values(entry.OldValues),
+		NewValues:  values(entry.NewValues),
+		IpAddress:  db.EncryptedString{String: entry.IPAddress, Valid: true},
+		UserAgent:  db.EncryptedString{String: entry.UserAgent, Valid: true},
+	})
+	return err
+}
+
+// encryptAll encrypts every value in place with the field encryption keys
+func encryptAll(columns ...[]string) error {
+	for _, values := range columns {
+		for i, value := range values {
+			encrypted, err := db.EncryptString(value)
+			if err != nil {
+				return err
+			}
+			values[i] = encrypted
+		}
+	}
+	return nil
+}
+
+func (w *Writer) written(ctx context.Context, entry Entry) {
+	if w.config.Written != nil {
+		w.config.Written(ctx, entry)
+	}
+}
+
+func (w *Writer) failed(err error, entry Entry) {
+	middleware.RecordAuditEntryDropped("write_failed")
+	w.logger.Error("Failed to create audit log", err, map[string]any{
+		"action":      entry.Action,
+		"entity_type": entry.EntityType,
+		"entity_id":   entry.EntityID,
+	})
+}
+
+// values returns m as JSON, or NULL when m is nil
+func values(m map[string]any) json.RawMessage {
+	if m == nil {
+		return nil
+	}
+	data, _ := json.Marshal(m)
+	return data
+}
@@ -0,0 +1,461 @@
+// internal/backup/backup.go - Database backups with the PostgreSQL client tools
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/storage"
+)
+
+// Backup and restore statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Verification statuses
+const (
+	VerifyRunning = "running"
+	VerifyPassed  = "passed"
+	VerifyFailed  = "failed"
+)
+
+var (
+	// ErrBusy is returned when a backup, verification or restore is
+	// started while another runs
+	ErrBusy = errors.New("a backup, verification or restore is already running")
+	// ErrNotRestorable is returned for backups that did not complete
+	ErrNotRestorable = errors.New("only completed backups can be verified or restored")
+)
+
+// excludedTables hold the backup history, which restoring an older dump
+// must not roll back
+var excludedTables = []string{"backups", "backup_restores"}
+
+// Config controls the client tools and how long their runs may take
+type Config struct {
+	PgDump    string
+	PgRestore string
+	Timeout   time.Duration
+	Keep      int // completed backups kept; 0 keeps all
+	Database  string
+	// Env returns the PG* variables that connect the tools to the database
+	Env func(ctx context.Context) ([]string, error)
+}
+
+// Manager takes logical dumps with pg_dump into file storage and checks or
+// restores them with pg_restore. Only one of these runs at a time per
+// process, and the backups table allows only one running backup overall.
+type Manager struct {
+	queries   db.Querier
+	store     storage.Store
+	config    Config
+	logger    *logging.Logger
+	onRestore func(restore db.BackupRestore)
+
+	running sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager creates a manager. store may be nil, in which case every
+// operation fails.
+func NewManager(queries db.Querier, store storage.Store, config Config, logger *logging.Logger) *Manager {
+	if config.Timeout <= 0 {
+		config.Timeout = time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		queries: queries,
+		store:   store,
+		config:  config,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// OnRestore registers fn to run after a restore completes, while the lock
+// is still held, e.g. to bring the restored schema up to date
+func (m *Manager) OnRestore(fn func(restore db.BackupRestore)) {
+	m.onRestore = fn
+}
+
+// Start records the runs a restart cut off as failed
+func (m *Manager) Start() {
+	ctx, cancel := context.WithTimeout(m.ctx, 10*time.Second)
+	defer cancel()
+
+	for name, fail := range map[string]func(context.Context) error{
+		"backups":       m.queries.FailInterruptedBackups,
+		"verifications": m.queries.FailInterruptedBackupVerifications,
+		"restores":      m.queries.FailInterruptedBackupRestores,
+	} {
+		if err := fail(ctx); err != nil {
+			m.logger.Error("Failed to mark interrupted "+name+" as failed", err, nil)
+		}
+	}
+}
+
+// Stop cancels a running operation, waiting until ctx expires. The
+// interrupted run is recorded as failed.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Backup starts a dump in the background and returns the new backup record
+func (m *Manager) Backup(ctx context.Context, triggeredBy uuid.NullUUID) (db.Backup, error) {
+	if m.store == nil {
+		return db.Backup{}, errors.New("file storage is not available")
+	}
+	if !m.running.TryLock() {
+		return db.Backup{}, ErrBusy
+	}
+
+	record, err := m.queries.CreateBackup(ctx, triggeredBy)
+	if err != nil {
+		m.running.Unlock()
+		if pgErr, ok := db.AsPgError(err); ok && pgErr.Code == "23505" {
+			// Another instance is taking one
+			return db.Backup{}, ErrBusy
+		}
+		return db.Backup{}, err
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.running.Unlock()
+		m.runBackup(record)
+	}()
+	return record, nil
+}
+
+// Verify starts checking a backup in the background: its file must match
+// the recorded checksum and pg_restore must be able to read it
+func (m *Manager) Verify(ctx context.Context, id uuid.UUID) (db.Backup, error) {
+	if m.store == nil {
+		return db.Backup{}, errors.New("file storage is not available")
+	}
+	if !m.running.TryLock() {
+		return db.Backup{}, ErrBusy
+	}
+
+	record, err := m.queries.StartBackupVerification(ctx, id)
+	if err != nil {
+		m.running.Unlock()
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, getErr := m.queries.GetBackup(ctx, id); getErr == nil {
+				return db.Backup{}, ErrNotRestorable
+			}
+		}
+		return db.Backup{}, err
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.running.Unlock()
+		m.runVerify(record)
+	}()
+	return record, nil
+}
+
+// Restore starts replacing the database contents with a backup in the
+// background and returns the new restore record. Callers make sure nothing
+// else writes to the database meanwhile.
+func (m *Manager) Restore(ctx context.Context, id uuid.UUID, triggeredBy uuid.NullUUID) (db.BackupRestore, error) {
+	if m.store == nil {
+		return db.BackupRestore{}, errors.New("file storage is not available")
+	}
+	if !m.running.TryLock() {
+		return db.BackupRestore{}, ErrBusy
+	}
+
+	record, err := m.queries.GetBackup(ctx, id)
+	if err == nil && record.Status != StatusCompleted {
+		err = ErrNotRestorable
+	}
+	var restore db.BackupRestore
+	if err == nil {
+		restore, err = m.queries.CreateBackupRestore(ctx, db.CreateBackupRestoreParams{
+			BackupID:    id,
+			TriggeredBy: triggeredBy,
+		})
+	}
+	if err != nil {
+		m.running.Unlock()
+		return db.BackupRestore{}, err
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.running.Unlock()
+		m.runRestore(record, restore)
+	}()
+	return restore, nil
+}
+
+// runBackup dumps the database, uploads the dump and prunes old backups
+func (m *Manager) runBackup(record db.Backup) {
+	started := time.Now()
+	finish := db.FinishBackupParams{ID: record.ID, Status: StatusCompleted}
+
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.Timeout)
+	defer cancel()
+
+	key := storage.Join(storage.PrefixBackups, record.ID.String()+".dump")
+	size, checksum, err := m.dump(ctx, key)
+	if err != nil {
+		finish.Status = StatusFailed
+		finish.Error = sql.NullString{String: err.Error(), Valid: true}
+	} else {
+		finish.StorageKey = sql.NullString{String: key, Valid: true}
+		finish.SizeBytes = sql.NullInt64{Int64: size, Valid: true}
+		finish.Checksum = sql.NullString{String: checksum, Valid: true}
+	}
+
+	// The run may have been cancelled; the result is still recorded
+	recordCtx, recordCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer recordCancel()
+	if _, err := m.queries.FinishBackup(recordCtx, finish); err != nil {
+		m.logger.Error("Failed to record backup result", err, map[string]any{"backup_id": record.ID})
+	}
+
+	fields := map[string]any{
+		"backup_id":   record.ID,
+		"status":      finish.Status,
+		"size_bytes":  finish.SizeBytes.Int64,
+		"duration_ms": time.Since(started).Milliseconds(),
+	}
+	if finish.Status == StatusFailed {
+		fields["error"] = finish.Error.String
+		m.logger.Warn("Database backup failed", fields)
+		return
+	}
+	m.logger.Info("Database backup completed", fields)
+	m.prune(recordCtx)
+}
+
+// dump runs pg_dump into a temporary file and stores it under key
+func (m *Manager) dump(ctx context.Context, key string) (int64, string, error) {
+	env, err := m.config.Env(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read database credentials: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "digiorder-backup-*.dump")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// The tables force row level security on their owner, and pg_dump stops
+	// at the first table it would filter unless it is allowed to dump
+	// through the policies. No digiorder.* setting is made on its session,
+	// so the policies hide nothing from it.
+	args := []string{"--format=custom", "--no-owner", "--no-privileges", "--enable-row-security", "--file=" + file.Name()}
+	for _, table := range excludedTables {
+		args = append(args, "--exclude-table="+table)
+	}
+	if err := m.run(ctx, env, m.config.PgDump, args...); err != nil {
+		return 0, "", err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+	if err := m.store.Put(ctx, key, file, size, "application/octet-stream"); err != nil {
+		return 0, "", fmt.Errorf("failed to store the dump: %w", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// prune deletes the completed backups beyond the newest Keep
+func (m *Manager) prune(ctx context.Context) {
+	if m.config.Keep <= 0 {
+		return
+	}
+	expired, err := m.queries.ListExpiredBackups(ctx, int32(m.config.Keep))
+	if err != nil {
+		m.logger.Error("Failed to list expired backups", err, nil)
+		return
+	}
+	for _, record := range expired {
+		if err := m.store.Delete(ctx, record.StorageKey.String); err != nil {
+			m.logger.Error("Failed to delete expired backup file", err, map[string]any{"backup_id": record.ID})
+			continue
+		}
+		if err := m.queries.DeleteBackup(ctx, record.ID); err != nil {
+			m.logger.Error("Failed to delete expired backup", err, map[string]any{"backup_id": record.ID})
+		}
+	}
+}
+
+// runVerify downloads a backup and lists its contents with pg_restore
+func (m *Manager) runVerify(record db.Backup) {
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.Timeout)
+	defer cancel()
+
+	finish := db.FinishBackupVerificationParams{
+		ID:           record.ID,
+		VerifyStatus: sql.NullString{String: VerifyPassed, Valid: true},
+	}
+	err := m.withDownload(ctx, record, func(path string) error {
+		return m.run(ctx, nil, m.config.PgRestore, "--list", path)
+	})
+	if err != nil {
+		finish.VerifyStatus.String = VerifyFailed
+		finish.VerifyError = sql.NullString{String: err.Error(), Valid: true}
+	}
+
+	recordCtx, recordCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer recordCancel()
+	if _, err := m.queries.FinishBackupVerification(recordCtx, finish); err != nil {
+		m.logger.Error("Failed to record backup verification", err, map[string]any{"backup_id": record.ID})
+	}
+
+	fields := map[string]any{"backup_id": record.ID, "status": finish.VerifyStatus.String}
+	if err != nil {
+		fields["error"] = err.Error()
+		m.logger.Warn("Backup verification failed", fields)
+		return
+	}
+	m.logger.Info("Backup verified", fields)
+}
+
+// runRestore downloads a backup and restores it over the database in one
+// transaction, so a failed restore leaves the database as it was
+func (m *Manager) runRestore(record db.Backup, restore db.BackupRestore) {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.Timeout)
+	defer cancel()
+
+	finish := db.FinishBackupRestoreParams{ID: restore.ID, Status: StatusCompleted}
+	err := m.withDownload(ctx, record, func(path string) error {
+		env, err := m.config.Env(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read database credentials: %w", err)
+		}
+		return m.run(ctx, env, m.config.PgRestore,
+			"--clean", "--if-exists", "--no-owner", "--no-privileges",
+			"--single-transaction", "--exit-on-error",
+			"--dbname="+m.config.Database, path)
+	})
+	if err != nil {
+		finish.Status = StatusFailed
+		finish.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+
+	recordCtx, recordCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer recordCancel()
+	finished, recordErr := m.queries.FinishBackupRestore(recordCtx, finish)
+	if recordErr != nil {
+		m.logger.Error("Failed to record restore result", recordErr, map[string]any{"restore_id": restore.ID})
+		finished = restore
+		finished.Status = finish.Status
+	}
+
+	fields := map[string]any{
+		"backup_id":   record.ID,
+		"restore_id":  restore.ID,
+		"status":      finish.Status,
+		"duration_ms": time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		m.logger.Warn("Database restore failed", fields)
+		return
+	}
+	m.logger.Info("Database restored", fields)
+	if m.onRestore != nil {
+		m.onRestore(finished)
+	}
+}
+
+// withDownload copies a backup into a temporary file, checks it against
+// the recorded checksum and calls fn with its path
+func (m *Manager) withDownload(ctx context.Context, record db.Backup, fn func(path string) error) error {
+	src, _, err := m.store.Get(ctx, record.StorageKey.String)
+	if err != nil {
+		return fmt.Errorf("failed to read the backup file: %w", err)
+	}
+	defer src.Close()
+
+	file, err := os.CreateTemp("", "digiorder-restore-*.dump")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), src)
+	if err != nil {
+		return fmt.Errorf("failed to read the backup file: %w", err)
+	}
+	if size != record.SizeBytes.Int64 || hex.EncodeToString(hash.Sum(nil)) != record.Checksum.String {
+		return errors.New("backup file does not match its recorded size and checksum")
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return fn(file.Name())
+}
+
+// run executes a client tool, reporting its output when it fails
+func (m *Manager) run(ctx context.Context, env []string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s stopped: %w", name, ctx.Err())
+	}
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if len(msg) > 1000 {
+			msg = msg[len(msg)-1000:]
+		}
+		if msg == "" {
+			return fmt.Errorf("%s failed: %w", name, err)
+		}
+		return fmt.Errorf("%s failed: %w: %s", name, err, msg)
+	}
+	return nil
+}
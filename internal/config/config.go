@@ -0,0 +1,1380 @@
+// internal/config/config.go - Unified application configuration
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/cron"
+	"github.com/jamalkaksouri/DigiOrder/internal/policy"
+	"go.yaml.in/yaml/v2"
+)
+
+// Config is the complete application configuration. Values are resolved in
+// order: built-in defaults, then the optional YAML file, then environment
+// variables, so existing .env based deployments keep working unchanged.
+type Config struct {
+	// Path is the file the configuration was loaded from, reused on reload
+	Path string `yaml:"-"`
+
+	// secretRefs maps the path of every setting read from a secret store
+	// to its reference, so the secret can be read again
+	secretRefs map[string]string
+
+	Env         string            `yaml:"env"`
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Features    FeatureFlags      `yaml:"features"`
+	Log         LogConfig         `yaml:"log"`
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	Notify      NotifyConfig      `yaml:"notify"`
+	Events      EventsConfig      `yaml:"events"`
+	FHIR        FHIRConfig        `yaml:"fhir"`
+	Registry    RegistryConfig    `yaml:"registry"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Reports     ReportsConfig     `yaml:"reports"`
+	PDF         PDFConfig         `yaml:"pdf"`
+	Recurring   RecurringConfig   `yaml:"recurring_orders"`
+	Labels      LabelsConfig      `yaml:"labels"`
+	ERP         ERPConfig         `yaml:"erp"`
+	APIUsage    APIUsageConfig    `yaml:"api_usage"`
+	Exports     ExportsConfig     `yaml:"exports"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Tenancy     TenancyConfig     `yaml:"tenancy"`
+	GeoIP       GeoIPConfig       `yaml:"geoip"`
+	Anomaly     AnomalyConfig     `yaml:"anomaly"`
+	Encryption  EncryptionConfig  `yaml:"field_encryption"`
+	Secrets     SecretsConfig     `yaml:"secrets"`
+	Backup      BackupConfig      `yaml:"backup"`
+	Frontend    FrontendConfig    `yaml:"frontend"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler"`
+	DrugChecks  DrugChecksConfig  `yaml:"interactions"`
+	Pricing     PricingConfig     `yaml:"pricing"`
+	Policies    []PolicyRule      `yaml:"policies"`
+}
+
+// ServerConfig holds HTTP listener settings
+type ServerConfig struct {
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// DatabaseConfig holds PostgreSQL connection and pool settings
+type DatabaseConfig struct {
+	Host              string        `yaml:"host"`
+	Port              string        `yaml:"port"`
+	User              string        `yaml:"user" secret:"true"`
+	Password          string        `yaml:"password" secret:"true"`
+	Name              string        `yaml:"name"`
+	SSLMode           string        `yaml:"sslmode"`
+	ApplicationName   string        `yaml:"application_name"`
+	MaxOpenConns      int           `yaml:"max_open_conns"`
+	MinIdleConns      int           `yaml:"min_idle_conns"`
+	ConnMaxLifetime   time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime   time.Duration `yaml:"conn_max_idle_time"`
+	ConnectTimeout    time.Duration `yaml:"connect_timeout"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+	AutoMigrate       bool          `yaml:"auto_migrate"`
+
+	// Statements slower than SlowQueryThreshold are kept for
+	// SlowQueryRetention; a zero threshold keeps none
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	SlowQueryRetention time.Duration `yaml:"slow_query_retention"`
+}
+
+// JWTConfig holds token signing settings. Expiry is the lifetime of access
+// tokens; RefreshExpiry that of the refresh tokens issued with them at
+// login, which are exchanged for a new pair until it runs out.
+//
+// Access tokens are signed with Secret (HS256) unless Algorithm is RS256
+// or EdDSA, when they are signed with the PEM private key in
+// PrivateKeyFile and published for other services at
+// /.well-known/jwks.json. Tokens signed with the keys in PreviousKeyFiles
+// (private or public PEM keys) are still accepted, so keys can be rotated
+// without signing anyone out. Secret is required either way; it also signs
+// download URLs.
+type JWTConfig struct {
+	Secret           string        `yaml:"secret" secret:"true"`
+	Expiry           time.Duration `yaml:"expiry"`
+	RefreshExpiry    time.Duration `yaml:"refresh_expiry"`
+	Algorithm        string        `yaml:"algorithm"`
+	PrivateKeyFile   string        `yaml:"private_key_file"`
+	PreviousKeyFiles []string      `yaml:"previous_key_files"`
+}
+
+// RateLimitConfig holds request throttling settings. Password reset
+// requests are limited per IP and per username within PasswordResetWindow;
+// APIKeyRPM limits each API key on top of the limits of its IP.
+type RateLimitConfig struct {
+	GlobalRPS            int           `yaml:"global_rps"`
+	GlobalBurst          int           `yaml:"global_burst"`
+	AuthenticatedRPM     int           `yaml:"authenticated_rpm"`
+	LoginMaxAttempts     int           `yaml:"login_max_attempts"`
+	LoginWindow          time.Duration `yaml:"login_window"`
+	MaxClients           int           `yaml:"max_clients"` // clients tracked in memory, least recently seen evicted
+	PasswordResetPerIP   int           `yaml:"password_reset_per_ip"`
+	PasswordResetPerUser int           `yaml:"password_reset_per_user"`
+	PasswordResetWindow  time.Duration `yaml:"password_reset_window"`
+	APIKeyRPM            int           `yaml:"api_key_rpm"`
+}
+
+// CORSConfig holds cross-origin settings
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// CacheConfig holds response cache TTLs and limits, how long roles and
+// their permissions are reused, and how list totals are kept. The cached routes share one response cache of at most
+// MaxEntries responses taking MaxMB megabytes, evicting the least recently
+// used. Unfiltered lists of tables above CountEstimateAbove rows report
+// the planner's estimate as their total instead of counting.
+type CacheConfig struct {
+	ProductsTTL        time.Duration `yaml:"products_ttl"`
+	CatalogTTL         time.Duration `yaml:"catalog_ttl"`
+	MaxEntries         int           `yaml:"max_entries"`
+	MaxMB              int           `yaml:"max_mb"`
+	RolesTTL           time.Duration `yaml:"roles_ttl"`            // 0 reads roles on every lookup
+	CountTTL           time.Duration `yaml:"count_ttl"`            // 0 counts every page
+	CountEstimateAbove int           `yaml:"count_estimate_above"` // 0 always counts
+}
+
+// FeatureFlags switch optional parts of the API on or off
+type FeatureFlags struct {
+	APIV2          bool `yaml:"api_v2"`
+	ResponseCache  bool `yaml:"response_cache"`
+	Metrics        bool `yaml:"metrics"`
+	SetupEndpoints bool `yaml:"setup_endpoints"`
+	APIDocs        bool `yaml:"api_docs"`
+}
+
+// LogConfig holds logger settings that can change at runtime
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// MaintenanceConfig holds the maintenance mode state applied at startup
+// and on reload
+type MaintenanceConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Message    string        `yaml:"message"`
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+// NotifyConfig holds notification delivery settings
+type NotifyConfig struct {
+	Workers     int        `yaml:"workers"`
+	QueueSize   int        `yaml:"queue_size"`
+	MaxAttempts int        `yaml:"max_attempts"`
+	SMTP        SMTPConfig `yaml:"smtp"`
+	SMS         SMSConfig  `yaml:"sms"`
+	Push        PushConfig `yaml:"push"`
+	Chat        ChatConfig `yaml:"chat"`
+}
+
+// SMTPConfig holds the outgoing mail server. Email is disabled while Host
+// is empty.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username" secret:"true"`
+	Password string `yaml:"password" secret:"true"`
+	From     string `yaml:"from"`
+}
+
+// SMSConfig holds the SMS gateway. SMS is disabled while Provider is
+// empty; Kavenegar needs APIKey, Twilio needs AccountSID, AuthToken and From.
+type SMSConfig struct {
+	Provider   string `yaml:"provider"`
+	APIKey     string `yaml:"api_key" secret:"true"`
+	AccountSID string `yaml:"account_sid"`
+	AuthToken  string `yaml:"auth_token" secret:"true"`
+	From       string `yaml:"from"`
+}
+
+// PushConfig holds the Firebase Cloud Messaging service account. Push is
+// disabled while CredentialsFile is empty; ProjectID defaults to the
+// project in the credentials.
+type PushConfig struct {
+	CredentialsFile string `yaml:"credentials_file"`
+	ProjectID       string `yaml:"project_id"`
+}
+
+// EventsConfig holds domain event delivery settings. Events are always
+// recorded in the outbox; the relay delivers them to the configured sinks.
+type EventsConfig struct {
+	PollInterval time.Duration  `yaml:"poll_interval"`
+	BatchSize    int            `yaml:"batch_size"`
+	Retention    time.Duration  `yaml:"retention"`
+	Webhooks     WebhooksConfig `yaml:"webhooks"`
+	Broker       BrokerConfig   `yaml:"broker"`
+}
+
+// WebhooksConfig lists HTTP endpoints that receive every event. Requests
+// are signed with Secret.
+type WebhooksConfig struct {
+	URLs    []string      `yaml:"urls"`
+	Secret  string        `yaml:"secret" secret:"true"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// BrokerConfig selects the message broker events are published to.
+// Publishing is disabled while Type is empty.
+type BrokerConfig struct {
+	Type      string        `yaml:"type"` // nats or kafka
+	URL       string        `yaml:"url"`  // nats://host:4222 or the Kafka REST Proxy URL
+	Username  string        `yaml:"username" secret:"true"`
+	Password  string        `yaml:"password" secret:"true"`
+	Token     string        `yaml:"token" secret:"true"` // NATS auth token
+	Prefix    string        `yaml:"prefix"`              // subject/topic prefix
+	JetStream bool          `yaml:"jetstream"`           // NATS: wait for JetStream acks
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// FHIRConfig holds FHIR export settings
+type FHIRConfig struct {
+	// SystemBase namespaces the identifier systems on exported resources,
+	// e.g. urn:digiorder -> urn:digiorder:product
+	SystemBase string `yaml:"system_base"`
+}
+
+// RegistryConfig holds the national drug registry sync. Scheduled syncs
+// run every SyncInterval while URL is set; 0 disables them, leaving manual
+// uploads through the API.
+type RegistryConfig struct {
+	URL          string        `yaml:"url"`
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// StorageConfig selects where product images, order attachments and
+// export files are kept
+type StorageConfig struct {
+	Backend     string        `yaml:"backend"` // local or s3
+	URLExpiry   time.Duration `yaml:"url_expiry"`
+	MaxUploadMB int           `yaml:"max_upload_mb"`
+	// SigningKey signs local download URLs; the JWT secret when empty
+	SigningKey string             `yaml:"signing_key" secret:"true"`
+	Local      LocalStorageConfig `yaml:"local"`
+	S3         S3StorageConfig    `yaml:"s3"`
+}
+
+// LocalStorageConfig keeps files on the server's disk
+type LocalStorageConfig struct {
+	Path string `yaml:"path"`
+}
+
+// S3StorageConfig holds an S3 or MinIO bucket. PublicEndpoint is used in
+// download URLs when clients reach the store under another address.
+type S3StorageConfig struct {
+	Endpoint       string `yaml:"endpoint"`
+	PublicEndpoint string `yaml:"public_endpoint"`
+	Region         string `yaml:"region"`
+	Bucket         string `yaml:"bucket"`
+	AccessKey      string `yaml:"access_key" secret:"true"`
+	SecretKey      string `yaml:"secret_key" secret:"true"`
+	PathStyle      bool   `yaml:"path_style"` // required for MinIO
+}
+
+// ReportsConfig holds the scheduled report emails. Send hours, days and
+// report periods are in Timezone; weekly reports go out on WeekStart.
+// With Calendar jalali the attachments label dates in both calendars.
+type ReportsConfig struct {
+	Timezone      string        `yaml:"timezone"`
+	WeekStart     string        `yaml:"week_start"`
+	Calendar      string        `yaml:"calendar"`       // gregorian or jalali
+	CheckInterval time.Duration `yaml:"check_interval"` // 0 disables sending
+}
+
+// PDFConfig holds the TrueType fonts PDFs are drawn in. Persian text
+// needs a font with Arabic glyphs; without one the standard Helvetica
+// fonts are used and such text prints as '?'.
+type PDFConfig struct {
+	Font     string `yaml:"font"`      // regular face
+	BoldFont string `yaml:"bold_font"` // bold face; the regular one when unset
+}
+
+// RecurringConfig holds the runner placing recurring orders. Orders are
+// placed at midnight in Timezone, which also dates the calendar feed; the
+// scheduler's recurring_orders job looks for due ones.
+type RecurringConfig struct {
+	Timezone string `yaml:"timezone"`
+}
+
+// LabelsConfig holds the network printers for shelf and order item labels.
+// Jobs naming no printer go to DefaultPrinter, or the first printer.
+type LabelsConfig struct {
+	Printers       []PrinterConfig `yaml:"printers"`
+	DefaultPrinter string          `yaml:"default_printer"`
+	Timeout        time.Duration   `yaml:"timeout"`
+}
+
+// PrinterConfig is a printer taking raw ESC/POS or ZPL jobs over TCP
+type PrinterConfig struct {
+	Name     string `yaml:"name"`
+	Address  string `yaml:"address"`  // host:port, usually port 9100
+	Language string `yaml:"language"` // escpos or zpl
+	Width    int    `yaml:"width"`    // printable width in dots; 0 for 576 (escpos) or 812 (zpl)
+}
+
+// ERPConfig maps fulfilled orders onto the document the pharmacy's
+// accounting/ERP system imports. Orders in Statuses are pulled through the
+// API or pushed to PushURL in batches of up to BatchSize orders. With
+// CallbackSecret set, the ERP may acknowledge pulled batches with requests
+// signed like our webhooks, sent within CallbackTolerance.
+type ERPConfig struct {
+	Format            string        `yaml:"format"` // csv, json or xml
+	Statuses          []string      `yaml:"statuses"`
+	BatchSize         int           `yaml:"batch_size"`
+	Fields            []ERPField    `yaml:"fields"`
+	PushURL           string        `yaml:"push_url"`
+	PushToken         string        `yaml:"push_token" secret:"true"` // sent as a bearer token
+	Timeout           time.Duration `yaml:"timeout"`
+	CallbackSecret    string        `yaml:"callback_secret" secret:"true"`
+	CallbackTolerance time.Duration `yaml:"callback_tolerance"`
+}
+
+// APIUsageConfig holds the per-consumer usage counters. Counters are
+// written every FlushInterval and daily rows kept for Retention; days
+// follow the reports timezone.
+type APIUsageConfig struct {
+	FlushInterval time.Duration `yaml:"flush_interval"` // 0 disables tracking
+	Retention     time.Duration `yaml:"retention"`
+}
+
+// ExportsConfig bounds the Excel and CSV exports. An export stops after
+// MaxRows rows or once it has run for Timeout, whichever comes first, and
+// says it was cut short.
+type ExportsConfig struct {
+	MaxRows int           `yaml:"max_rows"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// AuditConfig holds the queue of audit entries. Up to BufferSize entries
+// wait to be written, BatchSize at a time or every FlushInterval; entries
+// logged while it is full are dropped. Security changes skip the queue.
+type AuditConfig struct {
+	BufferSize    int           `yaml:"buffer_size"` // 0 writes every entry on its own
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// TenancyConfig holds multi-pharmacy settings. When enabled, every
+// authenticated request only sees the users, orders and products of its
+// user's tenant. With SharedCatalog, products created by any tenant are
+// visible to all of them.
+type TenancyConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	SharedCatalog bool              `yaml:"shared_catalog"`
+	Quotas        TenantQuotaConfig `yaml:"quotas"`
+}
+
+// GeoIPConfig holds the MaxMind databases (GeoLite2 or GeoIP2, .mmdb)
+// used to place login attempts and blocked addresses. Each lookup is
+// disabled while its path is empty.
+type GeoIPConfig struct {
+	CityDB string `yaml:"city_db"`
+	ASNDB  string `yaml:"asn_db"`
+}
+
+// AnomalyConfig controls the login anomaly analyzer, which looks at new
+// successful logins every CheckInterval. A login is impossible travel when
+// it is more than MinTravelKm from the user's previous one and getting
+// there would have taken more than MaxTravelSpeedKmh; it is at an unusual
+// hour when the user logged in at least UnusualHourMinLogins times within
+// History, never within an hour of it. Hours follow the reports timezone.
+type AnomalyConfig struct {
+	CheckInterval        time.Duration `yaml:"check_interval"` // 0 disables the analyzer
+	MaxTravelSpeedKmh    int           `yaml:"max_travel_speed_kmh"`
+	MinTravelKm          int           `yaml:"min_travel_km"`
+	UnusualHourMinLogins int           `yaml:"unusual_hour_min_logins"`
+	History              time.Duration `yaml:"history"`
+}
+
+// EncryptionConfig encrypts sensitive columns at rest with AES-256-GCM:
+// user full names, the addresses and user agents of audit log entries and
+// the device info of login attempts. Each key is "id:base64" with a 32-byte
+// key, or "id:kms:base64" with a data key wrapped by AWS KMS. The first key
+// encrypts new values; the others only decrypt, until the re-encryption
+// job run every RotateInterval has moved their values to the first.
+type EncryptionConfig struct {
+	Keys           []string      `yaml:"keys" secret:"true"`
+	RotateInterval time.Duration `yaml:"rotate_interval"` // 0 disables re-encryption
+	RotateBatch    int           `yaml:"rotate_batch"`
+	KMS            KMSConfig     `yaml:"kms"`
+}
+
+// KMSConfig reaches AWS KMS to unwrap data keys. Endpoint defaults to the
+// region's.
+type KMSConfig struct {
+	Region       string `yaml:"region"`
+	Endpoint     string `yaml:"endpoint"`
+	AccessKey    string `yaml:"access_key" secret:"true"`
+	SecretKey    string `yaml:"secret_key" secret:"true"`
+	SessionToken string `yaml:"session_token" secret:"true"`
+}
+
+// SecretsConfig holds the stores secret settings can be read from. Any
+// setting holding a password, key or token may instead hold a reference:
+// env:NAME, file:/path, vault:path#field or awssm:secret-id#field. With
+// RefreshInterval set, new database connections read their user and
+// password again once that long has passed, picking up rotated
+// credentials; connections already open keep theirs until
+// database.conn_max_lifetime retires them.
+type SecretsConfig struct {
+	Timeout         time.Duration    `yaml:"timeout"`
+	RefreshInterval time.Duration    `yaml:"refresh_interval"` // 0 keeps the credentials read at startup
+	Vault           VaultConfig      `yaml:"vault"`
+	AWS             AWSSecretsConfig `yaml:"aws"`
+}
+
+// VaultConfig reaches HashiCorp Vault. Its token may itself be an env: or
+// file: reference, e.g. to the file written by the Vault agent.
+type VaultConfig struct {
+	Address   string `yaml:"address"`
+	Token     string `yaml:"token" secret:"true"`
+	Namespace string `yaml:"namespace"`
+}
+
+// AWSSecretsConfig reaches AWS Secrets Manager. Endpoint defaults to the
+// region's; the keys may be env: or file: references.
+type AWSSecretsConfig struct {
+	Region       string `yaml:"region"`
+	Endpoint     string `yaml:"endpoint"`
+	AccessKey    string `yaml:"access_key" secret:"true"`
+	SecretKey    string `yaml:"secret_key" secret:"true"`
+	SessionToken string `yaml:"session_token" secret:"true"`
+}
+
+// BackupConfig holds the database backups taken through the API with the
+// PostgreSQL client tools, which must be installed alongside the server.
+// Backups are kept in file storage; Keep limits how many completed ones are
+// kept, 0 keeping all of them.
+type BackupConfig struct {
+	PgDump    string        `yaml:"pg_dump"`
+	PgRestore string        `yaml:"pg_restore"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Keep      int           `yaml:"keep"`
+}
+
+// FrontendConfig serves the single-page app from the server itself, for
+// installs without a web server in front. Dir serves a build from disk;
+// empty serves the build embedded at compile time (frontend/dist).
+type FrontendConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"`
+	CSP     string `yaml:"csp"`
+}
+
+// SchedulerConfig holds the periodic jobs (see internal/scheduler). Each
+// job runs on a cron expression read in Timezone; an empty expression
+// leaves the job to run only on request. AuditRetention is how long audit
+// entries are kept, 0 keeping them for good.
+type SchedulerConfig struct {
+	Enabled        bool                `yaml:"enabled"`
+	Timezone       string              `yaml:"timezone"`
+	Timeout        time.Duration       `yaml:"timeout"`
+	AuditRetention time.Duration       `yaml:"audit_retention"`
+	Jobs           SchedulerJobsConfig `yaml:"jobs"`
+}
+
+// SchedulerJobsConfig holds the cron expression of each job
+type SchedulerJobsConfig struct {
+	RateLimitArchive     string `yaml:"rate_limit_archive"`
+	LoginAttemptCleanup  string `yaml:"login_attempt_cleanup"`
+	RefreshTokenCleanup  string `yaml:"refresh_token_cleanup"`
+	PasswordResetCleanup string `yaml:"password_reset_cleanup"`
+	AuditRetention       string `yaml:"audit_retention"`
+	StockCheck           string `yaml:"stock_check"`
+	RecurringOrders      string `yaml:"recurring_orders"`
+}
+
+// DrugChecksConfig selects where drug interactions and ATC classes are
+// looked up when items are added to an order: "database" (the dataset
+// admins upload), "http" (a service at URL, sent Token as a bearer token)
+// or "none", which turns the warnings off.
+type DrugChecksConfig struct {
+	Provider string        `yaml:"provider"`
+	URL      string        `yaml:"url"`
+	Token    string        `yaml:"token" secret:"true"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// PricingConfig holds the tax added to the subtotal of an order's items
+// for its total, as a percentage
+type PricingConfig struct {
+	TaxPercent float64 `yaml:"tax_percent"`
+}
+
+// PolicyRule lets Roles (every role when empty, admins excepted) take
+// Action, such as orders:update, only while every condition of Require
+// holds, e.g. "resource.status == draft"; see internal/policy for the
+// conditions and attributes. Message is returned to denied callers.
+type PolicyRule struct {
+	Name    string   `yaml:"name"`
+	Action  string   `yaml:"action"`
+	Roles   []string `yaml:"roles"`
+	Require []string `yaml:"require"`
+	Message string   `yaml:"message"`
+}
+
+// TenantQuotaConfig holds the default limits of every tenant; a tenant can
+// override each one (see PUT /tenants/:id/quotas). 0 means unlimited. Daily
+// counts are shared between instances every SyncInterval, and days follow
+// the reports timezone.
+type TenantQuotaConfig struct {
+	RequestsPerMinute int           `yaml:"requests_per_minute"`
+	RequestsPerDay    int           `yaml:"requests_per_day"`
+	OrdersPerDay      int           `yaml:"orders_per_day"`
+	SyncInterval      time.Duration `yaml:"sync_interval"`
+}
+
+// ERPField is one field of the ERP document, taking the value of Source
+// (e.g. order.id, item.quantity, product.barcode) or the constant Value
+type ERPField struct {
+	Name   string `yaml:"name"`
+	Source string `yaml:"source"`
+	Value  string `yaml:"value"`
+}
+
+// ChatConfig holds the Slack and Microsoft Teams webhooks that receive
+// security alerts: IP bans, refused changes to protected administrators and
+// audit entries matching AuditRules ("entity_type.action", "*" matches any
+// part). The rule login.new_country alerts on a successful login from a
+// country the user has not logged in from before, and
+// security_event.<kind> on the findings of the anomaly analyzer. Each chat
+// is disabled while its URL is empty.
+type ChatConfig struct {
+	SlackWebhookURL string   `yaml:"slack_webhook_url" secret:"true"`
+	TeamsWebhookURL string   `yaml:"teams_webhook_url" secret:"true"`
+	AuditRules      []string `yaml:"audit_rules"`
+}
+
+// Default returns the configuration used when nothing else is provided
+func Default() *Config {
+	return &Config{
+		Env: "production",
+		Server: ServerConfig{
+			Host:            "0.0.0.0",
+			Port:            "5582",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:              "localhost",
+			Port:              "5432",
+			User:              "postgres",
+			Password:          "postgres",
+			Name:              "digiorder",
+			SSLMode:           "disable",
+			ApplicationName:   "digiorder-api",
+			MaxOpenConns:      25,
+			MinIdleConns:      2,
+			ConnMaxLifetime:   30 * time.Minute,
+			ConnMaxIdleTime:   5 * time.Minute,
+			ConnectTimeout:    5 * time.Second,
+			HealthCheckPeriod: time.Minute,
+			AutoMigrate:       true,
+
+			SlowQueryThreshold: 500 * time.Millisecond,
+			SlowQueryRetention: 30 * 24 * time.Hour,
+		},
+		JWT: JWTConfig{
+			Expiry:        15 * time.Minute,
+			RefreshExpiry: 30 * 24 * time.Hour,
+			Algorithm:     "HS256",
+		},
+		RateLimit: RateLimitConfig{
+			GlobalRPS:            100,
+			GlobalBurst:          200,
+			AuthenticatedRPM:     1000,
+			LoginMaxAttempts:     5,
+			LoginWindow:          5 * time.Minute,
+			MaxClients:           100000,
+			PasswordResetPerIP:   10,
+			PasswordResetPerUser: 3,
+			PasswordResetWindow:  time.Hour,
+			APIKeyRPM:            600,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{
+				"http://localhost:3000",
+				"http://localhost:5173", // Vite default
+			},
+		},
+		Cache: CacheConfig{
+			ProductsTTL:        5 * time.Minute,
+			CatalogTTL:         10 * time.Minute,
+			MaxEntries:         1000,
+			MaxMB:              64,
+			RolesTTL:           time.Minute,
+			CountTTL:           30 * time.Second,
+			CountEstimateAbove: 100000,
+		},
+		Features: FeatureFlags{
+			APIV2:          true,
+			ResponseCache:  true,
+			Metrics:        true,
+			SetupEndpoints: true,
+			APIDocs:        true,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Maintenance: MaintenanceConfig{
+			RetryAfter: 5 * time.Minute,
+		},
+		Notify: NotifyConfig{
+			Workers:     2,
+			QueueSize:   500,
+			MaxAttempts: 5,
+			SMTP: SMTPConfig{
+				Port: "587",
+				From: "DigiOrder <no-reply@digiorder.local>",
+			},
+			Chat: ChatConfig{
+				AuditRules: []string{"user.delete", "role_permission.*", "permission.delete", "config.reload", "login.new_country", "security_event.impossible_travel"},
+			},
+		},
+		Events: EventsConfig{
+			PollInterval: 2 * time.Second,
+			BatchSize:    100,
+			Retention:    7 * 24 * time.Hour,
+			Webhooks: WebhooksConfig{
+				Timeout: 10 * time.Second,
+			},
+			Broker: BrokerConfig{
+				Prefix:  "digiorder",
+				Timeout: 10 * time.Second,
+			},
+		},
+		FHIR: FHIRConfig{
+			SystemBase: "urn:digiorder",
+		},
+		Registry: RegistryConfig{
+			Timeout: 2 * time.Minute,
+		},
+		Storage: StorageConfig{
+			Backend:     "local",
+			URLExpiry:   15 * time.Minute,
+			MaxUploadMB: 10,
+			Local: LocalStorageConfig{
+				Path: "data/files",
+			},
+			S3: S3StorageConfig{
+				Region: "us-east-1",
+			},
+		},
+		Reports: ReportsConfig{
+			Timezone:      "UTC",
+			WeekStart:     "monday",
+			Calendar:      "gregorian",
+			CheckInterval: time.Minute,
+		},
+		Recurring: RecurringConfig{
+			Timezone: "UTC",
+		},
+		Labels: LabelsConfig{
+			Timeout: 5 * time.Second,
+		},
+		ERP: ERPConfig{
+			Format:    "csv",
+			Statuses:  []string{"fulfilled", "delivered", "completed"},
+			BatchSize: 500,
+			Fields: []ERPField{
+				{Name: "order_id", Source: "order.id"},
+				{Name: "order_date", Source: "order.created_at"},
+				{Name: "status", Source: "order.status"},
+				{Name: "product", Source: "product.name"},
+				{Name: "strength", Source: "product.strength"},
+				{Name: "barcode", Source: "product.barcode"},
+				{Name: "quantity", Source: "item.quantity"},
+				{Name: "unit", Source: "item.unit"},
+			},
+			Timeout:           30 * time.Second,
+			CallbackTolerance: 5 * time.Minute,
+		},
+		APIUsage: APIUsageConfig{
+			FlushInterval: time.Minute,
+			Retention:     90 * 24 * time.Hour,
+		},
+		Exports: ExportsConfig{
+			MaxRows: 100000,
+			Timeout: 5 * time.Minute,
+		},
+		Audit: AuditConfig{
+			BufferSize:    10000,
+			BatchSize:     100,
+			FlushInterval: time.Second,
+		},
+		Anomaly: AnomalyConfig{
+			CheckInterval:        time.Minute,
+			MaxTravelSpeedKmh:    900,
+			MinTravelKm:          300,
+			UnusualHourMinLogins: 20,
+			History:              30 * 24 * time.Hour,
+		},
+		Encryption: EncryptionConfig{
+			RotateInterval: time.Hour,
+			RotateBatch:    500,
+		},
+		Secrets: SecretsConfig{
+			Timeout: 10 * time.Second,
+		},
+		Backup: BackupConfig{
+			PgDump:    "pg_dump",
+			PgRestore: "pg_restore",
+			Timeout:   time.Hour,
+			Keep:      14,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:  true,
+			Timezone: "UTC",
+			Timeout:  time.Hour,
+			Jobs: SchedulerJobsConfig{
+				RateLimitArchive:     "0 * * * *",
+				LoginAttemptCleanup:  "30 3 * * *",
+				RefreshTokenCleanup:  "35 3 * * *",
+				PasswordResetCleanup: "40 3 * * *",
+				AuditRetention:       "45 3 * * *",
+				StockCheck:           "0 7 * * *",
+				RecurringOrders:      "* * * * *",
+			},
+		},
+		DrugChecks: DrugChecksConfig{
+			Provider: "database",
+			Timeout:  5 * time.Second,
+		},
+		Frontend: FrontendConfig{
+			CSP: "default-src 'self'; img-src 'self' data: blob:; style-src 'self' 'unsafe-inline'; " +
+				"font-src 'self' data:; connect-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'",
+		},
+		Tenancy: TenancyConfig{
+			SharedCatalog: true,
+			Quotas: TenantQuotaConfig{
+				SyncInterval: 30 * time.Second,
+			},
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file at path (if
+// path is not empty) and environment overrides, then reads the secrets
+// settings refer to. The result is not validated;
+// call Validate before using it to start the server.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	cfg.Path = path
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveSecrets(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile merges a YAML file into cfg. Unknown keys are rejected so typos
+// surface at startup instead of being silently ignored.
+func (cfg *Config) loadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("unsupported config file format %q (expected .yaml or .yml)", filepath.Ext(path))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Address returns the host:port the HTTP server listens on
+func (s ServerConfig) Address() string {
+	return net.JoinHostPort(s.Host, s.Port)
+}
+
+// IsDevelopment reports whether the app runs in the development environment
+func (cfg *Config) IsDevelopment() bool {
+	return cfg.Env == "development"
+}
+
+// Validate checks every section and reports all problems at once
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	switch cfg.Env {
+	case "development", "staging", "production":
+	default:
+		errs = append(errs, fmt.Errorf("env must be development, staging or production, got %q", cfg.Env))
+	}
+
+	if cfg.Server.Port == "" {
+		errs = append(errs, errors.New("server.port is required"))
+	}
+	if cfg.Server.ReadTimeout <= 0 || cfg.Server.WriteTimeout <= 0 {
+		errs = append(errs, errors.New("server read and write timeouts must be positive"))
+	}
+
+	if err := cfg.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if cfg.JWT.Secret == "" {
+		errs = append(errs, errors.New("jwt.secret (JWT_SECRET) is required"))
+	} else if len(cfg.JWT.Secret) < 32 {
+		errs = append(errs, fmt.Errorf("jwt.secret must be at least 32 characters, got %d", len(cfg.JWT.Secret)))
+	}
+	if cfg.JWT.Expiry <= 0 {
+		errs = append(errs, errors.New("jwt.expiry must be positive"))
+	}
+	if cfg.JWT.RefreshExpiry <= cfg.JWT.Expiry {
+		errs = append(errs, errors.New("jwt.refresh_expiry must be longer than jwt.expiry"))
+	}
+	switch cfg.JWT.Algorithm {
+	case "HS256":
+	case "RS256", "EdDSA":
+		if cfg.JWT.PrivateKeyFile == "" {
+			errs = append(errs, fmt.Errorf("jwt.private_key_file (JWT_PRIVATE_KEY_FILE) is required for %s", cfg.JWT.Algorithm))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("jwt.algorithm must be HS256, RS256 or EdDSA, got %q", cfg.JWT.Algorithm))
+	}
+
+	if cfg.RateLimit.GlobalRPS <= 0 || cfg.RateLimit.GlobalBurst <= 0 {
+		errs = append(errs, errors.New("rate_limit.global_rps and rate_limit.global_burst must be positive"))
+	}
+	if cfg.RateLimit.LoginMaxAttempts <= 0 || cfg.RateLimit.LoginWindow <= 0 {
+		errs = append(errs, errors.New("rate_limit.login_max_attempts and rate_limit.login_window must be positive"))
+	}
+	if cfg.RateLimit.MaxClients <= 0 {
+		errs = append(errs, errors.New("rate_limit.max_clients must be positive"))
+	}
+	if cfg.RateLimit.PasswordResetPerIP <= 0 || cfg.RateLimit.PasswordResetPerUser <= 0 || cfg.RateLimit.PasswordResetWindow <= 0 {
+		errs = append(errs, errors.New("rate_limit.password_reset_per_ip, rate_limit.password_reset_per_user and rate_limit.password_reset_window must be positive"))
+	}
+	if cfg.RateLimit.APIKeyRPM <= 0 {
+		errs = append(errs, errors.New("rate_limit.api_key_rpm must be positive"))
+	}
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" && cfg.Env == "production" {
+			errs = append(errs, errors.New("cors.allowed_origins must not contain * in production"))
+		}
+	}
+
+	if cfg.Cache.ProductsTTL < 0 || cfg.Cache.CatalogTTL < 0 || cfg.Cache.CountTTL < 0 || cfg.Cache.RolesTTL < 0 {
+		errs = append(errs, errors.New("cache TTLs must not be negative"))
+	}
+	if cfg.Cache.MaxEntries <= 0 || cfg.Cache.MaxMB <= 0 {
+		errs = append(errs, errors.New("cache.max_entries and cache.max_mb must be positive"))
+	}
+	if cfg.Cache.CountEstimateAbove < 0 {
+		errs = append(errs, errors.New("cache.count_estimate_above must not be negative"))
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, got %q", cfg.Log.Level))
+	}
+
+	if cfg.Maintenance.RetryAfter <= 0 {
+		errs = append(errs, errors.New("maintenance.retry_after must be positive"))
+	}
+
+	if cfg.Notify.Workers <= 0 || cfg.Notify.QueueSize <= 0 || cfg.Notify.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("notify.workers, notify.queue_size and notify.max_attempts must be positive"))
+	}
+	if cfg.Notify.SMTP.Host != "" && cfg.Notify.SMTP.From == "" {
+		errs = append(errs, errors.New("notify.smtp.from is required when notify.smtp.host is set"))
+	}
+	switch sms := cfg.Notify.SMS; strings.ToLower(sms.Provider) {
+	case "":
+	case "kavenegar":
+		if sms.APIKey == "" {
+			errs = append(errs, errors.New("notify.sms.api_key is required for kavenegar"))
+		}
+	case "twilio":
+		if sms.AccountSID == "" || sms.AuthToken == "" || sms.From == "" {
+			errs = append(errs, errors.New("notify.sms.account_sid, auth_token and from are required for twilio"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("notify.sms.provider must be kavenegar or twilio, got %q", sms.Provider))
+	}
+	for _, webhook := range []struct{ name, url string }{
+		{"slack_webhook_url", cfg.Notify.Chat.SlackWebhookURL},
+		{"teams_webhook_url", cfg.Notify.Chat.TeamsWebhookURL},
+	} {
+		if webhook.url == "" {
+			continue
+		}
+		if u, err := url.Parse(webhook.url); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("notify.chat.%s must be an https URL", webhook.name))
+		}
+	}
+	for _, rule := range cfg.Notify.Chat.AuditRules {
+		entity, action, ok := strings.Cut(rule, ".")
+		if !ok || entity == "" || action == "" {
+			errs = append(errs, fmt.Errorf("notify.chat.audit_rules: %q must look like entity_type.action", rule))
+		}
+	}
+
+	if cfg.Events.PollInterval <= 0 || cfg.Events.BatchSize <= 0 || cfg.Events.Retention <= 0 {
+		errs = append(errs, errors.New("events.poll_interval, events.batch_size and events.retention must be positive"))
+	}
+	for _, endpoint := range cfg.Events.Webhooks.URLs {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("events.webhooks.urls: %q is not an http(s) URL", endpoint))
+		}
+	}
+	if len(cfg.Events.Webhooks.URLs) > 0 && cfg.Events.Webhooks.Secret == "" {
+		errs = append(errs, errors.New("events.webhooks.secret (WEBHOOK_SECRET) is required when webhook URLs are set"))
+	}
+	switch broker := cfg.Events.Broker; strings.ToLower(broker.Type) {
+	case "":
+	case "nats", "kafka":
+		if broker.URL == "" {
+			errs = append(errs, fmt.Errorf("events.broker.url is required for %s", broker.Type))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("events.broker.type must be nats or kafka, got %q", broker.Type))
+	}
+
+	if base, err := url.Parse(cfg.FHIR.SystemBase); err != nil ||
+		(base.Scheme != "urn" && base.Scheme != "http" && base.Scheme != "https") {
+		errs = append(errs, fmt.Errorf("fhir.system_base must be a urn: or http(s) URI, got %q", cfg.FHIR.SystemBase))
+	}
+
+	if cfg.Registry.URL != "" {
+		u, err := url.Parse(cfg.Registry.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("registry.url: %q is not an http(s) URL", cfg.Registry.URL))
+		}
+	}
+	if cfg.Registry.SyncInterval < 0 || cfg.Registry.Timeout <= 0 {
+		errs = append(errs, errors.New("registry.sync_interval must not be negative and registry.timeout must be positive"))
+	}
+
+	switch store := cfg.Storage; strings.ToLower(store.Backend) {
+	case "local":
+		if store.Local.Path == "" {
+			errs = append(errs, errors.New("storage.local.path (STORAGE_LOCAL_PATH) is required for local storage"))
+		}
+	case "s3":
+		if store.S3.Endpoint == "" || store.S3.Bucket == "" || store.S3.AccessKey == "" || store.S3.SecretKey == "" {
+			errs = append(errs, errors.New("storage.s3.endpoint, bucket, access_key and secret_key are required for s3 storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend must be local or s3, got %q", store.Backend))
+	}
+	if cfg.Storage.URLExpiry < time.Second || cfg.Storage.URLExpiry > 7*24*time.Hour {
+		errs = append(errs, errors.New("storage.url_expiry must be between 1s and 168h"))
+	}
+	if cfg.Storage.MaxUploadMB <= 0 {
+		errs = append(errs, errors.New("storage.max_upload_mb must be positive"))
+	}
+
+	if _, err := time.LoadLocation(cfg.Reports.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("reports.timezone: %w", err))
+	}
+	if !validWeekday(cfg.Reports.WeekStart) {
+		errs = append(errs, fmt.Errorf("reports.week_start must be a day of the week, got %q", cfg.Reports.WeekStart))
+	}
+	if cfg.Reports.Calendar != "gregorian" && cfg.Reports.Calendar != "jalali" {
+		errs = append(errs, fmt.Errorf("reports.calendar must be gregorian or jalali, got %q", cfg.Reports.Calendar))
+	}
+	if cfg.Reports.CheckInterval < 0 {
+		errs = append(errs, errors.New("reports.check_interval must not be negative"))
+	}
+	if cfg.PDF.BoldFont != "" && cfg.PDF.Font == "" {
+		errs = append(errs, errors.New("pdf.bold_font requires pdf.font"))
+	}
+	if _, err := time.LoadLocation(cfg.Recurring.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("recurring_orders.timezone: %w", err))
+	}
+
+	names := map[string]bool{}
+	for i, printer := range cfg.Labels.Printers {
+		if printer.Name == "" || names[printer.Name] {
+			errs = append(errs, fmt.Errorf("labels.printers[%d]: name must be set and unique", i))
+		}
+		names[printer.Name] = true
+		if _, _, err := net.SplitHostPort(printer.Address); err != nil {
+			errs = append(errs, fmt.Errorf("labels.printers[%d]: address must be host:port, got %q", i, printer.Address))
+		}
+		if printer.Language != "escpos" && printer.Language != "zpl" {
+			errs = append(errs, fmt.Errorf("labels.printers[%d]: language must be escpos or zpl, got %q", i, printer.Language))
+		}
+		if printer.Width < 0 {
+			errs = append(errs, fmt.Errorf("labels.printers[%d]: width must not be negative", i))
+		}
+	}
+	if cfg.Labels.DefaultPrinter != "" && !names[cfg.Labels.DefaultPrinter] {
+		errs = append(errs, fmt.Errorf("labels.default_printer %q is not a configured printer", cfg.Labels.DefaultPrinter))
+	}
+	if cfg.Labels.Timeout <= 0 {
+		errs = append(errs, errors.New("labels.timeout must be positive"))
+	}
+
+	switch cfg.ERP.Format {
+	case "csv", "json", "xml":
+	default:
+		errs = append(errs, fmt.Errorf("erp.format must be csv, json or xml, got %q", cfg.ERP.Format))
+	}
+	if len(cfg.ERP.Statuses) == 0 {
+		errs = append(errs, errors.New("erp.statuses must list at least one order status"))
+	}
+	if cfg.ERP.BatchSize <= 0 || cfg.ERP.BatchSize > 5000 {
+		errs = append(errs, errors.New("erp.batch_size must be between 1 and 5000"))
+	}
+	if len(cfg.ERP.Fields) == 0 {
+		errs = append(errs, errors.New("erp.fields must map at least one field"))
+	}
+	for i, field := range cfg.ERP.Fields {
+		if field.Name == "" {
+			errs = append(errs, fmt.Errorf("erp.fields[%d]: name is required", i))
+		}
+		if field.Source != "" && field.Value != "" {
+			errs = append(errs, fmt.Errorf("erp.fields[%d]: set source or value, not both", i))
+		}
+	}
+	if cfg.ERP.PushURL != "" {
+		u, err := url.Parse(cfg.ERP.PushURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("erp.push_url: %q is not an http(s) URL", cfg.ERP.PushURL))
+		}
+	}
+	if cfg.ERP.Timeout <= 0 {
+		errs = append(errs, errors.New("erp.timeout must be positive"))
+	}
+	if cfg.ERP.CallbackSecret != "" && cfg.ERP.CallbackTolerance <= 0 {
+		errs = append(errs, errors.New("erp.callback_tolerance must be positive"))
+	}
+	if cfg.APIUsage.FlushInterval < 0 {
+		errs = append(errs, errors.New("api_usage.flush_interval must not be negative"))
+	}
+	if cfg.APIUsage.Retention < 0 {
+		errs = append(errs, errors.New("api_usage.retention must not be negative"))
+	}
+	if cfg.Exports.MaxRows <= 0 {
+		errs = append(errs, errors.New("exports.max_rows must be positive"))
+	}
+	if cfg.Exports.Timeout <= 0 {
+		errs = append(errs, errors.New("exports.timeout must be positive"))
+	}
+	if cfg.Audit.BufferSize < 0 {
+		errs = append(errs, errors.New("audit.buffer_size must not be negative"))
+	}
+	if cfg.Audit.BufferSize > 0 && cfg.Audit.BatchSize <= 0 {
+		errs = append(errs, errors.New("audit.batch_size must be positive"))
+	}
+	if cfg.Audit.BufferSize > 0 && cfg.Audit.FlushInterval <= 0 {
+		errs = append(errs, errors.New("audit.flush_interval must be positive"))
+	}
+	if q := cfg.Tenancy.Quotas; q.RequestsPerMinute < 0 || q.RequestsPerDay < 0 || q.OrdersPerDay < 0 {
+		errs = append(errs, errors.New("tenancy.quotas limits must not be negative"))
+	}
+	if cfg.Tenancy.Quotas.SyncInterval <= 0 {
+		errs = append(errs, errors.New("tenancy.quotas.sync_interval must be positive"))
+	}
+	if a := cfg.Anomaly; a.CheckInterval < 0 || a.MinTravelKm < 0 || a.UnusualHourMinLogins < 0 {
+		errs = append(errs, errors.New("anomaly.check_interval, min_travel_km and unusual_hour_min_logins must not be negative"))
+	}
+	if cfg.Anomaly.MaxTravelSpeedKmh <= 0 || cfg.Anomaly.History <= 0 {
+		errs = append(errs, errors.New("anomaly.max_travel_speed_kmh and anomaly.history must be positive"))
+	}
+	errs = append(errs, cfg.Encryption.validate()...)
+	if cfg.Secrets.Timeout <= 0 || cfg.Secrets.RefreshInterval < 0 {
+		errs = append(errs, errors.New("secrets.timeout must be positive and secrets.refresh_interval not negative"))
+	}
+	if cfg.Backup.PgDump == "" || cfg.Backup.PgRestore == "" {
+		errs = append(errs, errors.New("backup.pg_dump and backup.pg_restore are required"))
+	}
+	if cfg.Backup.Timeout <= 0 || cfg.Backup.Keep < 0 {
+		errs = append(errs, errors.New("backup.timeout must be positive and backup.keep not negative"))
+	}
+	if _, err := time.LoadLocation(cfg.Scheduler.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("scheduler.timezone: %w", err))
+	}
+	if cfg.Scheduler.Timeout <= 0 || cfg.Scheduler.AuditRetention < 0 {
+		errs = append(errs, errors.New("scheduler.timeout must be positive and scheduler.audit_retention not negative"))
+	}
+	for _, job := range cfg.Scheduler.Jobs.specs() {
+		if job.spec == "" {
+			continue
+		}
+		if _, err := cron.Parse(job.spec); err != nil {
+			errs = append(errs, fmt.Errorf("scheduler.jobs.%s: %w", job.name, err))
+		}
+	}
+	if cfg.Frontend.Enabled && cfg.Frontend.CSP == "" {
+		errs = append(errs, errors.New("frontend.csp is required when the frontend is enabled"))
+	}
+	switch cfg.DrugChecks.Provider {
+	case "database", "none":
+	case "http":
+		u, err := url.Parse(cfg.DrugChecks.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("interactions.url: %q is not an http(s) URL", cfg.DrugChecks.URL))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("interactions.provider must be database, http or none, got %q", cfg.DrugChecks.Provider))
+	}
+	if cfg.DrugChecks.Timeout <= 0 {
+		errs = append(errs, errors.New("interactions.timeout must be positive"))
+	}
+
+	if cfg.Pricing.TaxPercent < 0 || cfg.Pricing.TaxPercent > 100 {
+		errs = append(errs, errors.New("pricing.tax_percent must be between 0 and 100"))
+	}
+
+	ruleNames := map[string]bool{}
+	for i, rule := range cfg.Policies {
+		if rule.Name == "" || ruleNames[rule.Name] {
+			errs = append(errs, fmt.Errorf("policies[%d]: name must be set and unique", i))
+		}
+		ruleNames[rule.Name] = true
+		if _, err := policy.NewRule(rule.Name, rule.Action, rule.Roles, rule.Require, rule.Message); err != nil {
+			errs = append(errs, fmt.Errorf("policies[%d]: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the settings needed to open a database connection
+func (d DatabaseConfig) Validate() error {
+	var errs []error
+
+	if d.Host == "" {
+		errs = append(errs, errors.New("database.host (DB_HOST) is required"))
+	}
+	if d.User == "" {
+		errs = append(errs, errors.New("database.user (DB_USER) is required"))
+	}
+	if d.Name == "" {
+		errs = append(errs, errors.New("database.name (DB_NAME) is required"))
+	}
+	if d.MaxOpenConns > 0 && d.MinIdleConns > d.MaxOpenConns {
+		errs = append(errs, errors.New("database.min_idle_conns must not exceed database.max_open_conns"))
+	}
+	if d.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("database.slow_query_threshold must not be negative"))
+	}
+	if d.SlowQueryThreshold > 0 && d.SlowQueryRetention <= 0 {
+		errs = append(errs, errors.New("database.slow_query_retention must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reloadable returns a copy of cfg whose reloadable sections (rate limits,
+// tenant quota limits, CORS origins, log level, maintenance mode, policies,
+// event webhooks and chat alerts) are taken from next. All other settings
+// need a restart and keep their current values.
+func (cfg *Config) Reloadable(next *Config) *Config {
+	merged := *cfg
+	merged.RateLimit = next.RateLimit
+	merged.Tenancy.Quotas = next.Tenancy.Quotas
+	merged.Tenancy.Quotas.SyncInterval = cfg.Tenancy.Quotas.SyncInterval
+	merged.CORS = next.CORS
+	merged.Log = next.Log
+	merged.Maintenance = next.Maintenance
+	merged.Policies = next.Policies
+	merged.Events.Webhooks = next.Events.Webhooks
+	merged.Notify.Chat = next.Notify.Chat
+	return &merged
+}
+
+// RestartRequired lists the top-level sections of next that differ from cfg
+// but cannot be applied without a restart
+func (cfg *Config) RestartRequired(next *Config) []string {
+	var sections []string
+	if cfg.Env != next.Env {
+		sections = append(sections, "env")
+	}
+	if cfg.Server != next.Server {
+		sections = append(sections, "server")
+	}
+	if cfg.databaseSource() != next.databaseSource() {
+		sections = append(sections, "database")
+	}
+	if !reflect.DeepEqual(cfg.JWT, next.JWT) {
+		sections = append(sections, "jwt")
+	}
+	if cfg.Cache != next.Cache {
+		sections = append(sections, "cache")
+	}
+	if cfg.Features != next.Features {
+		sections = append(sections, "features")
+	}
+	// Chat alerts reload; the rest of notify does not
+	notify, nextNotify := cfg.Notify, next.Notify
+	notify.Chat, nextNotify.Chat = ChatConfig{}, ChatConfig{}
+	if !reflect.DeepEqual(notify, nextNotify) {
+		sections = append(sections, "notify")
+	}
+	// Webhooks reload; the rest of events does not
+	events, nextEvents := cfg.Events, next.Events
+	events.Webhooks, nextEvents.Webhooks = WebhooksConfig{}, WebhooksConfig{}
+	if !reflect.DeepEqual(events, nextEvents) {
+		sections = append(sections, "events")
+	}
+	if cfg.FHIR != next.FHIR {
+		sections = append(sections, "fhir")
+	}
+	if cfg.Registry != next.Registry {
+		sections = append(sections, "registry")
+	}
+	if cfg.Storage != next.Storage {
+		sections = append(sections, "storage")
+	}
+	if cfg.Reports != next.Reports {
+		sections = append(sections, "reports")
+	}
+	if cfg.PDF != next.PDF {
+		sections = append(sections, "pdf")
+	}
+	if cfg.Recurring != next.Recurring {
+		sections = append(sections, "recurring_orders")
+	}
+	if !reflect.DeepEqual(cfg.Labels, next.Labels) {
+		sections = append(sections, "labels")
+	}
+	if !reflect.DeepEqual(cfg.ERP, next.ERP) {
+		sections = append(sections, "erp")
+	}
+	if cfg.APIUsage != next.APIUsage {
+		sections = append(sections, "api_usage")
+	}
+	if cfg.Exports != next.Exports {
+		sections = append(sections, "exports")
+	}
+	if cfg.Audit != next.Audit {
+		sections = append(sections, "audit")
+	}
+	// Quota limits reload; the rest of tenancy does not
+	tenancy, nextTenancy := cfg.Tenancy, next.Tenancy
+	tenancy.Quotas = TenantQuotaConfig{SyncInterval: tenancy.Quotas.SyncInterval}
+	nextTenancy.Quotas = TenantQuotaConfig{SyncInterval: nextTenancy.Quotas.SyncInterval}
+	if tenancy != nextTenancy {
+		sections = append(sections, "tenancy")
+	}
+	if cfg.GeoIP != next.GeoIP {
+		sections = append(sections, "geoip")
+	}
+	if cfg.Anomaly != next.Anomaly {
+		sections = append(sections, "anomaly")
+	}
+	if !reflect.DeepEqual(cfg.Encryption, next.Encryption) {
+		sections = append(sections, "field_encryption")
+	}
+	if cfg.Secrets != next.Secrets {
+		sections = append(sections, "secrets")
+	}
+	if cfg.Backup != next.Backup {
+		sections = append(sections, "backup")
+	}
+	if cfg.Frontend != next.Frontend {
+		sections = append(sections, "frontend")
+	}
+	if cfg.Scheduler != next.Scheduler {
+		sections = append(sections, "scheduler")
+	}
+	if cfg.DrugChecks != next.DrugChecks {
+		sections = append(sections, "interactions")
+	}
+	if cfg.Pricing != next.Pricing {
+		sections = append(sections, "pricing")
+	}
+	return sections
+}
+
+// specs lists each job's name with its cron expression
+func (j SchedulerJobsConfig) specs() []struct{ name, spec string } {
+	return []struct{ name, spec string }{
+		{"rate_limit_archive", j.RateLimitArchive},
+		{"login_attempt_cleanup", j.LoginAttemptCleanup},
+		{"refresh_token_cleanup", j.RefreshTokenCleanup},
+		{"password_reset_cleanup", j.PasswordResetCleanup},
+		{"audit_retention", j.AuditRetention},
+		{"stock_check", j.StockCheck},
+		{"recurring_orders", j.RecurringOrders},
+	}
+}
+
+// validate checks the key list without echoing any key
+func (e EncryptionConfig) validate() []error {
+	var errs []error
+	seen := make(map[string]bool, len(e.Keys))
+	wrapped := false
+	for i, key := range e.Keys {
+		id, secret, ok := strings.Cut(strings.TrimSpace(key), ":")
+		if !ok || id == "" || secret == "" {
+			errs = append(errs, fmt.Errorf("field_encryption.keys[%d] must be id:base64 or id:kms:base64", i))
+			continue
+		}
+		if seen[id] {
+			errs = append(errs, fmt.Errorf("field_encryption.keys: key %q is listed twice", id))
+		}
+		seen[id] = true
+		wrapped = wrapped || strings.HasPrefix(secret, "kms:")
+	}
+	if wrapped && (e.KMS.Region == "" || e.KMS.AccessKey == "" || e.KMS.SecretKey == "") {
+		errs = append(errs, errors.New("field_encryption.kms needs a region, access key and secret key to unwrap kms keys"))
+	}
+	if e.RotateInterval < 0 {
+		errs = append(errs, errors.New("field_encryption.rotate_interval must not be negative"))
+	}
+	if len(e.Keys) > 0 && e.RotateBatch <= 0 {
+		errs = append(errs, errors.New("field_encryption.rotate_batch must be positive"))
+	}
+	return errs
+}
+
+// validWeekday reports whether name is an English day of the week, full or
+// abbreviated to at least three letters
+func validWeekday(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if len(name) >= 3 && strings.HasPrefix(full, name) {
+			return true
+		}
+	}
+	return false
+}
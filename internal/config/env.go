@@ -0,0 +1,367 @@
+// internal/config/env.go - Environment variable overrides
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv overrides file and default values with the environment variables
+// documented in .env.example. Malformed values are reported rather than
+// silently replaced by defaults.
+func (cfg *Config) applyEnv() error {
+	e := &envReader{}
+
+	e.string("ENV", &cfg.Env)
+
+	e.string("SERVER_HOST", &cfg.Server.Host)
+	e.string("SERVER_PORT", &cfg.Server.Port)
+	e.duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	e.duration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	e.duration("SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	e.string("DB_HOST", &cfg.Database.Host)
+	e.string("DB_PORT", &cfg.Database.Port)
+	e.string("DB_USER", &cfg.Database.User)
+	e.string("DB_PASSWORD", &cfg.Database.Password)
+	e.string("DB_NAME", &cfg.Database.Name)
+	e.string("DB_SSLMODE", &cfg.Database.SSLMode)
+	e.string("DB_APPLICATION_NAME", &cfg.Database.ApplicationName)
+	e.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	// DB_MAX_IDLE_CONNS is the name from before the pgx pool, which keeps
+	// a minimum of idle connections rather than a maximum
+	if _, ok := e.lookup("DB_MAX_IDLE_CONNS"); ok {
+		log.Print("DB_MAX_IDLE_CONNS is deprecated and will be removed; set DB_MIN_IDLE_CONNS instead")
+		e.int("DB_MAX_IDLE_CONNS", &cfg.Database.MinIdleConns)
+	}
+	e.int("DB_MIN_IDLE_CONNS", &cfg.Database.MinIdleConns)
+	e.duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	e.duration("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+	e.duration("DB_CONNECT_TIMEOUT", &cfg.Database.ConnectTimeout)
+	e.duration("DB_HEALTH_CHECK_PERIOD", &cfg.Database.HealthCheckPeriod)
+	e.bool("DB_AUTO_MIGRATE", &cfg.Database.AutoMigrate)
+	e.duration("DB_SLOW_QUERY_THRESHOLD", &cfg.Database.SlowQueryThreshold)
+	e.duration("DB_SLOW_QUERY_RETENTION", &cfg.Database.SlowQueryRetention)
+
+	e.string("JWT_SECRET", &cfg.JWT.Secret)
+	e.duration("JWT_EXPIRY", &cfg.JWT.Expiry)
+	e.duration("JWT_REFRESH_EXPIRY", &cfg.JWT.RefreshExpiry)
+	e.string("JWT_ALGORITHM", &cfg.JWT.Algorithm)
+	e.string("JWT_PRIVATE_KEY_FILE", &cfg.JWT.PrivateKeyFile)
+	e.list("JWT_PREVIOUS_KEY_FILES", &cfg.JWT.PreviousKeyFiles)
+
+	e.int("RATE_LIMIT_GLOBAL_RPS", &cfg.RateLimit.GlobalRPS)
+	e.int("RATE_LIMIT_GLOBAL_BURST", &cfg.RateLimit.GlobalBurst)
+	e.int("RATE_LIMIT_AUTHENTICATED_RPM", &cfg.RateLimit.AuthenticatedRPM)
+	e.int("RATE_LIMIT_LOGIN_MAX_ATTEMPTS", &cfg.RateLimit.LoginMaxAttempts)
+	e.duration("RATE_LIMIT_LOGIN_WINDOW", &cfg.RateLimit.LoginWindow)
+	e.int("RATE_LIMIT_MAX_CLIENTS", &cfg.RateLimit.MaxClients)
+	e.int("RATE_LIMIT_PASSWORD_RESET_PER_IP", &cfg.RateLimit.PasswordResetPerIP)
+	e.int("RATE_LIMIT_PASSWORD_RESET_PER_USER", &cfg.RateLimit.PasswordResetPerUser)
+	e.duration("RATE_LIMIT_PASSWORD_RESET_WINDOW", &cfg.RateLimit.PasswordResetWindow)
+	e.int("RATE_LIMIT_API_KEY_RPM", &cfg.RateLimit.APIKeyRPM)
+
+	e.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+
+	e.duration("CACHE_PRODUCTS_TTL", &cfg.Cache.ProductsTTL)
+	e.duration("CACHE_CATALOG_TTL", &cfg.Cache.CatalogTTL)
+	e.int("CACHE_MAX_ENTRIES", &cfg.Cache.MaxEntries)
+	e.int("CACHE_MAX_MB", &cfg.Cache.MaxMB)
+	e.duration("CACHE_ROLES_TTL", &cfg.Cache.RolesTTL)
+	e.duration("CACHE_COUNT_TTL", &cfg.Cache.CountTTL)
+	e.int("CACHE_COUNT_ESTIMATE_ABOVE", &cfg.Cache.CountEstimateAbove)
+
+	e.bool("FEATURE_API_V2", &cfg.Features.APIV2)
+	e.bool("FEATURE_RESPONSE_CACHE", &cfg.Features.ResponseCache)
+	e.bool("FEATURE_METRICS", &cfg.Features.Metrics)
+	e.bool("FEATURE_SETUP_ENDPOINTS", &cfg.Features.SetupEndpoints)
+	e.bool("FEATURE_API_DOCS", &cfg.Features.APIDocs)
+
+	e.string("LOG_LEVEL", &cfg.Log.Level)
+
+	e.bool("MAINTENANCE_MODE", &cfg.Maintenance.Enabled)
+	e.string("MAINTENANCE_MESSAGE", &cfg.Maintenance.Message)
+	e.duration("MAINTENANCE_RETRY_AFTER", &cfg.Maintenance.RetryAfter)
+
+	e.int("NOTIFY_WORKERS", &cfg.Notify.Workers)
+	e.int("NOTIFY_QUEUE_SIZE", &cfg.Notify.QueueSize)
+	e.int("NOTIFY_MAX_ATTEMPTS", &cfg.Notify.MaxAttempts)
+	e.string("SMTP_HOST", &cfg.Notify.SMTP.Host)
+	e.string("SMTP_PORT", &cfg.Notify.SMTP.Port)
+	e.string("SMTP_USERNAME", &cfg.Notify.SMTP.Username)
+	e.string("SMTP_PASSWORD", &cfg.Notify.SMTP.Password)
+	e.string("SMTP_FROM", &cfg.Notify.SMTP.From)
+	e.string("SMS_PROVIDER", &cfg.Notify.SMS.Provider)
+	e.string("SMS_API_KEY", &cfg.Notify.SMS.APIKey)
+	e.string("SMS_ACCOUNT_SID", &cfg.Notify.SMS.AccountSID)
+	e.string("SMS_AUTH_TOKEN", &cfg.Notify.SMS.AuthToken)
+	e.string("SMS_FROM", &cfg.Notify.SMS.From)
+	e.string("FCM_CREDENTIALS_FILE", &cfg.Notify.Push.CredentialsFile)
+	e.string("FCM_PROJECT_ID", &cfg.Notify.Push.ProjectID)
+	e.string("SLACK_WEBHOOK_URL", &cfg.Notify.Chat.SlackWebhookURL)
+	e.string("TEAMS_WEBHOOK_URL", &cfg.Notify.Chat.TeamsWebhookURL)
+	e.list("ALERT_AUDIT_RULES", &cfg.Notify.Chat.AuditRules)
+
+	e.duration("EVENTS_POLL_INTERVAL", &cfg.Events.PollInterval)
+	e.int("EVENTS_BATCH_SIZE", &cfg.Events.BatchSize)
+	e.duration("EVENTS_RETENTION", &cfg.Events.Retention)
+	e.list("WEBHOOK_URLS", &cfg.Events.Webhooks.URLs)
+	e.string("WEBHOOK_SECRET", &cfg.Events.Webhooks.Secret)
+	e.duration("WEBHOOK_TIMEOUT", &cfg.Events.Webhooks.Timeout)
+	e.string("EVENTS_BROKER", &cfg.Events.Broker.Type)
+	e.string("EVENTS_BROKER_URL", &cfg.Events.Broker.URL)
+	e.string("EVENTS_BROKER_USERNAME", &cfg.Events.Broker.Username)
+	e.string("EVENTS_BROKER_PASSWORD", &cfg.Events.Broker.Password)
+	e.string("EVENTS_BROKER_TOKEN", &cfg.Events.Broker.Token)
+	e.string("EVENTS_BROKER_PREFIX", &cfg.Events.Broker.Prefix)
+	e.bool("EVENTS_BROKER_JETSTREAM", &cfg.Events.Broker.JetStream)
+	e.duration("EVENTS_BROKER_TIMEOUT", &cfg.Events.Broker.Timeout)
+	e.string("FHIR_SYSTEM_BASE", &cfg.FHIR.SystemBase)
+	e.string("DRUG_REGISTRY_URL", &cfg.Registry.URL)
+	e.duration("DRUG_REGISTRY_SYNC_INTERVAL", &cfg.Registry.SyncInterval)
+	e.duration("DRUG_REGISTRY_TIMEOUT", &cfg.Registry.Timeout)
+	e.string("STORAGE_BACKEND", &cfg.Storage.Backend)
+	e.duration("STORAGE_URL_EXPIRY", &cfg.Storage.URLExpiry)
+	e.int("STORAGE_MAX_UPLOAD_MB", &cfg.Storage.MaxUploadMB)
+	e.string("STORAGE_SIGNING_KEY", &cfg.Storage.SigningKey)
+	e.string("STORAGE_LOCAL_PATH", &cfg.Storage.Local.Path)
+	e.string("STORAGE_S3_ENDPOINT", &cfg.Storage.S3.Endpoint)
+	e.string("STORAGE_S3_PUBLIC_ENDPOINT", &cfg.Storage.S3.PublicEndpoint)
+	e.string("STORAGE_S3_REGION", &cfg.Storage.S3.Region)
+	e.string("STORAGE_S3_BUCKET", &cfg.Storage.S3.Bucket)
+	e.string("STORAGE_S3_ACCESS_KEY", &cfg.Storage.S3.AccessKey)
+	e.string("STORAGE_S3_SECRET_KEY", &cfg.Storage.S3.SecretKey)
+	e.bool("STORAGE_S3_PATH_STYLE", &cfg.Storage.S3.PathStyle)
+	e.string("REPORTS_TIMEZONE", &cfg.Reports.Timezone)
+	e.string("REPORTS_WEEK_START", &cfg.Reports.WeekStart)
+	e.string("REPORTS_CALENDAR", &cfg.Reports.Calendar)
+	e.duration("REPORTS_CHECK_INTERVAL", &cfg.Reports.CheckInterval)
+	e.string("PDF_FONT", &cfg.PDF.Font)
+	e.string("PDF_BOLD_FONT", &cfg.PDF.BoldFont)
+	e.string("RECURRING_ORDERS_TIMEZONE", &cfg.Recurring.Timezone)
+	e.printers("LABEL_PRINTERS", &cfg.Labels.Printers)
+	e.string("LABEL_DEFAULT_PRINTER", &cfg.Labels.DefaultPrinter)
+	e.duration("LABEL_PRINT_TIMEOUT", &cfg.Labels.Timeout)
+	e.string("ERP_FORMAT", &cfg.ERP.Format)
+	e.list("ERP_ORDER_STATUSES", &cfg.ERP.Statuses)
+	e.int("ERP_BATCH_SIZE", &cfg.ERP.BatchSize)
+	e.erpFields("ERP_FIELDS", &cfg.ERP.Fields)
+	e.string("ERP_PUSH_URL", &cfg.ERP.PushURL)
+	e.string("ERP_PUSH_TOKEN", &cfg.ERP.PushToken)
+	e.duration("ERP_TIMEOUT", &cfg.ERP.Timeout)
+	e.string("ERP_CALLBACK_SECRET", &cfg.ERP.CallbackSecret)
+	e.duration("ERP_CALLBACK_TOLERANCE", &cfg.ERP.CallbackTolerance)
+	e.duration("API_USAGE_FLUSH_INTERVAL", &cfg.APIUsage.FlushInterval)
+	e.duration("API_USAGE_RETENTION", &cfg.APIUsage.Retention)
+	e.int("EXPORT_MAX_ROWS", &cfg.Exports.MaxRows)
+	e.duration("EXPORT_TIMEOUT", &cfg.Exports.Timeout)
+	e.int("AUDIT_BUFFER_SIZE", &cfg.Audit.BufferSize)
+	e.int("AUDIT_BATCH_SIZE", &cfg.Audit.BatchSize)
+	e.duration("AUDIT_FLUSH_INTERVAL", &cfg.Audit.FlushInterval)
+	e.bool("TENANCY_ENABLED", &cfg.Tenancy.Enabled)
+	e.bool("TENANCY_SHARED_CATALOG", &cfg.Tenancy.SharedCatalog)
+	e.int("TENANCY_REQUESTS_PER_MINUTE", &cfg.Tenancy.Quotas.RequestsPerMinute)
+	e.int("TENANCY_REQUESTS_PER_DAY", &cfg.Tenancy.Quotas.RequestsPerDay)
+	e.int("TENANCY_ORDERS_PER_DAY", &cfg.Tenancy.Quotas.OrdersPerDay)
+	e.duration("TENANCY_QUOTA_SYNC_INTERVAL", &cfg.Tenancy.Quotas.SyncInterval)
+	e.string("GEOIP_CITY_DB", &cfg.GeoIP.CityDB)
+	e.string("GEOIP_ASN_DB", &cfg.GeoIP.ASNDB)
+	e.duration("ANOMALY_CHECK_INTERVAL", &cfg.Anomaly.CheckInterval)
+	e.int("ANOMALY_MAX_TRAVEL_SPEED_KMH", &cfg.Anomaly.MaxTravelSpeedKmh)
+	e.int("ANOMALY_MIN_TRAVEL_KM", &cfg.Anomaly.MinTravelKm)
+	e.int("ANOMALY_UNUSUAL_HOUR_MIN_LOGINS", &cfg.Anomaly.UnusualHourMinLogins)
+	e.duration("ANOMALY_HISTORY", &cfg.Anomaly.History)
+	e.list("FIELD_ENCRYPTION_KEYS", &cfg.Encryption.Keys)
+	e.duration("FIELD_ENCRYPTION_ROTATE_INTERVAL", &cfg.Encryption.RotateInterval)
+	e.int("FIELD_ENCRYPTION_ROTATE_BATCH", &cfg.Encryption.RotateBatch)
+	e.string("FIELD_ENCRYPTION_KMS_REGION", &cfg.Encryption.KMS.Region)
+	e.string("FIELD_ENCRYPTION_KMS_ENDPOINT", &cfg.Encryption.KMS.Endpoint)
+	e.string("FIELD_ENCRYPTION_KMS_ACCESS_KEY", &cfg.Encryption.KMS.AccessKey)
+	e.string("FIELD_ENCRYPTION_KMS_SECRET_KEY", &cfg.Encryption.KMS.SecretKey)
+	e.string("FIELD_ENCRYPTION_KMS_SESSION_TOKEN", &cfg.Encryption.KMS.SessionToken)
+	e.duration("SECRETS_TIMEOUT", &cfg.Secrets.Timeout)
+	e.duration("SECRETS_REFRESH_INTERVAL", &cfg.Secrets.RefreshInterval)
+	e.string("VAULT_ADDR", &cfg.Secrets.Vault.Address)
+	e.string("VAULT_TOKEN", &cfg.Secrets.Vault.Token)
+	e.string("VAULT_NAMESPACE", &cfg.Secrets.Vault.Namespace)
+	e.string("SECRETS_AWS_REGION", &cfg.Secrets.AWS.Region)
+	e.string("SECRETS_AWS_ENDPOINT", &cfg.Secrets.AWS.Endpoint)
+	e.string("SECRETS_AWS_ACCESS_KEY", &cfg.Secrets.AWS.AccessKey)
+	e.string("SECRETS_AWS_SECRET_KEY", &cfg.Secrets.AWS.SecretKey)
+	e.string("SECRETS_AWS_SESSION_TOKEN", &cfg.Secrets.AWS.SessionToken)
+	e.string("BACKUP_PG_DUMP", &cfg.Backup.PgDump)
+	e.string("BACKUP_PG_RESTORE", &cfg.Backup.PgRestore)
+	e.duration("BACKUP_TIMEOUT", &cfg.Backup.Timeout)
+	e.int("BACKUP_KEEP", &cfg.Backup.Keep)
+	e.bool("FRONTEND_ENABLED", &cfg.Frontend.Enabled)
+	e.string("FRONTEND_DIR", &cfg.Frontend.Dir)
+	e.string("FRONTEND_CSP", &cfg.Frontend.CSP)
+	e.bool("SCHEDULER_ENABLED", &cfg.Scheduler.Enabled)
+	e.string("SCHEDULER_TIMEZONE", &cfg.Scheduler.Timezone)
+	e.duration("SCHEDULER_TIMEOUT", &cfg.Scheduler.Timeout)
+	e.duration("SCHEDULER_AUDIT_RETENTION", &cfg.Scheduler.AuditRetention)
+	e.string("SCHEDULER_JOB_RATE_LIMIT_ARCHIVE", &cfg.Scheduler.Jobs.RateLimitArchive)
+	e.string("SCHEDULER_JOB_LOGIN_ATTEMPT_CLEANUP", &cfg.Scheduler.Jobs.LoginAttemptCleanup)
+	e.string("SCHEDULER_JOB_REFRESH_TOKEN_CLEANUP", &cfg.Scheduler.Jobs.RefreshTokenCleanup)
+	e.string("SCHEDULER_JOB_PASSWORD_RESET_CLEANUP", &cfg.Scheduler.Jobs.PasswordResetCleanup)
+	e.string("SCHEDULER_JOB_AUDIT_RETENTION", &cfg.Scheduler.Jobs.AuditRetention)
+	e.string("SCHEDULER_JOB_STOCK_CHECK", &cfg.Scheduler.Jobs.StockCheck)
+	e.string("SCHEDULER_JOB_RECURRING_ORDERS", &cfg.Scheduler.Jobs.RecurringOrders)
+	e.string("INTERACTIONS_PROVIDER", &cfg.DrugChecks.Provider)
+	e.string("INTERACTIONS_URL", &cfg.DrugChecks.URL)
+	e.string("INTERACTIONS_TOKEN", &cfg.DrugChecks.Token)
+	e.duration("INTERACTIONS_TIMEOUT", &cfg.DrugChecks.Timeout)
+	e.float("PRICING_TAX_PERCENT", &cfg.Pricing.TaxPercent)
+
+	return e.err
+}
+
+// envReader applies set environment variables and keeps the first parse error
+type envReader struct {
+	err error
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+func (e *envReader) fail(key, value string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+	}
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(key, value, err)
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.fail(key, value, err)
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(key, value, err)
+		return
+	}
+	*dst = b
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(key, value, err)
+		return
+	}
+	*dst = d
+}
+
+func (e *envReader) list(key string, dst *[]string) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// printers reads a comma separated list of name=host:port/language entries,
+// optionally followed by /width, e.g. shelf=10.0.0.5:9100/zpl/406
+func (e *envReader) printers(key string, dst *[]PrinterConfig) {
+	var entries []string
+	e.list(key, &entries)
+	if entries == nil {
+		return
+	}
+
+	printers := make([]PrinterConfig, 0, len(entries))
+	for _, entry := range entries {
+		name, rest, ok := strings.Cut(entry, "=")
+		parts := strings.Split(rest, "/")
+		if !ok || len(parts) < 2 || len(parts) > 3 {
+			e.fail(key, entry, errors.New("expected name=host:port/language[/width]"))
+			return
+		}
+		printer := PrinterConfig{Name: strings.TrimSpace(name), Address: parts[0], Language: strings.ToLower(parts[1])}
+		if len(parts) == 3 {
+			width, err := strconv.Atoi(parts[2])
+			if err != nil {
+				e.fail(key, entry, err)
+				return
+			}
+			printer.Width = width
+		}
+		printers = append(printers, printer)
+	}
+	*dst = printers
+}
+
+// erpFields reads a comma separated list of name=source entries, e.g.
+// order_id=order.id,qty=item.quantity
+func (e *envReader) erpFields(key string, dst *[]ERPField) {
+	var entries []string
+	e.list(key, &entries)
+	if entries == nil {
+		return
+	}
+
+	fields := make([]ERPField, 0, len(entries))
+	for _, entry := range entries {
+		name, source, ok := strings.Cut(entry, "=")
+		if !ok {
+			e.fail(key, entry, errors.New("expected name=source"))
+			return
+		}
+		fields = append(fields, ERPField{Name: strings.TrimSpace(name), Source: strings.TrimSpace(source)})
+	}
+	*dst = fields
+}
@@ -0,0 +1,146 @@
+// internal/config/secrets.go - Settings read from secret stores
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/awsapi"
+	"github.com/jamalkaksouri/DigiOrder/internal/secrets"
+)
+
+// resolveSecrets replaces the references in settings tagged secret with
+// the values they name. The credentials of the secret stores are resolved
+// first, from the environment or files only.
+func (cfg *Config) resolveSecrets(ctx context.Context) error {
+	cfg.secretRefs = make(map[string]string)
+
+	local := secrets.NewResolver(map[string]secrets.Provider{
+		"env":  secrets.Env{},
+		"file": secrets.File{},
+	})
+	err := walkSecrets(reflect.ValueOf(&cfg.Secrets).Elem(), "secrets", func(path string, value *string) error {
+		return cfg.resolveSetting(ctx, local, path, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	resolver := cfg.Secrets.resolver()
+	return walkSecrets(reflect.ValueOf(cfg).Elem(), "", func(path string, value *string) error {
+		if strings.HasPrefix(path, "secrets.") {
+			return nil
+		}
+		return cfg.resolveSetting(ctx, resolver, path, value)
+	})
+}
+
+func (cfg *Config) resolveSetting(ctx context.Context, r *secrets.Resolver, path string, value *string) error {
+	if !r.IsReference(*value) {
+		return nil
+	}
+	resolved, err := r.Resolve(ctx, *value)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	cfg.secretRefs[path] = *value
+	*value = resolved
+	return nil
+}
+
+// resolver reads references of every scheme
+func (s SecretsConfig) resolver() *secrets.Resolver {
+	return secrets.NewResolver(map[string]secrets.Provider{
+		"env":  secrets.Env{},
+		"file": secrets.File{},
+		"vault": secrets.Vault{
+			Address:   s.Vault.Address,
+			Token:     s.Vault.Token,
+			Namespace: s.Vault.Namespace,
+			Timeout:   s.Timeout,
+		},
+		"awssm": secrets.AWSSecretsManager{Client: awsapi.Client{
+			Region:       s.AWS.Region,
+			Endpoint:     s.AWS.Endpoint,
+			AccessKey:    s.AWS.AccessKey,
+			SecretKey:    s.AWS.SecretKey,
+			SessionToken: s.AWS.SessionToken,
+			Timeout:      s.Timeout,
+		}},
+	})
+}
+
+// walkSecrets calls fn with the YAML path and address of every string, or
+// element of a string list, tagged secret:"true" under v
+func walkSecrets(v reflect.Value, prefix string, fn func(path string, value *string) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := walkSecrets(fv, path, fn); err != nil {
+				return err
+			}
+		case field.Tag.Get("secret") != "true":
+		case fv.Kind() == reflect.String:
+			if err := fn(path, fv.Addr().Interface().(*string)); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < fv.Len(); j++ {
+				if err := fn(fmt.Sprintf("%s[%d]", path, j), fv.Index(j).Addr().Interface().(*string)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DatabaseCredentials reads the database user and password again from
+// the secret stores they were read from at startup; settings that were not
+// references keep their values
+func (cfg *Config) DatabaseCredentials(ctx context.Context) (user, password string, err error) {
+	r := cfg.Secrets.resolver()
+	user, password = cfg.Database.User, cfg.Database.Password
+	if ref := cfg.secretRefs["database.user"]; ref != "" {
+		if user, err = r.Resolve(ctx, ref); err != nil {
+			return "", "", fmt.Errorf("failed to read database.user: %w", err)
+		}
+	}
+	if ref := cfg.secretRefs["database.password"]; ref != "" {
+		if password, err = r.Resolve(ctx, ref); err != nil {
+			return "", "", fmt.Errorf("failed to read database.password: %w", err)
+		}
+	}
+	return user, password, nil
+}
+
+// databaseSource is the database section with the credentials read from
+// secret stores replaced by their references, so rotated credentials do
+// not count as a change
+func (cfg *Config) databaseSource() DatabaseConfig {
+	d := cfg.Database
+	if ref := cfg.secretRefs["database.user"]; ref != "" {
+		d.User = ref
+	}
+	if ref := cfg.secretRefs["database.password"]; ref != "" {
+		d.Password = ref
+	}
+	return d
+}
@@ -28,7 +28,7 @@ type CreateBarcodeParams struct {
 }
 
 func (q *Queries) CreateBarcode(ctx context.Context, arg CreateBarcodeParams) (ProductBarcode, error) {
-	row := q.db.QueryRowContext(ctx, createBarcode, arg.ProductID, arg.Barcode, arg.BarcodeType)
+	row := q.db.QueryRow(ctx, createBarcode, arg.ProductID, arg.Barcode, arg.BarcodeType)
 	var i ProductBarcode
 	err := row.Scan(
 		&i.ID,
@@ -45,7 +45,7 @@ DELETE FROM product_barcodes WHERE id = $1
 `
 
 func (q *Queries) DeleteBarcode(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteBarcode, id)
+	_, err := q.db.Exec(ctx, deleteBarcode, id)
 	return err
 }
 
@@ -55,7 +55,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetBarcode(ctx context.Context, id uuid.UUID) (ProductBarcode, error) {
-	row := q.db.QueryRowContext(ctx, getBarcode, id)
+	row := q.db.QueryRow(ctx, getBarcode, id)
 	var i ProductBarcode
 	err := row.Scan(
 		&i.ID,
@@ -74,7 +74,7 @@ ORDER BY created_at DESC
 `
 
 func (q *Queries) GetBarcodesByProduct(ctx context.Context, productID uuid.NullUUID) ([]ProductBarcode, error) {
-	rows, err := q.db.QueryContext(ctx, getBarcodesByProduct, productID)
+	rows, err := q.db.Query(ctx, getBarcodesByProduct, productID)
 	if err != nil {
 		return nil, err
 	}
@@ -93,9 +93,6 @@ func (q *Queries) GetBarcodesByProduct(ctx context.Context, productID uuid.NullU
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -103,14 +100,14 @@ func (q *Queries) GetBarcodesByProduct(ctx context.Context, productID uuid.NullU
 }
 
 const getProductByBarcode = `-- name: GetProductByBarcode :one
-SELECT p.id, p.name, p.brand, p.dosage_form_id, p.strength, p.unit, p.category_id, p.description, p.created_at, p.deleted_at FROM products p
+SELECT p.id, p.name, p.brand, p.dosage_form_id, p.strength, p.unit, p.category_id, p.description, p.created_at, p.deleted_at, p.status, p.irc, p.generic_code FROM products p
 INNER JOIN product_barcodes pb ON p.id = pb.product_id
 WHERE pb.barcode = $1
 LIMIT 1
 `
 
 func (q *Queries) GetProductByBarcode(ctx context.Context, barcode string) (Product, error) {
-	row := q.db.QueryRowContext(ctx, getProductByBarcode, barcode)
+	row := q.db.QueryRow(ctx, getProductByBarcode, barcode)
 	var i Product
 	err := row.Scan(
 		&i.ID,
@@ -123,10 +120,47 @@ func (q *Queries) GetProductByBarcode(ctx context.Context, barcode string) (Prod
 		&i.Description,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Status,
+		&i.Irc,
+		&i.GenericCode,
 	)
 	return i, err
 }
 
+const listBarcodesByProducts = `-- name: ListBarcodesByProducts :many
+-- Barcodes of many products in one query, for ?include=barcodes
+SELECT id, product_id, barcode, barcode_type, created_at FROM product_barcodes
+WHERE product_id = ANY($1::uuid[])
+ORDER BY product_id, created_at DESC
+`
+
+// Barcodes of many products in one query, for ?include=barcodes
+func (q *Queries) ListBarcodesByProducts(ctx context.Context, productIds []uuid.UUID) ([]ProductBarcode, error) {
+	rows, err := q.db.Query(ctx, listBarcodesByProducts, productIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProductBarcode
+	for rows.Next() {
+		var i ProductBarcode
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProductID,
+			&i.Barcode,
+			&i.BarcodeType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchBarcodes = `-- name: SearchBarcodes :many
 SELECT id, product_id, barcode, barcode_type, created_at FROM product_barcodes
 WHERE barcode ILIKE '%' || $1 || '%'
@@ -141,7 +175,7 @@ type SearchBarcodesParams struct {
 }
 
 func (q *Queries) SearchBarcodes(ctx context.Context, arg SearchBarcodesParams) ([]ProductBarcode, error) {
-	rows, err := q.db.QueryContext(ctx, searchBarcodes, arg.Column1, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, searchBarcodes, arg.Column1, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -160,9 +194,6 @@ func (q *Queries) SearchBarcodes(ctx context.Context, arg SearchBarcodesParams)
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -185,7 +216,7 @@ type UpdateBarcodeParams struct {
 }
 
 func (q *Queries) UpdateBarcode(ctx context.Context, arg UpdateBarcodeParams) (ProductBarcode, error) {
-	row := q.db.QueryRowContext(ctx, updateBarcode, arg.ID, arg.Barcode, arg.BarcodeType)
+	row := q.db.QueryRow(ctx, updateBarcode, arg.ID, arg.Barcode, arg.BarcodeType)
 	var i ProductBarcode
 	err := row.Scan(
 		&i.ID,
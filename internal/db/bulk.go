@@ -0,0 +1,78 @@
+// internal/db/bulk.go - Batched multi-row inserts
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// BulkBatchSize is how many rows a bulk insert writes per statement
+const BulkBatchSize = 500
+
+// BatchError is the failure of one batch of a bulk insert. First and Last
+// are the positions of its first and last rows in the input, from 0.
+type BatchError struct {
+	Batch int
+	First int
+	Last  int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch %d (rows %d-%d): %v", e.Batch+1, e.First+1, e.Last+1, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// NewOrderItem is an item added by BulkCreateOrderItems
+type NewOrderItem struct {
+	ProductID    uuid.UUID
+	RequestedQty int32
+	Unit         string // stored as NULL when empty
+	Note         string // stored as NULL when empty
+	UnitPrice    string // stored as NULL when empty
+}
+
+// BulkCreateOrderItems adds items to an order with one CreateOrderItems
+// statement per batch of size rows, BulkBatchSize when size is 0, and
+// returns them in input order. It stops at the first batch that fails and
+// returns a *BatchError; q should belong to a transaction, so the batches
+// written before it are rolled back with it.
+func BulkCreateOrderItems(ctx context.Context, q Querier, orderID uuid.UUID, items []NewOrderItem, size int) ([]OrderItem, error) {
+	if size <= 0 {
+		size = BulkBatchSize
+	}
+	created := make([]OrderItem, 0, len(items))
+	for first := 0; first < len(items); first += size {
+		batch := items[first:min(first+size, len(items))]
+		arg := CreateOrderItemsParams{
+			OrderID:       orderID,
+			ProductIds:    make([]uuid.UUID, len(batch)),
+			RequestedQtys: make([]int32, len(batch)),
+			Units:         make([]string, len(batch)),
+			Notes:         make([]string, len(batch)),
+			UnitPrices:    make([]string, len(batch)),
+		}
+		for i, item := range batch {
+			arg.ProductIds[i] = item.ProductID
+			arg.RequestedQtys[i] = item.RequestedQty
+			arg.Units[i] = item.Unit
+			arg.Notes[i] = item.Note
+			arg.UnitPrices[i] = item.UnitPrice
+		}
+
+		rows, err := q.CreateOrderItems(ctx, arg)
+		if err == nil && len(rows) != len(batch) {
+			err = fmt.Errorf("inserted %d of %d rows", len(rows), len(batch))
+		}
+		if err != nil {
+			return created, &BatchError{Batch: first / size, First: first, Last: first + len(batch) - 1, Err: err}
+		}
+		created = append(created, rows...)
+	}
+	return created, nil
+}
@@ -16,7 +16,7 @@ RETURNING id, name
 `
 
 func (q *Queries) CreateCategory(ctx context.Context, name string) (Category, error) {
-	row := q.db.QueryRowContext(ctx, createCategory, name)
+	row := q.db.QueryRow(ctx, createCategory, name)
 	var i Category
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -29,7 +29,7 @@ RETURNING id, name
 `
 
 func (q *Queries) CreateDosageForm(ctx context.Context, name string) (DosageForm, error) {
-	row := q.db.QueryRowContext(ctx, createDosageForm, name)
+	row := q.db.QueryRow(ctx, createDosageForm, name)
 	var i DosageForm
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -42,7 +42,7 @@ RETURNING id, name
 `
 
 func (q *Queries) CreateRole(ctx context.Context, name string) (Role, error) {
-	row := q.db.QueryRowContext(ctx, createRole, name)
+	row := q.db.QueryRow(ctx, createRole, name)
 	var i Role
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -53,7 +53,7 @@ DELETE FROM roles WHERE id = $1
 `
 
 func (q *Queries) DeleteRole(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteRole, id)
+	_, err := q.db.Exec(ctx, deleteRole, id)
 	return err
 }
 
@@ -63,7 +63,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetCategory(ctx context.Context, id int32) (Category, error) {
-	row := q.db.QueryRowContext(ctx, getCategory, id)
+	row := q.db.QueryRow(ctx, getCategory, id)
 	var i Category
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -75,7 +75,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetDosageForm(ctx context.Context, id int32) (DosageForm, error) {
-	row := q.db.QueryRowContext(ctx, getDosageForm, id)
+	row := q.db.QueryRow(ctx, getDosageForm, id)
 	var i DosageForm
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -87,7 +87,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetRole(ctx context.Context, id int32) (Role, error) {
-	row := q.db.QueryRowContext(ctx, getRole, id)
+	row := q.db.QueryRow(ctx, getRole, id)
 	var i Role
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -99,7 +99,7 @@ ORDER BY name
 `
 
 func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
-	rows, err := q.db.QueryContext(ctx, listCategories)
+	rows, err := q.db.Query(ctx, listCategories)
 	if err != nil {
 		return nil, err
 	}
@@ -112,9 +112,6 @@ func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -127,7 +124,7 @@ ORDER BY name
 `
 
 func (q *Queries) ListDosageForms(ctx context.Context) ([]DosageForm, error) {
-	rows, err := q.db.QueryContext(ctx, listDosageForms)
+	rows, err := q.db.Query(ctx, listDosageForms)
 	if err != nil {
 		return nil, err
 	}
@@ -140,9 +137,6 @@ func (q *Queries) ListDosageForms(ctx context.Context) ([]DosageForm, error) {
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -155,7 +149,7 @@ ORDER BY name
 `
 
 func (q *Queries) ListRoles(ctx context.Context) ([]Role, error) {
-	rows, err := q.db.QueryContext(ctx, listRoles)
+	rows, err := q.db.Query(ctx, listRoles)
 	if err != nil {
 		return nil, err
 	}
@@ -168,9 +162,6 @@ func (q *Queries) ListRoles(ctx context.Context) ([]Role, error) {
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -190,7 +181,7 @@ type UpdateRoleParams struct {
 }
 
 func (q *Queries) UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, error) {
-	row := q.db.QueryRowContext(ctx, updateRole, arg.ID, arg.Name)
+	row := q.db.QueryRow(ctx, updateRole, arg.ID, arg.Name)
 	var i Role
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
@@ -1,51 +1,132 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"os"
+	"log"
+	"strings"
+	"sync"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
 )
 
-// Connect establishes a connection to the PostgreSQL database
-func Connect() (*sql.DB, error) {
-	// Get database connection parameters from environment variables
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "digiorder")
-	sslmode := getEnv("DB_SSLMODE", "disable")
+// CredentialsFunc reads the database user and password
+type CredentialsFunc func(ctx context.Context) (user, password string, err error)
 
-	// Build connection string
-	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
+// Connect opens a connection pool to the PostgreSQL database
+func Connect(cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
+	return ConnectRefreshing(cfg, nil, 0)
+}
 
-	// Open database connection
-	db, err := sql.Open("postgres", psqlInfo)
+// ConnectRefreshing is Connect for rotating credentials: a new connection
+// reads them with fetch once refresh has passed since they were last read,
+// keeping the previous ones if that fails. A zero refresh never reads them.
+func ConnectRefreshing(cfg config.DatabaseConfig, fetch CredentialsFunc, refresh time.Duration) (*pgxpool.Pool, error) {
+	poolConfig, err := newPoolConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
+		return nil, err
+	}
+	creds := &credentials{
+		fetch:     fetch,
+		refresh:   refresh,
+		user:      cfg.User,
+		password:  cfg.Password,
+		fetchedAt: time.Now(),
+	}
+	poolConfig.BeforeConnect = func(ctx context.Context, conn *pgx.ConnConfig) error {
+		conn.User, conn.Password = creds.current(ctx)
+		return nil
 	}
 
 	// Test the connection
-	err = db.Ping()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		db.Close()
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Set connection pool settings (optional)
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
+	return pool, nil
+}
+
+// newPoolConfig builds the pool settings from cfg. Durations that are
+// zero keep pgxpool's defaults.
+func newPoolConfig(cfg config.DatabaseConfig) (*pgxpool.Config, error) {
+	// Build connection string
+	// application_name makes API sessions easy to spot in pg_stat_activity
+	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s application_name=%s",
+		dsnValue(cfg.Host), dsnValue(cfg.Port), dsnValue(cfg.User), dsnValue(cfg.Password),
+		dsnValue(cfg.Name), dsnValue(cfg.SSLMode), dsnValue(cfg.ApplicationName))
+
+	poolConfig, err := pgxpool.ParseConfig(psqlInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database settings: %w", err)
+	}
+
+	// Connection pool settings
+	if cfg.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	poolConfig.MinIdleConns = int32(cfg.MinIdleConns)
+	if cfg.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+
+	// Statements carry the tag of their request (see QueryTag), so hardly
+	// two are alike and caching them would only fill the cache. Each one
+	// is described before it runs instead, as lib/pq did, so parameters
+	// are sent as the types the server expects.
+	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
 
-	return db, nil
+	return poolConfig, nil
 }
 
-// getEnv gets an environment variable with a fallback value
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// credentials are the user and password new connections open with
+type credentials struct {
+	fetch   CredentialsFunc
+	refresh time.Duration
+
+	mu        sync.Mutex
+	user      string
+	password  string
+	fetchedAt time.Time
+}
+
+func (c *credentials) current(ctx context.Context) (string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fetch != nil && c.refresh > 0 && time.Since(c.fetchedAt) >= c.refresh {
+		// Failures are retried after another refresh interval rather than
+		// on every new connection
+		c.fetchedAt = time.Now()
+		user, password, err := c.fetch(ctx)
+		if err != nil {
+			log.Printf("Failed to refresh database credentials, keeping the current ones: %v", err)
+		} else {
+			c.user, c.password = user, password
+		}
 	}
-	return fallback
+	return c.user, c.password
+}
+
+// dsnValue quotes a connection string value, which may hold spaces or
+// quotes when it comes from a secret store
+func dsnValue(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
 }
@@ -0,0 +1,119 @@
+// internal/db/encrypted.go - Columns encrypted at rest
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/fieldcrypt"
+)
+
+// errNoFieldKeys is returned when an encrypted value is read while no
+// keyring is in use
+var errNoFieldKeys = errors.New("column is encrypted but no field encryption keys are configured")
+
+// EncryptedString is a nullable text column encrypted with the keyring in
+// use (see fieldcrypt.Use). It is written encrypted under the active key,
+// or as plain text without a keyring, and reads both back as plain text,
+// so columns can be encrypted gradually.
+type EncryptedString struct {
+	String string
+	Valid  bool
+}
+
+// Scan implements sql.Scanner
+func (s *EncryptedString) Scan(value any) error {
+	var ns sql.NullString
+	if err := ns.Scan(value); err != nil {
+		return err
+	}
+	if !ns.Valid {
+		*s = EncryptedString{}
+		return nil
+	}
+	plaintext, err := decryptField(ns.String)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString{String: string(plaintext), Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (s EncryptedString) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return EncryptString(s.String)
+}
+
+// EncryptString encrypts a value bound without an EncryptedString, such as
+// an element of an array parameter
+func EncryptString(plaintext string) (string, error) {
+	keys := fieldcrypt.Current()
+	if keys == nil {
+		return plaintext, nil
+	}
+	return keys.Encrypt([]byte(plaintext))
+}
+
+// EncryptedJSON is a nullable jsonb column encrypted like EncryptedString.
+// Encrypted, the column holds the ciphertext as a JSON string.
+type EncryptedJSON struct {
+	RawMessage json.RawMessage
+	Valid      bool
+}
+
+// Scan implements sql.Scanner
+func (j *EncryptedJSON) Scan(value any) error {
+	var ns sql.NullString
+	if err := ns.Scan(value); err != nil {
+		return err
+	}
+	if !ns.Valid {
+		*j = EncryptedJSON{}
+		return nil
+	}
+	raw := json.RawMessage(ns.String)
+	var sealed string
+	if len(raw) > 0 && raw[0] == '"' &&
+		json.Unmarshal(raw, &sealed) == nil && fieldcrypt.IsEncrypted(sealed) {
+		plaintext, err := decryptField(sealed)
+		if err != nil {
+			return err
+		}
+		raw = plaintext
+	}
+	*j = EncryptedJSON{RawMessage: raw, Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (j EncryptedJSON) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+	keys := fieldcrypt.Current()
+	if keys == nil {
+		return []byte(j.RawMessage), nil
+	}
+	sealed, err := keys.Encrypt(j.RawMessage)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// decryptField returns a column value as plain text
+func decryptField(value string) ([]byte, error) {
+	if !fieldcrypt.IsEncrypted(value) {
+		return []byte(value), nil
+	}
+	keys := fieldcrypt.Current()
+	if keys == nil {
+		return nil, errNoFieldKeys
+	}
+	return keys.Decrypt(value)
+}
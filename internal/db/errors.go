@@ -0,0 +1,35 @@
+// internal/db/errors.go - Driver-independent PostgreSQL error inspection
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PgError is the driver-independent view of a PostgreSQL server error.
+// Handlers map on SQLSTATE codes through this type so the error mapping
+// does not depend on which driver (pgx today) produced the error.
+type PgError struct {
+	Code       string
+	Message    string
+	Detail     string
+	Column     string
+	Constraint string
+}
+
+// AsPgError extracts PostgreSQL error details from err, if any
+func AsPgError(err error) (*PgError, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return &PgError{
+			Code:       pgErr.Code,
+			Message:    pgErr.Message,
+			Detail:     pgErr.Detail,
+			Column:     pgErr.ColumnName,
+			Constraint: pgErr.ConstraintName,
+		}, true
+	}
+
+	return nil, false
+}
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sqlc-dev/pqtype"
 )
 
 const archiveOldRateLimits = `-- name: ArchiveOldRateLimits :exec
@@ -19,7 +18,7 @@ SELECT archive_old_rate_limits()
 `
 
 func (q *Queries) ArchiveOldRateLimits(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, archiveOldRateLimits)
+	_, err := q.db.Exec(ctx, archiveOldRateLimits)
 	return err
 }
 
@@ -28,7 +27,7 @@ SELECT cleanup_old_login_attempts()
 `
 
 func (q *Queries) CleanupOldLoginAttempts(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, cleanupOldLoginAttempts)
+	_, err := q.db.Exec(ctx, cleanupOldLoginAttempts)
 	return err
 }
 
@@ -45,7 +44,7 @@ type CountFailedAttemptsParams struct {
 }
 
 func (q *Queries) CountFailedAttempts(ctx context.Context, arg CountFailedAttemptsParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countFailedAttempts, arg.IpAddress, arg.Since)
+	row := q.db.QueryRow(ctx, countFailedAttempts, arg.IpAddress, arg.Since)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -58,7 +57,7 @@ WHERE window_start < $1
 `
 
 func (q *Queries) DeleteOldRateLimitsExcludingHealthMetrics(ctx context.Context, cutoff time.Time) error {
-	_, err := q.db.ExecContext(ctx, deleteOldRateLimitsExcludingHealthMetrics, cutoff)
+	_, err := q.db.Exec(ctx, deleteOldRateLimitsExcludingHealthMetrics, cutoff)
 	return err
 }
 
@@ -67,7 +66,7 @@ SELECT client_id, endpoint, total_attempts, last_attempt, block_windows FROM cur
 `
 
 func (q *Queries) GetCurrentlyBlockedIPs(ctx context.Context) ([]CurrentlyBlockedIp, error) {
-	rows, err := q.db.QueryContext(ctx, getCurrentlyBlockedIPs)
+	rows, err := q.db.Query(ctx, getCurrentlyBlockedIPs)
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +85,6 @@ func (q *Queries) GetCurrentlyBlockedIPs(ctx context.Context) ([]CurrentlyBlocke
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -100,7 +96,7 @@ SELECT hour, total_attempts, successful, failed, rate_limited_attempts, unique_i
 `
 
 func (q *Queries) GetLoginAttemptStats(ctx context.Context) ([]LoginAttemptStat, error) {
-	rows, err := q.db.QueryContext(ctx, getLoginAttemptStats)
+	rows, err := q.db.Query(ctx, getLoginAttemptStats)
 	if err != nil {
 		return nil, err
 	}
@@ -121,9 +117,6 @@ func (q *Queries) GetLoginAttemptStats(ctx context.Context) ([]LoginAttemptStat,
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -131,7 +124,7 @@ func (q *Queries) GetLoginAttemptStats(ctx context.Context) ([]LoginAttemptStat,
 }
 
 const getLoginAttemptsByUsername = `-- name: GetLoginAttemptsByUsername :many
-SELECT id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at FROM login_attempts_log
+SELECT id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at, asn, as_org, latitude, longitude, analyzed_at, device_fingerprint FROM login_attempts_log
 WHERE username = $1
   AND attempt_time >= $2
 ORDER BY attempt_time DESC
@@ -145,7 +138,7 @@ type GetLoginAttemptsByUsernameParams struct {
 }
 
 func (q *Queries) GetLoginAttemptsByUsername(ctx context.Context, arg GetLoginAttemptsByUsernameParams) ([]LoginAttemptsLog, error) {
-	rows, err := q.db.QueryContext(ctx, getLoginAttemptsByUsername, arg.Username, arg.Since, arg.Limit)
+	rows, err := q.db.Query(ctx, getLoginAttemptsByUsername, arg.Username, arg.Since, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -169,20 +162,50 @@ func (q *Queries) GetLoginAttemptsByUsername(ctx context.Context, arg GetLoginAt
 			&i.City,
 			&i.DeviceInfo,
 			&i.CreatedAt,
+			&i.Asn,
+			&i.AsOrg,
+			&i.Latitude,
+			&i.Longitude,
+			&i.AnalyzedAt,
+			&i.DeviceFingerprint,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+const getLoginCountryCounts = `-- name: GetLoginCountryCounts :one
+SELECT
+    COUNT(*) FILTER (WHERE country IS NOT NULL) AS located_logins,
+    COUNT(*) FILTER (WHERE country = $1) AS country_logins
+FROM login_attempts_log
+WHERE username = $2
+  AND success = true
+`
+
+type GetLoginCountryCountsParams struct {
+	Country  sql.NullString
+	Username string
+}
+
+type GetLoginCountryCountsRow struct {
+	LocatedLogins int64
+	CountryLogins int64
+}
+
+// Successful logins of a user with a known country, and those from country
+func (q *Queries) GetLoginCountryCounts(ctx context.Context, arg GetLoginCountryCountsParams) (GetLoginCountryCountsRow, error) {
+	row := q.db.QueryRow(ctx, getLoginCountryCounts, arg.Country, arg.Username)
+	var i GetLoginCountryCountsRow
+	err := row.Scan(&i.LocatedLogins, &i.CountryLogins)
+	return i, err
+}
+
 const getLoginSecurityReport = `-- name: GetLoginSecurityReport :many
 SELECT 
     ip_address,
@@ -213,7 +236,7 @@ type GetLoginSecurityReportRow struct {
 }
 
 func (q *Queries) GetLoginSecurityReport(ctx context.Context, limit int32) ([]GetLoginSecurityReportRow, error) {
-	rows, err := q.db.QueryContext(ctx, getLoginSecurityReport, limit)
+	rows, err := q.db.Query(ctx, getLoginSecurityReport, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -235,9 +258,6 @@ func (q *Queries) GetLoginSecurityReport(ctx context.Context, limit int32) ([]Ge
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -257,7 +277,7 @@ type GetRateLimitReleasesParams struct {
 }
 
 func (q *Queries) GetRateLimitReleases(ctx context.Context, arg GetRateLimitReleasesParams) ([]RateLimitRelease, error) {
-	rows, err := q.db.QueryContext(ctx, getRateLimitReleases, arg.IpAddress, arg.Limit)
+	rows, err := q.db.Query(ctx, getRateLimitReleases, arg.IpAddress, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -283,9 +303,6 @@ func (q *Queries) GetRateLimitReleases(ctx context.Context, arg GetRateLimitRele
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -307,7 +324,7 @@ type GetRateLimitWithExclusionParams struct {
 }
 
 func (q *Queries) GetRateLimitWithExclusion(ctx context.Context, arg GetRateLimitWithExclusionParams) ([]ApiRateLimit, error) {
-	rows, err := q.db.QueryContext(ctx, getRateLimitWithExclusion, arg.ClientID, arg.Endpoint, arg.WindowStart)
+	rows, err := q.db.Query(ctx, getRateLimitWithExclusion, arg.ClientID, arg.Endpoint, arg.WindowStart)
 	if err != nil {
 		return nil, err
 	}
@@ -328,9 +345,6 @@ func (q *Queries) GetRateLimitWithExclusion(ctx context.Context, arg GetRateLimi
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -338,7 +352,7 @@ func (q *Queries) GetRateLimitWithExclusion(ctx context.Context, arg GetRateLimi
 }
 
 const getRateLimitedAttempts = `-- name: GetRateLimitedAttempts :many
-SELECT id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at FROM login_attempts_log
+SELECT id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at, asn, as_org, latitude, longitude, analyzed_at, device_fingerprint FROM login_attempts_log
 WHERE rate_limited = true
   AND attempt_time >= NOW() - INTERVAL '24 hours'
 ORDER BY attempt_time DESC
@@ -351,7 +365,7 @@ type GetRateLimitedAttemptsParams struct {
 }
 
 func (q *Queries) GetRateLimitedAttempts(ctx context.Context, arg GetRateLimitedAttemptsParams) ([]LoginAttemptsLog, error) {
-	rows, err := q.db.QueryContext(ctx, getRateLimitedAttempts, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getRateLimitedAttempts, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -375,14 +389,17 @@ func (q *Queries) GetRateLimitedAttempts(ctx context.Context, arg GetRateLimited
 			&i.City,
 			&i.DeviceInfo,
 			&i.CreatedAt,
+			&i.Asn,
+			&i.AsOrg,
+			&i.Latitude,
+			&i.Longitude,
+			&i.AnalyzedAt,
+			&i.DeviceFingerprint,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -390,7 +407,7 @@ func (q *Queries) GetRateLimitedAttempts(ctx context.Context, arg GetRateLimited
 }
 
 const getRecentLoginAttempts = `-- name: GetRecentLoginAttempts :many
-SELECT id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at FROM login_attempts_log
+SELECT id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at, asn, as_org, latitude, longitude, analyzed_at, device_fingerprint FROM login_attempts_log
 WHERE ip_address = $1
   AND attempt_time >= $2
 ORDER BY attempt_time DESC
@@ -404,7 +421,7 @@ type GetRecentLoginAttemptsParams struct {
 }
 
 func (q *Queries) GetRecentLoginAttempts(ctx context.Context, arg GetRecentLoginAttemptsParams) ([]LoginAttemptsLog, error) {
-	rows, err := q.db.QueryContext(ctx, getRecentLoginAttempts, arg.IpAddress, arg.Since, arg.Limit)
+	rows, err := q.db.Query(ctx, getRecentLoginAttempts, arg.IpAddress, arg.Since, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -428,14 +445,17 @@ func (q *Queries) GetRecentLoginAttempts(ctx context.Context, arg GetRecentLogin
 			&i.City,
 			&i.DeviceInfo,
 			&i.CreatedAt,
+			&i.Asn,
+			&i.AsOrg,
+			&i.Latitude,
+			&i.Longitude,
+			&i.AnalyzedAt,
+			&i.DeviceFingerprint,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -472,7 +492,7 @@ type GetUserLoginHistoryRow struct {
 }
 
 func (q *Queries) GetUserLoginHistory(ctx context.Context, arg GetUserLoginHistoryParams) ([]GetUserLoginHistoryRow, error) {
-	rows, err := q.db.QueryContext(ctx, getUserLoginHistory, arg.Username, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getUserLoginHistory, arg.Username, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -492,9 +512,6 @@ func (q *Queries) GetUserLoginHistory(ctx context.Context, arg GetUserLoginHisto
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -505,7 +522,8 @@ const logLoginAttempt = `-- name: LogLoginAttempt :one
 
 INSERT INTO login_attempts_log (
     username, ip_address, user_agent, success, failure_reason, 
-    rate_limited, session_id, device_info
+    rate_limited, session_id, device_info, country, city, asn, as_org,
+    latitude, longitude, device_fingerprint
 )
 VALUES (
     $1, 
@@ -515,25 +533,39 @@ VALUES (
     $5,
     $6, 
     $7, 
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15
 )
-RETURNING id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at
+RETURNING id, username, ip_address, user_agent, attempt_time, success, failure_reason, rate_limited, rate_limit_released_at, released_by, session_id, country, city, device_info, created_at, asn, as_org, latitude, longitude, analyzed_at, device_fingerprint
 `
 
 type LogLoginAttemptParams struct {
-	Username      string
-	IpAddress     string
-	UserAgent     sql.NullString
-	Success       bool
-	FailureReason sql.NullString
-	RateLimited   sql.NullBool
-	SessionID     sql.NullString
-	DeviceInfo    pqtype.NullRawMessage
+	Username          string
+	IpAddress         string
+	UserAgent         sql.NullString
+	Success           bool
+	FailureReason     sql.NullString
+	RateLimited       sql.NullBool
+	SessionID         sql.NullString
+	DeviceInfo        EncryptedJSON
+	Country           sql.NullString
+	City              sql.NullString
+	Asn               sql.NullInt64
+	AsOrg             sql.NullString
+	Latitude          sql.NullFloat64
+	Longitude         sql.NullFloat64
+	DeviceFingerprint sql.NullString
 }
 
 // internal/db/query/login_attempts.sql
 func (q *Queries) LogLoginAttempt(ctx context.Context, arg LogLoginAttemptParams) (LoginAttemptsLog, error) {
-	row := q.db.QueryRowContext(ctx, logLoginAttempt,
+	row := q.db.QueryRow(ctx, logLoginAttempt,
 		arg.Username,
 		arg.IpAddress,
 		arg.UserAgent,
@@ -542,6 +574,13 @@ func (q *Queries) LogLoginAttempt(ctx context.Context, arg LogLoginAttemptParams
 		arg.RateLimited,
 		arg.SessionID,
 		arg.DeviceInfo,
+		arg.Country,
+		arg.City,
+		arg.Asn,
+		arg.AsOrg,
+		arg.Latitude,
+		arg.Longitude,
+		arg.DeviceFingerprint,
 	)
 	var i LoginAttemptsLog
 	err := row.Scan(
@@ -560,6 +599,12 @@ func (q *Queries) LogLoginAttempt(ctx context.Context, arg LogLoginAttemptParams
 		&i.City,
 		&i.DeviceInfo,
 		&i.CreatedAt,
+		&i.Asn,
+		&i.AsOrg,
+		&i.Latitude,
+		&i.Longitude,
+		&i.AnalyzedAt,
+		&i.DeviceFingerprint,
 	)
 	return i, err
 }
@@ -596,7 +641,7 @@ type LogRateLimitReleaseParams struct {
 }
 
 func (q *Queries) LogRateLimitRelease(ctx context.Context, arg LogRateLimitReleaseParams) (RateLimitRelease, error) {
-	row := q.db.QueryRowContext(ctx, logRateLimitRelease,
+	row := q.db.QueryRow(ctx, logRateLimitRelease,
 		arg.ClientID,
 		arg.IpAddress,
 		arg.Username,
@@ -632,7 +677,7 @@ WHERE client_id = $1
 `
 
 func (q *Queries) ManuallyReleaseRateLimit(ctx context.Context, clientID string) error {
-	_, err := q.db.ExecContext(ctx, manuallyReleaseRateLimit, clientID)
+	_, err := q.db.Exec(ctx, manuallyReleaseRateLimit, clientID)
 	return err
 }
 
@@ -652,6 +697,6 @@ type UpdateLoginAttemptReleaseParams struct {
 }
 
 func (q *Queries) UpdateLoginAttemptRelease(ctx context.Context, arg UpdateLoginAttemptReleaseParams) error {
-	_, err := q.db.ExecContext(ctx, updateLoginAttemptRelease, arg.ReleasedBy, arg.IpAddress)
+	_, err := q.db.Exec(ctx, updateLoginAttemptRelease, arg.ReleasedBy, arg.IpAddress)
 	return err
 }
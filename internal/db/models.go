@@ -6,6 +6,7 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,6 +25,25 @@ type ActiveIpBan struct {
 	MinutesRemaining int32
 }
 
+// Role-scoped keys for machine integrations (see internal/server/api_keys.go).
+type ApiKey struct {
+	ID           uuid.UUID
+	TenantID     uuid.UUID
+	Name         string
+	KeyHash      string
+	KeyPrefix    string
+	RoleID       int32
+	DepartmentID sql.NullInt32
+	Scopes       []string
+	ExpiresAt    sql.NullTime
+	LastUsedAt   sql.NullTime
+	LastUsedIp   sql.NullString
+	CreatedBy    uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	RevokedAt    sql.NullTime
+}
+
 type ApiRateLimit struct {
 	ID                  uuid.UUID
 	ClientID            string
@@ -45,24 +65,78 @@ type ApiRateLimitsArchive struct {
 	OriginalCreatedAt sql.NullTime
 }
 
+// Daily request counts and latency per user, access token and route (see internal/usage).
+type ApiUsageDaily struct {
+	Day          time.Time
+	UserID       uuid.UUID
+	TokenID      uuid.NullUUID
+	Method       string
+	Route        string
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	TotalMs      float64
+	MaxMs        float64
+}
+
 type AuditLog struct {
 	ID         uuid.UUID
 	UserID     uuid.NullUUID
 	Action     string
 	EntityType string
 	EntityID   string
-	OldValues  pqtype.NullRawMessage
-	NewValues  pqtype.NullRawMessage
-	IpAddress  sql.NullString
-	UserAgent  sql.NullString
+	OldValues  json.RawMessage
+	NewValues  json.RawMessage
+	IpAddress  EncryptedString
+	UserAgent  EncryptedString
 	CreatedAt  sql.NullTime
 }
 
+type Backup struct {
+	ID           uuid.UUID
+	Status       string
+	StorageKey   sql.NullString
+	SizeBytes    sql.NullInt64
+	Checksum     sql.NullString
+	Error        sql.NullString
+	TriggeredBy  uuid.NullUUID
+	StartedAt    time.Time
+	FinishedAt   sql.NullTime
+	VerifyStatus sql.NullString
+	VerifyError  sql.NullString
+	VerifiedAt   sql.NullTime
+}
+
+type BackupRestore struct {
+	ID          uuid.UUID
+	BackupID    uuid.UUID
+	Status      string
+	Error       sql.NullString
+	TriggeredBy uuid.NullUUID
+	StartedAt   time.Time
+	FinishedAt  sql.NullTime
+}
+
+type CalendarFeed struct {
+	UserID        uuid.UUID
+	TokenHash     string
+	CreatedAt     time.Time
+	LastFetchedAt sql.NullTime
+}
+
 type Category struct {
 	ID   int32
 	Name string
 }
 
+// Second-person approvals of orders with controlled substances.
+type ControlledApproval struct {
+	OrderID    uuid.UUID
+	ApprovedBy uuid.NullUUID
+	Note       sql.NullString
+	ApprovedAt time.Time
+}
+
 type CurrentlyBlockedIp struct {
 	ClientID      string
 	Endpoint      string
@@ -71,11 +145,100 @@ type CurrentlyBlockedIp struct {
 	BlockWindows  int64
 }
 
+// Departments within a tenant; non-admin users only see the orders of their own department.
+type Department struct {
+	ID        int32
+	TenantID  uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+type DeviceToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Token      string
+	Platform   string
+	Name       sql.NullString
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
 type DosageForm struct {
 	ID   int32
 	Name string
 }
 
+// ATC code of each generic, for duplicate-therapy warnings.
+type DrugClass struct {
+	GenericCode string
+	AtcCode     string
+}
+
+// Known interactions between two generics, for order item warnings.
+type DrugInteraction struct {
+	GenericCodeA string
+	GenericCodeB string
+	Severity     string
+	Description  string
+}
+
+type DrugRegistrySync struct {
+	ID           uuid.UUID
+	Source       string
+	Status       string
+	TriggeredBy  uuid.NullUUID
+	StartedAt    time.Time
+	FinishedAt   sql.NullTime
+	TotalEntries int32
+	Matched      int32
+	Created      int32
+	Ambiguous    int32
+	Conflicts    int32
+	Failed       int32
+	Error        sql.NullString
+}
+
+type DrugRegistrySyncItem struct {
+	ID          int64
+	SyncID      uuid.UUID
+	Irc         string
+	GenericCode sql.NullString
+	Name        string
+	Gtin        sql.NullString
+	Outcome     string
+	ProductID   uuid.NullUUID
+	Detail      sql.NullString
+}
+
+// Fulfilled orders handed to the accounting/ERP system (see internal/erp).
+type ErpBatch struct {
+	ID          uuid.UUID
+	Mode        string
+	Format      string
+	OrderCount  int32
+	Status      string
+	Error       sql.NullString
+	CreatedBy   uuid.NullUUID
+	CreatedAt   time.Time
+	DeliveredAt sql.NullTime
+}
+
+type ErpBatchOrder struct {
+	BatchID uuid.UUID
+	OrderID uuid.UUID
+}
+
+type ExportFile struct {
+	ID          uuid.UUID
+	Kind        string
+	ObjectKey   string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	CreatedBy   uuid.NullUUID
+	CreatedAt   time.Time
+}
+
 // Tracks temporarily banned IPs with automatic expiry and cleanup. Records are automatically removed after ban expires and retained for 30 days for auditing.
 type IpBan struct {
 	ID             uuid.UUID
@@ -108,6 +271,18 @@ type IpBanStat struct {
 	ManualBans        int64
 }
 
+// IP allow and deny rules enforced by the rate limiter (see internal/middleware/ip_rules.go).
+type IpRule struct {
+	ID        uuid.UUID
+	Cidr      pqtype.CIDR
+	Action    string
+	Reason    sql.NullString
+	ExpiresAt sql.NullTime
+	CreatedBy uuid.NullUUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 type LoginAttemptStat struct {
 	Hour                int64
 	TotalAttempts       int64
@@ -133,18 +308,66 @@ type LoginAttemptsLog struct {
 	SessionID           sql.NullString
 	Country             sql.NullString
 	City                sql.NullString
-	DeviceInfo          pqtype.NullRawMessage
+	DeviceInfo          EncryptedJSON
 	CreatedAt           sql.NullTime
+	Asn                 sql.NullInt64
+	AsOrg               sql.NullString
+	Latitude            sql.NullFloat64
+	Longitude           sql.NullFloat64
+	AnalyzedAt          sql.NullTime
+	DeviceFingerprint   sql.NullString
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	EventType string
+	Channel   string
+	Enabled   bool
+	UpdatedAt sql.NullTime
 }
 
 type Order struct {
+	ID           uuid.UUID
+	CreatedBy    uuid.NullUUID
+	Status       string
+	CreatedAt    sql.NullTime
+	SubmittedAt  sql.NullTime
+	Notes        sql.NullString
+	DeletedAt    sql.NullTime
+	Priority     string
+	NeededBy     sql.NullTime
+	TenantID     uuid.UUID
+	DepartmentID sql.NullInt32
+	RequesterID  uuid.NullUUID
+	Subtotal     string
+	Total        string
+}
+
+type OrderAssignment struct {
+	OrderID    uuid.UUID
+	UserID     uuid.UUID
+	AssignedBy uuid.NullUUID
+	AssignedAt time.Time
+}
+
+type OrderAttachment struct {
 	ID          uuid.UUID
-	CreatedBy   uuid.NullUUID
-	Status      string
-	CreatedAt   sql.NullTime
-	SubmittedAt sql.NullTime
-	Notes       sql.NullString
-	DeletedAt   sql.NullTime
+	OrderID     uuid.UUID
+	ObjectKey   string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	UploadedBy  uuid.NullUUID
+	CreatedAt   time.Time
+}
+
+// Reason codes and notes of cancelled orders.
+type OrderCancellation struct {
+	OrderID     uuid.UUID
+	Reason      string
+	Note        string
+	CancelledBy uuid.NullUUID
+	CancelledAt time.Time
 }
 
 type OrderItem struct {
@@ -154,6 +377,73 @@ type OrderItem struct {
 	RequestedQty int32
 	Unit         sql.NullString
 	Note         sql.NullString
+	UnitPrice    sql.NullString
+	LineTotal    sql.NullString
+	SupplierID   sql.NullInt32
+}
+
+// Catalog of order statuses; orders.status must be one of them.
+type OrderStatus struct {
+	Code      string
+	Label     string
+	SortOrder int32
+	CreatedAt time.Time
+}
+
+// One status change of an order, kept so the order's history can be told.
+type OrderStatusHistory struct {
+	ID        int64
+	OrderID   uuid.UUID
+	OldStatus string
+	NewStatus string
+	ChangedBy uuid.NullUUID
+	Note      sql.NullString
+	ChangedAt time.Time
+}
+
+// Interaction and duplicate-therapy warnings on the items of an order.
+type OrderWarning struct {
+	ID               uuid.UUID
+	OrderID          uuid.UUID
+	OrderItemID      uuid.UUID
+	OtherOrderItemID uuid.UUID
+	Kind             string
+	Severity         string
+	Message          string
+	CreatedAt        time.Time
+}
+
+// Domain events awaiting (or recently completed) delivery to webhooks and brokers.
+type OutboxEvent struct {
+	ID            uuid.UUID
+	EventType     string
+	AggregateType string
+	AggregateID   string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	PublishedAt   sql.NullTime
+}
+
+// Password reset requests, for rate limiting per IP and per username.
+type PasswordResetRequest struct {
+	ID        uuid.UUID
+	Username  string
+	IpAddress string
+	CreatedAt time.Time
+}
+
+// Single-use, expiring password reset tokens; only their hash is stored.
+type PasswordResetToken struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	TokenHash   string
+	RequestedIp string
+	ExpiresAt   time.Time
+	UsedAt      sql.NullTime
+	CreatedAt   time.Time
 }
 
 type Permission struct {
@@ -165,17 +455,39 @@ type Permission struct {
 	CreatedAt   sql.NullTime
 }
 
+// User-generated API tokens with scopes (see internal/middleware/tokens.go).
+type PersonalAccessToken struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	Scopes      []string
+	ExpiresAt   sql.NullTime
+	LastUsedAt  sql.NullTime
+	LastUsedIp  sql.NullString
+	CreatedAt   time.Time
+	RevokedAt   sql.NullTime
+}
+
 type Product struct {
-	ID           uuid.UUID
-	Name         string
-	Brand        sql.NullString
-	DosageFormID sql.NullInt32
-	Strength     sql.NullString
-	Unit         sql.NullString
-	CategoryID   sql.NullInt32
-	Description  sql.NullString
-	CreatedAt    sql.NullTime
-	DeletedAt    sql.NullTime
+	ID            uuid.UUID
+	Name          string
+	Brand         sql.NullString
+	DosageFormID  sql.NullInt32
+	Strength      sql.NullString
+	Unit          sql.NullString
+	CategoryID    sql.NullInt32
+	Description   sql.NullString
+	CreatedAt     sql.NullTime
+	DeletedAt     sql.NullTime
+	Status        string
+	Irc           sql.NullString
+	GenericCode   sql.NullString
+	TenantID      uuid.NullUUID
+	IsControlled  bool
+	ScheduleClass sql.NullString
+	Price         sql.NullString
 }
 
 type ProductBarcode struct {
@@ -186,6 +498,23 @@ type ProductBarcode struct {
 	CreatedAt   sql.NullTime
 }
 
+type ProductImage struct {
+	ProductID   uuid.UUID
+	ObjectKey   string
+	ContentType string
+	SizeBytes   int64
+	UploadedBy  uuid.NullUUID
+	UploadedAt  time.Time
+}
+
+type ProductStock struct {
+	ProductID    uuid.UUID
+	OnHand       int32
+	ReorderLevel int32
+	UpdatedBy    uuid.NullUUID
+	UpdatedAt    time.Time
+}
+
 // Tracks when users are released from rate limiting, either automatically or manually
 type RateLimitRelease struct {
 	ID               uuid.UUID
@@ -202,6 +531,64 @@ type RateLimitRelease struct {
 	CreatedAt        sql.NullTime
 }
 
+// Orders placed again on a schedule (see internal/recurring).
+type RecurringOrder struct {
+	ID              uuid.UUID
+	Name            string
+	TemplateOrderID uuid.UUID
+	Frequency       string
+	LeadDays        int32
+	Priority        string
+	Enabled         bool
+	NextRunAt       time.Time
+	LastRunAt       sql.NullTime
+	LastOrderID     uuid.NullUUID
+	LastError       sql.NullString
+	CreatedBy       uuid.NullUUID
+	CreatedAt       time.Time
+}
+
+// Reports emailed to users on a schedule (see internal/reports).
+// Rotating refresh tokens issued at login; a family is every token descended from one login.
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	RotatedAt sql.NullTime
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+type ReportSchedule struct {
+	ID            uuid.UUID
+	Report        string
+	RecipientID   uuid.UUID
+	Format        string
+	Frequency     string
+	SendHour      int32
+	Enabled       bool
+	NextRunAt     time.Time
+	LastRunAt     sql.NullTime
+	LastStatus    sql.NullString
+	LastError     sql.NullString
+	CreatedBy     uuid.NullUUID
+	CreatedAt     time.Time
+	SavedReportID uuid.NullUUID
+}
+
+// Patients and departments orders are placed for; name and reference are encrypted at rest.
+type Requester struct {
+	ID           uuid.UUID
+	TenantID     uuid.UUID
+	Kind         string
+	Name         EncryptedString
+	Reference    EncryptedString
+	DepartmentID sql.NullInt32
+	CreatedAt    time.Time
+}
+
 type Role struct {
 	ID   int32
 	Name string
@@ -214,7 +601,74 @@ type RolePermission struct {
 	CreatedAt    sql.NullTime
 }
 
+// Saved filter sets run on demand or emailed by report_schedules (see internal/reports).
+type SavedReport struct {
+	ID          uuid.UUID
+	Name        string
+	Description sql.NullString
+	Entity      string
+	Filters     json.RawMessage
+	Columns     []string
+	Sort        json.RawMessage
+	RowLimit    int32
+	CreatedBy   uuid.NullUUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Last run of each periodic job (see internal/scheduler).
+type ScheduledJob struct {
+	Name           string
+	Schedule       string
+	LastStatus     sql.NullString
+	LastError      sql.NullString
+	LastStartedAt  sql.NullTime
+	LastFinishedAt sql.NullTime
+	LastDurationMs sql.NullInt64
+	RunCount       int64
+	FailureCount   int64
+}
+
+// Login anomalies found by the analyzer (see internal/anomaly).
+type SecurityEvent struct {
+	ID             uuid.UUID
+	Kind           string
+	Username       string
+	LoginAttemptID uuid.NullUUID
+	IpAddress      string
+	Details        json.RawMessage
+	AcknowledgedAt sql.NullTime
+	AcknowledgedBy uuid.NullUUID
+	CreatedAt      time.Time
+}
+
+// Statements slower than the configured threshold, with parameters redacted (see internal/db/query_timing.go).
+type SlowQuery struct {
+	ID         int64
+	QueryName  string
+	Operation  string
+	TableName  string
+	Query      string
+	Params     []string
+	DurationMs float64
+	Error      sql.NullString
+	RequestID  sql.NullString
+	Route      sql.NullString
+	UserID     sql.NullString
+	CreatedAt  time.Time
+}
+
 // Tracks system initialization. Admin user must be created via secure setup endpoint with strong password.
+// Distributors within a tenant that order items are routed to.
+type Supplier struct {
+	ID        int32
+	TenantID  uuid.UUID
+	Name      string
+	Phone     sql.NullString
+	Email     sql.NullString
+	CreatedAt time.Time
+}
+
 type SystemSetup struct {
 	ID               int32
 	AdminCreated     sql.NullBool
@@ -223,12 +677,42 @@ type SystemSetup struct {
 	CreatedAt        sql.NullTime
 }
 
+// Pharmacies sharing this deployment; rows are scoped by row level security (see internal/db/tenant.go).
+type Tenant struct {
+	ID                uuid.UUID
+	Slug              string
+	Name              string
+	CreatedAt         time.Time
+	RequestsPerMinute sql.NullInt32
+	RequestsPerDay    sql.NullInt32
+	OrdersPerDay      sql.NullInt32
+}
+
+// Daily request counts per tenant, checked against the tenant request quota.
+type TenantRequestCount struct {
+	TenantID uuid.UUID
+	Day      time.Time
+	Requests int64
+}
+
 type User struct {
-	ID           uuid.UUID
-	Username     string
-	FullName     sql.NullString
-	PasswordHash string
-	RoleID       sql.NullInt32
-	CreatedAt    sql.NullTime
-	DeletedAt    sql.NullTime
+	ID             uuid.UUID
+	Username       string
+	FullName       EncryptedString
+	PasswordHash   string
+	RoleID         sql.NullInt32
+	CreatedAt      sql.NullTime
+	DeletedAt      sql.NullTime
+	TenantID       uuid.UUID
+	DepartmentID   sql.NullInt32
+	Language       sql.NullString
+	ServiceAccount bool
+}
+
+type UserNotificationSetting struct {
+	UserID    uuid.UUID
+	Email     sql.NullString
+	CreatedAt sql.NullTime
+	UpdatedAt sql.NullTime
+	Phone     sql.NullString
 }
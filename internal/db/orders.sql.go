@@ -8,27 +8,100 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const countOrderItems = `-- name: CountOrderItems :one
+SELECT COUNT(*) FROM order_items
+WHERE order_id = $1
+`
+
+func (q *Queries) CountOrderItems(ctx context.Context, orderID uuid.NullUUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countOrderItems, orderID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSearchOrders = `-- name: CountSearchOrders :one
+-- Number of orders SearchOrders lists with the same filters
+SELECT COUNT(*) FROM orders o
+WHERE ($1::timestamptz IS NULL OR o.created_at >= $1::timestamptz)
+  AND ($2::timestamptz IS NULL OR o.created_at < $2::timestamptz)
+  AND ($3::uuid IS NULL OR o.created_by = $3::uuid)
+  AND ($4::uuid IS NULL OR o.requester_id = $4::uuid)
+  AND (cardinality($5::text[]) = 0 OR o.status = ANY($5::text[]))
+  AND ($6::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM order_items pi
+        WHERE pi.order_id = o.id AND pi.product_id = $6::uuid
+    ))
+  AND ($7::text = ''
+    OR normalize_search(COALESCE(o.notes, '')) LIKE '%' || normalize_search($7::text) || '%'
+    OR EXISTS (
+        SELECT 1 FROM order_items i
+        LEFT JOIN products p ON p.id = i.product_id
+        WHERE i.order_id = o.id
+          AND (normalize_search(COALESCE(p.name, '')) LIKE '%' || normalize_search($7::text) || '%'
+            OR normalize_search(COALESCE(p.brand, '')) LIKE '%' || normalize_search($7::text) || '%'
+            OR normalize_search(COALESCE(i.note, '')) LIKE '%' || normalize_search($7::text) || '%')
+    ))
+`
+
+type CountSearchOrdersParams struct {
+	FromTime    sql.NullTime
+	ToTime      sql.NullTime
+	CreatedBy   uuid.NullUUID
+	RequesterID uuid.NullUUID
+	Statuses    []string
+	ProductID   uuid.NullUUID
+	Query       string
+}
+
+// Number of orders SearchOrders lists with the same filters
+func (q *Queries) CountSearchOrders(ctx context.Context, arg CountSearchOrdersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSearchOrders,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CreatedBy,
+		arg.RequesterID,
+		arg.Statuses,
+		arg.ProductID,
+		arg.Query,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createOrder = `-- name: CreateOrder :one
 INSERT INTO orders (
-    created_by, status, notes
+    created_by, status, notes, priority, needed_by, requester_id, department_id
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, (SELECT department_id FROM users WHERE id = $1)
 )
-RETURNING id, created_by, status, created_at, submitted_at, notes, deleted_at
+RETURNING id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total
 `
 
 type CreateOrderParams struct {
-	CreatedBy uuid.NullUUID
-	Status    string
-	Notes     sql.NullString
+	CreatedBy   uuid.NullUUID
+	Status      string
+	Notes       sql.NullString
+	Priority    string
+	NeededBy    sql.NullTime
+	RequesterID uuid.NullUUID
 }
 
 func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order, error) {
-	row := q.db.QueryRowContext(ctx, createOrder, arg.CreatedBy, arg.Status, arg.Notes)
+	row := q.db.QueryRow(ctx, createOrder,
+		arg.CreatedBy,
+		arg.Status,
+		arg.Notes,
+		arg.Priority,
+		arg.NeededBy,
+		arg.RequesterID,
+	)
 	var i Order
 	err := row.Scan(
 		&i.ID,
@@ -38,17 +111,24 @@ func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order
 		&i.SubmittedAt,
 		&i.Notes,
 		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
 	)
 	return i, err
 }
 
 const createOrderItem = `-- name: CreateOrderItem :one
 INSERT INTO order_items (
-    order_id, product_id, requested_qty, unit, note
+    order_id, product_id, requested_qty, unit, note, unit_price
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 )
-RETURNING id, order_id, product_id, requested_qty, unit, note
+RETURNING id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id
 `
 
 type CreateOrderItemParams struct {
@@ -57,15 +137,17 @@ type CreateOrderItemParams struct {
 	RequestedQty int32
 	Unit         sql.NullString
 	Note         sql.NullString
+	UnitPrice    sql.NullString
 }
 
 func (q *Queries) CreateOrderItem(ctx context.Context, arg CreateOrderItemParams) (OrderItem, error) {
-	row := q.db.QueryRowContext(ctx, createOrderItem,
+	row := q.db.QueryRow(ctx, createOrderItem,
 		arg.OrderID,
 		arg.ProductID,
 		arg.RequestedQty,
 		arg.Unit,
 		arg.Note,
+		arg.UnitPrice,
 	)
 	var i OrderItem
 	err := row.Scan(
@@ -75,16 +157,106 @@ func (q *Queries) CreateOrderItem(ctx context.Context, arg CreateOrderItemParams
 		&i.RequestedQty,
 		&i.Unit,
 		&i.Note,
+		&i.UnitPrice,
+		&i.LineTotal,
+		&i.SupplierID,
 	)
 	return i, err
 }
 
+const createOrderItems = `-- name: CreateOrderItems :many
+-- Adds many items to an order in one statement. The arrays are read side
+-- by side, one item per position; empty units, notes and unit prices are
+-- stored as NULL.
+INSERT INTO order_items (order_id, product_id, requested_qty, unit, note, unit_price)
+SELECT $1::uuid, i.product_id, i.requested_qty, NULLIF(i.unit, ''), NULLIF(i.note, ''),
+    NULLIF(i.unit_price, '')::numeric
+FROM unnest($2::uuid[], $3::int4[], $4::text[], $5::text[], $6::text[])
+    AS i(product_id, requested_qty, unit, note, unit_price)
+RETURNING id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id
+`
+
+type CreateOrderItemsParams struct {
+	OrderID       uuid.UUID
+	ProductIds    []uuid.UUID
+	RequestedQtys []int32
+	Units         []string
+	Notes         []string
+	UnitPrices    []string
+}
+
+// Adds many items to an order in one statement. The arrays are read side
+// by side, one item per position; empty units, notes and unit prices are
+// stored as NULL.
+func (q *Queries) CreateOrderItems(ctx context.Context, arg CreateOrderItemsParams) ([]OrderItem, error) {
+	rows, err := q.db.Query(ctx, createOrderItems,
+		arg.OrderID,
+		arg.ProductIds,
+		arg.RequestedQtys,
+		arg.Units,
+		arg.Notes,
+		arg.UnitPrices,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderItem
+	for rows.Next() {
+		var i OrderItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ProductID,
+			&i.RequestedQty,
+			&i.Unit,
+			&i.Note,
+			&i.UnitPrice,
+			&i.LineTotal,
+			&i.SupplierID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOrderStatusChange = `-- name: CreateOrderStatusChange :exec
+-- Records a status change in the order's history
+INSERT INTO order_status_history (order_id, old_status, new_status, changed_by, note)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateOrderStatusChangeParams struct {
+	OrderID   uuid.UUID
+	OldStatus string
+	NewStatus string
+	ChangedBy uuid.NullUUID
+	Note      sql.NullString
+}
+
+// Records a status change in the order's history
+func (q *Queries) CreateOrderStatusChange(ctx context.Context, arg CreateOrderStatusChangeParams) error {
+	_, err := q.db.Exec(ctx, createOrderStatusChange,
+		arg.OrderID,
+		arg.OldStatus,
+		arg.NewStatus,
+		arg.ChangedBy,
+		arg.Note,
+	)
+	return err
+}
+
 const deleteOrder = `-- name: DeleteOrder :exec
 DELETE FROM orders WHERE id = $1
 `
 
 func (q *Queries) DeleteOrder(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteOrder, id)
+	_, err := q.db.Exec(ctx, deleteOrder, id)
 	return err
 }
 
@@ -93,17 +265,49 @@ DELETE FROM order_items WHERE id = $1
 `
 
 func (q *Queries) DeleteOrderItem(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteOrderItem, id)
+	_, err := q.db.Exec(ctx, deleteOrderItem, id)
 	return err
 }
 
 const getOrder = `-- name: GetOrder :one
-SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at FROM orders
+SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total FROM orders
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetOrder(ctx context.Context, id uuid.UUID) (Order, error) {
-	row := q.db.QueryRowContext(ctx, getOrder, id)
+	row := q.db.QueryRow(ctx, getOrder, id)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedBy,
+		&i.Status,
+		&i.CreatedAt,
+		&i.SubmittedAt,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
+	)
+	return i, err
+}
+
+const getOrderForUpdate = `-- name: GetOrderForUpdate :one
+-- Locks the order until the transaction ends, so that checks made on it
+-- hold for the writes that follow
+SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total FROM orders
+WHERE id = $1
+FOR UPDATE
+`
+
+// Locks the order until the transaction ends, so that checks made on it
+// hold for the writes that follow
+func (q *Queries) GetOrderForUpdate(ctx context.Context, id uuid.UUID) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderForUpdate, id)
 	var i Order
 	err := row.Scan(
 		&i.ID,
@@ -113,18 +317,171 @@ func (q *Queries) GetOrder(ctx context.Context, id uuid.UUID) (Order, error) {
 		&i.SubmittedAt,
 		&i.Notes,
 		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
+	)
+	return i, err
+}
+
+const getOrderItem = `-- name: GetOrderItem :one
+SELECT id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id FROM order_items
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOrderItem(ctx context.Context, id uuid.UUID) (OrderItem, error) {
+	row := q.db.QueryRow(ctx, getOrderItem, id)
+	var i OrderItem
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.ProductID,
+		&i.RequestedQty,
+		&i.Unit,
+		&i.Note,
+		&i.UnitPrice,
+		&i.LineTotal,
+		&i.SupplierID,
 	)
 	return i, err
 }
 
 const getOrderItems = `-- name: GetOrderItems :many
-SELECT id, order_id, product_id, requested_qty, unit, note FROM order_items
+SELECT id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id FROM order_items
 WHERE order_id = $1
 ORDER BY id
 `
 
 func (q *Queries) GetOrderItems(ctx context.Context, orderID uuid.NullUUID) ([]OrderItem, error) {
-	rows, err := q.db.QueryContext(ctx, getOrderItems, orderID)
+	rows, err := q.db.Query(ctx, getOrderItems, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderItem
+	for rows.Next() {
+		var i OrderItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ProductID,
+			&i.RequestedQty,
+			&i.Unit,
+			&i.Note,
+			&i.UnitPrice,
+			&i.LineTotal,
+			&i.SupplierID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderCreators = `-- name: ListOrderCreators :many
+-- The users who created the orders, for ?include=creator
+SELECT o.id AS order_id, u.id AS user_id, u.username, u.full_name
+FROM orders o
+JOIN users u ON u.id = o.created_by
+WHERE o.id = ANY($1::uuid[])
+`
+
+type ListOrderCreatorsRow struct {
+	OrderID  uuid.UUID
+	UserID   uuid.UUID
+	Username string
+	FullName EncryptedString
+}
+
+// The users who created the orders, for ?include=creator
+func (q *Queries) ListOrderCreators(ctx context.Context, orderIds []uuid.UUID) ([]ListOrderCreatorsRow, error) {
+	rows, err := q.db.Query(ctx, listOrderCreators, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOrderCreatorsRow
+	for rows.Next() {
+		var i ListOrderCreatorsRow
+		if err := rows.Scan(
+			&i.OrderID,
+			&i.UserID,
+			&i.Username,
+			&i.FullName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderItems = `-- name: ListOrderItems :many
+-- A page of an order's items
+SELECT id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id FROM order_items
+WHERE order_id = $1
+ORDER BY id
+LIMIT $2 OFFSET $3
+`
+
+type ListOrderItemsParams struct {
+	OrderID uuid.NullUUID
+	Limit   int32
+	Offset  int32
+}
+
+// A page of an order's items
+func (q *Queries) ListOrderItems(ctx context.Context, arg ListOrderItemsParams) ([]OrderItem, error) {
+	rows, err := q.db.Query(ctx, listOrderItems, arg.OrderID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderItem
+	for rows.Next() {
+		var i OrderItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ProductID,
+			&i.RequestedQty,
+			&i.Unit,
+			&i.Note,
+			&i.UnitPrice,
+			&i.LineTotal,
+			&i.SupplierID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderItemsByOrders = `-- name: ListOrderItemsByOrders :many
+-- Items of many orders in one query, for ?include=items
+SELECT id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id FROM order_items
+WHERE order_id = ANY($1::uuid[])
+ORDER BY order_id, id
+`
+
+// Items of many orders in one query, for ?include=items
+func (q *Queries) ListOrderItemsByOrders(ctx context.Context, orderIds []uuid.UUID) ([]OrderItem, error) {
+	rows, err := q.db.Query(ctx, listOrderItemsByOrders, orderIds)
 	if err != nil {
 		return nil, err
 	}
@@ -139,14 +496,118 @@ func (q *Queries) GetOrderItems(ctx context.Context, orderID uuid.NullUUID) ([]O
 			&i.RequestedQty,
 			&i.Unit,
 			&i.Note,
+			&i.UnitPrice,
+			&i.LineTotal,
+			&i.SupplierID,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderItemsWithProducts = `-- name: ListOrderItemsWithProducts :many
+-- Items of many orders with their product names, for the CSV export
+SELECT i.id, i.order_id, p.name AS product_name, p.strength, i.requested_qty,
+       i.unit, i.unit_price, i.line_total, i.note
+FROM order_items i
+LEFT JOIN products p ON p.id = i.product_id
+WHERE i.order_id = ANY($1::uuid[])
+ORDER BY i.order_id, i.id
+`
+
+type ListOrderItemsWithProductsRow struct {
+	ID           uuid.UUID
+	OrderID      uuid.NullUUID
+	ProductName  sql.NullString
+	Strength     sql.NullString
+	RequestedQty int32
+	Unit         sql.NullString
+	UnitPrice    sql.NullString
+	LineTotal    sql.NullString
+	Note         sql.NullString
+}
+
+// Items of many orders with their product names, for the CSV export
+func (q *Queries) ListOrderItemsWithProducts(ctx context.Context, orderIds []uuid.UUID) ([]ListOrderItemsWithProductsRow, error) {
+	rows, err := q.db.Query(ctx, listOrderItemsWithProducts, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOrderItemsWithProductsRow
+	for rows.Next() {
+		var i ListOrderItemsWithProductsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ProductName,
+			&i.Strength,
+			&i.RequestedQty,
+			&i.Unit,
+			&i.UnitPrice,
+			&i.LineTotal,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderStatusChanges = `-- name: ListOrderStatusChanges :many
+-- The status history of an order, oldest first
+SELECT h.id, h.order_id, h.old_status, h.new_status, h.changed_by,
+       u.username AS changed_by_username, h.note, h.changed_at
+FROM order_status_history h
+LEFT JOIN users u ON u.id = h.changed_by
+WHERE h.order_id = $1
+ORDER BY h.changed_at, h.id
+`
+
+type ListOrderStatusChangesRow struct {
+	ID                int64
+	OrderID           uuid.UUID
+	OldStatus         string
+	NewStatus         string
+	ChangedBy         uuid.NullUUID
+	ChangedByUsername sql.NullString
+	Note              sql.NullString
+	ChangedAt         time.Time
+}
+
+// The status history of an order, oldest first
+func (q *Queries) ListOrderStatusChanges(ctx context.Context, orderID uuid.UUID) ([]ListOrderStatusChangesRow, error) {
+	rows, err := q.db.Query(ctx, listOrderStatusChanges, orderID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var items []ListOrderStatusChangesRow
+	for rows.Next() {
+		var i ListOrderStatusChangesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.OldStatus,
+			&i.NewStatus,
+			&i.ChangedBy,
+			&i.ChangedByUsername,
+			&i.Note,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -154,8 +615,8 @@ func (q *Queries) GetOrderItems(ctx context.Context, orderID uuid.NullUUID) ([]O
 }
 
 const listOrders = `-- name: ListOrders :many
-SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at FROM orders
-ORDER BY created_at DESC
+SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total FROM orders
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $1 OFFSET $2
 `
 
@@ -165,7 +626,7 @@ type ListOrdersParams struct {
 }
 
 func (q *Queries) ListOrders(ctx context.Context, arg ListOrdersParams) ([]Order, error) {
-	rows, err := q.db.QueryContext(ctx, listOrders, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listOrders, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -181,14 +642,18 @@ func (q *Queries) ListOrders(ctx context.Context, arg ListOrdersParams) ([]Order
 			&i.SubmittedAt,
 			&i.Notes,
 			&i.DeletedAt,
+			&i.Priority,
+			&i.NeededBy,
+			&i.TenantID,
+			&i.DepartmentID,
+			&i.RequesterID,
+			&i.Subtotal,
+			&i.Total,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -196,9 +661,9 @@ func (q *Queries) ListOrders(ctx context.Context, arg ListOrdersParams) ([]Order
 }
 
 const listOrdersByUser = `-- name: ListOrdersByUser :many
-SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at FROM orders
+SELECT id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total FROM orders
 WHERE created_by = $1
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $2 OFFSET $3
 `
 
@@ -209,7 +674,7 @@ type ListOrdersByUserParams struct {
 }
 
 func (q *Queries) ListOrdersByUser(ctx context.Context, arg ListOrdersByUserParams) ([]Order, error) {
-	rows, err := q.db.QueryContext(ctx, listOrdersByUser, arg.CreatedBy, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listOrdersByUser, arg.CreatedBy, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -225,43 +690,290 @@ func (q *Queries) ListOrdersByUser(ctx context.Context, arg ListOrdersByUserPara
 			&i.SubmittedAt,
 			&i.Notes,
 			&i.DeletedAt,
+			&i.Priority,
+			&i.NeededBy,
+			&i.TenantID,
+			&i.DepartmentID,
+			&i.RequesterID,
+			&i.Subtotal,
+			&i.Total,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const orderHasProduct = `-- name: OrderHasProduct :one
+SELECT EXISTS(
+    SELECT 1 FROM order_items
+    WHERE order_id = $1 AND product_id = $2
+) AS has_product
+`
+
+type OrderHasProductParams struct {
+	OrderID   uuid.NullUUID
+	ProductID uuid.NullUUID
+}
+
+// Whether the order already has an item for the product
+func (q *Queries) OrderHasProduct(ctx context.Context, arg OrderHasProductParams) (bool, error) {
+	row := q.db.QueryRow(ctx, orderHasProduct, arg.OrderID, arg.ProductID)
+	var has_product bool
+	err := row.Scan(&has_product)
+	return has_product, err
+}
+
+const recalculateOrderTotals = `-- name: RecalculateOrderTotals :one
+-- Sets an order's subtotal to the sum of its line totals, and its total to
+-- the subtotal with tax_percent added, rounded to the cent
+UPDATE orders o
+SET subtotal = t.subtotal,
+    total = ROUND(t.subtotal * (1 + $1::numeric / 100), 2)
+FROM (
+    SELECT COALESCE(SUM(line_total), 0) AS subtotal
+    FROM order_items
+    WHERE order_id = $2::uuid
+) t
+WHERE o.id = $2::uuid
+RETURNING o.id, o.created_by, o.status, o.created_at, o.submitted_at, o.notes, o.deleted_at, o.priority, o.needed_by, o.tenant_id, o.department_id, o.requester_id, o.subtotal, o.total
+`
+
+type RecalculateOrderTotalsParams struct {
+	TaxPercent string
+	OrderID    uuid.UUID
+}
+
+// Sets an order's subtotal to the sum of its line totals, and its total to
+// the subtotal with tax_percent added, rounded to the cent
+func (q *Queries) RecalculateOrderTotals(ctx context.Context, arg RecalculateOrderTotalsParams) (Order, error) {
+	row := q.db.QueryRow(ctx, recalculateOrderTotals, arg.TaxPercent, arg.OrderID)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedBy,
+		&i.Status,
+		&i.CreatedAt,
+		&i.SubmittedAt,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
+	)
+	return i, err
+}
+
+const searchOrders = `-- name: SearchOrders :many
+-- Orders matching every filter that is set: created in [from_time,
+-- to_time), by created_by, for requester_id, in one of statuses, holding
+-- an item for product_id, and with query in their notes or in the name,
+-- brand or note of an item, compared after normalize_search. Pages after
+-- the first seek past the keyset cursor (after_time, after_id).
+SELECT o.id, o.created_by, o.status, o.created_at, o.submitted_at, o.notes, o.deleted_at, o.priority, o.needed_by, o.tenant_id, o.department_id, o.requester_id, o.subtotal, o.total FROM orders o
+WHERE ($1::timestamptz IS NULL OR o.created_at >= $1::timestamptz)
+  AND ($2::timestamptz IS NULL OR o.created_at < $2::timestamptz)
+  AND ($3::uuid IS NULL OR o.created_by = $3::uuid)
+  AND ($4::uuid IS NULL OR o.requester_id = $4::uuid)
+  AND (cardinality($5::text[]) = 0 OR o.status = ANY($5::text[]))
+  AND ($6::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM order_items pi
+        WHERE pi.order_id = o.id AND pi.product_id = $6::uuid
+    ))
+  AND ($7::text = ''
+    OR normalize_search(COALESCE(o.notes, '')) LIKE '%' || normalize_search($7::text) || '%'
+    OR EXISTS (
+        SELECT 1 FROM order_items i
+        LEFT JOIN products p ON p.id = i.product_id
+        WHERE i.order_id = o.id
+          AND (normalize_search(COALESCE(p.name, '')) LIKE '%' || normalize_search($7::text) || '%'
+            OR normalize_search(COALESCE(p.brand, '')) LIKE '%' || normalize_search($7::text) || '%'
+            OR normalize_search(COALESCE(i.note, '')) LIKE '%' || normalize_search($7::text) || '%')
+    ))
+  AND ($8::timestamptz IS NULL
+    OR (o.created_at, o.id) < ($8::timestamptz, $9::uuid))
+ORDER BY /* sort */ o.created_at DESC, o.id DESC
+LIMIT $10 OFFSET $11
+`
+
+type SearchOrdersParams struct {
+	FromTime    sql.NullTime
+	ToTime      sql.NullTime
+	CreatedBy   uuid.NullUUID
+	RequesterID uuid.NullUUID
+	Statuses    []string
+	ProductID   uuid.NullUUID
+	Query       string
+	AfterTime   sql.NullTime
+	AfterID     uuid.NullUUID
+	Limit       int32
+	Offset      int32
+}
+
+// Orders matching every filter that is set: created in [from_time,
+// to_time), by created_by, for requester_id, in one of statuses, holding
+// an item for product_id, and with query in their notes or in the name,
+// brand or note of an item, compared after normalize_search. Pages after
+// the first seek past the keyset cursor (after_time, after_id).
+func (q *Queries) SearchOrders(ctx context.Context, arg SearchOrdersParams) ([]Order, error) {
+	rows, err := q.db.Query(ctx, searchOrders,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CreatedBy,
+		arg.RequesterID,
+		arg.Statuses,
+		arg.ProductID,
+		arg.Query,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var items []Order
+	for rows.Next() {
+		var i Order
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedBy,
+			&i.Status,
+			&i.CreatedAt,
+			&i.SubmittedAt,
+			&i.Notes,
+			&i.DeletedAt,
+			&i.Priority,
+			&i.NeededBy,
+			&i.TenantID,
+			&i.DepartmentID,
+			&i.RequesterID,
+			&i.Subtotal,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+const setOrderItemSupplier = `-- name: SetOrderItemSupplier :one
+-- Routes an item of an order to a supplier, or unroutes it with NULL
+UPDATE order_items
+SET supplier_id = $1
+WHERE id = $2 AND order_id = $3
+RETURNING id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id
+`
+
+type SetOrderItemSupplierParams struct {
+	SupplierID sql.NullInt32
+	ID         uuid.UUID
+	OrderID    uuid.NullUUID
+}
+
+// Routes an item of an order to a supplier, or unroutes it with NULL
+func (q *Queries) SetOrderItemSupplier(ctx context.Context, arg SetOrderItemSupplierParams) (OrderItem, error) {
+	row := q.db.QueryRow(ctx, setOrderItemSupplier, arg.SupplierID, arg.ID, arg.OrderID)
+	var i OrderItem
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.ProductID,
+		&i.RequestedQty,
+		&i.Unit,
+		&i.Note,
+		&i.UnitPrice,
+		&i.LineTotal,
+		&i.SupplierID,
+	)
+	return i, err
+}
+
+const setOrderRequester = `-- name: SetOrderRequester :one
+UPDATE orders
+SET requester_id = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total
+`
+
+type SetOrderRequesterParams struct {
+	ID          uuid.UUID
+	RequesterID uuid.NullUUID
+}
+
+func (q *Queries) SetOrderRequester(ctx context.Context, arg SetOrderRequesterParams) (Order, error) {
+	row := q.db.QueryRow(ctx, setOrderRequester, arg.ID, arg.RequesterID)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedBy,
+		&i.Status,
+		&i.CreatedAt,
+		&i.SubmittedAt,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
+	)
+	return i, err
+}
+
 const updateOrderItem = `-- name: UpdateOrderItem :one
+-- Changes an order item. The quantity keeps its value when NULL; unit,
+-- note and unit price are set to the value given, NULL included, when
+-- their set_ flag is true and keep their value otherwise.
 UPDATE order_items
-SET 
-    requested_qty = COALESCE($2, requested_qty),
-    unit = COALESCE($3, unit),
-    note = COALESCE($4, note)
-WHERE id = $1
-RETURNING id, order_id, product_id, requested_qty, unit, note
+SET
+    requested_qty = COALESCE($1, requested_qty),
+    unit = CASE WHEN $2::boolean THEN $3 ELSE unit END,
+    note = CASE WHEN $4::boolean THEN $5 ELSE note END,
+    unit_price = CASE WHEN $6::boolean THEN $7 ELSE unit_price END
+WHERE id = $8
+RETURNING id, order_id, product_id, requested_qty, unit, note, unit_price, line_total, supplier_id
 `
 
 type UpdateOrderItemParams struct {
-	ID           uuid.UUID
-	RequestedQty int32
+	RequestedQty sql.NullInt32
+	SetUnit      bool
 	Unit         sql.NullString
+	SetNote      bool
 	Note         sql.NullString
+	SetUnitPrice bool
+	UnitPrice    sql.NullString
+	ID           uuid.UUID
 }
 
+// Changes an order item. The quantity keeps its value when NULL; unit,
+// note and unit price are set to the value given, NULL included, when
+// their set_ flag is true and keep their value otherwise.
 func (q *Queries) UpdateOrderItem(ctx context.Context, arg UpdateOrderItemParams) (OrderItem, error) {
-	row := q.db.QueryRowContext(ctx, updateOrderItem,
-		arg.ID,
+	row := q.db.QueryRow(ctx, updateOrderItem,
 		arg.RequestedQty,
+		arg.SetUnit,
 		arg.Unit,
+		arg.SetNote,
 		arg.Note,
+		arg.SetUnitPrice,
+		arg.UnitPrice,
+		arg.ID,
 	)
 	var i OrderItem
 	err := row.Scan(
@@ -271,6 +983,43 @@ func (q *Queries) UpdateOrderItem(ctx context.Context, arg UpdateOrderItemParams
 		&i.RequestedQty,
 		&i.Unit,
 		&i.Note,
+		&i.UnitPrice,
+		&i.LineTotal,
+		&i.SupplierID,
+	)
+	return i, err
+}
+
+const updateOrderNeededBy = `-- name: UpdateOrderNeededBy :one
+UPDATE orders
+SET needed_by = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total
+`
+
+type UpdateOrderNeededByParams struct {
+	ID       uuid.UUID
+	NeededBy sql.NullTime
+}
+
+func (q *Queries) UpdateOrderNeededBy(ctx context.Context, arg UpdateOrderNeededByParams) (Order, error) {
+	row := q.db.QueryRow(ctx, updateOrderNeededBy, arg.ID, arg.NeededBy)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedBy,
+		&i.Status,
+		&i.CreatedAt,
+		&i.SubmittedAt,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
 	)
 	return i, err
 }
@@ -281,7 +1030,7 @@ SET
     status = $2,
     submitted_at = CASE WHEN $2 = 'submitted' THEN NOW() ELSE submitted_at END
 WHERE id = $1
-RETURNING id, created_by, status, created_at, submitted_at, notes, deleted_at
+RETURNING id, created_by, status, created_at, submitted_at, notes, deleted_at, priority, needed_by, tenant_id, department_id, requester_id, subtotal, total
 `
 
 type UpdateOrderStatusParams struct {
@@ -290,7 +1039,7 @@ type UpdateOrderStatusParams struct {
 }
 
 func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) (Order, error) {
-	row := q.db.QueryRowContext(ctx, updateOrderStatus, arg.ID, arg.Status)
+	row := q.db.QueryRow(ctx, updateOrderStatus, arg.ID, arg.Status)
 	var i Order
 	err := row.Scan(
 		&i.ID,
@@ -300,6 +1049,13 @@ func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusPa
 		&i.SubmittedAt,
 		&i.Notes,
 		&i.DeletedAt,
+		&i.Priority,
+		&i.NeededBy,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.RequesterID,
+		&i.Subtotal,
+		&i.Total,
 	)
 	return i, err
 }
@@ -8,9 +8,10 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/sqlc-dev/pqtype"
 )
 
 const assignPermissionToRole = `-- name: AssignPermissionToRole :one
@@ -25,7 +26,7 @@ type AssignPermissionToRoleParams struct {
 }
 
 func (q *Queries) AssignPermissionToRole(ctx context.Context, arg AssignPermissionToRoleParams) (RolePermission, error) {
-	row := q.db.QueryRowContext(ctx, assignPermissionToRole, arg.RoleID, arg.PermissionID)
+	row := q.db.QueryRow(ctx, assignPermissionToRole, arg.RoleID, arg.PermissionID)
 	var i RolePermission
 	err := row.Scan(
 		&i.ID,
@@ -53,7 +54,7 @@ type CheckRolePermissionParams struct {
 }
 
 func (q *Queries) CheckRolePermission(ctx context.Context, arg CheckRolePermissionParams) (bool, error) {
-	row := q.db.QueryRowContext(ctx, checkRolePermission, arg.RoleID, arg.Resource, arg.Action)
+	row := q.db.QueryRow(ctx, checkRolePermission, arg.RoleID, arg.Resource, arg.Action)
 	var has_permission bool
 	err := row.Scan(&has_permission)
 	return has_permission, err
@@ -65,7 +66,56 @@ WHERE role_id = 1 AND deleted_at IS NULL
 `
 
 func (q *Queries) CountAdminUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countAdminUsers)
+	row := q.db.QueryRow(ctx, countAdminUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAuditLogsBetween = `-- name: CountAuditLogsBetween :one
+-- Number of entries ListAuditLogsBetween lists with the same filters
+SELECT COUNT(*) FROM audit_logs
+WHERE ($1::timestamptz IS NULL OR created_at >= $1::timestamptz)
+  AND ($2::timestamptz IS NULL OR created_at < $2::timestamptz)
+  AND ($3::uuid IS NULL OR user_id = $3::uuid)
+  AND ($4::text = '' OR entity_type = $4::text)
+  AND ($5::text = '' OR entity_id = $5::text)
+  AND ($6::text = '' OR action = $6::text)
+`
+
+type CountAuditLogsBetweenParams struct {
+	FromTime   sql.NullTime
+	ToTime     sql.NullTime
+	UserID     uuid.NullUUID
+	EntityType string
+	EntityID   string
+	Action     string
+}
+
+// Number of entries ListAuditLogsBetween lists with the same filters
+func (q *Queries) CountAuditLogsBetween(ctx context.Context, arg CountAuditLogsBetweenParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countAuditLogsBetween,
+		arg.FromTime,
+		arg.ToTime,
+		arg.UserID,
+		arg.EntityType,
+		arg.EntityID,
+		arg.Action,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPermissions = `-- name: CountPermissions :one
+-- Number of permissions, only those of resource unless it is empty
+SELECT COUNT(*) FROM permissions
+WHERE ($1::text = '' OR resource = $1::text)
+`
+
+// Number of permissions, only those of resource unless it is empty
+func (q *Queries) CountPermissions(ctx context.Context, resource string) (int64, error) {
+	row := q.db.QueryRow(ctx, countPermissions, resource)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -91,14 +141,14 @@ type CreateAuditLogParams struct {
 	Action     string
 	EntityType string
 	EntityID   string
-	OldValues  pqtype.NullRawMessage
-	NewValues  pqtype.NullRawMessage
-	IpAddress  sql.NullString
-	UserAgent  sql.NullString
+	OldValues  json.RawMessage
+	NewValues  json.RawMessage
+	IpAddress  EncryptedString
+	UserAgent  EncryptedString
 }
 
 func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
-	row := q.db.QueryRowContext(ctx, createAuditLog,
+	row := q.db.QueryRow(ctx, createAuditLog,
 		arg.UserID,
 		arg.Action,
 		arg.EntityType,
@@ -124,6 +174,46 @@ func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams)
 	return i, err
 }
 
+const createAuditLogs = `-- name: CreateAuditLogs :exec
+-- Adds many audit log entries in one statement. The arrays are read side
+-- by side, one entry per position; a nil user id and empty old and new
+-- values are stored as NULL.
+INSERT INTO audit_logs (user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent)
+SELECT NULLIF(e.user_id, '00000000-0000-0000-0000-000000000000'), e.action, e.entity_type, e.entity_id,
+    NULLIF(e.old_values, '')::jsonb, NULLIF(e.new_values, '')::jsonb, e.ip_address, e.user_agent
+FROM unnest($1::uuid[], $2::text[], $3::text[], $4::text[],
+    $5::text[], $6::text[], $7::text[], $8::text[])
+    AS e(user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent)
+`
+
+type CreateAuditLogsParams struct {
+	UserIds     []uuid.UUID
+	Actions     []string
+	EntityTypes []string
+	EntityIds   []string
+	OldValues   []string
+	NewValues   []string
+	IpAddresses []string
+	UserAgents  []string
+}
+
+// Adds many audit log entries in one statement. The arrays are read side
+// by side, one entry per position; a nil user id and empty old and new
+// values are stored as NULL.
+func (q *Queries) CreateAuditLogs(ctx context.Context, arg CreateAuditLogsParams) error {
+	_, err := q.db.Exec(ctx, createAuditLogs,
+		arg.UserIds,
+		arg.Actions,
+		arg.EntityTypes,
+		arg.EntityIds,
+		arg.OldValues,
+		arg.NewValues,
+		arg.IpAddresses,
+		arg.UserAgents,
+	)
+	return err
+}
+
 const createPermission = `-- name: CreatePermission :one
 INSERT INTO permissions (name, resource, action, description)
 VALUES ($1, $2, $3, $4)
@@ -138,7 +228,7 @@ type CreatePermissionParams struct {
 }
 
 func (q *Queries) CreatePermission(ctx context.Context, arg CreatePermissionParams) (Permission, error) {
-	row := q.db.QueryRowContext(ctx, createPermission,
+	row := q.db.QueryRow(ctx, createPermission,
 		arg.Name,
 		arg.Resource,
 		arg.Action,
@@ -156,12 +246,25 @@ func (q *Queries) CreatePermission(ctx context.Context, arg CreatePermissionPara
 	return i, err
 }
 
+const deleteAuditLogsBefore = `-- name: DeleteAuditLogsBefore :execrows
+DELETE FROM audit_logs WHERE created_at < $1::timestamptz
+`
+
+// Audit retention (see internal/scheduler)
+func (q *Queries) DeleteAuditLogsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAuditLogsBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deletePermission = `-- name: DeletePermission :exec
 DELETE FROM permissions WHERE id = $1
 `
 
 func (q *Queries) DeletePermission(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deletePermission, id)
+	_, err := q.db.Exec(ctx, deletePermission, id)
 	return err
 }
 
@@ -170,7 +273,7 @@ SELECT id, user_id, action, entity_type, entity_id, old_values, new_values, ip_a
 `
 
 func (q *Queries) GetAuditLog(ctx context.Context, id uuid.UUID) (AuditLog, error) {
-	row := q.db.QueryRowContext(ctx, getAuditLog, id)
+	row := q.db.QueryRow(ctx, getAuditLog, id)
 	var i AuditLog
 	err := row.Scan(
 		&i.ID,
@@ -203,7 +306,7 @@ type GetAuditLogStatsRow struct {
 }
 
 func (q *Queries) GetAuditLogStats(ctx context.Context) (GetAuditLogStatsRow, error) {
-	row := q.db.QueryRowContext(ctx, getAuditLogStats)
+	row := q.db.QueryRow(ctx, getAuditLogStats)
 	var i GetAuditLogStatsRow
 	err := row.Scan(&i.TotalLogs, &i.UniqueUsers, &i.UniqueEntities)
 	return i, err
@@ -212,7 +315,7 @@ func (q *Queries) GetAuditLogStats(ctx context.Context) (GetAuditLogStatsRow, er
 const getAuditLogsByAction = `-- name: GetAuditLogsByAction :many
 SELECT id, user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent, created_at FROM audit_logs
 WHERE action = $1
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $3 OFFSET $2
 `
 
@@ -223,7 +326,7 @@ type GetAuditLogsByActionParams struct {
 }
 
 func (q *Queries) GetAuditLogsByAction(ctx context.Context, arg GetAuditLogsByActionParams) ([]AuditLog, error) {
-	rows, err := q.db.QueryContext(ctx, getAuditLogsByAction, arg.Action, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getAuditLogsByAction, arg.Action, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -247,9 +350,6 @@ func (q *Queries) GetAuditLogsByAction(ctx context.Context, arg GetAuditLogsByAc
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -260,7 +360,7 @@ const getAuditLogsByEntity = `-- name: GetAuditLogsByEntity :many
 SELECT id, user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent, created_at FROM audit_logs
 WHERE entity_type = $1
   AND entity_id = $2
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $4 OFFSET $3
 `
 
@@ -272,7 +372,7 @@ type GetAuditLogsByEntityParams struct {
 }
 
 func (q *Queries) GetAuditLogsByEntity(ctx context.Context, arg GetAuditLogsByEntityParams) ([]AuditLog, error) {
-	rows, err := q.db.QueryContext(ctx, getAuditLogsByEntity,
+	rows, err := q.db.Query(ctx, getAuditLogsByEntity,
 		arg.EntityType,
 		arg.EntityID,
 		arg.Offset,
@@ -301,9 +401,6 @@ func (q *Queries) GetAuditLogsByEntity(ctx context.Context, arg GetAuditLogsByEn
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -313,7 +410,7 @@ func (q *Queries) GetAuditLogsByEntity(ctx context.Context, arg GetAuditLogsByEn
 const getAuditLogsByUser = `-- name: GetAuditLogsByUser :many
 SELECT id, user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent, created_at FROM audit_logs
 WHERE user_id = $1
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $3 OFFSET $2
 `
 
@@ -324,7 +421,7 @@ type GetAuditLogsByUserParams struct {
 }
 
 func (q *Queries) GetAuditLogsByUser(ctx context.Context, arg GetAuditLogsByUserParams) ([]AuditLog, error) {
-	rows, err := q.db.QueryContext(ctx, getAuditLogsByUser, arg.UserID, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getAuditLogsByUser, arg.UserID, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -348,9 +445,6 @@ func (q *Queries) GetAuditLogsByUser(ctx context.Context, arg GetAuditLogsByUser
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -362,7 +456,7 @@ SELECT id, name, resource, action, description, created_at FROM permissions WHER
 `
 
 func (q *Queries) GetPermission(ctx context.Context, id int32) (Permission, error) {
-	row := q.db.QueryRowContext(ctx, getPermission, id)
+	row := q.db.QueryRow(ctx, getPermission, id)
 	var i Permission
 	err := row.Scan(
 		&i.ID,
@@ -383,7 +477,7 @@ ORDER BY p.resource, p.action
 `
 
 func (q *Queries) GetRolePermissions(ctx context.Context, roleID int32) ([]Permission, error) {
-	rows, err := q.db.QueryContext(ctx, getRolePermissions, roleID)
+	rows, err := q.db.Query(ctx, getRolePermissions, roleID)
 	if err != nil {
 		return nil, err
 	}
@@ -403,9 +497,6 @@ func (q *Queries) GetRolePermissions(ctx context.Context, roleID int32) ([]Permi
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -413,19 +504,32 @@ func (q *Queries) GetRolePermissions(ctx context.Context, roleID int32) ([]Permi
 }
 
 const listActiveUsers = `-- name: ListActiveUsers :many
-SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at FROM users
+-- Users not deleted, newest first; pages after the first seek past the
+-- keyset cursor (after_time, after_id)
+SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account FROM users
 WHERE deleted_at IS NULL
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $1
+  AND ($1::timestamptz IS NULL
+    OR (created_at, id) < ($1::timestamptz, $2::uuid))
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT $4 OFFSET $3
 `
 
 type ListActiveUsersParams struct {
-	Offset int32
-	Limit  int32
+	AfterTime sql.NullTime
+	AfterID   uuid.NullUUID
+	Offset    int32
+	Limit     int32
 }
 
+// Users not deleted, newest first; pages after the first seek past the
+// keyset cursor (after_time, after_id)
 func (q *Queries) ListActiveUsers(ctx context.Context, arg ListActiveUsersParams) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listActiveUsers, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, listActiveUsers,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Offset,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -441,14 +545,48 @@ func (q *Queries) ListActiveUsers(ctx context.Context, arg ListActiveUsersParams
 			&i.RoleID,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.TenantID,
+			&i.DepartmentID,
+			&i.Language,
+			&i.ServiceAccount,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return items, nil
+}
+
+const listAllPermissions = `-- name: ListAllPermissions :many
+SELECT id, name, resource, action, description, created_at FROM permissions
+ORDER BY resource, action
+`
+
+// Every permission, for the RBAC export
+func (q *Queries) ListAllPermissions(ctx context.Context) ([]Permission, error) {
+	rows, err := q.db.Query(ctx, listAllPermissions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Permission
+	for rows.Next() {
+		var i Permission
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Resource,
+			&i.Action,
+			&i.Description,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -457,7 +595,7 @@ func (q *Queries) ListActiveUsers(ctx context.Context, arg ListActiveUsersParams
 
 const listAuditLogs = `-- name: ListAuditLogs :many
 SELECT id, user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent, created_at FROM audit_logs
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $2 OFFSET $1
 `
 
@@ -467,7 +605,7 @@ type ListAuditLogsParams struct {
 }
 
 func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
-	rows, err := q.db.QueryContext(ctx, listAuditLogs, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, listAuditLogs, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -491,9 +629,81 @@ func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditLogsBetween = `-- name: ListAuditLogsBetween :many
+-- Audit log entries created in [from_time, to_time), either bound
+-- optional; every filter that is set narrows the list. Pages after the
+-- first seek past the keyset cursor (after_time, after_id).
+SELECT id, user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent, created_at FROM audit_logs
+WHERE ($1::timestamptz IS NULL OR created_at >= $1::timestamptz)
+  AND ($2::timestamptz IS NULL OR created_at < $2::timestamptz)
+  AND ($3::uuid IS NULL OR user_id = $3::uuid)
+  AND ($4::text = '' OR entity_type = $4::text)
+  AND ($5::text = '' OR entity_id = $5::text)
+  AND ($6::text = '' OR action = $6::text)
+  AND ($7::timestamptz IS NULL
+    OR (created_at, id) < ($7::timestamptz, $8::uuid))
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT $9 OFFSET $10
+`
+
+type ListAuditLogsBetweenParams struct {
+	FromTime   sql.NullTime
+	ToTime     sql.NullTime
+	UserID     uuid.NullUUID
+	EntityType string
+	EntityID   string
+	Action     string
+	AfterTime  sql.NullTime
+	AfterID    uuid.NullUUID
+	Limit      int32
+	Offset     int32
+}
+
+// Audit log entries created in [from_time, to_time), either bound
+// optional; every filter that is set narrows the list. Pages after the
+// first seek past the keyset cursor (after_time, after_id).
+func (q *Queries) ListAuditLogsBetween(ctx context.Context, arg ListAuditLogsBetweenParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, listAuditLogsBetween,
+		arg.FromTime,
+		arg.ToTime,
+		arg.UserID,
+		arg.EntityType,
+		arg.EntityID,
+		arg.Action,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Action,
+			&i.EntityType,
+			&i.EntityID,
+			&i.OldValues,
+			&i.NewValues,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -502,7 +712,7 @@ func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([
 
 const listPermissions = `-- name: ListPermissions :many
 SELECT id, name, resource, action, description, created_at FROM permissions
-ORDER BY resource, action
+ORDER BY /* sort */ resource, action
 LIMIT $2 OFFSET $1
 `
 
@@ -512,7 +722,7 @@ type ListPermissionsParams struct {
 }
 
 func (q *Queries) ListPermissions(ctx context.Context, arg ListPermissionsParams) ([]Permission, error) {
-	rows, err := q.db.QueryContext(ctx, listPermissions, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, listPermissions, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -532,9 +742,6 @@ func (q *Queries) ListPermissions(ctx context.Context, arg ListPermissionsParams
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -544,7 +751,7 @@ func (q *Queries) ListPermissions(ctx context.Context, arg ListPermissionsParams
 const listPermissionsByResource = `-- name: ListPermissionsByResource :many
 SELECT id, name, resource, action, description, created_at FROM permissions
 WHERE resource = $1
-ORDER BY action
+ORDER BY /* sort */ action
 LIMIT $3 OFFSET $2
 `
 
@@ -555,7 +762,7 @@ type ListPermissionsByResourceParams struct {
 }
 
 func (q *Queries) ListPermissionsByResource(ctx context.Context, arg ListPermissionsByResourceParams) ([]Permission, error) {
-	rows, err := q.db.QueryContext(ctx, listPermissionsByResource, arg.Resource, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, listPermissionsByResource, arg.Resource, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -575,9 +782,37 @@ func (q *Queries) ListPermissionsByResource(ctx context.Context, arg ListPermiss
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return items, nil
+}
+
+const listRolePermissionGrants = `-- name: ListRolePermissionGrants :many
+SELECT id, role_id, permission_id, created_at FROM role_permissions
+ORDER BY role_id, permission_id
+`
+
+// Every permission granted to every role, for the RBAC export
+func (q *Queries) ListRolePermissionGrants(ctx context.Context) ([]RolePermission, error) {
+	rows, err := q.db.Query(ctx, listRolePermissionGrants)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RolePermission
+	for rows.Next() {
+		var i RolePermission
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoleID,
+			&i.PermissionID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -595,7 +830,7 @@ type RevokePermissionFromRoleParams struct {
 }
 
 func (q *Queries) RevokePermissionFromRole(ctx context.Context, arg RevokePermissionFromRoleParams) error {
-	_, err := q.db.ExecContext(ctx, revokePermissionFromRole, arg.RoleID, arg.PermissionID)
+	_, err := q.db.Exec(ctx, revokePermissionFromRole, arg.RoleID, arg.PermissionID)
 	return err
 }
 
@@ -606,7 +841,7 @@ WHERE id = $1
 `
 
 func (q *Queries) SoftDeleteUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, softDeleteUser, id)
+	_, err := q.db.Exec(ctx, softDeleteUser, id)
 	return err
 }
 
@@ -630,7 +865,7 @@ type UpdatePermissionParams struct {
 }
 
 func (q *Queries) UpdatePermission(ctx context.Context, arg UpdatePermissionParams) (Permission, error) {
-	row := q.db.QueryRowContext(ctx, updatePermission,
+	row := q.db.QueryRow(ctx, updatePermission,
 		arg.Name,
 		arg.Resource,
 		arg.Action,
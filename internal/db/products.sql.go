@@ -12,13 +12,39 @@ import (
 	"github.com/google/uuid"
 )
 
+const countProducts = `-- name: CountProducts :one
+SELECT COUNT(*) FROM products
+`
+
+func (q *Queries) CountProducts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countProducts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSearchProducts = `-- name: CountSearchProducts :one
+-- Number of products SearchProducts lists for the query
+SELECT COUNT(*) FROM products
+WHERE normalize_search(name) LIKE '%' || normalize_search($1::text) || '%'
+   OR normalize_search(COALESCE(brand, '')) LIKE '%' || normalize_search($1::text) || '%'
+`
+
+// Number of products SearchProducts lists for the query
+func (q *Queries) CountSearchProducts(ctx context.Context, query string) (int64, error) {
+	row := q.db.QueryRow(ctx, countSearchProducts, query)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createProduct = `-- name: CreateProduct :one
 INSERT INTO products (
-    name, brand, dosage_form_id, strength, unit, category_id, description
+    name, brand, dosage_form_id, strength, unit, category_id, description, price
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at
+RETURNING id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price
 `
 
 type CreateProductParams struct {
@@ -29,10 +55,11 @@ type CreateProductParams struct {
 	Unit         sql.NullString
 	CategoryID   sql.NullInt32
 	Description  sql.NullString
+	Price        sql.NullString
 }
 
 func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
-	row := q.db.QueryRowContext(ctx, createProduct,
+	row := q.db.QueryRow(ctx, createProduct,
 		arg.Name,
 		arg.Brand,
 		arg.DosageFormID,
@@ -40,6 +67,7 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		arg.Unit,
 		arg.CategoryID,
 		arg.Description,
+		arg.Price,
 	)
 	var i Product
 	err := row.Scan(
@@ -53,6 +81,13 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		&i.Description,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Status,
+		&i.Irc,
+		&i.GenericCode,
+		&i.TenantID,
+		&i.IsControlled,
+		&i.ScheduleClass,
+		&i.Price,
 	)
 	return i, err
 }
@@ -62,17 +97,17 @@ DELETE FROM products WHERE id = $1
 `
 
 func (q *Queries) DeleteProduct(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteProduct, id)
+	_, err := q.db.Exec(ctx, deleteProduct, id)
 	return err
 }
 
 const getProduct = `-- name: GetProduct :one
-SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at FROM products
+SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price FROM products
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetProduct(ctx context.Context, id uuid.UUID) (Product, error) {
-	row := q.db.QueryRowContext(ctx, getProduct, id)
+	row := q.db.QueryRow(ctx, getProduct, id)
 	var i Product
 	err := row.Scan(
 		&i.ID,
@@ -85,23 +120,125 @@ func (q *Queries) GetProduct(ctx context.Context, id uuid.UUID) (Product, error)
 		&i.Description,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Status,
+		&i.Irc,
+		&i.GenericCode,
+		&i.TenantID,
+		&i.IsControlled,
+		&i.ScheduleClass,
+		&i.Price,
 	)
 	return i, err
 }
 
+const getProductsByIDs = `-- name: GetProductsByIDs :many
+SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price FROM products
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetProductsByIDs(ctx context.Context, ids []uuid.UUID) ([]Product, error) {
+	rows, err := q.db.Query(ctx, getProductsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.DosageFormID,
+			&i.Strength,
+			&i.Unit,
+			&i.CategoryID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Status,
+			&i.Irc,
+			&i.GenericCode,
+			&i.TenantID,
+			&i.IsControlled,
+			&i.ScheduleClass,
+			&i.Price,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductCategories = `-- name: ListProductCategories :many
+-- The categories of the products, for ?include=category
+SELECT p.id AS product_id, c.id, c.name
+FROM products p
+JOIN categories c ON c.id = p.category_id
+WHERE p.id = ANY($1::uuid[])
+`
+
+type ListProductCategoriesRow struct {
+	ProductID uuid.UUID
+	ID        int32
+	Name      string
+}
+
+// The categories of the products, for ?include=category
+func (q *Queries) ListProductCategories(ctx context.Context, productIds []uuid.UUID) ([]ListProductCategoriesRow, error) {
+	rows, err := q.db.Query(ctx, listProductCategories, productIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductCategoriesRow
+	for rows.Next() {
+		var i ListProductCategoriesRow
+		if err := rows.Scan(
+			&i.ProductID,
+			&i.ID,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProducts = `-- name: ListProducts :many
-SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at FROM products
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+-- Products newest first; pages after the first seek past the keyset
+-- cursor (after_time, after_id)
+SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price FROM products
+WHERE $1::timestamptz IS NULL
+   OR (created_at, id) < ($1::timestamptz, $2::uuid)
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT $3 OFFSET $4
 `
 
 type ListProductsParams struct {
-	Limit  int32
-	Offset int32
+	AfterTime sql.NullTime
+	AfterID   uuid.NullUUID
+	Limit     int32
+	Offset    int32
 }
 
+// Products newest first; pages after the first seek past the keyset
+// cursor (after_time, after_id)
 func (q *Queries) ListProducts(ctx context.Context, arg ListProductsParams) ([]Product, error) {
-	rows, err := q.db.QueryContext(ctx, listProducts, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listProducts,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -120,14 +257,18 @@ func (q *Queries) ListProducts(ctx context.Context, arg ListProductsParams) ([]P
 			&i.Description,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.Status,
+			&i.Irc,
+			&i.GenericCode,
+			&i.TenantID,
+			&i.IsControlled,
+			&i.ScheduleClass,
+			&i.Price,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -135,22 +276,37 @@ func (q *Queries) ListProducts(ctx context.Context, arg ListProductsParams) ([]P
 }
 
 const searchProducts = `-- name: SearchProducts :many
-SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at FROM products
-WHERE 
-    name ILIKE '%' || $1 || '%' 
-    OR brand ILIKE '%' || $1 || '%'
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+-- Products whose name or brand contains the query, both folded by
+-- normalize_search so Arabic and Persian spellings, digits and ZWNJ match,
+-- past the keyset cursor (after_time, after_id) when one is given
+SELECT id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price FROM products
+WHERE (normalize_search(name) LIKE '%' || normalize_search($1::text) || '%'
+    OR normalize_search(COALESCE(brand, '')) LIKE '%' || normalize_search($1::text) || '%')
+  AND ($2::timestamptz IS NULL
+    OR (created_at, id) < ($2::timestamptz, $3::uuid))
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT $4 OFFSET $5
 `
 
 type SearchProductsParams struct {
-	Column1 sql.NullString
-	Limit   int32
-	Offset  int32
+	Query     string
+	AfterTime sql.NullTime
+	AfterID   uuid.NullUUID
+	Limit     int32
+	Offset    int32
 }
 
+// Products whose name or brand contains the query, both folded by
+// normalize_search so Arabic and Persian spellings, digits and ZWNJ match,
+// past the keyset cursor (after_time, after_id) when one is given
 func (q *Queries) SearchProducts(ctx context.Context, arg SearchProductsParams) ([]Product, error) {
-	rows, err := q.db.QueryContext(ctx, searchProducts, arg.Column1, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, searchProducts,
+		arg.Query,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -169,55 +325,128 @@ func (q *Queries) SearchProducts(ctx context.Context, arg SearchProductsParams)
 			&i.Description,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.Status,
+			&i.Irc,
+			&i.GenericCode,
+			&i.TenantID,
+			&i.IsControlled,
+			&i.ScheduleClass,
+			&i.Price,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+const setProductControlled = `-- name: SetProductControlled :one
+-- Marks a product as a controlled substance of a schedule class, or clears
+-- both
+UPDATE products
+SET is_controlled = $1,
+    schedule_class = $2
+WHERE id = $3
+RETURNING id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price
+`
+
+type SetProductControlledParams struct {
+	IsControlled  bool
+	ScheduleClass sql.NullString
+	ID            uuid.UUID
+}
+
+// Marks a product as a controlled substance of a schedule class, or clears
+// both
+func (q *Queries) SetProductControlled(ctx context.Context, arg SetProductControlledParams) (Product, error) {
+	row := q.db.QueryRow(ctx, setProductControlled, arg.IsControlled, arg.ScheduleClass, arg.ID)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Brand,
+		&i.DosageFormID,
+		&i.Strength,
+		&i.Unit,
+		&i.CategoryID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Status,
+		&i.Irc,
+		&i.GenericCode,
+		&i.TenantID,
+		&i.IsControlled,
+		&i.ScheduleClass,
+		&i.Price,
+	)
+	return i, err
+}
+
 const updateProduct = `-- name: UpdateProduct :one
+-- Changes the fields of a product. Name and status keep their value when
+-- NULL; each other field is set to the value given, NULL included, when
+-- its set_ flag is true and keeps its value otherwise.
 UPDATE products
-SET 
-    name = COALESCE($2, name),
-    brand = COALESCE($3, brand),
-    dosage_form_id = COALESCE($4, dosage_form_id),
-    strength = COALESCE($5, strength),
-    unit = COALESCE($6, unit),
-    category_id = COALESCE($7, category_id),
-    description = COALESCE($8, description)
-WHERE id = $1
-RETURNING id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at
+SET
+    name = COALESCE($1, name),
+    brand = CASE WHEN $2::boolean THEN $3 ELSE brand END,
+    dosage_form_id = CASE WHEN $4::boolean THEN $5 ELSE dosage_form_id END,
+    strength = CASE WHEN $6::boolean THEN $7 ELSE strength END,
+    unit = CASE WHEN $8::boolean THEN $9 ELSE unit END,
+    category_id = CASE WHEN $10::boolean THEN $11 ELSE category_id END,
+    description = CASE WHEN $12::boolean THEN $13 ELSE description END,
+    price = CASE WHEN $14::boolean THEN $15 ELSE price END,
+    status = COALESCE($16, status)
+WHERE id = $17
+RETURNING id, name, brand, dosage_form_id, strength, unit, category_id, description, created_at, deleted_at, status, irc, generic_code, tenant_id, is_controlled, schedule_class, price
 `
 
 type UpdateProductParams struct {
-	ID           uuid.UUID
-	Name         string
-	Brand        sql.NullString
-	DosageFormID sql.NullInt32
-	Strength     sql.NullString
-	Unit         sql.NullString
-	CategoryID   sql.NullInt32
-	Description  sql.NullString
+	Name            sql.NullString
+	SetBrand        bool
+	Brand           sql.NullString
+	SetDosageFormID bool
+	DosageFormID    sql.NullInt32
+	SetStrength     bool
+	Strength        sql.NullString
+	SetUnit         bool
+	Unit            sql.NullString
+	SetCategoryID   bool
+	CategoryID      sql.NullInt32
+	SetDescription  bool
+	Description     sql.NullString
+	SetPrice        bool
+	Price           sql.NullString
+	Status          sql.NullString
+	ID              uuid.UUID
 }
 
+// Changes the fields of a product. Name and status keep their value when
+// NULL; each other field is set to the value given, NULL included, when
+// its set_ flag is true and keeps its value otherwise.
 func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (Product, error) {
-	row := q.db.QueryRowContext(ctx, updateProduct,
-		arg.ID,
+	row := q.db.QueryRow(ctx, updateProduct,
 		arg.Name,
+		arg.SetBrand,
 		arg.Brand,
+		arg.SetDosageFormID,
 		arg.DosageFormID,
+		arg.SetStrength,
 		arg.Strength,
+		arg.SetUnit,
 		arg.Unit,
+		arg.SetCategoryID,
 		arg.CategoryID,
+		arg.SetDescription,
 		arg.Description,
+		arg.SetPrice,
+		arg.Price,
+		arg.Status,
+		arg.ID,
 	)
 	var i Product
 	err := row.Scan(
@@ -231,6 +460,13 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 		&i.Description,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Status,
+		&i.Irc,
+		&i.GenericCode,
+		&i.TenantID,
+		&i.IsControlled,
+		&i.ScheduleClass,
+		&i.Price,
 	)
 	return i, err
 }
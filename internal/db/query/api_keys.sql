@@ -0,0 +1,58 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (name, key_hash, key_prefix, role_id, department_id, scopes, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListAPIKeys :many
+-- Keys that have not been revoked, newest first
+SELECT * FROM api_keys
+WHERE revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: GetAPIKeyByHash :one
+-- The key with the name of its role and the user it acts for, so a key
+-- stops working once its creator is deleted
+SELECT
+    k.id,
+    k.tenant_id,
+    k.role_id,
+    k.department_id,
+    k.scopes,
+    k.expires_at,
+    k.revoked_at,
+    k.created_by,
+    u.username,
+    u.language,
+    r.name AS role_name
+FROM api_keys k
+JOIN users u ON u.id = k.created_by AND u.deleted_at IS NULL
+JOIN roles r ON r.id = k.role_id
+WHERE k.key_hash = $1;
+
+-- name: UpdateAPIKey :one
+UPDATE api_keys
+SET name = $2,
+    role_id = $3,
+    department_id = $4,
+    scopes = $5,
+    expires_at = $6,
+    updated_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchAPIKey :exec
+-- Records use at most once a minute to spare the row
+UPDATE api_keys
+SET last_used_at = NOW(), last_used_ip = $2
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
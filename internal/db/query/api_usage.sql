@@ -0,0 +1,40 @@
+-- name: AddAPIUsage :exec
+INSERT INTO api_usage_daily (
+    day, user_id, token_id, method, route, requests, client_errors, server_errors, total_ms, max_ms
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (day, user_id, COALESCE(token_id, '00000000-0000-0000-0000-000000000000'::uuid), method, route)
+DO UPDATE SET
+    requests = api_usage_daily.requests + EXCLUDED.requests,
+    client_errors = api_usage_daily.client_errors + EXCLUDED.client_errors,
+    server_errors = api_usage_daily.server_errors + EXCLUDED.server_errors,
+    total_ms = api_usage_daily.total_ms + EXCLUDED.total_ms,
+    max_ms = GREATEST(api_usage_daily.max_ms, EXCLUDED.max_ms);
+
+-- name: ReportAPIUsage :many
+-- Usage per day, consumer and route in [from_day, to_day]
+SELECT
+    a.day,
+    a.user_id,
+    COALESCE(u.username, '')::text AS username,
+    a.token_id,
+    COALESCE(t.name, '')::text AS token_name,
+    a.method,
+    a.route,
+    a.requests,
+    a.client_errors,
+    a.server_errors,
+    a.total_ms,
+    a.max_ms
+FROM api_usage_daily a
+LEFT JOIN users u ON u.id = a.user_id
+LEFT JOIN personal_access_tokens t ON t.id = a.token_id
+WHERE a.day BETWEEN @from_day::date AND @to_day::date
+  AND (sqlc.narg(user_id)::uuid IS NULL OR a.user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(token_id)::uuid IS NULL OR a.token_id = sqlc.narg(token_id)::uuid)
+  AND (sqlc.narg(route)::text IS NULL OR a.route = sqlc.narg(route)::text)
+ORDER BY a.day, a.user_id, a.token_id, a.method, a.route;
+
+-- name: DeleteAPIUsageBefore :execrows
+DELETE FROM api_usage_daily WHERE day < @before::date;
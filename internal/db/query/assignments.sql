@@ -0,0 +1,17 @@
+-- name: AssignOrder :one
+INSERT INTO order_assignments (order_id, user_id, assigned_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (order_id) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+    assigned_by = EXCLUDED.assigned_by,
+    assigned_at = NOW()
+RETURNING *;
+
+-- name: GetOrderAssignment :one
+SELECT * FROM order_assignments
+WHERE order_id = $1 LIMIT 1;
+
+-- name: UnassignOrder :one
+DELETE FROM order_assignments
+WHERE order_id = $1
+RETURNING *;
@@ -0,0 +1,40 @@
+-- name: GetProductImage :one
+SELECT * FROM product_images
+WHERE product_id = $1 LIMIT 1;
+
+-- name: UpsertProductImage :one
+INSERT INTO product_images (
+    product_id, object_key, content_type, size_bytes, uploaded_by
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (product_id) DO UPDATE
+SET object_key = EXCLUDED.object_key,
+    content_type = EXCLUDED.content_type,
+    size_bytes = EXCLUDED.size_bytes,
+    uploaded_by = EXCLUDED.uploaded_by,
+    uploaded_at = NOW()
+RETURNING *;
+
+-- name: DeleteProductImage :exec
+DELETE FROM product_images WHERE product_id = $1;
+
+-- name: CreateOrderAttachment :one
+INSERT INTO order_attachments (
+    order_id, object_key, filename, content_type, size_bytes, uploaded_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetOrderAttachment :one
+SELECT * FROM order_attachments
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOrderAttachments :many
+SELECT * FROM order_attachments
+WHERE order_id = $1
+ORDER BY created_at;
+
+-- name: DeleteOrderAttachment :exec
+DELETE FROM order_attachments WHERE id = $1;
@@ -0,0 +1,101 @@
+-- name: CreateBackup :one
+-- Fails on idx_backups_one_running while another backup runs
+INSERT INTO backups (
+    triggered_by
+) VALUES (
+    $1
+)
+RETURNING *;
+
+-- name: FinishBackup :one
+UPDATE backups
+SET status = $2,
+    finished_at = NOW(),
+    storage_key = $3,
+    size_bytes = $4,
+    checksum = $5,
+    error = $6
+WHERE id = $1
+RETURNING *;
+
+-- name: GetBackup :one
+SELECT * FROM backups
+WHERE id = $1 LIMIT 1;
+
+-- name: CountBackups :one
+SELECT COUNT(*) FROM backups;
+
+-- name: ListBackups :many
+SELECT * FROM backups
+ORDER BY started_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListExpiredBackups :many
+-- Completed backups beyond the newest keep_count
+SELECT * FROM backups
+WHERE status = 'completed'
+ORDER BY started_at DESC
+OFFSET @keep_count;
+
+-- name: DeleteBackup :exec
+DELETE FROM backups
+WHERE id = $1;
+
+-- name: StartBackupVerification :one
+-- Only completed backups can be verified
+UPDATE backups
+SET verify_status = 'running',
+    verify_error = NULL
+WHERE id = $1 AND status = 'completed'
+RETURNING *;
+
+-- name: FinishBackupVerification :one
+UPDATE backups
+SET verify_status = $2,
+    verify_error = $3,
+    verified_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: FailInterruptedBackups :exec
+-- Run at startup: backups still marked running were cut off by a restart
+UPDATE backups
+SET status = 'failed',
+    finished_at = NOW(),
+    error = 'interrupted by a restart'
+WHERE status = 'running';
+
+-- name: FailInterruptedBackupVerifications :exec
+UPDATE backups
+SET verify_status = 'failed',
+    verify_error = 'interrupted by a restart',
+    verified_at = NOW()
+WHERE verify_status = 'running';
+
+-- name: CreateBackupRestore :one
+INSERT INTO backup_restores (
+    backup_id, triggered_by
+) VALUES (
+    $1, $2
+)
+RETURNING *;
+
+-- name: FinishBackupRestore :one
+UPDATE backup_restores
+SET status = $2,
+    finished_at = NOW(),
+    error = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: ListBackupRestores :many
+SELECT * FROM backup_restores
+WHERE backup_id = $1
+ORDER BY started_at DESC;
+
+-- name: FailInterruptedBackupRestores :exec
+UPDATE backup_restores
+SET status = 'failed',
+    finished_at = NOW(),
+    error = 'interrupted by a restart'
+WHERE status = 'running';
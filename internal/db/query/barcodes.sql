@@ -36,4 +36,10 @@ DELETE FROM product_barcodes WHERE id = $1;
 SELECT * FROM product_barcodes
 WHERE barcode ILIKE '%' || $1 || '%'
 ORDER BY barcode
-LIMIT $2 OFFSET $3;
\ No newline at end of file
+LIMIT $2 OFFSET $3;
+
+-- name: ListBarcodesByProducts :many
+-- Barcodes of many products in one query, for ?include=barcodes
+SELECT * FROM product_barcodes
+WHERE product_id = ANY(@product_ids::uuid[])
+ORDER BY product_id, created_at DESC;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: UpsertCalendarFeed :one
+INSERT INTO calendar_feeds (user_id, token_hash)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = EXCLUDED.token_hash,
+    created_at = NOW(),
+    last_fetched_at = NULL
+RETURNING *;
+
+-- name: GetCalendarFeed :one
+SELECT * FROM calendar_feeds
+WHERE user_id = $1 LIMIT 1;
+
+-- name: DeleteCalendarFeed :execrows
+DELETE FROM calendar_feeds WHERE user_id = $1;
+
+-- name: GetCalendarFeedUser :one
+SELECT f.user_id, u.username
+FROM calendar_feeds f
+JOIN users u ON u.id = f.user_id
+WHERE f.token_hash = $1 AND u.deleted_at IS NULL
+LIMIT 1;
+
+-- name: TouchCalendarFeed :exec
+UPDATE calendar_feeds
+SET last_fetched_at = NOW()
+WHERE user_id = $1;
@@ -0,0 +1,47 @@
+-- name: CountControlledOrderItems :one
+-- Number of items of an order whose product is a controlled substance
+SELECT COUNT(*) FROM order_items oi
+JOIN products p ON p.id = oi.product_id
+WHERE oi.order_id = @order_id AND p.is_controlled;
+
+-- name: CreateControlledApproval :exec
+-- Records the second-person approval of an order; an order already
+-- approved keeps its first approval
+INSERT INTO controlled_approvals (order_id, approved_by, note)
+VALUES (@order_id, @approved_by, sqlc.narg(note))
+ON CONFLICT (order_id) DO NOTHING;
+
+-- name: GetControlledApproval :one
+SELECT * FROM controlled_approvals
+WHERE order_id = $1;
+
+-- name: DeleteControlledApproval :exec
+DELETE FROM controlled_approvals WHERE order_id = $1;
+
+-- name: ListControlledRegister :many
+-- The controlled items of orders approved in [from_time, to_time), with who
+-- ordered and who approved them, oldest approval first
+SELECT
+    ca.approved_at,
+    o.id AS order_id,
+    o.status AS order_status,
+    oi.id AS order_item_id,
+    p.id AS product_id,
+    p.name AS product_name,
+    p.strength,
+    p.schedule_class,
+    oi.requested_qty,
+    oi.unit,
+    oi.note AS reason,
+    cu.username AS requested_by,
+    au.username AS approved_by,
+    ca.note AS approval_note
+FROM controlled_approvals ca
+JOIN orders o ON o.id = ca.order_id
+JOIN order_items oi ON oi.order_id = o.id
+JOIN products p ON p.id = oi.product_id
+LEFT JOIN users cu ON cu.id = o.created_by
+LEFT JOIN users au ON au.id = ca.approved_by
+WHERE p.is_controlled
+  AND ca.approved_at >= @from_time AND ca.approved_at < @to_time
+ORDER BY ca.approved_at, o.id, p.name;
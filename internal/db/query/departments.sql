@@ -0,0 +1,22 @@
+-- name: CreateDepartment :one
+INSERT INTO departments (name)
+VALUES ($1)
+RETURNING *;
+
+-- name: GetDepartment :one
+SELECT * FROM departments
+WHERE id = $1;
+
+-- name: ListDepartments :many
+SELECT * FROM departments
+ORDER BY name;
+
+-- name: UpdateDepartment :one
+UPDATE departments
+SET name = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDepartment :execrows
+DELETE FROM departments
+WHERE id = $1;
@@ -0,0 +1,54 @@
+-- name: RegisterDeviceToken :one
+-- A token moves to whoever signs in on the device last
+INSERT INTO device_tokens (user_id, token, platform, name)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (token) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+    platform = EXCLUDED.platform,
+    name = EXCLUDED.name,
+    last_seen_at = NOW()
+RETURNING *;
+
+-- name: ListDeviceTokens :many
+SELECT * FROM device_tokens
+WHERE user_id = $1
+ORDER BY last_seen_at DESC;
+
+-- name: DeleteDeviceToken :execrows
+DELETE FROM device_tokens
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteDeviceTokenByValue :exec
+DELETE FROM device_tokens
+WHERE token = $1;
+
+-- name: ListPushTokensByRole :many
+SELECT d.token
+FROM device_tokens d
+JOIN users u ON u.id = d.user_id
+JOIN roles r ON r.id = u.role_id
+WHERE u.deleted_at IS NULL
+  AND r.name = ANY(@role_names::text[])
+  AND NOT EXISTS (
+      SELECT 1 FROM notification_preferences p
+      WHERE p.user_id = u.id
+        AND p.event_type = @event_type
+        AND p.channel = 'push'
+        AND NOT p.enabled
+  )
+ORDER BY d.token;
+
+-- name: ListPushTokensForUser :many
+SELECT d.token
+FROM device_tokens d
+JOIN users u ON u.id = d.user_id
+WHERE u.id = @user_id
+  AND u.deleted_at IS NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM notification_preferences p
+      WHERE p.user_id = u.id
+        AND p.event_type = @event_type
+        AND p.channel = 'push'
+        AND NOT p.enabled
+  )
+ORDER BY d.token;
@@ -0,0 +1,77 @@
+-- name: GetProductByIRC :one
+SELECT * FROM products
+WHERE irc = @irc::text
+LIMIT 1;
+
+-- name: FindProductsByName :many
+-- Candidates for a registry entry without IRC or barcode match. strength is
+-- lower-cased without spaces; an empty strength matches any.
+SELECT * FROM products
+WHERE deleted_at IS NULL
+  AND lower(name) = ANY(@names::text[])
+  AND (@strength::text = '' OR lower(replace(COALESCE(strength, ''), ' ', '')) = @strength::text)
+ORDER BY created_at
+LIMIT 5;
+
+-- name: CreateStagingProduct :one
+INSERT INTO products (
+    name, brand, dosage_form_id, strength, unit, description, irc, generic_code, status
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, 'staging'
+)
+RETURNING *;
+
+-- name: SetProductRegistryCodes :one
+UPDATE products
+SET irc = @irc::text,
+    generic_code = COALESCE(sqlc.narg(generic_code), generic_code)
+WHERE id = @id
+RETURNING *;
+
+-- name: CreateDrugRegistrySync :one
+INSERT INTO drug_registry_syncs (
+    source, triggered_by
+) VALUES (
+    $1, $2
+)
+RETURNING *;
+
+-- name: FinishDrugRegistrySync :one
+UPDATE drug_registry_syncs
+SET status = $2,
+    finished_at = NOW(),
+    total_entries = $3,
+    matched = $4,
+    created = $5,
+    ambiguous = $6,
+    conflicts = $7,
+    failed = $8,
+    error = $9
+WHERE id = $1
+RETURNING *;
+
+-- name: GetDrugRegistrySync :one
+SELECT * FROM drug_registry_syncs
+WHERE id = $1 LIMIT 1;
+
+-- name: CountDrugRegistrySyncs :one
+SELECT COUNT(*) FROM drug_registry_syncs;
+
+-- name: ListDrugRegistrySyncs :many
+SELECT * FROM drug_registry_syncs
+ORDER BY started_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: CreateDrugRegistrySyncItem :exec
+INSERT INTO drug_registry_sync_items (
+    sync_id, irc, generic_code, name, gtin, outcome, product_id, detail
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: ListDrugRegistrySyncItems :many
+SELECT * FROM drug_registry_sync_items
+WHERE sync_id = @sync_id
+  AND (sqlc.narg(outcome)::text IS NULL OR outcome = sqlc.narg(outcome)::text)
+ORDER BY id
+LIMIT @limit_count OFFSET @offset_count;
@@ -0,0 +1,78 @@
+-- name: ListERPOrderRows :many
+-- One row per item of the oldest orders in the given statuses that no
+-- delivered batch holds, at most limit_count orders
+SELECT
+    o.id AS order_id,
+    o.status,
+    o.priority,
+    o.notes,
+    o.created_at,
+    o.submitted_at,
+    u.username,
+    oi.id AS item_id,
+    oi.requested_qty,
+    oi.unit AS item_unit,
+    oi.note AS item_note,
+    p.id AS product_id,
+    p.name AS product_name,
+    p.brand,
+    p.strength,
+    p.unit AS product_unit,
+    p.irc,
+    p.generic_code,
+    (SELECT pb.barcode FROM product_barcodes pb
+     WHERE pb.product_id = p.id
+     ORDER BY pb.created_at LIMIT 1) AS barcode
+FROM orders o
+JOIN order_items oi ON oi.order_id = o.id
+LEFT JOIN users u ON u.id = o.created_by
+LEFT JOIN products p ON p.id = oi.product_id
+WHERE o.id IN (
+    SELECT po.id FROM orders po
+    WHERE po.deleted_at IS NULL
+      AND po.status = ANY(@statuses::text[])
+      AND EXISTS (SELECT 1 FROM order_items i WHERE i.order_id = po.id)
+      AND NOT EXISTS (
+          SELECT 1 FROM erp_batch_orders bo
+          JOIN erp_batches b ON b.id = bo.batch_id
+          WHERE bo.order_id = po.id AND b.status = 'delivered'
+      )
+    ORDER BY po.created_at, po.id
+    LIMIT @limit_count
+)
+ORDER BY o.created_at, o.id, oi.id;
+
+-- name: CreateERPBatch :one
+INSERT INTO erp_batches (mode, format, order_count, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: AddERPBatchOrders :exec
+INSERT INTO erp_batch_orders (batch_id, order_id)
+SELECT @batch_id::uuid, unnest(@order_ids::uuid[]);
+
+-- name: FinishERPBatch :one
+-- Only pending batches are finished; no row means it was already settled
+UPDATE erp_batches
+SET status = @status,
+    error = sqlc.narg(error),
+    delivered_at = CASE WHEN @status::text = 'delivered' THEN NOW() END
+WHERE id = @id AND status = 'pending'
+RETURNING *;
+
+-- name: GetERPBatch :one
+SELECT * FROM erp_batches
+WHERE id = $1 LIMIT 1;
+
+-- name: CountERPBatches :one
+SELECT COUNT(*) FROM erp_batches;
+
+-- name: ListERPBatches :many
+SELECT * FROM erp_batches
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListERPBatchOrderIDs :many
+SELECT order_id FROM erp_batch_orders
+WHERE batch_id = $1
+ORDER BY order_id;
@@ -0,0 +1,55 @@
+-- name: CreateExportFile :one
+INSERT INTO export_files (
+    kind, object_key, filename, content_type, size_bytes, created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetExportFile :one
+SELECT * FROM export_files
+WHERE id = $1 LIMIT 1;
+
+-- name: CountExportFiles :one
+SELECT COUNT(*) FROM export_files;
+
+-- name: ListExportFiles :many
+SELECT * FROM export_files
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListOrderExportRows :many
+-- One row per order item (or per order without items) of the first
+-- order_limit orders created in [from_time, to_time), oldest first;
+-- batches after the first seek past the keyset cursor (after_time, after_id)
+WITH batch AS (
+    SELECT * FROM orders
+    WHERE deleted_at IS NULL
+      AND created_at >= @from_time::timestamptz
+      AND created_at < @to_time::timestamptz
+      AND (sqlc.narg(after_time)::timestamptz IS NULL
+        OR (created_at, id) > (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+    ORDER BY created_at, id
+    LIMIT @order_limit
+)
+SELECT
+    o.id AS order_id,
+    o.status,
+    o.priority,
+    o.created_at,
+    o.submitted_at,
+    u.username,
+    r.kind AS requester_kind,
+    r.name AS requester_name,
+    oi.id AS item_id,
+    p.name AS product_name,
+    p.strength,
+    oi.requested_qty,
+    oi.unit,
+    oi.note
+FROM batch o
+LEFT JOIN users u ON u.id = o.created_by
+LEFT JOIN requesters r ON r.id = o.requester_id
+LEFT JOIN order_items oi ON oi.order_id = o.id
+LEFT JOIN products p ON p.id = oi.product_id
+ORDER BY o.created_at, o.id, oi.id;
@@ -0,0 +1,19 @@
+-- name: EnsureFHIRResourceIDs :many
+-- Returns the FHIR ID of every local ID, assigning one where missing. The
+-- final SELECT does not see rows inserted by the CTE, so each ID appears once.
+WITH inserted AS (
+    INSERT INTO fhir_resource_ids (resource_type, local_id)
+    SELECT @resource_type::text, unnest(@local_ids::text[])
+    ON CONFLICT (resource_type, local_id) DO NOTHING
+    RETURNING local_id, fhir_id
+)
+SELECT local_id, fhir_id FROM inserted
+UNION ALL
+SELECT local_id, fhir_id FROM fhir_resource_ids
+WHERE resource_type = @resource_type::text
+  AND local_id = ANY(@local_ids::text[]);
+
+-- name: GetFHIRResourceLocalID :one
+SELECT local_id FROM fhir_resource_ids
+WHERE resource_type = $1 AND fhir_id = $2
+LIMIT 1;
@@ -0,0 +1,55 @@
+-- name: ListUnencryptedUserNames :many
+-- Users, in id order after after_id, whose full name is not encrypted
+-- under the key values with the given prefix are
+SELECT id, full_name FROM users
+WHERE id > @after_id
+  AND full_name IS NOT NULL
+  AND NOT starts_with(full_name, @prefix::text)
+ORDER BY id
+LIMIT @limit_count;
+
+-- name: ReencryptUserName :exec
+UPDATE users SET full_name = @full_name
+WHERE id = @id;
+
+-- name: ListUnencryptedAuditClients :many
+-- Audit log entries, in id order after after_id, whose address or user
+-- agent is not encrypted under the key values with the given prefix are
+SELECT id, ip_address, user_agent FROM audit_logs
+WHERE id > @after_id
+  AND ((ip_address IS NOT NULL AND NOT starts_with(ip_address, @prefix::text))
+    OR (user_agent IS NOT NULL AND NOT starts_with(user_agent, @prefix::text)))
+ORDER BY id
+LIMIT @limit_count;
+
+-- name: ReencryptAuditClient :exec
+UPDATE audit_logs SET ip_address = @ip_address, user_agent = @user_agent
+WHERE id = @id;
+
+-- name: ListUnencryptedLoginDevices :many
+-- Login attempts, in id order after after_id, whose device info is not
+-- encrypted under the key values with the given prefix are
+SELECT id, device_info FROM login_attempts_log
+WHERE id > @after_id
+  AND device_info IS NOT NULL
+  AND NOT (jsonb_typeof(device_info) = 'string' AND starts_with(device_info #>> '{}', @prefix::text))
+ORDER BY id
+LIMIT @limit_count;
+
+-- name: ReencryptLoginDevice :exec
+UPDATE login_attempts_log SET device_info = @device_info
+WHERE id = @id;
+
+-- name: ListUnencryptedRequesters :many
+-- Requesters, in id order after after_id, whose name or reference is not
+-- encrypted under the key values with the given prefix are
+SELECT id, name, reference FROM requesters
+WHERE id > @after_id
+  AND (NOT starts_with(name, @prefix::text)
+    OR (reference IS NOT NULL AND NOT starts_with(reference, @prefix::text)))
+ORDER BY id
+LIMIT @limit_count;
+
+-- name: ReencryptRequester :exec
+UPDATE requesters SET name = @name, reference = @reference
+WHERE id = @id;
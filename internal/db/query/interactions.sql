@@ -0,0 +1,66 @@
+-- name: CreateDrugClasses :exec
+-- Adds many ATC codes in one statement; the arrays are read side by side
+INSERT INTO drug_classes (generic_code, atc_code)
+SELECT c.generic_code, c.atc_code
+FROM unnest(@generic_codes::text[], @atc_codes::text[]) AS c(generic_code, atc_code);
+
+-- name: CreateDrugInteractions :exec
+-- Adds many interactions in one statement; the arrays are read side by side
+INSERT INTO drug_interactions (generic_code_a, generic_code_b, severity, description)
+SELECT i.generic_code_a, i.generic_code_b, i.severity, i.description
+FROM unnest(@generic_codes_a::text[], @generic_codes_b::text[], @severities::text[], @descriptions::text[])
+    AS i(generic_code_a, generic_code_b, severity, description);
+
+-- name: CreateOrderWarning :exec
+-- Stores a warning unless the same one is already stored
+INSERT INTO order_warnings (
+    order_id, order_item_id, other_order_item_id, kind, severity, message
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (order_item_id, other_order_item_id, kind) DO NOTHING;
+
+-- name: DeleteDrugClasses :exec
+DELETE FROM drug_classes;
+
+-- name: DeleteDrugInteractions :exec
+DELETE FROM drug_interactions;
+
+-- name: ListDrugClasses :many
+-- The ATC codes of those of the generics that have one
+SELECT generic_code, atc_code FROM drug_classes
+WHERE generic_code = ANY(@generic_codes::text[]);
+
+-- name: ListDrugInteractions :many
+-- The interactions between any two of the generics
+SELECT generic_code_a, generic_code_b, severity, description FROM drug_interactions
+WHERE generic_code_a = ANY(@generic_codes::text[])
+  AND generic_code_b = ANY(@generic_codes::text[]);
+
+-- name: ListOrderItemProducts :many
+-- The product of each item of an order, for interaction checks
+SELECT i.id, i.product_id, p.name, p.strength, p.generic_code
+FROM order_items i
+JOIN products p ON p.id = i.product_id
+WHERE i.order_id = $1
+ORDER BY i.id;
+
+-- name: ListOrderWarnings :many
+-- The warnings on an order, most severe first, with the products of both
+-- items
+SELECT w.id, w.order_item_id, i.product_id, p.name AS product_name,
+       w.other_order_item_id, oi.product_id AS other_product_id, op.name AS other_product_name,
+       w.kind, w.severity, w.message, w.created_at
+FROM order_warnings w
+JOIN order_items i ON i.id = w.order_item_id
+JOIN products p ON p.id = i.product_id
+JOIN order_items oi ON oi.id = w.other_order_item_id
+JOIN products op ON op.id = oi.product_id
+WHERE w.order_id = $1
+ORDER BY CASE w.severity
+        WHEN 'contraindicated' THEN 0
+        WHEN 'major' THEN 1
+        WHEN 'moderate' THEN 2
+        ELSE 3
+    END,
+    w.created_at, w.id;
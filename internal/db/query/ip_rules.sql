@@ -0,0 +1,42 @@
+-- name: CreateIPRule :one
+INSERT INTO ip_rules (cidr, action, reason, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetIPRule :one
+SELECT * FROM ip_rules
+WHERE id = $1;
+
+-- name: ListIPRules :many
+-- Every rule, expired ones included, newest first
+SELECT * FROM ip_rules
+ORDER BY created_at DESC, id DESC;
+
+-- name: ListActiveIPRules :many
+-- The rules the rate limiter enforces
+SELECT * FROM ip_rules
+WHERE expires_at IS NULL OR expires_at > NOW();
+
+-- name: UpdateIPRule :one
+UPDATE ip_rules
+SET cidr = $2,
+    action = $3,
+    reason = $4,
+    expires_at = $5,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteIPRule :execrows
+DELETE FROM ip_rules
+WHERE id = $1;
+
+-- name: DeleteIPBans :execrows
+-- Lifts the bans the rate limiter imposed on an address; rules admins
+-- created stay
+DELETE FROM ip_rules
+WHERE cidr = $1 AND action = 'deny' AND created_by IS NULL;
+
+-- name: DeleteExpiredIPRules :execrows
+DELETE FROM ip_rules
+WHERE expires_at < @before::timestamptz;
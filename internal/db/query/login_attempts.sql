@@ -3,7 +3,8 @@
 -- name: LogLoginAttempt :one
 INSERT INTO login_attempts_log (
     username, ip_address, user_agent, success, failure_reason, 
-    rate_limited, session_id, device_info
+    rate_limited, session_id, device_info, country, city, asn, as_org,
+    latitude, longitude, device_fingerprint
 )
 VALUES (
     sqlc.arg('username'), 
@@ -13,7 +14,14 @@ VALUES (
     sqlc.arg('failure_reason'),
     sqlc.arg('rate_limited'), 
     sqlc.arg('session_id'), 
-    sqlc.arg('device_info')
+    sqlc.arg('device_info'),
+    sqlc.arg('country'),
+    sqlc.arg('city'),
+    sqlc.arg('asn'),
+    sqlc.arg('as_org'),
+    sqlc.arg('latitude'),
+    sqlc.arg('longitude'),
+    sqlc.arg('device_fingerprint')
 )
 RETURNING *;
 
@@ -71,6 +79,15 @@ SELECT * FROM currently_blocked_ips;
 -- name: GetLoginAttemptStats :many
 SELECT * FROM login_attempt_stats;
 
+-- name: GetLoginCountryCounts :one
+-- Successful logins of a user with a known country, and those from country
+SELECT
+    COUNT(*) FILTER (WHERE country IS NOT NULL) AS located_logins,
+    COUNT(*) FILTER (WHERE country = sqlc.arg('country')) AS country_logins
+FROM login_attempts_log
+WHERE username = sqlc.arg('username')
+  AND success = true;
+
 -- name: CountFailedAttempts :one
 SELECT COUNT(*) FROM login_attempts_log
 WHERE ip_address = sqlc.arg('ip_address')
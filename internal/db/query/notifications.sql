@@ -0,0 +1,74 @@
+-- name: GetNotificationSettings :one
+SELECT * FROM user_notification_settings
+WHERE user_id = $1 LIMIT 1;
+
+-- name: UpsertNotificationSettings :one
+INSERT INTO user_notification_settings (user_id, email, phone)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email,
+    phone = EXCLUDED.phone,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: ListNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1
+ORDER BY event_type, channel;
+
+-- name: UpsertNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, event_type, channel, enabled)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, event_type, channel) DO UPDATE
+SET enabled = EXCLUDED.enabled,
+    updated_at = NOW();
+
+-- name: ListEmailRecipientsByRole :many
+SELECT u.id, u.username, u.full_name, s.email
+FROM users u
+JOIN roles r ON r.id = u.role_id
+JOIN user_notification_settings s ON s.user_id = u.id
+WHERE u.deleted_at IS NULL
+  AND r.name = ANY(@role_names::text[])
+  AND COALESCE(s.email, '') <> ''
+  AND NOT EXISTS (
+      SELECT 1 FROM notification_preferences p
+      WHERE p.user_id = u.id
+        AND p.event_type = @event_type
+        AND p.channel = 'email'
+        AND NOT p.enabled
+  )
+ORDER BY u.username;
+
+-- name: GetEmailRecipient :one
+SELECT u.id, u.username, u.full_name, s.email
+FROM users u
+JOIN user_notification_settings s ON s.user_id = u.id
+WHERE u.id = @user_id
+  AND u.deleted_at IS NULL
+  AND COALESCE(s.email, '') <> ''
+  AND NOT EXISTS (
+      SELECT 1 FROM notification_preferences p
+      WHERE p.user_id = u.id
+        AND p.event_type = @event_type
+        AND p.channel = 'email'
+        AND NOT p.enabled
+  )
+LIMIT 1;
+
+-- name: ListSMSRecipientsByRole :many
+SELECT u.id, u.username, u.full_name, s.phone
+FROM users u
+JOIN roles r ON r.id = u.role_id
+JOIN user_notification_settings s ON s.user_id = u.id
+WHERE u.deleted_at IS NULL
+  AND r.name = ANY(@role_names::text[])
+  AND COALESCE(s.phone, '') <> ''
+  AND NOT EXISTS (
+      SELECT 1 FROM notification_preferences p
+      WHERE p.user_id = u.id
+        AND p.event_type = @event_type
+        AND p.channel = 'sms'
+        AND NOT p.enabled
+  )
+ORDER BY u.username;
@@ -0,0 +1,18 @@
+-- name: CreateOrderCancellation :one
+-- Records why an order was cancelled, replacing the reason of an earlier
+-- cancellation
+INSERT INTO order_cancellations (order_id, reason, note, cancelled_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (order_id) DO UPDATE
+SET reason = EXCLUDED.reason,
+    note = EXCLUDED.note,
+    cancelled_by = EXCLUDED.cancelled_by,
+    cancelled_at = NOW()
+RETURNING *;
+
+-- name: GetOrderCancellation :one
+SELECT * FROM order_cancellations
+WHERE order_id = $1;
+
+-- name: DeleteOrderCancellation :exec
+DELETE FROM order_cancellations WHERE order_id = $1;
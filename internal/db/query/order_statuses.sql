@@ -0,0 +1,22 @@
+-- name: CreateOrderStatus :one
+INSERT INTO order_statuses (code, label, sort_order)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetOrderStatus :one
+SELECT * FROM order_statuses
+WHERE code = $1;
+
+-- name: ListOrderStatuses :many
+SELECT * FROM order_statuses
+ORDER BY sort_order, code;
+
+-- name: RelabelOrderStatus :one
+UPDATE order_statuses
+SET label = $2, sort_order = $3
+WHERE code = $1
+RETURNING *;
+
+-- name: DeleteOrderStatus :execrows
+DELETE FROM order_statuses
+WHERE code = $1;
@@ -1,8 +1,8 @@
 -- name: CreateOrder :one
 INSERT INTO orders (
-    created_by, status, notes
+    created_by, status, notes, priority, needed_by, requester_id, department_id
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, (SELECT department_id FROM users WHERE id = $1)
 )
 RETURNING *;
 
@@ -10,17 +10,78 @@ RETURNING *;
 SELECT * FROM orders
 WHERE id = $1 LIMIT 1;
 
+-- name: GetOrderForUpdate :one
+-- Locks the order until the transaction ends, so that checks made on it
+-- hold for the writes that follow
+SELECT * FROM orders
+WHERE id = $1
+FOR UPDATE;
+
 -- name: ListOrders :many
 SELECT * FROM orders
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $1 OFFSET $2;
 
 -- name: ListOrdersByUser :many
 SELECT * FROM orders
 WHERE created_by = $1
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT $2 OFFSET $3;
 
+-- name: SearchOrders :many
+-- Orders matching every filter that is set: created in [from_time,
+-- to_time), by created_by, for requester_id, in one of statuses, holding
+-- an item for product_id, and with query in their notes or in the name,
+-- brand or note of an item, compared after normalize_search. Pages after
+-- the first seek past the keyset cursor (after_time, after_id).
+SELECT o.* FROM orders o
+WHERE (sqlc.narg(from_time)::timestamptz IS NULL OR o.created_at >= sqlc.narg(from_time)::timestamptz)
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR o.created_at < sqlc.narg(to_time)::timestamptz)
+  AND (sqlc.narg(created_by)::uuid IS NULL OR o.created_by = sqlc.narg(created_by)::uuid)
+  AND (sqlc.narg(requester_id)::uuid IS NULL OR o.requester_id = sqlc.narg(requester_id)::uuid)
+  AND (cardinality(@statuses::text[]) = 0 OR o.status = ANY(@statuses::text[]))
+  AND (sqlc.narg(product_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM order_items pi
+        WHERE pi.order_id = o.id AND pi.product_id = sqlc.narg(product_id)::uuid
+    ))
+  AND (@query::text = ''
+    OR normalize_search(COALESCE(o.notes, '')) LIKE '%' || normalize_search(@query::text) || '%'
+    OR EXISTS (
+        SELECT 1 FROM order_items i
+        LEFT JOIN products p ON p.id = i.product_id
+        WHERE i.order_id = o.id
+          AND (normalize_search(COALESCE(p.name, '')) LIKE '%' || normalize_search(@query::text) || '%'
+            OR normalize_search(COALESCE(p.brand, '')) LIKE '%' || normalize_search(@query::text) || '%'
+            OR normalize_search(COALESCE(i.note, '')) LIKE '%' || normalize_search(@query::text) || '%')
+    ))
+  AND (sqlc.narg(after_time)::timestamptz IS NULL
+    OR (o.created_at, o.id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY /* sort */ o.created_at DESC, o.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountSearchOrders :one
+-- Number of orders SearchOrders lists with the same filters
+SELECT COUNT(*) FROM orders o
+WHERE (sqlc.narg(from_time)::timestamptz IS NULL OR o.created_at >= sqlc.narg(from_time)::timestamptz)
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR o.created_at < sqlc.narg(to_time)::timestamptz)
+  AND (sqlc.narg(created_by)::uuid IS NULL OR o.created_by = sqlc.narg(created_by)::uuid)
+  AND (sqlc.narg(requester_id)::uuid IS NULL OR o.requester_id = sqlc.narg(requester_id)::uuid)
+  AND (cardinality(@statuses::text[]) = 0 OR o.status = ANY(@statuses::text[]))
+  AND (sqlc.narg(product_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM order_items pi
+        WHERE pi.order_id = o.id AND pi.product_id = sqlc.narg(product_id)::uuid
+    ))
+  AND (@query::text = ''
+    OR normalize_search(COALESCE(o.notes, '')) LIKE '%' || normalize_search(@query::text) || '%'
+    OR EXISTS (
+        SELECT 1 FROM order_items i
+        LEFT JOIN products p ON p.id = i.product_id
+        WHERE i.order_id = o.id
+          AND (normalize_search(COALESCE(p.name, '')) LIKE '%' || normalize_search(@query::text) || '%'
+            OR normalize_search(COALESCE(p.brand, '')) LIKE '%' || normalize_search(@query::text) || '%'
+            OR normalize_search(COALESCE(i.note, '')) LIKE '%' || normalize_search(@query::text) || '%')
+    ));
+
 -- name: UpdateOrderStatus :one
 UPDATE orders
 SET 
@@ -29,30 +90,135 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateOrderNeededBy :one
+UPDATE orders
+SET needed_by = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetOrderRequester :one
+UPDATE orders
+SET requester_id = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
 -- name: DeleteOrder :exec
 DELETE FROM orders WHERE id = $1;
 
 -- name: CreateOrderItem :one
 INSERT INTO order_items (
-    order_id, product_id, requested_qty, unit, note
+    order_id, product_id, requested_qty, unit, note, unit_price
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 )
 RETURNING *;
 
+-- name: CreateOrderItems :many
+-- Adds many items to an order in one statement. The arrays are read side
+-- by side, one item per position; empty units, notes and unit prices are
+-- stored as NULL.
+INSERT INTO order_items (order_id, product_id, requested_qty, unit, note, unit_price)
+SELECT @order_id::uuid, i.product_id, i.requested_qty, NULLIF(i.unit, ''), NULLIF(i.note, ''),
+    NULLIF(i.unit_price, '')::numeric
+FROM unnest(@product_ids::uuid[], @requested_qtys::int4[], @units::text[], @notes::text[], @unit_prices::text[])
+    AS i(product_id, requested_qty, unit, note, unit_price)
+RETURNING *;
+
+-- name: GetOrderItem :one
+SELECT * FROM order_items
+WHERE id = $1 LIMIT 1;
+
 -- name: GetOrderItems :many
 SELECT * FROM order_items
 WHERE order_id = $1
 ORDER BY id;
 
+-- name: ListOrderItems :many
+-- A page of an order's items
+SELECT * FROM order_items
+WHERE order_id = $1
+ORDER BY id
+LIMIT $2 OFFSET $3;
+
+-- name: CountOrderItems :one
+SELECT COUNT(*) FROM order_items
+WHERE order_id = $1;
+
+-- name: OrderHasProduct :one
+-- Whether the order already has an item for the product
+SELECT EXISTS(
+    SELECT 1 FROM order_items
+    WHERE order_id = @order_id AND product_id = @product_id
+) AS has_product;
+
+-- name: SetOrderItemSupplier :one
+-- Routes an item of an order to a supplier, or unroutes it with NULL
+UPDATE order_items
+SET supplier_id = sqlc.narg(supplier_id)
+WHERE id = @id AND order_id = @order_id
+RETURNING *;
+
 -- name: UpdateOrderItem :one
+-- Changes an order item. The quantity keeps its value when NULL; unit,
+-- note and unit price are set to the value given, NULL included, when
+-- their set_ flag is true and keep their value otherwise.
 UPDATE order_items
-SET 
-    requested_qty = COALESCE($2, requested_qty),
-    unit = COALESCE($3, unit),
-    note = COALESCE($4, note)
-WHERE id = $1
+SET
+    requested_qty = COALESCE(sqlc.narg(requested_qty), requested_qty),
+    unit = CASE WHEN @set_unit::boolean THEN sqlc.narg(unit) ELSE unit END,
+    note = CASE WHEN @set_note::boolean THEN sqlc.narg(note) ELSE note END,
+    unit_price = CASE WHEN @set_unit_price::boolean THEN sqlc.narg(unit_price) ELSE unit_price END
+WHERE id = @id
 RETURNING *;
 
 -- name: DeleteOrderItem :exec
-DELETE FROM order_items WHERE id = $1;
\ No newline at end of file
+DELETE FROM order_items WHERE id = $1;
+
+-- name: RecalculateOrderTotals :one
+-- Sets an order's subtotal to the sum of its line totals, and its total to
+-- the subtotal with tax_percent added, rounded to the cent
+UPDATE orders o
+SET subtotal = t.subtotal,
+    total = ROUND(t.subtotal * (1 + @tax_percent::numeric / 100), 2)
+FROM (
+    SELECT COALESCE(SUM(line_total), 0) AS subtotal
+    FROM order_items
+    WHERE order_id = @order_id::uuid
+) t
+WHERE o.id = @order_id::uuid
+RETURNING o.*;
+-- name: ListOrderItemsByOrders :many
+-- Items of many orders in one query, for ?include=items
+SELECT * FROM order_items
+WHERE order_id = ANY(@order_ids::uuid[])
+ORDER BY order_id, id;
+
+-- name: ListOrderItemsWithProducts :many
+-- Items of many orders with their product names, for the CSV export
+SELECT i.id, i.order_id, p.name AS product_name, p.strength, i.requested_qty,
+       i.unit, i.unit_price, i.line_total, i.note
+FROM order_items i
+LEFT JOIN products p ON p.id = i.product_id
+WHERE i.order_id = ANY(@order_ids::uuid[])
+ORDER BY i.order_id, i.id;
+
+-- name: ListOrderCreators :many
+-- The users who created the orders, for ?include=creator
+SELECT o.id AS order_id, u.id AS user_id, u.username, u.full_name
+FROM orders o
+JOIN users u ON u.id = o.created_by
+WHERE o.id = ANY(@order_ids::uuid[]);
+
+-- name: CreateOrderStatusChange :exec
+-- Records a status change in the order's history
+INSERT INTO order_status_history (order_id, old_status, new_status, changed_by, note)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListOrderStatusChanges :many
+-- The status history of an order, oldest first
+SELECT h.id, h.order_id, h.old_status, h.new_status, h.changed_by,
+       u.username AS changed_by_username, h.note, h.changed_at
+FROM order_status_history h
+LEFT JOIN users u ON u.id = h.changed_by
+WHERE h.order_id = $1
+ORDER BY h.changed_at, h.id;
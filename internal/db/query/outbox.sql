@@ -0,0 +1,43 @@
+-- name: InsertOutboxEvent :exec
+INSERT INTO outbox_events (
+    id, event_type, aggregate_type, aggregate_id, payload
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: ClaimOutboxEvents :many
+-- Leases a batch of due events. Rows locked by another relay are skipped and
+-- the lease keeps them from being claimed again while they are published.
+UPDATE outbox_events
+SET attempts = attempts + 1,
+    next_attempt_at = NOW() + make_interval(secs => @lease_seconds::float8)
+WHERE id IN (
+    SELECT id FROM outbox_events
+    WHERE published_at IS NULL
+      AND next_attempt_at <= NOW()
+    ORDER BY created_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = NOW(),
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET last_error = $2,
+    next_attempt_at = $3
+WHERE id = $1;
+
+-- name: CountPendingOutboxEvents :one
+SELECT COUNT(*) FROM outbox_events
+WHERE published_at IS NULL;
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE published_at IS NOT NULL
+  AND published_at < @before::timestamptz;
@@ -0,0 +1,40 @@
+-- name: CreatePasswordResetRequest :exec
+INSERT INTO password_reset_requests (username, ip_address)
+VALUES ($1, $2);
+
+-- name: CountPasswordResetRequests :one
+-- Requests since a time from the IP and for the username
+SELECT
+    COUNT(*) FILTER (WHERE ip_address = @ip_address)::bigint AS by_ip,
+    COUNT(*) FILTER (WHERE username = @username)::bigint AS by_username
+FROM password_reset_requests
+WHERE created_at >= @since::timestamptz
+  AND (ip_address = @ip_address OR username = @username);
+
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (user_id, token_hash, requested_ip, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: UsePasswordResetToken :one
+-- Marks an unused, unexpired token used and returns it; no row comes back
+-- for any other token, so each one works once
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+-- Retires the user's outstanding tokens, when a newer one is issued or the
+-- password was reset
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteExpiredPasswordResetTokens :execrows
+DELETE FROM password_reset_tokens
+WHERE expires_at < @before::timestamptz;
+
+-- name: DeletePasswordResetRequestsBefore :execrows
+DELETE FROM password_reset_requests
+WHERE created_at < @before::timestamptz;
@@ -8,13 +8,18 @@ SELECT * FROM permissions WHERE id = sqlc.arg('id');
 
 -- name: ListPermissions :many
 SELECT * FROM permissions
-ORDER BY resource, action
+ORDER BY /* sort */ resource, action
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
+-- name: CountPermissions :one
+-- Number of permissions, only those of resource unless it is empty
+SELECT COUNT(*) FROM permissions
+WHERE (@resource::text = '' OR resource = @resource::text);
+
 -- name: ListPermissionsByResource :many
 SELECT * FROM permissions
 WHERE resource = sqlc.arg('resource')
-ORDER BY action
+ORDER BY /* sort */ action
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: UpdatePermission :one
@@ -45,6 +50,16 @@ JOIN role_permissions rp ON p.id = rp.permission_id
 WHERE rp.role_id = sqlc.arg('role_id')
 ORDER BY p.resource, p.action;
 
+-- name: ListAllPermissions :many
+-- Every permission, for the RBAC export
+SELECT * FROM permissions
+ORDER BY resource, action;
+
+-- name: ListRolePermissionGrants :many
+-- Every permission granted to every role, for the RBAC export
+SELECT * FROM role_permissions
+ORDER BY role_id, permission_id;
+
 -- name: CheckRolePermission :one
 SELECT EXISTS(
     SELECT 1 FROM role_permissions rp
@@ -55,9 +70,13 @@ SELECT EXISTS(
 ) as has_permission;
 
 -- name: ListActiveUsers :many
+-- Users not deleted, newest first; pages after the first seek past the
+-- keyset cursor (after_time, after_id)
 SELECT * FROM users
 WHERE deleted_at IS NULL
-ORDER BY created_at DESC
+  AND (sqlc.narg(after_time)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: SoftDeleteUser :exec
@@ -83,31 +102,68 @@ VALUES (
 )
 RETURNING *;
 
+-- name: CreateAuditLogs :exec
+-- Adds many audit log entries in one statement. The arrays are read side
+-- by side, one entry per position; a nil user id and empty old and new
+-- values are stored as NULL.
+INSERT INTO audit_logs (user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent)
+SELECT NULLIF(e.user_id, '00000000-0000-0000-0000-000000000000'), e.action, e.entity_type, e.entity_id,
+    NULLIF(e.old_values, '')::jsonb, NULLIF(e.new_values, '')::jsonb, e.ip_address, e.user_agent
+FROM unnest(@user_ids::uuid[], @actions::text[], @entity_types::text[], @entity_ids::text[],
+    @old_values::text[], @new_values::text[], @ip_addresses::text[], @user_agents::text[])
+    AS e(user_id, action, entity_type, entity_id, old_values, new_values, ip_address, user_agent);
+
 -- name: GetAuditLog :one
 SELECT * FROM audit_logs WHERE id = sqlc.arg('id');
 
 -- name: ListAuditLogs :many
 SELECT * FROM audit_logs
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
+-- name: ListAuditLogsBetween :many
+-- Audit log entries created in [from_time, to_time), either bound
+-- optional; every filter that is set narrows the list. Pages after the
+-- first seek past the keyset cursor (after_time, after_id).
+SELECT * FROM audit_logs
+WHERE (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time)::timestamptz)
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time)::timestamptz)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (@entity_type::text = '' OR entity_type = @entity_type::text)
+  AND (@entity_id::text = '' OR entity_id = @entity_id::text)
+  AND (@action::text = '' OR action = @action::text)
+  AND (sqlc.narg(after_time)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountAuditLogsBetween :one
+-- Number of entries ListAuditLogsBetween lists with the same filters
+SELECT COUNT(*) FROM audit_logs
+WHERE (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time)::timestamptz)
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time)::timestamptz)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (@entity_type::text = '' OR entity_type = @entity_type::text)
+  AND (@entity_id::text = '' OR entity_id = @entity_id::text)
+  AND (@action::text = '' OR action = @action::text);
+
 -- name: GetAuditLogsByUser :many
 SELECT * FROM audit_logs
 WHERE user_id = sqlc.arg('user_id')
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetAuditLogsByEntity :many
 SELECT * FROM audit_logs
 WHERE entity_type = sqlc.arg('entity_type')
   AND entity_id = sqlc.arg('entity_id')
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetAuditLogsByAction :many
 SELECT * FROM audit_logs
 WHERE action = sqlc.arg('action')
-ORDER BY created_at DESC
+ORDER BY /* sort */ created_at DESC, id DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetAuditLogStats :one
@@ -117,3 +173,7 @@ SELECT
     COUNT(DISTINCT entity_type) as unique_entities
 FROM audit_logs
 WHERE created_at >= NOW() - INTERVAL '24 hours';
+
+-- name: DeleteAuditLogsBefore :execrows
+-- Audit retention (see internal/scheduler)
+DELETE FROM audit_logs WHERE created_at < @before::timestamptz;
@@ -1,8 +1,8 @@
 -- name: CreateProduct :one
 INSERT INTO products (
-    name, brand, dosage_form_id, strength, unit, category_id, description
+    name, brand, dosage_form_id, strength, unit, category_id, description, price
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8
 )
 RETURNING *;
 
@@ -10,31 +10,73 @@ RETURNING *;
 SELECT * FROM products
 WHERE id = $1 LIMIT 1;
 
+-- name: GetProductsByIDs :many
+SELECT * FROM products
+WHERE id = ANY(@ids::uuid[]);
+
 -- name: ListProducts :many
+-- Products newest first; pages after the first seek past the keyset
+-- cursor (after_time, after_id)
 SELECT * FROM products
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+WHERE sqlc.narg(after_time)::timestamptz IS NULL
+   OR (created_at, id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid)
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountProducts :one
+SELECT COUNT(*) FROM products;
 
 -- name: UpdateProduct :one
+-- Changes the fields of a product. Name and status keep their value when
+-- NULL; each other field is set to the value given, NULL included, when
+-- its set_ flag is true and keeps its value otherwise.
+UPDATE products
+SET
+    name = COALESCE(sqlc.narg(name), name),
+    brand = CASE WHEN @set_brand::boolean THEN sqlc.narg(brand) ELSE brand END,
+    dosage_form_id = CASE WHEN @set_dosage_form_id::boolean THEN sqlc.narg(dosage_form_id) ELSE dosage_form_id END,
+    strength = CASE WHEN @set_strength::boolean THEN sqlc.narg(strength) ELSE strength END,
+    unit = CASE WHEN @set_unit::boolean THEN sqlc.narg(unit) ELSE unit END,
+    category_id = CASE WHEN @set_category_id::boolean THEN sqlc.narg(category_id) ELSE category_id END,
+    description = CASE WHEN @set_description::boolean THEN sqlc.narg(description) ELSE description END,
+    price = CASE WHEN @set_price::boolean THEN sqlc.narg(price) ELSE price END,
+    status = COALESCE(sqlc.narg(status), status)
+WHERE id = @id
+RETURNING *;
+
+-- name: SetProductControlled :one
+-- Marks a product as a controlled substance of a schedule class, or clears
+-- both
 UPDATE products
-SET 
-    name = COALESCE($2, name),
-    brand = COALESCE($3, brand),
-    dosage_form_id = COALESCE($4, dosage_form_id),
-    strength = COALESCE($5, strength),
-    unit = COALESCE($6, unit),
-    category_id = COALESCE($7, category_id),
-    description = COALESCE($8, description)
-WHERE id = $1
+SET is_controlled = @is_controlled,
+    schedule_class = sqlc.narg(schedule_class)
+WHERE id = @id
 RETURNING *;
 
 -- name: DeleteProduct :exec
 DELETE FROM products WHERE id = $1;
 
 -- name: SearchProducts :many
+-- Products whose name or brand contains the query, both folded by
+-- normalize_search so Arabic and Persian spellings, digits and ZWNJ match,
+-- past the keyset cursor (after_time, after_id) when one is given
 SELECT * FROM products
-WHERE 
-    name ILIKE '%' || $1 || '%' 
-    OR brand ILIKE '%' || $1 || '%'
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
\ No newline at end of file
+WHERE (normalize_search(name) LIKE '%' || normalize_search(@query::text) || '%'
+    OR normalize_search(COALESCE(brand, '')) LIKE '%' || normalize_search(@query::text) || '%')
+  AND (sqlc.narg(after_time)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY /* sort */ created_at DESC, id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountSearchProducts :one
+-- Number of products SearchProducts lists for the query
+SELECT COUNT(*) FROM products
+WHERE normalize_search(name) LIKE '%' || normalize_search(@query::text) || '%'
+   OR normalize_search(COALESCE(brand, '')) LIKE '%' || normalize_search(@query::text) || '%';
+
+-- name: ListProductCategories :many
+-- The categories of the products, for ?include=category
+SELECT p.id AS product_id, c.id, c.name
+FROM products p
+JOIN categories c ON c.id = p.category_id
+WHERE p.id = ANY(@product_ids::uuid[]);
\ No newline at end of file
@@ -0,0 +1,71 @@
+-- name: CreateRecurringOrder :one
+INSERT INTO recurring_orders (
+    name, template_order_id, frequency, lead_days, priority, enabled, next_run_at, created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetRecurringOrder :one
+SELECT * FROM recurring_orders
+WHERE id = $1 LIMIT 1;
+
+-- name: ListRecurringOrders :many
+SELECT * FROM recurring_orders
+ORDER BY next_run_at, name;
+
+-- name: ListEnabledRecurringOrders :many
+SELECT * FROM recurring_orders
+WHERE enabled
+ORDER BY next_run_at, name;
+
+-- name: UpdateRecurringOrder :one
+UPDATE recurring_orders
+SET name = $2,
+    frequency = $3,
+    lead_days = $4,
+    priority = $5,
+    enabled = $6,
+    next_run_at = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRecurringOrder :exec
+DELETE FROM recurring_orders WHERE id = $1;
+
+-- name: ClaimDueRecurringOrders :many
+-- Locks the due recurring orders so concurrent instances skip them; run
+-- inside a transaction that advances next_run_at
+SELECT * FROM recurring_orders
+WHERE enabled AND next_run_at <= @now::timestamptz
+ORDER BY next_run_at
+LIMIT @limit_count
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkRecurringOrderRun :exec
+UPDATE recurring_orders
+SET last_run_at = $2,
+    last_order_id = $3,
+    last_error = $4,
+    next_run_at = $5
+WHERE id = $1;
+
+-- name: CopyOrderItems :execrows
+-- Copies the items of one order into another, leaving out deleted products;
+-- the copies are priced at the products' current prices and keep their
+-- suppliers
+INSERT INTO order_items (order_id, product_id, requested_qty, unit, note, unit_price, supplier_id)
+SELECT @target_order_id::uuid, oi.product_id, oi.requested_qty, oi.unit, oi.note, p.price, oi.supplier_id
+FROM order_items oi
+JOIN products p ON p.id = oi.product_id
+WHERE oi.order_id = @source_order_id::uuid
+  AND p.deleted_at IS NULL;
+
+-- name: ListOrderDeadlines :many
+-- Orders still open with a needed_by date on or after from_date
+SELECT id, status, priority, notes, needed_by::date AS needed_by, created_at
+FROM orders
+WHERE deleted_at IS NULL
+  AND needed_by >= @from_date::date
+  AND NOT (status = ANY(@closed_statuses::text[]))
+ORDER BY needed_by, created_at;
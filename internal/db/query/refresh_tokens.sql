@@ -0,0 +1,47 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetRefreshTokenByHash :one
+-- The token with its user's current role, tenant, department and
+-- language, which the access tokens it is exchanged for carry
+SELECT
+    t.id,
+    t.user_id,
+    t.family_id,
+    t.expires_at,
+    t.rotated_at,
+    t.revoked_at,
+    u.username,
+    u.role_id,
+    u.tenant_id,
+    u.department_id,
+    u.language,
+    r.name AS role_name
+FROM refresh_tokens t
+JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+LEFT JOIN roles r ON r.id = u.role_id
+WHERE t.token_hash = $1;
+
+-- name: RotateRefreshToken :execrows
+-- Marks the token used; no row is affected when another request used or
+-- revoked it first
+UPDATE refresh_tokens
+SET rotated_at = NOW()
+WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :execrows
+-- Ends every session of the user, as when the password changes
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: DeleteExpiredRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < @before::timestamptz;
@@ -0,0 +1,281 @@
+-- name: GetProductStock :one
+SELECT * FROM product_stock
+WHERE product_id = $1 LIMIT 1;
+
+-- name: UpsertProductStock :one
+INSERT INTO product_stock (product_id, on_hand, reorder_level, updated_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (product_id) DO UPDATE
+SET on_hand = EXCLUDED.on_hand,
+    reorder_level = EXCLUDED.reorder_level,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: CreateReportSchedule :one
+INSERT INTO report_schedules (
+    report, recipient_id, format, frequency, send_hour, enabled, next_run_at, created_by, saved_report_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetReportSchedule :one
+SELECT * FROM report_schedules
+WHERE id = $1 LIMIT 1;
+
+-- name: CountReportSchedules :one
+SELECT COUNT(*) FROM report_schedules
+WHERE (sqlc.narg(recipient_id)::uuid IS NULL OR recipient_id = sqlc.narg(recipient_id)::uuid);
+
+-- name: ListReportSchedules :many
+SELECT * FROM report_schedules
+WHERE (sqlc.narg(recipient_id)::uuid IS NULL OR recipient_id = sqlc.narg(recipient_id)::uuid)
+ORDER BY created_at DESC
+LIMIT @limit_count OFFSET @offset_count;
+
+-- name: UpdateReportSchedule :one
+UPDATE report_schedules
+SET format = $2,
+    frequency = $3,
+    send_hour = $4,
+    enabled = $5,
+    next_run_at = $6
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteReportSchedule :exec
+DELETE FROM report_schedules WHERE id = $1;
+
+-- name: ClaimDueReportSchedules :many
+-- Locks the due schedules so concurrent instances skip them; run inside a
+-- transaction that advances next_run_at
+SELECT * FROM report_schedules
+WHERE enabled AND next_run_at <= @now::timestamptz
+ORDER BY next_run_at
+LIMIT @limit_count
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkReportScheduleRun :exec
+UPDATE report_schedules
+SET last_run_at = $2,
+    last_status = $3,
+    last_error = $4,
+    next_run_at = $5
+WHERE id = $1;
+
+-- name: GetReportRecipient :one
+SELECT u.id, u.username, u.full_name, s.email, u.tenant_id
+FROM users u
+LEFT JOIN user_notification_settings s ON s.user_id = u.id
+WHERE u.id = $1 AND u.deleted_at IS NULL
+LIMIT 1;
+
+-- name: ReportOrderCounts :many
+SELECT o.status, o.priority, COUNT(*) AS orders
+FROM orders o
+WHERE o.deleted_at IS NULL
+  AND o.created_at >= @from_time::timestamptz
+  AND o.created_at < @to_time::timestamptz
+GROUP BY o.status, o.priority
+ORDER BY o.status, o.priority;
+
+-- name: ReportTopRequestedProducts :many
+SELECT
+    p.id AS product_id,
+    p.name,
+    p.strength,
+    p.unit,
+    COUNT(DISTINCT o.id) AS orders,
+    SUM(oi.requested_qty)::bigint AS requested_qty
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+JOIN products p ON p.id = oi.product_id
+WHERE o.deleted_at IS NULL
+  AND o.created_at >= @from_time::timestamptz
+  AND o.created_at < @to_time::timestamptz
+GROUP BY p.id, p.name, p.strength, p.unit
+ORDER BY requested_qty DESC, p.name
+LIMIT @limit_count;
+
+-- name: ReportLowStock :many
+-- Tracked products at or below their reorder level, with the quantity
+-- requested in [from_time, to_time)
+SELECT
+    p.id AS product_id,
+    p.name,
+    p.strength,
+    p.unit,
+    s.on_hand,
+    s.reorder_level,
+    COALESCE((
+        SELECT SUM(oi.requested_qty)
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        WHERE oi.product_id = p.id
+          AND o.deleted_at IS NULL
+          AND o.created_at >= @from_time::timestamptz
+          AND o.created_at < @to_time::timestamptz
+    ), 0)::bigint AS requested_qty
+FROM product_stock s
+JOIN products p ON p.id = s.product_id
+WHERE p.deleted_at IS NULL
+  AND s.on_hand <= s.reorder_level
+ORDER BY s.on_hand - s.reorder_level, p.name;
+
+-- name: ReportAuditActions :many
+SELECT entity_type, action, COUNT(*) AS events
+FROM audit_logs
+WHERE created_at >= @from_time::timestamptz
+  AND created_at < @to_time::timestamptz
+GROUP BY entity_type, action
+ORDER BY events DESC, entity_type, action;
+
+-- name: ReportAuditUsers :many
+SELECT COALESCE(u.username, '(system)')::text AS username, COUNT(*) AS events
+FROM audit_logs a
+LEFT JOIN users u ON u.id = a.user_id
+WHERE a.created_at >= @from_time::timestamptz
+  AND a.created_at < @to_time::timestamptz
+GROUP BY u.username
+ORDER BY events DESC, username
+LIMIT @limit_count;
+
+-- name: ReportLoginSummary :one
+SELECT
+    COUNT(*) AS attempts,
+    COUNT(*) FILTER (WHERE NOT success) AS failed,
+    COUNT(*) FILTER (WHERE rate_limited) AS rate_limited,
+    COUNT(DISTINCT ip_address) FILTER (WHERE NOT success) AS failed_ips
+FROM login_attempts_log
+WHERE attempt_time >= @from_time::timestamptz
+  AND attempt_time < @to_time::timestamptz;
+
+-- name: ReportOrderTimeSeries :many
+-- Orders, items and requested quantity per local day in [from_time,
+-- to_time), split by status, product category or department when group_by
+-- says so
+SELECT
+    (o.created_at AT TIME ZONE @timezone::text)::date AS day,
+    (CASE @group_by::text
+        WHEN 'status' THEN o.status
+        WHEN 'category' THEN COALESCE(c.name, '')
+        WHEN 'department' THEN COALESCE(d.name, '')
+        ELSE ''
+    END)::text AS group_key,
+    COUNT(DISTINCT o.id) AS orders,
+    COUNT(oi.id) AS items,
+    COALESCE(SUM(oi.requested_qty), 0)::bigint AS quantity
+FROM orders o
+LEFT JOIN order_items oi ON oi.order_id = o.id
+LEFT JOIN products p ON p.id = oi.product_id
+LEFT JOIN categories c ON c.id = p.category_id
+LEFT JOIN departments d ON d.id = o.department_id
+WHERE o.deleted_at IS NULL
+  AND o.created_at >= @from_time::timestamptz
+  AND o.created_at < @to_time::timestamptz
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
+-- name: ReportProductDemand :many
+-- Quantity of a product requested per local day in [from_time, to_time),
+-- leaving out orders in excluded_statuses
+SELECT
+    (o.created_at AT TIME ZONE @timezone::text)::date AS day,
+    SUM(oi.requested_qty)::bigint AS quantity
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+WHERE oi.product_id = @product_id::uuid
+  AND o.deleted_at IS NULL
+  AND NOT (o.status = ANY(@excluded_statuses::text[]))
+  AND o.created_at >= @from_time::timestamptz
+  AND o.created_at < @to_time::timestamptz
+GROUP BY 1
+ORDER BY 1;
+
+-- name: ReportUserActivity :many
+-- Per active user in [from_time, to_time): orders created and their items,
+-- orders approved (status changes to approved in the audit log) and
+-- successful logins
+SELECT
+    u.id AS user_id,
+    u.username,
+    COALESCE(u.full_name, '')::text AS full_name,
+    COALESCE(r.name, '')::text AS role,
+    COALESCE(o.orders, 0)::bigint AS orders_created,
+    COALESCE(o.items, 0)::bigint AS items_added,
+    COALESCE(a.approvals, 0)::bigint AS approvals,
+    COALESCE(l.logins, 0)::bigint AS logins
+FROM users u
+LEFT JOIN roles r ON r.id = u.role_id
+LEFT JOIN (
+    SELECT o.created_by, COUNT(DISTINCT o.id) AS orders, COUNT(oi.id) AS items
+    FROM orders o
+    LEFT JOIN order_items oi ON oi.order_id = o.id
+    WHERE o.deleted_at IS NULL
+      AND o.created_at >= @from_time::timestamptz
+      AND o.created_at < @to_time::timestamptz
+    GROUP BY o.created_by
+) o ON o.created_by = u.id
+LEFT JOIN (
+    SELECT user_id, COUNT(*) AS approvals
+    FROM audit_logs
+    WHERE entity_type = 'order'
+      AND action = 'update_status'
+      AND new_values->>'status' = 'approved'
+      AND created_at >= @from_time::timestamptz
+      AND created_at < @to_time::timestamptz
+    GROUP BY user_id
+) a ON a.user_id = u.id
+LEFT JOIN (
+    SELECT username, COUNT(*) AS logins
+    FROM login_attempts_log
+    WHERE success
+      AND attempt_time >= @from_time::timestamptz
+      AND attempt_time < @to_time::timestamptz
+    GROUP BY username
+) l ON l.username = u.username
+WHERE u.deleted_at IS NULL
+  AND (sqlc.narg(user_id)::uuid IS NULL OR u.id = sqlc.narg(user_id)::uuid)
+ORDER BY orders_created DESC, approvals DESC, u.username;
+
+-- name: ReportAuditChanges :many
+-- Audit log entries per local day, entity type, action and user in
+-- [from_time, to_time); an empty entity_types keeps every type
+SELECT
+    (a.created_at AT TIME ZONE @timezone::text)::date AS day,
+    a.entity_type,
+    a.action,
+    a.user_id,
+    COALESCE(u.username, '')::text AS username,
+    COUNT(*) AS changes
+FROM audit_logs a
+LEFT JOIN users u ON u.id = a.user_id
+WHERE a.created_at >= @from_time::timestamptz
+  AND a.created_at < @to_time::timestamptz
+  AND (cardinality(@entity_types::text[]) = 0 OR a.entity_type = ANY(@entity_types::text[]))
+  AND (sqlc.narg(user_id)::uuid IS NULL OR a.user_id = sqlc.narg(user_id)::uuid)
+GROUP BY 1, 2, 3, 4, 5
+ORDER BY 1, 2, 3;
+
+-- name: ReportMostChangedEntities :many
+-- The per_type entities of each type with the most audit log entries in
+-- [from_time, to_time), and how many entities of the type changed at all
+SELECT entity_type, entity_id, changes, last_changed_at, entities
+FROM (
+    SELECT
+        entity_type,
+        entity_id,
+        COUNT(*) AS changes,
+        MAX(created_at)::timestamptz AS last_changed_at,
+        COUNT(*) OVER (PARTITION BY entity_type) AS entities,
+        ROW_NUMBER() OVER (PARTITION BY entity_type ORDER BY COUNT(*) DESC, MAX(created_at) DESC) AS position
+    FROM audit_logs
+    WHERE created_at >= @from_time::timestamptz
+      AND created_at < @to_time::timestamptz
+      AND (cardinality(@entity_types::text[]) = 0 OR entity_type = ANY(@entity_types::text[]))
+      AND (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+    GROUP BY entity_type, entity_id
+) e
+WHERE position <= @per_type::int
+ORDER BY entity_type, position;
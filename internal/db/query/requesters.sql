@@ -0,0 +1,65 @@
+-- name: CreateRequester :one
+INSERT INTO requesters (kind, name, reference, department_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetRequester :one
+SELECT * FROM requesters
+WHERE id = $1;
+
+-- name: ListRequestersByIDs :many
+SELECT * FROM requesters
+WHERE id = ANY(@ids::uuid[]);
+
+-- name: ListRequesters :many
+-- Requesters of the given kind and department, when set, newest first.
+-- Names are encrypted, so they cannot be searched here. Pages after the
+-- first seek past the keyset cursor (after_time, after_id).
+SELECT * FROM requesters
+WHERE (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind)::text)
+  AND (sqlc.narg(department_id)::int IS NULL OR department_id = sqlc.narg(department_id)::int)
+  AND (sqlc.narg(after_time)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountRequesters :one
+SELECT COUNT(*) FROM requesters
+WHERE (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind)::text)
+  AND (sqlc.narg(department_id)::int IS NULL OR department_id = sqlc.narg(department_id)::int);
+
+-- name: UpdateRequester :one
+UPDATE requesters
+SET kind = $2,
+    name = $3,
+    reference = $4,
+    department_id = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRequester :execrows
+-- Orders for the requester are kept, without a requester
+DELETE FROM requesters
+WHERE id = $1;
+
+-- name: ReportOrdersByRequester :many
+-- Per requester, the orders created for them in [from_time, to_time) and
+-- the items and quantity on those orders, most orders first
+SELECT
+    r.id,
+    r.kind,
+    r.name,
+    r.reference,
+    r.department_id,
+    COUNT(DISTINCT o.id)::bigint AS orders,
+    COUNT(oi.id)::bigint AS items,
+    COALESCE(SUM(oi.requested_qty), 0)::bigint AS quantity,
+    MAX(o.created_at)::timestamptz AS last_order_at
+FROM requesters r
+JOIN orders o ON o.requester_id = r.id
+LEFT JOIN order_items oi ON oi.order_id = o.id
+WHERE o.deleted_at IS NULL
+  AND o.created_at >= @from_time::timestamptz
+  AND o.created_at < @to_time::timestamptz
+GROUP BY r.id
+ORDER BY orders DESC, r.id;
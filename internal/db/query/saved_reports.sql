@@ -0,0 +1,31 @@
+-- name: CreateSavedReport :one
+INSERT INTO saved_reports (
+    name, description, entity, filters, columns, sort, row_limit, created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetSavedReport :one
+SELECT * FROM saved_reports
+WHERE id = $1 LIMIT 1;
+
+-- name: ListSavedReports :many
+SELECT * FROM saved_reports
+ORDER BY name;
+
+-- name: UpdateSavedReport :one
+UPDATE saved_reports
+SET name = $2,
+    description = $3,
+    entity = $4,
+    filters = $5,
+    columns = $6,
+    sort = $7,
+    row_limit = $8,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteSavedReport :execrows
+DELETE FROM saved_reports WHERE id = $1;
@@ -0,0 +1,31 @@
+-- name: ClaimScheduledJob :one
+-- Marks the job running for the run due at due, unless another instance
+-- has already started it then or is still running it. A run that has been
+-- running since before stale_before is taken to have died with its
+-- instance.
+INSERT INTO scheduled_jobs (name, schedule, last_status, last_started_at)
+VALUES (@name, @schedule, 'running', NOW())
+ON CONFLICT (name) DO UPDATE
+SET schedule = EXCLUDED.schedule,
+    last_status = 'running',
+    last_error = NULL,
+    last_started_at = NOW()
+WHERE scheduled_jobs.last_started_at IS NULL
+   OR (scheduled_jobs.last_started_at < @due::timestamptz
+       AND (scheduled_jobs.last_status IS DISTINCT FROM 'running'
+            OR scheduled_jobs.last_started_at < @stale_before::timestamptz))
+RETURNING *;
+
+-- name: FinishScheduledJob :exec
+UPDATE scheduled_jobs
+SET last_status = @status::text,
+    last_error = @error,
+    last_finished_at = NOW(),
+    last_duration_ms = @duration_ms::bigint,
+    run_count = run_count + 1,
+    failure_count = failure_count + CASE WHEN @status = 'failed' THEN 1 ELSE 0 END
+WHERE name = @name;
+
+-- name: ListScheduledJobs :many
+SELECT * FROM scheduled_jobs
+ORDER BY name;
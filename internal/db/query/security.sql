@@ -0,0 +1,42 @@
+-- name: ListRecentIPBans :many
+-- Bans are recorded in the login log by the rate limiter's ban manager
+SELECT ip_address, attempt_time AS banned_at, COALESCE(failure_reason, '')::text AS reason
+FROM login_attempts_log
+WHERE username = 'system'
+  AND user_agent = 'rate_limiter'
+  AND rate_limited
+  AND attempt_time >= @since::timestamptz
+ORDER BY attempt_time DESC
+LIMIT @limit_count;
+
+-- name: ListRecentRateLimitReleases :many
+SELECT
+    r.ip_address,
+    COALESCE(r.username, '')::text AS username,
+    r.blocked_at,
+    r.released_at,
+    r.released_by,
+    COALESCE(u.username, '')::text AS released_by_username,
+    COALESCE(r.release_reason, '')::text AS release_reason
+FROM rate_limit_releases r
+LEFT JOIN users u ON u.id = r.released_by_user_id
+WHERE r.released_at >= @since::timestamptz
+ORDER BY r.released_at DESC
+LIMIT @limit_count;
+
+-- name: ListRecentPermissionChanges :many
+SELECT
+    a.id,
+    a.action,
+    a.entity_type,
+    a.entity_id,
+    a.old_values,
+    a.new_values,
+    a.created_at,
+    COALESCE(u.username, '(system)')::text AS username
+FROM audit_logs a
+LEFT JOIN users u ON u.id = a.user_id
+WHERE a.entity_type = ANY(@entity_types::text[])
+  AND a.created_at >= @since::timestamptz
+ORDER BY a.created_at DESC
+LIMIT @limit_count;
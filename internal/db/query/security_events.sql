@@ -0,0 +1,86 @@
+-- name: ClaimUnanalyzedLogins :many
+-- Successful logins the anomaly analyzer has not looked at yet, marked as
+-- analyzed; SKIP LOCKED lets several instances share the work
+UPDATE login_attempts_log
+SET analyzed_at = NOW()
+WHERE id IN (
+    SELECT id FROM login_attempts_log
+    WHERE success AND analyzed_at IS NULL
+    ORDER BY attempt_time
+    LIMIT @limit_count
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: GetPreviousLogin :one
+-- The user's last successful login before the given one
+SELECT * FROM login_attempts_log
+WHERE username = @username
+  AND success
+  AND attempt_time < @before::timestamptz
+  AND id <> @id
+ORDER BY attempt_time DESC
+LIMIT 1;
+
+-- name: CountLoginFingerprints :one
+-- Successful logins of a user before a time with a device fingerprint,
+-- and those with the given one
+SELECT
+    COUNT(*) FILTER (WHERE device_fingerprint IS NOT NULL) AS fingerprinted,
+    COUNT(*) FILTER (WHERE device_fingerprint = @fingerprint::text) AS matching
+FROM login_attempts_log
+WHERE username = @username
+  AND success
+  AND attempt_time < @before::timestamptz;
+
+-- name: CountLoginsNearHour :one
+-- Successful logins of a user in [since, before), and those within an hour
+-- of the given local hour of day
+SELECT
+    COUNT(*) AS logins,
+    COUNT(*) FILTER (WHERE
+        LEAST(
+            ABS(EXTRACT(HOUR FROM attempt_time AT TIME ZONE @timezone::text)::int - @hour::int),
+            24 - ABS(EXTRACT(HOUR FROM attempt_time AT TIME ZONE @timezone::text)::int - @hour::int)
+        ) <= 1
+    ) AS near_hour
+FROM login_attempts_log
+WHERE username = @username
+  AND success
+  AND attempt_time >= @since::timestamptz
+  AND attempt_time < @before::timestamptz;
+
+-- name: CreateSecurityEvent :one
+INSERT INTO security_events (kind, username, login_attempt_id, ip_address, details)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetSecurityEvent :one
+SELECT * FROM security_events
+WHERE id = $1;
+
+-- name: ListSecurityEvents :many
+-- Security events matching every filter that is set, newest first. Pages
+-- after the first seek past the keyset cursor (after_time, after_id).
+SELECT * FROM security_events
+WHERE (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind)::text)
+  AND (sqlc.narg(username)::text IS NULL OR username = sqlc.narg(username)::text)
+  AND (sqlc.narg(acknowledged)::boolean IS NULL OR (acknowledged_at IS NOT NULL) = sqlc.narg(acknowledged)::boolean)
+  AND (sqlc.narg(after_time)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(after_time)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT @limit_count OFFSET @offset_count;
+
+-- name: CountSecurityEvents :one
+SELECT COUNT(*) FROM security_events
+WHERE (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind)::text)
+  AND (sqlc.narg(username)::text IS NULL OR username = sqlc.narg(username)::text)
+  AND (sqlc.narg(acknowledged)::boolean IS NULL OR (acknowledged_at IS NOT NULL) = sqlc.narg(acknowledged)::boolean);
+
+-- name: AcknowledgeSecurityEvent :one
+-- The first acknowledgement is kept
+UPDATE security_events
+SET acknowledged_at = COALESCE(acknowledged_at, NOW()),
+    acknowledged_by = COALESCE(acknowledged_by, @acknowledged_by)
+WHERE id = @id
+RETURNING *;
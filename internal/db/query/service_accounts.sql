@@ -0,0 +1,20 @@
+-- name: CreateServiceAccount :one
+-- The password hash matches no password, so the account cannot sign in
+INSERT INTO users (username, full_name, password_hash, role_id, department_id, service_account)
+VALUES ($1, $2, '!', $3, $4, TRUE)
+RETURNING *;
+
+-- name: ListServiceAccounts :many
+SELECT * FROM users
+WHERE service_account AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetServiceAccount :one
+SELECT * FROM users
+WHERE id = $1 AND service_account AND deleted_at IS NULL;
+
+-- name: DeleteServiceAccount :one
+UPDATE users
+SET deleted_at = NOW()
+WHERE id = $1 AND service_account AND deleted_at IS NULL
+RETURNING *;
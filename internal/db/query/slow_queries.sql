@@ -0,0 +1,33 @@
+-- name: InsertSlowQuery :exec
+INSERT INTO slow_queries (
+    query_name, operation, table_name, query, params, duration_ms, error, request_id, route, user_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+);
+
+-- name: ListSlowQueries :many
+SELECT id, query_name, operation, table_name, query, params, duration_ms, error, request_id, route, user_id, created_at
+FROM slow_queries
+WHERE created_at >= @since::timestamptz
+  AND (sqlc.narg(query_name)::text IS NULL OR query_name = sqlc.narg(query_name)::text)
+ORDER BY created_at DESC
+LIMIT @limit_count OFFSET @offset_count;
+
+-- name: SummarizeSlowQueries :many
+-- One row per statement, slowest first
+SELECT
+    query_name,
+    operation,
+    table_name,
+    COUNT(*) AS occurrences,
+    AVG(duration_ms)::float8 AS avg_ms,
+    MAX(duration_ms)::float8 AS max_ms,
+    MAX(created_at)::timestamptz AS last_seen
+FROM slow_queries
+WHERE created_at >= @since::timestamptz
+GROUP BY query_name, operation, table_name
+ORDER BY MAX(duration_ms) DESC
+LIMIT @limit_count;
+
+-- name: DeleteSlowQueriesBefore :execrows
+DELETE FROM slow_queries WHERE created_at < @before::timestamptz;
@@ -0,0 +1,4 @@
+-- name: EstimateRowCount :one
+-- The planner's estimate of the rows in a table, kept by ANALYZE and
+-- autovacuum; -1 for a table never analyzed
+SELECT COALESCE((SELECT reltuples FROM pg_class WHERE oid = to_regclass(@table_name::text)), -1)::bigint AS estimate;
@@ -0,0 +1,22 @@
+-- name: CreateSupplier :one
+INSERT INTO suppliers (name, phone, email)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetSupplier :one
+SELECT * FROM suppliers
+WHERE id = $1;
+
+-- name: ListSuppliers :many
+SELECT * FROM suppliers
+ORDER BY name;
+
+-- name: UpdateSupplier :one
+UPDATE suppliers
+SET name = $2, phone = $3, email = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteSupplier :execrows
+DELETE FROM suppliers
+WHERE id = $1;
@@ -0,0 +1,59 @@
+-- name: CreateTenant :one
+INSERT INTO tenants (slug, name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetTenant :one
+SELECT * FROM tenants
+WHERE id = $1;
+
+-- name: ListTenants :many
+SELECT * FROM tenants
+ORDER BY name;
+
+-- name: UpdateTenant :one
+UPDATE tenants
+SET name = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: SetTenantQuotas :one
+-- NULL keeps the tenancy.quotas default, 0 lifts the limit
+UPDATE tenants
+SET requests_per_minute = @requests_per_minute,
+    requests_per_day = @requests_per_day,
+    orders_per_day = @orders_per_day
+WHERE id = @id
+RETURNING *;
+
+-- name: ScopeToTenant :exec
+-- Scopes the rest of the transaction to a tenant; an empty
+-- product_tenant_id puts new products in the shared catalog
+SELECT
+    set_config('digiorder.tenant_id', @tenant_id::text, true),
+    set_config('digiorder.product_tenant_id', @product_tenant_id::text, true);
+
+-- name: AddTenantRequests :exec
+INSERT INTO tenant_request_counts (tenant_id, day, requests)
+VALUES ($1, $2, $3)
+ON CONFLICT (tenant_id, day)
+DO UPDATE SET requests = tenant_request_counts.requests + EXCLUDED.requests;
+
+-- name: ListTenantRequestCounts :many
+SELECT tenant_id, requests FROM tenant_request_counts
+WHERE day = $1;
+
+-- name: DeleteTenantRequestCountsBefore :execrows
+DELETE FROM tenant_request_counts
+WHERE day < $1;
+
+-- name: CountTenantOrdersSince :one
+-- Deleted orders count too, so deleting does not free quota
+SELECT COUNT(*) FROM orders
+WHERE tenant_id = @tenant_id
+  AND created_at >= @since::timestamptz;
+
+-- name: CountOrdersByTenantSince :many
+SELECT tenant_id, COUNT(*) AS orders FROM orders
+WHERE created_at >= @since::timestamptz
+GROUP BY tenant_id;
@@ -0,0 +1,48 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListPersonalAccessTokens :many
+-- The user's tokens that have not been revoked, newest first
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetPersonalAccessTokenByHash :one
+-- The token with its user's current role, tenant, department and
+-- language, so a token never outlives a demotion or deletion of its user
+SELECT
+    t.id,
+    t.user_id,
+    t.scopes,
+    t.expires_at,
+    t.revoked_at,
+    u.username,
+    u.role_id,
+    u.tenant_id,
+    u.department_id,
+    u.language,
+    r.name AS role_name
+FROM personal_access_tokens t
+JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+LEFT JOIN roles r ON r.id = u.role_id
+WHERE t.token_hash = $1;
+
+-- name: RevokePersonalAccessToken :one
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchPersonalAccessToken :exec
+-- Records use at most once a minute to spare the row
+UPDATE personal_access_tokens
+SET last_used_at = NOW(), last_used_ip = $2
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: RevokeUserPersonalAccessTokens :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
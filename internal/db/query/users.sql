@@ -27,6 +27,18 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: SetUserDepartment :one
+UPDATE users
+SET department_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserLanguage :one
+UPDATE users
+SET language = $2
+WHERE id = $1
+RETURNING *;
+
 -- name: UpdateUserPassword :exec
 UPDATE users
 SET password_hash = $2
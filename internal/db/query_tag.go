@@ -0,0 +1,103 @@
+// internal/db/query_tag.go - Request correlation for database sessions
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// QueryTag identifies the API request a statement was issued for. It is
+// rendered as a leading SQL comment so it shows up in pg_stat_activity and
+// in the PostgreSQL slow query log.
+type QueryTag struct {
+	RequestID string
+	TraceID   string
+	UserID    string
+	Route     string
+}
+
+type queryTagKey struct{}
+
+// WithQueryTag attaches a tag to ctx. The pointer is stored so middleware
+// that runs later (e.g. authentication) can fill in the user ID.
+func WithQueryTag(ctx context.Context, tag *QueryTag) context.Context {
+	return context.WithValue(ctx, queryTagKey{}, tag)
+}
+
+// QueryTagFromContext returns the tag attached to ctx, or nil
+func QueryTagFromContext(ctx context.Context) *QueryTag {
+	tag, _ := ctx.Value(queryTagKey{}).(*QueryTag)
+	return tag
+}
+
+// comment renders the tag as a SQL comment, e.g.
+// /* request_id=..., user_id=..., route=GET /api/v1/orders */
+func (t *QueryTag) comment() string {
+	var parts []string
+	add := func(key, value string) {
+		if value = sanitizeTagValue(value); value != "" {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	add("request_id", t.RequestID)
+	add("trace_id", t.TraceID)
+	add("user_id", t.UserID)
+	add("route", t.Route)
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return "/* " + strings.Join(parts, ", ") + " */ "
+}
+
+// sanitizeTagValue keeps only characters that cannot terminate the comment.
+// Request IDs may come from client headers, so this is a security boundary.
+func sanitizeTagValue(value string) string {
+	if len(value) > 128 {
+		value = value[:128]
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-', r == '_', r == '.', r == ':', r == '/', r == ' ':
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// TaggedDB wraps a DBTX and prefixes every statement with the QueryTag
+// found in the context, ordering list queries by the sort in it (see
+// WithSort)
+type TaggedDB struct {
+	db DBTX
+}
+
+// NewTaggedDB wraps db so queries carry request correlation comments
+func NewTaggedDB(db DBTX) *TaggedDB {
+	return &TaggedDB{db: db}
+}
+
+func tagQuery(ctx context.Context, query string) string {
+	query = sortQuery(ctx, query)
+	if tag := QueryTagFromContext(ctx); tag != nil {
+		return tag.comment() + query
+	}
+	return query
+}
+
+func (t *TaggedDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return t.db.Exec(ctx, tagQuery(ctx, query), args...)
+}
+
+func (t *TaggedDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return t.db.Query(ctx, tagQuery(ctx, query), args...)
+}
+
+func (t *TaggedDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return t.db.QueryRow(ctx, tagQuery(ctx, query), args...)
+}
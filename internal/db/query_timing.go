@@ -0,0 +1,195 @@
+// internal/db/query_timing.go - Per-statement latency observation
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// QueryStat describes one statement once it has run
+type QueryStat struct {
+	Name      string // sqlc query name, e.g. "GetOrder"; empty for other statements
+	Operation string // select, insert, update, delete, ...
+	Table     string // first table the statement reads or writes
+	Query     string // statement text, without the request tag
+	Args      []any
+	Duration  time.Duration
+	Err       error
+}
+
+// QueryObserver is called after every statement issued through a TimedDB
+type QueryObserver func(ctx context.Context, stat QueryStat)
+
+// TimedDB wraps a DBTX and reports the latency of every statement to an
+// observer. Rows are read after Query returns, so its duration covers the
+// query up to the first row; QueryRow is reported once its row is scanned.
+type TimedDB struct {
+	db      DBTX
+	observe QueryObserver
+}
+
+// NewTimedDB wraps db so every statement is reported to observe
+func NewTimedDB(db DBTX, observe QueryObserver) *TimedDB {
+	return &TimedDB{db: db, observe: observe}
+}
+
+func (t *TimedDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	start := time.Now()
+	tag, err := t.db.Exec(ctx, query, args...)
+	t.report(ctx, query, args, start, err)
+	return tag, err
+}
+
+func (t *TimedDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := t.db.Query(ctx, query, args...)
+	t.report(ctx, query, args, start, err)
+	return rows, err
+}
+
+func (t *TimedDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	start := time.Now()
+	row := t.db.QueryRow(ctx, query, args...)
+	return timedRow{row: row, report: func(err error) {
+		t.report(ctx, query, args, start, err)
+	}}
+}
+
+// timedRow reports its statement when it is scanned, as pgx returns the
+// statement's error from Scan. A missing row is not an error.
+type timedRow struct {
+	row    pgx.Row
+	report func(err error)
+}
+
+func (r timedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.report(nil)
+	} else {
+		r.report(err)
+	}
+	return err
+}
+
+func (t *TimedDB) report(ctx context.Context, query string, args []any, start time.Time, err error) {
+	info := describeQuery(query)
+	t.observe(ctx, QueryStat{
+		Name:      info.name,
+		Operation: info.operation,
+		Table:     info.table,
+		Query:     query,
+		Args:      args,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+}
+
+type queryInfo struct {
+	name, operation, table string
+}
+
+// maxQueryInfos bounds the describeQuery cache. sqlc statements are
+// constants and fit easily; ad-hoc ones (e.g. saved reports) may not.
+const maxQueryInfos = 1000
+
+var (
+	queryInfoMu sync.Mutex
+	queryInfos  = map[string]queryInfo{}
+
+	queryName  = regexp.MustCompile(`^--\s*name:\s*(\w+)`)
+	queryTable = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE)\s+(?:ONLY\s+)?([a-z_][a-z0-9_.]*)`)
+	lineNote   = regexp.MustCompile(`--[^\n]*`)
+)
+
+// describeQuery names a statement from its sqlc header, leading keyword
+// and first table
+func describeQuery(query string) queryInfo {
+	queryInfoMu.Lock()
+	info, ok := queryInfos[query]
+	queryInfoMu.Unlock()
+	if ok {
+		return info
+	}
+
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		info.name = m[1]
+	}
+	body := lineNote.ReplaceAllString(query, "")
+	if words := strings.Fields(body); len(words) > 0 {
+		info.operation = strings.ToLower(words[0])
+	}
+	if m := queryTable.FindStringSubmatch(body); m != nil {
+		info.table = strings.ToLower(m[1])
+	}
+	if info.operation == "" {
+		info.operation = "unknown"
+	}
+	if info.table == "" {
+		info.table = "none"
+	}
+
+	queryInfoMu.Lock()
+	if len(queryInfos) < maxQueryInfos {
+		queryInfos[query] = info
+	}
+	queryInfoMu.Unlock()
+	return info
+}
+
+// RedactArgs describes statement parameters without their values, e.g.
+// "uuid", "text(12)" or "null", so they can be logged safely
+func RedactArgs(args []any) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = redactArg(arg)
+	}
+	return out
+}
+
+func redactArg(arg any) string {
+	switch v := arg.(type) {
+	case uuid.UUID:
+		return "uuid"
+	case uuid.NullUUID:
+		if v.Valid {
+			return "uuid"
+		}
+		return "null"
+	}
+	if v, ok := arg.(driver.Valuer); ok {
+		value, err := v.Value()
+		if err != nil {
+			return "invalid"
+		}
+		arg = value
+	}
+	switch v := arg.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "text(" + strconv.Itoa(len(v)) + ")"
+	case []byte:
+		return "bytes(" + strconv.Itoa(len(v)) + ")"
+	case bool:
+		return "bool"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		return "number"
+	case time.Time:
+		return "time"
+	default:
+		return "other"
+	}
+}
@@ -24,7 +24,7 @@ type CountLoginAttemptsParams struct {
 }
 
 func (q *Queries) CountLoginAttempts(ctx context.Context, arg CountLoginAttemptsParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countLoginAttempts, arg.ClientID, arg.WindowStart)
+	row := q.db.QueryRow(ctx, countLoginAttempts, arg.ClientID, arg.WindowStart)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -36,7 +36,7 @@ WHERE window_start < $1
 `
 
 func (q *Queries) DeleteOldRateLimits(ctx context.Context, windowStart time.Time) error {
-	_, err := q.db.ExecContext(ctx, deleteOldRateLimits, windowStart)
+	_, err := q.db.Exec(ctx, deleteOldRateLimits, windowStart)
 	return err
 }
 
@@ -59,7 +59,7 @@ type GetOrCreateRateLimitParams struct {
 
 // internal/db/query/rate_limits.sql
 func (q *Queries) GetOrCreateRateLimit(ctx context.Context, arg GetOrCreateRateLimitParams) (ApiRateLimit, error) {
-	row := q.db.QueryRowContext(ctx, getOrCreateRateLimit, arg.ClientID, arg.Endpoint, arg.WindowStart)
+	row := q.db.QueryRow(ctx, getOrCreateRateLimit, arg.ClientID, arg.Endpoint, arg.WindowStart)
 	var i ApiRateLimit
 	err := row.Scan(
 		&i.ID,
@@ -88,7 +88,7 @@ type GetRateLimitByWindowParams struct {
 }
 
 func (q *Queries) GetRateLimitByWindow(ctx context.Context, arg GetRateLimitByWindowParams) (ApiRateLimit, error) {
-	row := q.db.QueryRowContext(ctx, getRateLimitByWindow, arg.ClientID, arg.Endpoint, arg.WindowStart)
+	row := q.db.QueryRow(ctx, getRateLimitByWindow, arg.ClientID, arg.Endpoint, arg.WindowStart)
 	var i ApiRateLimit
 	err := row.Scan(
 		&i.ID,
@@ -123,7 +123,7 @@ type GetRateLimitStatsRow struct {
 }
 
 func (q *Queries) GetRateLimitStats(ctx context.Context, limit int32) ([]GetRateLimitStatsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getRateLimitStats, limit)
+	rows, err := q.db.Query(ctx, getRateLimitStats, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -141,9 +141,6 @@ func (q *Queries) GetRateLimitStats(ctx context.Context, limit int32) ([]GetRate
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -175,7 +172,7 @@ type GetTopRateLimitedIPsRow struct {
 }
 
 func (q *Queries) GetTopRateLimitedIPs(ctx context.Context, arg GetTopRateLimitedIPsParams) ([]GetTopRateLimitedIPsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTopRateLimitedIPs, arg.RequestsCount, arg.Limit)
+	rows, err := q.db.Query(ctx, getTopRateLimitedIPs, arg.RequestsCount, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -188,9 +185,6 @@ func (q *Queries) GetTopRateLimitedIPs(ctx context.Context, arg GetTopRateLimite
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -206,7 +200,7 @@ RETURNING id, client_id, endpoint, requests_count, window_start, created_at, exc
 `
 
 func (q *Queries) RecordLoginAttempt(ctx context.Context, clientID string) (ApiRateLimit, error) {
-	row := q.db.QueryRowContext(ctx, recordLoginAttempt, clientID)
+	row := q.db.QueryRow(ctx, recordLoginAttempt, clientID)
 	var i ApiRateLimit
 	err := row.Scan(
 		&i.ID,
@@ -0,0 +1,53 @@
+// internal/db/schema.go - Schema introspection
+package db
+
+import (
+	"context"
+)
+
+const listSchemaColumns = `
+SELECT table_name, column_name
+FROM information_schema.columns
+WHERE table_schema = current_schema()
+`
+
+// SchemaColumns returns the columns of every table in the current schema,
+// keyed by table name
+func SchemaColumns(ctx context.Context, conn DBTX) (map[string]map[string]bool, error) {
+	rows, err := conn.Query(ctx, listSchemaColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		if tables[table] == nil {
+			tables[table] = make(map[string]bool)
+		}
+		tables[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+const currentRoleBypassesRLS = `
+SELECT rolsuper OR rolbypassrls
+FROM pg_roles
+WHERE rolname = current_user
+`
+
+// RoleBypassesRLS reports whether the connected role is exempt from row
+// level security, as superusers and BYPASSRLS roles are
+func RoleBypassesRLS(ctx context.Context, conn DBTX) (bool, error) {
+	var bypass bool
+	err := conn.QueryRow(ctx, currentRoleBypassesRLS).Scan(&bypass)
+	return bypass, err
+}
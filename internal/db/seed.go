@@ -0,0 +1,103 @@
+// internal/db/seed.go - Idempotent reference data seeding
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// referenceData mirrors the seed section of the initial migration so that a
+// database whose lookup tables were emptied can be restored without
+// re-running migrations. Every statement is safe to execute repeatedly.
+var referenceData = []struct {
+	name  string
+	query string
+}{
+	{"roles", `
+INSERT INTO roles (name) VALUES
+    ('admin'),
+    ('pharmacist'),
+    ('clerk')
+ON CONFLICT (name) DO NOTHING`},
+	{"categories", `
+INSERT INTO categories (name) VALUES
+    ('دارویی'),
+    ('آرایشی'),
+    ('بهداشتی'),
+    ('مکمل')
+ON CONFLICT (name) DO NOTHING`},
+	{"dosage_forms", `
+INSERT INTO dosage_forms (name) VALUES
+    ('قرص'),
+    ('کپسول'),
+    ('شربت'),
+    ('آمپول'),
+    ('قطره'),
+    ('پماد'),
+    ('ژل'),
+    ('اسپری')
+ON CONFLICT (name) DO NOTHING`},
+	{"permissions", `
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('view_products', 'products', 'read', 'View products'),
+    ('create_products', 'products', 'create', 'Create products'),
+    ('update_products', 'products', 'update', 'Update products'),
+    ('delete_products', 'products', 'delete', 'Delete products'),
+    ('view_orders', 'orders', 'read', 'View orders'),
+    ('create_orders', 'orders', 'create', 'Create orders'),
+    ('update_orders', 'orders', 'update', 'Update orders'),
+    ('delete_orders', 'orders', 'delete', 'Delete orders'),
+    ('view_users', 'users', 'read', 'View users'),
+    ('create_users', 'users', 'create', 'Create users'),
+    ('update_users', 'users', 'update', 'Update users'),
+    ('delete_users', 'users', 'delete', 'Delete users'),
+    ('view_audit_logs', 'audit', 'read', 'View audit logs'),
+    ('manage_permissions', 'permissions', 'manage', 'Manage permissions'),
+    ('manage_roles', 'roles', 'manage', 'Manage roles')
+ON CONFLICT (resource, action) DO NOTHING`},
+	{"role_permissions", `
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON
+    r.name = 'admin'
+    OR (r.name = 'pharmacist' AND p.name IN (
+        'view_products', 'create_products', 'update_products',
+        'view_orders', 'create_orders', 'update_orders'
+    ))
+    OR (r.name = 'clerk' AND p.action = 'read')
+ON CONFLICT DO NOTHING`},
+}
+
+// SeedResult reports how many rows each reference table gained
+type SeedResult struct {
+	Table    string
+	Inserted int64
+}
+
+// SeedReferenceData inserts the default roles, categories, dosage forms and
+// permissions in a single transaction. Existing rows are left untouched.
+func SeedReferenceData(ctx context.Context, database *pgxpool.Pool) ([]SeedResult, error) {
+	tx, err := database.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]SeedResult, 0, len(referenceData))
+	for _, seed := range referenceData {
+		res, err := tx.Exec(ctx, seed.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed %s: %w", seed.name, err)
+		}
+		results = append(results, SeedResult{Table: seed.name, Inserted: res.RowsAffected()})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit seed transaction: %w", err)
+	}
+
+	return results, nil
+}
@@ -28,7 +28,7 @@ type CompleteSystemSetupParams struct {
 }
 
 func (q *Queries) CompleteSystemSetup(ctx context.Context, arg CompleteSystemSetupParams) (SystemSetup, error) {
-	row := q.db.QueryRowContext(ctx, completeSystemSetup, arg.AdminCreated, arg.SetupByIp)
+	row := q.db.QueryRow(ctx, completeSystemSetup, arg.AdminCreated, arg.SetupByIp)
 	var i SystemSetup
 	err := row.Scan(
 		&i.ID,
@@ -43,19 +43,19 @@ func (q *Queries) CompleteSystemSetup(ctx context.Context, arg CompleteSystemSet
 const createAdminUser = `-- name: CreateAdminUser :one
 INSERT INTO users (id, username, full_name, password_hash, role_id, created_at)
 VALUES ($1, $2, $3, $4, $5, NOW())
-RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at
+RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account
 `
 
 type CreateAdminUserParams struct {
 	ID           uuid.UUID
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 }
 
 func (q *Queries) CreateAdminUser(ctx context.Context, arg CreateAdminUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createAdminUser,
+	row := q.db.QueryRow(ctx, createAdminUser,
 		arg.ID,
 		arg.Username,
 		arg.FullName,
@@ -71,6 +71,10 @@ func (q *Queries) CreateAdminUser(ctx context.Context, arg CreateAdminUserParams
 		&i.RoleID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
 	)
 	return i, err
 }
@@ -84,7 +88,7 @@ LIMIT 1
 
 // internal/db/query/setup.sql
 func (q *Queries) GetSystemSetupStatus(ctx context.Context) (SystemSetup, error) {
-	row := q.db.QueryRowContext(ctx, getSystemSetupStatus)
+	row := q.db.QueryRow(ctx, getSystemSetupStatus)
 	var i SystemSetup
 	err := row.Scan(
 		&i.ID,
@@ -105,7 +109,7 @@ SELECT EXISTS(
 `
 
 func (q *Queries) HasAdminUser(ctx context.Context) (bool, error) {
-	row := q.db.QueryRowContext(ctx, hasAdminUser)
+	row := q.db.QueryRow(ctx, hasAdminUser)
 	var has_admin bool
 	err := row.Scan(&has_admin)
 	return has_admin, err
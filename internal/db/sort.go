@@ -0,0 +1,44 @@
+// internal/db/sort.go - Caller-chosen order of list queries
+package db
+
+import (
+	"context"
+	"strings"
+)
+
+// sortMarker follows the ORDER BY of a list query whose order a request
+// may choose. The default order runs from it to the end of the line.
+const sortMarker = "ORDER BY /* sort */"
+
+type sortKey struct{}
+
+// WithSort attaches an ORDER BY list to ctx. List queries that carry the
+// sort marker and are issued through a TaggedDB with that context are
+// ordered by it instead of their default order; other statements are not
+// touched. The list must be built from a whitelist of columns, never from
+// request text, since it is spliced into the statement.
+func WithSort(ctx context.Context, orderBy string) context.Context {
+	if orderBy == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sortKey{}, orderBy)
+}
+
+// sortQuery replaces the default order of a list query with the one in
+// ctx, if any
+func sortQuery(ctx context.Context, query string) string {
+	orderBy, ok := ctx.Value(sortKey{}).(string)
+	if !ok {
+		return query
+	}
+	start := strings.Index(query, sortMarker)
+	if start < 0 {
+		return query
+	}
+	start += len(sortMarker)
+	end := strings.IndexByte(query[start:], '\n')
+	if end < 0 {
+		end = len(query) - start
+	}
+	return query[:start] + " " + orderBy + query[start+end:]
+}
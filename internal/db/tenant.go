@@ -0,0 +1,200 @@
+// internal/db/tenant.go - Scoping database sessions to a tenant
+package db
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultTenantID is the main tenant, which every row created before
+// tenancy existed belongs to
+var DefaultTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// TenantScope is the tenant, and optionally the department or the user
+// whose orders, a request acts for. Row level security on users, orders,
+// order_items and products reads it from the session settings
+// digiorder.tenant_id, digiorder.product_tenant_id,
+// digiorder.department_id and digiorder.owner_id; a session without them
+// sees every tenant, department and user's orders.
+type TenantScope struct {
+	ID            uuid.UUID // uuid.Nil sees every tenant
+	SharedCatalog bool      // new products go to the shared catalog
+	DepartmentID  int32     // 0 sees every department's orders
+	OwnerID       uuid.UUID // uuid.Nil sees every user's orders
+}
+
+// tenant is the digiorder.tenant_id setting for the scope
+func (s TenantScope) tenant() string {
+	if s.ID == uuid.Nil {
+		return ""
+	}
+	return s.ID.String()
+}
+
+// productTenant is the digiorder.product_tenant_id setting for the scope
+func (s TenantScope) productTenant() string {
+	if s.SharedCatalog {
+		return ""
+	}
+	return s.tenant()
+}
+
+// department is the digiorder.department_id setting for the scope
+func (s TenantScope) department() string {
+	if s.DepartmentID == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(s.DepartmentID))
+}
+
+// owner is the digiorder.owner_id setting for the scope
+func (s TenantScope) owner() string {
+	if s.OwnerID == uuid.Nil {
+		return ""
+	}
+	return s.OwnerID.String()
+}
+
+const (
+	setTenantSQL = `SELECT set_config('digiorder.tenant_id', $1, false), set_config('digiorder.product_tenant_id', $2, false),
+	set_config('digiorder.department_id', $3, false), set_config('digiorder.owner_id', $4, false)`
+	resetTenantSQL = `SELECT set_config('digiorder.tenant_id', '', false), set_config('digiorder.product_tenant_id', '', false),
+	set_config('digiorder.department_id', '', false), set_config('digiorder.owner_id', '', false)`
+)
+
+type tenantKey struct{}
+
+// pinnedConn is the connection a request was scoped on
+type pinnedConn struct {
+	conn  *pgxpool.Conn
+	scope TenantScope
+}
+
+// WithTenant attaches a tenant to ctx. Transactions begun with BeginTx
+// are scoped to it; other statements keep the scope of the connection
+// pinned by PinTenant, or see every tenant without one.
+func WithTenant(ctx context.Context, scope TenantScope) context.Context {
+	next := &pinnedConn{scope: scope}
+	if pinned, ok := ctx.Value(tenantKey{}).(*pinnedConn); ok {
+		next.conn = pinned.conn
+	}
+	return context.WithValue(ctx, tenantKey{}, next)
+}
+
+// TenantFromContext returns the tenant attached to ctx
+func TenantFromContext(ctx context.Context) (TenantScope, bool) {
+	pinned, ok := ctx.Value(tenantKey{}).(*pinnedConn)
+	if !ok {
+		return TenantScope{}, false
+	}
+	return pinned.scope, true
+}
+
+// PinTenant takes a connection from database, scopes its session to the
+// tenant and attaches both to the returned context, so every statement
+// issued through a TenantDB with that context sees only the tenant's
+// rows. release must be called once the request is done; it clears the
+// session before the connection goes back to the pool.
+func PinTenant(ctx context.Context, database *pgxpool.Pool, scope TenantScope) (context.Context, func(), error) {
+	conn, err := database.Acquire(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if _, err := conn.Exec(ctx, setTenantSQL, scope.tenant(), scope.productTenant(), scope.department(), scope.owner()); err != nil {
+		discardConn(conn)
+		return ctx, nil, err
+	}
+
+	release := func() {
+		// The request context may already be cancelled
+		resetCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(resetCtx, resetTenantSQL); err != nil {
+			discardConn(conn)
+			return
+		}
+		conn.Release()
+	}
+	return context.WithValue(ctx, tenantKey{}, &pinnedConn{conn: conn, scope: scope}), release, nil
+}
+
+// discardConn closes a connection whose session may still be scoped
+// instead of returning it to the pool
+func discardConn(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn.Hijack().Close(ctx)
+}
+
+// pinnedFromContext returns the connection a request was scoped on
+func pinnedFromContext(ctx context.Context) (*pgxpool.Conn, bool) {
+	pinned, ok := ctx.Value(tenantKey{}).(*pinnedConn)
+	if !ok || pinned.conn == nil {
+		return nil, false
+	}
+	return pinned.conn, true
+}
+
+// TenantDB issues statements on the connection pinned to the request by
+// PinTenant, and on the pool otherwise
+type TenantDB struct {
+	db *pgxpool.Pool
+}
+
+// NewTenantDB wraps db so statements run in the request's tenant
+func NewTenantDB(db *pgxpool.Pool) *TenantDB {
+	return &TenantDB{db: db}
+}
+
+func (t *TenantDB) conn(ctx context.Context) DBTX {
+	if conn, ok := pinnedFromContext(ctx); ok {
+		return conn
+	}
+	return t.db
+}
+
+func (t *TenantDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return t.conn(ctx).Exec(ctx, query, args...)
+}
+
+func (t *TenantDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return t.conn(ctx).Query(ctx, query, args...)
+}
+
+func (t *TenantDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return t.conn(ctx).QueryRow(ctx, query, args...)
+}
+
+// BeginTx begins a transaction on the request's pinned connection, or on
+// database without one, and scopes it to the tenant in ctx, if any. The
+// department and owner come from the pinned session.
+func BeginTx(ctx context.Context, database *pgxpool.Pool, opts pgx.TxOptions) (pgx.Tx, error) {
+	var tx pgx.Tx
+	var err error
+	if conn, ok := pinnedFromContext(ctx); ok {
+		tx, err = conn.BeginTx(ctx, opts)
+	} else {
+		tx, err = database.BeginTx(ctx, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if scope, ok := TenantFromContext(ctx); ok {
+		err := New(tx).ScopeToTenant(ctx, ScopeToTenantParams{
+			TenantID:        scope.tenant(),
+			ProductTenantID: scope.productTenant(),
+		})
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+	return tx, nil
+}
@@ -18,18 +18,18 @@ INSERT INTO users (
 ) VALUES (
     $1, $2, $3, $4
 )
-RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at
+RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account
 `
 
 type CreateUserParams struct {
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser,
+	row := q.db.QueryRow(ctx, createUser,
 		arg.Username,
 		arg.FullName,
 		arg.PasswordHash,
@@ -44,6 +44,10 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.RoleID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
 	)
 	return i, err
 }
@@ -53,17 +57,17 @@ DELETE FROM users WHERE id = $1
 `
 
 func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	_, err := q.db.Exec(ctx, deleteUser, id)
 	return err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at FROM users
+SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account FROM users
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUser, id)
+	row := q.db.QueryRow(ctx, getUser, id)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -73,17 +77,21 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.RoleID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at FROM users
+SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account FROM users
 WHERE username = $1 LIMIT 1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByUsername, username)
+	row := q.db.QueryRow(ctx, getUserByUsername, username)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -93,12 +101,16 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.RoleID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
 	)
 	return i, err
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at FROM users
+SELECT id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account FROM users
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -109,7 +121,7 @@ type ListUsersParams struct {
 }
 
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listUsers, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -125,37 +137,100 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.RoleID,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.TenantID,
+			&i.DepartmentID,
+			&i.Language,
+			&i.ServiceAccount,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+const setUserDepartment = `-- name: SetUserDepartment :one
+UPDATE users
+SET department_id = $2
+WHERE id = $1
+RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account
+`
+
+type SetUserDepartmentParams struct {
+	ID           uuid.UUID
+	DepartmentID sql.NullInt32
+}
+
+func (q *Queries) SetUserDepartment(ctx context.Context, arg SetUserDepartmentParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserDepartment, arg.ID, arg.DepartmentID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FullName,
+		&i.PasswordHash,
+		&i.RoleID,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
+	)
+	return i, err
+}
+
+const setUserLanguage = `-- name: SetUserLanguage :one
+UPDATE users
+SET language = $2
+WHERE id = $1
+RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account
+`
+
+type SetUserLanguageParams struct {
+	ID       uuid.UUID
+	Language sql.NullString
+}
+
+func (q *Queries) SetUserLanguage(ctx context.Context, arg SetUserLanguageParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserLanguage, arg.ID, arg.Language)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FullName,
+		&i.PasswordHash,
+		&i.RoleID,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET 
     full_name = COALESCE($2, full_name),
     role_id = COALESCE($3, role_id)
 WHERE id = $1
-RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at
+RETURNING id, username, full_name, password_hash, role_id, created_at, deleted_at, tenant_id, department_id, language, service_account
 `
 
 type UpdateUserParams struct {
 	ID       uuid.UUID
-	FullName sql.NullString
+	FullName EncryptedString
 	RoleID   sql.NullInt32
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.FullName, arg.RoleID)
+	row := q.db.QueryRow(ctx, updateUser, arg.ID, arg.FullName, arg.RoleID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -165,6 +240,10 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.RoleID,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
+		&i.DepartmentID,
+		&i.Language,
+		&i.ServiceAccount,
 	)
 	return i, err
 }
@@ -181,6 +260,6 @@ type UpdateUserPasswordParams struct {
 }
 
 func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
-	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
 	return err
 }
@@ -17,7 +17,7 @@ SELECT COUNT(*) FROM users WHERE deleted_at IS NULL
 `
 
 func (q *Queries) CountActiveUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countActiveUsers)
+	row := q.db.QueryRow(ctx, countActiveUsers)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -42,7 +42,7 @@ LIMIT 1
 type GetUserByUsernameWithRoleRow struct {
 	ID           uuid.UUID
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 	CreatedAt    sql.NullTime
@@ -51,7 +51,7 @@ type GetUserByUsernameWithRoleRow struct {
 }
 
 func (q *Queries) GetUserByUsernameWithRole(ctx context.Context, username string) (GetUserByUsernameWithRoleRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserByUsernameWithRole, username)
+	row := q.db.QueryRow(ctx, getUserByUsernameWithRole, username)
 	var i GetUserByUsernameWithRoleRow
 	err := row.Scan(
 		&i.ID,
@@ -85,7 +85,7 @@ LIMIT 1
 type GetUserWithRoleRow struct {
 	ID           uuid.UUID
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 	CreatedAt    sql.NullTime
@@ -94,7 +94,7 @@ type GetUserWithRoleRow struct {
 }
 
 func (q *Queries) GetUserWithRole(ctx context.Context, id uuid.UUID) (GetUserWithRoleRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserWithRole, id)
+	row := q.db.QueryRow(ctx, getUserWithRole, id)
 	var i GetUserWithRoleRow
 	err := row.Scan(
 		&i.ID,
@@ -135,7 +135,7 @@ type GetUsersByRoleParams struct {
 type GetUsersByRoleRow struct {
 	ID           uuid.UUID
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 	CreatedAt    sql.NullTime
@@ -144,7 +144,7 @@ type GetUsersByRoleRow struct {
 }
 
 func (q *Queries) GetUsersByRole(ctx context.Context, arg GetUsersByRoleParams) ([]GetUsersByRoleRow, error) {
-	rows, err := q.db.QueryContext(ctx, getUsersByRole, arg.RoleID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getUsersByRole, arg.RoleID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -166,9 +166,6 @@ func (q *Queries) GetUsersByRole(ctx context.Context, arg GetUsersByRoleParams)
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -201,7 +198,7 @@ type ListUsersWithRolesParams struct {
 type ListUsersWithRolesRow struct {
 	ID           uuid.UUID
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 	CreatedAt    sql.NullTime
@@ -212,7 +209,7 @@ type ListUsersWithRolesRow struct {
 // internal/db/query/users_optimized.sql
 // Optimized queries to fix N+1 problem
 func (q *Queries) ListUsersWithRoles(ctx context.Context, arg ListUsersWithRolesParams) ([]ListUsersWithRolesRow, error) {
-	rows, err := q.db.QueryContext(ctx, listUsersWithRoles, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listUsersWithRoles, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -234,9 +231,6 @@ func (q *Queries) ListUsersWithRoles(ctx context.Context, arg ListUsersWithRoles
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -273,7 +267,7 @@ type SearchUsersParams struct {
 type SearchUsersRow struct {
 	ID           uuid.UUID
 	Username     string
-	FullName     sql.NullString
+	FullName     EncryptedString
 	PasswordHash string
 	RoleID       sql.NullInt32
 	CreatedAt    sql.NullTime
@@ -282,7 +276,7 @@ type SearchUsersRow struct {
 }
 
 func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchUsers, arg.Column1, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, searchUsers, arg.Column1, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -304,9 +298,6 @@ func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]Sea
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
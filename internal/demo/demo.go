@@ -0,0 +1,279 @@
+// internal/demo/demo.go - Sample data for new deployments and frontend work
+package demo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/security"
+)
+
+// DefaultPassword signs in every demo user unless another is given
+const DefaultPassword = "Demo#Pharmacy2025"
+
+// ErrAlreadySeeded is returned when the demo users already exist
+var ErrAlreadySeeded = errors.New("demo data is already present")
+
+// Options controls how much demo data is created
+type Options struct {
+	Orders     int     // sample orders; 0 means 25
+	Password   string  // password of the demo users; empty means DefaultPassword
+	TaxPercent float64 // tax on the order totals, as pricing.tax_percent
+}
+
+// Result counts the rows the demo data added
+type Result struct {
+	Products   int `json:"products"`
+	Barcodes   int `json:"barcodes"`
+	Users      int `json:"users"`
+	Orders     int `json:"orders"`
+	OrderItems int `json:"order_items"`
+}
+
+// product is one catalog entry; the category and dosage form are the
+// names seeded with the reference data, and price is per unit
+type product struct {
+	name, brand, form, strength, unit, category, description string
+	price                                                    int
+}
+
+var products = []product{
+	{"Acetaminophen", "Daroupakhsh", "قرص", "500 mg", "box", "دارویی", "Pain and fever relief", 85000},
+	{"Ibuprofen", "Abidi", "قرص", "400 mg", "box", "دارویی", "Anti-inflammatory pain relief", 120000},
+	{"Amoxicillin", "Farabi", "کپسول", "500 mg", "box", "دارویی", "Penicillin antibiotic", 210000},
+	{"Azithromycin", "Tehran Chemie", "قرص", "250 mg", "box", "دارویی", "Macrolide antibiotic", 345000},
+	{"Cetirizine", "Razak", "شربت", "5 mg/5 ml", "bottle", "دارویی", "Antihistamine for allergies", 150000},
+	{"Metformin", "Osve", "قرص", "500 mg", "box", "دارویی", "Type 2 diabetes", 95000},
+	{"Atorvastatin", "Sobhan", "قرص", "20 mg", "box", "دارویی", "Cholesterol lowering", 280000},
+	{"Losartan", "Aburaihan", "قرص", "50 mg", "box", "دارویی", "Blood pressure", 190000},
+	{"Omeprazole", "Farabi", "کپسول", "20 mg", "box", "دارویی", "Acid reflux and ulcers", 230000},
+	{"Salbutamol", "Sina Darou", "اسپری", "100 mcg", "inhaler", "دارویی", "Asthma reliever", 410000},
+	{"Ceftriaxone", "Jaber Ebne Hayyan", "آمپول", "1 g", "vial", "دارویی", "Injectable antibiotic", 160000},
+	{"Diclofenac", "Darou Darman", "ژل", "1%", "tube", "دارویی", "Topical pain relief", 175000},
+	{"Betamethasone", "Behvazan", "پماد", "0.1%", "tube", "دارویی", "Topical corticosteroid", 98000},
+	{"Ciprofloxacin", "Sina Darou", "قطره", "0.3%", "bottle", "دارویی", "Antibiotic eye drops", 135000},
+	{"Vitamin D3", "Zahravi", "کپسول", "50000 IU", "box", "مکمل", "Vitamin D supplement", 260000},
+	{"Ferrous Sulfate", "Iran Hormone", "قرص", "50 mg", "box", "مکمل", "Iron supplement", 70000},
+	{"Multivitamin", "Daana", "قرص", "", "box", "مکمل", "Daily multivitamin", 320000},
+	{"Hand Sanitizer", "Shafa", "ژل", "70%", "bottle", "بهداشتی", "Alcohol hand sanitizer", 145000},
+	{"Sunscreen SPF 50", "Ardene", "", "50 ml", "tube", "آرایشی", "Broad spectrum sunscreen", 690000},
+	{"Moisturizing Cream", "Cinere", "", "75 ml", "tube", "آرایشی", "Cream for dry skin", 520000},
+}
+
+// Demo users are named demo_<role> for every role
+const userPrefix = "demo_"
+
+// cancelledStatus is the status of cancelled orders, which keep why in
+// order_cancellations
+const cancelledStatus = "cancelled"
+
+// Reasons the cancelled sample orders give
+var cancellationReasons = []string{"no_longer_needed", "duplicate", "out_of_stock", "supplier_unavailable"}
+
+// Notes of the sample orders
+var orderNotes = []string{
+	"Weekly restock",
+	"Urgent: running low before the weekend",
+	"Seasonal flu stock",
+	"",
+	"Requested by the night shift",
+}
+
+// Seed adds demo products with barcodes and stock, a user per role and
+// sample orders. Run it inside a transaction, after the reference data is
+// seeded. No domain events are recorded for the demo data.
+func Seed(ctx context.Context, q db.Querier, opts Options) (Result, error) {
+	if opts.Orders <= 0 {
+		opts.Orders = 25
+	}
+	if opts.Password == "" {
+		opts.Password = DefaultPassword
+	}
+
+	var result Result
+	roles, err := q.ListRoles(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list roles: %w", err)
+	}
+	if len(roles) == 0 {
+		return result, errors.New("no roles found; seed the reference data first")
+	}
+	if _, err := q.GetUserByUsername(ctx, userPrefix+roles[0].Name); err == nil {
+		return result, ErrAlreadySeeded
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return result, err
+	}
+
+	// The same fixed source every time, so every deployment shows the
+	// same demo data
+	rng := rand.New(rand.NewSource(1))
+
+	hash, err := security.HashPassword(opts.Password)
+	if err != nil {
+		return result, fmt.Errorf("demo password: %w", err)
+	}
+	users := make([]uuid.UUID, 0, len(roles))
+	for _, role := range roles {
+		user, err := q.CreateUser(ctx, db.CreateUserParams{
+			Username:     userPrefix + role.Name,
+			FullName:     db.EncryptedString{String: "Demo " + role.Name, Valid: true},
+			PasswordHash: hash,
+			RoleID:       sql.NullInt32{Int32: role.ID, Valid: true},
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create demo user %s: %w", userPrefix+role.Name, err)
+		}
+		users = append(users, user.ID)
+		result.Users++
+	}
+
+	categories, err := namedIDs(ctx, q.ListCategories, func(c db.Category) (string, int32) { return c.Name, c.ID })
+	if err != nil {
+		return result, err
+	}
+	forms, err := namedIDs(ctx, q.ListDosageForms, func(f db.DosageForm) (string, int32) { return f.Name, f.ID })
+	if err != nil {
+		return result, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(products))
+	for i, p := range products {
+		created, err := q.CreateProduct(ctx, db.CreateProductParams{
+			Name:         p.name,
+			Brand:        nullString(p.brand),
+			DosageFormID: nullID(forms, p.form),
+			Strength:     nullString(p.strength),
+			Unit:         nullString(p.unit),
+			CategoryID:   nullID(categories, p.category),
+			Description:  nullString(p.description),
+			Price:        price(p.price),
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create product %s: %w", p.name, err)
+		}
+		ids = append(ids, created.ID)
+		result.Products++
+
+		if _, err := q.CreateBarcode(ctx, db.CreateBarcodeParams{
+			ProductID:   uuid.NullUUID{UUID: created.ID, Valid: true},
+			Barcode:     ean13(fmt.Sprintf("626900%06d", i+1)),
+			BarcodeType: sql.NullString{String: "GTIN", Valid: true},
+		}); err != nil {
+			return result, fmt.Errorf("failed to create barcode of %s: %w", p.name, err)
+		}
+		result.Barcodes++
+
+		if _, err := q.UpsertProductStock(ctx, db.UpsertProductStockParams{
+			ProductID:    created.ID,
+			OnHand:       int32(rng.Intn(200)),
+			ReorderLevel: int32(10 + rng.Intn(40)),
+			UpdatedBy:    uuid.NullUUID{UUID: users[0], Valid: true},
+		}); err != nil {
+			return result, fmt.Errorf("failed to set stock of %s: %w", p.name, err)
+		}
+	}
+
+	statuses, err := q.ListOrderStatuses(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list order statuses: %w", err)
+	}
+	priorities := []string{"routine", "routine", "routine", "urgent", "stat"}
+	for i := 0; i < opts.Orders; i++ {
+		status := "draft"
+		if len(statuses) > 0 {
+			status = statuses[rng.Intn(len(statuses))].Code
+		}
+		createdBy := users[rng.Intn(len(users))]
+		order, err := q.CreateOrder(ctx, db.CreateOrderParams{
+			CreatedBy: uuid.NullUUID{UUID: createdBy, Valid: true},
+			Status:    status,
+			Notes:     nullString(orderNotes[rng.Intn(len(orderNotes))]),
+			Priority:  priorities[rng.Intn(len(priorities))],
+			NeededBy:  sql.NullTime{Time: time.Now().AddDate(0, 0, 1+rng.Intn(14)), Valid: rng.Intn(2) == 0},
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create order: %w", err)
+		}
+		result.Orders++
+
+		for _, n := range rng.Perm(len(ids))[:1+rng.Intn(5)] {
+			if _, err := q.CreateOrderItem(ctx, db.CreateOrderItemParams{
+				OrderID:      uuid.NullUUID{UUID: order.ID, Valid: true},
+				ProductID:    uuid.NullUUID{UUID: ids[n], Valid: true},
+				RequestedQty: int32(1 + rng.Intn(50)),
+				Unit:         nullString(products[n].unit),
+				UnitPrice:    price(products[n].price),
+			}); err != nil {
+				return result, fmt.Errorf("failed to create order item: %w", err)
+			}
+			result.OrderItems++
+		}
+		if _, err := q.RecalculateOrderTotals(ctx, db.RecalculateOrderTotalsParams{
+			TaxPercent: strconv.FormatFloat(opts.TaxPercent, 'f', -1, 64),
+			OrderID:    order.ID,
+		}); err != nil {
+			return result, fmt.Errorf("failed to total order: %w", err)
+		}
+
+		if status == cancelledStatus {
+			if _, err := q.CreateOrderCancellation(ctx, db.CreateOrderCancellationParams{
+				OrderID:     order.ID,
+				Reason:      cancellationReasons[rng.Intn(len(cancellationReasons))],
+				Note:        "Demo order",
+				CancelledBy: uuid.NullUUID{UUID: createdBy, Valid: true},
+			}); err != nil {
+				return result, fmt.Errorf("failed to record order cancellation: %w", err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// namedIDs maps the names of lookup rows to their IDs
+func namedIDs[T any](ctx context.Context, list func(context.Context) ([]T, error), key func(T) (string, int32)) (map[string]int32, error) {
+	rows, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int32, len(rows))
+	for _, row := range rows {
+		name, id := key(row)
+		ids[name] = id
+	}
+	return ids, nil
+}
+
+func nullID(ids map[string]int32, name string) sql.NullInt32 {
+	id, ok := ids[name]
+	return sql.NullInt32{Int32: id, Valid: ok}
+}
+
+// price formats a price for a NUMERIC column
+func price(amount int) sql.NullString {
+	return sql.NullString{String: strconv.Itoa(amount), Valid: true}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// ean13 appends the check digit to a 12-digit GTIN prefix
+func ean13(digits string) string {
+	sum := 0
+	for i, d := range digits {
+		n := int(d - '0')
+		if i%2 == 1 {
+			n *= 3
+		}
+		sum += n
+	}
+	return digits + fmt.Sprint((10-sum%10)%10)
+}
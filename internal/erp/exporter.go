@@ -0,0 +1,228 @@
+// internal/erp/exporter.go - Handing fulfilled orders to the ERP
+package erp
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Batch modes and outcomes, the mode and status columns of erp_batches
+const (
+	ModePush = "push"
+	ModePull = "pull"
+
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+var ordersExported = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "erp_orders_exported_total",
+		Help: "Orders handed to the ERP by mode (push, pull) and outcome (delivered, failed)",
+	},
+	[]string{"mode", "status"},
+)
+
+// Errors returned by the exporter
+var (
+	ErrNothingToExport = errors.New("no fulfilled orders are waiting for export")
+	ErrPushDisabled    = errors.New("no ERP push URL is configured")
+	ErrBatchSettled    = errors.New("the batch is no longer pending")
+)
+
+// TxFunc runs fn inside a database transaction
+type TxFunc func(ctx context.Context, fn func(q db.Querier) error) error
+
+// Config selects the orders to export and where pushes go
+type Config struct {
+	Statuses  []string // order statuses that count as fulfilled
+	BatchSize int      // orders per batch
+	PushURL   string
+	PushToken string // sent as a bearer token
+	Timeout   time.Duration
+}
+
+// Exporter hands fulfilled orders to the ERP in batches. An order is
+// exported once a delivered batch holds it: a pulled batch is delivered
+// when the ERP acknowledges it, a pushed one when the ERP accepts it.
+// Until then the order is offered again, so ERP imports should be
+// idempotent by order id.
+type Exporter struct {
+	queries db.Querier
+	withTx  TxFunc
+	mapping *Mapping
+	config  Config
+	client  *http.Client
+
+	// mu keeps this instance from building overlapping batches
+	mu sync.Mutex
+}
+
+// NewExporter creates an exporter writing documents with mapping
+func NewExporter(queries db.Querier, withTx TxFunc, mapping *Mapping, config Config) *Exporter {
+	return &Exporter{
+		queries: queries,
+		withTx:  withTx,
+		mapping: mapping,
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Mapping returns the document mapping
+func (e *Exporter) Mapping() *Mapping {
+	return e.mapping
+}
+
+// PushEnabled reports whether a push URL is configured
+func (e *Exporter) PushEnabled() bool {
+	return e.config.PushURL != ""
+}
+
+// Pull records a pending batch of the orders awaiting export and returns
+// it with its document. The orders stay available until Ack.
+func (e *Exporter) Pull(ctx context.Context, userID uuid.UUID) (db.ErpBatch, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	batch, doc, err := e.newBatch(ctx, ModePull, userID)
+	if err != nil {
+		return batch, nil, err
+	}
+	return batch, doc, nil
+}
+
+// Ack marks a pulled batch delivered, so its orders are not offered again
+func (e *Exporter) Ack(ctx context.Context, id uuid.UUID) (db.ErpBatch, error) {
+	batch, err := e.queries.FinishERPBatch(ctx, db.FinishERPBatchParams{ID: id, Status: StatusDelivered})
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := e.queries.GetERPBatch(ctx, id); err != nil {
+			return batch, err
+		}
+		return batch, ErrBatchSettled
+	}
+	if err != nil {
+		return batch, err
+	}
+	ordersExported.WithLabelValues(batch.Mode, StatusDelivered).Add(float64(batch.OrderCount))
+	return batch, nil
+}
+
+// Push sends the orders awaiting export to the push URL. The batch is
+// returned as delivered or, with the error, as failed.
+func (e *Exporter) Push(ctx context.Context, userID uuid.UUID) (db.ErpBatch, error) {
+	if !e.PushEnabled() {
+		return db.ErpBatch{}, ErrPushDisabled
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	batch, doc, err := e.newBatch(ctx, ModePush, userID)
+	if err != nil {
+		return batch, err
+	}
+
+	status, failure := StatusDelivered, sql.NullString{}
+	sendErr := e.send(ctx, batch.ID, doc)
+	if sendErr != nil {
+		status, failure = StatusFailed, sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+	// Record the outcome even when the request was cancelled mid-push
+	finished, err := e.queries.FinishERPBatch(context.WithoutCancel(ctx), db.FinishERPBatchParams{
+		ID:     batch.ID,
+		Status: status,
+		Error:  failure,
+	})
+	if err != nil {
+		return batch, err
+	}
+	ordersExported.WithLabelValues(ModePush, status).Add(float64(finished.OrderCount))
+	return finished, sendErr
+}
+
+// newBatch records a pending batch of the oldest orders awaiting export
+func (e *Exporter) newBatch(ctx context.Context, mode string, userID uuid.UUID) (db.ErpBatch, []byte, error) {
+	rows, err := e.queries.ListERPOrderRows(ctx, db.ListERPOrderRowsParams{
+		Statuses:   e.config.Statuses,
+		LimitCount: int32(e.config.BatchSize),
+	})
+	if err != nil {
+		return db.ErpBatch{}, nil, err
+	}
+	if len(rows) == 0 {
+		return db.ErpBatch{}, nil, ErrNothingToExport
+	}
+
+	doc, err := e.mapping.Render(rows)
+	if err != nil {
+		return db.ErpBatch{}, nil, err
+	}
+
+	var orderIDs []uuid.UUID
+	for i, r := range rows {
+		if i == 0 || r.OrderID != rows[i-1].OrderID {
+			orderIDs = append(orderIDs, r.OrderID)
+		}
+	}
+
+	var batch db.ErpBatch
+	err = e.withTx(ctx, func(q db.Querier) error {
+		var err error
+		batch, err = q.CreateERPBatch(ctx, db.CreateERPBatchParams{
+			Mode:       mode,
+			Format:     e.mapping.Format(),
+			OrderCount: int32(len(orderIDs)),
+			CreatedBy:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		if err != nil {
+			return err
+		}
+		return q.AddERPBatchOrders(ctx, db.AddERPBatchOrdersParams{BatchID: batch.ID, OrderIds: orderIDs})
+	})
+	return batch, doc, err
+}
+
+// send posts the document; any 2xx answer accepts the batch
+func (e *Exporter) send(ctx context.Context, batchID uuid.UUID, doc []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.PushURL, bytes.NewReader(doc))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", e.mapping.ContentType())
+	req.Header.Set("X-DigiOrder-Batch", batchID.String())
+	if e.config.PushToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.PushToken)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		// Keep credentials in the URL out of the recorded error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("ERP request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ERP returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+// internal/erp/mapping.go - Mapping order lines onto the ERP's fields
+package erp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// Document formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatXML  = "xml"
+)
+
+// Field is one field of the ERP document: the value of Source, or the
+// constant Value when Source is empty
+type Field struct {
+	Name   string
+	Source string
+	Value  string
+}
+
+// sources are the order line values a field can take. Fields from "order."
+// sources describe the order, the rest one of its lines.
+var sources = map[string]func(r db.ListERPOrderRowsRow) string{
+	"order.id":           func(r db.ListERPOrderRowsRow) string { return r.OrderID.String() },
+	"order.status":       func(r db.ListERPOrderRowsRow) string { return r.Status },
+	"order.priority":     func(r db.ListERPOrderRowsRow) string { return r.Priority },
+	"order.notes":        func(r db.ListERPOrderRowsRow) string { return r.Notes.String },
+	"order.created_at":   func(r db.ListERPOrderRowsRow) string { return formatTime(r.CreatedAt.Time, r.CreatedAt.Valid) },
+	"order.submitted_at": func(r db.ListERPOrderRowsRow) string { return formatTime(r.SubmittedAt.Time, r.SubmittedAt.Valid) },
+	"order.created_by":   func(r db.ListERPOrderRowsRow) string { return r.Username.String },
+	"item.id":            func(r db.ListERPOrderRowsRow) string { return r.ItemID.String() },
+	"item.quantity":      func(r db.ListERPOrderRowsRow) string { return strconv.Itoa(int(r.RequestedQty)) },
+	"item.unit": func(r db.ListERPOrderRowsRow) string {
+		if r.ItemUnit.Valid {
+			return r.ItemUnit.String
+		}
+		return r.ProductUnit.String
+	},
+	"item.note":            func(r db.ListERPOrderRowsRow) string { return r.ItemNote.String },
+	"product.id":           func(r db.ListERPOrderRowsRow) string { return nullUUID(r.ProductID) },
+	"product.name":         func(r db.ListERPOrderRowsRow) string { return r.ProductName.String },
+	"product.brand":        func(r db.ListERPOrderRowsRow) string { return r.Brand.String },
+	"product.strength":     func(r db.ListERPOrderRowsRow) string { return r.Strength.String },
+	"product.unit":         func(r db.ListERPOrderRowsRow) string { return r.ProductUnit.String },
+	"product.barcode":      func(r db.ListERPOrderRowsRow) string { return r.Barcode.String },
+	"product.irc":          func(r db.ListERPOrderRowsRow) string { return r.Irc.String },
+	"product.generic_code": func(r db.ListERPOrderRowsRow) string { return r.GenericCode.String },
+}
+
+// fieldName keeps names usable as XML elements and JSON keys
+var fieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Mapping turns order lines into an ERP document. CSV documents have one
+// row per line with the fields as columns; JSON and XML documents list the
+// orders with their order fields and a "lines" list of the other fields.
+type Mapping struct {
+	format string
+	fields []Field
+}
+
+// NewMapping checks fields against the known sources
+func NewMapping(format string, fields []Field) (*Mapping, error) {
+	switch format {
+	case FormatCSV, FormatJSON, FormatXML:
+	default:
+		return nil, fmt.Errorf("unsupported ERP format %q", format)
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("the ERP mapping has no fields")
+	}
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if !fieldName.MatchString(f.Name) || seen[f.Name] || f.Name == "lines" {
+			return nil, fmt.Errorf("ERP field name %q is invalid or repeated", f.Name)
+		}
+		seen[f.Name] = true
+		if _, ok := sources[f.Source]; f.Source != "" && !ok {
+			return nil, fmt.Errorf("ERP field %s: unknown source %q; sources are %s",
+				f.Name, f.Source, strings.Join(Sources(), ", "))
+		}
+	}
+	return &Mapping{format: format, fields: fields}, nil
+}
+
+// Sources lists the source names a field can use
+func Sources() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Format returns the document format
+func (m *Mapping) Format() string {
+	return m.format
+}
+
+// ContentType returns the media type of the document
+func (m *Mapping) ContentType() string {
+	switch m.format {
+	case FormatJSON:
+		return "application/json"
+	case FormatXML:
+		return "application/xml"
+	}
+	return "text/csv"
+}
+
+// Render writes the document for rows, which are ordered by order
+func (m *Mapping) Render(rows []db.ListERPOrderRowsRow) ([]byte, error) {
+	switch m.format {
+	case FormatJSON:
+		return m.renderJSON(rows)
+	case FormatXML:
+		return m.renderXML(rows)
+	}
+	return m.renderCSV(rows)
+}
+
+func (m *Mapping) value(f Field, r db.ListERPOrderRowsRow) string {
+	if f.Source == "" {
+		return f.Value
+	}
+	return sources[f.Source](r)
+}
+
+func (m *Mapping) renderCSV(rows []db.ListERPOrderRowsRow) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		header[i] = f.Name
+	}
+	w.Write(header)
+	for _, r := range rows {
+		record := make([]string, len(m.fields))
+		for i, f := range m.fields {
+			record[i] = m.value(f, r)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// orderFields splits the fields into those of the order (order sources and
+// constants) and those of its lines
+func (m *Mapping) orderFields() (order, line []Field) {
+	for _, f := range m.fields {
+		if f.Source == "" || strings.HasPrefix(f.Source, "order.") {
+			order = append(order, f)
+		} else {
+			line = append(line, f)
+		}
+	}
+	return order, line
+}
+
+// group calls fn with the rows of each order in turn
+func group(rows []db.ListERPOrderRowsRow, fn func(lines []db.ListERPOrderRowsRow)) {
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && rows[end].OrderID == rows[start].OrderID {
+			end++
+		}
+		fn(rows[start:end])
+		start = end
+	}
+}
+
+func (m *Mapping) renderJSON(rows []db.ListERPOrderRowsRow) ([]byte, error) {
+	orderFields, lineFields := m.orderFields()
+	orders := []orderedObject{}
+	group(rows, func(lines []db.ListERPOrderRowsRow) {
+		order := orderedObject{}
+		for _, f := range orderFields {
+			order = append(order, member{f.Name, m.value(f, lines[0])})
+		}
+		if len(lineFields) > 0 {
+			items := make([]orderedObject, len(lines))
+			for i, r := range lines {
+				for _, f := range lineFields {
+					items[i] = append(items[i], member{f.Name, m.value(f, r)})
+				}
+			}
+			order = append(order, member{"lines", items})
+		}
+		orders = append(orders, order)
+	})
+	return json.MarshalIndent(map[string]any{"orders": orders}, "", "  ")
+}
+
+// orderedObject is a JSON object that keeps the mapping's field order
+type orderedObject []member
+
+type member struct {
+	name  string
+	value any
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(m.name)
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m *Mapping) renderXML(rows []db.ListERPOrderRowsRow) ([]byte, error) {
+	orderFields, lineFields := m.orderFields()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	start := func(name string) { enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}}) }
+	end := func(name string) { enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}) }
+	field := func(name, value string) {
+		enc.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+	}
+
+	start("orders")
+	group(rows, func(lines []db.ListERPOrderRowsRow) {
+		start("order")
+		for _, f := range orderFields {
+			field(f.Name, m.value(f, lines[0]))
+		}
+		if len(lineFields) > 0 {
+			start("lines")
+			for _, r := range lines {
+				start("line")
+				for _, f := range lineFields {
+					field(f.Name, m.value(f, r))
+				}
+				end("line")
+			}
+			end("lines")
+		}
+		end("order")
+	})
+	end("orders")
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func formatTime(t time.Time, valid bool) string {
+	if !valid {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func nullUUID(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
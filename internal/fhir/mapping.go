@@ -0,0 +1,207 @@
+// internal/fhir/mapping.go - Conversion of DigiOrder rows to FHIR resources
+package fhir
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+const ucumSystem = "http://unitsofmeasure.org"
+
+// ucumCodes maps the unit spellings used in strengths onto UCUM codes
+var ucumCodes = map[string]string{
+	"mg": "mg", "g": "g", "mcg": "ug", "µg": "ug", "ug": "ug",
+	"ml": "mL", "l": "L", "iu": "[iU]", "%": "%",
+}
+
+// strengthPattern matches simple strengths such as "500mg" or "2.5 mg/ml"
+var strengthPattern = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*([A-Za-zµ%]+)(?:\s*/\s*(\d+(?:\.\d+)?)?\s*([A-Za-z]+))?\s*$`)
+
+// Mapper converts rows to resources. FHIR IDs come from the persisted
+// mapping; the local IDs are carried as identifiers so either side can
+// round-trip.
+type Mapper struct {
+	SystemBase string // e.g. urn:digiorder
+}
+
+// System returns the identifier system for kind (product, order, ...)
+func (m Mapper) System(kind string) string {
+	if strings.HasPrefix(m.SystemBase, "urn:") {
+		return m.SystemBase + ":" + kind
+	}
+	return strings.TrimRight(m.SystemBase, "/") + "/" + kind
+}
+
+// Medication maps a product. dosageForm is the dosage form's name.
+func (m Mapper) Medication(p db.Product, fhirID, dosageForm string) Medication {
+	med := Medication{
+		ResourceType: ResourceMedication,
+		ID:           fhirID,
+		Identifier:   []Identifier{{System: m.System("product"), Value: p.ID.String()}},
+		Code:         &CodeableConcept{Text: strings.TrimSpace(p.Name + " " + p.Strength.String)},
+		Status:       "active",
+	}
+	if p.DeletedAt.Valid || p.Status == "staging" {
+		med.Status = "inactive"
+	}
+	if p.Irc.Valid {
+		med.Identifier = append(med.Identifier, Identifier{System: m.System("irc"), Value: p.Irc.String})
+	}
+	if p.Brand.String != "" {
+		med.Manufacturer = &Reference{Display: p.Brand.String}
+	}
+	if dosageForm != "" {
+		med.Form = &CodeableConcept{Text: dosageForm}
+	}
+	if strength := parseStrength(p.Strength.String, p.Unit.String); strength != nil {
+		med.Ingredient = []MedicationIngredient{{
+			ItemCodeableConcept: &CodeableConcept{Text: p.Name},
+			IsActive:            true,
+			Strength:            strength,
+		}}
+	}
+	return med
+}
+
+// MedicationRequest maps one order item. medication is the reference to
+// the item's product and requester the ordering user.
+func (m Mapper) MedicationRequest(order db.Order, item db.OrderItem, fhirID string, medication, requester *Reference) MedicationRequest {
+	req := MedicationRequest{
+		ResourceType:        ResourceMedicationRequest,
+		ID:                  fhirID,
+		Identifier:          []Identifier{{System: m.System("order-item"), Value: item.ID.String()}},
+		GroupIdentifier:     &Identifier{System: m.System("order"), Value: order.ID.String()},
+		Status:              RequestStatus(order.Status),
+		Intent:              "order",
+		Priority:            order.Priority,
+		MedicationReference: medication,
+		// Ward stock orders are not for a patient
+		Subject:   Reference{Display: "Pharmacy stock"},
+		Requester: requester,
+		DispenseRequest: &DispenseRequest{Quantity: &Quantity{
+			Value: float64(item.RequestedQty),
+			Unit:  item.Unit.String,
+		}},
+	}
+	if order.DeletedAt.Valid {
+		req.Status = "cancelled"
+	}
+	if t := order.SubmittedAt; t.Valid {
+		authored := t.Time.UTC()
+		req.AuthoredOn = &authored
+	} else if t := order.CreatedAt; t.Valid {
+		authored := t.Time.UTC()
+		req.AuthoredOn = &authored
+	}
+	if order.Notes.String != "" {
+		req.Note = append(req.Note, Annotation{Text: order.Notes.String})
+	}
+	if item.Note.String != "" {
+		req.Note = append(req.Note, Annotation{Text: item.Note.String})
+	}
+	return req
+}
+
+// UserReference refers to a DigiOrder user by identifier
+func (m Mapper) UserReference(u db.User) *Reference {
+	display := u.FullName.String
+	if display == "" {
+		display = u.Username
+	}
+	return &Reference{
+		Identifier: &Identifier{System: m.System("user"), Value: u.ID.String()},
+		Display:    display,
+	}
+}
+
+// NewBundle starts a bundle of the given type (collection or searchset)
+func NewBundle(bundleType string) Bundle {
+	return Bundle{
+		ResourceType: ResourceBundle,
+		Type:         bundleType,
+		Timestamp:    time.Now().UTC(),
+		Entry:        []BundleEntry{},
+	}
+}
+
+// RequestStatus maps an order status onto the MedicationRequest status
+// value set
+func RequestStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "draft":
+		return "draft"
+	case "submitted", "approved", "processing":
+		return "active"
+	case "completed", "delivered", "fulfilled":
+		return "completed"
+	case "cancelled", "canceled", "rejected":
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// NewOperationOutcome describes an error for FHIR clients. code is the
+// DigiOrder error code, as in the regular API.
+func NewOperationOutcome(status int, code, diagnostics string) OperationOutcome {
+	issueCode := "exception"
+	switch status {
+	case http.StatusBadRequest:
+		issueCode = "invalid"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		issueCode = "security"
+	case http.StatusNotFound:
+		issueCode = "not-found"
+	}
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []Issue{{
+			Severity:    "error",
+			Code:        issueCode,
+			Details:     &CodeableConcept{Text: code},
+			Diagnostics: diagnostics,
+		}},
+	}
+}
+
+// parseStrength turns "500mg" (per unit) or "250mg/5ml" into a ratio, or
+// returns nil for anything it cannot read
+func parseStrength(strength, unit string) *Ratio {
+	match := strengthPattern.FindStringSubmatch(strength)
+	if match == nil {
+		return nil
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil
+	}
+
+	ratio := &Ratio{Numerator: ucumQuantity(value, match[2])}
+	switch {
+	case match[4] != "":
+		per := 1.0
+		if match[3] != "" {
+			per, _ = strconv.ParseFloat(match[3], 64)
+		}
+		ratio.Denominator = ucumQuantity(per, match[4])
+	case unit != "":
+		ratio.Denominator = &Quantity{Value: 1, Unit: unit}
+	default:
+		ratio.Denominator = &Quantity{Value: 1}
+	}
+	return ratio
+}
+
+// ucumQuantity codes the unit in UCUM when it is a known spelling
+func ucumQuantity(value float64, unit string) *Quantity {
+	q := &Quantity{Value: value, Unit: unit}
+	if code, ok := ucumCodes[strings.ToLower(unit)]; ok {
+		q.System, q.Code = ucumSystem, code
+	}
+	return q
+}
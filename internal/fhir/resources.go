@@ -0,0 +1,144 @@
+// internal/fhir/resources.go - FHIR R4 resource subset used by the export
+package fhir
+
+import "time"
+
+// ContentType is the media type of FHIR JSON responses
+const ContentType = "application/fhir+json"
+
+// Resource types, also the resource_type column of fhir_resource_ids
+const (
+	ResourceMedication        = "Medication"
+	ResourceMedicationRequest = "MedicationRequest"
+	ResourceBundle            = "Bundle"
+)
+
+// Identifier ties a resource to its DigiOrder row
+type Identifier struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+// Coding is a code from a terminology
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// CodeableConcept is a concept given as text and/or codings
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Reference points at another resource
+type Reference struct {
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+	Display    string      `json:"display,omitempty"`
+}
+
+// Quantity is a measured amount
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Ratio is a numerator/denominator pair, e.g. 500 mg per tablet
+type Ratio struct {
+	Numerator   *Quantity `json:"numerator,omitempty"`
+	Denominator *Quantity `json:"denominator,omitempty"`
+}
+
+// Annotation is a free-text note
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+// Medication describes a product
+type Medication struct {
+	ResourceType string                 `json:"resourceType"`
+	ID           string                 `json:"id"`
+	Identifier   []Identifier           `json:"identifier"`
+	Code         *CodeableConcept       `json:"code,omitempty"`
+	Status       string                 `json:"status"`
+	Manufacturer *Reference             `json:"manufacturer,omitempty"`
+	Form         *CodeableConcept       `json:"form,omitempty"`
+	Ingredient   []MedicationIngredient `json:"ingredient,omitempty"`
+}
+
+// MedicationIngredient is an active ingredient and its strength
+type MedicationIngredient struct {
+	ItemCodeableConcept *CodeableConcept `json:"itemCodeableConcept"`
+	IsActive            bool             `json:"isActive"`
+	Strength            *Ratio           `json:"strength,omitempty"`
+}
+
+// MedicationRequest is one order item
+type MedicationRequest struct {
+	ResourceType        string           `json:"resourceType"`
+	ID                  string           `json:"id"`
+	Identifier          []Identifier     `json:"identifier"`
+	GroupIdentifier     *Identifier      `json:"groupIdentifier,omitempty"`
+	Status              string           `json:"status"`
+	Intent              string           `json:"intent"`
+	Priority            string           `json:"priority,omitempty"`
+	MedicationReference *Reference       `json:"medicationReference"`
+	Subject             Reference        `json:"subject"`
+	AuthoredOn          *time.Time       `json:"authoredOn,omitempty"`
+	Requester           *Reference       `json:"requester,omitempty"`
+	Note                []Annotation     `json:"note,omitempty"`
+	DispenseRequest     *DispenseRequest `json:"dispenseRequest,omitempty"`
+}
+
+// DispenseRequest carries the requested quantity
+type DispenseRequest struct {
+	Quantity *Quantity `json:"quantity,omitempty"`
+}
+
+// Bundle is a collection of resources (an order) or a search result page
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	ID           string        `json:"id,omitempty"`
+	Identifier   *Identifier   `json:"identifier,omitempty"`
+	Type         string        `json:"type"`
+	Timestamp    time.Time     `json:"timestamp"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+// BundleLink is a paging link of a searchset
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+// BundleEntry wraps one resource in a bundle
+type BundleEntry struct {
+	FullURL  string       `json:"fullUrl,omitempty"`
+	Resource any          `json:"resource"`
+	Search   *EntrySearch `json:"search,omitempty"`
+}
+
+// EntrySearch marks why an entry is in a searchset
+type EntrySearch struct {
+	Mode string `json:"mode"` // match or include
+}
+
+// OperationOutcome reports an error to FHIR clients
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+// Issue is one problem in an OperationOutcome
+type Issue struct {
+	Severity    string           `json:"severity"`
+	Code        string           `json:"code"`
+	Details     *CodeableConcept `json:"details,omitempty"`
+	Diagnostics string           `json:"diagnostics,omitempty"`
+}
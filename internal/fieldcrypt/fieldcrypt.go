@@ -0,0 +1,169 @@
+// internal/fieldcrypt/fieldcrypt.go - AES-GCM encryption of column values
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// prefix starts every encrypted value: "enc:v1:<key id>:<base64 nonce and
+// ciphertext>". Values without it are plain text.
+const prefix = "enc:v1:"
+
+// KeySize is the length of a key in bytes (AES-256)
+const KeySize = 32
+
+var keyID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ErrUnknownKey is returned for a value encrypted under a key that is not
+// in the keyring
+var ErrUnknownKey = errors.New("value is encrypted under an unknown key")
+
+// Keyring holds the keys values are encrypted with. The active key
+// encrypts; every key decrypts.
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// Key is a named AES-256 key
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// NewKeyring builds a keyring whose first key is the active one
+func NewKeyring(keys []Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no keys")
+	}
+	k := &Keyring{active: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if !keyID.MatchString(key.ID) {
+			return nil, fmt.Errorf("key id %q must be 1 to 32 letters, digits, - or _", key.ID)
+		}
+		if _, dup := k.aeads[key.ID]; dup {
+			return nil, fmt.Errorf("key %q is listed twice", key.ID)
+		}
+		if len(key.Secret) != KeySize {
+			return nil, fmt.Errorf("key %q is %d bytes, want %d", key.ID, len(key.Secret), KeySize)
+		}
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[key.ID] = aead
+	}
+	return k, nil
+}
+
+// Load builds a keyring from "id:base64" specs, or "id:kms:base64" specs
+// whose data key is wrapped by AWS KMS and unwrapped through kms. No specs
+// is no keyring.
+func Load(ctx context.Context, specs []string, kms KMSConfig) (*Keyring, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	keys := make([]Key, 0, len(specs))
+	for _, spec := range specs {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		if !ok {
+			return nil, fmt.Errorf("key %q must be id:base64 or id:kms:base64", id)
+		}
+		wrapped, isKMS := strings.CutPrefix(encoded, "kms:")
+		if isKMS {
+			encoded = wrapped
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		if isKMS {
+			if secret, err = kms.Decrypt(ctx, secret); err != nil {
+				return nil, fmt.Errorf("unwrapping key %q: %w", id, err)
+			}
+		}
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	return NewKeyring(keys)
+}
+
+// ActiveID is the id of the key new values are encrypted with
+func (k *Keyring) ActiveID() string {
+	return k.active
+}
+
+// ActivePrefix starts every value encrypted under the active key; values
+// without it need re-encrypting
+func (k *Keyring) ActivePrefix() string {
+	return prefix + k.active + ":"
+}
+
+// Encrypt encrypts plaintext under the active key
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	aead := k.aeads[k.active]
+	header := k.ActivePrefix()
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(header))
+	return header + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value made by Encrypt with any key of the keyring
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return nil, errors.New("value is not encrypted")
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return nil, errors.New("encrypted value has no key id")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted value is malformed")
+	}
+	header := value[:len(value)-len(encoded)]
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(header))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting value under key %q: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted reports whether value was made by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+var current atomic.Pointer[Keyring]
+
+// Use makes k the keyring encrypted columns are read and written with; nil
+// stores new values as plain text
+func Use(k *Keyring) {
+	current.Store(k)
+}
+
+// Current is the keyring set by Use, or nil
+func Current() *Keyring {
+	return current.Load()
+}
@@ -0,0 +1,46 @@
+// internal/fieldcrypt/kms.go - Unwrapping data keys with AWS KMS
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/awsapi"
+)
+
+// KMSConfig reaches AWS KMS (or a compatible service) to decrypt data
+// keys, e.g. made with "aws kms generate-data-key --key-spec AES_256". The
+// endpoint defaults to the region's.
+type KMSConfig struct {
+	Region       string
+	Endpoint     string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Timeout      time.Duration
+}
+
+// Decrypt returns the plaintext of a KMS ciphertext blob
+func (c KMSConfig) Decrypt(ctx context.Context, blob []byte) ([]byte, error) {
+	client := awsapi.Client{
+		Service:      "kms",
+		Region:       c.Region,
+		Endpoint:     c.Endpoint,
+		AccessKey:    c.AccessKey,
+		SecretKey:    c.SecretKey,
+		SessionToken: c.SessionToken,
+		Timeout:      c.Timeout,
+	}
+	var out struct {
+		Plaintext []byte `json:"Plaintext"` // base64 in JSON
+	}
+	err := client.Call(ctx, "TrentService.Decrypt", map[string]string{
+		"CiphertextBlob": base64.StdEncoding.EncodeToString(blob),
+	}, &out)
+	if err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	return out.Plaintext, nil
+}
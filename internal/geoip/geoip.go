@@ -0,0 +1,107 @@
+// internal/geoip/geoip.go - Country, city and network of IP addresses
+package geoip
+
+import (
+	"fmt"
+	"net"
+)
+
+// Location is where an address is, as far as the databases know. Empty
+// fields are unknown.
+type Location struct {
+	Country   string  `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	City      string  `json:"city,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	ASN       uint32  `json:"asn,omitempty"`
+	ASOrg     string  `json:"as_org,omitempty"`
+}
+
+// Known reports whether anything is known about the address
+func (l Location) Known() bool {
+	return l != Location{}
+}
+
+// Positioned reports whether the coordinates of the address are known
+func (l Location) Positioned() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// Resolver looks addresses up in a city and an ASN database, either of
+// which may be missing. A nil Resolver knows nothing.
+type Resolver struct {
+	city *Reader
+	asn  *Reader
+}
+
+// Open loads the databases at cityPath and asnPath; an empty path skips
+// that database. Without either, Open returns nil.
+func Open(cityPath, asnPath string) (*Resolver, error) {
+	if cityPath == "" && asnPath == "" {
+		return nil, nil
+	}
+
+	r := &Resolver{}
+	if cityPath != "" {
+		reader, err := OpenReader(cityPath)
+		if err != nil {
+			return nil, fmt.Errorf("opening city database: %w", err)
+		}
+		r.city = reader
+	}
+	if asnPath != "" {
+		reader, err := OpenReader(asnPath)
+		if err != nil {
+			return nil, fmt.Errorf("opening ASN database: %w", err)
+		}
+		r.asn = reader
+	}
+	return r, nil
+}
+
+// Lookup returns the location of ip. Unparsable, private and unknown
+// addresses have an empty location.
+func (r *Resolver) Lookup(ip string) Location {
+	var loc Location
+	addr := net.ParseIP(ip)
+	if r == nil || addr == nil || addr.IsPrivate() || addr.IsLoopback() {
+		return loc
+	}
+
+	if r.city != nil {
+		if record, err := r.city.Lookup(addr); err == nil && record != nil {
+			loc.Country = str(record, "country", "iso_code")
+			if loc.Country == "" {
+				loc.Country = str(record, "registered_country", "iso_code")
+			}
+			loc.City = str(record, "city", "names", "en")
+			if position, ok := record["location"].(map[string]any); ok {
+				loc.Latitude, _ = position["latitude"].(float64)
+				loc.Longitude, _ = position["longitude"].(float64)
+			}
+		}
+	}
+	if r.asn != nil {
+		if record, err := r.asn.Lookup(addr); err == nil && record != nil {
+			if n, ok := record["autonomous_system_number"].(uint64); ok {
+				loc.ASN = uint32(n)
+			}
+			loc.ASOrg = str(record, "autonomous_system_organization")
+		}
+	}
+	return loc
+}
+
+// str reads the string at path in a record
+func str(record map[string]any, path ...string) string {
+	var value any = record
+	for _, key := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
@@ -0,0 +1,342 @@
+// internal/geoip/mmdb.go - Reader for MaxMind DB (.mmdb) files
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of the file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the run of zero bytes between the search tree
+// and the data section
+const dataSectionSeparator = 16
+
+// errCorrupt is returned when the file does not follow the format
+var errCorrupt = errors.New("geoip: corrupt database")
+
+// Data section field types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// Reader looks addresses up in a MaxMind DB file held in memory. It is
+// safe for concurrent use.
+type Reader struct {
+	// DatabaseType is the kind of database, e.g. GeoLite2-City
+	DatabaseType string
+
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+// OpenReader reads the database at path
+func OpenReader(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(buf)
+}
+
+// NewReader reads a database from its contents
+func NewReader(buf []byte) (*Reader, error) {
+	at := bytes.LastIndex(buf, metadataMarker)
+	if at < 0 {
+		return nil, errors.New("geoip: not a MaxMind DB file")
+	}
+	meta := buf[at+len(metadataMarker):]
+	value, _, err := (&decoder{buf: meta}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: reading metadata: %w", err)
+	}
+	metadata, ok := value.(map[string]any)
+	if !ok {
+		return nil, errCorrupt
+	}
+
+	r := &Reader{buf: buf}
+	r.DatabaseType, _ = metadata["database_type"].(string)
+	r.nodeCount = uint(asUint(metadata["node_count"]))
+	r.recordSize = uint(asUint(metadata["record_size"]))
+	r.ipVersion = uint(asUint(metadata["ip_version"]))
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("geoip: unsupported record size %d", r.recordSize)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(at) {
+		return nil, errCorrupt
+	}
+	r.data = buf[treeSize+dataSectionSeparator : at]
+
+	// IPv4 addresses sit under 96 zero bits in an IPv6 tree
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup returns the record for ip, or nil when the database has none
+func (r *Reader) Lookup(ip net.IP) (map[string]any, error) {
+	node, bits := r.ipv4Start, 32
+	addr := ip.To4()
+	if addr == nil {
+		if r.ipVersion != 6 {
+			return nil, nil
+		}
+		addr, node, bits = ip.To16(), 0, 128
+		if addr == nil {
+			return nil, nil
+		}
+	}
+
+	for i := 0; i < bits && node < r.nodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-uint(i&7))) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount+dataSectionSeparator {
+		return nil, errCorrupt
+	}
+
+	offset := node - r.nodeCount - dataSectionSeparator
+	value, _, err := (&decoder{buf: r.data}).decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+// record is the left (bit 0) or right (bit 1) record of node
+func (r *Reader) record(node, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decoder reads values of the data section
+type decoder struct {
+	buf []byte
+}
+
+// decode reads the value at offset and returns it with the offset of what
+// follows it
+func (d *decoder) decode(offset uint) (any, uint, error) {
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if kind == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target)
+		return value, next, err
+	}
+	return d.value(kind, size, offset)
+}
+
+// control reads a field's control byte(s): its type, its size and where
+// its payload starts. The size of a pointer is its raw control bits.
+func (d *decoder) control(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errCorrupt
+	}
+	ctrl := d.buf[offset]
+	offset++
+	kind := int(ctrl >> 5)
+	if kind == typePointer {
+		return kind, uint(ctrl & 0x1F), offset, nil
+	}
+	if kind == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errCorrupt
+		}
+		kind = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errCorrupt
+		}
+		extra := uint(0)
+		for _, b := range d.buf[offset : offset+n] {
+			extra = extra<<8 | uint(b)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return kind, size, offset, nil
+}
+
+// pointer reads the target of a pointer whose control bits are bits
+func (d *decoder) pointer(bits, offset uint) (uint, uint, error) {
+	n := (bits>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errCorrupt
+	}
+	target := uint(0)
+	if n < 4 {
+		target = bits & 0x7
+	}
+	for _, b := range d.buf[offset : offset+n] {
+		target = target<<8 | uint(b)
+	}
+	switch n {
+	case 2:
+		target += 2048
+	case 3:
+		target += 526336
+	}
+	return target, offset + n, nil
+}
+
+// value reads a field of the given type and size whose payload starts at
+// offset
+func (d *decoder) value(kind int, size, offset uint) (any, uint, error) {
+	switch kind {
+	case typeMap:
+		m := make(map[string]any, size)
+		for range size {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			m[name], offset, err = d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, size)
+		for i := range a {
+			var err error
+			if a[i], offset, err = d.decode(offset); err != nil {
+				return nil, 0, err
+			}
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errCorrupt
+	}
+	payload := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch kind {
+	case typeString:
+		return string(payload), next, nil
+	case typeBytes:
+		return append([]byte(nil), payload...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(payload))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		n := uint64(0)
+		for _, b := range payload {
+			n = n<<8 | uint64(b)
+		}
+		return n, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errCorrupt
+		}
+		n := uint32(0)
+		for _, b := range payload {
+			n = n<<8 | uint32(b)
+		}
+		if size == 4 {
+			return int64(int32(n)), next, nil
+		}
+		return int64(n), next, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, errCorrupt
+		}
+		return new(big.Int).SetBytes(payload), next, nil
+	}
+	return nil, 0, fmt.Errorf("geoip: unknown field type %d", kind)
+}
+
+// asUint reads an unsigned metadata value
+func asUint(v any) uint64 {
+	n, _ := v.(uint64)
+	return n
+}
@@ -0,0 +1,164 @@
+// internal/i18n/en.go - English error messages
+package i18n
+
+var english = map[string]string{
+	// Malformed or invalid requests
+	"invalid_request":         "The request is not valid.",
+	"validation_error":        "Some fields of the request are missing or not valid.",
+	"unknown_field":           "The request has a field this endpoint does not accept.",
+	"invalid_id":              "The provided ID is not valid.",
+	"invalid_format":          "The requested format is not supported.",
+	"invalid_limit":           "The limit is out of range.",
+	"invalid_offset":          "The offset is out of range.",
+	"invalid_cursor":          "The cursor is not valid; start again from the first page.",
+	"invalid_sort":            "The list cannot be sorted that way.",
+	"invalid_include":         "The related resource cannot be included.",
+	"invalid_user_id":         "The user ID is not valid.",
+	"invalid_order_id":        "The order ID is not valid.",
+	"invalid_product_id":      "The product ID is not valid.",
+	"invalid_role_id":         "The role ID is not valid.",
+	"invalid_permission_id":   "The permission ID is not valid.",
+	"invalid_attachment_id":   "The attachment ID is not valid.",
+	"invalid_token_id":        "The token ID is not valid.",
+	"invalid_product":         "The product does not exist.",
+	"invalid_role":            "The role does not exist.",
+	"invalid_category":        "The category does not exist.",
+	"invalid_dosage_form":     "The dosage form does not exist.",
+	"invalid_email":           "The email address is not valid.",
+	"invalid_phone":           "The phone number is not valid.",
+	"invalid_channel":         "The notification channel is not supported.",
+	"invalid_event_type":      "The event type is not supported.",
+	"invalid_retry_after":     "The retry delay is not valid.",
+	"missing_required_field":  "A required field is missing.",
+	"missing_parameters":      "Required parameters are missing.",
+	"missing_query":           "A search query is required.",
+	"missing_barcode":         "A barcode is required.",
+	"missing_username":        "A username is required.",
+	"query_too_short":         "The search query is too short.",
+	"password_mismatch":       "The passwords do not match.",
+	"foreign_key_violation":   "The request refers to a record that does not exist.",
+	"constraint_violation":    "The request breaks a data rule.",
+	"unsupported_preference":  "This notification preference is not supported.",
+	"unsupported_api_version": "The requested API version is not supported.",
+	"unsupported_language":    "The language is not supported.",
+	"invalid_registry_file":   "The drug registry file could not be read.",
+	"registry_not_configured": "The drug registry is not configured.",
+	"invalid_outcome":         "The outcome is not valid.",
+	"product_in_staging":      "The product is waiting for review in the registry import.",
+	"reason_required":         "A reason is required for controlled substances.",
+	"missing_file":            "A file is required.",
+	"invalid_file":            "The file could not be read.",
+	"invalid_range":           "The date range is not valid.",
+	"invalid_report":          "The report is not valid.",
+	"invalid_frequency":       "The schedule is not valid.",
+	"invalid_recipient":       "The recipient is not valid.",
+	"invalid_columns":         "One or more columns are not valid.",
+	"unknown_printer":         "The printer is not configured.",
+	"empty_order":             "The order has no items.",
+	"invalid_scope":           "One or more access token scopes are not valid.",
+	"invalid_granularity":     "The time granularity is not valid.",
+	"invalid_group_by":        "The grouping is not valid.",
+	"invalid_definition":      "The report definition is not valid.",
+	"invalid_rbac_document":   "The roles and permissions document is not valid.",
+	"invalid_saved_report":    "The saved report is not valid.",
+	"invalid_slug":            "The slug must be lowercase letters and digits separated by hyphens.",
+	"weak_password":           "The password is too weak.",
+	"invalid_department":      "The department does not exist.",
+	"invalid_requester":       "The requester does not exist.",
+	"invalid_supplier":        "The supplier does not exist.",
+	"invalid_order_item":      "The item is not on this order.",
+	"cancel_required":         "Cancel orders with a reason through the cancel endpoint.",
+	"invalid_cidr":            "The IP address or network is not valid.",
+	"invalid_calendar":        "The calendar must be gregorian or jalali.",
+	"invalid_status":          "The order status is not one of the configured statuses.",
+	"invalid_kind":            "The security event kind is not valid.",
+	"confirmation_mismatch":   "The confirmation does not match the backup ID.",
+
+	// Authentication and permissions
+	"unauthorized":             "Please sign in.",
+	"invalid_token":            "Your session has expired or is not valid. Please sign in again.",
+	"refresh_token_reused":     "This sign-in was already renewed elsewhere, so its sessions were signed out. Please sign in again.",
+	"invalid_credentials":      "The username or password is incorrect.",
+	"invalid_password":         "The current password is incorrect.",
+	"invalid_reset_token":      "The reset code is not valid, was already used or has expired.",
+	"invalid_setup_token":      "The setup token is not valid.",
+	"insufficient_permissions": "You do not have permission to do this.",
+	"protected_user":           "This user is protected and cannot be changed.",
+	"last_admin":               "The last administrator cannot be removed.",
+	"already_setup":            "The system has already been set up.",
+	"forbidden":                "You do not have permission to do this.",
+	"invalid_signature":        "The link is not valid or has expired.",
+	"token_not_allowed":        "Access tokens cannot be used for this.",
+	"insufficient_scope":       "The access token does not have the required scope.",
+	"bad_signature":            "The request signature is missing or not valid.",
+	"request_expired":          "The request is too old or its clock is wrong.",
+	"request_replayed":         "This request has already been received.",
+	"self_approval":            "Orders with controlled substances must be approved by someone other than their creator.",
+	"policy_denied":            "A policy does not allow this change.",
+
+	// Missing records
+	"not_found":            "The requested item was not found.",
+	"product_not_found":    "The product was not found.",
+	"role_not_found":       "The role was not found.",
+	"permission_not_found": "The permission was not found.",
+	"quotas_disabled":      "Pharmacy quotas are not enabled.",
+	"method_not_allowed":   "This method is not allowed here.",
+
+	// Conflicts
+	"conflict":                    "The item already exists.",
+	"duplicate_entry":             "An item with the same values already exists.",
+	"duplicate_username":          "The username is already taken.",
+	"duplicate_barcode":           "A product with this barcode already exists.",
+	"duplicate_slug":              "The slug is already taken.",
+	"duplicate_permission":        "The permission already exists.",
+	"duplicate_permission_name":   "A permission with this name already exists.",
+	"permission_already_assigned": "The role already has this permission.",
+	"permission_in_use":           "The permission is assigned to roles and cannot be deleted.",
+	"role_in_use":                 "Users or API keys have the role, so it cannot be deleted.",
+	"product_already_in_order":    "The product is already in the order.",
+	"sync_in_progress":            "A synchronization is already running.",
+	"batch_settled":               "The batch has already been settled.",
+	"status_in_use":               "Orders are in this status, so it cannot be deleted.",
+	"backup_in_progress":          "A backup, verification or restore is already running.",
+	"backup_not_restorable":       "Only completed backups can be verified or restored.",
+	"maintenance_required":        "Turn on maintenance mode before restoring.",
+	"demo_data_exists":            "Demo data has already been added.",
+	"job_running":                 "The job is already running.",
+	"controlled_order_approved":   "The controlled items of the order have been approved and cannot change.",
+	"approval_required":           "Orders with controlled substances must be approved first.",
+	"order_not_cancellable":       "The order can no longer be cancelled.",
+
+	// Uploads and limits
+	"file_too_large":        "The file is too large.",
+	"unsupported_file_type": "The file type is not supported.",
+	"config_reload_failed":  "The configuration could not be reloaded.",
+	"nothing_to_import":     "The file has nothing to import.",
+	"invalid_dataset":       "A row of the dataset is not valid.",
+	"rate_limited":          "Too many requests. Please slow down.",
+	"ip_banned":             "Too many failed attempts. Please try again later.",
+	"ip_temporarily_banned": "Too many failed attempts. Please try again later.",
+	"ip_denied":             "Requests from your IP address are not allowed.",
+	"tenant_quota_exceeded": "Your pharmacy has used its request quota. Please try again later.",
+	"order_quota_exceeded":  "Your pharmacy has placed as many orders as it may today.",
+
+	// Server and dependency failures
+	"db_error":                "A database error occurred. Please try again.",
+	"database_error":          "A database error occurred. Please try again.",
+	"database_timeout":        "The database took too long to respond. Please try again.",
+	"internal_error":          "An unexpected error occurred. Please try again.",
+	"hash_error":              "The password could not be processed. Please try again.",
+	"token_error":             "The sign-in token could not be created. Please try again.",
+	"storage_error":           "The file could not be stored or read. Please try again.",
+	"export_error":            "The export failed. Please try again.",
+	"printer_error":           "The printer could not be reached.",
+	"erp_push_failed":         "The ERP system did not accept the export.",
+	"maintenance":             "The system is under maintenance. Please try again later.",
+	"service_unavailable":     "The service is temporarily unavailable. Please try again later.",
+	"database_unavailable":    "The database is temporarily unavailable. Please try again later.",
+	"storage_unavailable":     "File storage is temporarily unavailable. Please try again later.",
+	"email_not_configured":    "Email is not configured.",
+	"alerts_not_configured":   "Alerts are not configured.",
+	"printing_not_configured": "Label printing is not configured.",
+	"erp_not_configured":      "The ERP export is not configured.",
+	"erp_push_not_configured": "Pushing to the ERP system is not configured.",
+}
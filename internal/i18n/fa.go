@@ -0,0 +1,164 @@
+// internal/i18n/fa.go - Persian error messages
+package i18n
+
+var persian = map[string]string{
+	// Malformed or invalid requests
+	"invalid_request":         "درخواست معتبر نیست.",
+	"validation_error":        "برخی از فیلدهای درخواست خالی یا نامعتبر هستند.",
+	"unknown_field":           "درخواست فیلدی دارد که این سرویس آن را نمی‌پذیرد.",
+	"invalid_id":              "شناسه واردشده معتبر نیست.",
+	"invalid_format":          "قالب درخواستی پشتیبانی نمی‌شود.",
+	"invalid_limit":           "مقدار limit خارج از محدوده مجاز است.",
+	"invalid_offset":          "مقدار offset خارج از محدوده مجاز است.",
+	"invalid_cursor":          "مقدار cursor معتبر نیست؛ از صفحه اول دوباره شروع کنید.",
+	"invalid_sort":            "مرتب‌سازی فهرست به این شکل ممکن نیست.",
+	"invalid_include":         "این منبع مرتبط قابل افزودن نیست.",
+	"invalid_user_id":         "شناسه کاربر معتبر نیست.",
+	"invalid_order_id":        "شناسه سفارش معتبر نیست.",
+	"invalid_product_id":      "شناسه کالا معتبر نیست.",
+	"invalid_role_id":         "شناسه نقش معتبر نیست.",
+	"invalid_permission_id":   "شناسه مجوز معتبر نیست.",
+	"invalid_attachment_id":   "شناسه پیوست معتبر نیست.",
+	"invalid_token_id":        "شناسه توکن معتبر نیست.",
+	"invalid_product":         "کالا وجود ندارد.",
+	"invalid_role":            "نقش وجود ندارد.",
+	"invalid_category":        "دسته‌بندی وجود ندارد.",
+	"invalid_dosage_form":     "شکل دارویی وجود ندارد.",
+	"invalid_email":           "نشانی ایمیل معتبر نیست.",
+	"invalid_phone":           "شماره تلفن معتبر نیست.",
+	"invalid_channel":         "کانال اعلان پشتیبانی نمی‌شود.",
+	"invalid_event_type":      "نوع رویداد پشتیبانی نمی‌شود.",
+	"invalid_retry_after":     "زمان تلاش دوباره معتبر نیست.",
+	"missing_required_field":  "یک فیلد الزامی خالی است.",
+	"missing_parameters":      "پارامترهای الزامی ارسال نشده‌اند.",
+	"missing_query":           "عبارت جستجو الزامی است.",
+	"missing_barcode":         "بارکد الزامی است.",
+	"missing_username":        "نام کاربری الزامی است.",
+	"query_too_short":         "عبارت جستجو خیلی کوتاه است.",
+	"password_mismatch":       "رمزهای عبور یکسان نیستند.",
+	"foreign_key_violation":   "درخواست به رکوردی اشاره می‌کند که وجود ندارد.",
+	"constraint_violation":    "درخواست با قواعد داده‌ها سازگار نیست.",
+	"unsupported_preference":  "این تنظیم اعلان پشتیبانی نمی‌شود.",
+	"unsupported_api_version": "نسخه درخواستی API پشتیبانی نمی‌شود.",
+	"unsupported_language":    "این زبان پشتیبانی نمی‌شود.",
+	"invalid_registry_file":   "فایل فهرست رسمی داروها خوانده نشد.",
+	"registry_not_configured": "فهرست رسمی داروها پیکربندی نشده است.",
+	"invalid_outcome":         "نتیجه انتخاب‌شده معتبر نیست.",
+	"product_in_staging":      "کالا در ورود فهرست رسمی داروها در انتظار بررسی است.",
+	"reason_required":         "برای داروهای تحت کنترل ذکر دلیل الزامی است.",
+	"missing_file":            "ارسال فایل الزامی است.",
+	"invalid_file":            "فایل خوانده نشد.",
+	"invalid_range":           "بازه تاریخ معتبر نیست.",
+	"invalid_report":          "گزارش معتبر نیست.",
+	"invalid_frequency":       "زمان‌بندی معتبر نیست.",
+	"invalid_recipient":       "گیرنده معتبر نیست.",
+	"invalid_columns":         "یک یا چند ستون معتبر نیستند.",
+	"unknown_printer":         "این چاپگر پیکربندی نشده است.",
+	"empty_order":             "سفارش هیچ قلمی ندارد.",
+	"invalid_scope":           "یک یا چند دسترسی توکن معتبر نیستند.",
+	"invalid_granularity":     "بازه زمانی گروه‌بندی معتبر نیست.",
+	"invalid_group_by":        "نوع گروه‌بندی معتبر نیست.",
+	"invalid_definition":      "تعریف گزارش معتبر نیست.",
+	"invalid_rbac_document":   "سند نقش‌ها و مجوزها معتبر نیست.",
+	"invalid_saved_report":    "گزارش ذخیره‌شده معتبر نیست.",
+	"invalid_slug":            "شناسه کوتاه باید از حروف کوچک لاتین و ارقام تشکیل شده و با خط تیره جدا شود.",
+	"weak_password":           "رمز عبور بیش از حد ساده است.",
+	"invalid_department":      "بخش وجود ندارد.",
+	"invalid_requester":       "درخواست‌کننده وجود ندارد.",
+	"invalid_supplier":        "تأمین‌کننده وجود ندارد.",
+	"invalid_order_item":      "این قلم در این سفارش نیست.",
+	"cancel_required":         "سفارش‌ها را با ذکر دلیل از طریق مسیر لغو، لغو کنید.",
+	"invalid_cidr":            "نشانی IP یا شبکه معتبر نیست.",
+	"invalid_calendar":        "تقویم باید gregorian یا jalali باشد.",
+	"invalid_status":          "وضعیت سفارش جزو وضعیت‌های تعریف‌شده نیست.",
+	"invalid_kind":            "نوع رویداد امنیتی معتبر نیست.",
+	"confirmation_mismatch":   "تأیید با شناسه پشتیبان مطابقت ندارد.",
+
+	// Authentication and permissions
+	"unauthorized":             "لطفاً وارد شوید.",
+	"invalid_token":            "نشست شما منقضی شده یا معتبر نیست. لطفاً دوباره وارد شوید.",
+	"refresh_token_reused":     "این ورود پیش‌تر در جای دیگری تمدید شده بود، بنابراین نشست‌های آن بسته شد. لطفاً دوباره وارد شوید.",
+	"invalid_credentials":      "نام کاربری یا رمز عبور نادرست است.",
+	"invalid_password":         "رمز عبور فعلی نادرست است.",
+	"invalid_reset_token":      "کد بازنشانی معتبر نیست، قبلاً استفاده شده یا منقضی شده است.",
+	"invalid_setup_token":      "توکن راه‌اندازی معتبر نیست.",
+	"insufficient_permissions": "شما اجازه انجام این کار را ندارید.",
+	"protected_user":           "این کاربر محافظت‌شده است و قابل تغییر نیست.",
+	"last_admin":               "آخرین مدیر سیستم را نمی‌توان حذف کرد.",
+	"already_setup":            "سیستم پیش‌تر راه‌اندازی شده است.",
+	"forbidden":                "شما اجازه انجام این کار را ندارید.",
+	"invalid_signature":        "پیوند معتبر نیست یا منقضی شده است.",
+	"token_not_allowed":        "برای این کار نمی‌توان از توکن دسترسی استفاده کرد.",
+	"insufficient_scope":       "توکن دسترسی مجوز لازم را ندارد.",
+	"bad_signature":            "امضای درخواست وجود ندارد یا معتبر نیست.",
+	"request_expired":          "درخواست قدیمی است یا ساعت فرستنده نادرست است.",
+	"request_replayed":         "این درخواست قبلاً دریافت شده است.",
+	"self_approval":            "سفارش‌های دارای داروی تحت کنترل باید توسط فردی غیر از ثبت‌کننده تأیید شوند.",
+	"policy_denied":            "یک سیاست دسترسی اجازه این تغییر را نمی‌دهد.",
+
+	// Missing records
+	"not_found":            "مورد درخواستی پیدا نشد.",
+	"product_not_found":    "کالا پیدا نشد.",
+	"role_not_found":       "نقش پیدا نشد.",
+	"permission_not_found": "مجوز پیدا نشد.",
+	"quotas_disabled":      "سهمیه داروخانه‌ها فعال نیست.",
+	"method_not_allowed":   "این روش درخواست در اینجا مجاز نیست.",
+
+	// Conflicts
+	"conflict":                    "این مورد از قبل وجود دارد.",
+	"duplicate_entry":             "موردی با همین مقادیر از قبل وجود دارد.",
+	"duplicate_username":          "این نام کاربری قبلاً ثبت شده است.",
+	"duplicate_barcode":           "کالایی با این بارکد از قبل وجود دارد.",
+	"duplicate_slug":              "این شناسه کوتاه قبلاً ثبت شده است.",
+	"duplicate_permission":        "این مجوز از قبل وجود دارد.",
+	"duplicate_permission_name":   "مجوزی با این نام از قبل وجود دارد.",
+	"permission_already_assigned": "این نقش از قبل این مجوز را دارد.",
+	"permission_in_use":           "این مجوز به نقش‌هایی داده شده و قابل حذف نیست.",
+	"role_in_use":                 "کاربران یا کلیدهای API این نقش را دارند و نمی‌توان آن را حذف کرد.",
+	"product_already_in_order":    "این کالا از قبل در سفارش هست.",
+	"sync_in_progress":            "یک همگام‌سازی در حال اجراست.",
+	"status_in_use":               "سفارش‌هایی در این وضعیت هستند و نمی‌توان آن را حذف کرد.",
+	"backup_in_progress":          "یک پشتیبان‌گیری، بررسی یا بازیابی در حال اجراست.",
+	"backup_not_restorable":       "فقط پشتیبان‌های کامل‌شده قابل بررسی یا بازیابی هستند.",
+	"maintenance_required":        "برای بازیابی ابتدا حالت نگهداری را فعال کنید.",
+	"demo_data_exists":            "داده‌های نمونه قبلاً اضافه شده است.",
+	"job_running":                 "این کار در حال اجراست.",
+	"controlled_order_approved":   "اقلام تحت کنترل این سفارش تأیید شده‌اند و قابل تغییر نیستند.",
+	"approval_required":           "سفارش‌های دارای داروی تحت کنترل ابتدا باید تأیید شوند.",
+	"order_not_cancellable":       "این سفارش دیگر قابل لغو نیست.",
+	"batch_settled":               "این دسته پیش‌تر تسویه شده است.",
+
+	// Uploads and limits
+	"file_too_large":        "حجم فایل بیش از حد مجاز است.",
+	"unsupported_file_type": "نوع فایل پشتیبانی نمی‌شود.",
+	"config_reload_failed":  "بارگذاری دوباره پیکربندی انجام نشد.",
+	"nothing_to_import":     "فایل چیزی برای ورود ندارد.",
+	"invalid_dataset":       "یکی از ردیف‌های مجموعه داده معتبر نیست.",
+	"rate_limited":          "تعداد درخواست‌ها بیش از حد است. لطفاً کمی صبر کنید.",
+	"ip_banned":             "تلاش‌های ناموفق بیش از حد بوده است. لطفاً بعداً دوباره تلاش کنید.",
+	"ip_temporarily_banned": "تلاش‌های ناموفق بیش از حد بوده است. لطفاً بعداً دوباره تلاش کنید.",
+	"ip_denied":             "درخواست از نشانی IP شما مجاز نیست.",
+	"tenant_quota_exceeded": "سهمیه درخواست داروخانه شما تمام شده است. لطفاً بعداً دوباره تلاش کنید.",
+	"order_quota_exceeded":  "داروخانه شما امروز به سقف مجاز ثبت سفارش رسیده است.",
+
+	// Server and dependency failures
+	"db_error":                "خطای پایگاه داده رخ داد. لطفاً دوباره تلاش کنید.",
+	"database_error":          "خطای پایگاه داده رخ داد. لطفاً دوباره تلاش کنید.",
+	"database_timeout":        "پاسخ پایگاه داده بیش از حد طول کشید. لطفاً دوباره تلاش کنید.",
+	"internal_error":          "خطای غیرمنتظره‌ای رخ داد. لطفاً دوباره تلاش کنید.",
+	"hash_error":              "پردازش رمز عبور انجام نشد. لطفاً دوباره تلاش کنید.",
+	"token_error":             "توکن ورود ساخته نشد. لطفاً دوباره تلاش کنید.",
+	"storage_error":           "ذخیره یا خواندن فایل انجام نشد. لطفاً دوباره تلاش کنید.",
+	"export_error":            "خروجی گرفتن انجام نشد. لطفاً دوباره تلاش کنید.",
+	"printer_error":           "ارتباط با چاپگر برقرار نشد.",
+	"erp_push_failed":         "سامانه ERP خروجی را نپذیرفت.",
+	"maintenance":             "سیستم در حال نگهداری است. لطفاً بعداً دوباره تلاش کنید.",
+	"service_unavailable":     "سرویس موقتاً در دسترس نیست. لطفاً بعداً دوباره تلاش کنید.",
+	"database_unavailable":    "پایگاه داده موقتاً در دسترس نیست. لطفاً بعداً دوباره تلاش کنید.",
+	"storage_unavailable":     "فضای ذخیره فایل موقتاً در دسترس نیست. لطفاً بعداً دوباره تلاش کنید.",
+	"email_not_configured":    "ایمیل پیکربندی نشده است.",
+	"alerts_not_configured":   "هشدارها پیکربندی نشده‌اند.",
+	"printing_not_configured": "چاپ برچسب پیکربندی نشده است.",
+	"erp_not_configured":      "خروجی ERP پیکربندی نشده است.",
+	"erp_push_not_configured": "ارسال به سامانه ERP پیکربندی نشده است.",
+}
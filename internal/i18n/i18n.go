@@ -0,0 +1,67 @@
+// internal/i18n/i18n.go - Localized API error messages
+package i18n
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Languages messages are available in
+const (
+	English = "en"
+	Persian = "fa"
+)
+
+// Default is the language used when the client asks for none we have
+const Default = English
+
+// Supported lists the languages with a message catalog
+var Supported = []string{English, Persian}
+
+// catalogs maps a language to its messages, keyed by error code
+var catalogs = map[string]map[string]string{
+	English: english,
+	Persian: persian,
+}
+
+// IsSupported reports whether lang has a message catalog
+func IsSupported(lang string) bool {
+	return slices.Contains(Supported, lang)
+}
+
+// Message returns the message for an error code in lang, falling back to
+// English; ok is false when no catalog has the code
+func Message(lang, code string) (string, bool) {
+	if msg, ok := catalogs[lang][code]; ok {
+		return msg, true
+	}
+	msg, ok := english[code]
+	return msg, ok
+}
+
+// Negotiate picks the supported language an Accept-Language header
+// prefers, or "" when it accepts none of them. Regional variants match
+// their language (fa-IR is fa).
+func Negotiate(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if lang == "*" {
+			lang = Default
+		}
+		if q > bestQ && IsSupported(lang) {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,128 @@
+// internal/ical/ical.go - iCalendar (RFC 5545) feeds
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of an iCalendar feed
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets is the longest content line RFC 5545 allows before folding
+const maxLineOctets = 75
+
+// Calendar is a feed of events
+type Calendar struct {
+	ProdID string // e.g. "-//DigiOrder//Orders//EN"
+	Name   string // shown by clients as the calendar title
+	Events []Event
+}
+
+// Event is an all-day VEVENT; Start and End are dates, End exclusive and
+// defaulting to the day after Start. RRule repeats the event, e.g.
+// "FREQ=WEEKLY".
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Categories  []string
+	Start       time.Time
+	End         time.Time
+	RRule       string
+	Stamp       time.Time // DTSTAMP; defaults to now
+}
+
+// Render writes the calendar as an iCalendar document with CRLF line
+// endings and long lines folded
+func (cal Calendar) Render() []byte {
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + cal.ProdID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		w.line("X-WR-CALNAME:" + Escape(cal.Name))
+	}
+
+	now := time.Now()
+	for _, e := range cal.Events {
+		stamp := e.Stamp
+		if stamp.IsZero() {
+			stamp = now
+		}
+		end := e.End
+		if end.IsZero() || !end.After(e.Start) {
+			end = e.Start.AddDate(0, 0, 1)
+		}
+
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + e.UID)
+		w.line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		w.line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+		w.line("DTEND;VALUE=DATE:" + end.Format("20060102"))
+		if e.RRule != "" {
+			w.line("RRULE:" + e.RRule)
+		}
+		w.line("SUMMARY:" + Escape(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION:" + Escape(e.Description))
+		}
+		if e.URL != "" {
+			w.line("URL:" + e.URL)
+		}
+		if len(e.Categories) > 0 {
+			escaped := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				escaped[i] = Escape(c)
+			}
+			w.line("CATEGORIES:" + strings.Join(escaped, ","))
+		}
+		w.line("TRANSP:TRANSPARENT")
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// Escape escapes a TEXT value: backslashes, semicolons, commas and
+// newlines
+func Escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line writes one content line, folding it into 75-octet pieces without
+// splitting a UTF-8 sequence
+func (w *writer) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // the leading space counts
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
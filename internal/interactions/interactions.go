@@ -0,0 +1,154 @@
+// internal/interactions/interactions.go - Drug interaction and duplicate-therapy checks
+package interactions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Warning kinds, the kind column of order_warnings
+const (
+	KindInteraction      = "interaction"
+	KindDuplicateTherapy = "duplicate_therapy"
+)
+
+// Severities, mildest first
+const (
+	SeverityMinor           = "minor"
+	SeverityModerate        = "moderate"
+	SeverityMajor           = "major"
+	SeverityContraindicated = "contraindicated"
+)
+
+// Severities lists the severities, mildest first
+var Severities = []string{SeverityMinor, SeverityModerate, SeverityMajor, SeverityContraindicated}
+
+// classLength is how much of an ATC code two generics must share to
+// duplicate therapy: the fourth level, the chemical subgroup (C09AA, plain
+// ACE inhibitors)
+const classLength = 5
+
+// Interaction is a known interaction between two generics
+type Interaction struct {
+	GenericCodeA string `json:"generic_code_a"`
+	GenericCodeB string `json:"generic_code_b"`
+	Severity     string `json:"severity"`
+	Description  string `json:"description"`
+}
+
+// Dataset is what a provider knows about a set of generics: the
+// interactions between them and the ATC code of each, by generic code
+type Dataset struct {
+	Interactions []Interaction     `json:"interactions"`
+	Classes      map[string]string `json:"classes"`
+}
+
+// Provider looks generics up in an interaction dataset
+type Provider interface {
+	// Lookup returns the interactions between any two of codes and the
+	// ATC codes of those of them it knows
+	Lookup(ctx context.Context, codes []string) (Dataset, error)
+}
+
+// Item is an order item as the checks see it. Items without a generic
+// code are left out of them.
+type Item struct {
+	ID          uuid.UUID
+	Name        string
+	GenericCode string
+}
+
+// Warning is raised for an added item and another item of the order
+type Warning struct {
+	Kind        string
+	Severity    string
+	ItemID      uuid.UUID
+	OtherItemID uuid.UUID
+	Message     string
+}
+
+// Check returns the warnings between each added item and every item added
+// before it or already in the order: interactions between their generics,
+// and duplicate therapy when they have the same generic or ATC chemical
+// subgroup. Duplicate therapy is a moderate warning.
+func Check(ctx context.Context, provider Provider, existing, added []Item) ([]Warning, error) {
+	all := slices.Concat(existing, added)
+	if len(all) < 2 {
+		return nil, nil
+	}
+	var codes []string
+	for _, item := range all {
+		if item.GenericCode != "" && !slices.Contains(codes, item.GenericCode) {
+			codes = append(codes, item.GenericCode)
+		}
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	dataset, err := provider.Lookup(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	interactions := make(map[[2]string]Interaction, len(dataset.Interactions))
+	for _, in := range dataset.Interactions {
+		interactions[pair(in.GenericCodeA, in.GenericCodeB)] = in
+	}
+
+	var warnings []Warning
+	for i, item := range added {
+		if item.GenericCode == "" {
+			continue
+		}
+		for _, other := range all[:len(existing)+i] {
+			if other.GenericCode == "" {
+				continue
+			}
+			warnings = append(warnings, check(item, other, interactions, dataset.Classes)...)
+		}
+	}
+	return warnings, nil
+}
+
+func check(item, other Item, interactions map[[2]string]Interaction, classes map[string]string) []Warning {
+	warn := func(kind, severity, message string) Warning {
+		return Warning{Kind: kind, Severity: severity, ItemID: item.ID, OtherItemID: other.ID, Message: message}
+	}
+
+	if item.GenericCode == other.GenericCode {
+		return []Warning{warn(KindDuplicateTherapy, SeverityModerate,
+			fmt.Sprintf("%s and %s are the same generic (%s).", item.Name, other.Name, item.GenericCode))}
+	}
+
+	var warnings []Warning
+	if in, ok := interactions[pair(item.GenericCode, other.GenericCode)]; ok {
+		warnings = append(warnings, warn(KindInteraction, in.Severity,
+			fmt.Sprintf("%s interacts with %s: %s", item.Name, other.Name, in.Description)))
+	}
+	class, otherClass := classes[item.GenericCode], classes[other.GenericCode]
+	if len(class) >= classLength && len(otherClass) >= classLength && class[:classLength] == otherClass[:classLength] {
+		warnings = append(warnings, warn(KindDuplicateTherapy, SeverityModerate,
+			fmt.Sprintf("%s and %s are in the same therapeutic class (ATC %s).", item.Name, other.Name, class[:classLength])))
+	}
+	return warnings
+}
+
+// pair orders two generic codes as the dataset stores them, the smaller
+// first
+func pair(a, b string) [2]string {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Pair returns the interaction with its codes in stored order, the
+// smaller first
+func (in Interaction) Pair() Interaction {
+	p := pair(in.GenericCodeA, in.GenericCodeB)
+	in.GenericCodeA, in.GenericCodeB = p[0], p[1]
+	return in
+}
@@ -0,0 +1,106 @@
+// internal/interactions/providers.go - Interaction datasets in the database or behind an HTTP API
+package interactions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// Database looks generics up in the dataset uploaded to drug_interactions
+// and drug_classes
+type Database struct {
+	Queries db.Querier
+}
+
+// Lookup implements Provider
+func (d Database) Lookup(ctx context.Context, codes []string) (Dataset, error) {
+	rows, err := d.Queries.ListDrugInteractions(ctx, codes)
+	if err != nil {
+		return Dataset{}, err
+	}
+	classes, err := d.Queries.ListDrugClasses(ctx, codes)
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	dataset := Dataset{
+		Interactions: make([]Interaction, len(rows)),
+		Classes:      make(map[string]string, len(classes)),
+	}
+	for i, row := range rows {
+		dataset.Interactions[i] = Interaction{
+			GenericCodeA: row.GenericCodeA,
+			GenericCodeB: row.GenericCodeB,
+			Severity:     row.Severity,
+			Description:  row.Description,
+		}
+	}
+	for _, class := range classes {
+		dataset.Classes[class.GenericCode] = class.AtcCode
+	}
+	return dataset, nil
+}
+
+// HTTP looks generics up in an external service. It posts
+// {"generic_codes": [...]} to URL and reads a Dataset back:
+//
+//	{"interactions": [{"generic_code_a": "...", "generic_code_b": "...",
+//	  "severity": "major", "description": "..."}],
+//	 "classes": {"<generic code>": "<ATC code>"}}
+type HTTP struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTP creates the provider for the service at url; token, when set, is
+// sent as a bearer token
+func NewHTTP(url, token string, timeout time.Duration) *HTTP {
+	return &HTTP{url: url, token: token, client: &http.Client{Timeout: timeout}}
+}
+
+// Lookup implements Provider
+func (h *HTTP) Lookup(ctx context.Context, codes []string) (Dataset, error) {
+	body, err := json.Marshal(map[string][]string{"generic_codes": codes})
+	if err != nil {
+		return Dataset{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return Dataset{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return Dataset{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return Dataset{}, fmt.Errorf("interaction provider returned %d", resp.StatusCode)
+	}
+
+	var dataset Dataset
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&dataset); err != nil {
+		return Dataset{}, fmt.Errorf("interaction provider response: %w", err)
+	}
+	for _, in := range dataset.Interactions {
+		if !slices.Contains(Severities, in.Severity) {
+			return Dataset{}, fmt.Errorf("interaction provider returned unknown severity %q", in.Severity)
+		}
+	}
+	return dataset, nil
+}
@@ -0,0 +1,216 @@
+// internal/jalali/jalali.go - Jalali (Solar Hijri) calendar dates
+package jalali
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar names accepted by the calendar query parameter
+const (
+	Gregorian = "gregorian"
+	Jalali    = "jalali"
+)
+
+// IsCalendar reports whether name is a supported calendar
+func IsCalendar(name string) bool {
+	return name == Gregorian || name == Jalali
+}
+
+// Supported years; the leap year table below covers no others
+const (
+	MinYear = 1
+	MaxYear = 3177
+)
+
+// ErrInvalidDate is returned for text that is not a Jalali date
+var ErrInvalidDate = errors.New("not a Jalali date")
+
+// breaks are the years starting a new 33-year leap cycle
+var breaks = []int{-61, 9, 38, 199, 426, 686, 756, 818, 1111, 1181, 1210,
+	1635, 2060, 2097, 2192, 2262, 2324, 2394, 2456, 3178}
+
+// Date is a day of the Jalali calendar; Month runs from 1 (Farvardin) to
+// 12 (Esfand)
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// String formats the date as YYYY-MM-DD
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Valid reports whether the date exists
+func (d Date) Valid() bool {
+	return d.Year >= MinYear && d.Year <= MaxYear &&
+		d.Month >= 1 && d.Month <= 12 &&
+		d.Day >= 1 && d.Day <= DaysIn(d.Year, d.Month)
+}
+
+// Time is the start of the date in loc
+func (d Date) Time(loc *time.Location) time.Time {
+	day := nowruz(d.Year).AddDate(0, 0, dayOfYear(d.Month, d.Day))
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+}
+
+// IsLeap reports whether Esfand of year has 30 days
+func IsLeap(year int) bool {
+	leap, _ := cycle(year)
+	return leap == 0
+}
+
+// DaysIn is the number of days in a month of year
+func DaysIn(year, month int) int {
+	switch {
+	case month <= 6:
+		return 31
+	case month <= 11:
+		return 30
+	case IsLeap(year):
+		return 30
+	default:
+		return 29
+	}
+}
+
+// FromTime is the Jalali date of t in its location; ok is false for years
+// outside the supported range
+func FromTime(t time.Time) (d Date, ok bool) {
+	civil := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	year := t.Year() - 621
+	if year < MinYear || year > MaxYear {
+		return Date{}, false
+	}
+	start := nowruz(year)
+	if civil.Before(start) {
+		year--
+		start = nowruz(year)
+	}
+	if year < MinYear || year > MaxYear {
+		return Date{}, false
+	}
+
+	days := int(civil.Sub(start).Hours() / 24)
+	if days < 186 {
+		return Date{Year: year, Month: days/31 + 1, Day: days%31 + 1}, true
+	}
+	days -= 186
+	return Date{Year: year, Month: days/30 + 7, Day: days%30 + 1}, true
+}
+
+// FormatDate formats the Jalali date of t as YYYY-MM-DD, or "" when it is
+// out of range
+func FormatDate(t time.Time) string {
+	d, ok := FromTime(t)
+	if !ok {
+		return ""
+	}
+	return d.String()
+}
+
+// FormatDateTime formats t as a Jalali date followed by its clock time,
+// e.g. 1403-10-17 14:05:09
+func FormatDateTime(t time.Time) string {
+	d, ok := FromTime(t)
+	if !ok {
+		return ""
+	}
+	return d.String() + " " + t.Format(time.TimeOnly)
+}
+
+// ParseDate reads YYYY-MM-DD or YYYY/MM/DD, in Latin or Persian digits
+func ParseDate(s string) (Date, error) {
+	s = strings.Map(latinDigit, strings.TrimSpace(s))
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '/' })
+	if len(parts) != 3 || strings.Count(s, "-")+strings.Count(s, "/") != 2 {
+		return Date{}, ErrInvalidDate
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || p[0] == '+' {
+			return Date{}, ErrInvalidDate
+		}
+		nums[i] = n
+	}
+	d := Date{Year: nums[0], Month: nums[1], Day: nums[2]}
+	if !d.Valid() {
+		return Date{}, ErrInvalidDate
+	}
+	return d, nil
+}
+
+// Parse reads a Jalali date as ParseDate does and returns its start in loc
+func Parse(s string, loc *time.Location) (time.Time, error) {
+	d, err := ParseDate(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return d.Time(loc), nil
+}
+
+// latinDigit maps Persian and Arabic-Indic digits to 0-9
+func latinDigit(r rune) rune {
+	switch {
+	case r >= '۰' && r <= '۹':
+		return '0' + r - '۰'
+	case r >= '٠' && r <= '٩':
+		return '0' + r - '٠'
+	}
+	return r
+}
+
+// dayOfYear counts the days of year before the date
+func dayOfYear(month, day int) int {
+	if month <= 6 {
+		return (month-1)*31 + day - 1
+	}
+	return 186 + (month-7)*30 + day - 1
+}
+
+// nowruz is the Gregorian date, in UTC, of 1 Farvardin of year
+func nowruz(year int) time.Time {
+	_, march := cycle(year)
+	return time.Date(year+621, time.March, march, 0, 0, 0, 0, time.UTC)
+}
+
+// cycle places year in its leap cycle. leap counts the years since the
+// last leap year, 0 meaning year itself is one; march is the March day of
+// 1 Farvardin. This is the arithmetic of Borkowski's algorithm, valid for
+// the years in breaks.
+func cycle(year int) (leap, march int) {
+	gy := year + 621
+	leapJ := -14
+	jp := breaks[0]
+	jump := 0
+	for _, jm := range breaks[1:] {
+		jump = jm - jp
+		if year < jm {
+			break
+		}
+		leapJ += jump/33*8 + jump%33/4
+		jp = jm
+	}
+	n := year - jp
+	leapJ += n/33*8 + (n%33+3)/4
+	if jump%33 == 4 && jump-n == 4 {
+		leapJ++
+	}
+	leapG := gy/4 - (gy/100+1)*3/4 - 150
+	march = 20 + leapJ - leapG
+
+	if jump-n < 6 {
+		n = n - jump + (jump+4)/33*33
+	}
+	leap = ((n+1)%33 - 1) % 4
+	if leap == -1 {
+		leap = 4
+	}
+	return leap, march
+}
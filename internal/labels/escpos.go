@@ -0,0 +1,74 @@
+// internal/labels/escpos.go - ESC/POS receipt and label printers
+package labels
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ESC/POS font A is 12 dots wide
+const escposCharWidth = 12
+
+// renderESCPOS prints each label centred, the name in bold double height,
+// followed by a partial cut. ESC/POS code pages vary between printers, so
+// text outside ASCII is replaced by "?"; use ZPL for Persian names.
+func renderESCPOS(width int, labels []Label, copies int) []byte {
+	columns := max(width/escposCharWidth, 16)
+
+	var b bytes.Buffer
+	b.Write([]byte{0x1b, '@'}) // initialise
+	for _, label := range labels {
+		for range copies {
+			b.Write([]byte{0x1b, 'a', 1}) // centre
+
+			b.Write([]byte{0x1b, 'E', 1, 0x1d, '!', 0x01}) // bold, double height
+			for _, line := range wrap(escposText(label.Name), columns) {
+				b.WriteString(line + "\n")
+			}
+			b.Write([]byte{0x1b, 'E', 0, 0x1d, '!', 0x00})
+			if label.Strength != "" {
+				b.WriteString(escposText(label.Strength) + "\n")
+			}
+
+			if kind, ok := symbology(label.Barcode); ok {
+				b.Write([]byte{
+					0x1d, 'h', 80, // height in dots
+					0x1d, 'w', 2, // module width
+					0x1d, 'H', 2, // human readable text below
+				})
+				b.WriteByte('\n')
+				if kind == symbologyEAN13 {
+					b.Write([]byte{0x1d, 'k', 67, 13})
+					b.WriteString(label.Barcode)
+				} else {
+					data := "{B" + label.Barcode
+					b.Write([]byte{0x1d, 'k', 73, byte(len(data))})
+					b.WriteString(data)
+				}
+				b.WriteByte('\n')
+			} else if label.Barcode != "" {
+				b.WriteString(escposText(label.Barcode) + "\n")
+			}
+
+			for _, text := range label.Lines {
+				for _, line := range wrap(escposText(text), columns) {
+					b.WriteString(line + "\n")
+				}
+			}
+			b.Write([]byte{0x1d, 'V', 66, 3}) // feed and partial cut
+		}
+	}
+	return b.Bytes()
+}
+
+func escposText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return ' '
+		case r < 0x20 || r > 0x7e:
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,130 @@
+// internal/labels/labels.go - Shelf and order item labels
+package labels
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Printer languages
+const (
+	LanguageESCPOS = "escpos"
+	LanguageZPL    = "zpl"
+)
+
+// Default printable widths in dots at 203 dpi: an 80 mm receipt roll and
+// a 4 inch label
+const (
+	defaultESCPOSWidth = 576
+	defaultZPLWidth    = 812
+)
+
+// MaxCopies is the most copies of a label printed by one job
+const MaxCopies = 100
+
+// Label is one shelf or order item label: the product name, its strength
+// and barcode, and any further lines of text
+type Label struct {
+	Name     string
+	Strength string
+	Barcode  string
+	Lines    []string
+}
+
+// Render encodes labels as a print job in language for a printable width
+// in dots (the language's default when 0). Each label is printed copies
+// times.
+func Render(language string, width int, labels []Label, copies int) ([]byte, error) {
+	if copies < 1 || copies > MaxCopies {
+		return nil, fmt.Errorf("copies must be between 1 and %d", MaxCopies)
+	}
+	switch language {
+	case LanguageESCPOS:
+		if width <= 0 {
+			width = defaultESCPOSWidth
+		}
+		return renderESCPOS(width, labels, copies), nil
+	case LanguageZPL:
+		if width <= 0 {
+			width = defaultZPLWidth
+		}
+		return renderZPL(width, labels, copies), nil
+	}
+	return nil, fmt.Errorf("unsupported printer language %q", language)
+}
+
+// Barcode symbologies
+const (
+	symbologyEAN13   = "ean13"
+	symbologyCode128 = "code128"
+)
+
+// symbology picks EAN-13 for valid 13 digit codes and Code 128 for other
+// printable ASCII codes. Codes neither can encode are printed as text.
+func symbology(code string) (string, bool) {
+	if code == "" || len(code) > 80 {
+		return "", false
+	}
+	if validEAN13(code) {
+		return symbologyEAN13, true
+	}
+	for _, r := range code {
+		if r < 0x20 || r > 0x7e {
+			return "", false
+		}
+	}
+	return symbologyCode128, true
+}
+
+func validEAN13(code string) bool {
+	if len(code) != 13 {
+		return false
+	}
+	sum := 0
+	for i, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+		digit := int(r - '0')
+		if i == 12 {
+			return (sum+digit)%10 == 0
+		}
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return false
+}
+
+// wrap breaks text into lines of at most width runes, splitting words only
+// when they are longer than a line
+func wrap(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.FieldsFunc(text, unicode.IsSpace) {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
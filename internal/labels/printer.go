@@ -0,0 +1,112 @@
+// internal/labels/printer.go - Sending print jobs to network printers
+package labels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var labelsPrinted = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "labels_printed_total",
+		Help: "Labels sent to printers by printer and outcome (sent, failed)",
+	},
+	[]string{"printer", "status"},
+)
+
+// Errors returned by Print
+var (
+	ErrNoPrinters     = errors.New("no label printers are configured")
+	ErrUnknownPrinter = errors.New("unknown printer")
+)
+
+// Printer is a network printer that takes raw jobs over TCP, usually on
+// port 9100
+type Printer struct {
+	Name     string
+	Address  string // host:port
+	Language string // escpos or zpl
+	Width    int    // printable width in dots; 0 uses the language default
+}
+
+// Printers sends label jobs to the configured printers
+type Printers struct {
+	printers       []Printer
+	defaultPrinter string
+	timeout        time.Duration
+}
+
+// NewPrinters creates the printer set. Jobs naming no printer go to
+// defaultPrinter, or the first printer when it is empty.
+func NewPrinters(printers []Printer, defaultPrinter string, timeout time.Duration) *Printers {
+	if defaultPrinter == "" && len(printers) > 0 {
+		defaultPrinter = printers[0].Name
+	}
+	return &Printers{printers: printers, defaultPrinter: defaultPrinter, timeout: timeout}
+}
+
+// List returns the configured printers
+func (p *Printers) List() []Printer {
+	return p.printers
+}
+
+// Default returns the name of the default printer
+func (p *Printers) Default() string {
+	return p.defaultPrinter
+}
+
+// Lookup returns the named printer, or the default one when name is empty
+func (p *Printers) Lookup(name string) (Printer, error) {
+	if len(p.printers) == 0 {
+		return Printer{}, ErrNoPrinters
+	}
+	if name == "" {
+		name = p.defaultPrinter
+	}
+	for _, printer := range p.printers {
+		if printer.Name == name {
+			return printer, nil
+		}
+	}
+	return Printer{}, fmt.Errorf("%w %q", ErrUnknownPrinter, name)
+}
+
+// Print renders labels for the printer and sends the job, waiting until
+// the printer has accepted it
+func (p *Printers) Print(ctx context.Context, printer Printer, labels []Label, copies int) error {
+	job, err := Render(printer.Language, printer.Width, labels, copies)
+	if err != nil {
+		return err
+	}
+
+	err = p.send(ctx, printer, job)
+	status := "sent"
+	if err != nil {
+		status = "failed"
+	}
+	labelsPrinted.WithLabelValues(printer.Name, status).Add(float64(len(labels) * copies))
+	return err
+}
+
+func (p *Printers) send(ctx context.Context, printer Printer, job []byte) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", printer.Address)
+	if err != nil {
+		return fmt.Errorf("printer %s is unreachable: %w", printer.Name, err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Write(job); err != nil {
+		return fmt.Errorf("sending to printer %s: %w", printer.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// internal/labels/zpl.go - ZPL label printers
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layout of a ZPL label in dots at 203 dpi
+const (
+	zplMargin        = 20
+	zplNameHeight    = 40
+	zplTextHeight    = 28
+	zplBarcodeHeight = 80
+	zplModuleWidth   = 2
+)
+
+// renderZPL lays out each label as one ZPL format with the text centred in
+// field blocks. Text is sent as UTF-8 (^CI28); printing Persian names
+// needs a Unicode font loaded on the printer.
+func renderZPL(width int, labels []Label, copies int) []byte {
+	var b strings.Builder
+	for _, label := range labels {
+		var body strings.Builder
+		y := zplMargin
+		text := func(s string, height, maxLines int) {
+			fmt.Fprintf(&body, "^FO0,%d^A0N,%d,%d^FB%d,%d,0,C^FH^FD%s^FS\n",
+				y, height, height, width, maxLines, zplText(s))
+			y += (height + 4) * maxLines
+		}
+
+		text(label.Name, zplNameHeight, 2)
+		if label.Strength != "" {
+			text(label.Strength, zplTextHeight, 1)
+		}
+		if kind, ok := symbology(label.Barcode); ok {
+			y += 8
+			x := max((width-zplBarcodeWidth(kind, label.Barcode))/2, 0)
+			fmt.Fprintf(&body, "^FO%d,%d^BY%d", x, y, zplModuleWidth)
+			if kind == symbologyEAN13 {
+				fmt.Fprintf(&body, "^BEN,%d,Y,N", zplBarcodeHeight)
+			} else {
+				fmt.Fprintf(&body, "^BCN,%d,Y,N,N", zplBarcodeHeight)
+			}
+			fmt.Fprintf(&body, "^FH^FD%s^FS\n", zplText(label.Barcode))
+			y += zplBarcodeHeight + zplTextHeight + 8
+		} else if label.Barcode != "" {
+			text(label.Barcode, zplTextHeight, 1)
+		}
+		for _, line := range label.Lines {
+			text(line, zplTextHeight, 1)
+		}
+
+		fmt.Fprintf(&b, "^XA\n^CI28\n^PW%d\n^LL%d\n^LH0,0\n", width, y+zplMargin)
+		b.WriteString(body.String())
+		fmt.Fprintf(&b, "^PQ%d\n^XZ\n", copies)
+	}
+	return []byte(b.String())
+}
+
+// zplBarcodeWidth estimates the printed width of a barcode in dots
+func zplBarcodeWidth(kind, code string) int {
+	modules := 95 // EAN-13
+	if kind == symbologyCode128 {
+		modules = 11*(len(code)+3) + 2 // start, data, check and stop
+	}
+	return modules * zplModuleWidth
+}
+
+// zplText escapes the ZPL control characters with ^FH hex escapes and
+// flattens line breaks
+func zplText(s string) string {
+	return strings.NewReplacer(
+		"_", "_5F", "^", "_5E", "~", "_7E", "\r", " ", "\n", " ",
+	).Replace(s)
+}
@@ -0,0 +1,143 @@
+// internal/logging/echo.go - echo.Logger adapter for the structured logger
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/labstack/gommon/log"
+)
+
+// EchoLogger adapts Logger to the echo.Logger interface so framework
+// messages (recovered panics, startup errors) use the same structured output
+type EchoLogger struct {
+	logger *Logger
+	prefix string
+}
+
+// NewEchoLogger wraps a structured logger for use as echo.Echo.Logger
+func NewEchoLogger(logger *Logger) *EchoLogger {
+	return &EchoLogger{logger: logger, prefix: "echo"}
+}
+
+func (e *EchoLogger) write(level LogLevel, msg string, fields map[string]any) {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["source"] = e.prefix
+	e.logger.log(LogEntry{Level: level, Message: msg, Fields: fields})
+}
+
+func (e *EchoLogger) writej(level LogLevel, j log.JSON) {
+	msg, _ := j["message"].(string)
+	if msg == "" {
+		data, _ := json.Marshal(j)
+		msg = string(data)
+	}
+	e.write(level, msg, map[string]any(j))
+}
+
+// Output returns the underlying writer
+func (e *EchoLogger) Output() io.Writer { return e.logger.Output() }
+
+// SetOutput changes the underlying writer
+func (e *EchoLogger) SetOutput(w io.Writer) { e.logger.SetOutput(w) }
+
+// Prefix returns the source tag added to every entry
+func (e *EchoLogger) Prefix() string { return e.prefix }
+
+// SetPrefix changes the source tag added to every entry
+func (e *EchoLogger) SetPrefix(p string) { e.prefix = p }
+
+// Level returns the minimum level as a gommon level
+func (e *EchoLogger) Level() log.Lvl {
+	switch e.logger.Level() {
+	case LevelDebug:
+		return log.DEBUG
+	case LevelWarn:
+		return log.WARN
+	case LevelError, LevelFatal:
+		return log.ERROR
+	default:
+		return log.INFO
+	}
+}
+
+// SetLevel changes the minimum level from a gommon level
+func (e *EchoLogger) SetLevel(v log.Lvl) {
+	switch v {
+	case log.DEBUG:
+		e.logger.SetLevel(LevelDebug)
+	case log.WARN:
+		e.logger.SetLevel(LevelWarn)
+	case log.ERROR:
+		e.logger.SetLevel(LevelError)
+	default:
+		e.logger.SetLevel(LevelInfo)
+	}
+}
+
+// SetHeader is a no-op; the structured format has a fixed header
+func (e *EchoLogger) SetHeader(string) {}
+
+func (e *EchoLogger) Print(i ...any) { e.write(LevelInfo, fmt.Sprint(i...), nil) }
+func (e *EchoLogger) Printf(format string, args ...any) {
+	e.write(LevelInfo, fmt.Sprintf(format, args...), nil)
+}
+func (e *EchoLogger) Printj(j log.JSON) { e.writej(LevelInfo, j) }
+func (e *EchoLogger) Debug(i ...any)    { e.write(LevelDebug, fmt.Sprint(i...), nil) }
+func (e *EchoLogger) Debugf(format string, args ...any) {
+	e.write(LevelDebug, fmt.Sprintf(format, args...), nil)
+}
+func (e *EchoLogger) Debugj(j log.JSON) { e.writej(LevelDebug, j) }
+func (e *EchoLogger) Info(i ...any)     { e.write(LevelInfo, fmt.Sprint(i...), nil) }
+func (e *EchoLogger) Infof(format string, args ...any) {
+	e.write(LevelInfo, fmt.Sprintf(format, args...), nil)
+}
+func (e *EchoLogger) Infoj(j log.JSON) { e.writej(LevelInfo, j) }
+func (e *EchoLogger) Warn(i ...any)    { e.write(LevelWarn, fmt.Sprint(i...), nil) }
+func (e *EchoLogger) Warnf(format string, args ...any) {
+	e.write(LevelWarn, fmt.Sprintf(format, args...), nil)
+}
+func (e *EchoLogger) Warnj(j log.JSON) { e.writej(LevelWarn, j) }
+func (e *EchoLogger) Error(i ...any)   { e.write(LevelError, fmt.Sprint(i...), nil) }
+func (e *EchoLogger) Errorf(format string, args ...any) {
+	e.write(LevelError, fmt.Sprintf(format, args...), nil)
+}
+func (e *EchoLogger) Errorj(j log.JSON) { e.writej(LevelError, j) }
+
+// Fatal logs and exits the process
+func (e *EchoLogger) Fatal(i ...any) { e.logger.Fatal(fmt.Sprint(i...), nil, nil) }
+
+// Fatalf logs and exits the process
+func (e *EchoLogger) Fatalf(format string, args ...any) {
+	e.logger.Fatal(fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Fatalj logs and exits the process
+func (e *EchoLogger) Fatalj(j log.JSON) {
+	data, _ := json.Marshal(j)
+	e.logger.Fatal(string(data), nil, nil)
+}
+
+// Panic logs and panics
+func (e *EchoLogger) Panic(i ...any) {
+	msg := fmt.Sprint(i...)
+	e.write(LevelFatal, msg, nil)
+	panic(msg)
+}
+
+// Panicf logs and panics
+func (e *EchoLogger) Panicf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	e.write(LevelFatal, msg, nil)
+	panic(msg)
+}
+
+// Panicj logs and panics
+func (e *EchoLogger) Panicj(j log.JSON) {
+	data, _ := json.Marshal(j)
+	e.write(LevelFatal, string(data), nil)
+	panic(string(data))
+}
@@ -4,7 +4,11 @@ package logging
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,7 +30,11 @@ const (
 type Logger struct {
 	serviceName string
 	environment string
-	minLevel    LogLevel
+	minLevel    atomic.Value // LogLevel; changed at runtime by SetLevel
+	out         io.Writer
+	closer      io.Closer
+	color       bool
+	sampler     *sampler
 }
 
 // LogEntry represents a structured log entry
@@ -50,14 +58,76 @@ type LogEntry struct {
 // NewLogger creates a new structured logger
 func NewLogger(serviceName, environment string) *Logger {
 	minLevel := LevelInfo
-	if os.Getenv("LOG_LEVEL") == "debug" {
-		minLevel = LevelDebug
+	if level, err := ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+		minLevel = level
 	}
 
-	return &Logger{
+	logger := &Logger{
 		serviceName: serviceName,
 		environment: environment,
-		minLevel:    minLevel,
+		out:         os.Stdout,
+		color:       true,
+		sampler:     newSamplerFromEnv(),
+	}
+	logger.minLevel.Store(minLevel)
+
+	if logger.sampler != nil {
+		go logger.sampler.run(logger)
+	}
+
+	out, err := OutputFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logging: %v; falling back to stdout\n", err)
+		return logger
+	}
+	logger.SetOutput(out)
+
+	return logger
+}
+
+// ParseLevel converts a level name such as "debug" or "WARN" to a LogLevel
+func ParseLevel(name string) (LogLevel, error) {
+	level := LogLevel(strings.ToUpper(strings.TrimSpace(name)))
+	switch level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown log level %q", name)
+}
+
+// Level returns the minimum level currently written
+func (l *Logger) Level() LogLevel {
+	return l.minLevel.Load().(LogLevel)
+}
+
+// SetLevel changes the minimum level; safe to call while logging
+func (l *Logger) SetLevel(level LogLevel) {
+	l.minLevel.Store(level)
+}
+
+// Close stops the sampler's summary loop and releases the file sink, if any
+func (l *Logger) Close() error {
+	if l.sampler != nil {
+		l.sampler.stop()
+	}
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Output returns the writer log entries are written to
+func (l *Logger) Output() io.Writer {
+	return l.out
+}
+
+// SetOutput changes the writer log entries are written to.
+// Terminal colors are only used when writing to stdout.
+func (l *Logger) SetOutput(w io.Writer) {
+	l.out = w
+	l.color = w == os.Stdout
+	if c, ok := w.(io.Closer); ok && w != os.Stdout {
+		l.closer = c
 	}
 }
 
@@ -72,10 +142,26 @@ func (l *Logger) log(entry LogEntry) {
 		return
 	}
 
+	// Collapse floods of identical warnings and errors
+	if l.sampler != nil && (entry.Level == LevelWarn || entry.Level == LevelError) {
+		allowed, summary := l.sampler.allow(entry)
+		if summary != nil {
+			l.write(*summary)
+		}
+		if !allowed {
+			return
+		}
+	}
+
+	l.write(entry)
+}
+
+// write formats and outputs an entry that passed level and sampling checks
+func (l *Logger) write(entry LogEntry) {
 	// JSON output
 	if os.Getenv("LOG_FORMAT") == "json" {
 		data, _ := json.Marshal(entry)
-		fmt.Println(string(data))
+		l.out.Write(append(data, '\n'))
 	} else {
 		// Human-readable format
 		l.printReadable(entry)
@@ -91,56 +177,63 @@ func (l *Logger) shouldLog(level LogLevel) bool {
 		LevelError: 3,
 		LevelFatal: 4,
 	}
-	return levels[level] >= levels[l.minLevel]
+	return levels[level] >= levels[l.Level()]
 }
 
 // printReadable outputs human-readable logs
 func (l *Logger) printReadable(entry LogEntry) {
 	color := ""
-	reset := "\033[0m"
+	reset := ""
 
-	switch entry.Level {
-	case LevelDebug:
+	switch {
+	case !l.color:
+	case entry.Level == LevelDebug:
 		color = "\033[36m" // Cyan
-	case LevelInfo:
+	case entry.Level == LevelInfo:
 		color = "\033[32m" // Green
-	case LevelWarn:
+	case entry.Level == LevelWarn:
 		color = "\033[33m" // Yellow
-	case LevelError:
+	case entry.Level == LevelError:
 		color = "\033[31m" // Red
-	case LevelFatal:
+	case entry.Level == LevelFatal:
 		color = "\033[35m" // Magenta
 	}
+	if color != "" {
+		reset = "\033[0m"
+	}
 
-	fmt.Printf("%s[%s]%s %s | %s",
+	// Build the whole line first so concurrent entries are not interleaved
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s[%s]%s %s | %s",
 		color, entry.Level, reset,
 		entry.Timestamp.Format("2006-01-02 15:04:05"),
 		entry.Message)
 
 	if entry.RequestID != "" {
-		fmt.Printf(" | req_id=%s", entry.RequestID)
+		fmt.Fprintf(&b, " | req_id=%s", entry.RequestID)
 	}
 	if entry.UserID != "" {
-		fmt.Printf(" | user_id=%s", entry.UserID)
+		fmt.Fprintf(&b, " | user_id=%s", entry.UserID)
 	}
 	if entry.Method != "" && entry.Path != "" {
-		fmt.Printf(" | %s %s", entry.Method, entry.Path)
+		fmt.Fprintf(&b, " | %s %s", entry.Method, entry.Path)
 	}
 	if entry.StatusCode > 0 {
-		fmt.Printf(" | status=%d", entry.StatusCode)
+		fmt.Fprintf(&b, " | status=%d", entry.StatusCode)
 	}
 	if entry.Duration > 0 {
-		fmt.Printf(" | duration=%dms", entry.Duration)
+		fmt.Fprintf(&b, " | duration=%dms", entry.Duration)
 	}
 	if entry.Error != "" {
-		fmt.Printf(" | error=%s", entry.Error)
+		fmt.Fprintf(&b, " | error=%s", entry.Error)
 	}
 
 	if len(entry.Fields) > 0 {
-		fmt.Printf(" | fields=%v", entry.Fields)
+		fmt.Fprintf(&b, " | fields=%v", entry.Fields)
 	}
 
-	fmt.Println()
+	b.WriteByte('\n')
+	io.WriteString(l.out, b.String())
 }
 
 // Debug logs a debug message
@@ -263,7 +356,7 @@ func (cl *ContextLogger) Error(msg string, err error, fields map[string]any) {
 }
 
 // LogRequest logs an HTTP request with full context
-func (cl *ContextLogger) LogRequest(statusCode int, duration time.Duration) {
+func (cl *ContextLogger) LogRequest(statusCode int, duration time.Duration, fields map[string]any) {
 	level := LevelInfo
 	if statusCode >= 500 {
 		level = LevelError
@@ -281,10 +374,25 @@ func (cl *ContextLogger) LogRequest(statusCode int, duration time.Duration) {
 		Path:       cl.path,
 		StatusCode: statusCode,
 		Duration:   duration.Milliseconds(),
+		Fields:     fields,
 	})
 }
 
-// LoggingMiddleware creates middleware with structured logging
+// refresh picks up identifiers that later middleware (JWT, tracing)
+// stored after the context logger was created
+func (cl *ContextLogger) refresh(c echo.Context) {
+	if cl.userID == "" {
+		if uid, ok := c.Get("user_id").(uuid.UUID); ok {
+			cl.userID = uid.String()
+		}
+	}
+	if cl.traceID == "" {
+		cl.traceID = c.Response().Header().Get("X-Trace-ID")
+	}
+}
+
+// LoggingMiddleware creates middleware with structured logging.
+// It is the single access log for the API.
 func LoggingMiddleware(logger *Logger) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -294,16 +402,21 @@ func LoggingMiddleware(logger *Logger) echo.MiddlewareFunc {
 			// Store logger in context
 			c.Set("logger", contextLogger)
 
-			// Execute request
-			err := next(c)
+			// Execute request and commit the error response so the
+			// logged status code is the one the client receives
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
 
 			// Log request
-			statusCode := c.Response().Status
-			duration := time.Since(start)
-
-			contextLogger.LogRequest(statusCode, duration)
-
-			return err
+			contextLogger.refresh(c)
+			contextLogger.LogRequest(c.Response().Status, time.Since(start), map[string]any{
+				"client_ip":  c.RealIP(),
+				"user_agent": c.Request().UserAgent(),
+				"bytes_out":  c.Response().Size,
+			})
+
+			return nil
 		}
 	}
 }
@@ -313,6 +426,14 @@ func GetLogger(c echo.Context) *ContextLogger {
 	if logger, ok := c.Get("logger").(*ContextLogger); ok {
 		return logger
 	}
-	// Fallback to creating new logger
-	return NewLogger("digiorder", "production").FromContext(c)
+	// Fallback to a shared logger so the file sink is opened only once
+	fallbackOnce.Do(func() {
+		fallbackLogger = NewLogger("digiorder", "production")
+	})
+	return fallbackLogger.FromContext(c)
 }
+
+var (
+	fallbackOnce   sync.Once
+	fallbackLogger *Logger
+)
@@ -0,0 +1,218 @@
+// internal/logging/rotate.go - File sink with size and age based rotation
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is appended to rotated file names
+const backupTimeFormat = "20060102-150405"
+
+// RotatingFile is an io.Writer that rotates the underlying file when it
+// exceeds MaxSize bytes or is older than MaxAge, keeping at most MaxBackups
+// rotated files next to it.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile opens (or creates) the log file at path
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		Path:       path,
+		MaxSize:    maxSize,
+		MaxAge:     maxAge,
+		MaxBackups: maxBackups,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+// Write appends p to the log file, rotating first if needed
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// open opens the log file in append mode and records its size and age
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	rf.openedAt = info.ModTime()
+	if rf.size == 0 {
+		rf.openedAt = time.Now()
+	}
+
+	return nil
+}
+
+// shouldRotate checks size and age limits
+func (rf *RotatingFile) shouldRotate(incoming int64) bool {
+	if rf.MaxSize > 0 && rf.size+incoming > rf.MaxSize && rf.size > 0 {
+		return true
+	}
+	if rf.MaxAge > 0 && time.Since(rf.openedAt) > rf.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate renames the current file with a timestamp suffix and reopens
+func (rf *RotatingFile) rotate() error {
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		rf.file = nil
+	}
+
+	backup := rf.Path + "." + time.Now().Format(backupTimeFormat)
+	// Avoid clobbering a backup created within the same second
+	for i := 1; fileExists(backup); i++ {
+		backup = rf.Path + "." + time.Now().Format(backupTimeFormat) + "." + strconv.Itoa(i)
+	}
+
+	if err := os.Rename(rf.Path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	rf.pruneBackups()
+
+	return rf.open()
+}
+
+// pruneBackups removes the oldest rotated files beyond MaxBackups
+func (rf *RotatingFile) pruneBackups() {
+	if rf.MaxBackups <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(rf.Path + ".*")
+	if err != nil {
+		return
+	}
+
+	backups := matches[:0]
+	for _, m := range matches {
+		if strings.HasPrefix(filepath.Base(m), filepath.Base(rf.Path)+".") {
+			backups = append(backups, m)
+		}
+	}
+	if len(backups) <= rf.MaxBackups {
+		return
+	}
+
+	// Timestamp suffixes sort chronologically
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-rf.MaxBackups] {
+		os.Remove(old)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// OutputFromEnv builds the log writer described by the environment:
+//
+//	LOG_OUTPUT            stdout (default), file or both
+//	LOG_FILE_PATH         log file location (default logs/digiorder.log)
+//	LOG_FILE_MAX_SIZE_MB  rotate after this many megabytes (default 100)
+//	LOG_FILE_MAX_AGE      rotate after this duration, e.g. 24h (default 24h)
+//	LOG_FILE_MAX_BACKUPS  rotated files to keep (default 7)
+func OutputFromEnv() (io.Writer, error) {
+	output := os.Getenv("LOG_OUTPUT")
+	if output == "" || output == "stdout" {
+		return os.Stdout, nil
+	}
+
+	path := os.Getenv("LOG_FILE_PATH")
+	if path == "" {
+		path = filepath.Join("logs", "digiorder.log")
+	}
+
+	maxSizeMB := 100
+	if v, err := strconv.Atoi(os.Getenv("LOG_FILE_MAX_SIZE_MB")); err == nil && v > 0 {
+		maxSizeMB = v
+	}
+	maxAge := 24 * time.Hour
+	if v, err := time.ParseDuration(os.Getenv("LOG_FILE_MAX_AGE")); err == nil && v > 0 {
+		maxAge = v
+	}
+	maxBackups := 7
+	if v, err := strconv.Atoi(os.Getenv("LOG_FILE_MAX_BACKUPS")); err == nil && v >= 0 {
+		maxBackups = v
+	}
+
+	file, err := NewRotatingFile(path, int64(maxSizeMB)*1024*1024, maxAge, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	switch output {
+	case "file":
+		return file, nil
+	case "both":
+		return io.MultiWriter(os.Stdout, file), nil
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unknown LOG_OUTPUT %q (expected stdout, file or both)", output)
+	}
+}
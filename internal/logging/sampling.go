@@ -0,0 +1,173 @@
+// internal/logging/sampling.go - Deduplication of repetitive log entries
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	logEntriesSampled = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_entries_suppressed_total",
+			Help: "Total number of log entries suppressed by sampling",
+		},
+		[]string{"level"},
+	)
+
+	logEntriesSummarized = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_entries_summarized_total",
+			Help: "Total number of repeat summaries emitted by sampling",
+		},
+		[]string{"level"},
+	)
+)
+
+// sampleCounter tracks occurrences of one distinct entry in the current window
+type sampleCounter struct {
+	start      time.Time
+	seen       int
+	suppressed int
+	entry      LogEntry
+}
+
+// sampler lets the first Burst identical WARN/ERROR entries through per
+// Window and collapses the rest into a single "repeated N times" summary
+type sampler struct {
+	window   time.Duration
+	burst    int
+	mu       sync.Mutex
+	counters map[string]*sampleCounter
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newSamplerFromEnv builds a sampler from LOG_SAMPLING_WINDOW (default 60s)
+// and LOG_SAMPLING_BURST (default 5). A burst of 0 disables sampling.
+func newSamplerFromEnv() *sampler {
+	window := 60 * time.Second
+	if v, err := time.ParseDuration(os.Getenv("LOG_SAMPLING_WINDOW")); err == nil && v > 0 {
+		window = v
+	}
+	burst := 5
+	if v, err := strconv.Atoi(os.Getenv("LOG_SAMPLING_BURST")); err == nil && v >= 0 {
+		burst = v
+	}
+	if burst == 0 {
+		return nil
+	}
+
+	return &sampler{
+		window:   window,
+		burst:    burst,
+		counters: make(map[string]*sampleCounter),
+		done:     make(chan struct{}),
+	}
+}
+
+// sampleKey identifies "the same" log line. Access log entries share a
+// message, so the route and status are part of the key.
+func sampleKey(entry LogEntry) string {
+	return fmt.Sprintf("%s|%s|%s|%s %s|%d", entry.Level, entry.Message,
+		entry.Error, entry.Method, entry.Path, entry.StatusCode)
+}
+
+// allow reports whether entry should be written. When a previous window
+// for the same entry had suppressed lines, its summary is returned too.
+func (s *sampler) allow(entry LogEntry) (bool, *LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sampleKey(entry)
+	now := entry.Timestamp
+
+	counter, exists := s.counters[key]
+	if !exists || now.Sub(counter.start) >= s.window {
+		var summary *LogEntry
+		if exists {
+			summary = s.summary(counter, now)
+		}
+		s.counters[key] = &sampleCounter{start: now, seen: 1, entry: entry}
+		return true, summary
+	}
+
+	counter.seen++
+	if counter.seen <= s.burst {
+		return true, nil
+	}
+
+	counter.suppressed++
+	logEntriesSampled.WithLabelValues(string(entry.Level)).Inc()
+	return false, nil
+}
+
+// flush returns summaries for windows that have closed and forgets them
+func (s *sampler) flush(now time.Time) []LogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var summaries []LogEntry
+	for key, counter := range s.counters {
+		if now.Sub(counter.start) < s.window {
+			continue
+		}
+		if summary := s.summary(counter, now); summary != nil {
+			summaries = append(summaries, *summary)
+		}
+		delete(s.counters, key)
+	}
+
+	return summaries
+}
+
+// summary builds the "repeated N times" entry for a closed window
+func (s *sampler) summary(counter *sampleCounter, now time.Time) *LogEntry {
+	if counter.suppressed == 0 {
+		return nil
+	}
+
+	logEntriesSummarized.WithLabelValues(string(counter.entry.Level)).Inc()
+
+	summary := counter.entry
+	summary.Timestamp = now
+	summary.Message = fmt.Sprintf("%s (repeated %d times in last %s)",
+		counter.entry.Message, counter.suppressed, s.window)
+	summary.Fields = map[string]any{
+		"sampled":          true,
+		"suppressed_count": counter.suppressed,
+		"window_seconds":   int(s.window.Seconds()),
+	}
+
+	return &summary
+}
+
+// run periodically emits summaries so a burst that stops abruptly is
+// still reported
+func (s *sampler) run(l *Logger) {
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, summary := range s.flush(now) {
+				l.write(summary)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// stop ends run; safe to call more than once
+func (s *sampler) stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"container/list"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
@@ -17,20 +19,55 @@ type CacheEntry struct {
 	StatusCode int
 	Headers    http.Header
 	Timestamp  time.Time
+	Expires    time.Time
 }
 
-// Cache manages cached responses
+// size approximates the memory an entry stored under key takes
+func (e *CacheEntry) size(key string) int64 {
+	n := len(key) + len(e.Body)
+	for k, v := range e.Headers {
+		n += len(k)
+		for _, vv := range v {
+			n += len(vv)
+		}
+	}
+	return int64(n)
+}
+
+// Cache keeps responses in least recently used order, bounded both by the
+// number of entries and by their total size. Adding an entry past either
+// limit evicts the entries used longest ago.
 type Cache struct {
-	entries map[string]*CacheEntry
-	mu      sync.RWMutex
-	ttl     time.Duration
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // of *cacheItem, most recently used first
+	items      map[string]*list.Element
 }
 
-// NewCache creates a new cache with specified TTL
-func NewCache(ttl time.Duration) *Cache {
+type cacheItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// CacheStats is the current size of a cache and its limits
+type CacheStats struct {
+	Entries    int   `json:"entries"`
+	Bytes      int64 `json:"bytes"`
+	MaxEntries int   `json:"max_entries"`
+	MaxBytes   int64 `json:"max_bytes"`
+}
+
+// NewCache creates a cache holding at most maxEntries entries and
+// maxBytes bytes of responses
+func NewCache(maxEntries int, maxBytes int64) *Cache {
 	cache := &Cache{
-		entries: make(map[string]*CacheEntry),
-		ttl:     ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
 	}
 
 	// Start cleanup goroutine
@@ -39,30 +76,53 @@ func NewCache(ttl time.Duration) *Cache {
 	return cache
 }
 
-// Get retrieves a cached entry
+// Get retrieves a cached entry, marking it as the most recently used
 func (c *Cache) Get(key string) (*CacheEntry, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	entry, exists := c.entries[key]
+	el, exists := c.items[key]
 	if !exists {
+		RecordCacheMiss(CacheResponses)
 		return nil, false
 	}
 
-	// Check if entry is expired
-	if time.Since(entry.Timestamp) > c.ttl {
+	// Expired entries are dropped on sight
+	item := el.Value.(*cacheItem)
+	if time.Now().After(item.entry.Expires) {
+		c.remove(el)
+		c.updateMetrics()
+		RecordCacheMiss(CacheResponses)
 		return nil, false
 	}
 
-	return entry, true
+	c.order.MoveToFront(el)
+	RecordCacheHit(CacheResponses)
+	return item.entry, true
 }
 
-// Set stores a cache entry
+// Set stores a cache entry, evicting the least recently used entries
+// until both limits hold. An entry larger than the whole cache is not
+// stored.
 func (c *Cache) Set(key string, entry *CacheEntry) {
+	size := entry.size(key)
+	if size > c.maxBytes {
+		return
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.entries[key] = entry
+	if el, exists := c.items[key]; exists {
+		c.remove(el)
+	}
+	for c.order.Len() > 0 && (c.order.Len() >= c.maxEntries || c.bytes+size > c.maxBytes) {
+		c.remove(c.order.Back())
+		RecordCacheEviction()
+	}
+	c.items[key] = c.order.PushFront(&cacheItem{key: key, entry: entry, size: size})
+	c.bytes += size
+	c.updateMetrics()
 }
 
 // Delete removes a cache entry
@@ -70,7 +130,10 @@ func (c *Cache) Delete(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.entries, key)
+	if el, exists := c.items[key]; exists {
+		c.remove(el)
+		c.updateMetrics()
+	}
 }
 
 // Clear removes all cache entries
@@ -78,21 +141,55 @@ func (c *Cache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.entries = make(map[string]*CacheEntry)
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+	c.updateMetrics()
 }
 
-// cleanup removes expired entries periodically
+// Stats returns the cache's size and limits
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Entries:    c.order.Len(),
+		Bytes:      c.bytes,
+		MaxEntries: c.maxEntries,
+		MaxBytes:   c.maxBytes,
+	}
+}
+
+// remove drops the entry at el; c.mu must be held
+func (c *Cache) remove(el *list.Element) {
+	item := c.order.Remove(el).(*cacheItem)
+	delete(c.items, item.key)
+	c.bytes -= item.size
+}
+
+// updateMetrics publishes the cache size; c.mu must be held
+func (c *Cache) updateMetrics() {
+	UpdateCacheSize(c.order.Len())
+	UpdateCacheBytes(c.bytes)
+}
+
+// cleanup removes expired entries periodically, so they do not hold
+// memory until they are evicted
 func (c *Cache) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
 		c.mu.Lock()
-		for key, entry := range c.entries {
-			if time.Since(entry.Timestamp) > c.ttl {
-				delete(c.entries, key)
+		now := time.Now()
+		for el := c.order.Back(); el != nil; {
+			prev := el.Prev()
+			if now.After(el.Value.(*cacheItem).entry.Expires) {
+				c.remove(el)
 			}
+			el = prev
 		}
+		c.updateMetrics()
 		c.mu.Unlock()
 	}
 }
@@ -109,15 +206,19 @@ func generateCacheKey(c echo.Context) string {
 		base += fmt.Sprintf(":user:%s", userID)
 	}
 
+	// Tenants must never be served each other's responses
+	if tenantID, ok := c.Get("tenant_id").(uuid.UUID); ok {
+		base += fmt.Sprintf(":tenant:%s", tenantID)
+	}
+
 	// Create hash
 	hash := md5.Sum([]byte(base))
 	return hex.EncodeToString(hash[:])
 }
 
-// CacheMiddleware creates a caching middleware
-func CacheMiddleware(ttl time.Duration, cachableStatuses ...int) echo.MiddlewareFunc {
-	cache := NewCache(ttl)
-
+// CacheMiddleware creates a caching middleware keeping responses in cache
+// for ttl. Several route groups may share one cache, each with its own ttl.
+func CacheMiddleware(cache *Cache, ttl time.Duration, cachableStatuses ...int) echo.MiddlewareFunc {
 	// Default cachable statuses
 	if len(cachableStatuses) == 0 {
 		cachableStatuses = []int{http.StatusOK}
@@ -168,13 +269,15 @@ func CacheMiddleware(ttl time.Duration, cachableStatuses ...int) echo.Middleware
 				}
 			}
 
-			if err == nil && shouldCache {
+			if err == nil && shouldCache && !rec.streamed {
 				// Store in cache
+				now := time.Now()
 				entry := &CacheEntry{
 					Body:       rec.body,
 					StatusCode: rec.status,
 					Headers:    c.Response().Header().Clone(),
-					Timestamp:  time.Now(),
+					Timestamp:  now,
+					Expires:    now.Add(ttl),
 				}
 				cache.Set(key, entry)
 				c.Response().Header().Set("X-Cache", "MISS")
@@ -185,18 +288,32 @@ func CacheMiddleware(ttl time.Duration, cachableStatuses ...int) echo.Middleware
 	}
 }
 
-// responseRecorder captures the response for caching
+// responseRecorder captures the response for caching. A response that is
+// flushed is being streamed, and is neither kept nor cached.
 type responseRecorder struct {
 	http.ResponseWriter
-	body   []byte
-	status int
+	body     []byte
+	status   int
+	streamed bool
 }
 
 func (r *responseRecorder) Write(b []byte) (int, error) {
-	r.body = append(r.body, b...)
+	if !r.streamed {
+		r.body = append(r.body, b...)
+	}
 	return r.ResponseWriter.Write(b)
 }
 
+func (r *responseRecorder) Flush() {
+	r.streamed, r.body = true, nil
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (r *responseRecorder) WriteHeader(statusCode int) {
 	r.status = statusCode
 	r.ResponseWriter.WriteHeader(statusCode)
@@ -2,6 +2,8 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
@@ -45,6 +47,13 @@ func GetRequestID(c echo.Context) string {
 	return ""
 }
 
+// GetRequestStart returns when the request started, or the zero time
+// when it was not recorded
+func GetRequestStart(c echo.Context) time.Time {
+	start, _ := c.Get("request_start").(time.Time)
+	return start
+}
+
 // GetTraceID retrieves trace ID from context
 func GetTraceID(c echo.Context) string {
 	if traceID, ok := c.Get("trace_id").(string); ok {
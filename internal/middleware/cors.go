@@ -3,7 +3,9 @@ package middleware
 
 import (
 	"os"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -40,12 +42,21 @@ func DefaultCORSConfig() CORSConfig {
 			"Content-Type",
 			"X-CSRF-Token",
 			"X-Request-ID",
+			HeaderAcceptVersion,
 		},
 		ExposeHeaders: []string{
 			"X-Request-ID",
 			"X-Trace-ID",
 			"X-Cache",
 			"X-Cache-Age",
+			HeaderAPIVersion,
+			"Deprecation",
+			"Sunset",
+			"Link",
+			HeaderRateLimitLimit,
+			HeaderRateLimitRemaining,
+			HeaderRateLimitReset,
+			HeaderRetryAfter,
 		},
 		AllowCredentials: true,
 		MaxAge:           3600, // 1 hour
@@ -54,8 +65,11 @@ func DefaultCORSConfig() CORSConfig {
 
 // SecureCORSMiddleware creates CORS middleware with security checks
 func SecureCORSMiddleware() echo.MiddlewareFunc {
-	config := DefaultCORSConfig()
+	return SecureCORSWithConfig(DefaultCORSConfig())
+}
 
+// SecureCORSWithConfig creates CORS middleware from an explicit config
+func SecureCORSWithConfig(config CORSConfig) echo.MiddlewareFunc {
 	return middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     config.AllowOrigins,
 		AllowMethods:     config.AllowMethods,
@@ -66,6 +80,55 @@ func SecureCORSMiddleware() echo.MiddlewareFunc {
 	})
 }
 
+// CORSOrigins holds the allowed origin list so it can be replaced at runtime
+type CORSOrigins struct {
+	mu      sync.RWMutex
+	origins []string
+}
+
+// NewCORSOrigins creates a reloadable origin list
+func NewCORSOrigins(origins []string) *CORSOrigins {
+	return &CORSOrigins{origins: slices.Clone(origins)}
+}
+
+// Set replaces the allowed origins
+func (o *CORSOrigins) Set(origins []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.origins = slices.Clone(origins)
+}
+
+// Get returns a copy of the allowed origins
+func (o *CORSOrigins) Get() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return slices.Clone(o.origins)
+}
+
+// Allow reports whether origin is currently allowed
+func (o *CORSOrigins) Allow(origin string) (bool, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if slices.Contains(o.origins, "*") {
+		return true, nil
+	}
+	return ValidateOrigin(origin, o.origins), nil
+}
+
+// ReloadableCORSMiddleware creates CORS middleware whose allowed origins are
+// read from origins on every request; config.AllowOrigins is ignored
+func ReloadableCORSMiddleware(config CORSConfig, origins *CORSOrigins) echo.MiddlewareFunc {
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc:  origins.Allow,
+		AllowMethods:     config.AllowMethods,
+		AllowHeaders:     config.AllowHeaders,
+		ExposeHeaders:    config.ExposeHeaders,
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           config.MaxAge,
+	})
+}
+
 // getEnvList retrieves a comma-separated list from environment
 func getEnvList(key string, fallback []string) []string {
 	value := os.Getenv(key)
@@ -92,7 +155,7 @@ func ValidateOrigin(origin string, allowedOrigins []string) bool {
 
 		// Support wildcard subdomains
 		if strings.HasPrefix(allowed, "*.") {
-			domain := strings.TrimPrefix(allowed, "*.")
+			domain := strings.TrimPrefix(allowed, "*")
 			if strings.HasSuffix(origin, domain) {
 				return true
 			}
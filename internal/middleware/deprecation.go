@@ -0,0 +1,87 @@
+// internal/middleware/deprecation.go - Endpoint deprecation signalling
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var deprecatedRequestsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "deprecated_endpoint_requests_total",
+		Help: "Total number of requests served by deprecated endpoints",
+	},
+	[]string{"method", "endpoint"},
+)
+
+// DeprecationInfo describes why and until when a route is served
+type DeprecationInfo struct {
+	// Since is when the route was deprecated (Deprecation header)
+	Since time.Time
+	// Sunset is when the route will be removed (Sunset header); optional
+	Sunset time.Time
+	// Successor is the path clients should migrate to; optional
+	Successor string
+	// Message is surfaced in the response envelope's warning field
+	Message string
+}
+
+// warning builds the human-readable warning for the response envelope
+func (d DeprecationInfo) warning() string {
+	if d.Message != "" {
+		return d.Message
+	}
+
+	msg := "This endpoint is deprecated"
+	if !d.Sunset.IsZero() {
+		msg += fmt.Sprintf(" and will be removed on %s", d.Sunset.Format("2006-01-02"))
+	}
+	if d.Successor != "" {
+		msg += fmt.Sprintf(". Use %s instead", d.Successor)
+	}
+	return msg + "."
+}
+
+// Deprecated marks a route as deprecated. It sets the Deprecation (RFC 9745),
+// Sunset (RFC 8594) and Link successor headers, counts usage in metrics and
+// stores a warning that RespondSuccess adds to the response envelope.
+//
+//	products.GET("/legacy", s.Legacy, middleware.Deprecated(middleware.DeprecationInfo{...}))
+func Deprecated(info DeprecationInfo) echo.MiddlewareFunc {
+	deprecation := "true"
+	if !info.Since.IsZero() {
+		deprecation = fmt.Sprintf("@%d", info.Since.Unix())
+	}
+	warning := info.warning()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set("Deprecation", deprecation)
+			if !info.Sunset.IsZero() {
+				header.Set("Sunset", info.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if info.Successor != "" {
+				header.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", info.Successor))
+			}
+
+			deprecatedRequestsTotal.WithLabelValues(c.Request().Method, c.Path()).Inc()
+			c.Set("deprecation_warning", warning)
+
+			return next(c)
+		}
+	}
+}
+
+// GetDeprecationWarning returns the warning for a deprecated route, if any
+func GetDeprecationWarning(c echo.Context) string {
+	if warning, ok := c.Get("deprecation_warning").(string); ok {
+		return warning
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"os"
+	"strconv"
+)
+
+// getEnvString retrieves a string from environment with a fallback
+func getEnvString(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt retrieves an integer from environment with a fallback
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvBool retrieves a boolean from environment with a fallback
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
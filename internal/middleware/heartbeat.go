@@ -0,0 +1,43 @@
+// internal/middleware/heartbeat.go - Liveness tracking for background loops
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat records when a periodic background loop last ran so readiness
+// checks can detect a worker that has stopped or is stuck
+type Heartbeat struct {
+	name     string
+	interval time.Duration
+	last     atomic.Int64 // unix nanoseconds
+}
+
+// NewHeartbeat creates a heartbeat for a loop that ticks every interval.
+// It counts as alive from creation until the first tick is due.
+func NewHeartbeat(name string, interval time.Duration) *Heartbeat {
+	h := &Heartbeat{name: name, interval: interval}
+	h.Beat()
+	return h
+}
+
+// Beat marks the loop as having just run
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Name returns the worker name
+func (h *Heartbeat) Name() string {
+	return h.name
+}
+
+// LastBeat returns the time of the last tick
+func (h *Heartbeat) LastBeat() time.Time {
+	return time.Unix(0, h.last.Load())
+}
+
+// Alive reports whether the loop ticked within two intervals
+func (h *Heartbeat) Alive() bool {
+	return time.Since(h.LastBeat()) <= 2*h.interval
+}
@@ -0,0 +1,199 @@
+// internal/middleware/ip_rules.go - Persistent IP allow and deny rules
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// Rule actions
+const (
+	IPRuleAllow = "allow"
+	IPRuleDeny  = "deny"
+)
+
+// ipRulesRefreshInterval is how often the rules are re-read, so a rule
+// added on one node applies on all of them
+const ipRulesRefreshInterval = 30 * time.Second
+
+// ipRulesRetention is how long expired rules are kept before they are
+// deleted
+const ipRulesRetention = 7 * 24 * time.Hour
+
+// IPRule allows or denies the addresses of Network until Expires, or for
+// good when Expires is zero
+type IPRule struct {
+	Network *net.IPNet
+	Action  string
+	Reason  string
+	Expires time.Time
+}
+
+// IPRules is this node's copy of the ip_rules table. It is read at
+// startup, re-read every ipRulesRefreshInterval and after every change
+// made through this node.
+type IPRules struct {
+	queries   db.Querier
+	ticker    *time.Ticker
+	heartbeat *Heartbeat
+
+	mu    sync.RWMutex
+	rules []IPRule
+}
+
+// NewIPRules loads the active rules and keeps them up to date. Without
+// queries there are no rules.
+func NewIPRules(queries db.Querier) *IPRules {
+	r := &IPRules{
+		queries:   queries,
+		ticker:    time.NewTicker(ipRulesRefreshInterval),
+		heartbeat: NewHeartbeat("ip_rules_refresh", ipRulesRefreshInterval),
+	}
+	if queries != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = r.Reload(ctx) // retried every refresh
+		cancel()
+	}
+
+	go r.refresh()
+
+	return r
+}
+
+// Reload re-reads the active rules. The previous rules stay in force when
+// they cannot be read.
+func (r *IPRules) Reload(ctx context.Context) error {
+	if r.queries == nil {
+		return nil
+	}
+	rows, err := r.queries.ListActiveIPRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := make([]IPRule, 0, len(rows))
+	for _, row := range rows {
+		if !row.Cidr.Valid {
+			continue
+		}
+		network := row.Cidr.IPNet
+		rules = append(rules, IPRule{
+			Network: &network,
+			Action:  row.Action,
+			Reason:  row.Reason.String,
+			Expires: row.ExpiresAt.Time,
+		})
+	}
+
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+	return nil
+}
+
+// Match returns the rule for ip: the most specific of the unexpired rules
+// whose network holds it, a deny rule when an allow and a deny rule are
+// as specific
+func (r *IPRules) Match(ip string) (IPRule, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return IPRule{}, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var match IPRule
+	best := -1
+	for _, rule := range r.rules {
+		if !rule.Expires.IsZero() && !now.Before(rule.Expires) {
+			continue
+		}
+		if !rule.Network.Contains(addr) {
+			continue
+		}
+		ones, _ := rule.Network.Mask.Size()
+		if ones > best || (ones == best && rule.Action == IPRuleDeny) {
+			match, best = rule, ones
+		}
+	}
+	return match, best >= 0
+}
+
+// refresh re-reads the rules and deletes long expired ones until Stop
+func (r *IPRules) refresh() {
+	for range r.ticker.C {
+		if r.queries != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			_ = r.Reload(ctx)
+			_, _ = r.queries.DeleteExpiredIPRules(ctx, time.Now().Add(-ipRulesRetention))
+			cancel()
+		}
+		r.heartbeat.Beat()
+	}
+}
+
+// Heartbeat reports whether the refresh loop is running
+func (r *IPRules) Heartbeat() *Heartbeat {
+	return r.heartbeat
+}
+
+// Stop stops the refresh goroutine
+func (r *IPRules) Stop() {
+	r.ticker.Stop()
+}
+
+// ParseIPNetwork reads an address ("203.0.113.7") or a network in CIDR
+// notation ("203.0.113.0/24"); an address is a network of its own. Host
+// bits of a network are cleared.
+func ParseIPNetwork(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR network", s)
+		}
+		if v4 := ip.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not an IP address or CIDR network", s)
+	}
+	return network, nil
+}
+
+// hostCIDR is the network of ip alone, as stored for a ban
+func hostCIDR(ip string) (pqtype.CIDR, bool) {
+	network, err := ParseIPNetwork(ip)
+	if err != nil {
+		return pqtype.CIDR{}, false
+	}
+	return pqtype.CIDR{IPNet: *network, Valid: true}, true
+}
+
+// persistBan keeps a ban as an expiring deny rule without a creator
+func persistBan(ctx context.Context, queries db.Querier, ip, reason string, until time.Time) error {
+	cidr, ok := hostCIDR(ip)
+	if !ok {
+		return nil
+	}
+	_, err := queries.CreateIPRule(ctx, db.CreateIPRuleParams{
+		Cidr:      cidr,
+		Action:    IPRuleDeny,
+		Reason:    sql.NullString{String: reason, Valid: reason != ""},
+		ExpiresAt: sql.NullTime{Time: until, Valid: true},
+	})
+	return err
+}
@@ -3,7 +3,6 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"os"
 	"strings"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/labstack/echo/v4"
 )
 
@@ -24,15 +24,39 @@ var (
 
 // JWTClaims represents the claims stored in JWT
 type JWTClaims struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Username string    `json:"username"`
-	RoleID   int32     `json:"role_id"`
-	RoleName string    `json:"role_name"`
+	UserID       uuid.UUID `json:"user_id"`
+	Username     string    `json:"username"`
+	RoleID       int32     `json:"role_id"`
+	RoleName     string    `json:"role_name"`
+	TenantID     uuid.UUID `json:"tenant_id"`
+	DepartmentID int32     `json:"department_id,omitempty"`
+	Language     string    `json:"language,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GetJWTSecret retrieves JWT secret from environment
+// jwtSettings holds values injected by ConfigureJWT. When unset the
+// JWT_SECRET and JWT_EXPIRY environment variables are used.
+var jwtSettings struct {
+	secret []byte
+	expiry time.Duration
+	// signing and keys are set by ConfigureJWTKeys
+	signing *SigningKey
+	keys    []*SigningKey
+}
+
+// ConfigureJWT sets the signing secret and token lifetime from the
+// application configuration
+func ConfigureJWT(secret string, expiry time.Duration) {
+	jwtSettings.secret = []byte(secret)
+	jwtSettings.expiry = expiry
+}
+
+// GetJWTSecret retrieves JWT secret from configuration or environment
 func GetJWTSecret() []byte {
+	if len(jwtSettings.secret) > 0 {
+		return jwtSettings.secret
+	}
+
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
 		panic("JWT_SECRET environment variable is not set")
@@ -42,6 +66,10 @@ func GetJWTSecret() []byte {
 
 // GetJWTExpiry returns JWT expiration duration
 func GetJWTExpiry() time.Duration {
+	if jwtSettings.expiry > 0 {
+		return jwtSettings.expiry
+	}
+
 	expiryStr := os.Getenv("JWT_EXPIRY")
 	if expiryStr == "" {
 		return 24 * time.Hour // Default 24 hours
@@ -55,13 +83,17 @@ func GetJWTExpiry() time.Duration {
 	return duration
 }
 
-// GenerateToken creates a new JWT token
-func GenerateToken(userID uuid.UUID, username string, roleID int32, roleName string) (string, error) {
+// GenerateToken creates a new JWT token, signed with the key set by
+// ConfigureJWTKeys or else the HS256 secret
+func GenerateToken(userID uuid.UUID, username string, roleID int32, roleName string, tenantID uuid.UUID, departmentID int32, language string) (string, error) {
 	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
-		RoleID:   roleID,
-		RoleName: roleName,
+		UserID:       userID,
+		Username:     username,
+		RoleID:       roleID,
+		RoleName:     roleName,
+		TenantID:     tenantID,
+		DepartmentID: departmentID,
+		Language:     language,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(GetJWTExpiry())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -71,19 +103,20 @@ func GenerateToken(userID uuid.UUID, username string, roleID int32, roleName str
 		},
 	}
 
+	if key := jwtSettings.signing; key != nil {
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.private)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(GetJWTSecret())
 }
 
-// ValidateToken validates and parses a JWT token
+// ValidateToken validates and parses a JWT token signed with the HS256
+// secret or, once ConfigureJWTKeys was called, with one of its keys
 func ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return GetJWTSecret(), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -157,8 +190,15 @@ func JWTMiddleware() echo.MiddlewareFunc {
 			c.Set("username", claims.Username)
 			c.Set("role_id", claims.RoleID)
 			c.Set("role_name", claims.RoleName)
+			c.Set("tenant_id", claims.TenantID)
+			c.Set("department_id", claims.DepartmentID)
+			c.Set("language", claims.Language)
 			c.Set("jwt_claims", claims)
 
+			updateQueryTag(c, func(tag *db.QueryTag) {
+				tag.UserID = claims.UserID.String()
+			})
+
 			return next(c)
 		}
 	}
@@ -200,6 +240,33 @@ func GetUserIDFromContext(c echo.Context) (uuid.UUID, error) {
 	return userID, nil
 }
 
+// GetTenantIDFromContext retrieves the tenant of the authenticated user.
+// Tokens issued before tenancy carry none and belong to the main tenant.
+func GetTenantIDFromContext(c echo.Context) (uuid.UUID, error) {
+	tenantID, ok := c.Get("tenant_id").(uuid.UUID)
+	if !ok {
+		return uuid.Nil, errors.New("tenant ID not found in context")
+	}
+	if tenantID == uuid.Nil {
+		return db.DefaultTenantID, nil
+	}
+	return tenantID, nil
+}
+
+// GetDepartmentIDFromContext retrieves the department of the authenticated
+// user, 0 when the user has none
+func GetDepartmentIDFromContext(c echo.Context) int32 {
+	departmentID, _ := c.Get("department_id").(int32)
+	return departmentID
+}
+
+// GetLanguageFromContext retrieves the language the authenticated user
+// chose for messages, "" when the user has not chosen one
+func GetLanguageFromContext(c echo.Context) string {
+	language, _ := c.Get("language").(string)
+	return language
+}
+
 // GetUsernameFromContext retrieves username from context
 func GetUsernameFromContext(c echo.Context) (string, error) {
 	username, ok := c.Get("username").(string)
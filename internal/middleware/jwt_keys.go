@@ -0,0 +1,237 @@
+// internal/middleware/jwt_keys.go - Asymmetric JWT signing keys and their JWKS
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSPath is where the public keys tokens are verified with are published
+const JWKSPath = "/.well-known/jwks.json"
+
+// minRSAKeyBits is the smallest RSA key accepted for signing or verifying
+const minRSAKeyBits = 2048
+
+// SigningKey is a key tokens are verified with and, when it holds the
+// private key, signed with. ID is the RFC 7638 thumbprint of the public
+// key, sent as the kid header of the tokens it signs.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  crypto.PublicKey
+	private crypto.PrivateKey
+}
+
+// JSONWebKey is the public half of a signing key in JWK form (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet lists the keys tokens may be signed with
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// LoadSigningKey reads a PEM key file: a private key (PKCS #8, or PKCS #1
+// for RSA), or a public key (PKIX) for a key that only verifies tokens.
+// RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+func LoadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParseSigningKey parses a PEM key as LoadSigningKey does
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private crypto.PrivateKey
+	var public crypto.PublicKey
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		public = key
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if signer, ok := private.(crypto.Signer); ok {
+		public = signer.Public()
+	}
+
+	key := &SigningKey{Public: public, private: private}
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key has %d bits, at least %d are required", pub.N.BitLen(), minRSAKeyBits)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", public)
+	}
+	key.ID = key.thumbprint()
+	return key, nil
+}
+
+// CanSign reports whether the key holds its private key
+func (k *SigningKey) CanSign() bool {
+	return k.private != nil
+}
+
+// JWK returns the public key in JWK form
+func (k *SigningKey) JWK() JSONWebKey {
+	jwk := JSONWebKey{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// thumbprint is the RFC 7638 SHA-256 thumbprint of the public key: the
+// hash of its required JWK members, in lexical order without whitespace
+func (k *SigningKey) thumbprint() string {
+	jwk := k.JWK()
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ConfigureJWTKeys signs tokens with current and accepts tokens signed
+// with current or any of previous. Tokens signed with the HS256 secret are
+// no longer accepted. A nil current keeps HS256.
+func ConfigureJWTKeys(current *SigningKey, previous []*SigningKey) error {
+	if current == nil {
+		jwtSettings.signing = nil
+		jwtSettings.keys = nil
+		return nil
+	}
+	if !current.CanSign() {
+		return errors.New("the signing key must be a private key")
+	}
+
+	keys := []*SigningKey{current}
+	for _, key := range previous {
+		if jwtKey(keys, key.ID) == nil {
+			keys = append(keys, key)
+		}
+	}
+	jwtSettings.signing = current
+	jwtSettings.keys = keys
+	return nil
+}
+
+// JWTSigningKeys returns the key tokens are signed with and the number of
+// previous keys still accepted; the key is nil while tokens are signed with
+// the HS256 secret
+func JWTSigningKeys() (*SigningKey, int) {
+	if jwtSettings.signing == nil {
+		return nil, 0
+	}
+	return jwtSettings.signing, len(jwtSettings.keys) - 1
+}
+
+// JWKS returns the public keys tokens are verified with, the signing key
+// first. It is empty while tokens are signed with the HS256 secret.
+func JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, len(jwtSettings.keys))}
+	for i, key := range jwtSettings.keys {
+		set.Keys[i] = key.JWK()
+	}
+	return set
+}
+
+// jwtKey returns the key of keys with the ID, or nil
+func jwtKey(keys []*SigningKey, id string) *SigningKey {
+	for _, key := range keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// verificationKey is the jwt.Keyfunc for ValidateToken: the HS256 secret,
+// or the key named by the token's kid with the algorithm of that key
+func verificationKey(token *jwt.Token) (any, error) {
+	if jwtSettings.signing == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return GetJWTSecret(), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key := jwtKey(jwtSettings.keys, kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
@@ -0,0 +1,190 @@
+// internal/middleware/maintenance.go - Planned maintenance switch
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultMaintenanceMessage = "DigiOrder is undergoing scheduled maintenance. Please try again shortly."
+
+// MaintenanceStatus describes the current maintenance state
+type MaintenanceStatus struct {
+	Enabled    bool      `json:"enabled"`
+	Message    string    `json:"message"`
+	RetryAfter int       `json:"retry_after_seconds"`
+	Since      time.Time `json:"since,omitempty"`
+}
+
+// MaintenanceMode holds the runtime maintenance flag
+type MaintenanceMode struct {
+	mu         sync.RWMutex
+	enabled    bool
+	message    string
+	retryAfter time.Duration
+	since      time.Time
+}
+
+// NewMaintenanceMode creates the maintenance switch in the given state.
+// Empty message / zero retryAfter fall back to the defaults.
+func NewMaintenanceMode(enabled bool, message string, retryAfter time.Duration) *MaintenanceMode {
+	m := &MaintenanceMode{
+		message:    defaultMaintenanceMessage,
+		retryAfter: 5 * time.Minute,
+	}
+	if enabled {
+		m.Enable(message, retryAfter)
+	} else {
+		m.Configure(message, retryAfter)
+	}
+	return m
+}
+
+// NewMaintenanceModeFromEnv reads MAINTENANCE_MODE, MAINTENANCE_MESSAGE and
+// MAINTENANCE_RETRY_AFTER (duration, default 5m)
+func NewMaintenanceModeFromEnv() *MaintenanceMode {
+	m := &MaintenanceMode{
+		message:    getEnvString("MAINTENANCE_MESSAGE", defaultMaintenanceMessage),
+		retryAfter: 5 * time.Minute,
+	}
+
+	if v, err := time.ParseDuration(os.Getenv("MAINTENANCE_RETRY_AFTER")); err == nil && v > 0 {
+		m.retryAfter = v
+	}
+	if getEnvBool("MAINTENANCE_MODE", false) {
+		m.enabled = true
+		m.since = time.Now()
+	}
+
+	return m
+}
+
+// Enable turns maintenance mode on. Empty message / zero retryAfter keep
+// the current values.
+func (m *MaintenanceMode) Enable(message string, retryAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message != "" {
+		m.message = message
+	}
+	if retryAfter > 0 {
+		m.retryAfter = retryAfter
+	}
+	if !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = true
+}
+
+// Configure updates the message and Retry-After without changing the
+// enabled state. Empty message / zero retryAfter keep the current values.
+func (m *MaintenanceMode) Configure(message string, retryAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message != "" {
+		m.message = message
+	}
+	if retryAfter > 0 {
+		m.retryAfter = retryAfter
+	}
+}
+
+// Disable turns maintenance mode off
+func (m *MaintenanceMode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enabled = false
+	m.since = time.Time{}
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *MaintenanceMode) Enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled
+}
+
+// Status returns a snapshot of the maintenance state
+func (m *MaintenanceMode) Status() MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return MaintenanceStatus{
+		Enabled:    m.enabled,
+		Message:    m.message,
+		RetryAfter: int(m.retryAfter.Seconds()),
+		Since:      m.since,
+	}
+}
+
+// Respond writes the 503 maintenance response
+func (m *MaintenanceMode) Respond(c echo.Context) error {
+	status := m.Status()
+
+	c.Response().Header().Set("Retry-After", strconv.Itoa(status.RetryAfter))
+	return c.JSON(http.StatusServiceUnavailable, map[string]any{
+		"error":               "maintenance",
+		"details":             status.Message,
+		"retry_after_seconds": status.RetryAfter,
+		"maintenance_since":   status.Since.Format(time.RFC3339),
+	})
+}
+
+// Middleware rejects requests with 503 while maintenance mode is on.
+// Health checks, metrics, the JWKS, login, the web app and requests
+// carrying an admin token pass through so operators can still work on the system.
+func (m *MaintenanceMode) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !m.Enabled() || maintenanceExempt(c) {
+				return next(c)
+			}
+
+			return m.Respond(c)
+		}
+	}
+}
+
+// maintenanceExempt decides which requests bypass maintenance mode
+func maintenanceExempt(c echo.Context) bool {
+	path := c.Path()
+	if shouldSkipRateLimit(path) || strings.HasPrefix(path, "/health") {
+		return true
+	}
+
+	// Other services keep verifying tokens issued before maintenance
+	if path == JWKSPath {
+		return true
+	}
+
+	// The web app's pages and files (the catch-all route), so it loads and
+	// shows the notice its API calls get
+	if path == "/*" && !strings.HasPrefix(c.Request().URL.Path, "/api") {
+		return true
+	}
+
+	// The login handler enforces admin-only logins itself
+	if IsLoginPath(path) {
+		return true
+	}
+
+	tokenString, err := ExtractToken(c)
+	if err != nil {
+		return false
+	}
+	claims, err := ValidateToken(tokenString)
+	if err != nil {
+		return false
+	}
+
+	return claims.RoleName == "admin"
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -98,18 +99,27 @@ var (
 	)
 
 	// Cache metrics
-	cacheHitsTotal = promauto.NewCounter(
+	cacheHitsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "cache_hits_total",
 			Help: "Total number of cache hits",
 		},
+		[]string{"cache"},
 	)
 
-	cacheMissesTotal = promauto.NewCounter(
+	cacheMissesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "cache_misses_total",
 			Help: "Total number of cache misses",
 		},
+		[]string{"cache"},
+	)
+
+	cacheEvictionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_evictions_total",
+			Help: "Total number of cache entries evicted to stay within the cache limits",
+		},
 	)
 
 	cacheEntriesTotal = promauto.NewGauge(
@@ -119,6 +129,13 @@ var (
 		},
 	)
 
+	cacheSizeBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_size_bytes",
+			Help: "Approximate size of the cached responses in bytes",
+		},
+	)
+
 	// Rate limiting metrics
 	rateLimitExceeded = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -128,6 +145,31 @@ var (
 		[]string{"endpoint"},
 	)
 
+	rateLimiterClients = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rate_limiter_clients",
+			Help: "Number of clients with an in-memory rate limiter",
+		},
+		[]string{"limiter"},
+	)
+
+	rateLimiterEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limiter_evictions_total",
+			Help: "Total number of client rate limiters evicted to stay within the client limit",
+		},
+		[]string{"limiter"},
+	)
+
+	// Audit metrics
+	auditEntriesDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_entries_dropped_total",
+			Help: "Total number of audit entries not stored, by reason",
+		},
+		[]string{"reason"},
+	)
+
 	// Business metrics
 	ordersCreated = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -180,11 +222,6 @@ func PrometheusMiddleware() echo.MiddlewareFunc {
 				).Observe(float64(c.Request().ContentLength))
 			}
 
-			// Generate unique request ID
-			requestID := uuid.New().String()
-			c.Response().Header().Set("X-Request-ID", requestID)
-			c.Set("request_id", requestID)
-
 			// Call next handler
 			err := next(c)
 
@@ -235,14 +272,26 @@ func RecordAuthAttempt(success bool) {
 	authAttemptsTotal.WithLabelValues(status).Inc()
 }
 
-// RecordCacheHit records cache hit
-func RecordCacheHit() {
-	cacheHitsTotal.Inc()
+// Caches whose lookups are counted by RecordCacheHit and RecordCacheMiss
+const (
+	CacheResponses   = "responses"
+	CacheRoles       = "roles"
+	CachePermissions = "permissions"
+)
+
+// RecordCacheHit records a hit in the named cache
+func RecordCacheHit(cache string) {
+	cacheHitsTotal.WithLabelValues(cache).Inc()
+}
+
+// RecordCacheMiss records a miss in the named cache
+func RecordCacheMiss(cache string) {
+	cacheMissesTotal.WithLabelValues(cache).Inc()
 }
 
-// RecordCacheMiss records cache miss
-func RecordCacheMiss() {
-	cacheMissesTotal.Inc()
+// RecordCacheEviction records an entry evicted to make room
+func RecordCacheEviction() {
+	cacheEvictionsTotal.Inc()
 }
 
 // UpdateCacheSize updates cache size metric
@@ -250,11 +299,32 @@ func UpdateCacheSize(size int) {
 	cacheEntriesTotal.Set(float64(size))
 }
 
+// UpdateCacheBytes updates cache memory metric
+func UpdateCacheBytes(bytes int64) {
+	cacheSizeBytes.Set(float64(bytes))
+}
+
 // RecordRateLimitExceeded records rate limit exceeded
 func RecordRateLimitExceeded(endpoint string) {
 	rateLimitExceeded.WithLabelValues(endpoint).Inc()
 }
 
+// UpdateRateLimiterClients adds delta to the clients tracked by a limiter
+func UpdateRateLimiterClients(limiter string, delta int) {
+	rateLimiterClients.WithLabelValues(limiter).Add(float64(delta))
+}
+
+// RecordRateLimiterEviction records a client limiter evicted to make room
+func RecordRateLimiterEviction(limiter string) {
+	rateLimiterEvictions.WithLabelValues(limiter).Inc()
+}
+
+// RecordAuditEntryDropped records an audit entry that was not stored,
+// because the queue was full or the write failed
+func RecordAuditEntryDropped(reason string) {
+	auditEntriesDropped.WithLabelValues(reason).Inc()
+}
+
 // RecordOrderCreated records order creation
 func RecordOrderCreated(status string) {
 	ordersCreated.WithLabelValues(status).Inc()
@@ -303,6 +373,10 @@ func TracingMiddleware() echo.MiddlewareFunc {
 			c.Set("trace_id", traceID)
 			c.Set("span_id", spanID)
 
+			updateQueryTag(c, func(tag *db.QueryTag) {
+				tag.TraceID = traceID
+			})
+
 			// Continue
 			return next(c)
 		}
@@ -0,0 +1,63 @@
+// internal/middleware/permissions.go - Route access from the role_permissions table
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PermissionChecker reports whether a role is granted action on resource
+type PermissionChecker func(ctx context.Context, roleID int32, resource, action string) (bool, error)
+
+// permissionChecker is how RequirePermission looks up grants; nil denies
+// every request
+var permissionChecker PermissionChecker
+
+// ConfigurePermissions sets how RequirePermission looks up grants. The
+// server passes its cached lookup, so checks do not query the database on
+// every request.
+func ConfigurePermissions(check PermissionChecker) {
+	permissionChecker = check
+}
+
+// RequirePermission lets a request through when the role of its user is
+// granted action on resource in the permissions tables. The admin role
+// passes every check, so that revoking a permission cannot lock
+// administrators out of the routes that grant it back. Token scopes apply
+// on top, as for RequireRole.
+func RequirePermission(resource, action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			roleID, err := GetRoleIDFromContext(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, map[string]string{
+					"error":   "unauthorized",
+					"message": "Authentication required",
+				})
+			}
+			if roleName, _ := GetRoleNameFromContext(c); roleName == "admin" {
+				return next(c)
+			}
+
+			granted := false
+			if permissionChecker != nil {
+				granted, err = permissionChecker(c.Request().Context(), roleID, resource, action)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, map[string]string{
+						"error":   "internal_error",
+						"message": "Failed to check permissions.",
+					}).SetInternal(err)
+				}
+			}
+			if !granted {
+				return echo.NewHTTPError(http.StatusForbidden, map[string]string{
+					"error":   "insufficient_permissions",
+					"message": "You don't have permission to access this resource",
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// internal/middleware/query_tag.go - Correlate SQL statements with requests
+package middleware
+
+import (
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// QueryTagMiddleware attaches a db.QueryTag to the request context so every
+// statement issued through db.TaggedDB carries the request ID and route.
+// Must run after the request ID middleware.
+func QueryTagMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			tag := &db.QueryTag{
+				RequestID: GetRequestID(c),
+				Route:     req.Method + " " + c.Path(),
+			}
+			if tag.RequestID == "" {
+				tag.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+			}
+
+			c.SetRequest(req.WithContext(db.WithQueryTag(req.Context(), tag)))
+
+			return next(c)
+		}
+	}
+}
+
+// updateQueryTag lets later middleware add identifiers to the request's tag
+func updateQueryTag(c echo.Context, update func(tag *db.QueryTag)) {
+	if tag := db.QueryTagFromContext(c.Request().Context()); tag != nil {
+		update(tag)
+	}
+}
@@ -0,0 +1,53 @@
+// internal/middleware/rate_limit_headers.go - Rate limit state for clients
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+// Headers telling clients how much of their rate limit is left
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// setRateLimitHeaders describes l to the client after a request: the
+// limit is the burst, remaining the requests that may be made right away
+// and reset the seconds until the bucket is full again
+func setRateLimitHeaders(c echo.Context, l *rate.Limiter) {
+	burst := l.Burst()
+	tokens := math.Max(l.TokensAt(time.Now()), 0)
+	reset := 0
+	if limit := float64(l.Limit()); limit > 0 && tokens < float64(burst) {
+		reset = int(math.Ceil((float64(burst) - tokens) / limit))
+	}
+
+	h := c.Response().Header()
+	h.Set(HeaderRateLimitLimit, strconv.Itoa(burst))
+	h.Set(HeaderRateLimitRemaining, strconv.Itoa(int(tokens)))
+	h.Set(HeaderRateLimitReset, strconv.Itoa(reset))
+}
+
+// limiterRetryAfter is how long until l allows another request
+func limiterRetryAfter(l *rate.Limiter) time.Duration {
+	tokens := l.TokensAt(time.Now())
+	limit := float64(l.Limit())
+	if tokens >= 1 || limit <= 0 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / limit * float64(time.Second))
+}
+
+// setRetryAfter tells a rejected client to wait d, in whole seconds
+// rounded up and never less than one
+func setRetryAfter(c echo.Context, d time.Duration) {
+	seconds := max(int(math.Ceil(d.Seconds())), 1)
+	c.Response().Header().Set(HeaderRetryAfter, strconv.Itoa(seconds))
+}
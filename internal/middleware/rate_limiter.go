@@ -3,16 +3,15 @@ package middleware
 import (
 	"net/http"
 	"sync"
-	"time"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/time/rate"
 )
 
-// RateLimiter manages rate limiting for clients
+// RateLimiter manages rate limiting for clients, tracking at most
+// DefaultMaxRateLimitClients of them
 type RateLimiter struct {
-	visitors map[string]*rate.Limiter
-	mu       sync.RWMutex
+	visitors *limiterSet
 	rate     rate.Limit
 	burst    int
 }
@@ -20,7 +19,7 @@ type RateLimiter struct {
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
 	return &RateLimiter{
-		visitors: make(map[string]*rate.Limiter),
+		visitors: newLimiterSet("ip", DefaultMaxRateLimitClients),
 		rate:     r,
 		burst:    b,
 	}
@@ -28,41 +27,19 @@ func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
 
 // GetLimiter returns the rate limiter for a given IP
 func (rl *RateLimiter) GetLimiter(ip string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	limiter, exists := rl.visitors[ip]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.visitors[ip] = limiter
-	}
-
-	return limiter
+	return rl.visitors.get(ip, rl.rate, rl.burst)
 }
 
-// CleanupVisitors removes old visitors periodically
+// CleanupVisitors removes visitors whose bucket has refilled. GetLimiter
+// also does so every few minutes, so calling it is optional.
 func (rl *RateLimiter) CleanupVisitors() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		for ip, limiter := range rl.visitors {
-			if limiter.Tokens() == float64(rl.burst) {
-				delete(rl.visitors, ip)
-			}
-		}
-		rl.mu.Unlock()
-	}
+	rl.visitors.sweep()
 }
 
 // RateLimitMiddleware creates a rate limiting middleware
 func RateLimitMiddleware(requestsPerSecond int, burst int) echo.MiddlewareFunc {
 	limiter := NewRateLimiter(rate.Limit(requestsPerSecond), burst)
 
-	// Start cleanup goroutine
-	go limiter.CleanupVisitors()
-
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Get client IP
@@ -71,7 +48,10 @@ func RateLimitMiddleware(requestsPerSecond int, burst int) echo.MiddlewareFunc {
 			// Get limiter for this IP
 			l := limiter.GetLimiter(ip)
 
-			if !l.Allow() {
+			allowed := l.Allow()
+			setRateLimitHeaders(c, l)
+			if !allowed {
+				setRetryAfter(c, limiterRetryAfter(l))
 				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
 			}
 
@@ -80,9 +60,10 @@ func RateLimitMiddleware(requestsPerSecond int, burst int) echo.MiddlewareFunc {
 	}
 }
 
-// APIKeyRateLimiter manages rate limiting based on API keys
+// APIKeyRateLimiter manages rate limiting based on API keys, tracking at
+// most DefaultMaxRateLimitClients of them
 type APIKeyRateLimiter struct {
-	limiters map[string]*rate.Limiter
+	limiters *limiterSet
 	mu       sync.RWMutex
 	rate     rate.Limit
 	burst    int
@@ -91,7 +72,7 @@ type APIKeyRateLimiter struct {
 // NewAPIKeyRateLimiter creates a new API key-based rate limiter
 func NewAPIKeyRateLimiter(r rate.Limit, b int) *APIKeyRateLimiter {
 	return &APIKeyRateLimiter{
-		limiters: make(map[string]*rate.Limiter),
+		limiters: newLimiterSet("api_key", DefaultMaxRateLimitClients),
 		rate:     r,
 		burst:    b,
 	}
@@ -99,33 +80,42 @@ func NewAPIKeyRateLimiter(r rate.Limit, b int) *APIKeyRateLimiter {
 
 // GetLimiter returns the rate limiter for a given API key
 func (rl *APIKeyRateLimiter) GetLimiter(apiKey string) *rate.Limiter {
+	rl.mu.RLock()
+	r, b := rl.rate, rl.burst
+	rl.mu.RUnlock()
+	return rl.limiters.get(apiKey, r, b)
+}
+
+// UpdateRate applies a new per-key limit to new and tracked keys, as on a
+// configuration reload
+func (rl *APIKeyRateLimiter) UpdateRate(requestsPerMinute int) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	limiter, exists := rl.limiters[apiKey]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.limiters[apiKey] = limiter
-	}
-
-	return limiter
+	rl.rate = rate.Limit(float64(requestsPerMinute) / 60.0)
+	rl.burst = requestsPerMinute
+	rl.limiters.each(func(limiter *rate.Limiter) {
+		limiter.SetLimit(rl.rate)
+		limiter.SetBurst(rl.burst)
+	})
 }
 
-// APIKeyRateLimitMiddleware creates an API key-based rate limiting middleware
-func APIKeyRateLimitMiddleware(requestsPerMinute int) echo.MiddlewareFunc {
-	limiter := NewAPIKeyRateLimiter(rate.Limit(float64(requestsPerMinute)/60.0), requestsPerMinute)
-
+// Middleware limits the requests of each key in the X-API-Key header.
+// Requests without a key are left to the IP rate limiter.
+func (rl *APIKeyRateLimiter) Middleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Get API key from header or use IP as fallback
-			apiKey := c.Request().Header.Get("X-API-Key")
+			apiKey := c.Request().Header.Get(HeaderAPIKey)
 			if apiKey == "" {
-				apiKey = c.RealIP()
+				return next(c)
 			}
 
-			l := limiter.GetLimiter(apiKey)
+			l := rl.GetLimiter(apiKey)
 
-			if !l.Allow() {
+			allowed := l.Allow()
+			setRateLimitHeaders(c, l)
+			if !allowed {
+				setRetryAfter(c, limiterRetryAfter(l))
 				return echo.NewHTTPError(http.StatusTooManyRequests, "API rate limit exceeded")
 			}
 
@@ -133,3 +123,8 @@ func APIKeyRateLimitMiddleware(requestsPerMinute int) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// APIKeyRateLimitMiddleware creates an API key-based rate limiting middleware
+func APIKeyRateLimitMiddleware(requestsPerMinute int) echo.MiddlewareFunc {
+	return NewAPIKeyRateLimiter(rate.Limit(float64(requestsPerMinute)/60.0), requestsPerMinute).Middleware()
+}
@@ -4,8 +4,8 @@ package middleware
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net/http"
-	"sync"
 	"time"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
@@ -15,13 +15,13 @@ import (
 
 // PersistentRateLimiter tracks rate limits in database
 type PersistentRateLimiter struct {
-	queries       *db.Queries
-	inMemory      map[string]*rate.Limiter
-	mu            sync.RWMutex
+	queries       db.Querier
+	inMemory      *limiterSet
 	globalRate    rate.Limit
 	globalBurst   int
 	windowSize    time.Duration
 	cleanupTicker *time.Ticker
+	heartbeat     *Heartbeat
 }
 
 // RateLimitConfig holds configuration for rate limiting
@@ -32,6 +32,7 @@ type RateLimitConfig struct {
 	LoginMaxAttempts int           // Max login attempts per IP
 	LoginWindow      time.Duration // Time window for login attempts
 	WindowSize       time.Duration // Database record window
+	MaxClients       int           // Clients tracked in memory at once
 }
 
 // DefaultRateLimitConfig returns sensible defaults
@@ -43,18 +44,20 @@ func DefaultRateLimitConfig() RateLimitConfig {
 		LoginMaxAttempts: 5,
 		LoginWindow:      5 * time.Minute,
 		WindowSize:       1 * time.Minute,
+		MaxClients:       DefaultMaxRateLimitClients,
 	}
 }
 
 // NewPersistentRateLimiter creates a rate limiter with DB backing
-func NewPersistentRateLimiter(queries *db.Queries, config RateLimitConfig) *PersistentRateLimiter {
+func NewPersistentRateLimiter(queries db.Querier, config RateLimitConfig) *PersistentRateLimiter {
 	rl := &PersistentRateLimiter{
 		queries:       queries,
-		inMemory:      make(map[string]*rate.Limiter),
+		inMemory:      newLimiterSet("persistent", config.MaxClients),
 		globalRate:    rate.Limit(config.GlobalRPS),
 		globalBurst:   config.GlobalBurst,
 		windowSize:    config.WindowSize,
 		cleanupTicker: time.NewTicker(5 * time.Minute),
+		heartbeat:     NewHeartbeat("rate_limit_cleanup", 5*time.Minute),
 	}
 
 	// Start background cleanup
@@ -65,16 +68,7 @@ func NewPersistentRateLimiter(queries *db.Queries, config RateLimitConfig) *Pers
 
 // GetLimiter returns or creates a limiter for a client
 func (rl *PersistentRateLimiter) GetLimiter(clientID string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	limiter, exists := rl.inMemory[clientID]
-	if !exists {
-		limiter = rate.NewLimiter(rl.globalRate, rl.globalBurst)
-		rl.inMemory[clientID] = limiter
-	}
-
-	return limiter
+	return rl.inMemory.get(clientID, rl.globalRate, rl.globalBurst)
 }
 
 // RecordRequest records a rate limit entry in the database
@@ -114,7 +108,7 @@ func (rl *PersistentRateLimiter) CheckRateLimit(ctx context.Context, clientID, e
 	})
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			// First request in this window
 			return true, nil
 		}
@@ -124,6 +118,12 @@ func (rl *PersistentRateLimiter) CheckRateLimit(ctx context.Context, clientID, e
 	return limit.RequestsCount < maxRequests, nil
 }
 
+// windowRemaining is how long until the current database window ends
+func (rl *PersistentRateLimiter) windowRemaining() time.Duration {
+	now := time.Now()
+	return now.Truncate(rl.windowSize).Add(rl.windowSize).Sub(now)
+}
+
 // cleanup removes old rate limit records
 func (rl *PersistentRateLimiter) cleanup() {
 	for range rl.cleanupTicker.C {
@@ -134,23 +134,23 @@ func (rl *PersistentRateLimiter) cleanup() {
 		_ = rl.queries.DeleteOldRateLimits(ctx, cutoff)
 
 		// Clean in-memory
-		rl.mu.Lock()
-		for id, limiter := range rl.inMemory {
-			if limiter.Tokens() == float64(rl.globalBurst) {
-				delete(rl.inMemory, id)
-			}
-		}
-		rl.mu.Unlock()
+		rl.inMemory.sweep()
+		rl.heartbeat.Beat()
 	}
 }
 
+// Heartbeat reports whether the cleanup loop is running
+func (rl *PersistentRateLimiter) Heartbeat() *Heartbeat {
+	return rl.heartbeat
+}
+
 // Stop stops the cleanup goroutine
 func (rl *PersistentRateLimiter) Stop() {
 	rl.cleanupTicker.Stop()
 }
 
 // PersistentRateLimitMiddleware creates middleware with DB backing
-func PersistentRateLimitMiddleware(queries *db.Queries, config RateLimitConfig) echo.MiddlewareFunc {
+func PersistentRateLimitMiddleware(queries db.Querier, config RateLimitConfig) echo.MiddlewareFunc {
 	limiter := NewPersistentRateLimiter(queries, config)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -165,19 +165,22 @@ func PersistentRateLimitMiddleware(queries *db.Queries, config RateLimitConfig)
 
 			// Check in-memory rate limit first (fast path)
 			inMemLimiter := limiter.GetLimiter(clientID)
-			if !inMemLimiter.Allow() {
+			allowed := inMemLimiter.Allow()
+			setRateLimitHeaders(c, inMemLimiter)
+			if !allowed {
 				// Record the rate limit hit
 				limiter.RecordRequest(ctx, clientID, endpoint, false)
 
 				// Record metrics
 				RecordRateLimitExceeded(endpoint)
 
+				setRetryAfter(c, limiterRetryAfter(inMemLimiter))
 				return echo.NewHTTPError(http.StatusTooManyRequests,
 					"Rate limit exceeded. Please try again later.")
 			}
 
 			// For critical endpoints, also check DB
-			if endpoint == "/api/v1/auth/login" {
+			if IsLoginPath(endpoint) {
 				allowed, err := limiter.CheckRateLimit(ctx, clientID, endpoint,
 					int32(config.LoginMaxAttempts))
 				if err != nil {
@@ -185,6 +188,7 @@ func PersistentRateLimitMiddleware(queries *db.Queries, config RateLimitConfig)
 					c.Logger().Error("Rate limit DB check failed:", err)
 				} else if !allowed {
 					limiter.RecordRequest(ctx, clientID, endpoint, false)
+					setRetryAfter(c, limiter.windowRemaining())
 					return echo.NewHTTPError(http.StatusTooManyRequests,
 						"Too many login attempts. Please try again later.")
 				}
@@ -199,7 +203,7 @@ func PersistentRateLimitMiddleware(queries *db.Queries, config RateLimitConfig)
 }
 
 // LoginRateLimitMiddleware specifically for login endpoint
-func LoginRateLimitMiddleware(queries *db.Queries, maxAttempts int, window time.Duration) echo.MiddlewareFunc {
+func LoginRateLimitMiddleware(queries db.Querier, maxAttempts int, window time.Duration) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			clientIP := c.RealIP()
@@ -217,6 +221,7 @@ func LoginRateLimitMiddleware(queries *db.Queries, maxAttempts int, window time.
 				// Allow request on error to prevent DOS via DB errors
 			} else if count >= int64(maxAttempts) {
 				RecordRateLimitExceeded("/api/v1/auth/login")
+				setRetryAfter(c, window)
 				return echo.NewHTTPError(http.StatusTooManyRequests,
 					"Too many login attempts. Please try again in 5 minutes.")
 			}
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/geoip"
 	"github.com/labstack/echo/v4"
-	"github.com/sqlc-dev/pqtype"
 	"golang.org/x/time/rate"
 )
 
@@ -25,18 +25,22 @@ type BannedIP struct {
 
 // IPBanManager manages temporarily banned IPs
 type IPBanManager struct {
-	bans    map[string]*BannedIP
-	mu      sync.RWMutex
-	queries *db.Queries
-	ticker  *time.Ticker
+	bans      map[string]*BannedIP
+	mu        sync.RWMutex
+	queries   db.Querier
+	ticker    *time.Ticker
+	heartbeat *Heartbeat
+	onBan     func(ip, reason string, duration time.Duration, attempts int)
+	geo       *geoip.Resolver
 }
 
 // NewIPBanManager creates a new IP ban manager with auto-cleanup
-func NewIPBanManager(queries *db.Queries) *IPBanManager {
+func NewIPBanManager(queries db.Querier) *IPBanManager {
 	manager := &IPBanManager{
-		bans:    make(map[string]*BannedIP),
-		queries: queries,
-		ticker:  time.NewTicker(30 * time.Second), // Check every 30 seconds
+		bans:      make(map[string]*BannedIP),
+		queries:   queries,
+		ticker:    time.NewTicker(30 * time.Second), // Check every 30 seconds
+		heartbeat: NewHeartbeat("ip_ban_cleanup", 30*time.Second),
 	}
 
 	// Start cleanup goroutine
@@ -80,12 +84,21 @@ func (m *IPBanManager) BanIP(ip, reason string, duration time.Duration, attempts
 		Attempts:    attempts,
 	}
 
+	if m.onBan != nil {
+		go m.onBan(ip, reason, duration, attempts)
+	}
+
 	// Log to database for persistence
 	if m.queries != nil {
+		geo := m.geo
 		go func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
+			// Other nodes, and this one after a restart, keep the ban
+			_ = persistBan(ctx, m.queries, ip, reason, bannedUntil)
+
+			loc := geo.Lookup(ip)
 			_, err := m.queries.LogLoginAttempt(ctx, db.LogLoginAttemptParams{
 				Username:      "system",
 				IpAddress:     ip,
@@ -94,9 +107,10 @@ func (m *IPBanManager) BanIP(ip, reason string, duration time.Duration, attempts
 				FailureReason: sql.NullString{String: reason, Valid: true},
 				RateLimited:   sql.NullBool{Bool: true, Valid: true},
 				SessionID:     sql.NullString{String: "ban_" + time.Now().Format("20060102150405"), Valid: true},
-				DeviceInfo: pqtype.NullRawMessage{
-					Valid: true,
-				},
+				Country:       sql.NullString{String: loc.Country, Valid: loc.Country != ""},
+				City:          sql.NullString{String: loc.City, Valid: loc.City != ""},
+				Asn:           sql.NullInt64{Int64: int64(loc.ASN), Valid: loc.ASN != 0},
+				AsOrg:         sql.NullString{String: loc.ASOrg, Valid: loc.ASOrg != ""},
 			})
 			if err != nil {
 				// Log error but don't fail
@@ -153,56 +167,190 @@ func (m *IPBanManager) cleanupExpiredBans() {
 		}
 
 		m.mu.Unlock()
+		m.heartbeat.Beat()
 	}
 }
 
+// OnBan registers a callback invoked (asynchronously) for every new ban,
+// e.g. to alert administrators
+func (m *IPBanManager) OnBan(fn func(ip, reason string, duration time.Duration, attempts int)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onBan = fn
+}
+
+// SetGeoIP places the bans logged from now on with geo
+func (m *IPBanManager) SetGeoIP(geo *geoip.Resolver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.geo = geo
+}
+
+// geoIP returns the resolver set by SetGeoIP
+func (m *IPBanManager) geoIP() *geoip.Resolver {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.geo
+}
+
+// Heartbeat reports whether the ban cleanup loop is running
+func (m *IPBanManager) Heartbeat() *Heartbeat {
+	return m.heartbeat
+}
+
 // Stop stops the cleanup goroutine
 func (m *IPBanManager) Stop() {
 	m.ticker.Stop()
 }
 
-// EnhancedRateLimiter with IP ban tracking
+// EnhancedRateLimiter with IP ban tracking. At most config.MaxClients IPs
+// are tracked at once.
 type EnhancedRateLimiter struct {
-	limiters    map[string]*rate.Limiter
-	mu          sync.RWMutex
-	globalRate  rate.Limit
-	globalBurst int
-	queries     *db.Queries
-	banManager  *IPBanManager
+	limiters         *limiterSet
+	mu               sync.RWMutex
+	globalRate       rate.Limit
+	globalBurst      int
+	loginMaxAttempts int
+	loginWindow      time.Duration
+	queries          db.Querier
+	banManager       *IPBanManager
+	rules            *IPRules
 }
 
 // NewEnhancedRateLimiter creates a production-ready rate limiter
-func NewEnhancedRateLimiter(queries *db.Queries, globalRPS, burst int) *EnhancedRateLimiter {
+func NewEnhancedRateLimiter(queries db.Querier, globalRPS, burst int) *EnhancedRateLimiter {
+	config := DefaultRateLimitConfig()
+	config.GlobalRPS = globalRPS
+	config.GlobalBurst = burst
+	return NewEnhancedRateLimiterWithConfig(queries, config)
+}
+
+// NewEnhancedRateLimiterWithConfig creates a rate limiter whose global and
+// login limits come from config
+func NewEnhancedRateLimiterWithConfig(queries db.Querier, config RateLimitConfig) *EnhancedRateLimiter {
 	return &EnhancedRateLimiter{
-		limiters:    make(map[string]*rate.Limiter),
-		globalRate:  rate.Limit(globalRPS),
-		globalBurst: burst,
-		queries:     queries,
-		banManager:  NewIPBanManager(queries),
+		limiters:         newLimiterSet("global", config.MaxClients),
+		globalRate:       rate.Limit(config.GlobalRPS),
+		globalBurst:      config.GlobalBurst,
+		loginMaxAttempts: config.LoginMaxAttempts,
+		loginWindow:      config.LoginWindow,
+		queries:          queries,
+		banManager:       NewIPBanManager(queries),
+		rules:            NewIPRules(queries),
 	}
 }
 
-// GetLimiter returns or creates a limiter for an IP
-func (rl *EnhancedRateLimiter) GetLimiter(ip string) *rate.Limiter {
+// OnBan registers a callback invoked for every IP this limiter bans
+func (rl *EnhancedRateLimiter) OnBan(fn func(ip, reason string, duration time.Duration, attempts int)) {
+	rl.banManager.OnBan(fn)
+}
+
+// SetGeoIP places the addresses this limiter bans, in its log and in
+// GetBannedIPsHandler
+func (rl *EnhancedRateLimiter) SetGeoIP(geo *geoip.Resolver) {
+	rl.banManager.SetGeoIP(geo)
+}
+
+// BannedIPs returns the bans that have not expired yet
+func (rl *EnhancedRateLimiter) BannedIPs() []BannedIP {
+	return rl.banManager.GetBannedIPs()
+}
+
+// Heartbeat reports whether the limiter's ban cleanup loop is running
+func (rl *EnhancedRateLimiter) Heartbeat() *Heartbeat {
+	return rl.banManager.Heartbeat()
+}
+
+// IPRules returns the allow and deny rules the limiter enforces
+func (rl *EnhancedRateLimiter) IPRules() *IPRules {
+	return rl.rules
+}
+
+// UnbanIP lifts the ban on ip here and, through the ban kept in ip_rules,
+// on every node
+func (rl *EnhancedRateLimiter) UnbanIP(ctx context.Context, ip string) error {
+	rl.banManager.UnbanIP(ip)
+	if rl.queries == nil {
+		return nil
+	}
+	if cidr, ok := hostCIDR(ip); ok {
+		if _, err := rl.queries.DeleteIPBans(ctx, cidr); err != nil {
+			return err
+		}
+	}
+	return rl.rules.Reload(ctx)
+}
+
+// UpdateLimits applies new global and login limits. Existing per-IP
+// limiters are adjusted in place so clients keep their current tokens.
+func (rl *EnhancedRateLimiter) UpdateLimits(config RateLimitConfig) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	limiter, exists := rl.limiters[ip]
-	if !exists {
-		limiter = rate.NewLimiter(rl.globalRate, rl.globalBurst)
-		rl.limiters[ip] = limiter
-	}
+	rl.globalRate = rate.Limit(config.GlobalRPS)
+	rl.globalBurst = config.GlobalBurst
+	rl.loginMaxAttempts = config.LoginMaxAttempts
+	rl.loginWindow = config.LoginWindow
+
+	rl.limiters.setMax(config.MaxClients)
+	rl.limiters.each(func(limiter *rate.Limiter) {
+		limiter.SetLimit(rl.globalRate)
+		limiter.SetBurst(rl.globalBurst)
+	})
+}
+
+// loginPolicy returns the current login attempt limit and window
+func (rl *EnhancedRateLimiter) loginPolicy() (int, time.Duration) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.loginMaxAttempts, rl.loginWindow
+}
 
-	return limiter
+// GetLimiter returns or creates a limiter for an IP
+func (rl *EnhancedRateLimiter) GetLimiter(ip string) *rate.Limiter {
+	rl.mu.RLock()
+	r, b := rl.globalRate, rl.globalBurst
+	rl.mu.RUnlock()
+	return rl.limiters.get(ip, r, b)
 }
 
 // CheckRateLimit checks both in-memory and database rate limits
 func (rl *EnhancedRateLimiter) CheckRateLimit(c echo.Context, endpoint string) error {
 	clientIP := c.RealIP()
 
+	// Allowed addresses are never limited; denied ones are refused, for
+	// good or, when the rule expires, as banned until it does
+	if rule, ok := rl.rules.Match(clientIP); ok {
+		if rule.Action == IPRuleAllow {
+			return nil
+		}
+		RecordRateLimitExceeded(endpoint)
+		if rule.Expires.IsZero() {
+			return echo.NewHTTPError(http.StatusForbidden, map[string]any{
+				"error":   "ip_denied",
+				"message": "Requests from your IP address are not allowed",
+			})
+		}
+		remaining := time.Until(rule.Expires)
+		setRetryAfter(c, remaining)
+		return echo.NewHTTPError(http.StatusTooManyRequests, map[string]any{
+			"error":   "ip_temporarily_banned",
+			"message": "Your IP has been temporarily banned",
+			"details": map[string]any{
+				"banned_until": rule.Expires.Format(time.RFC3339),
+				"time_remaining": map[string]int{
+					"minutes": int(remaining.Minutes()),
+					"seconds": int(remaining.Seconds()) % 60,
+				},
+				"reason": rule.Reason,
+			},
+		})
+	}
+
 	// Check if IP is banned first
 	if banned, remaining := rl.banManager.IsBanned(clientIP); banned {
 		RecordRateLimitExceeded(endpoint)
+		setRetryAfter(c, remaining)
 
 		minutes := int(remaining.Minutes())
 		seconds := int(remaining.Seconds()) % 60
@@ -223,23 +371,28 @@ func (rl *EnhancedRateLimiter) CheckRateLimit(c echo.Context, endpoint string) e
 
 	// Check in-memory rate limit
 	limiter := rl.GetLimiter(clientIP)
-	if !limiter.Allow() {
+	allowed := limiter.Allow()
+	setRateLimitHeaders(c, limiter)
+	if !allowed {
 		RecordRateLimitExceeded(endpoint)
+		setRetryAfter(c, limiterRetryAfter(limiter))
 
 		// Check if this is a login endpoint - stricter enforcement
-		if endpoint == "/api/v1/auth/login" {
-			// Check failed attempts in last 5 minutes
+		if IsLoginPath(endpoint) {
+			// Check failed attempts in the login window
 			ctx := c.Request().Context()
-			windowStart := time.Now().Add(-5 * time.Minute)
+			maxAttempts, window := rl.loginPolicy()
+			windowStart := time.Now().Add(-window)
 
 			count, err := rl.queries.CountFailedAttempts(ctx, db.CountFailedAttemptsParams{
 				IpAddress: clientIP,
 				Since:     sql.NullTime{Time: windowStart, Valid: true},
 			})
 
-			if err == nil && count >= 5 {
+			if err == nil && count >= int64(maxAttempts) {
 				// Ban for 5 minutes
 				rl.banManager.BanIP(clientIP, "too_many_failed_logins", 5*time.Minute, int(count))
+				setRetryAfter(c, 5*time.Minute)
 
 				return echo.NewHTTPError(http.StatusTooManyRequests, map[string]any{
 					"error":   "ip_banned",
@@ -261,9 +414,14 @@ func (rl *EnhancedRateLimiter) CheckRateLimit(c echo.Context, endpoint string) e
 }
 
 // ProductionRateLimitMiddleware - Enhanced rate limiting with bans
-func ProductionRateLimitMiddleware(queries *db.Queries) echo.MiddlewareFunc {
-	limiter := NewEnhancedRateLimiter(queries, 100, 200) // 100 req/sec, burst 200
+func ProductionRateLimitMiddleware(queries db.Querier) echo.MiddlewareFunc {
+	return NewEnhancedRateLimiter(queries, 100, 200).Middleware() // 100 req/sec, burst 200
+}
 
+// Middleware enforces this limiter on every request. Sharing one limiter
+// between the middleware and the ban management handlers keeps unban
+// requests effective.
+func (limiter *EnhancedRateLimiter) Middleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			endpoint := c.Path()
@@ -287,6 +445,8 @@ func ProductionRateLimitMiddleware(queries *db.Queries) echo.MiddlewareFunc {
 func shouldSkipRateLimit(endpoint string) bool {
 	skipEndpoints := []string{
 		"/health",
+		"/livez",
+		"/readyz",
 		"/metrics",
 		"/api/health",
 		"/api/metrics",
@@ -305,11 +465,13 @@ func shouldSkipRateLimit(endpoint string) bool {
 func GetBannedIPsHandler(limiter *EnhancedRateLimiter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		banned := limiter.banManager.GetBannedIPs()
+		geo := limiter.banManager.geoIP()
 
 		result := make([]map[string]any, len(banned))
 		now := time.Now()
 
-		for i, ban := range banned {
+		for i := range banned {
+			ban := &banned[i]
 			remaining := ban.BannedUntil.Sub(now)
 			result[i] = map[string]any{
 				"ip":              ban.IP,
@@ -321,6 +483,9 @@ func GetBannedIPsHandler(limiter *EnhancedRateLimiter) echo.HandlerFunc {
 					"seconds": int(remaining.Seconds()) % 60,
 				},
 			}
+			if loc := geo.Lookup(ban.IP); loc.Known() {
+				result[i]["location"] = loc
+			}
 		}
 
 		return c.JSON(http.StatusOK, map[string]any{
@@ -343,7 +508,11 @@ func UnbanIPHandler(limiter *EnhancedRateLimiter) echo.HandlerFunc {
 			})
 		}
 
-		limiter.banManager.UnbanIP(req.IPAddress)
+		if err := limiter.UnbanIP(c.Request().Context(), req.IPAddress); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to lift the ban",
+			})
+		}
 
 		return c.JSON(http.StatusOK, map[string]string{
 			"message": "IP successfully unbanned",
@@ -0,0 +1,123 @@
+// internal/middleware/rate_limiter_set.go - Bounded per-client limiters
+package middleware
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxRateLimitClients bounds the clients a limiter tracks when not
+// configured otherwise
+const DefaultMaxRateLimitClients = 100000
+
+// limiterSweepInterval is how often idle limiters are dropped
+const limiterSweepInterval = 5 * time.Minute
+
+// limiterSet holds a token bucket per client, at most max of them. Buckets
+// that have refilled are dropped every sweep interval, as a new one would
+// be no different; when the set is full, the least recently used bucket
+// makes room for a new client.
+type limiterSet struct {
+	name string // rate_limiter_clients label
+
+	mu        sync.Mutex
+	max       int
+	order     *list.List // of *limiterItem, most recently used first
+	items     map[string]*list.Element
+	lastSweep time.Time
+}
+
+type limiterItem struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+func newLimiterSet(name string, max int) *limiterSet {
+	if max <= 0 {
+		max = DefaultMaxRateLimitClients
+	}
+	return &limiterSet{
+		name:      name,
+		max:       max,
+		order:     list.New(),
+		items:     make(map[string]*list.Element),
+		lastSweep: time.Now(),
+	}
+}
+
+// get returns the limiter of key, creating one allowing r with burst b
+func (s *limiterSet) get(key string, r rate.Limit, b int) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastSweep) >= limiterSweepInterval {
+		s.sweepLocked()
+	}
+	if el, exists := s.items[key]; exists {
+		s.order.MoveToFront(el)
+		return el.Value.(*limiterItem).limiter
+	}
+
+	for s.order.Len() >= s.max {
+		s.remove(s.order.Back())
+		RecordRateLimiterEviction(s.name)
+	}
+	limiter := rate.NewLimiter(r, b)
+	s.items[key] = s.order.PushFront(&limiterItem{key: key, limiter: limiter})
+	UpdateRateLimiterClients(s.name, 1)
+	return limiter
+}
+
+// each calls fn with every limiter in the set
+func (s *limiterSet) each(fn func(*rate.Limiter)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		fn(el.Value.(*limiterItem).limiter)
+	}
+}
+
+// setMax changes the bound, evicting the least recently used limiters
+// beyond it
+func (s *limiterSet) setMax(max int) {
+	if max <= 0 {
+		max = DefaultMaxRateLimitClients
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.max = max
+	for s.order.Len() > s.max {
+		s.remove(s.order.Back())
+		RecordRateLimiterEviction(s.name)
+	}
+}
+
+// sweep drops the limiters whose bucket is full again
+func (s *limiterSet) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+}
+
+func (s *limiterSet) sweepLocked() {
+	for el := s.order.Back(); el != nil; {
+		prev := el.Prev()
+		if l := el.Value.(*limiterItem).limiter; l.Tokens() >= float64(l.Burst()) {
+			s.remove(el)
+		}
+		el = prev
+	}
+	s.lastSweep = time.Now()
+}
+
+// remove drops the limiter at el; s.mu must be held
+func (s *limiterSet) remove(el *list.Element) {
+	item := s.order.Remove(el).(*limiterItem)
+	delete(s.items, item.key)
+	UpdateRateLimiterClients(s.name, -1)
+}
@@ -0,0 +1,91 @@
+// internal/middleware/strict_json.go - Rejecting unknown JSON fields
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// UnknownFieldError is returned by StrictBinder for a JSON body holding a
+// field the request type does not have
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return "unknown field " + strconv.Quote(e.Field)
+}
+
+// StrictBinder binds like echo's default binder, except that in strict
+// mode a JSON body may only hold the fields of the type it is bound to,
+// so a misspelt field is reported instead of silently left out. Requests
+// are strict from API version 2, and on version 1 routes using StrictJSON.
+type StrictBinder struct {
+	echo.DefaultBinder
+}
+
+// Bind binds path parameters, query parameters of GET, DELETE and HEAD
+// requests, and the body
+func (b *StrictBinder) Bind(i any, c echo.Context) error {
+	req := c.Request()
+	if !IsStrictJSON(c) || !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return b.DefaultBinder.Bind(i, c)
+	}
+
+	if err := b.BindPathParams(c, i); err != nil {
+		return err
+	}
+	method := req.Method
+	if method == http.MethodGet || method == http.MethodDelete || method == http.MethodHead {
+		if err := b.BindQueryParams(c, i); err != nil {
+			return err
+		}
+	}
+	if req.ContentLength == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(i); err != nil {
+		// encoding/json has no error type for unknown fields
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				field = unquoted
+			}
+			return &UnknownFieldError{Field: field}
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+// StrictJSON makes a version 1 route bind its body strictly, as every
+// route does from version 2
+func StrictJSON() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("strict_json", true)
+			return next(c)
+		}
+	}
+}
+
+// IsStrictJSON reports whether the request's JSON body must not hold
+// unknown fields
+func IsStrictJSON(c echo.Context) bool {
+	strict, _ := c.Get("strict_json").(bool)
+	return strict || GetAPIVersion(c) >= 2
+}
+
+// AsUnknownFieldError returns the unknown field error in err's chain
+func AsUnknownFieldError(err error) (*UnknownFieldError, bool) {
+	var unknown *UnknownFieldError
+	ok := errors.As(err, &unknown)
+	return unknown, ok
+}
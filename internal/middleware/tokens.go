@@ -0,0 +1,276 @@
+// internal/middleware/tokens.go - Personal access tokens, API keys and their scopes
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// PATPrefix starts every personal access token, telling it apart from a JWT
+const PATPrefix = "dgo_pat_"
+
+// APIKeyPrefix starts every API key
+const APIKeyPrefix = "dgo_key_"
+
+// HeaderAPIKey carries the API key of machine integrations
+const HeaderAPIKey = "X-API-Key"
+
+// Token scopes. A write scope includes the read scope of its area; read
+// scopes cover GET and HEAD requests.
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeOrdersRead    = "orders:read"
+	ScopeOrdersWrite   = "orders:write"
+	ScopeExportsRead   = "exports:read"
+	ScopeExportsWrite  = "exports:write"
+	ScopeAdminRead     = "admin:read"
+	ScopeAdminWrite    = "admin:write"
+)
+
+// Scopes lists the scopes a token can be given
+var Scopes = []string{
+	ScopeProductsRead, ScopeProductsWrite,
+	ScopeOrdersRead, ScopeOrdersWrite,
+	ScopeExportsRead, ScopeExportsWrite,
+	ScopeAdminRead, ScopeAdminWrite,
+}
+
+// scopeAreas maps the first segment of an API path to the area whose
+// scopes cover it. Paths not listed need the admin scopes.
+var scopeAreas = map[string]string{
+	"products":         "products",
+	"barcodes":         "products",
+	"categories":       "products",
+	"dosage_forms":     "products",
+	"drug-registry":    "products",
+	"printers":         "products",
+	"orders":           "orders",
+	"order_items":      "orders",
+	"recurring-orders": "orders",
+	"exports":          "exports",
+	"erp":              "exports",
+	"fhir":             "exports",
+	"report-schedules": "exports",
+	"reports":          "exports",
+}
+
+// permissionScopes maps a permission ("resource:action") to the scope a
+// token needs to make any request the permission allows: the least scope
+// of the routes it guards. Unlisted permissions need the scope of the
+// area named like their resource, as a path would, reading for the read
+// actions and writing otherwise. Keep in step with the routes.
+var permissionScopes = map[string]string{
+	"barcodes:delete":               ScopeProductsWrite,
+	"barcodes:manage":               ScopeProductsWrite,
+	"catalog:manage":                ScopeProductsWrite,
+	"controlled_substances:approve": ScopeOrdersWrite,
+	"controlled_substances:order":   ScopeOrdersWrite,
+	"controlled_substances:read":    ScopeExportsRead,
+	"drug_registry:read":            ScopeProductsRead,
+	"drug_registry:sync":            ScopeProductsWrite,
+	"erp:manage":                    ScopeExportsRead,
+	"exports:manage":                ScopeExportsRead,
+	"permissions:manage":            ScopeAdminRead,
+	"recurring_orders:manage":       ScopeOrdersWrite,
+	"report_schedules:manage":       ScopeExportsRead,
+	"reports:manage":                ScopeExportsRead,
+	"reports:read":                  ScopeExportsRead,
+	"roles:manage":                  ScopeAdminRead,
+	"security:manage":               ScopeAdminRead,
+	"stock:forecast":                ScopeProductsRead,
+	"stock:update":                  ScopeProductsWrite,
+	"suppliers:assign":              ScopeOrdersWrite,
+	"system:manage":                 ScopeAdminRead,
+	"tenants:manage":                ScopeAdminRead,
+}
+
+// tokenAuthPaths are the /auth endpoints a token may read with any scope.
+// The others, such as password changes and token management, need a login.
+var tokenAuthPaths = []string{"/auth/profile", "/auth/check-permission", "/auth/permissions"}
+
+// loginPaths are endpoints outside /auth that need a login, so that a
+// token or key cannot create further keys or tokens
+var loginPaths = []string{"/admin/api-keys", "/admin/service-accounts"}
+
+// TokenPrincipal is the user behind a personal access token, with the
+// user's current role, or the creator of an API key with the key's role
+// and department. Exactly one of TokenID and APIKeyID is set.
+type TokenPrincipal struct {
+	TokenID      uuid.UUID
+	APIKeyID     uuid.UUID
+	UserID       uuid.UUID
+	Username     string
+	RoleID       int32
+	RoleName     string
+	TenantID     uuid.UUID
+	DepartmentID int32
+	Language     string
+	Scopes       []string
+}
+
+// TokenResolver looks up a personal access token or API key. It returns
+// ErrInvalidToken for unknown or revoked ones and ErrExpiredToken for
+// expired ones.
+type TokenResolver func(c echo.Context, token string) (*TokenPrincipal, error)
+
+// ValidScope reports whether scope is one of Scopes
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// HasScope reports whether scopes grant want
+func HasScope(scopes []string, want string) bool {
+	if slices.Contains(scopes, want) {
+		return true
+	}
+	area, access, _ := strings.Cut(want, ":")
+	return access == "read" && slices.Contains(scopes, area+":write")
+}
+
+// PermissionScope returns the scope a token needs to use the permission
+// to do action on resource (see permissionScopes)
+func PermissionScope(resource, action string) string {
+	if scope, ok := permissionScopes[resource+":"+action]; ok {
+		return scope
+	}
+	area, found := scopeAreas[resource]
+	if !found {
+		area = "admin"
+	}
+	if action == "read" || action == "read_all" {
+		return area + ":read"
+	}
+	return area + ":write"
+}
+
+// GetScopesFromContext returns the scopes of the token or API key the
+// request was made with; ok is false for a login, which is not limited by
+// scopes
+func GetScopesFromContext(c echo.Context) (scopes []string, ok bool) {
+	scopes, ok = c.Get("scopes").([]string)
+	return scopes, ok
+}
+
+// RequiredScope returns the scope a request to the route path needs. An
+// empty scope means any token may make the request; ok is false when no
+// token may.
+func RequiredScope(method, path string) (scope string, ok bool) {
+	// Drop the /api/vN prefix
+	if rest, found := strings.CutPrefix(path, "/api/"); found {
+		_, rest, _ = strings.Cut(rest, "/")
+		path = "/" + rest
+	}
+	read := method == http.MethodGet || method == http.MethodHead
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment == "auth" {
+		return "", read && slices.Contains(tokenAuthPaths, path)
+	}
+
+	for _, prefix := range loginPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "", false
+		}
+	}
+
+	area, found := scopeAreas[segment]
+	if !found {
+		area = "admin"
+	}
+	if read {
+		return area + ":read", true
+	}
+	return area + ":write", true
+}
+
+// AuthMiddleware authenticates requests with a JWT, a personal access
+// token or an API key in the X-API-Key header. Tokens act as their user and
+// keys as their creator with the key's role, both limited to their scopes;
+// role checks such as RequireRole still apply on top.
+func AuthMiddleware(resolveToken, resolveKey TokenResolver) echo.MiddlewareFunc {
+	jwtAuth := JWTMiddleware()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJWT := jwtAuth(next)
+		return func(c echo.Context) error {
+			if key := c.Request().Header.Get(HeaderAPIKey); key != "" {
+				principal, err := resolveKey(c, key)
+				if err != nil {
+					message := "Invalid or revoked API key."
+					if errors.Is(err, ErrExpiredToken) {
+						message = "API key has expired. Ask an administrator for a new one."
+					}
+					return echo.NewHTTPError(http.StatusUnauthorized, map[string]string{
+						"error":   "invalid_token",
+						"message": message,
+					})
+				}
+				return authenticatePrincipal(c, next, principal, "API keys", "API key")
+			}
+
+			tokenString, err := ExtractToken(c)
+			if err != nil || !strings.HasPrefix(tokenString, PATPrefix) {
+				return withJWT(c)
+			}
+
+			principal, err := resolveToken(c, tokenString)
+			if err != nil {
+				message := "Invalid or revoked access token."
+				if errors.Is(err, ErrExpiredToken) {
+					message = "Access token has expired. Create a new one."
+				}
+				return echo.NewHTTPError(http.StatusUnauthorized, map[string]string{
+					"error":   "invalid_token",
+					"message": message,
+				})
+			}
+			return authenticatePrincipal(c, next, principal, "Access tokens", "access token")
+		}
+	}
+}
+
+// authenticatePrincipal checks the scopes of a resolved token or key
+// against the route and, when they cover it, continues as its principal.
+// plural and singular name the kind of credential in error messages.
+func authenticatePrincipal(c echo.Context, next echo.HandlerFunc, principal *TokenPrincipal, plural, singular string) error {
+	scope, ok := RequiredScope(c.Request().Method, c.Path())
+	if !ok {
+		return echo.NewHTTPError(http.StatusForbidden, map[string]string{
+			"error":   "token_not_allowed",
+			"message": plural + " cannot call this endpoint. Sign in with your password.",
+		})
+	}
+	if scope != "" && !HasScope(principal.Scopes, scope) {
+		return echo.NewHTTPError(http.StatusForbidden, map[string]string{
+			"error":   "insufficient_scope",
+			"message": fmt.Sprintf("This %s needs the %s scope.", singular, scope),
+		})
+	}
+
+	c.Set("user_id", principal.UserID)
+	c.Set("username", principal.Username)
+	c.Set("role_id", principal.RoleID)
+	c.Set("role_name", principal.RoleName)
+	if principal.APIKeyID != uuid.Nil {
+		c.Set("api_key_id", principal.APIKeyID)
+	} else {
+		c.Set("token_id", principal.TokenID)
+	}
+	c.Set("tenant_id", principal.TenantID)
+	c.Set("department_id", principal.DepartmentID)
+	c.Set("language", principal.Language)
+	c.Set("scopes", principal.Scopes)
+
+	updateQueryTag(c, func(tag *db.QueryTag) {
+		tag.UserID = principal.UserID.String()
+	})
+
+	return next(c)
+}
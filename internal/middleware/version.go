@@ -0,0 +1,144 @@
+// internal/middleware/version.go - API version resolution
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultAPIVersion is used for unversioned /api/... requests without
+	// an Accept-Version header, so existing kiosk clients keep v1 behaviour
+	DefaultAPIVersion = 1
+
+	// LatestAPIVersion is the newest version served
+	LatestAPIVersion = 2
+
+	// HeaderAcceptVersion is the request header used for version negotiation
+	HeaderAcceptVersion = "Accept-Version"
+
+	// HeaderAPIVersion reports the version that served the response
+	HeaderAPIVersion = "API-Version"
+)
+
+var (
+	versionedPathPattern = regexp.MustCompile(`^/api/v(\d+)(/|$)`)
+	vendorMediaPattern   = regexp.MustCompile(`application/vnd\.digiorder\.v(\d+)\+json`)
+)
+
+// SupportedAPIVersion reports whether version is served by this build
+func SupportedAPIVersion(version int) bool {
+	return version >= 1 && version <= LatestAPIVersion
+}
+
+// parseVersion accepts "2", "v2" and "2.0"
+func parseVersion(value string) (int, bool) {
+	value = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "v")
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// negotiateVersion resolves the requested version from the Accept-Version
+// header or a vendor media type in Accept
+func negotiateVersion(req *http.Request) (int, bool, error) {
+	if header := req.Header.Get(HeaderAcceptVersion); header != "" {
+		version, ok := parseVersion(header)
+		if !ok {
+			return 0, false, fmt.Errorf("invalid %s header %q", HeaderAcceptVersion, header)
+		}
+		return version, true, nil
+	}
+
+	if m := vendorMediaPattern.FindStringSubmatch(req.Header.Get(echo.HeaderAccept)); m != nil {
+		version, _ := strconv.Atoi(m[1])
+		return version, true, nil
+	}
+
+	return DefaultAPIVersion, false, nil
+}
+
+// APIVersionRewrite is a Pre middleware that maps unversioned /api/...
+// paths onto /api/vN/... using Accept-Version (or an
+// application/vnd.digiorder.vN+json Accept header). Explicit /api/vN paths
+// always win over headers.
+func APIVersionRewrite() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			path := req.URL.Path
+
+			if !strings.HasPrefix(path, "/api/") || versionedPathPattern.MatchString(path) {
+				return next(c)
+			}
+
+			version, _, err := negotiateVersion(req)
+			if err != nil {
+				return unsupportedVersion(c, err.Error())
+			}
+			if !SupportedAPIVersion(version) {
+				return unsupportedVersion(c, fmt.Sprintf("API version %d is not supported", version))
+			}
+
+			req.URL.Path = fmt.Sprintf("/api/v%d%s", version, strings.TrimPrefix(path, "/api"))
+			if req.URL.RawPath != "" {
+				req.URL.RawPath = fmt.Sprintf("/api/v%d%s", version, strings.TrimPrefix(req.URL.RawPath, "/api"))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// APIVersionMiddleware stores the version that serves the request in the
+// context and echoes it in the API-Version response header
+func APIVersionMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m := versionedPathPattern.FindStringSubmatch(c.Request().URL.Path); m != nil {
+				version, _ := strconv.Atoi(m[1])
+				c.Set("api_version", version)
+				c.Response().Header().Set(HeaderAPIVersion, "v"+m[1])
+			}
+			return next(c)
+		}
+	}
+}
+
+// GetAPIVersion returns the API version serving the request
+func GetAPIVersion(c echo.Context) int {
+	if version, ok := c.Get("api_version").(int); ok {
+		return version
+	}
+	return DefaultAPIVersion
+}
+
+// IsLoginPath reports whether path is the login endpoint of any API version
+func IsLoginPath(path string) bool {
+	m := versionedPathPattern.FindStringSubmatch(path)
+	return m != nil && strings.HasSuffix(path, "/auth/login") &&
+		strings.Count(path, "/") == 4
+}
+
+func unsupportedVersion(c echo.Context, details string) error {
+	supported := make([]string, 0, LatestAPIVersion)
+	for v := 1; v <= LatestAPIVersion; v++ {
+		supported = append(supported, "v"+strconv.Itoa(v))
+	}
+
+	return c.JSON(http.StatusBadRequest, map[string]any{
+		"error":              "unsupported_api_version",
+		"details":            details,
+		"supported_versions": supported,
+	})
+}
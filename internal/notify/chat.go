@@ -0,0 +1,172 @@
+// internal/notify/chat.go - Slack and Microsoft Teams webhook senders
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Chat channels post to a shared incoming webhook rather than a user, so
+// they are not in Channels and carry no preferences
+const (
+	ChannelSlack Channel = "slack"
+	ChannelTeams Channel = "teams"
+)
+
+// ChatChannels lists every chat channel
+var ChatChannels = []Channel{ChannelSlack, ChannelTeams}
+
+// Fact is a labelled value shown in a chat message
+type Fact struct {
+	Name  string
+	Value string
+}
+
+// maxSlackFields is the most fields a Slack section block holds
+const maxSlackFields = 10
+
+// NewSlackSender posts messages to a Slack incoming webhook
+func NewSlackSender(webhookURL string) Sender {
+	return &SlackSender{url: webhookURL, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// SlackSender formats messages as Block Kit blocks: a header with the
+// subject, the text and the facts as fields
+type SlackSender struct {
+	url    string
+	client *http.Client
+}
+
+// Send posts msg.Subject, msg.Text and msg.Facts
+func (s *SlackSender) Send(ctx context.Context, msg Message) error {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type     string `json:"type"`
+		Text     *text  `json:"text,omitempty"`
+		Fields   []text `json:"fields,omitempty"`
+		Elements []text `json:"elements,omitempty"`
+	}
+
+	blocks := []block{{Type: "header", Text: &text{Type: "plain_text", Text: truncateRunes(msg.Subject, 150)}}}
+	if msg.Text != "" {
+		blocks = append(blocks, block{Type: "section", Text: &text{Type: "mrkdwn", Text: slackEscape(msg.Text)}})
+	}
+	for start := 0; start < len(msg.Facts); start += maxSlackFields {
+		section := block{Type: "section"}
+		for _, f := range msg.Facts[start:min(start+maxSlackFields, len(msg.Facts))] {
+			section.Fields = append(section.Fields, text{Type: "mrkdwn",
+				Text: "*" + slackEscape(f.Name) + "*\n" + slackEscape(f.Value)})
+		}
+		blocks = append(blocks, section)
+	}
+	blocks = append(blocks, block{Type: "context", Elements: []text{{Type: "mrkdwn", Text: "DigiOrder"}}})
+
+	return postJSON(ctx, s.client, s.url, map[string]any{
+		"text":   msg.Subject, // notification fallback
+		"blocks": blocks,
+	})
+}
+
+// slackEscape escapes the characters Slack treats as markup
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// NewTeamsSender posts messages to a Microsoft Teams incoming webhook or
+// Workflows webhook
+func NewTeamsSender(webhookURL string) Sender {
+	return &TeamsSender{url: webhookURL, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// TeamsSender formats messages as an Adaptive Card with the subject as
+// title, the text and the facts as a fact set
+type TeamsSender struct {
+	url    string
+	client *http.Client
+}
+
+// Send posts msg.Subject, msg.Text and msg.Facts
+func (s *TeamsSender) Send(ctx context.Context, msg Message) error {
+	body := []map[string]any{{
+		"type": "TextBlock", "text": msg.Subject, "weight": "Bolder", "size": "Medium",
+		"color": "Attention", "wrap": true,
+	}}
+	if msg.Text != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": msg.Text, "wrap": true})
+	}
+	if len(msg.Facts) > 0 {
+		facts := make([]map[string]string, len(msg.Facts))
+		for i, f := range msg.Facts {
+			facts[i] = map[string]string{"title": f.Name, "value": f.Value}
+		}
+		body = append(body, map[string]any{"type": "FactSet", "facts": facts})
+	}
+
+	return postJSON(ctx, s.client, s.url, map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	})
+}
+
+// postJSON submits a webhook payload. As with SMS providers, client errors
+// other than throttling are permanent; everything else is retried.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(raw))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The webhook URL is a credential; keep it out of logged errors
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("webhook request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+// internal/notify/dispatcher.go - Asynchronous delivery with retries
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var notificationsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "notifications_total",
+		Help: "Notifications by channel, event and outcome (sent, failed, dropped)",
+	},
+	[]string{"channel", "event", "status"},
+)
+
+// DispatcherConfig controls queueing and retry behaviour
+type DispatcherConfig struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	SendTimeout time.Duration
+}
+
+// DefaultDispatcherConfig returns conservative delivery settings
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		Workers:     2,
+		QueueSize:   500,
+		MaxAttempts: 5,
+		BaseBackoff: 2 * time.Second,
+		SendTimeout: 30 * time.Second,
+	}
+}
+
+// Dispatcher queues messages and delivers them in the background so
+// request handlers never wait on a mail server
+type Dispatcher struct {
+	config  DispatcherConfig
+	mu      sync.RWMutex
+	senders map[Channel]Sender
+	logger  *logging.Logger
+
+	queue  chan Message
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewDispatcher starts the delivery workers
+func NewDispatcher(config DispatcherConfig, logger *logging.Logger) *Dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		config:  config,
+		senders: make(map[Channel]Sender),
+		logger:  logger,
+		queue:   make(chan Message, config.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+
+	return d
+}
+
+// Register sets the sender for a channel, or removes it when sender is
+// nil. Senders may be replaced while messages are delivered; queued
+// messages go to the sender registered when they are sent.
+func (d *Dispatcher) Register(channel Channel, sender Sender) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sender == nil {
+		delete(d.senders, channel)
+		return
+	}
+	d.senders[channel] = sender
+}
+
+// Enabled reports whether a sender is registered for channel
+func (d *Dispatcher) Enabled(channel Channel) bool {
+	_, ok := d.sender(channel)
+	return ok
+}
+
+// sender returns the sender registered for channel
+func (d *Dispatcher) sender(channel Channel) (Sender, bool) {
+	if d == nil {
+		return nil, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	sender, ok := d.senders[channel]
+	return sender, ok
+}
+
+// Enqueue schedules msg for delivery. It never blocks: when the queue is
+// full the message is dropped and counted.
+func (d *Dispatcher) Enqueue(msg Message) bool {
+	if !d.Enabled(msg.Channel) {
+		return false
+	}
+
+	select {
+	case d.queue <- msg:
+		return true
+	default:
+		notificationsTotal.WithLabelValues(string(msg.Channel), string(msg.Event), "dropped").Inc()
+		d.logger.Warn("Notification queue full, dropping message", map[string]any{
+			"channel": msg.Channel,
+			"event":   msg.Event,
+		})
+		return false
+	}
+}
+
+// ErrChannelDisabled is returned by SendNow for channels without a sender
+var ErrChannelDisabled = errors.New("notification channel is not configured")
+
+// SendNow delivers msg once, bypassing the queue, and returns the outcome.
+// It is meant for test messages where the caller wants to see failures.
+func (d *Dispatcher) SendNow(ctx context.Context, msg Message) error {
+	sender, ok := d.sender(msg.Channel)
+	if !ok {
+		return ErrChannelDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.SendTimeout)
+	defer cancel()
+	if err := sender.Send(ctx, msg); err != nil {
+		notificationsTotal.WithLabelValues(string(msg.Channel), string(msg.Event), "failed").Inc()
+		return err
+	}
+	notificationsTotal.WithLabelValues(string(msg.Channel), string(msg.Event), "sent").Inc()
+	return nil
+}
+
+// Close stops accepting messages and waits for queued ones to be attempted
+// until ctx expires
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() { close(d.queue) })
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		return ctx.Err()
+	}
+}
+
+// worker delivers messages until the queue is closed
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for msg := range d.queue {
+		d.deliver(msg)
+	}
+}
+
+// deliver sends msg with exponential backoff between attempts
+func (d *Dispatcher) deliver(msg Message) {
+	sender, ok := d.sender(msg.Channel)
+	if !ok {
+		// The channel was removed by a configuration reload while the
+		// message was queued
+		notificationsTotal.WithLabelValues(string(msg.Channel), string(msg.Event), "dropped").Inc()
+		return
+	}
+	backoff := d.config.BaseBackoff
+
+	var err error
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(d.ctx, d.config.SendTimeout)
+		err = sender.Send(ctx, msg)
+		cancel()
+
+		if err == nil {
+			notificationsTotal.WithLabelValues(string(msg.Channel), string(msg.Event), "sent").Inc()
+			return
+		}
+
+		var permanent *PermanentError
+		if errors.As(err, &permanent) || attempt == d.config.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-d.ctx.Done():
+			attempt = d.config.MaxAttempts
+		}
+	}
+
+	notificationsTotal.WithLabelValues(string(msg.Channel), string(msg.Event), "failed").Inc()
+	d.logger.Error("Notification delivery failed", err, map[string]any{
+		"channel": msg.Channel,
+		"event":   msg.Event,
+	})
+}
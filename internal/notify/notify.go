@@ -0,0 +1,140 @@
+// internal/notify/notify.go - Notification events, messages and senders
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// EventType identifies what a notification is about
+type EventType string
+
+const (
+	EventOrderSubmitted EventType = "order_submitted"
+	EventOrderApproved  EventType = "order_approved"
+	EventAccountInvited EventType = "account_invited"
+	EventPasswordReset  EventType = "password_reset"
+	EventSecurityAlert  EventType = "security_alert"
+	EventUrgentOrder    EventType = "urgent_order"
+	EventOrderAssigned  EventType = "order_assigned"
+	EventOrderCancelled EventType = "order_cancelled"
+
+	// EventScheduledReport is sent to report schedule recipients. It is
+	// not in Events: recipients are managed by admins, not preferences.
+	EventScheduledReport EventType = "scheduled_report"
+
+	// EventPasswordResetRequested carries a password reset token. It is
+	// not in Events, so it cannot be turned off: it is only sent on request.
+	EventPasswordResetRequested EventType = "password_reset_requested"
+)
+
+// Events lists every event users can configure preferences for
+var Events = []EventType{
+	EventOrderSubmitted,
+	EventOrderApproved,
+	EventAccountInvited,
+	EventPasswordReset,
+	EventSecurityAlert,
+	EventUrgentOrder,
+	EventOrderAssigned,
+	EventOrderCancelled,
+}
+
+// Channel identifies a delivery mechanism
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+	ChannelPush  Channel = "push"
+)
+
+// Channels lists every delivery channel
+var Channels = []Channel{
+	ChannelEmail,
+	ChannelSMS,
+	ChannelPush,
+}
+
+// channelEvents lists the events each channel carries. SMS is reserved for
+// time-critical alerts since every message costs money; push carries what
+// needs someone's attention on the floor.
+var channelEvents = map[Channel][]EventType{
+	ChannelEmail: {
+		EventOrderSubmitted,
+		EventOrderApproved,
+		EventAccountInvited,
+		EventPasswordReset,
+		EventSecurityAlert,
+		EventOrderAssigned,
+		EventOrderCancelled,
+	},
+	ChannelSMS: {
+		EventSecurityAlert,
+		EventUrgentOrder,
+	},
+	ChannelPush: {
+		EventOrderSubmitted,
+		EventUrgentOrder,
+		EventOrderAssigned,
+		EventOrderCancelled,
+	},
+}
+
+// ValidEvent reports whether name is a known event type
+func ValidEvent(name string) bool {
+	return slices.Contains(Events, EventType(name))
+}
+
+// ValidChannel reports whether name is a known channel
+func ValidChannel(name string) bool {
+	return slices.Contains(Channels, Channel(name))
+}
+
+// Supports reports whether channel delivers event
+func Supports(channel Channel, event EventType) bool {
+	return slices.Contains(channelEvents[channel], event)
+}
+
+// Message is a rendered notification ready for delivery
+type Message struct {
+	Channel Channel
+	Event   EventType
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Attachments are sent with email only
+	Attachments []Attachment
+	// Facts are shown as a table by chat channels
+	Facts []Fact
+	// Data is handed to the app with push messages, e.g. the order to open
+	Data map[string]string
+}
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Sender delivers a message over one channel
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// PermanentError marks a failure that retrying cannot fix, such as a
+// rejected recipient address
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("permanent delivery failure: %v", e.Err)
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,239 @@
+// internal/notify/push.go - Firebase Cloud Messaging push sender
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fcmScope   = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmBaseURL = "https://fcm.googleapis.com"
+)
+
+// FCMConfig holds the Firebase service account. ProjectID defaults to the
+// project of the service account.
+type FCMConfig struct {
+	CredentialsFile string
+	ProjectID       string
+	BaseURL         string // overrides the FCM endpoint, mainly for tests
+	// OnUnregistered is called with device tokens FCM no longer accepts,
+	// so they can be forgotten
+	OnUnregistered func(token string)
+}
+
+// serviceAccount is the part of a Google service account key file the
+// sender needs
+type serviceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewFCMSender reads the service account key file
+func NewFCMSender(config FCMConfig) (*FCMSender, error) {
+	raw, err := os.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("FCM credentials are not a service account key: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, errors.New("FCM credentials lack client_email, private_key or token_uri")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM private key: %w", err)
+	}
+
+	if config.ProjectID == "" {
+		config.ProjectID = account.ProjectID
+	}
+	if config.ProjectID == "" {
+		return nil, errors.New("no FCM project ID configured or in the credentials")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = fcmBaseURL
+	}
+
+	return &FCMSender{
+		config:  config,
+		account: account,
+		key:     key,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// FCMSender sends push notifications through the FCM HTTP v1 API,
+// authenticating with an OAuth2 token minted from the service account
+type FCMSender struct {
+	config  FCMConfig
+	account serviceAccount
+	key     *rsa.PrivateKey
+	client  *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// Send delivers msg.Subject and msg.Text, with msg.Data, to the device
+// token msg.To
+func (s *FCMSender) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return &PermanentError{Err: errors.New("missing device token")}
+	}
+
+	token, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"message": map[string]any{
+			"token":        msg.To,
+			"notification": map[string]string{"title": msg.Subject, "body": msg.Text},
+			"data":         msg.Data,
+			"android":      map[string]any{"priority": "high"},
+			"apns":         map[string]any{"headers": map[string]string{"apns-priority": "10"}},
+		},
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send",
+		strings.TrimRight(s.config.BaseURL, "/"), url.PathEscape(s.config.ProjectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(raw))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	code, message := fcmError(body)
+	err = fmt.Errorf("FCM returned %d: %s", resp.StatusCode, message)
+	switch {
+	case code == "UNREGISTERED" || (code == "INVALID_ARGUMENT" && strings.Contains(message, "registration token")):
+		if s.config.OnUnregistered != nil {
+			s.config.OnUnregistered(msg.To)
+		}
+		return &PermanentError{Err: err}
+	case resp.StatusCode == http.StatusUnauthorized:
+		s.mu.Lock()
+		s.accessToken = "" // mint a fresh one on the retry
+		s.mu.Unlock()
+		return err
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// token returns a cached OAuth2 access token, exchanging a signed service
+// account assertion for a new one shortly before it expires
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Until(s.expiresAt) > time.Minute {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	assertion.Header["kid"] = s.account.PrivateKeyID
+	signed, err := assertion.SignedString(s.key)
+	if err != nil {
+		return "", &PermanentError{Err: err}
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM token exchange returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var grant struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(raw, &grant); err != nil || grant.AccessToken == "" {
+		return "", errors.New("FCM token exchange returned no access token")
+	}
+
+	s.accessToken = grant.AccessToken
+	s.expiresAt = now.Add(time.Duration(grant.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
+
+// fcmError extracts the FCM error code and message from an error body
+func fcmError(body []byte) (code, message string) {
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) != nil || payload.Error.Message == "" {
+		return "", strings.TrimSpace(string(body))
+	}
+	code = payload.Error.Status
+	for _, d := range payload.Error.Details {
+		if d.ErrorCode != "" {
+			code = d.ErrorCode
+		}
+	}
+	return code, payload.Error.Message
+}
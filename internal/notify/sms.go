@@ -0,0 +1,161 @@
+// internal/notify/sms.go - SMS senders (Kavenegar, Twilio)
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supported SMS providers
+const (
+	SMSProviderKavenegar = "kavenegar"
+	SMSProviderTwilio    = "twilio"
+)
+
+// SMSConfig holds SMS gateway credentials. Kavenegar uses APIKey; Twilio
+// uses AccountSID and AuthToken. From is the sender line or number.
+type SMSConfig struct {
+	Provider   string
+	APIKey     string
+	AccountSID string
+	AuthToken  string
+	From       string
+	BaseURL    string // overrides the provider endpoint, mainly for sandboxes
+}
+
+// NewSMSSender creates the sender for the configured provider
+func NewSMSSender(config SMSConfig) (Sender, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	switch strings.ToLower(config.Provider) {
+	case SMSProviderKavenegar:
+		if config.APIKey == "" {
+			return nil, errors.New("kavenegar requires an API key")
+		}
+		if config.BaseURL == "" {
+			config.BaseURL = "https://api.kavenegar.com"
+		}
+		return &KavenegarSender{config: config, client: client}, nil
+	case SMSProviderTwilio:
+		if config.AccountSID == "" || config.AuthToken == "" || config.From == "" {
+			return nil, errors.New("twilio requires an account SID, auth token and sender number")
+		}
+		if config.BaseURL == "" {
+			config.BaseURL = "https://api.twilio.com"
+		}
+		return &TwilioSender{config: config, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", config.Provider)
+	}
+}
+
+// KavenegarSender sends SMS through the Kavenegar REST API
+type KavenegarSender struct {
+	config SMSConfig
+	client *http.Client
+}
+
+// Send delivers msg.Text to msg.To
+func (s *KavenegarSender) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return &PermanentError{Err: errors.New("missing recipient number")}
+	}
+
+	form := url.Values{
+		"receptor": {msg.To},
+		"message":  {msg.Text},
+	}
+	if s.config.From != "" {
+		form.Set("sender", s.config.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/sms/send.json",
+		strings.TrimRight(s.config.BaseURL, "/"), url.PathEscape(s.config.APIKey))
+
+	return postForm(ctx, s.client, endpoint, form, nil)
+}
+
+// TwilioSender sends SMS through the Twilio Messages API
+type TwilioSender struct {
+	config SMSConfig
+	client *http.Client
+}
+
+// Send delivers msg.Text to msg.To
+func (s *TwilioSender) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return &PermanentError{Err: errors.New("missing recipient number")}
+	}
+
+	form := url.Values{
+		"To":   {msg.To},
+		"From": {s.config.From},
+		"Body": {msg.Text},
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json",
+		strings.TrimRight(s.config.BaseURL, "/"), url.PathEscape(s.config.AccountSID))
+
+	return postForm(ctx, s.client, endpoint, form, func(req *http.Request) {
+		req.SetBasicAuth(s.config.AccountSID, s.config.AuthToken)
+	})
+}
+
+// postForm submits a provider request and classifies the response. Client
+// errors other than throttling are permanent; everything else is retried.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, prepare func(*http.Request)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if prepare != nil {
+		prepare(req)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	err = fmt.Errorf("SMS provider returned %d: %s", resp.StatusCode, providerMessage(resp.Body))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// providerMessage extracts the error text from a Kavenegar or Twilio body
+func providerMessage(body io.Reader) string {
+	raw, _ := io.ReadAll(io.LimitReader(body, 4096))
+
+	var payload struct {
+		Return struct {
+			Message string `json:"message"`
+		} `json:"return"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &payload) == nil {
+		if payload.Return.Message != "" {
+			return payload.Return.Message
+		}
+		if payload.Message != "" {
+			return payload.Message
+		}
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,193 @@
+// internal/notify/smtp.go - SMTP email sender
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPConfig holds outgoing mail server settings
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender sends email through an SMTP relay. STARTTLS is used whenever
+// the server offers it; credentials are only sent over TLS or to localhost.
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender creates an email sender
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+// Send delivers msg as a multipart text/HTML email with any attachments
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return &PermanentError{Err: errors.New("missing recipient address")}
+	}
+
+	body, err := s.buildMessage(msg)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+
+	// net/smtp has no context support, so honour cancellation by abandoning
+	// the send; the goroutine finishes on its own once the server responds
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, s.config.From, []string{msg.To}, body)
+	}()
+
+	select {
+	case err := <-done:
+		var tpErr *textproto.Error
+		if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+			return &PermanentError{Err: err}
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMessage renders the RFC 5322 message with text and HTML parts,
+// wrapped in multipart/mixed when there are attachments
+func (s *SMTPSender) buildMessage(msg Message) ([]byte, error) {
+	body, contentType, err := alternativeBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Attachments) > 0 {
+		body, contentType, err = mixedBody(body, contentType, msg.Attachments)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers := []string{
+		"From: " + s.config.From,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID(s.config.From),
+		"MIME-Version: 1.0",
+		"Content-Type: " + contentType,
+	}
+	for _, h := range headers {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, fmt.Errorf("invalid header %q", h)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(strings.Join(headers, "\r\n"))
+	out.WriteString("\r\n\r\n")
+	out.Write(body)
+	return out.Bytes(), nil
+}
+
+// alternativeBody renders the text and HTML parts as multipart/alternative
+func alternativeBody(msg Message) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.body == "" {
+			continue
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write([]byte(p.body))
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/alternative; boundary=" + writer.Boundary(), nil
+}
+
+// mixedBody puts the message body first and the base64-encoded
+// attachments after it
+func mixedBody(body []byte, contentType string, attachments []Attachment) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return nil, "", err
+	}
+	part.Write(body)
+
+	for _, a := range attachments {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})
+		if disposition == "" {
+			return nil, "", fmt.Errorf("invalid attachment name %q", a.Filename)
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Disposition":       {disposition},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/mixed; boundary=" + writer.Boundary(), nil
+}
+
+// messageID generates a unique Message-ID on the sender's domain
+func messageID(from string) string {
+	domain := "digiorder.local"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
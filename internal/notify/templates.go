@@ -0,0 +1,83 @@
+// internal/notify/templates.go - Templated notification content
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Each <event>.tmpl file defines "<event>_subject", "<event>_text" and
+// "<event>_html" blocks, plus "<event>_sms" for events delivered by SMS and
+// "<event>_push_title" and "<event>_push" for events delivered by push.
+// All but html are rendered as plain text, html with contextual escaping.
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.tmpl"))
+)
+
+// Render builds the subject, text and HTML body for an event
+func Render(event EventType, data any) (subject, text, html string, err error) {
+	subject, err = executeText(event, "subject", data)
+	if err != nil {
+		return "", "", "", err
+	}
+	text, err = executeText(event, "text", data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&buf, string(event)+"_html", data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render %s html: %w", event, err)
+	}
+
+	return strings.TrimSpace(subject), strings.TrimSpace(text), buf.String(), nil
+}
+
+// maxSMSLength caps SMS bodies at two concatenated Unicode segments
+const maxSMSLength = 134
+
+// RenderSMS builds the short plain-text body for an SMS notification
+func RenderSMS(event EventType, data any) (string, error) {
+	text, err := executeText(event, "sms", data)
+	if err != nil {
+		return "", err
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxSMSLength {
+		text = string(runes[:maxSMSLength-1]) + "…"
+	}
+	return text, nil
+}
+
+// maxPushBodyLength keeps push bodies to what lock screens show
+const maxPushBodyLength = 240
+
+// RenderPush builds the title and body of a push notification
+func RenderPush(event EventType, data any) (title, body string, err error) {
+	title, err = executeText(event, "push_title", data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err = executeText(event, "push", data)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(title), truncateRunes(strings.Join(strings.Fields(body), " "), maxPushBodyLength), nil
+}
+
+// executeText renders one plain-text block of an event template
+func executeText(event EventType, block string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&buf, string(event)+"_"+block, data); err != nil {
+		return "", fmt.Errorf("failed to render %s %s: %w", event, block, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,349 @@
+// internal/orderimport/import.go - Matching requirement lists to products
+package orderimport
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// Ways a row was matched to a product
+const (
+	MatchedBarcode = "barcode"
+	MatchedName    = "name"
+)
+
+// Reasons a row was not imported
+const (
+	ReasonMissingProduct  = "missing_product"
+	ReasonInvalidQuantity = "invalid_quantity"
+	ReasonNotFound        = "not_found"
+	ReasonAmbiguous       = "ambiguous"
+	ReasonStaging         = "product_in_staging"
+)
+
+// Columns names the header of each field, overriding the header spellings
+// recognised by default. Empty fields are detected.
+type Columns struct {
+	Barcode  string `json:"barcode"`
+	Name     string `json:"name"`
+	Strength string `json:"strength"`
+	Quantity string `json:"quantity"`
+	Unit     string `json:"unit"`
+	Note     string `json:"note"`
+}
+
+// headerAliases maps header spellings seen in supplier lists, including
+// Persian ones, onto fields
+var headerAliases = map[string]string{
+	"barcode":       "barcode",
+	"gtin":          "barcode",
+	"ean":           "barcode",
+	"ean13":         "barcode",
+	"بارکد":         "barcode",
+	"name":          "name",
+	"product":       "name",
+	"product_name":  "name",
+	"item":          "name",
+	"description":   "name",
+	"drug":          "name",
+	"نام":           "name",
+	"نام_کالا":      "name",
+	"نام_دارو":      "name",
+	"strength":      "strength",
+	"dose":          "strength",
+	"قدرت":          "strength",
+	"quantity":      "quantity",
+	"qty":           "quantity",
+	"requested_qty": "quantity",
+	"count":         "quantity",
+	"تعداد":         "quantity",
+	"unit":          "unit",
+	"واحد":          "unit",
+	"note":          "note",
+	"notes":         "note",
+	"comment":       "note",
+	"توضیحات":       "note",
+}
+
+// Row is one line of a requirement list. Line is the 1-based line in the
+// sheet, counting the header.
+type Row struct {
+	Line     int
+	Barcode  string
+	Name     string
+	Strength string
+	Quantity string
+	Unit     string
+	Note     string
+}
+
+// ParseRows maps the header, the first non-empty row of records, onto
+// fields and returns the non-empty rows below it. A quantity column and a
+// barcode or name column are required.
+func ParseRows(records [][]string, columns Columns) ([]Row, error) {
+	start := 0
+	for start < len(records) && blank(records[start]) {
+		start++
+	}
+	if start == len(records) {
+		return nil, errors.New("the sheet is empty")
+	}
+	header := records[start]
+
+	overrides := map[string]string{}
+	for field, header := range map[string]string{
+		"barcode": columns.Barcode, "name": columns.Name, "strength": columns.Strength,
+		"quantity": columns.Quantity, "unit": columns.Unit, "note": columns.Note,
+	} {
+		if header != "" {
+			overrides[headerKey(header)] = field
+		}
+	}
+
+	// Named columns first, then the recognised spellings of the rest
+	fields := make([]string, len(header))
+	found := map[string]bool{}
+	for i, name := range header {
+		if field := overrides[headerKey(name)]; field != "" && !found[field] {
+			fields[i] = field
+			found[field] = true
+		}
+	}
+	for i, name := range header {
+		if field := headerAliases[headerKey(name)]; fields[i] == "" && field != "" && !found[field] {
+			fields[i] = field
+			found[field] = true
+		}
+	}
+	for _, name := range []string{columns.Barcode, columns.Name, columns.Strength, columns.Quantity, columns.Unit, columns.Note} {
+		if name != "" && !headerPresent(header, name) {
+			return nil, fmt.Errorf("column %q is not in the header row", name)
+		}
+	}
+	if !found["quantity"] {
+		return nil, errors.New("the header row has no quantity column")
+	}
+	if !found["barcode"] && !found["name"] {
+		return nil, errors.New("the header row has no barcode or name column")
+	}
+
+	var rows []Row
+	for n := start + 1; n < len(records); n++ {
+		record := records[n]
+		if blank(record) {
+			continue
+		}
+		row := Row{Line: n + 1}
+		for i, value := range record {
+			if i >= len(fields) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch fields[i] {
+			case "barcode":
+				row.Barcode = value
+			case "name":
+				row.Name = value
+			case "strength":
+				row.Strength = value
+			case "quantity":
+				row.Quantity = value
+			case "unit":
+				row.Unit = value
+			case "note":
+				row.Note = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func blank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func headerKey(header string) string {
+	return strings.Join(strings.Fields(strings.ToLower(header)), "_")
+}
+
+func headerPresent(header []string, name string) bool {
+	for _, h := range header {
+		if headerKey(h) == headerKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Item is a product to order. Rows naming the same product are merged
+// into one item with their quantities added up.
+type Item struct {
+	Product   db.Product
+	Quantity  int32
+	Unit      string
+	Note      string
+	MatchedBy string
+	Lines     []int
+}
+
+// Unmatched is a row left out of the order and why
+type Unmatched struct {
+	Line     int
+	Barcode  string
+	Name     string
+	Quantity string
+	Reason   string
+	Detail   string
+}
+
+// Result is the outcome of matching a requirement list
+type Result struct {
+	Rows      int
+	Items     []Item
+	Unmatched []Unmatched
+}
+
+// Match looks up the product of every row: by barcode when the row has
+// one, otherwise by name and strength. Only active products are ordered.
+func Match(ctx context.Context, q db.Querier, rows []Row) (*Result, error) {
+	result := &Result{Rows: len(rows)}
+	byProduct := map[uuid.UUID]int{}
+
+	for _, row := range rows {
+		skip := func(reason, detail string) {
+			result.Unmatched = append(result.Unmatched, Unmatched{
+				Line: row.Line, Barcode: row.Barcode, Name: row.Name, Quantity: row.Quantity,
+				Reason: reason, Detail: detail,
+			})
+		}
+
+		if row.Barcode == "" && row.Name == "" {
+			skip(ReasonMissingProduct, "The row has no barcode or product name.")
+			continue
+		}
+		qty, ok := parseQuantity(row.Quantity)
+		if !ok {
+			skip(ReasonInvalidQuantity, "The quantity must be a positive whole number.")
+			continue
+		}
+
+		product, matchedBy, reason, err := find(ctx, q, row)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case reason == ReasonNotFound:
+			skip(reason, "No product has this barcode or name.")
+			continue
+		case reason == ReasonAmbiguous:
+			skip(reason, "Several products have this name; add the strength or barcode.")
+			continue
+		case product.Status == "staging":
+			skip(ReasonStaging, "The product must be approved before it can be ordered.")
+			continue
+		}
+
+		if i, ok := byProduct[product.ID]; ok {
+			item := &result.Items[i]
+			if int64(item.Quantity)+int64(qty) > math.MaxInt32 {
+				skip(ReasonInvalidQuantity, "The total quantity of this product is too large.")
+				continue
+			}
+			item.Quantity += qty
+			item.Lines = append(item.Lines, row.Line)
+			if row.Note != "" {
+				item.Note = strings.TrimPrefix(item.Note+"; "+row.Note, "; ")
+			}
+			continue
+		}
+
+		unit := row.Unit
+		if unit == "" {
+			unit = product.Unit.String
+		}
+		byProduct[product.ID] = len(result.Items)
+		result.Items = append(result.Items, Item{
+			Product:   product,
+			Quantity:  qty,
+			Unit:      unit,
+			Note:      row.Note,
+			MatchedBy: matchedBy,
+			Lines:     []int{row.Line},
+		})
+	}
+	return result, nil
+}
+
+// find returns the product of a row, or the reason there is none
+func find(ctx context.Context, q db.Querier, row Row) (product db.Product, matchedBy, reason string, err error) {
+	if row.Barcode != "" {
+		product, err = q.GetProductByBarcode(ctx, row.Barcode)
+		switch {
+		case err == nil && !product.DeletedAt.Valid:
+			return product, MatchedBarcode, "", nil
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return product, "", "", err
+		}
+		if row.Name == "" {
+			return product, "", ReasonNotFound, nil
+		}
+	}
+
+	candidates, err := q.FindProductsByName(ctx, db.FindProductsByNameParams{
+		Names:    []string{strings.ToLower(row.Name)},
+		Strength: strings.ToLower(strings.ReplaceAll(row.Strength, " ", "")),
+	})
+	switch {
+	case err != nil:
+		return product, "", "", err
+	case len(candidates) == 0:
+		return product, "", ReasonNotFound, nil
+	case len(candidates) > 1:
+		return product, "", ReasonAmbiguous, nil
+	}
+	return candidates[0], MatchedName, "", nil
+}
+
+// parseQuantity reads a positive whole number, accepting Persian and
+// Arabic digits, thousands separators and a zero fraction such as "10.0"
+func parseQuantity(s string) (int32, bool) {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '۰' && r <= '۹':
+			return '0' + r - '۰'
+		case r >= '٠' && r <= '٩':
+			return '0' + r - '٠'
+		case r == ',' || r == '٬' || r == ' ':
+			return -1
+		case r == '٫':
+			return '.'
+		}
+		return r
+	}, s)
+	if whole, fraction, ok := strings.Cut(s, "."); ok {
+		if strings.Trim(fraction, "0") != "" {
+			return 0, false
+		}
+		s = whole
+	}
+
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return int32(n), true
+}
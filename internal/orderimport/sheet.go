@@ -0,0 +1,263 @@
+// internal/orderimport/sheet.go - Reading CSV and Excel requirement lists
+package orderimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Limits on an uploaded sheet
+const (
+	maxRows     = 5000
+	maxColumns  = 100
+	maxPartSize = 64 << 20 // decompressed size of one part of an .xlsx file
+)
+
+// ReadSheet returns the cells of a CSV file or of the first worksheet of an
+// Excel (.xlsx) workbook, which is recognised by its zip signature. Record i
+// holds line i+1 of the sheet, so empty lines are kept.
+func ReadSheet(data []byte) ([][]string, error) {
+	var records [][]string
+	var err error
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		records, err = readXLSX(data)
+	} else {
+		records, err = readCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > maxRows+1 {
+		return nil, fmt.Errorf("the sheet has more than %d rows", maxRows)
+	}
+	return records, nil
+}
+
+// readCSV reads comma, semicolon or tab separated values, guessing the
+// separator from the first line
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	switch {
+	case bytes.Count(first, []byte("\t")) > bytes.Count(first, []byte(",")):
+		reader.Comma = '\t'
+	case bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")):
+		reader.Comma = ';'
+	}
+
+	// The reader skips empty lines; pad them back so records match lines
+	var records [][]string
+	for len(records) <= maxRows+1 {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		for len(records) < line-1 {
+			records = append(records, nil)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readXLSX reads the first worksheet of a workbook: the sheet order comes
+// from xl/workbook.xml, its part from the workbook relationships, and text
+// cells from the shared string table.
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Excel file: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("the workbook has no worksheets")
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPart := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RID {
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetPart = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				sheetPart = path.Join("xl", rel.Target)
+			}
+		}
+	}
+	if sheetPart == "" {
+		return nil, errors.New("the first worksheet is missing from the workbook")
+	}
+
+	var shared []string
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []richText `xml:"si"`
+		}
+		if err := decodePart(parts, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		shared = make([]string, len(sst.Items))
+		for i, item := range sst.Items {
+			shared[i] = item.String()
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Line  int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodePart(parts, sheetPart, &sheet); err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// Rows without cells are usually left out of the sheet
+		for row.Line > len(records)+1 && len(records) <= maxRows {
+			records = append(records, nil)
+		}
+
+		var record []string
+		for _, cell := range row.Cells {
+			col := len(record)
+			if cell.Ref != "" {
+				col = columnIndex(cell.Ref)
+			}
+			if col >= maxColumns {
+				continue
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				if i, err := strconv.Atoi(cell.Value); err == nil && i >= 0 && i < len(shared) {
+					record[col] = shared[i]
+				}
+			case "inlineStr":
+				record[col] = cell.Inline.String()
+			case "b":
+				record[col] = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+			case "str", "e":
+				record[col] = cell.Value
+			default:
+				record[col] = formatNumber(cell.Value)
+			}
+		}
+		records = append(records, record)
+		if len(records) > maxRows+1 {
+			break
+		}
+	}
+	return records, nil
+}
+
+// richText is a shared or inline string: plain text or formatted runs
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r richText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+func decodePart(parts map[string]*zip.File, name string, v any) error {
+	f, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("invalid Excel file: %s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("invalid Excel file: %w", err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid Excel file: %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to
+// a zero-based column
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > maxColumns {
+			break
+		}
+	}
+	return max(col-1, 0)
+}
+
+// formatNumber writes numeric cells without exponents, so long barcodes
+// stored as numbers keep all their digits
+func formatNumber(value string) string {
+	if !strings.ContainsAny(value, "eE.") {
+		return value
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,180 @@
+// internal/outbox/event.go - Domain event envelope and payloads
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// SchemaVersion is bumped whenever a payload changes incompatibly
+const SchemaVersion = 1
+
+// Domain event types, named <aggregate>.<change>
+const (
+	OrderCreated       = "order.created"
+	OrderStatusChanged = "order.status_changed"
+	OrderDeleted       = "order.deleted"
+	ProductCreated     = "product.created"
+	ProductUpdated     = "product.updated"
+	ProductDeleted     = "product.deleted"
+	StockLow           = "stock.low"
+	UserCreated        = "user.created"
+	UserUpdated        = "user.updated"
+	UserDeleted        = "user.deleted"
+)
+
+// Event is the envelope delivered to every publisher. ID is stable across
+// redeliveries so consumers can deduplicate.
+type Event struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Actor         string          `json:"actor,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// NewEvent builds an envelope for eventType. The aggregate type is the part
+// of the event type before the dot.
+func NewEvent(eventType, aggregateID, actor string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	aggregateType, _, _ := strings.Cut(eventType, ".")
+	return Event{
+		ID:            uuid.New(),
+		Type:          eventType,
+		Version:       SchemaVersion,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    time.Now().UTC(),
+		Actor:         actor,
+		Data:          raw,
+	}, nil
+}
+
+// OrderData is the payload of order.created and order.status_changed
+type OrderData struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	NeededBy    string     `json:"needed_by,omitempty"` // delivery deadline, YYYY-MM-DD
+	RequesterID string     `json:"requester_id,omitempty"`
+}
+
+// ProductData is the payload of product.created and product.updated
+type ProductData struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Brand        string     `json:"brand,omitempty"`
+	DosageFormID int32      `json:"dosage_form_id,omitempty"`
+	Strength     string     `json:"strength,omitempty"`
+	Unit         string     `json:"unit,omitempty"`
+	CategoryID   int32      `json:"category_id,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	IRC          string     `json:"irc,omitempty"`
+	GenericCode  string     `json:"generic_code,omitempty"`
+	Controlled   bool       `json:"is_controlled,omitempty"`
+	Schedule     string     `json:"schedule_class,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// StockData is the payload of stock.low, recorded by the scheduled stock
+// check for every product at or below its reorder level
+type StockData struct {
+	ProductID    string `json:"product_id"`
+	Name         string `json:"name"`
+	OnHand       int32  `json:"on_hand"`
+	ReorderLevel int32  `json:"reorder_level"`
+	RequestedQty int64  `json:"requested_qty"` // requested in the last 30 days
+}
+
+// UserData is the payload of user.created and user.updated. Credentials
+// are never included.
+type UserData struct {
+	ID             string `json:"id"`
+	Username       string `json:"username"`
+	FullName       string `json:"full_name,omitempty"`
+	RoleID         int32  `json:"role_id,omitempty"`
+	ServiceAccount bool   `json:"service_account,omitempty"`
+}
+
+// DeletedData is the payload of every *.deleted event
+type DeletedData struct {
+	ID string `json:"id"`
+}
+
+// OrderPayload converts an order row to its event payload
+func OrderPayload(o db.Order) OrderData {
+	data := OrderData{
+		ID:          o.ID.String(),
+		Status:      o.Status,
+		Priority:    o.Priority,
+		Notes:       o.Notes.String,
+		CreatedAt:   nullTime(o.CreatedAt),
+		SubmittedAt: nullTime(o.SubmittedAt),
+	}
+	if o.CreatedBy.Valid {
+		data.CreatedBy = o.CreatedBy.UUID.String()
+	}
+	if o.NeededBy.Valid {
+		data.NeededBy = o.NeededBy.Time.Format(time.DateOnly)
+	}
+	if o.RequesterID.Valid {
+		data.RequesterID = o.RequesterID.UUID.String()
+	}
+	return data
+}
+
+// ProductPayload converts a product row to its event payload
+func ProductPayload(p db.Product) ProductData {
+	return ProductData{
+		ID:           p.ID.String(),
+		Name:         p.Name,
+		Brand:        p.Brand.String,
+		DosageFormID: p.DosageFormID.Int32,
+		Strength:     p.Strength.String,
+		Unit:         p.Unit.String,
+		CategoryID:   p.CategoryID.Int32,
+		Description:  p.Description.String,
+		Status:       p.Status,
+		IRC:          p.Irc.String,
+		GenericCode:  p.GenericCode.String,
+		Controlled:   p.IsControlled,
+		Schedule:     p.ScheduleClass.String,
+		CreatedAt:    nullTime(p.CreatedAt),
+	}
+}
+
+// UserPayload converts a user row to its event payload
+func UserPayload(u db.User) UserData {
+	return UserData{
+		ID:             u.ID.String(),
+		Username:       u.Username,
+		FullName:       u.FullName.String,
+		RoleID:         u.RoleID.Int32,
+		ServiceAccount: u.ServiceAccount,
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
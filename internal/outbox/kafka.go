@@ -0,0 +1,121 @@
+// internal/outbox/kafka.go - Kafka publisher via the REST Proxy API
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaConfig describes a Kafka REST Proxy (Confluent REST Proxy v2 API,
+// also served by Redpanda) and how events map to topics
+type KafkaConfig struct {
+	URL      string // e.g. http://kafka-rest:8082
+	Username string
+	Password string
+	Prefix   string // topic prefix, e.g. "digiorder" -> digiorder.order
+	Timeout  time.Duration
+}
+
+// KafkaPublisher produces each event to the topic of its aggregate
+// ("<prefix>.order", "<prefix>.product", "<prefix>.user"), keyed by the
+// aggregate ID so all changes to one entity land in the same partition and
+// keep their order.
+type KafkaPublisher struct {
+	config KafkaConfig
+	client *http.Client
+}
+
+// NewKafkaPublisher creates a publisher
+func NewKafkaPublisher(config KafkaConfig) (*KafkaPublisher, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Kafka REST Proxy URL %q", config.URL)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &KafkaPublisher{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Name identifies the publisher in logs and metrics
+func (p *KafkaPublisher) Name() string {
+	if u, err := url.Parse(p.config.URL); err == nil {
+		return "kafka:" + u.Host
+	}
+	return "kafka"
+}
+
+// Topic returns the topic an event is produced to
+func (p *KafkaPublisher) Topic(event Event) string {
+	if p.config.Prefix == "" {
+		return event.AggregateType
+	}
+	return p.config.Prefix + "." + event.AggregateType
+}
+
+// Publish produces event and checks the per-record result
+func (p *KafkaPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []map[string]any{{
+			"key":   event.AggregateID,
+			"value": event,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(p.config.URL, "/") + "/topics/" + url.PathEscape(p.Topic(event))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.config.Username != "" {
+		req.SetBasicAuth(p.config.Username, p.config.Password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(raw, &result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if result.Message != "" {
+			return fmt.Errorf("REST proxy returned %d: %s", resp.StatusCode, result.Message)
+		}
+		return fmt.Errorf("REST proxy returned %d", resp.StatusCode)
+	}
+
+	// The proxy answers 200 even when the broker rejected the record
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			return fmt.Errorf("record rejected by broker: %s", offset.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+// internal/outbox/nats.go - NATS / JetStream publisher
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSConfig describes the NATS server and how events map to subjects
+type NATSConfig struct {
+	URL       string // nats://[user:pass@]host:4222 or tls://host:4222
+	Token     string
+	Prefix    string // subject prefix, e.g. "digiorder" -> digiorder.order.created
+	JetStream bool   // wait for a JetStream ack instead of a server flush
+	Timeout   time.Duration
+}
+
+// NATSPublisher publishes events with the NATS client protocol. Each event
+// carries a Nats-Msg-Id header set to the event ID, so a JetStream stream
+// with a duplicate window discards redeliveries from the relay.
+//
+// Only the subset of the protocol needed to publish is implemented, which
+// keeps the dependency footprint at the standard library.
+type NATSPublisher struct {
+	config NATSConfig
+	target *url.URL
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	inbox  string
+	seq    uint64
+}
+
+// natsInfo is the subset of the server INFO message the publisher uses
+type natsInfo struct {
+	Headers     bool `json:"headers"`
+	TLSRequired bool `json:"tls_required"`
+}
+
+// NewNATSPublisher creates a publisher. The connection is opened lazily
+// and re-established after errors.
+func NewNATSPublisher(config NATSConfig) (*NATSPublisher, error) {
+	target, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	switch target.Scheme {
+	case "nats", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported NATS URL scheme %q", target.Scheme)
+	}
+	if target.Port() == "" {
+		target.Host = net.JoinHostPort(target.Hostname(), "4222")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &NATSPublisher{config: config, target: target}, nil
+}
+
+// Name identifies the publisher in logs and metrics
+func (p *NATSPublisher) Name() string {
+	return "nats:" + p.target.Host
+}
+
+// Subject returns the subject an event is published on
+func (p *NATSPublisher) Subject(event Event) string {
+	if p.config.Prefix == "" {
+		return event.Type
+	}
+	return p.config.Prefix + "." + event.Type
+}
+
+// Publish sends event and waits for the server (or JetStream) to confirm it
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A stale connection is only detected on use, so retry once on a
+	// fresh one before reporting failure
+	reused := p.conn != nil
+	err = p.publish(ctx, p.Subject(event), event.ID.String(), body)
+	if err != nil && reused && p.conn == nil {
+		err = p.publish(ctx, p.Subject(event), event.ID.String(), body)
+	}
+	return err
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+	return nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject, msgID string, body []byte) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(p.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	p.conn.SetDeadline(deadline)
+
+	header := "NATS/1.0\r\nNats-Msg-Id: " + msgID + "\r\n\r\n"
+
+	var reply string
+	if p.config.JetStream {
+		p.seq++
+		reply = p.inbox + "." + strconv.FormatUint(p.seq, 10)
+	}
+
+	var cmd strings.Builder
+	cmd.WriteString("HPUB " + subject + " ")
+	if reply != "" {
+		cmd.WriteString(reply + " ")
+	}
+	fmt.Fprintf(&cmd, "%d %d\r\n%s%s\r\n", len(header), len(header)+len(body), header, body)
+	if reply == "" {
+		// Core NATS has no per-message ack; a PONG confirms the server
+		// processed everything written before the PING
+		cmd.WriteString("PING\r\n")
+	}
+
+	if _, err := p.conn.Write([]byte(cmd.String())); err != nil {
+		p.reset()
+		return err
+	}
+
+	if reply == "" {
+		err := p.await(func(line string) (bool, error) { return line == "PONG", nil })
+		if err != nil {
+			p.reset()
+		}
+		return err
+	}
+
+	err := p.await(func(line string) (bool, error) {
+		if !strings.HasPrefix(line, "MSG "+reply+" ") && !strings.HasPrefix(line, "HMSG "+reply+" ") {
+			return false, nil
+		}
+		header, ack, err := p.readPayload(line)
+		if err != nil {
+			return true, err
+		}
+		if strings.HasPrefix(header, "NATS/1.0 503") {
+			return true, &jetStreamError{Code: 503, Description: "no stream matches the subject"}
+		}
+		return true, jetStreamAckError(ack)
+	})
+	if err != nil {
+		var nak *jetStreamError
+		if !errors.As(err, &nak) {
+			p.reset()
+		}
+	}
+	return err
+}
+
+// connect dials the server, performs the CONNECT handshake and subscribes
+// to the reply inbox used for JetStream acks
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: p.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.target.Host)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(p.config.Timeout))
+	reader := bufio.NewReader(conn)
+
+	line, err := readLine(reader)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		conn.Close()
+		return fmt.Errorf("invalid NATS INFO: %w", err)
+	}
+	if !info.Headers {
+		conn.Close()
+		return errors.New("NATS server does not support headers (2.2+ required)")
+	}
+
+	if p.target.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.target.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	options := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "digiorder-outbox",
+		"lang":     "go",
+		"version":  "1.0",
+		"protocol": 1,
+		"headers":  true,
+	}
+	if user := p.target.User; user != nil {
+		options["user"] = user.Username()
+		options["pass"], _ = user.Password()
+	}
+	if p.config.Token != "" {
+		options["auth_token"] = p.config.Token
+	}
+	connect, _ := json.Marshal(options)
+
+	p.conn, p.reader = conn, reader
+	p.inbox = "_INBOX." + randomToken()
+
+	handshake := "CONNECT " + string(connect) + "\r\n"
+	if p.config.JetStream {
+		handshake += "SUB " + p.inbox + ".* 1\r\n"
+	}
+	handshake += "PING\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		p.reset()
+		return err
+	}
+
+	// Authentication failures are reported as -ERR before the PONG
+	if err := p.await(func(line string) (bool, error) { return line == "PONG", nil }); err != nil {
+		p.reset()
+		return err
+	}
+	return nil
+}
+
+// await reads protocol lines until match reports done, answering server
+// PINGs and surfacing -ERR along the way
+func (p *NATSPublisher) await(match func(line string) (bool, error)) error {
+	for {
+		line, err := readLine(p.reader)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+			continue
+		case line == "+OK", strings.HasPrefix(line, "INFO "):
+			continue
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.Trim(strings.TrimPrefix(line, "-ERR"), " '"))
+		}
+
+		done, err := match(line)
+		if done || err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "MSG ") || strings.HasPrefix(line, "HMSG ") {
+			// A late ack for an earlier attempt; discard its payload
+			if _, _, err := p.readPayload(line); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readPayload reads the headers and body announced by a MSG/HMSG line
+func (p *NATSPublisher) readPayload(line string) (string, []byte, error) {
+	fields := strings.Fields(line)
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 {
+		return "", nil, fmt.Errorf("malformed NATS message line %q", line)
+	}
+
+	buf := make([]byte, size+2) // payload + CRLF
+	if _, err := io.ReadFull(p.reader, buf); err != nil {
+		return "", nil, err
+	}
+	if strings.HasPrefix(line, "HMSG ") && len(fields) >= 3 {
+		headerSize, err := strconv.Atoi(fields[len(fields)-2])
+		if err == nil && headerSize >= 0 && headerSize <= size {
+			return string(buf[:headerSize]), buf[headerSize:size], nil
+		}
+	}
+	return "", buf[:size], nil
+}
+
+func (p *NATSPublisher) reset() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.reader = nil, nil
+}
+
+// jetStreamError is a negative ack; the connection stays usable
+type jetStreamError struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+func (e *jetStreamError) Error() string {
+	return fmt.Sprintf("JetStream rejected message (%d): %s", e.Code, e.Description)
+}
+
+func jetStreamAckError(ack []byte) error {
+	var resp struct {
+		Stream string          `json:"stream"`
+		Error  *jetStreamError `json:"error"`
+	}
+	if err := json.Unmarshal(ack, &resp); err != nil {
+		return fmt.Errorf("invalid JetStream ack: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if resp.Stream == "" {
+		return errors.New("no JetStream stream matched the subject")
+	}
+	return nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func randomToken() string {
+	b := make([]byte, 11)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,40 @@
+// internal/outbox/outbox.go - Recording events alongside domain changes
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// Publisher delivers an event to one downstream system. Publish must be
+// safe to call again with the same event: delivery is at-least-once.
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// Record stores an event for later delivery. q should be bound to the
+// transaction that makes the change the event describes, so the event is
+// committed or rolled back together with it.
+func Record(ctx context.Context, q db.Querier, eventType, aggregateID, actor string, data any) error {
+	event, err := NewEvent(eventType, aggregateID, actor, data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	return q.InsertOutboxEvent(ctx, db.InsertOutboxEventParams{
+		ID:            event.ID,
+		EventType:     event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Payload:       payload,
+	})
+}
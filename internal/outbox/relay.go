@@ -0,0 +1,330 @@
+// internal/outbox/relay.go - Background delivery of recorded events
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	outboxPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "Outbox events delivered to every publisher, by event type",
+		},
+		[]string{"type"},
+	)
+
+	outboxFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Failed publish attempts by publisher",
+		},
+		[]string{"publisher"},
+	)
+
+	outboxPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_events_pending",
+			Help: "Outbox events not yet delivered",
+		},
+	)
+)
+
+// RelayConfig controls polling, retries and retention
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Lease        time.Duration // how long a claimed event is hidden from other relays
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	Retention    time.Duration // how long published events are kept
+}
+
+// DefaultRelayConfig returns the settings used when none are configured
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval: 2 * time.Second,
+		BatchSize:    100,
+		Lease:        time.Minute,
+		BaseBackoff:  5 * time.Second,
+		MaxBackoff:   time.Hour,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+// Relay polls the outbox and hands pending events to every publisher. An
+// event is marked published only once all publishers accepted it; failures
+// are retried with exponential backoff, so publishers may see an event more
+// than once and must deduplicate by event ID.
+type Relay struct {
+	queries    db.Querier
+	mu         sync.RWMutex
+	publishers []Publisher
+	config     RelayConfig
+	logger     *logging.Logger
+	heartbeat  *middleware.Heartbeat
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewRelay creates a relay. Call Start to begin delivery.
+func NewRelay(queries db.Querier, config RelayConfig, logger *logging.Logger, publishers ...Publisher) *Relay {
+	defaults := DefaultRelayConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.Lease <= 0 {
+		config.Lease = defaults.Lease
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaults.BaseBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+
+	return &Relay{
+		queries:    queries,
+		publishers: publishers,
+		config:     config,
+		logger:     logger,
+		heartbeat:  middleware.NewHeartbeat("outbox_relay", config.PollInterval),
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start launches the delivery loop
+func (r *Relay) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Notify wakes the relay so a freshly committed event is delivered without
+// waiting for the next poll. It never blocks.
+func (r *Relay) Notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Stop ends the delivery loop, waiting for the current batch until ctx
+// expires. Undelivered events stay in the outbox for the next start.
+func (r *Relay) Stop(ctx context.Context) error {
+	r.once.Do(func() { close(r.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for _, p := range r.currentPublishers() {
+		if closer, ok := p.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
+// SetWebhooks replaces the webhook publishers, keeping the others. An event
+// being delivered finishes with the previous webhooks.
+func (r *Relay) SetWebhooks(webhooks []*WebhookPublisher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	publishers := make([]Publisher, 0, len(webhooks)+len(r.publishers))
+	for _, w := range webhooks {
+		publishers = append(publishers, w)
+	}
+	for _, p := range r.publishers {
+		if _, ok := p.(*WebhookPublisher); !ok {
+			publishers = append(publishers, p)
+		}
+	}
+	r.publishers = publishers
+}
+
+// currentPublishers returns the publishers events are delivered to
+func (r *Relay) currentPublishers() []Publisher {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.publishers
+}
+
+// Heartbeat reports whether the delivery loop is running
+func (r *Relay) Heartbeat() *middleware.Heartbeat {
+	return r.heartbeat
+}
+
+func (r *Relay) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		case <-cleanup.C:
+			r.cleanup()
+			continue
+		}
+
+		r.drain()
+		r.heartbeat.Beat()
+	}
+}
+
+// drain delivers due events until a short batch shows the outbox is empty
+func (r *Relay) drain() {
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		events, err := r.queries.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+			LeaseSeconds: r.config.Lease.Seconds(),
+			BatchSize:    int32(r.config.BatchSize),
+		})
+		cancel()
+		if err != nil {
+			r.logger.Error("Failed to claim outbox events", err, nil)
+			return
+		}
+
+		for _, row := range events {
+			r.deliver(row)
+		}
+
+		if len(events) < r.config.BatchSize {
+			r.updatePending()
+			return
+		}
+	}
+}
+
+// deliver publishes one event and records the outcome
+func (r *Relay) deliver(row db.OutboxEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var event Event
+	if err := json.Unmarshal(row.Payload, &event); err != nil {
+		// Cannot succeed on retry; keep it at the slowest retry rate so
+		// it stays visible in the failure metrics and logs
+		r.fail(ctx, row, err, time.Now().Add(r.config.MaxBackoff))
+		return
+	}
+
+	var errs []error
+	for _, p := range r.currentPublishers() {
+		if err := p.Publish(ctx, event); err != nil {
+			outboxFailuresTotal.WithLabelValues(p.Name()).Inc()
+			errs = append(errs, errors.New(p.Name()+": "+err.Error()))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		r.fail(ctx, row, err, time.Now().Add(r.backoff(row.Attempts)))
+		return
+	}
+
+	if err := r.queries.MarkOutboxEventPublished(ctx, row.ID); err != nil {
+		// The lease expires and the event is delivered again
+		r.logger.Error("Failed to mark outbox event published", err, map[string]any{"event_id": row.ID})
+		return
+	}
+	outboxPublishedTotal.WithLabelValues(row.EventType).Inc()
+}
+
+func (r *Relay) fail(ctx context.Context, row db.OutboxEvent, cause error, next time.Time) {
+	r.logger.Warn("Outbox event delivery failed", map[string]any{
+		"event_id": row.ID,
+		"type":     row.EventType,
+		"attempts": row.Attempts,
+		"error":    cause.Error(),
+		"retry_at": next,
+	})
+
+	err := r.queries.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+		ID:            row.ID,
+		LastError:     sql.NullString{String: cause.Error(), Valid: true},
+		NextAttemptAt: next,
+	})
+	if err != nil {
+		r.logger.Error("Failed to record outbox delivery failure", err, map[string]any{"event_id": row.ID})
+	}
+}
+
+// backoff doubles the base delay per attempt, capped at MaxBackoff
+func (r *Relay) backoff(attempts int32) time.Duration {
+	delay := r.config.BaseBackoff
+	for i := int32(1); i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.config.MaxBackoff)
+}
+
+func (r *Relay) updatePending() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if pending, err := r.queries.CountPendingOutboxEvents(ctx); err == nil {
+		outboxPending.Set(float64(pending))
+	}
+}
+
+// cleanup removes published events older than the retention period
+func (r *Relay) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := r.queries.DeletePublishedOutboxEvents(ctx, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		r.logger.Error("Failed to clean up outbox", err, nil)
+		return
+	}
+	if deleted > 0 {
+		r.logger.Info("Outbox cleanup completed", map[string]any{"deleted": deleted})
+	}
+}
@@ -0,0 +1,82 @@
+// internal/outbox/webhook.go - Signed HTTP webhook publisher
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/signature"
+)
+
+// WebhookPublisher POSTs each event as JSON to one endpoint. When a secret
+// is set the request is signed (see the signature package), so receivers
+// can verify its origin and reject replays; the event id, kept across
+// retries, lets them drop duplicates.
+type WebhookPublisher struct {
+	endpoint string
+	secret   []byte
+	client   *http.Client
+}
+
+// NewWebhookPublisher creates a publisher for endpoint
+func NewWebhookPublisher(endpoint, secret string, timeout time.Duration) *WebhookPublisher {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookPublisher{
+		endpoint: endpoint,
+		secret:   []byte(secret),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Name identifies the endpoint in logs and metrics without its path or
+// query, which may carry tokens
+func (w *WebhookPublisher) Name() string {
+	if u, err := url.Parse(w.endpoint); err == nil {
+		return "webhook:" + u.Host
+	}
+	return "webhook"
+}
+
+// Publish delivers event; any non-2xx response is an error
+func (w *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DigiOrder-Webhook/1")
+	req.Header.Set("X-DigiOrder-Event", event.Type)
+	req.Header.Set(signature.HeaderEventID, event.ID.String())
+	req.Header.Set(signature.HeaderTimestamp, timestamp)
+	if len(w.secret) > 0 {
+		req.Header.Set(signature.HeaderSignature, signature.Header(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
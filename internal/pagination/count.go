@@ -0,0 +1,109 @@
+// internal/pagination/count.go - Cached totals of paged lists
+package pagination
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// maxCountEntries bounds the cached totals; expired ones are dropped
+// when it is reached, and every one if that is not enough
+const maxCountEntries = 10000
+
+// Total is the number of rows a list holds across its pages
+type Total struct {
+	Count     int64
+	Estimated bool // the planner's estimate for the table, not a count
+}
+
+// Counter caches list totals for a short while, so paging through a list
+// counts its rows once instead of on every page. An unfiltered list of a
+// table above the threshold is not counted at all: its total is the
+// planner's row estimate, which costs nothing to read.
+type Counter struct {
+	queries   db.Querier
+	ttl       time.Duration // 0 counts on every call
+	threshold int64         // 0 always counts
+
+	mu      sync.Mutex
+	entries map[string]countEntry
+}
+
+type countEntry struct {
+	total   Total
+	expires time.Time
+}
+
+// NewCounter creates a counter keeping totals for ttl and estimating
+// unfiltered lists of tables with more than threshold rows
+func NewCounter(queries db.Querier, ttl time.Duration, threshold int64) *Counter {
+	return &Counter{
+		queries:   queries,
+		ttl:       ttl,
+		threshold: threshold,
+		entries:   make(map[string]countEntry),
+	}
+}
+
+// Count returns the total of a list of table. filter tells lists of the
+// table apart, "" for the unfiltered one, and count counts its rows.
+// Totals are kept per tenant, department and order owner, as row level
+// security gives each its own. Only a session seeing every tenant gets an
+// estimate, since the planner's covers the whole table.
+func (c *Counter) Count(ctx context.Context, table, filter string, count func(context.Context) (int64, error)) (Total, error) {
+	scope, _ := db.TenantFromContext(ctx)
+	key := table + "\x00" + scope.ID.String() + "\x00" + strconv.Itoa(int(scope.DepartmentID)) + "\x00" + scope.OwnerID.String() + "\x00" + filter
+
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.total, nil
+	}
+
+	var total Total
+	if filter == "" && c.threshold > 0 && scope.ID == uuid.Nil && scope.DepartmentID == 0 && scope.OwnerID == uuid.Nil {
+		estimate, err := c.queries.EstimateRowCount(ctx, table)
+		if err != nil {
+			return Total{}, err
+		}
+		if estimate > c.threshold {
+			total = Total{Count: estimate, Estimated: true}
+		}
+	}
+	if !total.Estimated {
+		n, err := count(ctx)
+		if err != nil {
+			return Total{}, err
+		}
+		total = Total{Count: n}
+	}
+
+	if c.ttl > 0 {
+		c.store(key, countEntry{total: total, expires: now.Add(c.ttl)}, now)
+	}
+	return total, nil
+}
+
+// store keeps entry under key, making room first when the cache is full
+func (c *Counter) store(key string, entry countEntry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCountEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCountEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = entry
+}
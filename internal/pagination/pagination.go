@@ -0,0 +1,104 @@
+// internal/pagination/pagination.go - Keyset pagination cursors
+package pagination
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Page sizes of list endpoints
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is returned for a cursor this package did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position after a row in a list ordered newest first by
+// created_at, with the row ID breaking ties. Lists seek past it through
+// the (created_at, id) index instead of counting skipped rows, so a deep
+// page costs as much as the first.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// cursorLen is the length of an encoded cursor: microseconds since the
+// epoch, which is PostgreSQL's precision, followed by the ID
+const cursorLen = 8 + 16
+
+// Encode writes the cursor as an opaque URL-safe token
+func (c Cursor) Encode() string {
+	var b [cursorLen]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(c.CreatedAt.UnixMicro()))
+	copy(b[8:], c.ID[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// Decode reads a token written by Encode
+func Decode(token string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != cursorLen {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	c.CreatedAt = time.UnixMicro(int64(binary.BigEndian.Uint64(b[:8]))).UTC()
+	copy(c.ID[:], b[8:])
+	return c, nil
+}
+
+// ClampLimit brings a requested page size into [1, MaxLimit], giving
+// DefaultLimit when none was asked for
+func ClampLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultLimit
+	case limit > MaxLimit:
+		return MaxLimit
+	default:
+		return limit
+	}
+}
+
+// Page is the slice of a list a request asks for: Limit rows after the
+// cursor when one is given, otherwise after skipping Offset rows. A list
+// in an order the request chose (Sorted) is paged by offset only, as
+// cursors follow the default order.
+type Page struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+	Sorted bool
+}
+
+// AfterTime is the cursor's created_at as a query argument, NULL for the
+// first page
+func (p Page) AfterTime() sql.NullTime {
+	if p.After == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: p.After.CreatedAt, Valid: true}
+}
+
+// AfterID is the cursor's ID as a query argument, NULL for the first page
+func (p Page) AfterID() uuid.NullUUID {
+	if p.After == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.After.ID, Valid: true}
+}
+
+// Next is the cursor of the page after rows, or "" when rows is the last
+// page or the list is sorted; key gives a row's position
+func Next[T any](p Page, rows []T, key func(T) Cursor) string {
+	if p.Sorted || len(rows) == 0 || len(rows) < p.Limit {
+		return ""
+	}
+	return key(rows[len(rows)-1]).Encode()
+}
@@ -0,0 +1,92 @@
+// internal/pagination/sort.go - Caller-chosen order of list endpoints
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxSortFields is how many fields a list can be sorted by at once
+const MaxSortFields = 5
+
+// ErrInvalidSort is wrapped by the errors of ParseSort
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortColumns maps the names a list can be sorted by to their columns.
+// Columns only ever come from here, never from the request.
+type SortColumns map[string]string
+
+// SortField is one field of a sort, with the column it names
+type SortField struct {
+	Name   string
+	Column string
+	Desc   bool
+}
+
+// Sort is the order a list request asks for, e.g. ?sort=-created_at,name:
+// fields in order of precedence, each ascending unless prefixed with "-".
+// The zero Sort keeps the list's default order.
+type Sort []SortField
+
+// ParseSort reads a sort parameter against the columns a list allows
+func ParseSort(raw string, columns SortColumns) (Sort, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) > MaxSortFields {
+		return nil, fmt.Errorf("%w: at most %d fields", ErrInvalidSort, MaxSortFields)
+	}
+
+	sort := make(Sort, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		name := strings.TrimSpace(part)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "-"), "+")
+		column, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %q is given twice", ErrInvalidSort, name)
+		}
+		seen[name] = true
+		sort = append(sort, SortField{Name: name, Column: column, Desc: desc})
+	}
+	return sort, nil
+}
+
+// OrderBy renders the sort as an ORDER BY list, empty values last,
+// followed by tieBreaker unless the sort already has that column, so
+// rows that compare equal keep the same order from page to page
+func (s Sort) OrderBy(tieBreaker string) string {
+	order := make([]string, 0, len(s)+1)
+	tied := false
+	for _, f := range s {
+		dir := "ASC"
+		if f.Desc {
+			dir = "DESC"
+		}
+		order = append(order, f.Column+" "+dir+" NULLS LAST")
+		tied = tied || f.Column == tieBreaker
+	}
+	if !tied && tieBreaker != "" {
+		order = append(order, tieBreaker)
+	}
+	return strings.Join(order, ", ")
+}
+
+// Names lists the names the columns can be sorted by, in order, for
+// error messages
+func (c SortColumns) Names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,265 @@
+// internal/pdf/bidi.go - Right-to-left text reordering
+package pdf
+
+import "unicode"
+
+// Bidirectional character types, as in the Unicode Bidirectional Algorithm
+type bidiClass uint8
+
+const (
+	classON  bidiClass = iota // other neutral, including white space
+	classL                    // left-to-right letter
+	classR                    // Hebrew letter
+	classAL                   // Arabic letter
+	classEN                   // European digit
+	classAN                   // Arabic digit
+	classES                   // plus and minus
+	classET                   // currency and percent signs
+	classCS                   // separators within numbers
+	classNSM                  // combining mark
+)
+
+// classify returns the bidirectional type of r, simplified to the scripts
+// the documents hold
+func classify(r rune) bidiClass {
+	switch {
+	case r >= '0' && r <= '9', r >= '۰' && r <= '۹':
+		return classEN
+	case r >= '٠' && r <= '٩', r == '٫', r == '٬', r >= 0x600 && r <= 0x605:
+		return classAN
+	case r == '+' || r == '-':
+		return classES
+	case r == '#' || r == '$' || r == '%' || r == '°' || r == '٪' ||
+		(r >= '¢' && r <= '¥') || (r >= 0x20a0 && r <= 0x20cf):
+		return classET
+	case r == ',' || r == '.' || r == ':' || r == '/' || r == 0xa0 || r == '،':
+		return classCS
+	case r == 0x200e:
+		return classL
+	case r == 0x200f:
+		return classR
+	case unicode.Is(unicode.Mn, r):
+		return classNSM
+	case r >= 0x590 && r <= 0x5ff, r >= 0xfb1d && r <= 0xfb4f:
+		return classR
+	case r >= 0x600 && r <= 0x6ff, r >= 0x750 && r <= 0x77f, r >= 0x8a0 && r <= 0x8ff,
+		r >= 0xfb50 && r <= 0xfdff, r >= 0xfe70 && r <= 0xfefe:
+		return classAL
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		return classL
+	default:
+		return classON
+	}
+}
+
+// mirrors pairs the characters shown mirrored in right-to-left runs
+var mirrors = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{',
+	'<': '>', '>': '<', '«': '»', '»': '«', '‹': '›', '›': '‹',
+}
+
+// rtl reports whether the first strong character of runes is
+// right-to-left, which makes the text a right-to-left paragraph
+func rtl(runes []rune) bool {
+	for _, r := range runes {
+		switch classify(r) {
+		case classL:
+			return false
+		case classR, classAL:
+			return true
+		}
+	}
+	return false
+}
+
+// visual reorders a line from logical to display order. It follows the
+// Unicode Bidirectional Algorithm for a single paragraph without explicit
+// embeddings or isolates, whose direction is that of its first strong
+// character: numbers keep their digit order inside right-to-left text,
+// neutrals take the direction of the text around them and brackets in
+// right-to-left runs are mirrored. Directional marks are dropped.
+func visual(runes []rune) []rune {
+	if !hasRTL(runes) {
+		return dropMarks(runes)
+	}
+	base := classL
+	if rtl(runes) {
+		base = classR
+	}
+
+	types := make([]bidiClass, len(runes))
+	for i, r := range runes {
+		types[i] = classify(r)
+	}
+
+	// W1-W3: marks take the type before them, digits after Arabic letters
+	// are Arabic, Arabic letters are right-to-left
+	prev, strong := base, base
+	for i, t := range types {
+		if t == classNSM {
+			t = prev
+		}
+		switch t {
+		case classL, classR, classAL:
+			strong = t
+		case classEN:
+			if strong == classAL {
+				t = classAN
+			}
+		}
+		if t == classAL {
+			t = classR
+		}
+		types[i], prev = t, t
+	}
+
+	// W4: a single separator between two numbers of a type joins them
+	for i := 1; i+1 < len(types); i++ {
+		a, b := types[i-1], types[i+1]
+		switch {
+		case types[i] == classES && a == classEN && b == classEN:
+			types[i] = classEN
+		case types[i] == classCS && a == b && (a == classEN || a == classAN):
+			types[i] = a
+		}
+	}
+
+	// W5: currency and percent signs next to European numbers join them
+	for i := 0; i < len(types); {
+		if types[i] != classET {
+			i++
+			continue
+		}
+		j := i
+		for j < len(types) && types[j] == classET {
+			j++
+		}
+		if (i > 0 && types[i-1] == classEN) || (j < len(types) && types[j] == classEN) {
+			for k := i; k < j; k++ {
+				types[k] = classEN
+			}
+		}
+		i = j
+	}
+
+	// W6-W7: leftover separators are neutral, and European numbers in
+	// left-to-right text are left-to-right
+	strong = base
+	for i, t := range types {
+		switch t {
+		case classES, classET, classCS:
+			types[i] = classON
+		case classL, classR:
+			strong = t
+		case classEN:
+			if strong == classL {
+				types[i] = classL
+			}
+		}
+	}
+
+	// N1-N2: neutrals between text of one direction take it, numbers
+	// counting as right-to-left; others take the paragraph direction
+	for i := 0; i < len(types); {
+		if types[i] != classON {
+			i++
+			continue
+		}
+		j := i
+		for j < len(types) && types[j] == classON {
+			j++
+		}
+		before, after := base, base
+		if i > 0 {
+			before = strongDirection(types[i-1])
+		}
+		if j < len(types) {
+			after = strongDirection(types[j])
+		}
+		dir := base
+		if before == after {
+			dir = before
+		}
+		for k := i; k < j; k++ {
+			types[k] = dir
+		}
+		i = j
+	}
+
+	// I1-I2: resolve levels
+	levels := make([]int, len(types))
+	for i, t := range types {
+		switch {
+		case base == classL && t == classR:
+			levels[i] = 1
+		case base == classL && (t == classEN || t == classAN):
+			levels[i] = 2
+		case base == classR && t == classR:
+			levels[i] = 1
+		case base == classR:
+			levels[i] = 2
+		}
+	}
+
+	// L4: mirror brackets at odd levels
+	out := make([]rune, len(runes))
+	for i, r := range runes {
+		if m, ok := mirrors[r]; ok && levels[i]%2 == 1 {
+			r = m
+		}
+		out[i] = r
+	}
+
+	// L2: from the highest level down to the lowest odd one, reverse every
+	// run at that level or above
+	for level := 2; level >= 1; level-- {
+		for i := 0; i < len(out); {
+			if levels[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(out) && levels[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				out[a], out[b] = out[b], out[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+
+	return dropMarks(out)
+}
+
+// dropMarks removes the left-to-right and right-to-left marks, which
+// have done their work once the text is reordered
+func dropMarks(runes []rune) []rune {
+	kept := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		if r != 0x200e && r != 0x200f {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// hasRTL reports whether runes hold any right-to-left character
+func hasRTL(runes []rune) bool {
+	for _, r := range runes {
+		if c := classify(r); c == classR || c == classAL || c == classAN {
+			return true
+		}
+	}
+	return false
+}
+
+// strongDirection is the direction a resolved type lends neighbouring
+// neutrals
+func strongDirection(t bidiClass) bidiClass {
+	if t == classL {
+		return classL
+	}
+	return classR
+}
@@ -0,0 +1,474 @@
+// internal/pdf/font.go - TrueType fonts embedded in documents
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrInvalidFont is returned for files that are not TrueType fonts
+var ErrInvalidFont = errors.New("not a TrueType font")
+
+// Font is a TrueType font. Only the tables needed to measure text and
+// embed glyphs are read; a document embeds just the glyphs it uses.
+type Font struct {
+	name       string
+	tables     map[string][]byte
+	unitsPerEm int
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+	advances   []uint16 // by glyph ID; the last one repeats
+	numGlyphs  int
+	cmap       map[rune]uint16
+}
+
+// Fonts are the regular and bold faces of a document. The zero value
+// uses the standard Helvetica fonts, which cover Windows-1252 only.
+type Fonts struct {
+	Regular *Font
+	Bold    *Font // Regular when nil
+}
+
+// Embedded reports whether the fonts are TrueType fonts rather than the
+// standard ones
+func (f Fonts) Embedded() bool {
+	return f.Regular != nil
+}
+
+// face returns the font to draw with, nil for the standard fonts
+func (f Fonts) face(bold bool) *Font {
+	if bold && f.Bold != nil {
+		return f.Bold
+	}
+	return f.Regular
+}
+
+// LoadFonts reads the regular and bold font files. No regular font means
+// the standard fonts; no bold font means bold text uses the regular one.
+func LoadFonts(regular, bold string) (Fonts, error) {
+	var fonts Fonts
+	if regular == "" {
+		return fonts, nil
+	}
+	var err error
+	if fonts.Regular, err = LoadFont(regular); err != nil {
+		return Fonts{}, err
+	}
+	if bold != "" {
+		if fonts.Bold, err = LoadFont(bold); err != nil {
+			return Fonts{}, err
+		}
+	}
+	return fonts, nil
+}
+
+// LoadFont reads a TrueType font file
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseFont reads a TrueType font. Collections and OpenType fonts with
+// CFF outlines are not supported.
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, ErrInvalidFont
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+	case "OTTO":
+		return nil, fmt.Errorf("%w: CFF outlines are not supported", ErrInvalidFont)
+	default:
+		return nil, ErrInvalidFont
+	}
+
+	f := &Font{tables: map[string][]byte{}}
+	count := int(u16(data, 4))
+	if len(data) < 12+16*count {
+		return nil, ErrInvalidFont
+	}
+	for i := range count {
+		rec := data[12+16*i:]
+		tag := string(rec[:4])
+		off, length := int(u32(rec, 8)), int(u32(rec, 12))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, fmt.Errorf("%w: table %q out of bounds", ErrInvalidFont, tag)
+		}
+		f.tables[tag] = data[off : off+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "loca", "glyf"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("%w: no %s table", ErrInvalidFont, tag)
+		}
+	}
+
+	head, hhea, maxp := f.tables["head"], f.tables["hhea"], f.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidFont)
+	}
+	f.unitsPerEm = int(u16(head, 18))
+	if f.unitsPerEm == 0 {
+		return nil, fmt.Errorf("%w: no units per em", ErrInvalidFont)
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(u16(head, 36+2*i)))
+	}
+	f.ascent = int(int16(u16(hhea, 4)))
+	f.descent = int(int16(u16(hhea, 6)))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		f.capHeight = int(int16(u16(os2, 88)))
+	}
+	f.numGlyphs = int(u16(maxp, 4))
+
+	metrics := int(u16(hhea, 34))
+	hmtx := f.tables["hmtx"]
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, fmt.Errorf("%w: truncated hmtx table", ErrInvalidFont)
+	}
+	f.advances = make([]uint16, metrics)
+	for i := range f.advances {
+		f.advances[i] = u16(hmtx, 4*i)
+	}
+
+	var err error
+	if f.cmap, err = parseCmap(f.tables["cmap"]); err != nil {
+		return nil, err
+	}
+	f.name = postScriptName(f.tables["name"])
+	return f, nil
+}
+
+// has reports whether the font has a glyph for r
+func (f *Font) has(r rune) bool {
+	_, ok := f.cmap[r]
+	return ok
+}
+
+// glyph is the glyph ID of r, falling back to '?' and then .notdef
+func (f *Font) glyph(r rune) uint16 {
+	if g, ok := f.cmap[r]; ok {
+		return g
+	}
+	return f.cmap['?']
+}
+
+// width is the advance of glyph g in thousandths of the font size
+func (f *Font) width(g uint16) float64 {
+	adv := f.advances[len(f.advances)-1]
+	if int(g) < len(f.advances) {
+		adv = f.advances[g]
+	}
+	return float64(adv) * 1000 / float64(f.unitsPerEm)
+}
+
+// scale converts font units to thousandths of the font size
+func (f *Font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// parseCmap reads the Unicode mapping, preferring a full-repertoire
+// format 12 subtable to a BMP format 4 one
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, fmt.Errorf("%w: truncated cmap table", ErrInvalidFont)
+	}
+	var bmp, full []byte
+	count := int(u16(cmap, 2))
+	for i := range count {
+		rec := 4 + 8*i
+		if rec+8 > len(cmap) {
+			break
+		}
+		platform, encoding, off := u16(cmap, rec), u16(cmap, rec+2), int(u32(cmap, rec+4))
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode || off+4 > len(cmap) {
+			continue
+		}
+		sub := cmap[off:]
+		switch u16(sub, 0) {
+		case 4:
+			bmp = sub
+		case 12:
+			full = sub
+		}
+	}
+
+	glyphs := map[rune]uint16{}
+	switch {
+	case full != nil:
+		if len(full) < 16 {
+			break
+		}
+		groups := int(u32(full, 12))
+		for i := range groups {
+			g := 16 + 12*i
+			if g+12 > len(full) {
+				break
+			}
+			start, end, glyph := u32(full, g), u32(full, g+4), u32(full, g+8)
+			for c := start; c <= end && c <= 0x10ffff; c++ {
+				glyphs[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case bmp != nil:
+		if len(bmp) < 14 {
+			break
+		}
+		segs := int(u16(bmp, 6)) / 2
+		ends, starts := 14, 16+2*segs
+		deltas, ranges := starts+2*segs, starts+4*segs
+		if ranges+2*segs > len(bmp) {
+			return nil, fmt.Errorf("%w: truncated cmap subtable", ErrInvalidFont)
+		}
+		for i := range segs {
+			start, end := u16(bmp, starts+2*i), u16(bmp, ends+2*i)
+			delta, rangeOff := u16(bmp, deltas+2*i), int(u16(bmp, ranges+2*i))
+			for c := int(start); c <= int(end) && c != 0xffff; c++ {
+				var g uint16
+				if rangeOff == 0 {
+					g = uint16(c) + delta
+				} else {
+					at := ranges + 2*i + rangeOff + 2*(c-int(start))
+					if at+2 > len(bmp) {
+						continue
+					}
+					if g = u16(bmp, at); g != 0 {
+						g += delta
+					}
+				}
+				if g != 0 {
+					glyphs[rune(c)] = g
+				}
+			}
+		}
+	}
+	if len(glyphs) == 0 {
+		return nil, fmt.Errorf("%w: no Unicode cmap", ErrInvalidFont)
+	}
+	return glyphs, nil
+}
+
+// postScriptName reads name ID 6, as PDF font names may not hold spaces
+func postScriptName(name []byte) string {
+	if len(name) >= 6 {
+		count, storage := int(u16(name, 2)), int(u16(name, 4))
+		for i := range count {
+			rec := 6 + 12*i
+			if rec+12 > len(name) {
+				break
+			}
+			platform, id := u16(name, rec), u16(name, rec+6)
+			length, off := int(u16(name, rec+8)), storage+int(u16(name, rec+10))
+			if id != 6 || off+length > len(name) {
+				continue
+			}
+			raw := name[off : off+length]
+			var s string
+			switch platform {
+			case 1:
+				s = string(raw)
+			case 0, 3:
+				units := make([]uint16, len(raw)/2)
+				for j := range units {
+					units[j] = u16(raw, 2*j)
+				}
+				s = string(utf16.Decode(units))
+			default:
+				continue
+			}
+			if s = pdfName(s); s != "" {
+				return s
+			}
+		}
+	}
+	return "Embedded"
+}
+
+// pdfName keeps the characters of s allowed unescaped in a PDF name
+func pdfName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Composite glyph flags
+const (
+	argsAreWords   = 0x0001
+	haveScale      = 0x0008
+	moreComponents = 0x0020
+	haveXYScale    = 0x0040
+	haveTwoByTwo   = 0x0080
+)
+
+// subset returns the font program with every glyph but those used, and
+// the components they are built from, emptied. Glyph IDs are unchanged,
+// so the document can address glyphs by their ID.
+func (f *Font) subset(used map[uint16]bool) ([]byte, error) {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	offsets := make([]int, f.numGlyphs+1)
+	long := int16(u16(f.tables["head"], 50)) == 1
+	for i := range offsets {
+		switch {
+		case long && 4*i+4 <= len(loca):
+			offsets[i] = int(u32(loca, 4*i))
+		case !long && 2*i+2 <= len(loca):
+			offsets[i] = 2 * int(u16(loca, 2*i))
+		default:
+			return nil, fmt.Errorf("%w: truncated loca table", ErrInvalidFont)
+		}
+		if offsets[i] > len(glyf) || (i > 0 && offsets[i] < offsets[i-1]) {
+			return nil, fmt.Errorf("%w: bad glyph offset", ErrInvalidFont)
+		}
+	}
+
+	keep := map[uint16]bool{0: true}
+	queue := make([]uint16, 0, len(used))
+	for g := range used {
+		queue = append(queue, g)
+	}
+	for len(queue) > 0 {
+		g := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if keep[g] || int(g) >= f.numGlyphs {
+			continue
+		}
+		keep[g] = true
+		data := glyf[offsets[g]:offsets[g+1]]
+		if len(data) < 10 || int16(u16(data, 0)) >= 0 {
+			continue
+		}
+		for at := 10; at+4 <= len(data); {
+			flags := u16(data, at)
+			queue = append(queue, u16(data, at+2))
+			at += 4
+			if flags&argsAreWords != 0 {
+				at += 4
+			} else {
+				at += 2
+			}
+			switch {
+			case flags&haveScale != 0:
+				at += 2
+			case flags&haveXYScale != 0:
+				at += 4
+			case flags&haveTwoByTwo != 0:
+				at += 8
+			}
+			if flags&moreComponents == 0 {
+				break
+			}
+		}
+	}
+
+	var newGlyf []byte
+	newLoca := make([]byte, 4*(f.numGlyphs+1))
+	for g := range f.numGlyphs {
+		binary.BigEndian.PutUint32(newLoca[4*g:], uint32(len(newGlyf)))
+		if keep[uint16(g)] {
+			newGlyf = append(newGlyf, glyf[offsets[g]:offsets[g+1]]...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*f.numGlyphs:], uint32(len(newGlyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{"glyf": newGlyf, "loca": newLoca, "head": head}
+	for _, tag := range []string{"cmap", "cvt ", "fpgm", "hhea", "hmtx", "maxp", "prep"} {
+		if t, ok := f.tables[tag]; ok {
+			tables[tag] = t
+		}
+	}
+	program := writeFont(tables)
+	sum := 0xb1b0afba - checksum(program)
+	binary.BigEndian.PutUint32(program[headOffset(program)+8:], sum)
+	return program, nil
+}
+
+// writeFont assembles a TrueType file from its tables
+func writeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	search := 1
+	selector := 0
+	for search*2 <= n {
+		search *= 2
+		selector++
+	}
+	out := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(n))
+	binary.BigEndian.PutUint16(out[6:], uint16(16*search))
+	binary.BigEndian.PutUint16(out[8:], uint16(selector))
+	binary.BigEndian.PutUint16(out[10:], uint16(16*(n-search)))
+	for i, tag := range tags {
+		data := tables[tag]
+		rec := out[12+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], checksum(data))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(data)))
+		out = append(out, data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// headOffset finds the head table in a file written by writeFont
+func headOffset(program []byte) int {
+	for i := range int(u16(program, 4)) {
+		rec := program[12+16*i:]
+		if string(rec[:4]) == "head" {
+			return int(u32(rec, 8))
+		}
+	}
+	return 0
+}
+
+// checksum sums data as big-endian 32-bit words, zero padded
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func u16(b []byte, off int) uint16 {
+	return binary.BigEndian.Uint16(b[off:])
+}
+
+func u32(b []byte, off int) uint32 {
+	return binary.BigEndian.Uint32(b[off:])
+}
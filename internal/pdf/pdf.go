@@ -0,0 +1,363 @@
+// internal/pdf/pdf.go - PDF documents with right-to-left text
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+)
+
+// A4 portrait in points, with the margin around the content
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+	Margin     = 40.0
+)
+
+// Options configure a document
+type Options struct {
+	Fonts    Fonts
+	Footer   string         // pages are footed "<Footer> - page N"
+	Jalali   bool           // Date and DateTime add the Jalali date
+	Location *time.Location // zone of Date and DateTime, UTC when nil
+}
+
+// Document lays out lines of text and rules on A4 pages. Text is shaped
+// and reordered for display, so Persian and Arabic read right to left
+// with numbers and Latin words inside them left to right. Persian text
+// needs embedded TrueType fonts; with the standard fonts anything outside
+// Windows-1252 prints as '?'.
+type Document struct {
+	Y float64 // baseline of the next line on the current page
+
+	opts  Options
+	pages []*bytes.Buffer
+	used  map[*Font]map[uint16][]rune // glyphs drawn, and the text they show
+}
+
+// New starts a document on its first page
+func New(opts Options) *Document {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	d := &Document{opts: opts, used: map[*Font]map[uint16][]rune{}}
+	d.NewPage()
+	return d
+}
+
+// NewPage starts a page, placing Y below the top margin
+func (d *Document) NewPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.Y = PageHeight - Margin - 12
+	if d.opts.Footer != "" {
+		d.Text(Margin, Margin-16, 8, false, fmt.Sprintf("%s - page %d", d.opts.Footer, len(d.pages)))
+	}
+}
+
+// Ensure starts a new page unless height fits above the bottom margin,
+// reporting whether it did
+func (d *Document) Ensure(height float64) bool {
+	if d.Y-height >= Margin {
+		return false
+	}
+	d.NewPage()
+	return true
+}
+
+// Text draws s with its start at x
+func (d *Document) Text(x, y, size float64, bold bool, s string) {
+	d.draw(x, y, size, bold, d.layout(bold, s))
+}
+
+// TextRight draws s with its end at x
+func (d *Document) TextRight(x, y, size float64, bold bool, s string) {
+	runes := d.layout(bold, s)
+	d.draw(x-d.measure(bold, runes)*size/1000, y, size, bold, runes)
+}
+
+// Paragraph draws s from the left margin, or up to the right margin when
+// it is right-to-left text
+func (d *Document) Paragraph(y, size float64, bold bool, s string) {
+	if RTL(s) {
+		d.TextRight(PageWidth-Margin, y, size, bold, s)
+		return
+	}
+	d.Text(Margin, y, size, bold, s)
+}
+
+// Row draws one table row from x, each cell truncated to its column
+// width. Right-to-left cells are aligned to the right of their column.
+func (d *Document) Row(x float64, widths []float64, size float64, bold bool, cells []string) {
+	for i, w := range widths {
+		if i < len(cells) && cells[i] != "" {
+			cell := d.Fit(cells[i], w-4, size, bold)
+			if RTL(cell) {
+				d.TextRight(x+w-4, d.Y, size, bold, cell)
+			} else {
+				d.Text(x, d.Y, size, bold, cell)
+			}
+		}
+		x += w
+	}
+}
+
+// Line draws a rule
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Width is how wide s is drawn, in points
+func (d *Document) Width(s string, size float64, bold bool) float64 {
+	return d.measure(bold, d.layout(bold, s)) * size / 1000
+}
+
+// Fit shortens s with an ellipsis until it is at most width points wide
+func (d *Document) Fit(s string, width, size float64, bold bool) string {
+	if d.Width(s, size, bold) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		cut := string(runes[:n]) + "…"
+		if d.Width(cut, size, bold) <= width {
+			return cut
+		}
+	}
+	return ""
+}
+
+// Date formats the date of t in the document's zone, followed by its
+// Jalali date in parentheses when the document shows them
+func (d *Document) Date(t time.Time) string {
+	t = t.In(d.opts.Location)
+	s := t.Format(time.DateOnly)
+	if d.opts.Jalali {
+		s += " (" + jalali.FormatDate(t) + ")"
+	}
+	return s
+}
+
+// DateTime formats t to the minute as Date does, with its zone
+func (d *Document) DateTime(t time.Time) string {
+	t = t.In(d.opts.Location)
+	s := t.Format("2006-01-02 15:04 MST")
+	if d.opts.Jalali {
+		s += " (" + jalali.FormatDate(t) + ")"
+	}
+	return s
+}
+
+// RTL reports whether s is right-to-left text, going by its first letter
+func RTL(s string) bool {
+	return rtl([]rune(s))
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// layout shapes s and puts it in display order
+func (d *Document) layout(bold bool, s string) []rune {
+	has := func(rune) bool { return false }
+	if f := d.opts.Fonts.face(bold); f != nil {
+		has = f.has
+	}
+	return visual(shape([]rune(s), has))
+}
+
+// measure sums the advances of runes in thousandths of the font size
+func (d *Document) measure(bold bool, runes []rune) float64 {
+	f := d.opts.Fonts.face(bold)
+	var w float64
+	for _, r := range runes {
+		if f != nil {
+			w += f.width(f.glyph(r))
+		} else {
+			w += helveticaWidth(r, bold)
+		}
+	}
+	return w
+}
+
+// draw writes runes, already in display order, as one text object
+func (d *Document) draw(x, y, size float64, bold bool, runes []rune) {
+	name := "F1"
+	if bold {
+		name = "F2"
+	}
+	f := d.opts.Fonts.face(bold)
+	if f == nil {
+		fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", name, size, x, y, winAnsiString(runes))
+		return
+	}
+
+	used := d.used[f]
+	if used == nil {
+		used = map[uint16][]rune{}
+		d.used[f] = used
+	}
+	var hex strings.Builder
+	for _, r := range runes {
+		g := f.glyph(r)
+		if _, ok := used[g]; !ok && g != 0 {
+			if base, ok := presentationBase[r]; ok {
+				used[g] = base
+			} else {
+				used[g] = []rune{r}
+			}
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td <%s> Tj ET\n", name, size, x, y, hex.String())
+}
+
+// Bytes assembles the document: catalog, page tree, fonts, then a page
+// and content stream object per page, followed by the xref table
+func (d *Document) Bytes() []byte {
+	w := &writer{}
+	catalog, pages := w.reserve(), w.reserve()
+
+	var fonts [2]int
+	if d.opts.Fonts.Embedded() {
+		refs := map[*Font]int{}
+		for i, bold := range []bool{false, true} {
+			f := d.opts.Fonts.face(bold)
+			if refs[f] == 0 {
+				refs[f] = w.font(f, d.used[f])
+			}
+			fonts[i] = refs[f]
+		}
+	} else {
+		fonts[0] = w.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+		fonts[1] = w.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	}
+
+	kids := make([]string, len(d.pages))
+	for i, content := range d.pages {
+		stream := w.stream("", content.Bytes())
+		page := w.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, PageWidth, PageHeight, fonts[0], fonts[1], stream))
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	return w.bytes()
+}
+
+// writer collects numbered objects, object n at index n-1
+type writer struct {
+	objects [][]byte
+}
+
+func (w *writer) reserve() int {
+	w.objects = append(w.objects, nil)
+	return len(w.objects)
+}
+
+func (w *writer) set(n int, body string) {
+	w.objects[n-1] = []byte(body)
+}
+
+func (w *writer) add(body string) int {
+	n := w.reserve()
+	w.set(n, body)
+	return n
+}
+
+// stream adds a compressed stream; dict holds entries besides its length
+// and filter
+func (w *writer) stream(dict string, data []byte) int {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+	return w.add(fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n%s\nendstream", z.Len(), dict, z.Bytes()))
+}
+
+// font adds f as a Type 0 font addressing glyphs by ID, embedding the
+// glyphs in used with their widths and the text they show
+func (w *writer) font(f *Font, used map[uint16][]rune) int {
+	glyphs := make([]uint16, 0, len(used))
+	keep := map[uint16]bool{}
+	for g := range used {
+		glyphs = append(glyphs, g)
+		keep[g] = true
+	}
+	slices.Sort(glyphs)
+
+	// The subset tag names the glyph set, so different subsets of one font
+	// are told apart
+	sum := crc32.NewIEEE()
+	var widths, cmap strings.Builder
+	for i, g := range glyphs {
+		fmt.Fprintf(sum, "%d,", g)
+		fmt.Fprintf(&widths, "%d [%.0f] ", g, f.width(g))
+		if i%100 == 0 {
+			if i > 0 {
+				cmap.WriteString("endbfchar\n")
+			}
+			fmt.Fprintf(&cmap, "%d beginbfchar\n", min(100, len(glyphs)-i))
+		}
+		fmt.Fprintf(&cmap, "<%04X> <", g)
+		for _, u := range utf16.Encode(used[g]) {
+			fmt.Fprintf(&cmap, "%04X", u)
+		}
+		cmap.WriteString(">\n")
+	}
+	if len(glyphs) > 0 {
+		cmap.WriteString("endbfchar\n")
+	}
+	tag := make([]byte, 6)
+	for i, v := 0, sum.Sum32(); i < len(tag); i, v = i+1, v>>4 {
+		tag[i] = 'A' + byte(v&0xf)
+	}
+	name := string(tag) + "+" + f.name
+
+	descriptor := fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80",
+		name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight))
+	if program, err := f.subset(keep); err == nil {
+		file := w.stream(fmt.Sprintf(" /Length1 %d", len(program)), program)
+		descriptor += fmt.Sprintf(" /FontFile2 %d 0 R", file)
+	}
+	desc := w.add(descriptor + " >>")
+
+	cid := w.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>", name, desc, widths.String()))
+	toUnicode := w.stream("", []byte("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n"+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n"+
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n"+
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n"+
+		cmap.String()+
+		"endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n"))
+	return w.add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, cid, toUnicode))
+}
+
+func (w *writer) bytes() []byte {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, body := range w.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
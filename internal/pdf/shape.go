@@ -0,0 +1,160 @@
+// internal/pdf/shape.go - Arabic script shaping to presentation forms
+package pdf
+
+import "unicode"
+
+// Join controls
+const (
+	zwnj = '\u200c'
+	zwj  = '\u200d'
+)
+
+// arabicForms lists the isolated, final, initial and medial presentation
+// forms of each letter, 0 where it has none. Letters without an initial
+// form never join the letter after them.
+var arabicForms = map[rune][4]rune{
+	'ء': {0xfe80, 0, 0, 0},
+	'آ': {0xfe81, 0xfe82, 0, 0},
+	'أ': {0xfe83, 0xfe84, 0, 0},
+	'ؤ': {0xfe85, 0xfe86, 0, 0},
+	'إ': {0xfe87, 0xfe88, 0, 0},
+	'ئ': {0xfe89, 0xfe8a, 0xfe8b, 0xfe8c},
+	'ا': {0xfe8d, 0xfe8e, 0, 0},
+	'ب': {0xfe8f, 0xfe90, 0xfe91, 0xfe92},
+	'ة': {0xfe93, 0xfe94, 0, 0},
+	'ت': {0xfe95, 0xfe96, 0xfe97, 0xfe98},
+	'ث': {0xfe99, 0xfe9a, 0xfe9b, 0xfe9c},
+	'ج': {0xfe9d, 0xfe9e, 0xfe9f, 0xfea0},
+	'ح': {0xfea1, 0xfea2, 0xfea3, 0xfea4},
+	'خ': {0xfea5, 0xfea6, 0xfea7, 0xfea8},
+	'د': {0xfea9, 0xfeaa, 0, 0},
+	'ذ': {0xfeab, 0xfeac, 0, 0},
+	'ر': {0xfead, 0xfeae, 0, 0},
+	'ز': {0xfeaf, 0xfeb0, 0, 0},
+	'س': {0xfeb1, 0xfeb2, 0xfeb3, 0xfeb4},
+	'ش': {0xfeb5, 0xfeb6, 0xfeb7, 0xfeb8},
+	'ص': {0xfeb9, 0xfeba, 0xfebb, 0xfebc},
+	'ض': {0xfebd, 0xfebe, 0xfebf, 0xfec0},
+	'ط': {0xfec1, 0xfec2, 0xfec3, 0xfec4},
+	'ظ': {0xfec5, 0xfec6, 0xfec7, 0xfec8},
+	'ع': {0xfec9, 0xfeca, 0xfecb, 0xfecc},
+	'غ': {0xfecd, 0xfece, 0xfecf, 0xfed0},
+	'ف': {0xfed1, 0xfed2, 0xfed3, 0xfed4},
+	'ق': {0xfed5, 0xfed6, 0xfed7, 0xfed8},
+	'ك': {0xfed9, 0xfeda, 0xfedb, 0xfedc},
+	'ل': {0xfedd, 0xfede, 0xfedf, 0xfee0},
+	'م': {0xfee1, 0xfee2, 0xfee3, 0xfee4},
+	'ن': {0xfee5, 0xfee6, 0xfee7, 0xfee8},
+	'ه': {0xfee9, 0xfeea, 0xfeeb, 0xfeec},
+	'و': {0xfeed, 0xfeee, 0, 0},
+	'ى': {0xfeef, 0xfef0, 0xfbe8, 0xfbe9},
+	'ي': {0xfef1, 0xfef2, 0xfef3, 0xfef4},
+	'پ': {0xfb56, 0xfb57, 0xfb58, 0xfb59},
+	'چ': {0xfb7a, 0xfb7b, 0xfb7c, 0xfb7d},
+	'ژ': {0xfb8a, 0xfb8b, 0, 0},
+	'ک': {0xfb8e, 0xfb8f, 0xfb90, 0xfb91},
+	'گ': {0xfb92, 0xfb93, 0xfb94, 0xfb95},
+	'ۀ': {0xfba4, 0xfba5, 0, 0},
+	'ی': {0xfbfc, 0xfbfd, 0xfbfe, 0xfbff},
+}
+
+// lamAlef lists the isolated and final forms of lam followed by each alef
+var lamAlef = map[rune][2]rune{
+	'آ': {0xfef5, 0xfef6},
+	'أ': {0xfef7, 0xfef8},
+	'إ': {0xfef9, 0xfefa},
+	'ا': {0xfefb, 0xfefc},
+}
+
+// presentationBase maps each presentation form back to the letters it
+// shows, for the text a reader copies out of the document
+var presentationBase = func() map[rune][]rune {
+	base := map[rune][]rune{}
+	for letter, forms := range arabicForms {
+		for _, form := range forms {
+			if form != 0 {
+				base[form] = []rune{letter}
+			}
+		}
+	}
+	for alef, forms := range lamAlef {
+		for _, form := range forms {
+			base[form] = []rune{'ل', alef}
+		}
+	}
+	return base
+}()
+
+// joinsNext reports whether r connects to the letter after it
+func joinsNext(r rune) bool {
+	return r == zwj || r == 'ـ' || arabicForms[r][2] != 0
+}
+
+// joinsPrev reports whether r connects to the letter before it
+func joinsPrev(r rune) bool {
+	return r == zwj || r == 'ـ' || arabicForms[r][1] != 0
+}
+
+// neighbour finds the closest letter from i in direction step, skipping
+// the combining marks that sit between joined letters; -1 when none
+func neighbour(runes []rune, i, step int) int {
+	for i += step; i >= 0 && i < len(runes); i += step {
+		if !unicode.Is(unicode.Mn, runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// shape replaces Arabic letters in logical order by the presentation form
+// for their position in a word, and lam-alef pairs by their ligature.
+// Forms the font lacks, as has reports, are left as the plain letter.
+// Join controls are dropped once they have done their work.
+func shape(runes []rune, has func(rune) bool) []rune {
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, ok := arabicForms[r]
+		if !ok {
+			if r != zwj && r != zwnj {
+				out = append(out, r)
+			}
+			continue
+		}
+
+		prev, next := neighbour(runes, i, -1), neighbour(runes, i, 1)
+		joined := prev >= 0 && joinsNext(runes[prev])
+		if r == 'ل' && next == i+1 {
+			if lig, ok := lamAlef[runes[next]]; ok {
+				form := lig[0]
+				if joined {
+					form = lig[1]
+				}
+				if has(form) {
+					out = append(out, form)
+					i = next
+					continue
+				}
+			}
+		}
+
+		before := joined && forms[1] != 0
+		after := next >= 0 && forms[2] != 0 && joinsPrev(runes[next])
+		var form rune
+		switch {
+		case before && after:
+			form = forms[3]
+		case before:
+			form = forms[1]
+		case after:
+			form = forms[2]
+		default:
+			form = forms[0]
+		}
+		if form == 0 || !has(form) {
+			form = r
+		}
+		out = append(out, form)
+	}
+	return out
+}
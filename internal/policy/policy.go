@@ -0,0 +1,238 @@
+// internal/policy/policy.go - Attribute-based rules on top of role permissions
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Resources lists the actions rules can limit on each resource and the
+// attributes of the resource their conditions can test, as resource.<name>
+var Resources = map[string]struct {
+	Actions    []string
+	Attributes []string
+}{
+	"orders": {
+		Actions:    []string{"update", "delete", "assign"},
+		Attributes: []string{"id", "status", "priority", "created_by", "department_id", "requester_id"},
+	},
+}
+
+// SubjectAttributes are the attributes of the caller, from the JWT claims
+// or the token, that conditions can test as subject.<name>
+var SubjectAttributes = []string{"user_id", "role", "department_id", "tenant_id"}
+
+// Attributes are the values of subject.* and resource.* attributes for
+// one request; a missing value is ""
+type Attributes map[string]string
+
+// Condition is a parsed condition of a rule: an attribute compared with
+// literals or with another attribute
+type Condition struct {
+	attribute string
+	negate    bool
+	values    []string // literals; the value of ref when it is set
+	ref       string
+}
+
+// ParseCondition parses a condition of one of the forms
+//
+//	resource.status == draft
+//	resource.status != cancelled
+//	resource.priority in [urgent, stat]
+//	resource.status not in [completed, cancelled]
+//	resource.created_by == subject.user_id
+//
+// A value naming a subject.* or resource.* attribute compares with that
+// attribute; anything else is a literal.
+func ParseCondition(expr string) (Condition, error) {
+	fields := strings.Fields(expr)
+	if len(fields) < 3 {
+		return Condition{}, fmt.Errorf("condition %q must be <attribute> <operator> <value>", expr)
+	}
+
+	c := Condition{attribute: fields[0]}
+	rest := fields[1:]
+	list := false
+	switch {
+	case rest[0] == "==":
+	case rest[0] == "!=":
+		c.negate = true
+	case rest[0] == "in":
+		list = true
+	case rest[0] == "not" && len(rest) > 2 && rest[1] == "in":
+		c.negate, list = true, true
+		rest = rest[1:]
+	default:
+		return Condition{}, fmt.Errorf("condition %q: operator must be ==, !=, in or not in", expr)
+	}
+	operand := strings.Join(rest[1:], " ")
+
+	if err := checkAttribute(c.attribute); err != nil {
+		return Condition{}, fmt.Errorf("condition %q: %w", expr, err)
+	}
+	if !list {
+		if isAttribute(operand) {
+			if err := checkAttribute(operand); err != nil {
+				return Condition{}, fmt.Errorf("condition %q: %w", expr, err)
+			}
+			c.ref = operand
+		} else {
+			c.values = []string{operand}
+		}
+		return c, nil
+	}
+
+	if !strings.HasPrefix(operand, "[") || !strings.HasSuffix(operand, "]") {
+		return Condition{}, fmt.Errorf("condition %q: in takes a list like [a, b]", expr)
+	}
+	for _, v := range strings.Split(operand[1:len(operand)-1], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			c.values = append(c.values, v)
+		}
+	}
+	if len(c.values) == 0 {
+		return Condition{}, fmt.Errorf("condition %q: the list is empty", expr)
+	}
+	return c, nil
+}
+
+// isAttribute reports whether a value names an attribute rather than
+// being a literal
+func isAttribute(s string) bool {
+	return strings.HasPrefix(s, "subject.") || strings.HasPrefix(s, "resource.")
+}
+
+// checkAttribute checks that a subject attribute is known; resource
+// attributes are checked against the rule's resource by NewRule
+func checkAttribute(name string) error {
+	if sub, ok := strings.CutPrefix(name, "subject."); ok {
+		if !slices.Contains(SubjectAttributes, sub) {
+			return fmt.Errorf("unknown attribute %s", name)
+		}
+		return nil
+	}
+	if !strings.HasPrefix(name, "resource.") {
+		return fmt.Errorf("%s must be a subject.* or resource.* attribute", name)
+	}
+	return nil
+}
+
+// Holds reports whether the condition is met by attrs
+func (c Condition) Holds(attrs Attributes) bool {
+	value := attrs[c.attribute]
+	match := false
+	if c.ref != "" {
+		match = value == attrs[c.ref]
+	} else {
+		match = slices.Contains(c.values, value)
+	}
+	return match != c.negate
+}
+
+// attributes lists the attributes the condition reads
+func (c Condition) attributes() []string {
+	if c.ref != "" {
+		return []string{c.attribute, c.ref}
+	}
+	return []string{c.attribute}
+}
+
+// Rule lets the roles it names take an action on a resource only when all
+// of its conditions hold
+type Rule struct {
+	Name     string
+	Resource string
+	Action   string
+	Roles    []string // empty for every role
+	Require  []Condition
+	Message  string
+}
+
+// NewRule parses a rule. action is resource:action, e.g. orders:update,
+// and require lists conditions in the forms ParseCondition accepts.
+func NewRule(name, action string, roles, require []string, message string) (Rule, error) {
+	resource, verb, _ := strings.Cut(action, ":")
+	spec, ok := Resources[resource]
+	if !ok || !slices.Contains(spec.Actions, verb) {
+		return Rule{}, fmt.Errorf("action %q is not one rules can limit", action)
+	}
+	if len(require) == 0 {
+		return Rule{}, fmt.Errorf("rule %q has no conditions", name)
+	}
+
+	rule := Rule{Name: name, Resource: resource, Action: verb, Roles: roles, Message: message}
+	for _, expr := range require {
+		c, err := ParseCondition(expr)
+		if err != nil {
+			return Rule{}, err
+		}
+		for _, attr := range c.attributes() {
+			if sub, ok := strings.CutPrefix(attr, "resource."); ok && !slices.Contains(spec.Attributes, sub) {
+				return Rule{}, fmt.Errorf("condition %q: %s has no attribute %s", expr, resource, sub)
+			}
+		}
+		rule.Require = append(rule.Require, c)
+	}
+	return rule, nil
+}
+
+// appliesTo reports whether the rule limits role taking action on
+// resource
+func (r Rule) appliesTo(resource, action, role string) bool {
+	return r.Resource == resource && r.Action == action &&
+		(len(r.Roles) == 0 || slices.Contains(r.Roles, role))
+}
+
+// Engine holds the rules in force; they can be replaced while requests
+// are evaluated
+type Engine struct {
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// NewEngine creates an engine with rules
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{rules: slices.Clone(rules)}
+}
+
+// Set replaces the rules
+func (e *Engine) Set(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = slices.Clone(rules)
+}
+
+// Applies reports whether any rule limits role taking action on resource,
+// so the resource only needs loading when it does
+func (e *Engine) Applies(resource, action, role string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range e.rules {
+		if r.appliesTo(resource, action, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the first rule that denies the caller with attrs
+// taking action on resource, or nil when every rule that applies holds.
+// The caller's role is attrs["subject.role"].
+func (e *Engine) Evaluate(resource, action string, attrs Attributes) *Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range e.rules {
+		if !r.appliesTo(resource, action, attrs["subject.role"]) {
+			continue
+		}
+		for _, c := range r.Require {
+			if !c.Holds(attrs) {
+				return &r
+			}
+		}
+	}
+	return nil
+}
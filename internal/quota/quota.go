@@ -0,0 +1,529 @@
+// internal/quota/quota.go - Per-tenant request and order quotas
+package quota
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// Names of the quotas, used in responses and metric labels
+const (
+	RequestsPerMinute = "requests_per_minute"
+	RequestsPerDay    = "requests_per_day"
+	OrdersPerDay      = "orders_per_day"
+)
+
+// How long daily request counts are kept
+const countRetention = 90 * 24 * time.Hour
+
+var (
+	quotaUsage = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tenant_quota_usage",
+			Help: "Requests or orders a tenant used today, as of the last quota sync",
+		},
+		[]string{"tenant", "quota"},
+	)
+	quotaLimit = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tenant_quota_limit",
+			Help: "Quota of a tenant; 0 means unlimited",
+		},
+		[]string{"tenant", "quota"},
+	)
+	quotaExceeded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tenant_quota_exceeded_total",
+			Help: "Requests rejected because a tenant exceeded a quota",
+		},
+		[]string{"tenant", "quota"},
+	)
+)
+
+// Limits are the quotas of a tenant; 0 means unlimited
+type Limits struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	RequestsPerDay    int `json:"requests_per_day"`
+	OrdersPerDay      int `json:"orders_per_day"`
+}
+
+// Config holds the default limits and how often counts are shared with
+// other instances. Days are counted in Location.
+type Config struct {
+	Enabled      bool
+	Defaults     Limits
+	SyncInterval time.Duration
+	Location     *time.Location
+}
+
+// Usage is what a tenant used today against its limits
+type Usage struct {
+	TenantID      uuid.UUID `json:"tenant_id"`
+	Slug          string    `json:"slug"`
+	Name          string    `json:"name"`
+	Limits        Limits    `json:"limits"`
+	RequestsToday int64     `json:"requests_today"`
+	OrdersToday   int64     `json:"orders_today"`
+}
+
+// tenant is the state of one tenant on this instance
+type tenant struct {
+	slug      string
+	overrides db.Tenant // only the quota columns are used
+	limits    Limits
+	minute    *rate.Limiter // nil when unlimited
+
+	requests int64 // today's requests of every instance as of the last sync
+	orders   int64 // today's orders as of the last sync
+}
+
+// key is one row of tenant_request_counts
+type key struct {
+	tenantID uuid.UUID
+	day      string
+}
+
+// Limiter enforces the quotas of every tenant. Requests per minute are
+// limited on each instance; daily requests are counted in memory and
+// added to tenant_request_counts every sync, so the request path never
+// waits on the database and several instances share one daily quota,
+// overshooting it by at most a sync interval of requests.
+type Limiter struct {
+	queries   db.Querier
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	mu       sync.Mutex
+	defaults Limits
+	tenants  map[uuid.UUID]*tenant
+	day      string
+	pending  map[key]int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewLimiter creates a limiter. Call Start to begin sharing counts.
+func NewLimiter(queries db.Querier, config Config, logger *logging.Logger) *Limiter {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Limiter{
+		queries:  queries,
+		config:   config,
+		logger:   logger,
+		defaults: config.Defaults,
+		tenants:  make(map[uuid.UUID]*tenant),
+		pending:  make(map[key]int64),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	if config.Enabled {
+		l.heartbeat = middleware.NewHeartbeat("tenant_quotas", config.SyncInterval)
+	}
+	return l
+}
+
+// Enabled reports whether quotas are enforced
+func (l *Limiter) Enabled() bool {
+	return l.heartbeat != nil
+}
+
+// UpdateDefaults replaces the limits of tenants without overrides
+func (l *Limiter) UpdateDefaults(defaults Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults = defaults
+	for _, t := range l.tenants {
+		l.applyLimits(t)
+	}
+}
+
+// SetOverrides applies a tenant's quota columns right away instead of at
+// the next sync
+func (l *Limiter) SetOverrides(row db.Tenant) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := l.tenant(row.ID)
+	t.slug, t.overrides = row.Slug, row
+	l.applyLimits(t)
+}
+
+// Middleware rejects requests of a tenant over its request quotas with
+// 429. It must run after authentication; other requests pass through.
+func (l *Limiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !l.Enabled() {
+			return next
+		}
+		return func(c echo.Context) error {
+			tenantID, err := middleware.GetTenantIDFromContext(c)
+			if err != nil {
+				return next(c)
+			}
+
+			now := time.Now()
+			l.mu.Lock()
+			today := l.rollover(now)
+			t := l.tenant(tenantID)
+			label, limits := t.label(tenantID), t.limits
+			if t.minute != nil && !t.minute.AllowN(now, 1) {
+				l.mu.Unlock()
+				retry := int(math.Ceil(60 / float64(limits.RequestsPerMinute)))
+				return reject(c, label, RequestsPerMinute, limits.RequestsPerMinute, retry)
+			}
+			k := key{tenantID: tenantID, day: today}
+			if limits.RequestsPerDay > 0 && t.requests+l.pending[k] >= int64(limits.RequestsPerDay) {
+				l.mu.Unlock()
+				return reject(c, label, RequestsPerDay, limits.RequestsPerDay, l.untilTomorrow(now))
+			}
+			l.pending[k]++
+			l.mu.Unlock()
+
+			return next(c)
+		}
+	}
+}
+
+// OrderQuota rejects a request that would place an order over the
+// tenant's daily order quota with 429. Concurrent orders may overshoot
+// the quota by a few; if the count cannot be read, the order goes
+// through.
+func (l *Limiter) OrderQuota(next echo.HandlerFunc) echo.HandlerFunc {
+	if !l.Enabled() {
+		return next
+	}
+	return func(c echo.Context) error {
+		tenantID, err := middleware.GetTenantIDFromContext(c)
+		if err != nil {
+			return next(c)
+		}
+
+		now := time.Now()
+		l.mu.Lock()
+		t := l.tenant(tenantID)
+		label, limit := t.label(tenantID), t.limits.OrdersPerDay
+		l.mu.Unlock()
+		if limit == 0 {
+			return next(c)
+		}
+
+		// Counted outside the request's session, which may be limited
+		// to a department
+		ctx, cancel := context.WithTimeout(l.ctx, 5*time.Second)
+		defer cancel()
+		orders, err := l.queries.CountTenantOrdersSince(ctx, db.CountTenantOrdersSinceParams{
+			TenantID: tenantID,
+			Since:    l.startOfDay(now),
+		})
+		if err != nil {
+			l.logger.Error("Failed to count orders for quota", err, map[string]any{"tenant": label})
+			return next(c)
+		}
+		if orders >= int64(limit) {
+			return reject(c, label, OrdersPerDay, limit, l.untilTomorrow(now))
+		}
+		return next(c)
+	}
+}
+
+// Usage lists every tenant's limits and what it used today, sorted by
+// slug. Requests made on other instances since their last sync are not
+// included yet. It reads outside the caller's tenant scope.
+func (l *Limiter) Usage() ([]Usage, error) {
+	ctx, cancel := context.WithTimeout(l.ctx, 10*time.Second)
+	defer cancel()
+	tenants, err := l.queries.ListTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	orders, err := l.queries.CountOrdersByTenantSince(ctx, l.startOfDay(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+	ordersByTenant := make(map[uuid.UUID]int64, len(orders))
+	for _, row := range orders {
+		ordersByTenant[row.TenantID] = row.Orders
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	today := l.rollover(time.Now())
+	usage := make([]Usage, 0, len(tenants))
+	for _, row := range tenants {
+		t := l.tenant(row.ID)
+		t.slug, t.overrides = row.Slug, row
+		l.applyLimits(t)
+		usage = append(usage, Usage{
+			TenantID:      row.ID,
+			Slug:          row.Slug,
+			Name:          row.Name,
+			Limits:        t.limits,
+			RequestsToday: t.requests + l.pending[key{tenantID: row.ID, day: today}],
+			OrdersToday:   ordersByTenant[row.ID],
+		})
+	}
+	slices.SortFunc(usage, func(a, b Usage) int { return cmp.Compare(a.Slug, b.Slug) })
+	return usage, nil
+}
+
+// Start launches the loop that shares counts
+func (l *Limiter) Start() {
+	if l.heartbeat == nil {
+		return
+	}
+	l.wg.Add(1)
+	go l.loop()
+}
+
+// Stop ends the loop, writing the counts still in memory unless ctx
+// expires first
+func (l *Limiter) Stop(ctx context.Context) error {
+	l.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the loop is running, or nil when disabled
+func (l *Limiter) Heartbeat() *middleware.Heartbeat {
+	return l.heartbeat
+}
+
+func (l *Limiter) loop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.config.SyncInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		l.sync(l.ctx)
+		if time.Since(pruned) >= 24*time.Hour {
+			l.prune(l.ctx)
+			pruned = time.Now()
+		}
+		l.heartbeat.Beat()
+
+		select {
+		case <-l.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			l.flush(ctx)
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync writes the counts in memory, then reloads every tenant's
+// overrides and today's counts of all instances
+func (l *Limiter) sync(ctx context.Context) {
+	l.flush(ctx)
+
+	tenants, err := l.queries.ListTenants(ctx)
+	if err != nil {
+		l.logger.Error("Failed to load tenant quotas", err, nil)
+		return
+	}
+	now := time.Now()
+	day := now.In(l.config.Location).Format(time.DateOnly)
+	counts, err := l.queries.ListTenantRequestCounts(ctx, dayDate(day))
+	if err != nil {
+		l.logger.Error("Failed to load tenant request counts", err, nil)
+		return
+	}
+	orders, err := l.queries.CountOrdersByTenantSince(ctx, l.startOfDay(now))
+	if err != nil {
+		l.logger.Error("Failed to count tenant orders", err, nil)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rollover(time.Now()) != day {
+		return // the counts are of yesterday; the next sync catches up
+	}
+	for _, row := range tenants {
+		t := l.tenant(row.ID)
+		t.slug, t.overrides = row.Slug, row
+		l.applyLimits(t)
+		t.requests, t.orders = 0, 0
+	}
+	for _, row := range counts {
+		l.tenant(row.TenantID).requests = row.Requests
+	}
+	for _, row := range orders {
+		l.tenant(row.TenantID).orders = row.Orders
+	}
+	for id, t := range l.tenants {
+		label := t.label(id)
+		quotaUsage.WithLabelValues(label, RequestsPerDay).Set(float64(t.requests + l.pending[key{tenantID: id, day: day}]))
+		quotaUsage.WithLabelValues(label, OrdersPerDay).Set(float64(t.orders))
+		quotaLimit.WithLabelValues(label, RequestsPerMinute).Set(float64(t.limits.RequestsPerMinute))
+		quotaLimit.WithLabelValues(label, RequestsPerDay).Set(float64(t.limits.RequestsPerDay))
+		quotaLimit.WithLabelValues(label, OrdersPerDay).Set(float64(t.limits.OrdersPerDay))
+	}
+}
+
+// flush adds the counts in memory to the database. Rows that fail are
+// put back to be retried on the next sync.
+func (l *Limiter) flush(ctx context.Context) {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = make(map[key]int64)
+	l.mu.Unlock()
+
+	failed := 0
+	var lastErr error
+	for k, n := range batch {
+		err := l.queries.AddTenantRequests(ctx, db.AddTenantRequestsParams{
+			TenantID: k.tenantID,
+			Day:      dayDate(k.day),
+			Requests: n,
+		})
+		if err != nil {
+			failed++
+			lastErr = err
+			// The tenant does not exist; retrying cannot help
+			if pqErr, ok := db.AsPgError(err); !ok || pqErr.Code != "23503" {
+				l.mu.Lock()
+				l.pending[k] += n
+				l.mu.Unlock()
+			}
+		}
+	}
+	if failed > 0 {
+		l.logger.Error("Failed to write tenant request counts", lastErr, map[string]any{"rows": failed})
+	}
+}
+
+func (l *Limiter) prune(ctx context.Context) {
+	day := time.Now().In(l.config.Location).Add(-countRetention).Format(time.DateOnly)
+	if _, err := l.queries.DeleteTenantRequestCountsBefore(ctx, dayDate(day)); err != nil {
+		l.logger.Error("Failed to prune tenant request counts", err, nil)
+	}
+}
+
+// rollover starts a new day's counts when now is past the current day and
+// returns the day. l.mu must be held.
+func (l *Limiter) rollover(now time.Time) string {
+	today := now.In(l.config.Location).Format(time.DateOnly)
+	if today != l.day {
+		l.day = today
+		for _, t := range l.tenants {
+			t.requests, t.orders = 0, 0
+		}
+	}
+	return today
+}
+
+// tenant returns the state of a tenant, with the default limits for one
+// not loaded yet. l.mu must be held.
+func (l *Limiter) tenant(id uuid.UUID) *tenant {
+	t := l.tenants[id]
+	if t == nil {
+		t = &tenant{}
+		l.tenants[id] = t
+		l.applyLimits(t)
+	}
+	return t
+}
+
+// applyLimits works out a tenant's limits from its overrides and the
+// defaults. l.mu must be held.
+func (l *Limiter) applyLimits(t *tenant) {
+	limits := Limits{
+		RequestsPerMinute: override(t.overrides.RequestsPerMinute, l.defaults.RequestsPerMinute),
+		RequestsPerDay:    override(t.overrides.RequestsPerDay, l.defaults.RequestsPerDay),
+		OrdersPerDay:      override(t.overrides.OrdersPerDay, l.defaults.OrdersPerDay),
+	}
+	if t.minute == nil || limits.RequestsPerMinute != t.limits.RequestsPerMinute {
+		t.minute = nil
+		if rpm := limits.RequestsPerMinute; rpm > 0 {
+			t.minute = rate.NewLimiter(rate.Limit(float64(rpm)/60), rpm)
+		}
+	}
+	t.limits = limits
+}
+
+// startOfDay is midnight of now's day in the quota time zone
+func (l *Limiter) startOfDay(now time.Time) time.Time {
+	y, m, d := now.In(l.config.Location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, l.config.Location)
+}
+
+// untilTomorrow is the number of seconds until the daily quotas reset
+func (l *Limiter) untilTomorrow(now time.Time) int {
+	return int(math.Ceil(l.startOfDay(now).AddDate(0, 0, 1).Sub(now).Seconds()))
+}
+
+// label names the tenant in metrics and logs
+func (t *tenant) label(id uuid.UUID) string {
+	if t.slug == "" {
+		return id.String()
+	}
+	return t.slug
+}
+
+// override is a tenant's own limit, or def when it has none
+func override(v sql.NullInt32, def int) int {
+	if !v.Valid {
+		return def
+	}
+	return int(v.Int32)
+}
+
+// dayDate is the DATE value of a day formatted as YYYY-MM-DD
+func dayDate(day string) time.Time {
+	t, _ := time.Parse(time.DateOnly, day)
+	return t
+}
+
+// reject writes the 429 response for an exceeded quota
+func reject(c echo.Context, tenant, quota string, limit, retryAfter int) error {
+	quotaExceeded.WithLabelValues(tenant, quota).Inc()
+
+	code, details := "tenant_quota_exceeded", "Your pharmacy has used its request quota. Please try again later."
+	if quota == OrdersPerDay {
+		code, details = "order_quota_exceeded", "Your pharmacy has placed as many orders as it may today."
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	return c.JSON(http.StatusTooManyRequests, map[string]any{
+		"error":               code,
+		"details":             details,
+		"quota":               quota,
+		"limit":               limit,
+		"retry_after_seconds": retryAfter,
+	})
+}
@@ -0,0 +1,183 @@
+// internal/recurring/runner.go - Placing due recurring orders
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// OrderStatus is the status of the orders a run creates
+const OrderStatus = "draft"
+
+var runsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "recurring_order_runs_total",
+		Help: "Recurring order runs by outcome (created, skipped)",
+	},
+	[]string{"status"},
+)
+
+// TxFunc runs fn inside a database transaction
+type TxFunc func(ctx context.Context, fn func(q db.Querier) error) error
+
+// Config holds the time zone of run dates, which are midnights in Location,
+// and the tax added to the subtotal of the orders placed for their total
+type Config struct {
+	Location   *time.Location
+	TaxPercent float64
+}
+
+// Runner turns due recurring orders into draft orders, copying the items
+// of each template order. Due rows are claimed with SKIP LOCKED, so each
+// run places one order even with several instances running. The scheduler
+// calls RunDue.
+type Runner struct {
+	withTx TxFunc
+	config Config
+	logger *logging.Logger
+}
+
+// NewRunner creates a runner
+func NewRunner(withTx TxFunc, config Config, logger *logging.Logger) *Runner {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	return &Runner{
+		withTx: withTx,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Location is the time zone of run dates
+func (r *Runner) Location() *time.Location {
+	return r.config.Location
+}
+
+// RunDue places due recurring orders one at a time until none are left
+func (r *Runner) RunDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		ran, err := r.runNext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to place recurring orders: %w", err)
+		}
+		if !ran {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// runNext claims the oldest due recurring order, places it and advances it
+// to its next run. The order and its outbox event commit together with the
+// new next_run_at.
+func (r *Runner) runNext(ctx context.Context) (bool, error) {
+	found := false
+	err := r.withTx(ctx, func(q db.Querier) error {
+		due, err := q.ClaimDueRecurringOrders(ctx, db.ClaimDueRecurringOrdersParams{Now: time.Now(), LimitCount: 1})
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		found = true
+		recurring := due[0]
+
+		order, runErr := r.Place(ctx, q, recurring, recurring.NextRunAt.In(r.config.Location))
+		status := "created"
+		if runErr != nil {
+			status = "skipped"
+			r.logger.Warn("Recurring order skipped", map[string]any{
+				"recurring_order_id": recurring.ID.String(),
+				"error":              runErr.Error(),
+			})
+		}
+		runsTotal.WithLabelValues(status).Inc()
+
+		now := time.Now()
+		params := db.MarkRecurringOrderRunParams{
+			ID:          recurring.ID,
+			LastRunAt:   sql.NullTime{Time: now, Valid: true},
+			LastOrderID: recurring.LastOrderID,
+			NextRunAt:   NextRun(recurring.Frequency, recurring.NextRunAt.In(r.config.Location), now),
+		}
+		if runErr != nil {
+			params.LastError = sql.NullString{String: runErr.Error(), Valid: true}
+		} else {
+			params.LastOrderID = uuid.NullUUID{UUID: order.ID, Valid: true}
+		}
+		return q.MarkRecurringOrderRun(ctx, params)
+	})
+	return found, err
+}
+
+// Place creates the draft order of a run on runDate, needed lead_days
+// later, with the items of the template order at the products' current
+// prices. q should be bound to a transaction. A deleted or empty template
+// skips the run with an error; after a database error the transaction is
+// aborted and rolls back.
+func (r *Runner) Place(ctx context.Context, q db.Querier, recurring db.RecurringOrder, runDate time.Time) (db.Order, error) {
+	template, err := q.GetOrder(ctx, recurring.TemplateOrderID)
+	if err != nil {
+		return db.Order{}, err
+	}
+	if template.DeletedAt.Valid {
+		return db.Order{}, errors.New("template order has been deleted")
+	}
+	// The runner works across tenants; the new order and its items belong
+	// to the template's
+	if err := q.ScopeToTenant(ctx, db.ScopeToTenantParams{TenantID: template.TenantID.String()}); err != nil {
+		return db.Order{}, err
+	}
+	items, err := q.GetOrderItems(ctx, uuid.NullUUID{UUID: template.ID, Valid: true})
+	if err != nil {
+		return db.Order{}, err
+	}
+	if len(items) == 0 {
+		return db.Order{}, errors.New("template order has no items")
+	}
+
+	y, m, d := runDate.Date()
+	neededBy := time.Date(y, m, d+int(recurring.LeadDays), 0, 0, 0, 0, time.UTC)
+	order, err := q.CreateOrder(ctx, db.CreateOrderParams{
+		CreatedBy: recurring.CreatedBy,
+		Status:    OrderStatus,
+		Notes:     sql.NullString{String: "Recurring order: " + recurring.Name, Valid: true},
+		Priority:  recurring.Priority,
+		NeededBy:  sql.NullTime{Time: neededBy, Valid: true},
+	})
+	if err != nil {
+		return db.Order{}, err
+	}
+	if _, err := q.CopyOrderItems(ctx, db.CopyOrderItemsParams{
+		TargetOrderID: order.ID,
+		SourceOrderID: template.ID,
+	}); err != nil {
+		return db.Order{}, err
+	}
+	order, err = q.RecalculateOrderTotals(ctx, db.RecalculateOrderTotalsParams{
+		TaxPercent: strconv.FormatFloat(r.config.TaxPercent, 'f', -1, 64),
+		OrderID:    order.ID,
+	})
+	if err != nil {
+		return db.Order{}, err
+	}
+
+	var actor string
+	if recurring.CreatedBy.Valid {
+		actor = recurring.CreatedBy.UUID.String()
+	}
+	if err := outbox.Record(ctx, q, outbox.OrderCreated, order.ID.String(), actor, outbox.OrderPayload(order)); err != nil {
+		return db.Order{}, err
+	}
+	return order, nil
+}
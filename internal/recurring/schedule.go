@@ -0,0 +1,72 @@
+// internal/recurring/schedule.go - Run dates of recurring orders
+package recurring
+
+import (
+	"slices"
+	"time"
+)
+
+// Frequencies
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Frequencies lists every recurring order frequency
+var Frequencies = []string{FrequencyDaily, FrequencyWeekly, FrequencyMonthly}
+
+// ValidFrequency reports whether frequency is known
+func ValidFrequency(frequency string) bool {
+	return slices.Contains(Frequencies, frequency)
+}
+
+// MaxMonthlyDay is the latest day of the month a monthly recurring order
+// may start on, so that every month has its run day
+const MaxMonthlyDay = 28
+
+// Step returns the run after run
+func Step(frequency string, run time.Time) time.Time {
+	switch frequency {
+	case FrequencyMonthly:
+		return run.AddDate(0, 1, 0)
+	case FrequencyWeekly:
+		return run.AddDate(0, 0, 7)
+	default:
+		return run.AddDate(0, 0, 1)
+	}
+}
+
+// NextRun returns the first run strictly after after, counting from run.
+// Runs missed while the server was down are skipped.
+func NextRun(frequency string, run, after time.Time) time.Time {
+	for !run.After(after) {
+		run = Step(frequency, run)
+	}
+	return run
+}
+
+// FirstRun returns the first run of an order starting on start: midnight
+// of start in loc, or the first later run not before today
+func FirstRun(frequency string, start time.Time, loc *time.Location, now time.Time) time.Time {
+	y, m, d := start.Date()
+	run := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	y, m, d = now.In(loc).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	for run.Before(today) {
+		run = Step(frequency, run)
+	}
+	return run
+}
+
+// RRule is the iCalendar recurrence rule of frequency
+func RRule(frequency string) string {
+	switch frequency {
+	case FrequencyMonthly:
+		return "FREQ=MONTHLY"
+	case FrequencyWeekly:
+		return "FREQ=WEEKLY"
+	default:
+		return "FREQ=DAILY"
+	}
+}
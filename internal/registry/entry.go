@@ -0,0 +1,191 @@
+// internal/registry/entry.go - National drug registry export parsing
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxExportSize bounds a registry export read into memory
+const maxExportSize = 64 << 20
+
+// Entry is one product of the national drug registry
+type Entry struct {
+	IRC          string `json:"irc"`
+	GenericCode  string `json:"generic_code"`
+	Name         string `json:"name"`
+	GenericName  string `json:"generic_name"`
+	DosageForm   string `json:"dosage_form"`
+	Strength     string `json:"strength"`
+	Unit         string `json:"unit"`
+	Manufacturer string `json:"manufacturer"`
+	GTIN         string `json:"gtin"`
+}
+
+// csvColumns maps the header spellings seen in registry exports onto Entry
+// fields
+var csvColumns = map[string]string{
+	"irc":           "irc",
+	"iran_code":     "irc",
+	"generic_code":  "generic_code",
+	"genericcode":   "generic_code",
+	"name":          "name",
+	"brand_name":    "name",
+	"trade_name":    "name",
+	"generic_name":  "generic_name",
+	"genericname":   "generic_name",
+	"dosage_form":   "dosage_form",
+	"form":          "dosage_form",
+	"strength":      "strength",
+	"unit":          "unit",
+	"manufacturer":  "manufacturer",
+	"company":       "manufacturer",
+	"gtin":          "gtin",
+	"barcode":       "gtin",
+	"license_owner": "manufacturer",
+}
+
+// Parse reads a registry export. JSON (an array of entries, or an object
+// with an "entries" array) is recognised by its first character; anything
+// else is read as CSV with a header row.
+func Parse(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxExportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxExportSize {
+		return nil, fmt.Errorf("registry export exceeds %d MB", maxExportSize>>20)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var entries []Entry
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0:
+		return nil, errors.New("registry export is empty")
+	case trimmed[0] == '[':
+		err = json.Unmarshal(trimmed, &entries)
+	case trimmed[0] == '{':
+		var wrapper struct {
+			Entries []Entry `json:"entries"`
+		}
+		err = json.Unmarshal(trimmed, &wrapper)
+		entries = wrapper.Entries
+	default:
+		entries, err = parseCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry export: %w", err)
+	}
+
+	for i := range entries {
+		entries[i].normalize()
+	}
+	return entries, nil
+}
+
+func parseCSV(data []byte) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(header))
+	hasIRC := false
+	for i, name := range header {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+		fields[i] = csvColumns[key]
+		hasIRC = hasIRC || fields[i] == "irc"
+	}
+	if !hasIRC {
+		return nil, errors.New("CSV header has no irc column")
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var e Entry
+		for i, value := range record {
+			if i >= len(fields) {
+				break
+			}
+			switch fields[i] {
+			case "irc":
+				e.IRC = value
+			case "generic_code":
+				e.GenericCode = value
+			case "name":
+				e.Name = value
+			case "generic_name":
+				e.GenericName = value
+			case "dosage_form":
+				e.DosageForm = value
+			case "strength":
+				e.Strength = value
+			case "unit":
+				e.Unit = value
+			case "manufacturer":
+				e.Manufacturer = value
+			case "gtin":
+				e.GTIN = value
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (e *Entry) normalize() {
+	e.IRC = strings.TrimSpace(e.IRC)
+	e.GenericCode = strings.TrimSpace(e.GenericCode)
+	e.Name = strings.TrimSpace(e.Name)
+	e.GenericName = strings.TrimSpace(e.GenericName)
+	e.DosageForm = strings.TrimSpace(e.DosageForm)
+	e.Strength = strings.TrimSpace(e.Strength)
+	e.Unit = strings.TrimSpace(e.Unit)
+	e.Manufacturer = strings.TrimSpace(e.Manufacturer)
+	e.GTIN = strings.TrimSpace(e.GTIN)
+	if e.Name == "" {
+		e.Name = e.GenericName
+	}
+}
+
+// Fetch downloads and parses the registry export at url
+func Fetch(ctx context.Context, url string, timeout time.Duration) ([]Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, text/csv")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned %s", resp.Status)
+	}
+	return Parse(resp.Body)
+}
@@ -0,0 +1,464 @@
+// internal/registry/sync.go - Reconciliation of the registry with the catalog
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Reconciliation outcomes, the outcome column of drug_registry_sync_items
+const (
+	OutcomeMatchedIRC     = "matched_irc"
+	OutcomeMatchedBarcode = "matched_barcode"
+	OutcomeMatchedName    = "matched_name"
+	OutcomeCreated        = "created"
+	OutcomeAmbiguous      = "ambiguous"
+	OutcomeConflict       = "conflict"
+	OutcomeFailed         = "failed"
+)
+
+// Sync statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// ErrSyncInProgress is returned when a sync is started while another runs
+var ErrSyncInProgress = errors.New("a drug registry sync is already running")
+
+var registryEntriesTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "drug_registry_entries_total",
+		Help: "Registry entries reconciled, by outcome",
+	},
+	[]string{"outcome"},
+)
+
+// TxFunc runs fn inside a database transaction
+type TxFunc func(ctx context.Context, fn func(q db.Querier) error) error
+
+// Config controls where the registry is fetched from and how often
+type Config struct {
+	URL      string
+	Interval time.Duration // 0 disables scheduled syncs
+	Timeout  time.Duration
+}
+
+// Syncer imports registry exports. Each entry is matched to a product by
+// IRC, then barcode (GTIN), then name and strength; matches are linked to
+// the entry's IRC and unmatched entries become staging products. Only one
+// sync runs at a time per process.
+type Syncer struct {
+	queries   db.Querier
+	withTx    TxFunc
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	running sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewSyncer creates a syncer. Call Start to enable scheduled syncs.
+func NewSyncer(queries db.Querier, withTx TxFunc, config Config, logger *logging.Logger) *Syncer {
+	if config.Timeout <= 0 {
+		config.Timeout = time.Minute
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Syncer{
+		queries: queries,
+		withTx:  withTx,
+		config:  config,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if config.Interval > 0 {
+		s.heartbeat = middleware.NewHeartbeat("drug_registry_sync", config.Interval)
+	}
+	return s
+}
+
+// Start launches the scheduled sync loop when an interval and URL are
+// configured
+func (s *Syncer) Start() {
+	if s.heartbeat == nil || s.config.URL == "" {
+		return
+	}
+	s.wg.Add(1)
+	go s.schedule()
+}
+
+// Stop cancels a running sync and the schedule, waiting until ctx expires.
+// An interrupted sync is recorded as failed.
+func (s *Syncer) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the schedule is running, or nil when scheduled
+// syncs are disabled
+func (s *Syncer) Heartbeat() *middleware.Heartbeat {
+	if s.config.URL == "" {
+		return nil
+	}
+	return s.heartbeat
+}
+
+// URL returns the configured registry export URL
+func (s *Syncer) URL() string {
+	return s.config.URL
+}
+
+// SyncEntries starts reconciling already parsed entries in the background
+// and returns the new sync record
+func (s *Syncer) SyncEntries(ctx context.Context, source string, triggeredBy uuid.NullUUID, entries []Entry) (db.DrugRegistrySync, error) {
+	return s.begin(ctx, source, triggeredBy, func(context.Context) ([]Entry, error) {
+		return entries, nil
+	})
+}
+
+// SyncURL starts fetching and reconciling the configured registry export
+// in the background and returns the new sync record
+func (s *Syncer) SyncURL(ctx context.Context, triggeredBy uuid.NullUUID) (db.DrugRegistrySync, error) {
+	if s.config.URL == "" {
+		return db.DrugRegistrySync{}, errors.New("no drug registry URL is configured")
+	}
+	return s.begin(ctx, s.config.URL, triggeredBy, func(ctx context.Context) ([]Entry, error) {
+		return Fetch(ctx, s.config.URL, s.config.Timeout)
+	})
+}
+
+func (s *Syncer) schedule() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.heartbeat.Beat()
+		if _, err := s.SyncURL(s.ctx, uuid.NullUUID{}); err != nil && !errors.Is(err, ErrSyncInProgress) {
+			s.logger.Error("Failed to start scheduled drug registry sync", err, nil)
+		}
+	}
+}
+
+// begin records the sync and runs it in the background. The lock is held
+// until the run finishes.
+func (s *Syncer) begin(ctx context.Context, source string, triggeredBy uuid.NullUUID, load func(context.Context) ([]Entry, error)) (db.DrugRegistrySync, error) {
+	if !s.running.TryLock() {
+		return db.DrugRegistrySync{}, ErrSyncInProgress
+	}
+
+	record, err := s.queries.CreateDrugRegistrySync(ctx, db.CreateDrugRegistrySyncParams{
+		Source:      source,
+		TriggeredBy: triggeredBy,
+	})
+	if err != nil {
+		s.running.Unlock()
+		return db.DrugRegistrySync{}, err
+	}
+
+	var actor string
+	if triggeredBy.Valid {
+		actor = triggeredBy.UUID.String()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Unlock()
+		s.run(record, actor, load)
+	}()
+	return record, nil
+}
+
+// run loads the entries, reconciles each and records the totals
+func (s *Syncer) run(record db.DrugRegistrySync, actor string, load func(context.Context) ([]Entry, error)) {
+	started := time.Now()
+	finish := db.FinishDrugRegistrySyncParams{ID: record.ID, Status: StatusCompleted}
+
+	entries, err := load(s.ctx)
+	if err == nil {
+		err = s.reconcileAll(record.ID, actor, entries, &finish)
+	}
+	if err != nil {
+		finish.Status = StatusFailed
+		finish.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+
+	// The run may have been cancelled; the result is still recorded
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := s.queries.FinishDrugRegistrySync(ctx, finish); err != nil {
+		s.logger.Error("Failed to record drug registry sync result", err, map[string]any{"sync_id": record.ID})
+	}
+
+	fields := map[string]any{
+		"sync_id":     record.ID,
+		"source":      record.Source,
+		"status":      finish.Status,
+		"entries":     finish.TotalEntries,
+		"matched":     finish.Matched,
+		"created":     finish.Created,
+		"ambiguous":   finish.Ambiguous,
+		"conflicts":   finish.Conflicts,
+		"failed":      finish.Failed,
+		"duration_ms": time.Since(started).Milliseconds(),
+	}
+	if finish.Status == StatusFailed {
+		fields["error"] = finish.Error.String
+		s.logger.Warn("Drug registry sync failed", fields)
+		return
+	}
+	s.logger.Info("Drug registry sync completed", fields)
+}
+
+func (s *Syncer) reconcileAll(syncID uuid.UUID, actor string, entries []Entry, totals *db.FinishDrugRegistrySyncParams) error {
+	forms, err := s.dosageForms(s.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load dosage forms: %w", err)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if err := s.ctx.Err(); err != nil {
+			return errors.New("sync interrupted by shutdown")
+		}
+
+		totals.TotalEntries++
+		item := s.reconcile(entry, actor, forms, seen)
+		if entry.IRC != "" {
+			seen[entry.IRC] = true
+		}
+
+		switch item.Outcome {
+		case OutcomeMatchedIRC, OutcomeMatchedBarcode, OutcomeMatchedName:
+			totals.Matched++
+		case OutcomeCreated:
+			totals.Created++
+		case OutcomeAmbiguous:
+			totals.Ambiguous++
+		case OutcomeConflict:
+			totals.Conflicts++
+		default:
+			totals.Failed++
+		}
+		registryEntriesTotal.WithLabelValues(item.Outcome).Inc()
+
+		item.SyncID = syncID
+		item.Irc = entry.IRC
+		item.GenericCode = nullString(entry.GenericCode)
+		item.Name = entry.Name
+		item.Gtin = nullString(entry.GTIN)
+		if err := s.queries.CreateDrugRegistrySyncItem(s.ctx, item); err != nil {
+			return fmt.Errorf("failed to record reconciliation of IRC %s: %w", entry.IRC, err)
+		}
+	}
+	return nil
+}
+
+// reconcile matches one entry and applies the change. Only Outcome,
+// ProductID and Detail of the result are set.
+func (s *Syncer) reconcile(entry Entry, actor string, forms map[string]int32, seen map[string]bool) db.CreateDrugRegistrySyncItemParams {
+	switch {
+	case entry.IRC == "":
+		return outcome(OutcomeFailed, uuid.Nil, "entry has no IRC")
+	case entry.Name == "":
+		return outcome(OutcomeFailed, uuid.Nil, "entry has no name")
+	case seen[entry.IRC]:
+		return outcome(OutcomeFailed, uuid.Nil, "duplicate IRC in this export")
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
+	defer cancel()
+
+	var result db.CreateDrugRegistrySyncItemParams
+	err := s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		result, err = s.match(ctx, q, entry, actor, forms)
+		return err
+	})
+	if err != nil {
+		return outcome(OutcomeFailed, uuid.Nil, err.Error())
+	}
+	return result
+}
+
+func (s *Syncer) match(ctx context.Context, q db.Querier, entry Entry, actor string, forms map[string]int32) (db.CreateDrugRegistrySyncItemParams, error) {
+	// 1. Already linked by IRC; only fill in a missing generic code
+	product, err := q.GetProductByIRC(ctx, entry.IRC)
+	if err == nil {
+		if entry.GenericCode != "" && product.GenericCode.String != entry.GenericCode {
+			if err := s.link(ctx, q, product, entry, actor); err != nil {
+				return db.CreateDrugRegistrySyncItemParams{}, err
+			}
+		}
+		return outcome(OutcomeMatchedIRC, product.ID, ""), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return db.CreateDrugRegistrySyncItemParams{}, err
+	}
+
+	// 2. Barcode
+	if entry.GTIN != "" {
+		product, err := q.GetProductByBarcode(ctx, entry.GTIN)
+		switch {
+		case err == nil && product.Irc.Valid:
+			return outcome(OutcomeConflict, product.ID,
+				fmt.Sprintf("barcode %s belongs to a product linked to IRC %s", entry.GTIN, product.Irc.String)), nil
+		case err == nil:
+			if err := s.link(ctx, q, product, entry, actor); err != nil {
+				return db.CreateDrugRegistrySyncItemParams{}, err
+			}
+			return outcome(OutcomeMatchedBarcode, product.ID, ""), nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return db.CreateDrugRegistrySyncItemParams{}, err
+		}
+	}
+
+	// 3. Name and strength
+	names := []string{strings.ToLower(entry.Name)}
+	if entry.GenericName != "" && !strings.EqualFold(entry.GenericName, entry.Name) {
+		names = append(names, strings.ToLower(entry.GenericName))
+	}
+	candidates, err := q.FindProductsByName(ctx, db.FindProductsByNameParams{
+		Names:    names,
+		Strength: normalizeStrength(entry.Strength),
+	})
+	if err != nil {
+		return db.CreateDrugRegistrySyncItemParams{}, err
+	}
+	switch {
+	case len(candidates) > 1:
+		ids := make([]string, len(candidates))
+		for i, c := range candidates {
+			ids[i] = c.ID.String()
+		}
+		return outcome(OutcomeAmbiguous, uuid.Nil,
+			"several products match by name: "+strings.Join(ids, ", ")), nil
+	case len(candidates) == 1 && candidates[0].Irc.Valid:
+		return outcome(OutcomeConflict, candidates[0].ID,
+			"product matching by name is linked to IRC "+candidates[0].Irc.String), nil
+	case len(candidates) == 1:
+		if err := s.link(ctx, q, candidates[0], entry, actor); err != nil {
+			return db.CreateDrugRegistrySyncItemParams{}, err
+		}
+		return outcome(OutcomeMatchedName, candidates[0].ID, ""), nil
+	}
+
+	// 4. New staging product
+	created, err := q.CreateStagingProduct(ctx, db.CreateStagingProductParams{
+		Name:         entry.Name,
+		Brand:        nullString(entry.Manufacturer),
+		DosageFormID: dosageFormID(forms, entry.DosageForm),
+		Strength:     nullString(entry.Strength),
+		Unit:         nullString(entry.Unit),
+		Description:  nullString(entry.GenericName),
+		Irc:          nullString(entry.IRC),
+		GenericCode:  nullString(entry.GenericCode),
+	})
+	if err != nil {
+		return db.CreateDrugRegistrySyncItemParams{}, err
+	}
+	if entry.GTIN != "" {
+		if _, err := q.CreateBarcode(ctx, db.CreateBarcodeParams{
+			ProductID:   uuid.NullUUID{UUID: created.ID, Valid: true},
+			Barcode:     entry.GTIN,
+			BarcodeType: sql.NullString{String: "GTIN", Valid: true},
+		}); err != nil {
+			return db.CreateDrugRegistrySyncItemParams{}, err
+		}
+	}
+	if err := outbox.Record(ctx, q, outbox.ProductCreated, created.ID.String(), actor, outbox.ProductPayload(created)); err != nil {
+		return db.CreateDrugRegistrySyncItemParams{}, err
+	}
+
+	var detail string
+	if entry.DosageForm != "" && !created.DosageFormID.Valid {
+		detail = fmt.Sprintf("unknown dosage form %q", entry.DosageForm)
+	}
+	return outcome(OutcomeCreated, created.ID, detail), nil
+}
+
+// link stores the entry's registry codes on a matched product
+func (s *Syncer) link(ctx context.Context, q db.Querier, product db.Product, entry Entry, actor string) error {
+	updated, err := q.SetProductRegistryCodes(ctx, db.SetProductRegistryCodesParams{
+		Irc:         entry.IRC,
+		GenericCode: nullString(entry.GenericCode),
+		ID:          product.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return outbox.Record(ctx, q, outbox.ProductUpdated, updated.ID.String(), actor, outbox.ProductPayload(updated))
+}
+
+// dosageForms maps lower-cased dosage form names to IDs
+func (s *Syncer) dosageForms(ctx context.Context) (map[string]int32, error) {
+	forms, err := s.queries.ListDosageForms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int32, len(forms))
+	for _, form := range forms {
+		ids[strings.ToLower(form.Name)] = form.ID
+	}
+	return ids, nil
+}
+
+func dosageFormID(forms map[string]int32, name string) sql.NullInt32 {
+	id, ok := forms[strings.ToLower(name)]
+	return sql.NullInt32{Int32: id, Valid: ok && name != ""}
+}
+
+// normalizeStrength makes "500 MG" and "500mg" compare equal
+func normalizeStrength(strength string) string {
+	return strings.ToLower(strings.ReplaceAll(strength, " ", ""))
+}
+
+func outcome(result string, productID uuid.UUID, detail string) db.CreateDrugRegistrySyncItemParams {
+	return db.CreateDrugRegistrySyncItemParams{
+		Outcome:   result,
+		ProductID: uuid.NullUUID{UUID: productID, Valid: productID != uuid.Nil},
+		Detail:    nullString(detail),
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
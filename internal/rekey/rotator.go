@@ -0,0 +1,245 @@
+// internal/rekey/rotator.go - Re-encrypting columns under the active key
+package rekey
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/fieldcrypt"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var reencryptedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "field_reencrypted_rows_total",
+		Help: "Rows whose encrypted columns were rewritten under the active key, by table",
+	},
+	[]string{"table"},
+)
+
+// TxFunc runs fn inside a database transaction
+type TxFunc func(ctx context.Context, fn func(q db.Querier) error) error
+
+// Config controls the rotator
+type Config struct {
+	Interval  time.Duration // 0 disables the rotator
+	BatchSize int
+}
+
+// Rotator rewrites encrypted columns whose values are plain text or under
+// a key other than the active one, so retired keys can be removed. Rows
+// are read and written back through the column types, which decrypt with
+// any key and encrypt with the active one.
+type Rotator struct {
+	withTx    TxFunc
+	keys      *fieldcrypt.Keyring
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// table re-encrypts one batch of a table after a row id, returning the
+// last id it rewrote and how many rows it did
+type table struct {
+	name  string
+	batch func(ctx context.Context, q db.Querier, prefix string, after uuid.UUID, limit int32) (uuid.UUID, int, error)
+}
+
+var tables = []table{
+	{"users", reencryptUsers},
+	{"audit_logs", reencryptAuditLogs},
+	{"login_attempts_log", reencryptLoginDevices},
+	{"requesters", reencryptRequesters},
+}
+
+// NewRotator creates a rotator for keys, which does nothing without them.
+// Call Start to begin re-encrypting.
+func NewRotator(withTx TxFunc, keys *fieldcrypt.Keyring, config Config, logger *logging.Logger) *Rotator {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Rotator{
+		withTx: withTx,
+		keys:   keys,
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if keys != nil && config.Interval > 0 && config.BatchSize > 0 {
+		r.heartbeat = middleware.NewHeartbeat("field_reencryption", config.Interval)
+	}
+	return r
+}
+
+// Start launches the loop that re-encrypts every Interval
+func (r *Rotator) Start() {
+	if r.heartbeat == nil {
+		return
+	}
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// Stop ends the loop, waiting for a run in progress until ctx expires
+func (r *Rotator) Stop(ctx context.Context) error {
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the loop is running, or nil when disabled
+func (r *Rotator) Heartbeat() *middleware.Heartbeat {
+	return r.heartbeat
+}
+
+func (r *Rotator) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.Run(r.ctx)
+		r.heartbeat.Beat()
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run re-encrypts every table once, a batch per transaction
+func (r *Rotator) Run(ctx context.Context) {
+	if r.keys == nil {
+		return
+	}
+	prefix := r.keys.ActivePrefix()
+	for _, t := range tables {
+		var after uuid.UUID
+		total := 0
+		for ctx.Err() == nil {
+			var last uuid.UUID
+			var n int
+			err := r.withTx(ctx, func(q db.Querier) error {
+				var err error
+				last, n, err = t.batch(ctx, q, prefix, after, int32(r.config.BatchSize))
+				return err
+			})
+			if err != nil {
+				r.logger.Error("Failed to re-encrypt rows", err, map[string]any{"table": t.name})
+				break
+			}
+			total += n
+			reencryptedTotal.WithLabelValues(t.name).Add(float64(n))
+			if n < r.config.BatchSize {
+				break
+			}
+			after = last
+		}
+		if total > 0 {
+			r.logger.Info("Re-encrypted rows", map[string]any{"table": t.name, "rows": total, "key": r.keys.ActiveID()})
+		}
+	}
+}
+
+func reencryptUsers(ctx context.Context, q db.Querier, prefix string, after uuid.UUID, limit int32) (uuid.UUID, int, error) {
+	rows, err := q.ListUnencryptedUserNames(ctx, db.ListUnencryptedUserNamesParams{
+		AfterID:    after,
+		Prefix:     prefix,
+		LimitCount: limit,
+	})
+	if err != nil {
+		return after, 0, err
+	}
+	for _, row := range rows {
+		if err := q.ReencryptUserName(ctx, db.ReencryptUserNameParams{FullName: row.FullName, ID: row.ID}); err != nil {
+			return after, 0, err
+		}
+		after = row.ID
+	}
+	return after, len(rows), nil
+}
+
+func reencryptAuditLogs(ctx context.Context, q db.Querier, prefix string, after uuid.UUID, limit int32) (uuid.UUID, int, error) {
+	rows, err := q.ListUnencryptedAuditClients(ctx, db.ListUnencryptedAuditClientsParams{
+		AfterID:    after,
+		Prefix:     prefix,
+		LimitCount: limit,
+	})
+	if err != nil {
+		return after, 0, err
+	}
+	for _, row := range rows {
+		if err := q.ReencryptAuditClient(ctx, db.ReencryptAuditClientParams{
+			IpAddress: row.IpAddress,
+			UserAgent: row.UserAgent,
+			ID:        row.ID,
+		}); err != nil {
+			return after, 0, err
+		}
+		after = row.ID
+	}
+	return after, len(rows), nil
+}
+
+func reencryptLoginDevices(ctx context.Context, q db.Querier, prefix string, after uuid.UUID, limit int32) (uuid.UUID, int, error) {
+	rows, err := q.ListUnencryptedLoginDevices(ctx, db.ListUnencryptedLoginDevicesParams{
+		AfterID:    after,
+		Prefix:     prefix,
+		LimitCount: limit,
+	})
+	if err != nil {
+		return after, 0, err
+	}
+	for _, row := range rows {
+		if err := q.ReencryptLoginDevice(ctx, db.ReencryptLoginDeviceParams{DeviceInfo: row.DeviceInfo, ID: row.ID}); err != nil {
+			return after, 0, err
+		}
+		after = row.ID
+	}
+	return after, len(rows), nil
+}
+
+func reencryptRequesters(ctx context.Context, q db.Querier, prefix string, after uuid.UUID, limit int32) (uuid.UUID, int, error) {
+	rows, err := q.ListUnencryptedRequesters(ctx, db.ListUnencryptedRequestersParams{
+		AfterID:    after,
+		Prefix:     prefix,
+		LimitCount: limit,
+	})
+	if err != nil {
+		return after, 0, err
+	}
+	for _, row := range rows {
+		if err := q.ReencryptRequester(ctx, db.ReencryptRequesterParams{
+			Name:      row.Name,
+			Reference: row.Reference,
+			ID:        row.ID,
+		}); err != nil {
+			return after, 0, err
+		}
+		after = row.ID
+	}
+	return after, len(rows), nil
+}
@@ -0,0 +1,203 @@
+// internal/reports/changes.go - How often entities change, from the audit log
+package reports
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// MaxMostChanged caps the entities listed per type
+const MaxMostChanged = 50
+
+// ChangeFilter narrows a change report. Empty EntityTypes keeps every
+// type; MostChanged is how many entities to list per type.
+type ChangeFilter struct {
+	EntityTypes []string
+	UserID      uuid.NullUUID
+	MostChanged int
+}
+
+// ChangeReport counts audit log entries per entity type and bucket from
+// From (inclusive) to To (exclusive), both on bucket boundaries, and per
+// user. Types and users are listed most changes first.
+type ChangeReport struct {
+	Granularity string          `json:"granularity"`
+	Timezone    string          `json:"timezone"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	Total       int64           `json:"total"`
+	Entities    []EntityChanges `json:"entities"`
+	Users       []UserChanges   `json:"users"`
+}
+
+// EntityChanges is the churn of one entity type: how often it changed,
+// how many distinct entities did, by action and by bucket
+type EntityChanges struct {
+	EntityType  string           `json:"entity_type"`
+	Changes     int64            `json:"changes"`
+	Entities    int64            `json:"entities"`
+	Actions     map[string]int64 `json:"actions"`
+	Points      []ChangePoint    `json:"points"`
+	MostChanged []ChangedEntity  `json:"most_changed"`
+}
+
+// ChangePoint is one bucket of an entity type
+type ChangePoint struct {
+	Start       time.Time `json:"start"`
+	StartJalali string    `json:"start_jalali,omitempty"`
+	Changes     int64     `json:"changes"`
+}
+
+// ChangedEntity is one of the entities changed most often
+type ChangedEntity struct {
+	EntityID      string    `json:"entity_id"`
+	Changes       int64     `json:"changes"`
+	LastChangedAt time.Time `json:"last_changed_at"`
+}
+
+// UserChanges counts the changes one user made per entity type. UserID is
+// nil for changes made by the system.
+type UserChanges struct {
+	UserID   *uuid.UUID       `json:"user_id,omitempty"`
+	Username string           `json:"username"`
+	Changes  int64            `json:"changes"`
+	Entities map[string]int64 `json:"entities"`
+}
+
+// Username reported for audit log entries without a user
+const systemUsername = "(system)"
+
+// BuildChangeReport counts the audit log entries of [from, to), widened
+// to whole buckets
+func BuildChangeReport(ctx context.Context, q db.Querier, cal Calendar, granularity string, from, to time.Time, filter ChangeFilter) (*ChangeReport, error) {
+	if !ValidGranularity(granularity) {
+		return nil, fmt.Errorf("unknown granularity %q", granularity)
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+	if filter.MostChanged < 0 || filter.MostChanged > MaxMostChanged {
+		return nil, fmt.Errorf("most changed must be between 0 and %d", MaxMostChanged)
+	}
+	if filter.EntityTypes == nil {
+		filter.EntityTypes = []string{}
+	}
+
+	buckets, err := cal.buckets(granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	start, end := buckets[0], cal.NextBucket(granularity, buckets[len(buckets)-1])
+	index := bucketIndex(buckets)
+
+	rows, err := q.ReportAuditChanges(ctx, db.ReportAuditChangesParams{
+		Timezone:    cal.Location.String(),
+		FromTime:    start,
+		ToTime:      end,
+		EntityTypes: filter.EntityTypes,
+		UserID:      filter.UserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count changes: %w", err)
+	}
+	top, err := q.ReportMostChangedEntities(ctx, db.ReportMostChangedEntitiesParams{
+		FromTime:    start,
+		ToTime:      end,
+		EntityTypes: filter.EntityTypes,
+		UserID:      filter.UserID,
+		PerType:     int32(max(filter.MostChanged, 1)), // one row carries the entity count
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the most changed entities: %w", err)
+	}
+
+	report := &ChangeReport{
+		Granularity: granularity,
+		Timezone:    cal.Location.String(),
+		From:        start,
+		To:          end,
+		Entities:    []EntityChanges{},
+		Users:       []UserChanges{},
+	}
+	entities := map[string]*EntityChanges{}
+	users := map[uuid.UUID]*UserChanges{}
+	for _, row := range rows {
+		e := entities[row.EntityType]
+		if e == nil {
+			e = newEntityChanges(cal, row.EntityType, buckets)
+			entities[row.EntityType] = e
+		}
+		u := users[row.UserID.UUID]
+		if u == nil {
+			u = &UserChanges{Username: row.Username, Entities: map[string]int64{}}
+			if row.UserID.Valid {
+				id := row.UserID.UUID
+				u.UserID = &id
+			}
+			if u.Username == "" {
+				u.Username = systemUsername
+			}
+			users[row.UserID.UUID] = u
+		}
+
+		report.Total += row.Changes
+		e.Changes += row.Changes
+		e.Actions[row.Action] += row.Changes
+		if i, ok := index[cal.dayBucket(granularity, row.Day).Unix()]; ok {
+			e.Points[i].Changes += row.Changes
+		}
+		u.Changes += row.Changes
+		u.Entities[row.EntityType] += row.Changes
+	}
+	for _, row := range top {
+		e := entities[row.EntityType]
+		if e == nil {
+			continue
+		}
+		e.Entities = row.Entities
+		if len(e.MostChanged) == filter.MostChanged {
+			continue
+		}
+		e.MostChanged = append(e.MostChanged, ChangedEntity{
+			EntityID:      row.EntityID,
+			Changes:       row.Changes,
+			LastChangedAt: row.LastChangedAt,
+		})
+	}
+
+	for _, e := range entities {
+		report.Entities = append(report.Entities, *e)
+	}
+	slices.SortFunc(report.Entities, func(a, b EntityChanges) int {
+		return cmp.Or(cmp.Compare(b.Changes, a.Changes), cmp.Compare(a.EntityType, b.EntityType))
+	})
+	for _, u := range users {
+		report.Users = append(report.Users, *u)
+	}
+	slices.SortFunc(report.Users, func(a, b UserChanges) int {
+		return cmp.Or(cmp.Compare(b.Changes, a.Changes), cmp.Compare(a.Username, b.Username))
+	})
+	return report, nil
+}
+
+// newEntityChanges returns an entity type with a zero point per bucket
+func newEntityChanges(cal Calendar, entityType string, buckets []time.Time) *EntityChanges {
+	e := &EntityChanges{
+		EntityType:  entityType,
+		Actions:     map[string]int64{},
+		Points:      make([]ChangePoint, len(buckets)),
+		MostChanged: []ChangedEntity{},
+	}
+	for i, b := range buckets {
+		e.Points[i].Start = b
+		e.Points[i].StartJalali = cal.jalaliDate(b)
+	}
+	return e
+}
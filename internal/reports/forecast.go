@@ -0,0 +1,203 @@
+// internal/reports/forecast.go - Demand forecasts for purchasing
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// excludedDemandStatuses are orders that never turned into demand
+var excludedDemandStatuses = []string{"cancelled", "canceled", "rejected"}
+
+// ForecastOptions sizes a forecast in buckets of Granularity: History
+// past buckets feed it, the moving average spans the last Window of them
+// and Horizon future buckets are projected
+type ForecastOptions struct {
+	Granularity string
+	History     int
+	Window      int
+	Horizon     int
+}
+
+// Forecast projects the demand for one product. History holds the
+// requested quantity of each complete bucket before now; Projection starts
+// with the current bucket.
+type Forecast struct {
+	ProductID     uuid.UUID       `json:"product_id"`
+	Granularity   string          `json:"granularity"`
+	Timezone      string          `json:"timezone"`
+	MovingAverage float64         `json:"moving_average"`
+	Trend         Trend           `json:"trend"`
+	History       []DemandPoint   `json:"history"`
+	Projection    []ForecastPoint `json:"projection"`
+
+	// ProjectedDemand sums the projection over the horizon
+	ProjectedDemand int64 `json:"projected_demand"`
+	// Stock is nil for products whose stock is not tracked
+	Stock *ForecastStock `json:"stock,omitempty"`
+	// SuggestedReorderQty covers the projected demand and the reorder
+	// level after what is on hand
+	SuggestedReorderQty int64 `json:"suggested_reorder_qty"`
+}
+
+// Trend is the least-squares line through the history: quantity =
+// Intercept + Slope*i for bucket i, counting the oldest as 0
+type Trend struct {
+	Slope     float64 `json:"slope"`
+	Intercept float64 `json:"intercept"`
+}
+
+// DemandPoint is the quantity requested in one past bucket
+type DemandPoint struct {
+	Start    time.Time `json:"start"`
+	Quantity int64     `json:"quantity"`
+}
+
+// ForecastPoint is the projected demand of one future bucket: the higher
+// of the moving average and the trend, so a rising trend is not ordered
+// short
+type ForecastPoint struct {
+	Start         time.Time `json:"start"`
+	MovingAverage float64   `json:"moving_average"`
+	Trend         float64   `json:"trend"`
+	Quantity      int64     `json:"quantity"`
+}
+
+// ForecastStock is the product's stock when the forecast was made
+type ForecastStock struct {
+	OnHand       int32 `json:"on_hand"`
+	ReorderLevel int32 `json:"reorder_level"`
+}
+
+// ValidateForecastOptions checks the sizes of a forecast
+func ValidateForecastOptions(opts ForecastOptions) error {
+	switch {
+	case !ValidGranularity(opts.Granularity):
+		return fmt.Errorf("unknown granularity %q", opts.Granularity)
+	case opts.History < 2 || opts.History > MaxBuckets:
+		return fmt.Errorf("history must be between 2 and %d", MaxBuckets)
+	case opts.Window < 1 || opts.Window > opts.History:
+		return errors.New("window must be between 1 and history")
+	case opts.Horizon < 1 || opts.Horizon > 52:
+		return errors.New("horizon must be between 1 and 52")
+	}
+	return nil
+}
+
+// BuildForecast projects the demand for a product from the orders of the
+// last opts.History buckets before now
+func BuildForecast(ctx context.Context, q db.Querier, cal Calendar, productID uuid.UUID, opts ForecastOptions, now time.Time) (*Forecast, error) {
+	if err := ValidateForecastOptions(opts); err != nil {
+		return nil, err
+	}
+
+	// Complete buckets only; the current one is still filling up
+	current := cal.BucketStart(opts.Granularity, now)
+	starts := make([]time.Time, opts.History)
+	b := current
+	for i := opts.History - 1; i >= 0; i-- {
+		b = cal.BucketStart(opts.Granularity, b.Add(-time.Nanosecond))
+		starts[i] = b
+	}
+	index := make(map[int64]int, len(starts))
+	for i, start := range starts {
+		index[start.Unix()] = i
+	}
+
+	rows, err := q.ReportProductDemand(ctx, db.ReportProductDemandParams{
+		Timezone:         cal.Location.String(),
+		ProductID:        productID,
+		ExcludedStatuses: excludedDemandStatuses,
+		FromTime:         starts[0],
+		ToTime:           current,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demand: %w", err)
+	}
+
+	f := &Forecast{
+		ProductID:   productID,
+		Granularity: opts.Granularity,
+		Timezone:    cal.Location.String(),
+		History:     make([]DemandPoint, len(starts)),
+	}
+	for i, start := range starts {
+		f.History[i].Start = start
+	}
+	for _, row := range rows {
+		y, m, d := row.Day.Date()
+		if i, ok := index[cal.BucketStart(opts.Granularity, time.Date(y, m, d, 0, 0, 0, 0, cal.Location)).Unix()]; ok {
+			f.History[i].Quantity += row.Quantity
+		}
+	}
+
+	quantities := make([]float64, len(f.History))
+	for i, p := range f.History {
+		quantities[i] = float64(p.Quantity)
+	}
+	f.MovingAverage = round2(mean(quantities[len(quantities)-opts.Window:]))
+	f.Trend = fitTrend(quantities)
+
+	start := current
+	for i := range opts.Horizon {
+		trend := math.Max(0, f.Trend.Intercept+f.Trend.Slope*float64(len(quantities)+i))
+		qty := int64(math.Ceil(math.Max(f.MovingAverage, trend)))
+		f.Projection = append(f.Projection, ForecastPoint{
+			Start:         start,
+			MovingAverage: f.MovingAverage,
+			Trend:         round2(trend),
+			Quantity:      qty,
+		})
+		f.ProjectedDemand += qty
+		start = cal.NextBucket(opts.Granularity, start)
+	}
+
+	f.SuggestedReorderQty = f.ProjectedDemand
+	stock, err := q.GetProductStock(ctx, productID)
+	switch {
+	case err == nil:
+		f.Stock = &ForecastStock{OnHand: stock.OnHand, ReorderLevel: stock.ReorderLevel}
+		f.SuggestedReorderQty = max(0, f.ProjectedDemand+int64(stock.ReorderLevel)-int64(stock.OnHand))
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to read stock: %w", err)
+	}
+	return f, nil
+}
+
+// fitTrend fits a least-squares line through ys at x = 0, 1, 2, ...
+func fitTrend(ys []float64) Trend {
+	n := float64(len(ys))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range ys {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return Trend{Intercept: round2(sumY / n)}
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	return Trend{Slope: round2(slope), Intercept: round2((sumY - slope*sumX) / n)}
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
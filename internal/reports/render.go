@@ -0,0 +1,129 @@
+// internal/reports/render.go - CSV and PDF output
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/pdf"
+)
+
+// Content types of the attachment formats
+const (
+	ContentTypeCSV = "text/csv"
+	ContentTypePDF = "application/pdf"
+)
+
+// Render writes the report in format, with times shown in the calendar's
+// zone. PDFs are drawn in fonts.
+func Render(r *Report, format string, cal Calendar, fonts pdf.Fonts) (data []byte, contentType string, err error) {
+	switch format {
+	case FormatCSV:
+		data, err = renderCSV(r, cal)
+		return data, ContentTypeCSV, err
+	case FormatPDF:
+		return renderPDF(r, cal, fonts), ContentTypePDF, nil
+	default:
+		return nil, "", fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// renderCSV writes a title block followed by each section as a header row
+// and its rows, separated by blank lines
+func renderCSV(r *Report, cal Calendar) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	generated := r.Generated.In(cal.Location)
+	w.Write([]string{r.Title})
+	w.Write([]string{"Period", r.PeriodLabel(cal)})
+	w.Write([]string{"Generated", generated.Format(time.RFC3339)})
+	if cal.Jalali {
+		w.Write([]string{"Generated (Jalali)", jalali.FormatDateTime(generated)})
+	}
+	for _, s := range r.Sections {
+		w.Write(nil)
+		w.Write([]string{s.Title})
+		w.Write(s.Columns)
+		for _, row := range s.Rows {
+			w.Write(row)
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Layout of report pages
+const (
+	rowHeight    = 14.0
+	bodySize     = 9.0
+	maxColumnLen = 40
+)
+
+func renderPDF(r *Report, cal Calendar, fonts pdf.Fonts) []byte {
+	doc := pdf.New(pdf.Options{
+		Fonts:    fonts,
+		Footer:   "DigiOrder",
+		Jalali:   cal.Jalali,
+		Location: cal.Location,
+	})
+	doc.Paragraph(doc.Y, 16, true, r.Title)
+	doc.Y -= 20
+	doc.Text(pdf.Margin, doc.Y, bodySize, false, "Period: "+r.PeriodLabel(cal)+"    Generated: "+doc.DateTime(r.Generated))
+	doc.Y -= 22
+	for _, h := range r.Highlights {
+		doc.Paragraph(doc.Y, 10, false, "• "+h)
+		doc.Y -= rowHeight
+	}
+
+	for _, s := range r.Sections {
+		doc.Y -= 12
+		doc.Ensure(3 * rowHeight)
+		doc.Paragraph(doc.Y, 12, true, s.Title)
+		doc.Y -= 18
+
+		widths := columnWidths(s)
+		header := func() {
+			doc.Row(pdf.Margin, widths, bodySize, true, s.Columns)
+			doc.Y -= rowHeight
+			doc.Line(pdf.Margin, doc.Y+rowHeight-3, pdf.PageWidth-pdf.Margin, doc.Y+rowHeight-3)
+		}
+		header()
+		if len(s.Rows) == 0 {
+			doc.Text(pdf.Margin, doc.Y, bodySize, false, "None")
+			doc.Y -= rowHeight
+		}
+		for _, row := range s.Rows {
+			if doc.Ensure(rowHeight) {
+				header()
+			}
+			doc.Row(pdf.Margin, widths, bodySize, false, row)
+			doc.Y -= rowHeight
+		}
+	}
+	return doc.Bytes()
+}
+
+// columnWidths shares the page width between columns by content length
+func columnWidths(s Section) []float64 {
+	lengths := make([]float64, len(s.Columns))
+	var total float64
+	for i, col := range s.Columns {
+		n := len([]rune(col))
+		for _, row := range s.Rows {
+			if i < len(row) {
+				n = max(n, len([]rune(row[i])))
+			}
+		}
+		lengths[i] = float64(min(max(n, 4), maxColumnLen))
+		total += lengths[i]
+	}
+	for i := range lengths {
+		lengths[i] = lengths[i] / total * (pdf.PageWidth - 2*pdf.Margin)
+	}
+	return lengths
+}
@@ -0,0 +1,235 @@
+// internal/reports/reports.go - Report contents built from the database
+package reports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+)
+
+// Report kinds, the report column of report_schedules
+const (
+	KindOrderSummary = "order_summary"
+	KindLowStock     = "low_stock"
+	KindAuditDigest  = "audit_digest"
+	KindSaved        = "saved" // a saved report, see BuildSaved
+)
+
+// Kinds lists every report that can be scheduled
+var Kinds = []string{KindOrderSummary, KindLowStock, KindAuditDigest, KindSaved}
+
+// Output formats
+const (
+	FormatCSV = "csv"
+	FormatPDF = "pdf"
+)
+
+// Formats lists every attachment format
+var Formats = []string{FormatCSV, FormatPDF}
+
+// ValidKind reports whether kind is a known report
+func ValidKind(kind string) bool {
+	return slices.Contains(Kinds, kind)
+}
+
+// ValidFormat reports whether format is a known attachment format
+func ValidFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// DefaultFrequency is how often a report is sent unless the schedule says
+// otherwise
+func DefaultFrequency(kind string) string {
+	switch kind {
+	case KindLowStock:
+		return FrequencyWeekly
+	case KindAuditDigest:
+		return FrequencyMonthly
+	default:
+		return FrequencyDaily
+	}
+}
+
+// topProducts caps the product and user rankings
+const topProducts = 20
+
+// Report is a rendered-format-independent report: a title, the period it
+// covers, a few highlight lines and one or more tables
+type Report struct {
+	Kind       string
+	Title      string
+	From       time.Time // inclusive
+	To         time.Time // exclusive
+	Generated  time.Time
+	Highlights []string
+	Sections   []Section
+}
+
+// Section is one table of a report
+type Section struct {
+	Title   string
+	Columns []string
+	Rows    [][]string
+}
+
+// PeriodLabel describes the covered period in the calendar's zone, e.g.
+// "2025-01-06" for a day or "2025-01-06 to 2025-01-12", followed by the
+// Jalali dates in parentheses when the calendar asks for them
+func (r *Report) PeriodLabel(cal Calendar) string {
+	from := r.From.In(cal.Location)
+	last := r.To.In(cal.Location).AddDate(0, 0, -1)
+	label := func(format func(time.Time) string) string {
+		if !last.After(from) {
+			return format(from)
+		}
+		return format(from) + " to " + format(last)
+	}
+	period := label(func(t time.Time) string { return t.Format(time.DateOnly) })
+	if cal.Jalali {
+		period += " (" + label(jalali.FormatDate) + ")"
+	}
+	return period
+}
+
+// Filename names the attachment, e.g. order_summary-20250106.pdf
+func (r *Report) Filename(format string, loc *time.Location) string {
+	return r.Kind + "-" + r.From.In(loc).Format("20060102") + "." + format
+}
+
+// Build queries the data of a report covering [from, to)
+func Build(ctx context.Context, q db.Querier, kind string, from, to time.Time) (*Report, error) {
+	r := &Report{Kind: kind, From: from, To: to, Generated: time.Now()}
+
+	var err error
+	switch kind {
+	case KindOrderSummary:
+		r.Title = "Order summary"
+		err = buildOrderSummary(ctx, q, r)
+	case KindLowStock:
+		r.Title = "Low stock"
+		err = buildLowStock(ctx, q, r)
+	case KindAuditDigest:
+		r.Title = "Audit digest"
+		err = buildAuditDigest(ctx, q, r)
+	case KindSaved:
+		return nil, fmt.Errorf("%s reports are built from their definition with BuildSaved", kind)
+	default:
+		return nil, fmt.Errorf("unknown report %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s report: %w", kind, err)
+	}
+	return r, nil
+}
+
+func buildOrderSummary(ctx context.Context, q db.Querier, r *Report) error {
+	counts, err := q.ReportOrderCounts(ctx, db.ReportOrderCountsParams{FromTime: r.From, ToTime: r.To})
+	if err != nil {
+		return err
+	}
+	products, err := q.ReportTopRequestedProducts(ctx, db.ReportTopRequestedProductsParams{
+		FromTime:   r.From,
+		ToTime:     r.To,
+		LimitCount: topProducts,
+	})
+	if err != nil {
+		return err
+	}
+
+	var total, urgent int64
+	byStatus := Section{Title: "Orders by status and priority", Columns: []string{"Status", "Priority", "Orders"}}
+	for _, c := range counts {
+		total += c.Orders
+		if c.Priority == "urgent" || c.Priority == "stat" {
+			urgent += c.Orders
+		}
+		byStatus.Rows = append(byStatus.Rows, []string{c.Status, c.Priority, itoa(c.Orders)})
+	}
+
+	top := Section{Title: "Most requested products", Columns: []string{"Product", "Strength", "Unit", "Orders", "Requested"}}
+	for _, p := range products {
+		top.Rows = append(top.Rows, []string{p.Name, p.Strength.String, p.Unit.String, itoa(p.Orders), itoa(p.RequestedQty)})
+	}
+
+	r.Highlights = []string{
+		fmt.Sprintf("%d orders created", total),
+		fmt.Sprintf("%d urgent or stat", urgent),
+	}
+	r.Sections = []Section{byStatus, top}
+	return nil
+}
+
+func buildLowStock(ctx context.Context, q db.Querier, r *Report) error {
+	rows, err := q.ReportLowStock(ctx, db.ReportLowStockParams{FromTime: r.From, ToTime: r.To})
+	if err != nil {
+		return err
+	}
+
+	var out int
+	low := Section{
+		Title:   "Products at or below their reorder level",
+		Columns: []string{"Product", "Strength", "Unit", "On hand", "Reorder level", "Requested in period"},
+	}
+	for _, p := range rows {
+		if p.OnHand == 0 {
+			out++
+		}
+		low.Rows = append(low.Rows, []string{
+			p.Name, p.Strength.String, p.Unit.String,
+			itoa(int64(p.OnHand)), itoa(int64(p.ReorderLevel)), itoa(p.RequestedQty),
+		})
+	}
+
+	r.Highlights = []string{
+		fmt.Sprintf("%d products at or below their reorder level", len(rows)),
+		fmt.Sprintf("%d out of stock", out),
+	}
+	r.Sections = []Section{low}
+	return nil
+}
+
+func buildAuditDigest(ctx context.Context, q db.Querier, r *Report) error {
+	actions, err := q.ReportAuditActions(ctx, db.ReportAuditActionsParams{FromTime: r.From, ToTime: r.To})
+	if err != nil {
+		return err
+	}
+	users, err := q.ReportAuditUsers(ctx, db.ReportAuditUsersParams{FromTime: r.From, ToTime: r.To, LimitCount: topProducts})
+	if err != nil {
+		return err
+	}
+	logins, err := q.ReportLoginSummary(ctx, db.ReportLoginSummaryParams{FromTime: r.From, ToTime: r.To})
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	byAction := Section{Title: "Changes by entity and action", Columns: []string{"Entity", "Action", "Events"}}
+	for _, a := range actions {
+		total += a.Events
+		byAction.Rows = append(byAction.Rows, []string{a.EntityType, a.Action, itoa(a.Events)})
+	}
+
+	byUser := Section{Title: "Most active users", Columns: []string{"User", "Events"}}
+	for _, u := range users {
+		byUser.Rows = append(byUser.Rows, []string{u.Username, itoa(u.Events)})
+	}
+
+	security := Section{Title: "Sign-ins", Columns: []string{"Attempts", "Failed", "Rate limited", "IPs with failures"}}
+	security.Rows = [][]string{{itoa(logins.Attempts), itoa(logins.Failed), itoa(logins.RateLimited), itoa(logins.FailedIps)}}
+
+	r.Highlights = []string{
+		fmt.Sprintf("%d audited changes", total),
+		fmt.Sprintf("%d failed sign-ins from %d IPs", logins.Failed, logins.FailedIps),
+	}
+	r.Sections = []Section{byAction, byUser, security}
+	return nil
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
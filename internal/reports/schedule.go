@@ -0,0 +1,118 @@
+// internal/reports/schedule.go - Send times and report periods
+package reports
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+)
+
+// Frequencies
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Frequencies lists every schedule frequency
+var Frequencies = []string{FrequencyDaily, FrequencyWeekly, FrequencyMonthly}
+
+// ValidFrequency reports whether frequency is known
+func ValidFrequency(frequency string) bool {
+	return slices.Contains(Frequencies, frequency)
+}
+
+// Calendar places send times in the reporting time zone. Weekly reports go
+// out on WeekStart, monthly ones on the first of the month. With Jalali
+// set, rendered reports also label dates in the Jalali calendar.
+type Calendar struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+	Jalali    bool
+}
+
+// NewCalendar loads the time zone and parses the week start day; system
+// is gregorian or jalali
+func NewCalendar(timezone, weekStart, system string) (Calendar, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("unknown time zone %q: %w", timezone, err)
+	}
+	day, err := ParseWeekday(weekStart)
+	if err != nil {
+		return Calendar{}, err
+	}
+	if !jalali.IsCalendar(system) {
+		return Calendar{}, fmt.Errorf("unknown calendar %q", system)
+	}
+	return Calendar{Location: loc, WeekStart: day, Jalali: system == jalali.Jalali}, nil
+}
+
+// jalaliDate is the Jalali date of t when the calendar labels dates in it
+func (c Calendar) jalaliDate(t time.Time) string {
+	if !c.Jalali {
+		return ""
+	}
+	return jalali.FormatDate(t.In(c.Location))
+}
+
+// ParseWeekday parses an English day name such as "monday" or "Sat"
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("unknown weekday %q", name)
+}
+
+// NextRun returns the first send time strictly after after
+func (c Calendar) NextRun(frequency string, hour int, after time.Time) time.Time {
+	local := after.In(c.Location)
+	y, m, d := local.Date()
+
+	switch frequency {
+	case FrequencyMonthly:
+		next := time.Date(y, m, 1, hour, 0, 0, 0, c.Location)
+		if !next.After(after) {
+			next = time.Date(y, m+1, 1, hour, 0, 0, 0, c.Location)
+		}
+		return next
+	case FrequencyWeekly:
+		next := time.Date(y, m, d, hour, 0, 0, 0, c.Location)
+		for next.Weekday() != c.WeekStart || !next.After(after) {
+			y, m, d = next.Date()
+			next = time.Date(y, m, d+1, hour, 0, 0, 0, c.Location)
+		}
+		return next
+	default:
+		next := time.Date(y, m, d, hour, 0, 0, 0, c.Location)
+		if !next.After(after) {
+			next = time.Date(y, m, d+1, hour, 0, 0, 0, c.Location)
+		}
+		return next
+	}
+}
+
+// Period returns the span a report sent at runAt covers: the previous day,
+// the previous seven days or the previous calendar month, ending at
+// midnight of runAt's day
+func (c Calendar) Period(frequency string, runAt time.Time) (from, to time.Time) {
+	y, m, d := runAt.In(c.Location).Date()
+	to = time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+
+	switch frequency {
+	case FrequencyMonthly:
+		to = time.Date(y, m, 1, 0, 0, 0, 0, c.Location)
+		return time.Date(y, m-1, 1, 0, 0, 0, 0, c.Location), to
+	case FrequencyWeekly:
+		return time.Date(y, m, d-7, 0, 0, 0, 0, c.Location), to
+	default:
+		return time.Date(y, m, d-1, 0, 0, 0, 0, c.Location), to
+	}
+}
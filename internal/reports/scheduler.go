@@ -0,0 +1,288 @@
+// internal/reports/scheduler.go - Emailing due report schedules
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/pdf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Run outcomes, the last_status column of report_schedules
+const (
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+var reportsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "scheduled_reports_total",
+		Help: "Scheduled report runs by report and outcome (sent, failed, skipped)",
+	},
+	[]string{"report", "status"},
+)
+
+// TxFunc runs fn inside a database transaction
+type TxFunc func(ctx context.Context, fn func(q db.Querier) error) error
+
+// ScopeFunc scopes the statements issued with the returned context to a
+// tenant until release is called
+type ScopeFunc func(ctx context.Context, tenantID uuid.UUID) (scoped context.Context, release func(), err error)
+
+// Config controls how often due schedules are looked for
+type Config struct {
+	Interval time.Duration // 0 disables the scheduler
+	Calendar Calendar
+	Fonts    pdf.Fonts // draw PDF attachments; the zero value uses the standard fonts
+	Conn     db.DBTX   // runs saved reports; nil fails their schedules
+	Scope    ScopeFunc // limits reports to the recipient's tenant; nil covers every tenant
+}
+
+// Scheduler sends due report schedules by email through the notification
+// dispatcher. Due rows are claimed with SKIP LOCKED, so each report goes
+// out once even with several instances running.
+type Scheduler struct {
+	queries   db.Querier
+	withTx    TxFunc
+	notifier  *notify.Dispatcher
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler. Call Start to begin sending.
+func NewScheduler(queries db.Querier, withTx TxFunc, notifier *notify.Dispatcher, config Config, logger *logging.Logger) *Scheduler {
+	if config.Calendar.Location == nil {
+		config.Calendar.Location = time.UTC
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		queries:  queries,
+		withTx:   withTx,
+		notifier: notifier,
+		config:   config,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	if config.Interval > 0 {
+		s.heartbeat = middleware.NewHeartbeat("report_scheduler", config.Interval)
+	}
+	return s
+}
+
+// Calendar returns the reporting calendar
+func (s *Scheduler) Calendar() Calendar {
+	return s.config.Calendar
+}
+
+// Start launches the loop that sends due reports
+func (s *Scheduler) Start() {
+	if s.heartbeat == nil {
+		return
+	}
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop ends the loop, waiting for a send in progress until ctx expires
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the loop is running, or nil when disabled
+func (s *Scheduler) Heartbeat() *middleware.Heartbeat {
+	return s.heartbeat
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue()
+		s.heartbeat.Beat()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends due schedules one at a time until none are left
+func (s *Scheduler) sendDue() {
+	for s.ctx.Err() == nil {
+		sent, err := s.sendNext(s.ctx)
+		if err != nil {
+			s.logger.Error("Failed to process report schedules", err, nil)
+			return
+		}
+		if !sent {
+			return
+		}
+	}
+}
+
+// sendNext claims the oldest due schedule, sends it and advances it to its
+// next run. A late run still covers the period of its scheduled time.
+func (s *Scheduler) sendNext(ctx context.Context) (bool, error) {
+	found := false
+	err := s.withTx(ctx, func(q db.Querier) error {
+		due, err := q.ClaimDueReportSchedules(ctx, db.ClaimDueReportSchedulesParams{Now: time.Now(), LimitCount: 1})
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		found = true
+		schedule := due[0]
+
+		// Reports are read outside the transaction so a failing query
+		// cannot abort it
+		status, sendErr := s.Send(ctx, schedule, schedule.NextRunAt)
+		now := time.Now()
+		return q.MarkReportScheduleRun(ctx, db.MarkReportScheduleRunParams{
+			ID:         schedule.ID,
+			LastRunAt:  sql.NullTime{Time: now, Valid: true},
+			LastStatus: sql.NullString{String: status, Valid: true},
+			LastError:  errorString(sendErr),
+			NextRunAt:  s.config.Calendar.NextRun(schedule.Frequency, int(schedule.SendHour), now),
+		})
+	})
+	return found, err
+}
+
+// Send builds the schedule's report for the period ending before runAt and
+// queues it for the recipient. It returns the run status; the error says
+// why a run failed or was skipped.
+func (s *Scheduler) Send(ctx context.Context, schedule db.ReportSchedule, runAt time.Time) (string, error) {
+	status, err := s.send(ctx, schedule, runAt)
+	reportsTotal.WithLabelValues(schedule.Report, status).Inc()
+	if err != nil && status == StatusFailed {
+		s.logger.Error("Scheduled report failed", err, map[string]any{
+			"schedule_id": schedule.ID.String(),
+			"report":      schedule.Report,
+		})
+	}
+	return status, err
+}
+
+func (s *Scheduler) send(ctx context.Context, schedule db.ReportSchedule, runAt time.Time) (string, error) {
+	if !s.notifier.Enabled(notify.ChannelEmail) {
+		return StatusSkipped, errors.New("email delivery is not configured")
+	}
+
+	recipient, err := s.queries.GetReportRecipient(ctx, schedule.RecipientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return StatusSkipped, errors.New("recipient no longer exists")
+	}
+	if err != nil {
+		return StatusFailed, err
+	}
+	if recipient.Email.String == "" {
+		return StatusSkipped, errors.New("recipient has no email address")
+	}
+	if s.config.Scope != nil {
+		scoped, release, err := s.config.Scope(ctx, recipient.TenantID)
+		if err != nil {
+			return StatusFailed, err
+		}
+		defer release()
+		ctx = scoped
+	}
+
+	cal := s.config.Calendar
+	from, to := cal.Period(schedule.Frequency, runAt)
+	report, err := s.build(ctx, schedule, from, to)
+	if err != nil {
+		return StatusFailed, err
+	}
+	data, contentType, err := Render(report, schedule.Format, cal, s.config.Fonts)
+	if err != nil {
+		return StatusFailed, err
+	}
+
+	filename := report.Filename(schedule.Format, cal.Location)
+	name := recipient.Username
+	if recipient.FullName.String != "" {
+		name = recipient.FullName.String
+	}
+	subject, text, html, err := notify.Render(notify.EventScheduledReport, map[string]any{
+		"Name":       name,
+		"Title":      report.Title,
+		"Frequency":  schedule.Frequency,
+		"Period":     report.PeriodLabel(cal),
+		"Filename":   filename,
+		"Highlights": report.Highlights,
+	})
+	if err != nil {
+		return StatusFailed, err
+	}
+
+	queued := s.notifier.Enqueue(notify.Message{
+		Channel:     notify.ChannelEmail,
+		Event:       notify.EventScheduledReport,
+		To:          recipient.Email.String,
+		Subject:     subject,
+		Text:        text,
+		HTML:        html,
+		Attachments: []notify.Attachment{{Filename: filename, ContentType: contentType, Data: data}},
+	})
+	if !queued {
+		return StatusFailed, errors.New("notification queue is full")
+	}
+	return StatusSent, nil
+}
+
+// build queries the report of a schedule covering [from, to)
+func (s *Scheduler) build(ctx context.Context, schedule db.ReportSchedule, from, to time.Time) (*Report, error) {
+	if schedule.Report != KindSaved {
+		return Build(ctx, s.queries, schedule.Report, from, to)
+	}
+	if s.config.Conn == nil {
+		return nil, errors.New("saved reports need a database connection")
+	}
+	saved, err := s.queries.GetSavedReport(ctx, schedule.SavedReportID.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved report: %w", err)
+	}
+	return BuildSaved(ctx, s.config.Conn, saved, from, to, s.config.Calendar.Location)
+}
+
+func errorString(err error) sql.NullString {
+	if err == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: err.Error(), Valid: true}
+}
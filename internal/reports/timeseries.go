@@ -0,0 +1,247 @@
+// internal/reports/timeseries.go - Order statistics bucketed over time
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// Time series granularities
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// Granularities lists every bucket size
+var Granularities = []string{GranularityDay, GranularityWeek, GranularityMonth}
+
+// Time series groupings; GroupNone puts everything in one series
+const (
+	GroupNone       = ""
+	GroupStatus     = "status"
+	GroupCategory   = "category"
+	GroupDepartment = "department"
+)
+
+// GroupBys lists the groupings a time series can be split by
+var GroupBys = []string{GroupStatus, GroupCategory, GroupDepartment}
+
+// MaxBuckets caps the points of each series
+const MaxBuckets = 400
+
+// ErrTooManyBuckets is returned for ranges longer than MaxBuckets buckets
+var ErrTooManyBuckets = fmt.Errorf("the range spans more than %d buckets", MaxBuckets)
+
+// Series keys used when there is no group, category or department
+const (
+	seriesAll           = "all"
+	seriesUncategorized = "uncategorized"
+	seriesNoDepartment  = "no_department"
+)
+
+// ValidGranularity reports whether granularity is a known bucket size
+func ValidGranularity(granularity string) bool {
+	return slices.Contains(Granularities, granularity)
+}
+
+// ValidGroupBy reports whether groupBy is empty or a known grouping
+func ValidGroupBy(groupBy string) bool {
+	return groupBy == GroupNone || slices.Contains(GroupBys, groupBy)
+}
+
+// TimeSeries holds order statistics per bucket from From (inclusive) to
+// To (exclusive), both on bucket boundaries. Every series has a point for
+// every bucket, zero when nothing was ordered.
+type TimeSeries struct {
+	Granularity string    `json:"granularity"`
+	GroupBy     string    `json:"group_by,omitempty"`
+	Timezone    string    `json:"timezone"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Series      []Series  `json:"series"`
+}
+
+// Series is one line of a chart: every order, or the orders of one status
+// or category
+type Series struct {
+	Key    string  `json:"key"`
+	Total  Totals  `json:"total"`
+	Points []Point `json:"points"`
+}
+
+// Point is one bucket of a series
+type Point struct {
+	Start       time.Time `json:"start"`
+	StartJalali string    `json:"start_jalali,omitempty"`
+	Orders      int64     `json:"orders"`
+	Items       int64     `json:"items"`
+	Quantity    int64     `json:"quantity"`
+}
+
+// Totals counts orders, their items and the requested quantity. Grouped
+// by category, an order with items in several categories counts in each.
+type Totals struct {
+	Orders   int64 `json:"orders"`
+	Items    int64 `json:"items"`
+	Quantity int64 `json:"quantity"`
+}
+
+func (t *Totals) add(row db.ReportOrderTimeSeriesRow) {
+	t.Orders += row.Orders
+	t.Items += row.Items
+	t.Quantity += row.Quantity
+}
+
+func (p *Point) add(row db.ReportOrderTimeSeriesRow) {
+	p.Orders += row.Orders
+	p.Items += row.Items
+	p.Quantity += row.Quantity
+}
+
+// BucketStart returns the start of the bucket holding t: midnight, the
+// last WeekStart or the first of the month
+func (c Calendar) BucketStart(granularity string, t time.Time) time.Time {
+	y, m, d := t.In(c.Location).Date()
+	switch granularity {
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, c.Location)
+	case GranularityWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+		back := (int(day.Weekday()) - int(c.WeekStart) + 7) % 7
+		return day.AddDate(0, 0, -back)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+	}
+}
+
+// NextBucket returns the start of the bucket after the one starting at
+// start
+func (c Calendar) NextBucket(granularity string, start time.Time) time.Time {
+	switch granularity {
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// buckets returns the starts of the buckets covering [from, to)
+func (c Calendar) buckets(granularity string, from, to time.Time) ([]time.Time, error) {
+	var buckets []time.Time
+	for b := c.BucketStart(granularity, from); b.Before(to); b = c.NextBucket(granularity, b) {
+		if len(buckets) == MaxBuckets {
+			return nil, ErrTooManyBuckets
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// dayBucket returns the start of the bucket holding a date read from the
+// database, which comes back as midnight UTC
+func (c Calendar) dayBucket(granularity string, day time.Time) time.Time {
+	y, m, d := day.Date()
+	return c.BucketStart(granularity, time.Date(y, m, d, 0, 0, 0, 0, c.Location))
+}
+
+// bucketIndex maps each bucket start to its position
+func bucketIndex(buckets []time.Time) map[int64]int {
+	index := make(map[int64]int, len(buckets))
+	for i, b := range buckets {
+		index[b.Unix()] = i
+	}
+	return index
+}
+
+// BuildOrderTimeSeries counts the orders created in [from, to), widened to
+// whole buckets, per bucket and group
+func BuildOrderTimeSeries(ctx context.Context, q db.Querier, cal Calendar, granularity, groupBy string, from, to time.Time) (*TimeSeries, error) {
+	if !ValidGranularity(granularity) {
+		return nil, fmt.Errorf("unknown granularity %q", granularity)
+	}
+	if !ValidGroupBy(groupBy) {
+		return nil, fmt.Errorf("unknown grouping %q", groupBy)
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+
+	buckets, err := cal.buckets(granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	start, end := buckets[0], cal.NextBucket(granularity, buckets[len(buckets)-1])
+	index := bucketIndex(buckets)
+
+	rows, err := q.ReportOrderTimeSeries(ctx, db.ReportOrderTimeSeriesParams{
+		Timezone: cal.Location.String(),
+		GroupBy:  groupBy,
+		FromTime: start,
+		ToTime:   end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	ts := &TimeSeries{
+		Granularity: granularity,
+		GroupBy:     groupBy,
+		Timezone:    cal.Location.String(),
+		From:        start,
+		To:          end,
+		Series:      []Series{},
+	}
+	series := map[string]*Series{}
+	var keys []string
+	for _, row := range rows {
+		key := row.GroupKey
+		switch {
+		case groupBy == GroupNone:
+			key = seriesAll
+		case key == "" && groupBy == GroupDepartment:
+			key = seriesNoDepartment
+		case key == "":
+			key = seriesUncategorized
+		}
+		s, ok := series[key]
+		if !ok {
+			s = newSeries(cal, key, buckets)
+			series[key] = s
+			keys = append(keys, key)
+		}
+
+		i, ok := index[cal.dayBucket(granularity, row.Day).Unix()]
+		if !ok {
+			continue
+		}
+		s.Points[i].add(row)
+		s.Total.add(row)
+	}
+
+	slices.Sort(keys)
+	for _, key := range keys {
+		ts.Series = append(ts.Series, *series[key])
+	}
+	if len(ts.Series) == 0 && groupBy == GroupNone {
+		ts.Series = append(ts.Series, *newSeries(cal, seriesAll, buckets))
+	}
+	return ts, nil
+}
+
+// newSeries returns a series with a zero point per bucket
+func newSeries(cal Calendar, key string, buckets []time.Time) *Series {
+	s := &Series{Key: key, Points: make([]Point, len(buckets))}
+	for i, b := range buckets {
+		s.Points[i].Start = b
+		s.Points[i].StartJalali = cal.jalaliDate(b)
+	}
+	return s
+}
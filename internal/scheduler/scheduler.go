@@ -0,0 +1,326 @@
+// internal/scheduler/scheduler.go - Periodic jobs on cron schedules
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/cron"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Run outcomes, the last_status column of scheduled_jobs
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var (
+	// ErrUnknownJob is returned for a job name that was never added
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrRunning is returned when the job is already running here or on
+	// another instance
+	ErrRunning = errors.New("job is already running")
+)
+
+var runsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "scheduled_job_runs_total",
+		Help: "Scheduled job runs by job and outcome (succeeded, failed)",
+	},
+	[]string{"job", "status"},
+)
+
+// Func is the work of a job. ctx ends when the run times out or the
+// scheduler stops.
+type Func func(ctx context.Context) error
+
+// Config controls when jobs run
+type Config struct {
+	Enabled  bool           // false runs jobs only on request
+	Location *time.Location // cron expressions are read in Location
+	Timeout  time.Duration  // longest a run may take
+}
+
+// Job describes an added job
+type Job struct {
+	Name     string
+	Schedule string    // cron expression; empty when the job only runs on request
+	NextRun  time.Time // zero when the job has no schedule or the scheduler is off
+	Running  bool      // running on this instance
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule *cron.Schedule
+	run      Func
+	next     time.Time
+	running  atomic.Bool
+}
+
+// Scheduler runs jobs on cron schedules. Every instance runs the
+// scheduler, but a run is claimed in scheduled_jobs first, so each
+// scheduled run happens on one instance only. Runs missed while no
+// instance was up are skipped.
+type Scheduler struct {
+	queries   db.Querier
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	mu   sync.Mutex
+	jobs []*job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler. Add the jobs, then call Start.
+func NewScheduler(queries db.Querier, config Config, logger *logging.Logger) *Scheduler {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		queries: queries,
+		config:  config,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if config.Enabled {
+		// The loop wakes every minute
+		s.heartbeat = middleware.NewHeartbeat("scheduler", time.Minute)
+	}
+	return s
+}
+
+// Add registers a job under a unique name. An empty spec adds a job that
+// only runs on request.
+func (s *Scheduler) Add(name, spec string, run Func) error {
+	j := &job{name: name, spec: spec, run: run}
+	if spec != "" {
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", name, err)
+		}
+		j.schedule = &schedule
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.jobs {
+		if existing.name == name {
+			return fmt.Errorf("job %s is already added", name)
+		}
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Start launches the loop that runs due jobs
+func (s *Scheduler) Start() {
+	if s.heartbeat == nil {
+		return
+	}
+
+	now := time.Now().In(s.config.Location)
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		if j.schedule != nil {
+			j.next = j.schedule.Next(now)
+		}
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop ends the loop, waiting for runs in progress until ctx expires.
+// Their context is cancelled first.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the loop is running, or nil when disabled
+func (s *Scheduler) Heartbeat() *middleware.Heartbeat {
+	return s.heartbeat
+}
+
+// Jobs lists the added jobs in the order they were added
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, len(s.jobs))
+	for i, j := range s.jobs {
+		jobs[i] = Job{
+			Name:     j.name,
+			Schedule: j.spec,
+			NextRun:  j.next,
+			Running:  j.running.Load(),
+		}
+	}
+	return jobs
+}
+
+// RunNow starts a run of the job outside its schedule and returns once the
+// run is claimed; the job itself runs in the background
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	var found *job
+	for _, j := range s.jobs {
+		if j.name == name {
+			found = j
+		}
+	}
+	s.mu.Unlock()
+	if found == nil {
+		return ErrUnknownJob
+	}
+
+	if !found.running.CompareAndSwap(false, true) {
+		return ErrRunning
+	}
+	claimed, err := s.claim(ctx, found, time.Now())
+	if err != nil || !claimed {
+		found.running.Store(false)
+		if err == nil {
+			err = ErrRunning
+		}
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.execute(found)
+	return nil
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	for {
+		now := time.Now().In(s.config.Location)
+		s.mu.Lock()
+		for _, j := range s.jobs {
+			if j.schedule == nil || j.next.IsZero() || j.next.After(now) {
+				continue
+			}
+			s.startScheduled(j, j.next)
+			j.next = j.schedule.Next(now)
+		}
+		s.mu.Unlock()
+		s.heartbeat.Beat()
+
+		// Schedules have minute resolution
+		timer := time.NewTimer(time.Until(now.Truncate(time.Minute).Add(time.Minute)))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// startScheduled runs j for the run due at due, unless its previous run is
+// still going
+func (s *Scheduler) startScheduled(j *job, due time.Time) {
+	if !j.running.CompareAndSwap(false, true) {
+		s.logger.Warn("Scheduled job still running; skipping a run", map[string]any{"job": j.name})
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		claimed, err := s.claim(s.ctx, j, due)
+		if err != nil || !claimed {
+			if err != nil {
+				s.logger.Error("Failed to claim scheduled job", err, map[string]any{"job": j.name})
+			}
+			j.running.Store(false)
+			s.wg.Done()
+			return
+		}
+		s.execute(j)
+	}()
+}
+
+// claim records the run in scheduled_jobs. False means another instance
+// has already started the run or is still running the job.
+func (s *Scheduler) claim(ctx context.Context, j *job, due time.Time) (bool, error) {
+	_, err := s.queries.ClaimScheduledJob(ctx, db.ClaimScheduledJobParams{
+		Name:        j.name,
+		Schedule:    j.spec,
+		Due:         due,
+		StaleBefore: time.Now().Add(-s.config.Timeout),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// execute runs a claimed job and records the outcome
+func (s *Scheduler) execute(j *job) {
+	defer s.wg.Done()
+	defer j.running.Store(false)
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.Timeout)
+	started := time.Now()
+	err := j.run(ctx)
+	cancel()
+	elapsed := time.Since(started)
+
+	status := StatusSucceeded
+	var lastError sql.NullString
+	if err != nil {
+		status = StatusFailed
+		lastError = sql.NullString{String: err.Error(), Valid: true}
+		s.logger.Error("Scheduled job failed", err, map[string]any{"job": j.name, "duration_ms": elapsed.Milliseconds()})
+	} else {
+		s.logger.Debug("Scheduled job finished", map[string]any{"job": j.name, "duration_ms": elapsed.Milliseconds()})
+	}
+	runsTotal.WithLabelValues(j.name, status).Inc()
+
+	// Recorded even when the scheduler is stopping
+	recordCtx, cancelRecord := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelRecord()
+	if err := s.queries.FinishScheduledJob(recordCtx, db.FinishScheduledJobParams{
+		Status:     status,
+		Error:      lastError,
+		DurationMs: elapsed.Milliseconds(),
+		Name:       j.name,
+	}); err != nil {
+		s.logger.Error("Failed to record scheduled job run", err, map[string]any{"job": j.name})
+	}
+}
@@ -0,0 +1,33 @@
+// internal/secrets/awssm.go - AWS Secrets Manager provider
+package secrets
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/awsapi"
+)
+
+// AWSSecretsManager reads the current version of secrets by name or ARN:
+// awssm:secret-id#field, the field picking a key of a JSON secret such as
+// those RDS rotates
+type AWSSecretsManager struct {
+	Client awsapi.Client
+}
+
+// Fetch implements Provider
+func (a AWSSecretsManager) Fetch(ctx context.Context, id string) (Secret, error) {
+	client := a.Client
+	client.Service = "secretsmanager"
+
+	var out struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := client.Call(ctx, "secretsmanager.GetSecretValue", map[string]string{"SecretId": id}, &out); err != nil {
+		return Secret{}, err
+	}
+	if out.SecretString == nil {
+		return Secret{}, errors.New("secret is binary; only string secrets are supported")
+	}
+	return Secret{Value: *out.SecretString}, nil
+}
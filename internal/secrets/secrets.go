@@ -0,0 +1,147 @@
+// internal/secrets/secrets.go - Resolving secret references
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A reference names a secret instead of holding it: "scheme:path", with
+// "#field" picking one value of a secret holding several (a JSON object,
+// or the key/value data of a Vault secret), e.g.
+//
+//	env:DB_PASSWORD
+//	file:/run/secrets/jwt_secret
+//	vault:secret/data/digiorder#db_password
+//	awssm:prod/digiorder#password
+
+// Secret is what a provider returns for a path: a single value, the
+// fields of a structured secret, or both
+type Secret struct {
+	Value  string
+	Fields map[string]string
+}
+
+// Provider fetches secrets from one store
+type Provider interface {
+	Fetch(ctx context.Context, path string) (Secret, error)
+}
+
+// field returns the value named by a reference's field, parsing Value as
+// a JSON object when the provider returned no fields
+func (s Secret) field(name string) (string, error) {
+	if name == "" {
+		if s.Fields != nil && s.Value == "" {
+			return "", errors.New("secret has several fields; name one with #field")
+		}
+		return s.Value, nil
+	}
+	fields := s.Fields
+	if fields == nil {
+		var err error
+		if fields, err = parseFields([]byte(s.Value)); err != nil {
+			return "", fmt.Errorf("secret is not a JSON object, so has no field %q", name)
+		}
+	}
+	value, ok := fields[name]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", name)
+	}
+	return value, nil
+}
+
+// parseFields reads a JSON object as fields, keeping non-string values as
+// JSON
+func parseFields(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string, len(raw))
+	for name, value := range raw {
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			fields[name] = s
+		} else {
+			fields[name] = string(value)
+		}
+	}
+	return fields, nil
+}
+
+// Resolver turns references into values. A secret is fetched once per
+// resolver, so fields of a secret issued on every read, such as the user
+// and password of Vault database credentials, come from the same lease.
+type Resolver struct {
+	providers map[string]Provider
+	fetched   map[string]Secret
+}
+
+// NewResolver creates a resolver reading each scheme from its provider
+func NewResolver(providers map[string]Provider) *Resolver {
+	return &Resolver{providers: providers, fetched: make(map[string]Secret)}
+}
+
+// IsReference reports whether value is a reference to a secret of one of
+// the resolver's schemes
+func (r *Resolver) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	return ok && r.providers[scheme] != nil
+}
+
+// Resolve returns the secret value references, or value itself when it is
+// not a reference. Errors name the reference but never a secret.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !r.IsReference(value) {
+		return value, nil
+	}
+	scheme, rest, _ := strings.Cut(value, ":")
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return "", fmt.Errorf("secret reference %q has no path", value)
+	}
+
+	key := scheme + ":" + path
+	secret, ok := r.fetched[key]
+	if !ok {
+		var err error
+		if secret, err = r.providers[scheme].Fetch(ctx, path); err != nil {
+			return "", fmt.Errorf("%s: %w", key, err)
+		}
+		r.fetched[key] = secret
+	}
+	resolved, err := secret.field(field)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", value, err)
+	}
+	return resolved, nil
+}
+
+// Env reads environment variables: env:NAME
+type Env struct{}
+
+// Fetch implements Provider
+func (Env) Fetch(_ context.Context, name string) (Secret, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return Secret{}, errors.New("environment variable is not set")
+	}
+	return Secret{Value: value}, nil
+}
+
+// File reads files, such as Docker or Kubernetes secrets, without their
+// trailing newline: file:/path
+type File struct{}
+
+// Fetch implements Provider
+func (File) Fetch(_ context.Context, path string) (Secret, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Secret{}, err
+	}
+	return Secret{Value: strings.TrimRight(string(data), "\r\n")}, nil
+}
@@ -0,0 +1,77 @@
+// internal/secrets/vault.go - HashiCorp Vault provider
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets over Vault's HTTP API with a token: vault:path#field,
+// where path is the API path under /v1. Key/value version 2 paths include
+// "data/" (secret/data/digiorder); reads of other engines, such as
+// database/creds/digiorder, return their data as fields.
+type Vault struct {
+	Address   string
+	Token     string
+	Namespace string // Vault Enterprise namespace
+	Timeout   time.Duration
+}
+
+// Fetch implements Provider
+func (v Vault) Fetch(ctx context.Context, path string) (Secret, error) {
+	if v.Address == "" || v.Token == "" {
+		return Secret{}, errors.New("vault needs an address and a token")
+	}
+	timeout := v.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	url := strings.TrimRight(v.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Secret{}, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return Secret{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Secret{}, fmt.Errorf("vault: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Secret{}, fmt.Errorf("vault: %w", err)
+	}
+	// Key/value version 2 nests the secret under data.data, next to its
+	// metadata
+	var kv2 struct {
+		Data     json.RawMessage `json:"data"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	data := out.Data
+	if json.Unmarshal(data, &kv2) == nil && kv2.Data != nil && kv2.Metadata != nil {
+		data = kv2.Data
+	}
+	fields, err := parseFields(data)
+	if err != nil {
+		return Secret{}, errors.New("vault: secret has no data")
+	}
+	return Secret{Fields: fields}, nil
+}
@@ -0,0 +1,114 @@
+// internal/server/alerts.go - Security alerts to Slack and Microsoft Teams
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/labstack/echo/v4"
+)
+
+// AlertDelivery is the outcome of a test alert on one chat channel
+type AlertDelivery struct {
+	Channel   string `json:"channel"`
+	Delivered bool   `json:"delivered"`
+	Error     string `json:"error,omitempty"`
+}
+
+// postAlert queues a security alert for every configured chat channel
+func (s *Server) postAlert(title, text string, facts ...notify.Fact) {
+	facts = append(facts, notify.Fact{Name: "Time", Value: time.Now().Format(time.RFC1123)})
+	for _, channel := range notify.ChatChannels {
+		s.notifier.Enqueue(notify.Message{
+			Channel: channel,
+			Event:   notify.EventSecurityAlert,
+			Subject: title,
+			Text:    text,
+			Facts:   facts,
+		})
+	}
+}
+
+// alertProtectedAdmin reports a refused attempt to change the primary or
+// last administrator
+func (s *Server) alertProtectedAdmin(c echo.Context, targetID uuid.UUID, attempt, reason string) {
+	actor, _ := middleware.GetUsernameFromContext(c)
+	s.postAlert("Protected administrator change refused",
+		fmt.Sprintf("%s tried to %s: %s", actor, attempt, reason),
+		notify.Fact{Name: "User", Value: actor},
+		notify.Fact{Name: "Target", Value: targetID.String()},
+		notify.Fact{Name: "IP address", Value: c.RealIP()},
+	)
+}
+
+// alertAudit posts an audit entry matching one of the configured audit
+// rules
+func (s *Server) alertAudit(ctx context.Context, userID uuid.UUID, action, entityType, entityID, ipAddress string) {
+	if !auditRuleMatches(s.config.Notify.Chat.AuditRules, entityType, action) {
+		return
+	}
+
+	actor := userID.String()
+	if user, err := s.queries.GetUser(ctx, userID); err == nil {
+		actor = user.Username
+	}
+	s.postAlert("Audit alert: "+entityType+" "+action,
+		fmt.Sprintf("%s performed %s on %s %s", actor, action, entityType, entityID),
+		notify.Fact{Name: "User", Value: actor},
+		notify.Fact{Name: "Entity", Value: entityType + " " + entityID},
+		notify.Fact{Name: "IP address", Value: ipAddress},
+	)
+}
+
+// auditRuleMatches reports whether any "entity_type.action" rule matches;
+// "*" stands for any entity type or action
+func auditRuleMatches(rules []string, entityType, action string) bool {
+	for _, rule := range rules {
+		entity, act, _ := strings.Cut(rule, ".")
+		if (entity == "*" || entity == entityType) && (act == "*" || act == action) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestAlert handles POST /api/v1/security/alerts/test. It posts a test
+// message to each configured chat right away and reports what happened.
+func (s *Server) TestAlert(c echo.Context) error {
+	actor, _ := middleware.GetUsernameFromContext(c)
+	msg := notify.Message{
+		Event:   notify.EventSecurityAlert,
+		Subject: "DigiOrder test alert",
+		Text:    "Security alerts from DigiOrder will be posted here.",
+		Facts: []notify.Fact{
+			{Name: "Requested by", Value: actor},
+			{Name: "Time", Value: time.Now().Format(time.RFC1123)},
+		},
+	}
+
+	var results []AlertDelivery
+	for _, channel := range notify.ChatChannels {
+		if !s.notifier.Enabled(channel) {
+			continue
+		}
+		msg.Channel = channel
+		result := AlertDelivery{Channel: string(channel), Delivered: true}
+		if err := s.notifier.SendNow(c.Request().Context(), msg); err != nil {
+			result.Delivered = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return RespondError(c, http.StatusServiceUnavailable, "alerts_not_configured",
+			"No alert channel is configured. Set notify.chat.slack_webhook_url or teams_webhook_url.")
+	}
+
+	return RespondSuccess(c, http.StatusOK, results)
+}
@@ -0,0 +1,310 @@
+// internal/server/api_keys.go - Role-scoped API keys for machine integrations
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyReq defines the request body for creating or replacing an API
+// key. ExpiresInDays counts from the request; 0 means the key never
+// expires.
+type APIKeyReq struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	RoleID        int32    `json:"role_id" validate:"required,gt=0"`
+	DepartmentID  *int32   `json:"department_id,omitempty" validate:"omitempty,gt=0"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"min=0,max=366"`
+}
+
+// APIKey is a key for a machine integration. Key holds the secret and is
+// only returned when the key is created.
+type APIKey struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	Key          string     `json:"key,omitempty"`
+	Prefix       string     `json:"prefix"`
+	RoleID       int32      `json:"role_id"`
+	DepartmentID *int32     `json:"department_id,omitempty"`
+	Scopes       []string   `json:"scopes"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Expired      bool       `json:"expired"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP   string     `json:"last_used_ip,omitempty"`
+	CreatedBy    uuid.UUID  `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func apiKeyResponse(k db.ApiKey) APIKey {
+	resp := APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.KeyPrefix,
+		RoleID:     k.RoleID,
+		Scopes:     k.Scopes,
+		LastUsedIP: k.LastUsedIp.String,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		UpdatedAt:  k.UpdatedAt,
+	}
+	if k.DepartmentID.Valid {
+		resp.DepartmentID = &k.DepartmentID.Int32
+	}
+	if k.ExpiresAt.Valid {
+		resp.ExpiresAt = &k.ExpiresAt.Time
+		resp.Expired = time.Now().After(k.ExpiresAt.Time)
+	}
+	if k.LastUsedAt.Valid {
+		resp.LastUsedAt = &k.LastUsedAt.Time
+	}
+	return resp
+}
+
+// department returns the department of the request, unset without one
+func (r APIKeyReq) department() sql.NullInt32 {
+	if r.DepartmentID == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *r.DepartmentID, Valid: true}
+}
+
+// expiry returns when a key saved now with the request expires
+func (r APIKeyReq) expiry() sql.NullTime {
+	if r.ExpiresInDays == 0 {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Now().AddDate(0, 0, r.ExpiresInDays), Valid: true}
+}
+
+// bindAPIKey reads and checks an API key request, writing the error
+// response when it is invalid
+func (s *Server) bindAPIKey(c echo.Context) (APIKeyReq, bool, error) {
+	var req APIKeyReq
+	if err := c.Bind(&req); err != nil {
+		return req, false, respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return req, false, respondValidationError(c, err)
+	}
+	for _, scope := range req.Scopes {
+		if !middleware.ValidScope(scope) {
+			return req, false, RespondError(c, http.StatusBadRequest, "invalid_scope",
+				fmt.Sprintf("Unknown scope %q.", scope))
+		}
+	}
+	if _, err := s.roles.role(c.Request().Context(), req.RoleID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return req, false, respondFieldError(c, "invalid_role", "role_id",
+				fmt.Sprintf("Role with ID %d does not exist.", req.RoleID))
+		}
+		return req, false, HandleDatabaseError(c, err, "Role")
+	}
+	if req.DepartmentID != nil {
+		if ok, err := s.requireDepartment(c, *req.DepartmentID); !ok {
+			return req, false, err
+		}
+	}
+	return req, true, nil
+}
+
+// resolveAPIKey is the middleware.TokenResolver for API keys. The key acts
+// as its creator with its own role and department, in its creator's
+// pharmacy and language.
+func (s *Server) resolveAPIKey(c echo.Context, key string) (*middleware.TokenPrincipal, error) {
+	ctx := c.Request().Context()
+	row, err := s.queries.GetAPIKeyByHash(ctx, hashAccessToken(key))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error("Failed to look up API key", err, nil)
+		}
+		return nil, middleware.ErrInvalidToken
+	}
+	if row.RevokedAt.Valid {
+		return nil, middleware.ErrInvalidToken
+	}
+	if row.ExpiresAt.Valid && time.Now().After(row.ExpiresAt.Time) {
+		return nil, middleware.ErrExpiredToken
+	}
+
+	err = s.queries.TouchAPIKey(ctx, db.TouchAPIKeyParams{
+		ID:         row.ID,
+		LastUsedIp: sql.NullString{String: c.RealIP(), Valid: c.RealIP() != ""},
+	})
+	if err != nil {
+		s.logger.Warn("Failed to record API key use", map[string]any{
+			"api_key_id": row.ID.String(),
+			"error":      err.Error(),
+		})
+	}
+
+	return &middleware.TokenPrincipal{
+		APIKeyID:     row.ID,
+		UserID:       row.CreatedBy,
+		Username:     row.Username,
+		RoleID:       row.RoleID,
+		RoleName:     row.RoleName,
+		TenantID:     row.TenantID,
+		DepartmentID: row.DepartmentID.Int32,
+		Language:     row.Language.String,
+		Scopes:       row.Scopes,
+	}, nil
+}
+
+// CreateAPIKey handles POST /api/v1/admin/api-keys. The key is in the
+// response and cannot be retrieved again.
+func (s *Server) CreateAPIKey(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+	req, ok, err := s.bindAPIKey(c)
+	if !ok {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to generate the key.")
+	}
+	key := middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	ctx := c.Request().Context()
+	created, err := s.queries.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		Name:         req.Name,
+		KeyHash:      hashAccessToken(key),
+		KeyPrefix:    key[:len(middleware.APIKeyPrefix)+4],
+		RoleID:       req.RoleID,
+		DepartmentID: req.department(),
+		Scopes:       req.Scopes,
+		ExpiresAt:    req.expiry(),
+		CreatedBy:    userID,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "API key")
+	}
+
+	s.logAudit(ctx, userID, "create", "api_key", created.ID.String(), nil, map[string]any{
+		"name":          created.Name,
+		"role_id":       created.RoleID,
+		"department_id": created.DepartmentID.Int32,
+		"scopes":        created.Scopes,
+	}, c.RealIP(), c.Request().UserAgent())
+
+	resp := apiKeyResponse(created)
+	resp.Key = key
+	return RespondSuccess(c, http.StatusCreated, resp)
+}
+
+// ListAPIKeys handles GET /api/v1/admin/api-keys, listing the keys that
+// have not been revoked, newest first
+func (s *Server) ListAPIKeys(c echo.Context) error {
+	keys, err := s.queries.ListAPIKeys(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch API keys.")
+	}
+
+	resp := make([]APIKey, len(keys))
+	for i, k := range keys {
+		resp[i] = apiKeyResponse(k)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// GetAPIKey handles GET /api/v1/admin/api-keys/:id
+func (s *Server) GetAPIKey(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	key, err := s.queries.GetAPIKey(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "API key")
+	}
+	return RespondSuccess(c, http.StatusOK, apiKeyResponse(key))
+}
+
+// UpdateAPIKey handles PUT /api/v1/admin/api-keys/:id, replacing the name,
+// role, department, scopes and expiry of a key. The key itself does not
+// change.
+func (s *Server) UpdateAPIKey(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	req, ok, err := s.bindAPIKey(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetAPIKey(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "API key")
+	}
+	key, err := s.queries.UpdateAPIKey(ctx, db.UpdateAPIKeyParams{
+		ID:           id,
+		Name:         req.Name,
+		RoleID:       req.RoleID,
+		DepartmentID: req.department(),
+		Scopes:       req.Scopes,
+		ExpiresAt:    req.expiry(),
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "API key")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "api_key", key.ID.String(),
+		apiKeyAuditValues(old), apiKeyAuditValues(key),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, apiKeyResponse(key))
+}
+
+// RevokeAPIKey handles DELETE /api/v1/admin/api-keys/:id. Requests with
+// the key fail from then on.
+func (s *Server) RevokeAPIKey(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	revoked, err := s.queries.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "API key")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "revoke", "api_key", revoked.ID.String(),
+		apiKeyAuditValues(revoked), nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// apiKeyAuditValues is what the audit log keeps of a key
+func apiKeyAuditValues(k db.ApiKey) map[string]any {
+	values := map[string]any{
+		"name":          k.Name,
+		"role_id":       k.RoleID,
+		"department_id": k.DepartmentID.Int32,
+		"scopes":        k.Scopes,
+	}
+	if k.ExpiresAt.Valid {
+		values["expires_at"] = k.ExpiresAt.Time
+	}
+	return values
+}
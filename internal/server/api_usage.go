@@ -0,0 +1,233 @@
+// internal/server/api_usage.go - Per-consumer API usage report
+package server
+
+import (
+	"cmp"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/usage"
+	"github.com/labstack/echo/v4"
+)
+
+// Defaults and limits of GET /admin/api-usage
+const (
+	defaultAPIUsageDays  = 30
+	maxAPIUsageDays      = 366
+	defaultAPIUsageLimit = 100
+	maxAPIUsageLimit     = 1000
+)
+
+// Ways GET /admin/api-usage can split the counts, combined with commas
+var apiUsageGroups = []string{"day", "consumer", "route"}
+
+// APIUsageReport sums authenticated requests over [from, to], days
+// counted in the reporting time zone, split by group_by and busiest first
+type APIUsageReport struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	GroupBy []string       `json:"group_by"`
+	Rows    []APIUsageRow  `json:"rows"`
+	Total   APIUsageCounts `json:"total"`
+}
+
+// APIUsageRow is the usage of one group; fields not grouped by are empty.
+// A consumer is a user, and the access token when one was used.
+type APIUsageRow struct {
+	Day       string     `json:"day,omitempty"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	TokenID   *uuid.UUID `json:"token_id,omitempty"`
+	TokenName string     `json:"token_name,omitempty"`
+	Method    string     `json:"method,omitempty"`
+	Route     string     `json:"route,omitempty"`
+	APIUsageCounts
+}
+
+// APIUsageCounts are the request counts and latency of a group
+type APIUsageCounts struct {
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"client_errors"`
+	ServerErrors int64   `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgMs        float64 `json:"avg_ms"`
+	MaxMs        float64 `json:"max_ms"`
+
+	totalMs float64
+}
+
+func (n *APIUsageCounts) add(row db.ReportAPIUsageRow) {
+	n.Requests += row.Requests
+	n.ClientErrors += row.ClientErrors
+	n.ServerErrors += row.ServerErrors
+	n.totalMs += row.TotalMs
+	n.MaxMs = max(n.MaxMs, row.MaxMs)
+}
+
+func (n *APIUsageCounts) finish() {
+	if n.Requests == 0 {
+		return
+	}
+	n.ErrorRate = float64(n.ClientErrors+n.ServerErrors) / float64(n.Requests)
+	n.AvgMs = n.totalMs / float64(n.Requests)
+}
+
+// newUsageTracker creates the API usage tracker. Without a database it
+// never counts, since nothing would write the counters.
+func newUsageTracker(hasDB bool, queries db.Querier, cfg config.APIUsageConfig,
+	loc *time.Location, logger *logging.Logger) *usage.Tracker {
+	flush := cfg.FlushInterval
+	if !hasDB {
+		flush = 0
+	}
+	return usage.NewTracker(queries, usage.Config{
+		FlushInterval: flush,
+		Retention:     cfg.Retention,
+		Location:      loc,
+	}, logger)
+}
+
+// GetAPIUsage handles GET /api/v1/admin/api-usage. from and to are dates
+// (YYYY-MM-DD), both included, defaulting to the last 30 days; user_id,
+// token_id and route narrow the requests counted. group_by combines day,
+// consumer and route (default consumer). Counts are written every
+// api_usage.flush_interval, so the latest requests may be missing.
+func (s *Server) GetAPIUsage(c echo.Context) error {
+	loc := s.usage.Location()
+	today, _ := time.Parse(time.DateOnly, time.Now().In(loc).Format(time.DateOnly))
+	to, from := today, today.AddDate(0, 0, 1-defaultAPIUsageDays)
+	var err error
+	if raw := c.QueryParam("to"); raw != "" {
+		if to, err = time.Parse(time.DateOnly, raw); err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_range", "to must be a date (YYYY-MM-DD).")
+		}
+		from = to.AddDate(0, 0, 1-defaultAPIUsageDays)
+	}
+	if raw := c.QueryParam("from"); raw != "" {
+		if from, err = time.Parse(time.DateOnly, raw); err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be a date (YYYY-MM-DD).")
+		}
+	}
+	if to.Before(from) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must not be after to.")
+	}
+	if to.Sub(from) >= maxAPIUsageDays*24*time.Hour {
+		return RespondError(c, http.StatusBadRequest, "invalid_range",
+			"The range cannot be longer than "+strconv.Itoa(maxAPIUsageDays)+" days.")
+	}
+
+	groupBy := []string{"consumer"}
+	if raw := c.QueryParam("group_by"); raw != "" {
+		groupBy = strings.Split(raw, ",")
+		for _, g := range groupBy {
+			if !slices.Contains(apiUsageGroups, g) {
+				return RespondError(c, http.StatusBadRequest, "invalid_group_by",
+					"group_by must combine "+strings.Join(apiUsageGroups, ", ")+".")
+			}
+		}
+	}
+	limit, ok := overviewParam(c, "limit", defaultAPIUsageLimit, maxAPIUsageLimit)
+	if !ok {
+		return RespondError(c, http.StatusBadRequest, "invalid_limit",
+			"limit must be a whole number between 1 and "+strconv.Itoa(maxAPIUsageLimit)+".")
+	}
+
+	params := db.ReportAPIUsageParams{FromDay: from, ToDay: to}
+	for _, f := range []struct {
+		name string
+		id   *uuid.NullUUID
+	}{{"user_id", &params.UserID}, {"token_id", &params.TokenID}} {
+		raw := c.QueryParam(f.name)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_id", f.name+" must be a UUID.")
+		}
+		*f.id = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if route := c.QueryParam("route"); route != "" {
+		params.Route = sql.NullString{String: route, Valid: true}
+	}
+
+	rows, err := s.queries.ReportAPIUsage(c.Request().Context(), params)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch API usage.")
+	}
+
+	report := APIUsageReport{
+		From:    from.Format(time.DateOnly),
+		To:      to.Format(time.DateOnly),
+		GroupBy: groupBy,
+		Rows:    []APIUsageRow{},
+	}
+	groups := map[apiUsageKey]*APIUsageRow{}
+	for _, row := range rows {
+		report.Total.add(row)
+		k, g := apiUsageGroup(row, groupBy)
+		if groups[k] == nil {
+			groups[k] = &g
+		}
+		groups[k].add(row)
+	}
+	report.Total.finish()
+	for _, g := range groups {
+		g.finish()
+		report.Rows = append(report.Rows, *g)
+	}
+	slices.SortFunc(report.Rows, func(a, b APIUsageRow) int {
+		return cmp.Or(
+			cmp.Compare(b.Requests, a.Requests),
+			cmp.Compare(a.Day, b.Day),
+			cmp.Compare(a.Username, b.Username),
+			cmp.Compare(a.TokenName, b.TokenName),
+			cmp.Compare(a.Route, b.Route),
+			cmp.Compare(a.Method, b.Method),
+		)
+	})
+	report.Rows = report.Rows[:min(limit, len(report.Rows))]
+
+	return RespondSuccess(c, http.StatusOK, report)
+}
+
+// apiUsageKey identifies a group of GET /admin/api-usage
+type apiUsageKey struct {
+	day             string
+	userID, tokenID uuid.UUID
+	method, route   string
+}
+
+// apiUsageGroup keeps the fields of row that groupBy splits by
+func apiUsageGroup(row db.ReportAPIUsageRow, groupBy []string) (apiUsageKey, APIUsageRow) {
+	var k apiUsageKey
+	var g APIUsageRow
+	for _, by := range groupBy {
+		switch by {
+		case "day":
+			k.day = row.Day.Format(time.DateOnly)
+			g.Day = k.day
+		case "consumer":
+			k.userID = row.UserID
+			g.UserID = &row.UserID
+			g.Username = row.Username
+			if row.TokenID.Valid {
+				k.tokenID = row.TokenID.UUID
+				g.TokenID = &row.TokenID.UUID
+				g.TokenName = row.TokenName
+			}
+		case "route":
+			k.method, k.route = row.Method, row.Route
+			g.Method, g.Route = row.Method, row.Route
+		}
+	}
+	return k, g
+}
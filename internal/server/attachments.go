@@ -0,0 +1,360 @@
+// internal/server/attachments.go - Product images and order attachments
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// Accepted upload types, as sniffed from the content
+var (
+	imageTypes      = []string{"image/jpeg", "image/png", "image/webp"}
+	attachmentTypes = []string{"application/pdf", "image/jpeg", "image/png", "image/webp", "text/plain"}
+)
+
+// ProductImage is a product's picture with a presigned download URL
+type ProductImage struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// OrderAttachment is a file attached to an order, such as a signed
+// requisition or a supplier invoice
+type OrderAttachment struct {
+	ID          uuid.UUID  `json:"id"`
+	OrderID     uuid.UUID  `json:"order_id"`
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"content_type"`
+	SizeBytes   int64      `json:"size_bytes"`
+	UploadedBy  *uuid.UUID `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	URL         string     `json:"url"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// UploadProductImage handles PUT /api/v1/products/:id/image. The image is
+// the "image" form field (JPEG, PNG or WebP) and replaces any previous one.
+func (s *Server) UploadProductImage(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	product, err := s.queries.GetProduct(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product")
+	}
+	if product.DeletedAt.Valid {
+		return ErrNotFound.WithDetails("Product has been deleted").Send(c)
+	}
+
+	file, err := s.readUpload(c, "image", imageTypes)
+	if file == nil {
+		return err
+	}
+
+	key := storage.Join(storage.PrefixProductImages, id.String(), uuid.NewString()+extensionFor(file.ContentType))
+	if err := s.store.Put(ctx, key, bytes.NewReader(file.Data), int64(len(file.Data)), file.ContentType); err != nil {
+		s.logger.Error("Failed to store product image", err, map[string]any{"product_id": id.String()})
+		return RespondError(c, http.StatusBadGateway, "storage_error", "Failed to store the image.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var previous sql.NullString
+	var image db.ProductImage
+	err = s.withTx(ctx, func(q db.Querier) error {
+		old, err := q.GetProductImage(ctx, id)
+		if err == nil {
+			previous = sql.NullString{String: old.ObjectKey, Valid: true}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		image, err = q.UpsertProductImage(ctx, db.UpsertProductImageParams{
+			ProductID:   id,
+			ObjectKey:   key,
+			ContentType: file.ContentType,
+			SizeBytes:   int64(len(file.Data)),
+			UploadedBy:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		return err
+	})
+	if err != nil {
+		s.deleteObject(key)
+		return HandleDatabaseError(c, err, "Product image")
+	}
+	if previous.Valid && previous.String != key {
+		s.deleteObject(previous.String)
+	}
+
+	s.logAudit(ctx, userID, "upload_image", "product", id.String(),
+		nil, map[string]any{"content_type": image.ContentType, "size_bytes": image.SizeBytes},
+		c.RealIP(), c.Request().UserAgent())
+
+	resp, err := s.productImageResponse(c, image)
+	if err != nil {
+		return presignFailed(c)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// GetProductImage handles GET /api/v1/products/:id/image, returning a
+// presigned URL rather than the image itself
+func (s *Server) GetProductImage(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	product, err := s.queries.GetProduct(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product")
+	}
+	if product.DeletedAt.Valid {
+		return ErrNotFound.WithDetails("Product has been deleted").Send(c)
+	}
+
+	image, err := s.queries.GetProductImage(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product image")
+	}
+
+	resp, err := s.productImageResponse(c, image)
+	if err != nil {
+		return presignFailed(c)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// DeleteProductImage handles DELETE /api/v1/products/:id/image
+func (s *Server) DeleteProductImage(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	image, err := s.queries.GetProductImage(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product image")
+	}
+	if err := s.queries.DeleteProductImage(ctx, id); err != nil {
+		return HandleDatabaseError(c, err, "Product image")
+	}
+	s.deleteObject(image.ObjectKey)
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "delete_image", "product", id.String(),
+		map[string]any{"content_type": image.ContentType, "size_bytes": image.SizeBytes}, nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateOrderAttachment handles POST /api/v1/orders/:id/attachments. The
+// file is the "file" form field: a PDF, an image or plain text.
+func (s *Server) CreateOrderAttachment(c echo.Context) error {
+	orderID, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if ok, err := s.requireOrder(c, orderID); !ok {
+		return err
+	}
+
+	file, err := s.readUpload(c, "file", attachmentTypes)
+	if file == nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	key := storage.Join(storage.PrefixOrderAttachments, orderID.String(), uuid.NewString()+extensionFor(file.ContentType))
+	if err := s.store.Put(ctx, key, bytes.NewReader(file.Data), int64(len(file.Data)), file.ContentType); err != nil {
+		s.logger.Error("Failed to store order attachment", err, map[string]any{"order_id": orderID.String()})
+		return RespondError(c, http.StatusBadGateway, "storage_error", "Failed to store the file.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var attachment db.OrderAttachment
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		attachment, err = q.CreateOrderAttachment(ctx, db.CreateOrderAttachmentParams{
+			OrderID:     orderID,
+			ObjectKey:   key,
+			Filename:    file.Filename,
+			ContentType: file.ContentType,
+			SizeBytes:   int64(len(file.Data)),
+			UploadedBy:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		return err
+	})
+	if err != nil {
+		s.deleteObject(key)
+		if denied, err := respondPolicyDenied(c, err); denied {
+			return err
+		}
+		return HandleDatabaseError(c, err, "Order attachment")
+	}
+
+	s.logAudit(ctx, userID, "attach", "order", orderID.String(),
+		nil, map[string]any{"attachment_id": attachment.ID.String(), "filename": attachment.Filename},
+		c.RealIP(), c.Request().UserAgent())
+
+	resp, err := s.orderAttachmentResponse(c, attachment)
+	if err != nil {
+		return presignFailed(c)
+	}
+	return RespondSuccess(c, http.StatusCreated, resp)
+}
+
+// ListOrderAttachments handles GET /api/v1/orders/:id/attachments
+func (s *Server) ListOrderAttachments(c echo.Context) error {
+	orderID, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+	if ok, err := s.requireOrder(c, orderID); !ok {
+		return err
+	}
+
+	records, err := s.queries.ListOrderAttachments(c.Request().Context(), orderID)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order attachments.")
+	}
+
+	attachments := make([]OrderAttachment, len(records))
+	for i, record := range records {
+		if attachments[i], err = s.orderAttachmentResponse(c, record); err != nil {
+			return presignFailed(c)
+		}
+	}
+	return RespondSuccess(c, http.StatusOK, attachments)
+}
+
+// DeleteOrderAttachment handles DELETE
+// /api/v1/orders/:id/attachments/:attachment_id. Admins may delete any
+// attachment, other users only their own.
+func (s *Server) DeleteOrderAttachment(c echo.Context) error {
+	orderID, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	attachmentID, err := ParseUUID(c, "attachment_id")
+	if err != nil {
+		return err
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	attachment, err := s.queries.GetOrderAttachment(ctx, attachmentID)
+	if err == nil && attachment.OrderID != orderID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order attachment")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	if !middleware.HasRole(c, "admin") && (!attachment.UploadedBy.Valid || attachment.UploadedBy.UUID != userID) {
+		return RespondError(c, http.StatusForbidden, "forbidden",
+			"Only the uploader or an admin can delete this attachment.")
+	}
+
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		return q.DeleteOrderAttachment(ctx, attachmentID)
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order attachment")
+	}
+	s.deleteObject(attachment.ObjectKey)
+
+	s.logAudit(ctx, userID, "detach", "order", orderID.String(),
+		map[string]any{"attachment_id": attachmentID.String(), "filename": attachment.Filename}, nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// requireOrder reports whether the order exists and is not deleted,
+// writing the error response when it does not
+func (s *Server) requireOrder(c echo.Context, id uuid.UUID) (bool, error) {
+	order, err := s.queries.GetOrder(c.Request().Context(), id)
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Order")
+	}
+	if order.DeletedAt.Valid {
+		return false, ErrNotFound.WithDetails("Order has been deleted").Send(c)
+	}
+	return true, nil
+}
+
+func (s *Server) productImageResponse(c echo.Context, image db.ProductImage) (ProductImage, error) {
+	link, expires, err := s.downloadURL(c, image.ObjectKey, "")
+	if err != nil {
+		return ProductImage{}, err
+	}
+	return ProductImage{
+		ProductID:   image.ProductID,
+		ContentType: image.ContentType,
+		SizeBytes:   image.SizeBytes,
+		UploadedAt:  image.UploadedAt,
+		URL:         link,
+		ExpiresAt:   expires,
+	}, nil
+}
+
+func (s *Server) orderAttachmentResponse(c echo.Context, a db.OrderAttachment) (OrderAttachment, error) {
+	link, expires, err := s.downloadURL(c, a.ObjectKey, a.Filename)
+	if err != nil {
+		return OrderAttachment{}, err
+	}
+	resp := OrderAttachment{
+		ID:          a.ID,
+		OrderID:     a.OrderID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		SizeBytes:   a.SizeBytes,
+		CreatedAt:   a.CreatedAt,
+		URL:         link,
+		ExpiresAt:   expires,
+	}
+	if a.UploadedBy.Valid {
+		resp.UploadedBy = &a.UploadedBy.UUID
+	}
+	return resp, nil
+}
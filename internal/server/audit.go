@@ -5,15 +5,16 @@ package server
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/audit"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
 	"github.com/labstack/echo/v4"
-	"github.com/sqlc-dev/pqtype"
 )
 
 // AuditLogFilter for querying audit logs
@@ -24,102 +25,188 @@ type AuditLogFilter struct {
 	Action     string `query:"action"`
 	StartDate  string `query:"start_date"`
 	EndDate    string `query:"end_date"`
-	Limit      int    `query:"limit"`
-	Offset     int    `query:"offset"`
 }
 
-// logAudit creates an audit log entry
-func (s *Server) logAudit(_ context.Context, userID uuid.UUID, action, entityType, entityID string,
-	oldValues, newValues map[string]any, ipAddress, userAgent string) {
-
-	// Make async to not block request
-	go func() {
-		asyncCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+// securityAuditEntities are the entity types whose audit entries are
+// written before the request returns rather than queued, so a change to
+// who may do what is on record even when the queue is full or the process
+// stops before flushing it
+var securityAuditEntities = map[string]bool{
+	"user":             true,
+	"access_token":     true,
+	"permission":       true,
+	"role_permission":  true,
+	"tenant":           true,
+	"config":           true,
+	"maintenance_mode": true,
+	"backup":           true,
+}
 
-		var oldJSON, newJSON pqtype.NullRawMessage
-		if oldValues != nil {
-			data, _ := json.Marshal(oldValues)
-			oldJSON = pqtype.NullRawMessage{RawMessage: data, Valid: true}
-		}
-		if newValues != nil {
-			data, _ := json.Marshal(newValues)
-			newJSON = pqtype.NullRawMessage{RawMessage: data, Valid: true}
-		}
+// newAuditWriter creates the audit log writer, which raises the alerts of
+// entries once they are stored. Without a database every entry is written
+// on its own, as there is no worker to flush a queue.
+func (s *Server) newAuditWriter(hasDB bool, cfg config.AuditConfig) *audit.Writer {
+	buffer := cfg.BufferSize
+	if !hasDB {
+		buffer = 0
+	}
+	return audit.NewWriter(s.queries, audit.Config{
+		BufferSize:    buffer,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		Written: func(ctx context.Context, e audit.Entry) {
+			s.alertAudit(ctx, e.UserID, e.Action, e.EntityType, e.EntityID, e.IPAddress)
+		},
+	}, s.logger)
+}
 
-		_, err := s.queries.CreateAuditLog(asyncCtx, db.CreateAuditLogParams{
-			UserID:     uuid.NullUUID{UUID: userID, Valid: true},
-			Action:     action,
-			EntityType: entityType,
-			EntityID:   entityID,
-			OldValues:  oldJSON,
-			NewValues:  newJSON,
-			IpAddress:  sql.NullString{String: ipAddress, Valid: true},
-			UserAgent:  sql.NullString{String: userAgent, Valid: true},
-		})
-
-		if err != nil && s.logger != nil {
-			s.logger.Error("Failed to create audit log", err, map[string]any{
-				"action":      action,
-				"entity_type": entityType,
-				"entity_id":   entityID,
-			})
-		}
-	}()
+// logAudit records an audit log entry. Entries of security changes are
+// written before it returns and the others queued; a failure to store
+// either is logged, not returned.
+func (s *Server) logAudit(_ context.Context, userID uuid.UUID, action, entityType, entityID string,
+	oldValues, newValues map[string]any, ipAddress, userAgent string) {
+	entry := audit.Entry{
+		UserID:     userID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		OldValues:  oldValues,
+		NewValues:  newValues,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+	}
+	if securityAuditEntities[entityType] {
+		s.audit.Write(entry)
+		return
+	}
+	s.audit.Log(entry)
 }
 
-// GetAuditLogs handles GET /api/v1/audit-logs
+// GetAuditLogs handles GET /api/v1/audit-logs. With format=xlsx every
+// matching entry is downloaded as an Excel sheet. start_date and end_date
+// (included) are dates or RFC 3339 times; with calendar=jalali the dates
+// are Jalali and each entry also has created_at_jalali.
 func (s *Server) GetAuditLogs(c echo.Context) error {
 	var filter AuditLogFilter
 	if err := c.Bind(&filter); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"Invalid query parameters.")
 	}
 
-	if filter.Limit <= 0 || filter.Limit > 100 {
-		filter.Limit = 50
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
 	}
-	if filter.Offset < 0 {
-		filter.Offset = 0
+	sortBy, ok := parseSort(c, &page, auditLogSortColumns)
+	if !ok {
+		return nil
 	}
 
-	ctx := c.Request().Context()
-
-	var logs []db.AuditLog
-	var err error
-
-	// Apply filters
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	loc := s.reports.Calendar().Location
+	var from, to sql.NullTime
+	if filter.StartDate != "" {
+		t, ok := parseDateParam(filter.StartDate, calendar, loc, false)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"start_date must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		from = sql.NullTime{Time: t, Valid: true}
+	}
+	if filter.EndDate != "" {
+		t, ok := parseDateParam(filter.EndDate, calendar, loc, true)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"end_date must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		to = sql.NullTime{Time: t, Valid: true}
+	}
+	var userID uuid.NullUUID
 	if filter.UserID != "" {
-		userID, err := uuid.Parse(filter.UserID)
+		id, err := uuid.Parse(filter.UserID)
 		if err != nil {
 			return RespondError(c, http.StatusBadRequest, "invalid_user_id",
 				"Invalid user ID format.")
 		}
-		logs, err = s.queries.GetAuditLogsByUser(ctx, db.GetAuditLogsByUserParams{
-			UserID: uuid.NullUUID{UUID: userID, Valid: true},
-			Limit:  int32(filter.Limit),
-			Offset: int32(filter.Offset),
-		})
-	} else if filter.EntityType != "" && filter.EntityID != "" {
-		logs, err = s.queries.GetAuditLogsByEntity(ctx, db.GetAuditLogsByEntityParams{
-			EntityType: filter.EntityType,
-			EntityID:   filter.EntityID,
-			Limit:      int32(filter.Limit),
-			Offset:     int32(filter.Offset),
-		})
-	} else if filter.Action != "" {
-		logs, err = s.queries.GetAuditLogsByAction(ctx, db.GetAuditLogsByActionParams{
-			Action: filter.Action,
-			Limit:  int32(filter.Limit),
-			Offset: int32(filter.Offset),
-		})
-	} else {
-		logs, err = s.queries.ListAuditLogs(ctx, db.ListAuditLogsParams{
-			Limit:  int32(filter.Limit),
-			Offset: int32(filter.Offset),
-		})
+		userID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	count := db.CountAuditLogsBetweenParams{
+		FromTime:   from,
+		ToTime:     to,
+		UserID:     userID,
+		EntityType: filter.EntityType,
+		EntityID:   filter.EntityID,
+		Action:     filter.Action,
+	}
+
+	// Apply filters; a lone user, entity or action filter has a query of
+	// its own, anything else goes through ListAuditLogsBetween, which
+	// applies every filter given and seeks past a cursor, as pages after
+	// the first and Excel downloads need
+	var list func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error)
+	switch {
+	case page.After != nil || wantsXLSX(c):
+	case count == db.CountAuditLogsBetweenParams{}:
+		list = func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error) {
+			return s.queries.ListAuditLogs(ctx, db.ListAuditLogsParams{
+				Limit:  int32(page.Limit),
+				Offset: int32(page.Offset),
+			})
+		}
+	case count == db.CountAuditLogsBetweenParams{UserID: userID}:
+		list = func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error) {
+			return s.queries.GetAuditLogsByUser(ctx, db.GetAuditLogsByUserParams{
+				UserID: userID,
+				Limit:  int32(page.Limit),
+				Offset: int32(page.Offset),
+			})
+		}
+	case count == db.CountAuditLogsBetweenParams{EntityType: filter.EntityType, EntityID: filter.EntityID} &&
+		filter.EntityType != "" && filter.EntityID != "":
+		list = func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error) {
+			return s.queries.GetAuditLogsByEntity(ctx, db.GetAuditLogsByEntityParams{
+				EntityType: filter.EntityType,
+				EntityID:   filter.EntityID,
+				Limit:      int32(page.Limit),
+				Offset:     int32(page.Offset),
+			})
+		}
+	case count == db.CountAuditLogsBetweenParams{Action: filter.Action}:
+		list = func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error) {
+			return s.queries.GetAuditLogsByAction(ctx, db.GetAuditLogsByActionParams{
+				Action: filter.Action,
+				Limit:  int32(page.Limit),
+				Offset: int32(page.Offset),
+			})
+		}
+	}
+	if list == nil {
+		list = func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error) {
+			return s.queries.ListAuditLogsBetween(ctx, db.ListAuditLogsBetweenParams{
+				FromTime:   from,
+				ToTime:     to,
+				UserID:     userID,
+				EntityType: filter.EntityType,
+				EntityID:   filter.EntityID,
+				Action:     filter.Action,
+				AfterTime:  page.AfterTime(),
+				AfterID:    page.AfterID(),
+				Limit:      int32(page.Limit),
+				Offset:     int32(page.Offset),
+			})
+		}
+	}
+
+	if wantsXLSX(c) {
+		return s.exportAuditLogsXLSX(c, list, calendar == jalali.Jalali)
 	}
 
+	logs, err := list(withSort(ctx, sortBy, "id"), page)
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to retrieve audit logs.")
@@ -138,12 +225,15 @@ func (s *Server) GetAuditLogs(c echo.Context) error {
 			"action":      log.Action,
 			"entity_type": log.EntityType,
 			"entity_id":   log.EntityID,
-			"old_values":  json.RawMessage(log.OldValues.RawMessage),
-			"new_values":  json.RawMessage(log.NewValues.RawMessage),
+			"old_values":  log.OldValues,
+			"new_values":  log.NewValues,
 			"ip_address":  log.IpAddress.String,
 			"user_agent":  log.UserAgent.String,
 			"created_at":  log.CreatedAt,
 		}
+		if calendar == jalali.Jalali {
+			enriched["created_at_jalali"] = s.jalaliTime(log.CreatedAt)
+		}
 
 		// Get username if available
 		if log.UserID.Valid {
@@ -156,22 +246,65 @@ func (s *Server) GetAuditLogs(c echo.Context) error {
 		enrichedLogs[i] = enriched
 	}
 
-	return RespondSuccess(c, http.StatusOK, enrichedLogs)
+	var signature any
+	if count != (db.CountAuditLogsBetweenParams{}) {
+		signature = count
+	}
+	total := s.listTotal(ctx, "audit_logs", signature, func(ctx context.Context) (int64, error) {
+		return s.queries.CountAuditLogsBetween(ctx, count)
+	})
+	return respondPage(c, page, total, logs, auditLogCursor, enrichedLogs)
+}
+
+// exportAuditLogsXLSX streams the entries returned by list, looking each
+// user's name up once; withJalali adds a column with the Jalali time
+func (s *Server) exportAuditLogsXLSX(c echo.Context, list func(ctx context.Context, page pagination.Page) ([]db.AuditLog, error), withJalali bool) error {
+	usernames := map[uuid.UUID]string{}
+	username := func(ctx context.Context, id uuid.NullUUID) string {
+		if !id.Valid {
+			return ""
+		}
+		name, ok := usernames[id.UUID]
+		if !ok {
+			if user, err := s.queries.GetUser(ctx, id.UUID); err == nil {
+				name = user.Username
+			}
+			usernames[id.UUID] = name
+		}
+		return name
+	}
+
+	header := []string{"ID", "Created At", "User ID", "Username", "Action", "Entity Type", "Entity ID",
+		"IP Address", "User Agent", "Old Values", "New Values"}
+	if withJalali {
+		header = append(header, "Created At (Jalali)")
+	}
+	return s.streamXLSX(c, "audit-logs", header, keysetSource(list, auditLogCursor, func(ctx context.Context, log db.AuditLog) []any {
+		var userID any
+		if log.UserID.Valid {
+			userID = log.UserID.UUID
+		}
+		row := []any{log.ID, s.xlsxTime(log.CreatedAt), userID, username(ctx, log.UserID),
+			log.Action, log.EntityType, log.EntityID, log.IpAddress.String, log.UserAgent.String,
+			string(log.OldValues), string(log.NewValues)}
+		if withJalali {
+			row = append(row, s.jalaliTime(log.CreatedAt))
+		}
+		return row
+	}))
 }
 
 // GetAuditLog handles GET /api/v1/audit-logs/:id
 func (s *Server) GetAuditLog(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid UUID.")
+		return err
 	}
 
 	ctx := c.Request().Context()
 	log, err := s.queries.GetAuditLog(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Audit log with the specified ID was not found.")
 		}
@@ -185,8 +318,8 @@ func (s *Server) GetAuditLog(c echo.Context) error {
 		"action":      log.Action,
 		"entity_type": log.EntityType,
 		"entity_id":   log.EntityID,
-		"old_values":  json.RawMessage(log.OldValues.RawMessage),
-		"new_values":  json.RawMessage(log.NewValues.RawMessage),
+		"old_values":  log.OldValues,
+		"new_values":  log.NewValues,
 		"ip_address":  log.IpAddress.String,
 		"user_agent":  log.UserAgent.String,
 		"created_at":  log.CreatedAt,
@@ -205,30 +338,21 @@ func (s *Server) GetAuditLog(c echo.Context) error {
 
 // GetUserActivity handles GET /api/v1/users/:user_id/activity
 func (s *Server) GetUserActivity(c echo.Context) error {
-	userIDStr := c.Param("user_id")
-	userID, err := uuid.Parse(userIDStr)
+	userID, err := ParseUUID(c, "user_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_user_id",
-			"The provided user ID is not a valid UUID.")
+		return err
 	}
 
-	limitStr := c.QueryParam("limit")
-	offsetStr := c.QueryParam("offset")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 50
-	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	page, ok := parseOffsetPage(c, pagination.DefaultLimit)
+	if !ok {
+		return nil
 	}
 
 	ctx := c.Request().Context()
 	logs, err := s.queries.GetAuditLogsByUser(ctx, db.GetAuditLogsByUserParams{
 		UserID: uuid.NullUUID{UUID: userID, Valid: true},
-		Limit:  int32(limit),
-		Offset: int32(offset),
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
 	})
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
@@ -252,7 +376,11 @@ func (s *Server) GetUserActivity(c echo.Context) error {
 		}
 	}
 
-	return RespondSuccess(c, http.StatusOK, activities)
+	count := db.CountAuditLogsBetweenParams{UserID: uuid.NullUUID{UUID: userID, Valid: true}}
+	total := s.listTotal(ctx, "audit_logs", count, func(ctx context.Context) (int64, error) {
+		return s.queries.CountAuditLogsBetween(ctx, count)
+	})
+	return respondOffsetPage(c, page, total, len(logs), activities)
 }
 
 // GetEntityHistory handles GET /api/v1/audit-logs/entity/:type/:id
@@ -260,24 +388,17 @@ func (s *Server) GetEntityHistory(c echo.Context) error {
 	entityType := c.Param("type")
 	entityID := c.Param("id")
 
-	limitStr := c.QueryParam("limit")
-	offsetStr := c.QueryParam("offset")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 50
-	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	page, ok := parseOffsetPage(c, pagination.DefaultLimit)
+	if !ok {
+		return nil
 	}
 
 	ctx := c.Request().Context()
 	logs, err := s.queries.GetAuditLogsByEntity(ctx, db.GetAuditLogsByEntityParams{
 		EntityType: entityType,
 		EntityID:   entityID,
-		Limit:      int32(limit),
-		Offset:     int32(offset),
+		Limit:      int32(page.Limit),
+		Offset:     int32(page.Offset),
 	})
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
@@ -294,8 +415,8 @@ func (s *Server) GetEntityHistory(c echo.Context) error {
 		h := map[string]any{
 			"id":         log.ID,
 			"action":     log.Action,
-			"old_values": json.RawMessage(log.OldValues.RawMessage),
-			"new_values": json.RawMessage(log.NewValues.RawMessage),
+			"old_values": log.OldValues,
+			"new_values": log.NewValues,
 			"ip_address": log.IpAddress.String,
 			"created_at": log.CreatedAt,
 		}
@@ -312,7 +433,11 @@ func (s *Server) GetEntityHistory(c echo.Context) error {
 		history[i] = h
 	}
 
-	return RespondSuccess(c, http.StatusOK, history)
+	count := db.CountAuditLogsBetweenParams{EntityType: entityType, EntityID: entityID}
+	total := s.listTotal(ctx, "audit_logs", count, func(ctx context.Context) (int64, error) {
+		return s.queries.CountAuditLogsBetween(ctx, count)
+	})
+	return respondOffsetPage(c, page, total, len(logs), history)
 }
 
 // GetAuditStats handles GET /api/v1/audit-logs/stats
@@ -2,11 +2,17 @@ package server
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/i18n"
 	"github.com/jamalkaksouri/DigiOrder/internal/logging"
 	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
 	"github.com/jamalkaksouri/DigiOrder/internal/security"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
@@ -18,11 +24,14 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse defines the login response
+// LoginResponse defines the login response: a short-lived access token
+// and the refresh token to renew it with (see RefreshToken)
 type LoginResponse struct {
-	Token     string   `json:"token"`
-	ExpiresIn string   `json:"expires_in"`
-	User      UserInfo `json:"user"`
+	Token            string   `json:"token"`
+	ExpiresIn        string   `json:"expires_in"`
+	RefreshToken     string   `json:"refresh_token"`
+	RefreshExpiresIn string   `json:"refresh_expires_in"`
+	User             UserInfo `json:"user"`
 }
 
 // UserInfo contains basic user information
@@ -32,11 +41,20 @@ type UserInfo struct {
 	FullName string `json:"full_name"`
 	RoleID   int32  `json:"role_id"`
 	RoleName string `json:"role_name"`
+	Language string `json:"language,omitempty"`
 }
 
-// RefreshTokenRequest defines the refresh token request
-type RefreshTokenRequest struct {
-	Token string `json:"token" validate:"required"`
+// SetLanguageReq chooses the language of the user's error messages; an
+// empty language follows Accept-Language again
+type SetLanguageReq struct {
+	Language string `json:"language"`
+}
+
+// SetLanguageResponse is the chosen language and a token carrying it
+type SetLanguageResponse struct {
+	Language  string `json:"language"`
+	Token     string `json:"token"`
+	ExpiresIn string `json:"expires_in"`
 }
 
 // Login handles POST /api/v1/auth/login
@@ -45,11 +63,11 @@ func (s *Server) Login(c echo.Context) error {
 	logger := logging.GetLogger(c)
 	var req LoginRequest
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -57,12 +75,19 @@ func (s *Server) Login(c echo.Context) error {
 	// Get user by username
 	user, err := s.queries.GetUserByUsername(ctx, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.recordLoginAttempt(c, req.Username, false, "unknown_user")
 			return RespondError(c, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to authenticate user.")
 	}
 
+	// Service accounts only use access tokens
+	if user.ServiceAccount {
+		s.recordLoginAttempt(c, req.Username, false, "service_account")
+		return RespondError(c, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password.")
+	}
+
 	// Use secure password comparison
 	err = security.ComparePassword(user.PasswordHash, req.Password)
 	if err != nil {
@@ -70,6 +95,7 @@ func (s *Server) Login(c echo.Context) error {
 			"username": req.Username,
 			"ip":       c.RealIP(),
 		})
+		s.recordLoginAttempt(c, req.Username, false, "invalid_password")
 		return RespondError(c, http.StatusUnauthorized,
 			"invalid_credentials", "Invalid username or password.")
 	}
@@ -84,11 +110,12 @@ func (s *Server) Login(c echo.Context) error {
 	if err != nil {
 		return RespondError(c, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password.")
 	}
+	s.recordLoginAttempt(c, user.Username, true, "")
 
 	// Get role name
 	var roleName string
 	if user.RoleID.Valid {
-		role, err := s.queries.GetRole(ctx, user.RoleID.Int32)
+		role, err := s.roles.role(ctx, user.RoleID.Int32)
 		if err != nil {
 			// Log but don't fail the login
 			if s.logger != nil {
@@ -103,59 +130,43 @@ func (s *Server) Login(c echo.Context) error {
 		}
 	}
 
+	// Only administrators may sign in during planned maintenance
+	if s.maintenance != nil && s.maintenance.Enabled() && roleName != "admin" {
+		return s.maintenance.Respond(c)
+	}
+
 	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.RoleID.Int32, roleName)
+	token, err := middleware.GenerateToken(user.ID, user.Username, user.RoleID.Int32, roleName, user.TenantID, user.DepartmentID.Int32, user.Language.String)
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to generate authentication token.")
 	}
 
+	// Every login starts a new family of refresh tokens
+	refresh, err := s.issueRefreshToken(ctx, s.queries, user.ID, uuid.New())
+	if err != nil {
+		s.logger.Error("Failed to issue refresh token", err, map[string]any{"user_id": user.ID})
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to generate authentication token.")
+	}
+
 	// Prepare response
 	response := LoginResponse{
-		Token:     token,
-		ExpiresIn: middleware.GetJWTExpiry().String(),
+		Token:            token,
+		ExpiresIn:        middleware.GetJWTExpiry().String(),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: s.config.JWT.RefreshExpiry.String(),
 		User: UserInfo{
 			ID:       user.ID.String(),
 			Username: user.Username,
 			FullName: user.FullName.String,
 			RoleID:   user.RoleID.Int32,
 			RoleName: roleName,
+			Language: user.Language.String,
 		},
 	}
 
 	return RespondSuccess(c, http.StatusOK, response)
 }
 
-// RefreshToken handles POST /api/v1/auth/refresh
-func (s *Server) RefreshToken(c echo.Context) error {
-	var req RefreshTokenRequest
-	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
-	}
-
-	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
-	}
-
-	// Validate existing token
-	claims, err := middleware.ValidateToken(req.Token)
-	if err != nil {
-		return RespondError(c, http.StatusUnauthorized, "invalid_token", "Invalid or expired token.")
-	}
-
-	// Generate new token with same claims
-	newToken, err := middleware.GenerateToken(claims.UserID, claims.Username, claims.RoleID, claims.RoleName)
-	if err != nil {
-		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to refresh token.")
-	}
-
-	response := map[string]any{
-		"token":      newToken,
-		"expires_in": middleware.GetJWTExpiry().String(),
-	}
-
-	return RespondSuccess(c, http.StatusOK, response)
-}
-
 // GetProfile handles GET /api/v1/auth/profile
 func (s *Server) GetProfile(c echo.Context) error {
 	userID, err := middleware.GetUserIDFromContext(c)
@@ -166,7 +177,7 @@ func (s *Server) GetProfile(c echo.Context) error {
 	ctx := c.Request().Context()
 	user, err := s.queries.GetUser(ctx, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "User not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve user profile.")
@@ -175,7 +186,7 @@ func (s *Server) GetProfile(c echo.Context) error {
 	// Get role name
 	var roleName string
 	if user.RoleID.Valid {
-		role, err := s.queries.GetRole(ctx, user.RoleID.Int32)
+		role, err := s.roles.role(ctx, user.RoleID.Int32)
 		if err == nil {
 			roleName = role.Name
 		}
@@ -187,6 +198,7 @@ func (s *Server) GetProfile(c echo.Context) error {
 		FullName: user.FullName.String,
 		RoleID:   user.RoleID.Int32,
 		RoleName: roleName,
+		Language: user.Language.String,
 	}
 
 	return RespondSuccess(c, http.StatusOK, userInfo)
@@ -205,11 +217,11 @@ func (s *Server) ChangePassword(c echo.Context) error {
 	}
 
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -241,7 +253,61 @@ func (s *Server) ChangePassword(c echo.Context) error {
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to update password.")
 	}
 
+	// Sessions elsewhere end once their access tokens expire
+	if _, err := s.queries.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		s.logger.Error("Failed to revoke refresh tokens", err, map[string]any{"user_id": userID})
+	}
+
+	s.notifyUser(ctx, notify.EventPasswordReset, userID, map[string]any{
+		"Username": user.Username,
+		"IP":       c.RealIP(),
+		"Time":     time.Now().Format(time.RFC1123),
+	})
+
 	return RespondSuccess(c, http.StatusOK, map[string]string{
 		"message": "Password updated successfully",
 	})
 }
+
+// SetLanguage handles PUT /api/v1/auth/language. The JWT carries the
+// language, so clients switch to the new token in the response.
+func (s *Server) SetLanguage(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+	var req SetLanguageReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+	if req.Language != "" && !i18n.IsSupported(req.Language) {
+		return RespondError(c, http.StatusBadRequest, "unsupported_language",
+			"language must be one of "+strings.Join(i18n.Supported, ", ")+", or empty.")
+	}
+
+	ctx := c.Request().Context()
+	user, err := s.queries.SetUserLanguage(ctx, db.SetUserLanguageParams{
+		ID:       userID,
+		Language: sql.NullString{String: req.Language, Valid: req.Language != ""},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "User")
+	}
+
+	// Access tokens cannot call /auth routes, so the request has a JWT
+	claims, ok := c.Get("jwt_claims").(*middleware.JWTClaims)
+	if !ok {
+		return RespondError(c, http.StatusUnauthorized, "unauthorized", "Authentication required.")
+	}
+	token, err := middleware.GenerateToken(claims.UserID, claims.Username, claims.RoleID, claims.RoleName,
+		claims.TenantID, claims.DepartmentID, user.Language.String)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to generate authentication token.")
+	}
+	response := SetLanguageResponse{
+		Language:  user.Language.String,
+		Token:     token,
+		ExpiresIn: middleware.GetJWTExpiry().String(),
+	}
+	return RespondSuccess(c, http.StatusOK, response)
+}
@@ -0,0 +1,303 @@
+// internal/server/backups.go - Database backups and restores
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/backup"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// Backup describes one database backup
+type Backup struct {
+	ID           uuid.UUID  `json:"id"`
+	Status       string     `json:"status"`
+	SizeBytes    *int64     `json:"size_bytes,omitempty"`
+	Checksum     string     `json:"checksum,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	TriggeredBy  *uuid.UUID `json:"triggered_by,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	VerifyStatus string     `json:"verify_status,omitempty"`
+	VerifyError  string     `json:"verify_error,omitempty"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+}
+
+// BackupRestore describes one restore of a backup
+type BackupRestore struct {
+	ID          uuid.UUID  `json:"id"`
+	BackupID    uuid.UUID  `json:"backup_id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	TriggeredBy *uuid.UUID `json:"triggered_by,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// BackupDetail is a backup with its restores and, once completed, a
+// download URL for its file
+type BackupDetail struct {
+	Backup
+	URL       string          `json:"url,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Restores  []BackupRestore `json:"restores"`
+}
+
+// RestoreBackupReq confirms a restore by repeating the backup's ID
+type RestoreBackupReq struct {
+	Confirm string `json:"confirm" validate:"required"`
+}
+
+// newBackupManager creates the backup manager, which runs the PostgreSQL
+// client tools against the configured database
+func (s *Server) newBackupManager(cfg *config.Config) *backup.Manager {
+	database := cfg.Database
+	manager := backup.NewManager(s.queries, s.store, backup.Config{
+		PgDump:    cfg.Backup.PgDump,
+		PgRestore: cfg.Backup.PgRestore,
+		Timeout:   cfg.Backup.Timeout,
+		Keep:      cfg.Backup.Keep,
+		Database:  database.Name,
+		Env: func(ctx context.Context) ([]string, error) {
+			user, password, err := cfg.DatabaseCredentials(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return []string{
+				"PGHOST=" + database.Host,
+				"PGPORT=" + database.Port,
+				"PGUSER=" + user,
+				"PGPASSWORD=" + password,
+				"PGDATABASE=" + database.Name,
+				"PGSSLMODE=" + database.SSLMode,
+				"PGAPPNAME=" + database.ApplicationName + "-backup",
+			}, nil
+		},
+	}, s.logger)
+	manager.OnRestore(s.restored)
+	return manager
+}
+
+// restored brings the restored schema up to date and drops everything
+// cached from the database as it was. The audit entry is written now, as
+// entries written before the restore were replaced with the backup's.
+func (s *Server) restored(restore db.BackupRestore) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if s.migrator != nil {
+		if _, err := s.migrator.Up(ctx); err != nil {
+			s.logger.Error("Failed to apply migrations after restore", err, map[string]any{"restore_id": restore.ID})
+		}
+	}
+	s.responses.Clear()
+	s.roles.invalidate()
+
+	s.logAudit(ctx, restore.TriggeredBy.UUID, "restore", "backup", restore.BackupID.String(),
+		nil, map[string]any{"restore_id": restore.ID}, "", "")
+}
+
+// StartBackup handles POST /api/v1/admin/backup. The dump runs in the
+// background; poll the returned backup for its status.
+func (s *Server) StartBackup(c echo.Context) error {
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := middleware.GetUserIDFromContext(c)
+	record, err := s.backups.Backup(ctx, uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil})
+	if err != nil {
+		return s.backupError(c, err, "Failed to start the backup.")
+	}
+
+	s.logAudit(ctx, userID, "create", "backup", record.ID.String(),
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusAccepted, backupResponse(record))
+}
+
+// ListBackups handles GET /api/v1/admin/backups, newest first
+func (s *Server) ListBackups(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, ok := parseOffsetPage(c, 20)
+	if !ok {
+		return nil
+	}
+
+	records, err := s.queries.ListBackups(ctx, db.ListBackupsParams{
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch backups.")
+	}
+
+	backups := make([]Backup, len(records))
+	for i, record := range records {
+		backups[i] = backupResponse(record)
+	}
+	total := s.listTotal(ctx, "backups", nil, s.queries.CountBackups)
+	return respondOffsetPage(c, page, total, len(records), backups)
+}
+
+// GetBackup handles GET /api/v1/admin/backups/:id with its restores and a
+// fresh download URL
+func (s *Server) GetBackup(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	record, err := s.queries.GetBackup(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Backup")
+	}
+	restores, err := s.queries.ListBackupRestores(ctx, id)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch the restores of the backup.")
+	}
+
+	resp := BackupDetail{
+		Backup:   backupResponse(record),
+		Restores: make([]BackupRestore, len(restores)),
+	}
+	for i, restore := range restores {
+		resp.Restores[i] = backupRestoreResponse(restore)
+	}
+	if record.Status == backup.StatusCompleted && s.store != nil {
+		filename := "digiorder-" + record.StartedAt.UTC().Format("20060102-150405") + ".dump"
+		link, expires, err := s.downloadURL(c, record.StorageKey.String, filename)
+		if err != nil {
+			return presignFailed(c)
+		}
+		resp.URL = link
+		resp.ExpiresAt = &expires
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// VerifyBackup handles POST /api/v1/admin/backups/:id/verify. The check
+// runs in the background; poll the backup for verify_status.
+func (s *Server) VerifyBackup(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	record, err := s.backups.Verify(c.Request().Context(), id)
+	if err != nil {
+		return s.backupError(c, err, "Failed to start the verification.")
+	}
+	return RespondSuccess(c, http.StatusAccepted, backupResponse(record))
+}
+
+// RestoreBackup handles POST /api/v1/admin/backups/:id/restore, replacing
+// every table with the backup's contents. Maintenance mode must be on, so
+// only admins reach the API meanwhile, and the body must repeat the
+// backup's ID. The restore runs in the background; poll the backup for it.
+func (s *Server) RestoreBackup(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req RestoreBackupReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+	if !s.maintenance.Status().Enabled {
+		return RespondError(c, http.StatusConflict, "maintenance_required",
+			"Turn on maintenance mode (PUT /api/v1/system/maintenance) before restoring.")
+	}
+	if req.Confirm != id.String() {
+		return RespondUnprocessable(c, "confirmation_mismatch",
+			"Set confirm to the ID of the backup to restore.")
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := middleware.GetUserIDFromContext(c)
+	restore, err := s.backups.Restore(ctx, id, uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil})
+	if err != nil {
+		return s.backupError(c, err, "Failed to start the restore.")
+	}
+
+	s.logAudit(ctx, userID, "restore_started", "backup", id.String(),
+		nil, map[string]any{"restore_id": restore.ID}, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusAccepted, backupRestoreResponse(restore))
+}
+
+func (s *Server) backupError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, backup.ErrBusy):
+		return RespondError(c, http.StatusConflict, "backup_in_progress",
+			"A backup, verification or restore is already running. Try again when it has finished.")
+	case errors.Is(err, backup.ErrNotRestorable):
+		return RespondError(c, http.StatusConflict, "backup_not_restorable",
+			"Only completed backups can be verified or restored.")
+	}
+	return HandleDatabaseError(c, err, "Backup")
+}
+
+func backupResponse(r db.Backup) Backup {
+	resp := Backup{
+		ID:           r.ID,
+		Status:       r.Status,
+		Checksum:     r.Checksum.String,
+		Error:        r.Error.String,
+		StartedAt:    r.StartedAt,
+		VerifyStatus: r.VerifyStatus.String,
+		VerifyError:  r.VerifyError.String,
+	}
+	if r.SizeBytes.Valid {
+		resp.SizeBytes = &r.SizeBytes.Int64
+	}
+	if r.TriggeredBy.Valid {
+		resp.TriggeredBy = &r.TriggeredBy.UUID
+	}
+	if r.FinishedAt.Valid {
+		resp.FinishedAt = &r.FinishedAt.Time
+	}
+	if r.VerifiedAt.Valid {
+		resp.VerifiedAt = &r.VerifiedAt.Time
+	}
+	return resp
+}
+
+func backupRestoreResponse(r db.BackupRestore) BackupRestore {
+	resp := BackupRestore{
+		ID:        r.ID,
+		BackupID:  r.BackupID,
+		Status:    r.Status,
+		Error:     r.Error.String,
+		StartedAt: r.StartedAt,
+	}
+	if r.TriggeredBy.Valid {
+		resp.TriggeredBy = &r.TriggeredBy.UUID
+	}
+	if r.FinishedAt.Valid {
+		resp.FinishedAt = &r.FinishedAt.Time
+	}
+	return resp
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -31,11 +32,11 @@ type SearchByBarcodeReq struct {
 func (s *Server) CreateBarcode(c echo.Context) error {
 	var req CreateBarcodeReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	productID, err := uuid.Parse(req.ProductID)
@@ -48,7 +49,7 @@ func (s *Server) CreateBarcode(c echo.Context) error {
 	// Verify product exists
 	_, err = s.queries.GetProduct(ctx, productID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "product_not_found", "Product does not exist.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to verify product.")
@@ -69,10 +70,9 @@ func (s *Server) CreateBarcode(c echo.Context) error {
 
 // GetBarcodesByProduct handles GET /api/v1/products/:product_id/barcodes
 func (s *Server) GetBarcodesByProduct(c echo.Context) error {
-	productIDStr := c.Param("product_id")
-	productID, err := uuid.Parse(productIDStr)
+	productID, err := ParseUUID(c, "product_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_product_id", "Invalid product ID format.")
+		return err
 	}
 
 	ctx := c.Request().Context()
@@ -98,7 +98,7 @@ func (s *Server) SearchProductByBarcode(c echo.Context) error {
 	ctx := c.Request().Context()
 	product, err := s.queries.GetProductByBarcode(ctx, barcode)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "Product with this barcode not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to search product.")
@@ -109,15 +109,14 @@ func (s *Server) SearchProductByBarcode(c echo.Context) error {
 
 // UpdateBarcode handles PUT /api/v1/barcodes/:id
 func (s *Server) UpdateBarcode(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid barcode ID format.")
+		return err
 	}
 
 	var req UpdateBarcodeReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	ctx := c.Request().Context()
@@ -135,7 +134,7 @@ func (s *Server) UpdateBarcode(c echo.Context) error {
 
 	barcode, err := s.queries.UpdateBarcode(ctx, params)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "Barcode not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to update barcode.")
@@ -146,10 +145,9 @@ func (s *Server) UpdateBarcode(c echo.Context) error {
 
 // DeleteBarcode handles DELETE /api/v1/barcodes/:id
 func (s *Server) DeleteBarcode(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid barcode ID format.")
+		return err
 	}
 
 	ctx := c.Request().Context()
@@ -0,0 +1,212 @@
+// internal/server/calendar.go - iCalendar feed of recurring orders and deadlines
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/ical"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/recurring"
+	"github.com/labstack/echo/v4"
+)
+
+// closedOrderStatuses are left out of the deadline calendar
+var closedOrderStatuses = []string{"completed", "delivered", "fulfilled", "cancelled", "canceled", "rejected"}
+
+// deadlineLookback keeps recently missed deadlines of open orders in the
+// feed
+const deadlineLookback = 30 * 24 * time.Hour
+
+// CalendarFeed is the caller's private feed. URL and WebcalURL carry the
+// token and are only returned when the feed is created.
+type CalendarFeed struct {
+	URL           string     `json:"url,omitempty"`
+	WebcalURL     string     `json:"webcal_url,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+}
+
+// CreateCalendarFeed handles POST /api/v1/calendar/feed. A new URL
+// replaces the caller's previous one, which stops working.
+func (s *Server) CreateCalendarFeed(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to generate the feed token.")
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	ctx := c.Request().Context()
+	feed, err := s.queries.UpsertCalendarFeed(ctx, db.UpsertCalendarFeedParams{
+		UserID:    userID,
+		TokenHash: hashAccessToken(token),
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Calendar feed")
+	}
+
+	s.logAudit(ctx, userID, "create", "calendar_feed", userID.String(),
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	resp := calendarFeedResponse(feed)
+	resp.URL = calendarFeedURL(c, token)
+	resp.WebcalURL = "webcal://" + strings.SplitN(resp.URL, "://", 2)[1]
+	return RespondSuccess(c, http.StatusCreated, resp)
+}
+
+// GetCalendarFeed handles GET /api/v1/calendar/feed
+func (s *Server) GetCalendarFeed(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.queries.GetCalendarFeed(c.Request().Context(), userID)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Calendar feed")
+	}
+	return RespondSuccess(c, http.StatusOK, calendarFeedResponse(feed))
+}
+
+// DeleteCalendarFeed handles DELETE /api/v1/calendar/feed, turning the
+// caller's feed URL off
+func (s *Server) DeleteCalendarFeed(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	deleted, err := s.queries.DeleteCalendarFeed(ctx, userID)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to remove calendar feed.")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "Calendar feed not found.")
+	}
+
+	s.logAudit(ctx, userID, "delete", "calendar_feed", userID.String(),
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ServeCalendarFeed handles GET /api/v1/calendar/feed.ics?token=. Calendar
+// apps cannot send a bearer token, so the feed token in the URL replaces
+// it. The feed holds the runs of enabled recurring orders and the
+// deadlines of open orders as all-day events.
+func (s *Server) ServeCalendarFeed(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return RespondError(c, http.StatusUnauthorized, "invalid_token", "Missing calendar feed token.")
+	}
+
+	ctx := c.Request().Context()
+	owner, err := s.queries.GetCalendarFeedUser(ctx, hashAccessToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RespondError(c, http.StatusUnauthorized, "invalid_token",
+				"Invalid or revoked calendar feed token.")
+		}
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to load calendar feed.")
+	}
+
+	loc := s.recurring.Location()
+	schedules, err := s.queries.ListEnabledRecurringOrders(ctx)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to load recurring orders.")
+	}
+	deadlines, err := s.queries.ListOrderDeadlines(ctx, db.ListOrderDeadlinesParams{
+		FromDate:       time.Now().In(loc).Add(-deadlineLookback),
+		ClosedStatuses: closedOrderStatuses,
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to load order deadlines.")
+	}
+
+	if err := s.queries.TouchCalendarFeed(ctx, owner.UserID); err != nil {
+		s.logger.Warn("Failed to record calendar feed fetch", map[string]any{
+			"user_id": owner.UserID.String(),
+			"error":   err.Error(),
+		})
+	}
+
+	host := c.Request().Host
+	cal := ical.Calendar{
+		ProdID: "-//DigiOrder//Order Calendar//EN",
+		Name:   "DigiOrder orders",
+	}
+	for _, r := range schedules {
+		cal.Events = append(cal.Events, recurringOrderEvent(r, loc, host))
+	}
+	for _, d := range deadlines {
+		cal.Events = append(cal.Events, orderDeadlineEvent(d, host))
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=300")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="digiorder.ics"`)
+	return c.Blob(http.StatusOK, ical.ContentType, cal.Render())
+}
+
+// recurringOrderEvent repeats on the run days of a recurring order from
+// its next run on
+func recurringOrderEvent(r db.RecurringOrder, loc *time.Location, host string) ical.Event {
+	description := fmt.Sprintf("A %s draft order is placed from order %s, needed %d day(s) later.",
+		r.Priority, r.TemplateOrderID, r.LeadDays)
+	return ical.Event{
+		UID:         "recurring-" + r.ID.String() + "@" + host,
+		Summary:     "Recurring order: " + r.Name,
+		Description: description,
+		Categories:  []string{"Recurring order"},
+		Start:       r.NextRunAt.In(loc),
+		RRule:       recurring.RRule(r.Frequency),
+		Stamp:       r.CreatedAt,
+	}
+}
+
+// orderDeadlineEvent falls on the day an open order is needed by
+func orderDeadlineEvent(d db.ListOrderDeadlinesRow, host string) ical.Event {
+	summary := "Order due " + d.ID.String()[:8]
+	if d.Notes.String != "" {
+		summary = "Order due: " + d.Notes.String
+	}
+	if d.Priority != "routine" {
+		summary = "[" + strings.ToUpper(d.Priority) + "] " + summary
+	}
+	return ical.Event{
+		UID:         "order-" + d.ID.String() + "@" + host,
+		Summary:     summary,
+		Description: fmt.Sprintf("Order %s is %s, priority %s.", d.ID, d.Status, d.Priority),
+		Categories:  []string{"Order deadline"},
+		Start:       d.NeededBy,
+		Stamp:       d.CreatedAt.Time,
+	}
+}
+
+// calendarFeedURL is the absolute feed URL for token, e.g.
+// https://host/api/v1/calendar/feed.ics?token=...
+func calendarFeedURL(c echo.Context, token string) string {
+	prefix, _, _ := strings.Cut(c.Path(), "/calendar/")
+	return c.Scheme() + "://" + c.Request().Host + prefix + "/calendar/feed.ics?token=" + url.QueryEscape(token)
+}
+
+func calendarFeedResponse(f db.CalendarFeed) CalendarFeed {
+	resp := CalendarFeed{CreatedAt: f.CreatedAt}
+	if f.LastFetchedAt.Valid {
+		resp.LastFetchedAt = &f.LastFetchedAt.Time
+	}
+	return resp
+}
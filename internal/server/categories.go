@@ -2,8 +2,8 @@ package server
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
-	"strconv"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/labstack/echo/v4"
@@ -18,11 +18,11 @@ type CreateCategoryReq struct {
 func (s *Server) CreateCategory(c echo.Context) error {
 	var req CreateCategoryReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -51,16 +51,15 @@ func (s *Server) ListCategories(c echo.Context) error {
 
 // GetCategory handles GET /api/v1/categories/:id
 func (s *Server) GetCategory(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "The provided ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	category, err := s.queries.GetCategory(ctx, int32(id))
+	category, err := s.queries.GetCategory(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "Category with the specified ID was not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve category.")
@@ -0,0 +1,123 @@
+// internal/server/config.go - Runtime configuration reload
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// ReloadResult describes what a configuration reload changed
+type ReloadResult struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// ReloadConfig re-reads the configuration file and environment and applies
+// the settings that are safe to change while serving traffic: rate limits,
+// CORS origins, log level, maintenance mode, policies, event webhooks and
+// chat alerts. Everything else keeps its startup value and is reported in
+// RestartRequired. In-flight requests are not interrupted.
+func (s *Server) ReloadConfig() (*ReloadResult, error) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	next, err := config.Load(s.config.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	current := s.config
+	result := &ReloadResult{
+		Applied:         []string{},
+		RestartRequired: current.RestartRequired(next),
+	}
+
+	if current.RateLimit != next.RateLimit {
+		s.ipLimiter.UpdateLimits(rateLimitConfig(next))
+		s.keyLimiter.UpdateRate(next.RateLimit.APIKeyRPM)
+		result.Applied = append(result.Applied, "rate_limit")
+	}
+
+	if quotaLimits(current.Tenancy.Quotas) != quotaLimits(next.Tenancy.Quotas) {
+		s.quotas.UpdateDefaults(quotaLimits(next.Tenancy.Quotas))
+		result.Applied = append(result.Applied, "tenancy.quotas")
+	}
+
+	if !slices.Equal(current.CORS.AllowedOrigins, next.CORS.AllowedOrigins) {
+		s.corsOrigins.Set(next.CORS.AllowedOrigins)
+		result.Applied = append(result.Applied, "cors")
+	}
+
+	if current.Log != next.Log {
+		level, _ := logging.ParseLevel(next.Log.Level) // checked by Validate
+		s.logger.SetLevel(level)
+		result.Applied = append(result.Applied, "log")
+	}
+
+	// Only act on maintenance settings that changed in the config, so a
+	// reload does not undo a toggle made through the admin endpoint
+	if current.Maintenance != next.Maintenance {
+		if next.Maintenance.Enabled != current.Maintenance.Enabled {
+			if next.Maintenance.Enabled {
+				s.maintenance.Enable(next.Maintenance.Message, next.Maintenance.RetryAfter)
+			} else {
+				s.maintenance.Disable()
+			}
+		}
+		s.maintenance.Configure(next.Maintenance.Message, next.Maintenance.RetryAfter)
+		result.Applied = append(result.Applied, "maintenance")
+	}
+
+	if !reflect.DeepEqual(current.Policies, next.Policies) {
+		s.policies.Set(policyRules(next.Policies))
+		result.Applied = append(result.Applied, "policies")
+	}
+
+	if !reflect.DeepEqual(current.Events.Webhooks, next.Events.Webhooks) {
+		// There is no relay without a database
+		if s.outbox != nil {
+			s.outbox.SetWebhooks(webhookPublishers(next.Events.Webhooks))
+		}
+		result.Applied = append(result.Applied, "events.webhooks")
+	}
+
+	if !reflect.DeepEqual(current.Notify.Chat, next.Notify.Chat) {
+		registerChatSenders(s.notifier, next.Notify.Chat)
+		result.Applied = append(result.Applied, "notify.chat")
+	}
+
+	s.config = current.Reloadable(next)
+
+	s.logger.Info("Configuration reloaded", map[string]any{
+		"applied":          result.Applied,
+		"restart_required": result.RestartRequired,
+	})
+
+	return result, nil
+}
+
+// ReloadConfigHandler handles POST /api/v1/system/config/reload
+func (s *Server) ReloadConfigHandler(c echo.Context) error {
+	result, err := s.ReloadConfig()
+	if err != nil {
+		return RespondError(c, http.StatusUnprocessableEntity, "config_reload_failed", err.Error())
+	}
+
+	// Log audit
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(c.Request().Context(), currentUserID, "reload", "config", "system",
+		nil, map[string]any{"applied": result.Applied, "restart_required": result.RestartRequired},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, result)
+}
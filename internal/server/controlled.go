@@ -0,0 +1,293 @@
+// internal/server/controlled.go - Controlled-substance ordering, approval and register
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// controlledResource is the permission resource of controlled substances;
+// its actions are order, approve and read (the register)
+const controlledResource = "controlled_substances"
+
+var (
+	// controlledReleaseStatuses come after approval; an order with
+	// controlled items only reaches them once a second user approved it
+	controlledReleaseStatuses = []string{"processing", "fulfilled", "delivered", "completed"}
+	// controlledReopenStatuses send an order back for changes, withdrawing
+	// the approval of its controlled items
+	controlledReopenStatuses = []string{"draft", "submitted"}
+)
+
+// ControlledRegisterEntry is a controlled item of an approved order
+type ControlledRegisterEntry struct {
+	ApprovedAt    time.Time `json:"approved_at"`
+	OrderID       uuid.UUID `json:"order_id"`
+	OrderStatus   string    `json:"order_status"`
+	OrderItemID   uuid.UUID `json:"order_item_id"`
+	ProductID     uuid.UUID `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	Strength      string    `json:"strength,omitempty"`
+	ScheduleClass string    `json:"schedule_class,omitempty"`
+	RequestedQty  int32     `json:"requested_qty"`
+	Unit          string    `json:"unit,omitempty"`
+	Reason        string    `json:"reason"`
+	RequestedBy   string    `json:"requested_by,omitempty"`
+	ApprovedBy    string    `json:"approved_by,omitempty"`
+	ApprovalNote  string    `json:"approval_note,omitempty"`
+}
+
+// ControlledRegister lists the controlled items of the orders approved in
+// [From, To)
+type ControlledRegister struct {
+	From    time.Time                 `json:"from"`
+	To      time.Time                 `json:"to"`
+	Entries []ControlledRegisterEntry `json:"entries"`
+}
+
+// controlledItem is an item about to be added to or changed on an order
+type controlledItem struct {
+	product db.Product
+	note    string
+	field   string // the note field, for errors
+	label   string // prefix of error details, such as "Item 2: "
+}
+
+// controlledChange is what a status change does to the second-person
+// approval of an order with controlled items
+type controlledChange struct {
+	approve  bool
+	withdraw bool
+}
+
+// canControlled reports whether the role of the request is granted action
+// on controlled substances
+func (s *Server) canControlled(c echo.Context, action string) (bool, error) {
+	roleID, err := middleware.GetRoleIDFromContext(c)
+	if err != nil {
+		return false, nil
+	}
+	return s.roles.hasPermission(c.Request().Context(), roleID, controlledResource, action)
+}
+
+// requireControlledItems checks items about to be added to or changed on
+// an order. Controlled ones take the controlled_substances order
+// permission and a reason in their note, and cannot change once the
+// order's controlled items were approved. orderID is uuid.Nil for an order
+// yet to be created.
+func (s *Server) requireControlledItems(c echo.Context, orderID uuid.UUID, items []controlledItem) (bool, error) {
+	var controlled []controlledItem
+	for _, item := range items {
+		if item.product.IsControlled {
+			controlled = append(controlled, item)
+		}
+	}
+	if len(controlled) == 0 {
+		return true, nil
+	}
+
+	allowed, err := s.canControlled(c, "order")
+	if err != nil {
+		return false, RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to check permission.")
+	}
+	for _, item := range controlled {
+		if !allowed {
+			return false, RespondError(c, http.StatusForbidden, "insufficient_permissions",
+				fmt.Sprintf("%s%s is a controlled substance; ordering it takes the %s order permission.",
+					item.label, item.product.Name, controlledResource))
+		}
+		if strings.TrimSpace(item.note) == "" {
+			return false, respondFieldError(c, "reason_required", item.field,
+				fmt.Sprintf("%s%s is a controlled substance; give the reason for ordering it in the note.",
+					item.label, item.product.Name))
+		}
+	}
+	if orderID == uuid.Nil {
+		return true, nil
+	}
+	return s.requireUnapproved(c, orderID)
+}
+
+// requireUnapproved refuses changes to the controlled items of an order
+// whose controlled items were approved
+func (s *Server) requireUnapproved(c echo.Context, orderID uuid.UUID) (bool, error) {
+	_, err := s.queries.GetControlledApproval(c.Request().Context(), orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Order")
+	}
+	return false, RespondError(c, http.StatusConflict, "controlled_order_approved",
+		"The controlled items of this order were approved; move it back to draft or submitted to change them.")
+}
+
+// controlledStatusChange checks a status change of an order with
+// controlled items. Approving it takes the controlled_substances approve
+// permission and a user other than its creator; the statuses after
+// approval take an approval; going back to draft or submitted withdraws
+// the approval.
+func (s *Server) controlledStatusChange(c echo.Context, orderID uuid.UUID, status string) (controlledChange, bool, error) {
+	ctx := c.Request().Context()
+	count, err := s.queries.CountControlledOrderItems(ctx, uuid.NullUUID{UUID: orderID, Valid: true})
+	if err != nil {
+		return controlledChange{}, false, RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to check the order items.")
+	}
+	if count == 0 {
+		return controlledChange{}, true, nil
+	}
+
+	switch {
+	case status == "approved":
+		allowed, err := s.canControlled(c, "approve")
+		if err != nil {
+			return controlledChange{}, false, RespondError(c, http.StatusInternalServerError, "db_error",
+				"Failed to check permission.")
+		}
+		if !allowed {
+			return controlledChange{}, false, RespondError(c, http.StatusForbidden, "insufficient_permissions",
+				fmt.Sprintf("The order has controlled substances; approving it takes the %s approve permission.", controlledResource))
+		}
+		order, err := s.queries.GetOrder(ctx, orderID)
+		if err != nil {
+			return controlledChange{}, false, HandleDatabaseError(c, err, "Order")
+		}
+		userID, _ := middleware.GetUserIDFromContext(c)
+		if userID == uuid.Nil || (order.CreatedBy.Valid && order.CreatedBy.UUID == userID) {
+			return controlledChange{}, false, RespondError(c, http.StatusForbidden, "self_approval",
+				"The order has controlled substances, so someone other than its creator must approve it.")
+		}
+		return controlledChange{approve: true}, true, nil
+
+	case slices.Contains(controlledReleaseStatuses, status):
+		_, err := s.queries.GetControlledApproval(ctx, orderID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return controlledChange{}, false, RespondError(c, http.StatusConflict, "approval_required",
+				fmt.Sprintf("The order has %d controlled item(s); it must be approved before it can be %s.", count, status))
+		}
+		if err != nil {
+			return controlledChange{}, false, HandleDatabaseError(c, err, "Order")
+		}
+
+	case slices.Contains(controlledReopenStatuses, status):
+		return controlledChange{withdraw: true}, true, nil
+	}
+	return controlledChange{}, true, nil
+}
+
+// apply records the change in the transaction of the status change
+func (ch controlledChange) apply(c echo.Context, q db.Querier, orderID uuid.UUID, note string) error {
+	ctx := c.Request().Context()
+	switch {
+	case ch.approve:
+		userID, _ := middleware.GetUserIDFromContext(c)
+		return q.CreateControlledApproval(ctx, db.CreateControlledApprovalParams{
+			OrderID:    orderID,
+			ApprovedBy: uuid.NullUUID{UUID: userID, Valid: true},
+			Note:       sql.NullString{String: note, Valid: note != ""},
+		})
+	case ch.withdraw:
+		return q.DeleteControlledApproval(ctx, orderID)
+	}
+	return nil
+}
+
+// GetControlledRegister handles GET /api/v1/reports/controlled-register:
+// every controlled item of the orders approved in the range, with the
+// reason it was ordered for and who ordered and approved it. from, to and
+// calendar work as for the order time series, the range defaulting to the
+// last 30 days. With format=xlsx the register is downloaded as an Excel
+// sheet. It takes the controlled_substances read permission.
+func (s *Server) GetControlledRegister(c echo.Context) error {
+	allowed, err := s.canControlled(c, "read")
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to check permission.")
+	}
+	if !allowed {
+		return RespondError(c, http.StatusForbidden, "insufficient_permissions",
+			fmt.Sprintf("The register takes the %s read permission.", controlledResource))
+	}
+
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	cal := s.reports.Calendar()
+	to := time.Now()
+	if raw := c.QueryParam("to"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, true)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"to must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if raw := c.QueryParam("from"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, false)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"from must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be before to.")
+	}
+
+	rows, err := s.queries.ListControlledRegister(c.Request().Context(), db.ListControlledRegisterParams{
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		s.logger.Error("Failed to build controlled-substance register", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch the register.")
+	}
+
+	if wantsXLSX(c) {
+		header := []string{"Approved At", "Order ID", "Order Status", "Product", "Strength", "Schedule Class",
+			"Quantity", "Unit", "Reason", "Requested By", "Approved By", "Approval Note"}
+		cells := make([][]any, len(rows))
+		for i, r := range rows {
+			cells[i] = []any{r.ApprovedAt.In(cal.Location), r.OrderID, r.OrderStatus, r.ProductName,
+				r.Strength.String, r.ScheduleClass.String, r.RequestedQty, r.Unit.String, r.Reason.String,
+				r.RequestedBy.String, r.ApprovedBy.String, r.ApprovalNote.String}
+		}
+		return s.streamXLSX(c, "controlled-register", header, sliceSource(cells))
+	}
+
+	register := ControlledRegister{From: from, To: to, Entries: make([]ControlledRegisterEntry, len(rows))}
+	for i, r := range rows {
+		register.Entries[i] = ControlledRegisterEntry{
+			ApprovedAt:    r.ApprovedAt,
+			OrderID:       r.OrderID,
+			OrderStatus:   r.OrderStatus,
+			OrderItemID:   r.OrderItemID,
+			ProductID:     r.ProductID,
+			ProductName:   r.ProductName,
+			Strength:      r.Strength.String,
+			ScheduleClass: r.ScheduleClass.String,
+			RequestedQty:  r.RequestedQty,
+			Unit:          r.Unit.String,
+			Reason:        r.Reason.String,
+			RequestedBy:   r.RequestedBy.String,
+			ApprovedBy:    r.ApprovedBy.String,
+			ApprovalNote:  r.ApprovalNote.String,
+		}
+	}
+	return RespondSuccess(c, http.StatusOK, register)
+}
@@ -0,0 +1,48 @@
+// internal/server/demo.go - Demo data for development
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/demo"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// SeedDemoDataReq sets how many sample orders are created
+type SeedDemoDataReq struct {
+	Orders int `json:"orders,omitempty" validate:"omitempty,min=1,max=500"`
+}
+
+// SeedDemoData handles POST /api/v1/system/demo-data, registered only in
+// development. Demo users sign in with demo.DefaultPassword.
+func (s *Server) SeedDemoData(c echo.Context) error {
+	var req SeedDemoDataReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	var result demo.Result
+	err := s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		result, err = demo.Seed(ctx, q, demo.Options{Orders: req.Orders, TaxPercent: s.config.Pricing.TaxPercent})
+		return err
+	})
+	if errors.Is(err, demo.ErrAlreadySeeded) {
+		return RespondError(c, http.StatusConflict, "demo_data_exists",
+			"Demo data has already been added.")
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Demo data")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "create", "demo_data", "system",
+		nil, map[string]any{"products": result.Products, "users": result.Users, "orders": result.Orders},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, result)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateDepartmentReq defines the request body for creating a department
+type CreateDepartmentReq struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// UpdateDepartmentReq defines the request body for renaming a department
+type UpdateDepartmentReq struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreateDepartment handles POST /api/v1/departments
+func (s *Server) CreateDepartment(c echo.Context) error {
+	var req CreateDepartmentReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	department, err := s.queries.CreateDepartment(c.Request().Context(), req.Name)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Department")
+	}
+
+	return RespondSuccess(c, http.StatusCreated, department)
+}
+
+// ListDepartments handles GET /api/v1/departments
+func (s *Server) ListDepartments(c echo.Context) error {
+	departments, err := s.queries.ListDepartments(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve departments.")
+	}
+
+	if departments == nil {
+		departments = []db.Department{}
+	}
+
+	return RespondSuccess(c, http.StatusOK, departments)
+}
+
+// GetDepartment handles GET /api/v1/departments/:id
+func (s *Server) GetDepartment(c echo.Context) error {
+	id, err := ParseInt(c, "id")
+	if err != nil {
+		return err
+	}
+
+	department, err := s.queries.GetDepartment(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Department")
+	}
+
+	return RespondSuccess(c, http.StatusOK, department)
+}
+
+// UpdateDepartment handles PUT /api/v1/departments/:id
+func (s *Server) UpdateDepartment(c echo.Context) error {
+	id, err := ParseInt(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateDepartmentReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	department, err := s.queries.UpdateDepartment(c.Request().Context(), db.UpdateDepartmentParams{
+		ID:   id,
+		Name: req.Name,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Department")
+	}
+
+	return RespondSuccess(c, http.StatusOK, department)
+}
+
+// DeleteDepartment handles DELETE /api/v1/departments/:id. Its users and
+// orders are left without a department: the users then see every order,
+// the orders are seen only by admins and users without a department.
+func (s *Server) DeleteDepartment(c echo.Context) error {
+	id, err := ParseInt(c, "id")
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.queries.DeleteDepartment(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Department")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "Department with the specified ID was not found.")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// requireDepartment responds with invalid_department unless the department
+// exists
+func (s *Server) requireDepartment(c echo.Context, id int32) (bool, error) {
+	_, err := s.queries.GetDepartment(c.Request().Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, respondFieldError(c, "invalid_department", "department_id",
+			fmt.Sprintf("Department with ID %d does not exist.", id))
+	}
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Department")
+	}
+	return true, nil
+}
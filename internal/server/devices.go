@@ -0,0 +1,114 @@
+// internal/server/devices.go - Push notification device registration
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterDeviceReq defines the request body for registering a device
+type RegisterDeviceReq struct {
+	Token    string `json:"token" validate:"required,max=4096"`
+	Platform string `json:"platform" validate:"required,oneof=android ios web"`
+	Name     string `json:"name" validate:"max=100"`
+}
+
+// Device is a device registered for push notifications
+type Device struct {
+	ID         uuid.UUID `json:"id"`
+	Platform   string    `json:"platform"`
+	Name       string    `json:"name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// RegisterDevice handles POST /api/v1/notifications/devices. Apps call it
+// after sign-in and whenever FCM rotates their token; a token already
+// registered moves to the caller.
+func (s *Server) RegisterDevice(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req RegisterDeviceReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	device, err := s.queries.RegisterDeviceToken(c.Request().Context(), db.RegisterDeviceTokenParams{
+		UserID:   userID,
+		Token:    req.Token,
+		Platform: req.Platform,
+		Name:     sql.NullString{String: req.Name, Valid: req.Name != ""},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Device")
+	}
+
+	return RespondSuccess(c, http.StatusCreated, deviceResponse(device))
+}
+
+// ListDevices handles GET /api/v1/notifications/devices
+func (s *Server) ListDevices(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	devices, err := s.queries.ListDeviceTokens(c.Request().Context(), userID)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch devices.")
+	}
+
+	resp := make([]Device, len(devices))
+	for i, d := range devices {
+		resp[i] = deviceResponse(d)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// UnregisterDevice handles DELETE /api/v1/notifications/devices/:id,
+// called by apps on sign-out
+func (s *Server) UnregisterDevice(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.queries.DeleteDeviceToken(c.Request().Context(), db.DeleteDeviceTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to remove device.")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "Device not found.")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func deviceResponse(d db.DeviceToken) Device {
+	return Device{
+		ID:         d.ID,
+		Platform:   d.Platform,
+		Name:       d.Name.String,
+		CreatedAt:  d.CreatedAt,
+		LastSeenAt: d.LastSeenAt,
+	}
+}
@@ -2,8 +2,8 @@ package server
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
-	"strconv"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/labstack/echo/v4"
@@ -18,11 +18,11 @@ type CreateDosageFormReq struct {
 func (s *Server) CreateDosageForm(c echo.Context) error {
 	var req CreateDosageFormReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -51,16 +51,15 @@ func (s *Server) ListDosageForms(c echo.Context) error {
 
 // GetDosageForm handles GET /api/v1/dosage_forms/:id
 func (s *Server) GetDosageForm(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "The provided ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	dosageForm, err := s.queries.GetDosageForm(ctx, int32(id))
+	dosageForm, err := s.queries.GetDosageForm(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "Dosage form with the specified ID was not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve dosage form.")
@@ -0,0 +1,230 @@
+// internal/server/drug_registry.go - National drug registry sync
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/registry"
+	"github.com/labstack/echo/v4"
+)
+
+// DrugRegistrySync summarises one registry import
+type DrugRegistrySync struct {
+	ID           uuid.UUID  `json:"id"`
+	Source       string     `json:"source"`
+	Status       string     `json:"status"`
+	TriggeredBy  *uuid.UUID `json:"triggered_by,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	TotalEntries int32      `json:"total_entries"`
+	Matched      int32      `json:"matched"`
+	Created      int32      `json:"created"`
+	Ambiguous    int32      `json:"ambiguous"`
+	Conflicts    int32      `json:"conflicts"`
+	Failed       int32      `json:"failed"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// DrugRegistrySyncItem is the reconciliation of one registry entry
+type DrugRegistrySyncItem struct {
+	IRC         string     `json:"irc"`
+	GenericCode string     `json:"generic_code,omitempty"`
+	Name        string     `json:"name"`
+	GTIN        string     `json:"gtin,omitempty"`
+	Outcome     string     `json:"outcome"`
+	ProductID   *uuid.UUID `json:"product_id,omitempty"`
+	Detail      string     `json:"detail,omitempty"`
+}
+
+// DrugRegistryReport is a sync with a page of its reconciled entries
+type DrugRegistryReport struct {
+	DrugRegistrySync
+	Items []DrugRegistrySyncItem `json:"items"`
+}
+
+// newRegistrySyncer creates the syncer; scheduled syncs start with Start
+func newRegistrySyncer(queries db.Querier, withTx registry.TxFunc, cfg config.RegistryConfig, logger *logging.Logger) *registry.Syncer {
+	return registry.NewSyncer(queries, withTx, registry.Config{
+		URL:      cfg.URL,
+		Interval: cfg.SyncInterval,
+		Timeout:  cfg.Timeout,
+	}, logger)
+}
+
+// StartDrugRegistrySync handles POST /api/v1/drug-registry/syncs. The
+// export is taken from the "file" form field (CSV or JSON) or, without one,
+// fetched from the configured registry URL. The sync runs in the
+// background; poll the returned sync for the report.
+func (s *Server) StartDrugRegistrySync(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, _ := middleware.GetUserIDFromContext(c)
+	triggeredBy := uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil}
+
+	var record db.DrugRegistrySync
+	file, err := c.FormFile("file")
+	switch {
+	case err == nil:
+		src, err := file.Open()
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_request",
+				"The uploaded file could not be read.")
+		}
+		entries, err := registry.Parse(src)
+		src.Close()
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_registry_file", err.Error())
+		}
+		record, err = s.registry.SyncEntries(ctx, "upload:"+file.Filename, triggeredBy, entries)
+		if err != nil {
+			return s.registrySyncError(c, err)
+		}
+	case s.registry.URL() == "":
+		return RespondError(c, http.StatusBadRequest, "registry_not_configured",
+			"Upload a registry export as \"file\" or configure the registry URL.")
+	default:
+		record, err = s.registry.SyncURL(ctx, triggeredBy)
+		if err != nil {
+			return s.registrySyncError(c, err)
+		}
+	}
+
+	s.logAudit(ctx, userID, "sync", "drug_registry", record.ID.String(),
+		nil, map[string]any{"source": record.Source},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusAccepted, drugRegistrySyncResponse(record))
+}
+
+// ListDrugRegistrySyncs handles GET /api/v1/drug-registry/syncs
+func (s *Server) ListDrugRegistrySyncs(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, ok := parseOffsetPage(c, 20)
+	if !ok {
+		return nil
+	}
+
+	records, err := s.queries.ListDrugRegistrySyncs(ctx, db.ListDrugRegistrySyncsParams{
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch drug registry syncs.")
+	}
+
+	syncs := make([]DrugRegistrySync, len(records))
+	for i, record := range records {
+		syncs[i] = drugRegistrySyncResponse(record)
+	}
+	total := s.listTotal(ctx, "drug_registry_syncs", nil, s.queries.CountDrugRegistrySyncs)
+	return respondOffsetPage(c, page, total, len(records), syncs)
+}
+
+// GetDrugRegistrySync handles GET /api/v1/drug-registry/syncs/:id, the
+// reconciliation report. ?outcome= narrows the entries, e.g. to the
+// ambiguous and conflicting ones that need a pharmacist.
+func (s *Server) GetDrugRegistrySync(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	outcome := c.QueryParam("outcome")
+	switch outcome {
+	case "", registry.OutcomeMatchedIRC, registry.OutcomeMatchedBarcode, registry.OutcomeMatchedName,
+		registry.OutcomeCreated, registry.OutcomeAmbiguous, registry.OutcomeConflict, registry.OutcomeFailed:
+	default:
+		return RespondError(c, http.StatusBadRequest, "invalid_outcome",
+			"outcome must be one of matched_irc, matched_barcode, matched_name, created, ambiguous, conflict, failed.")
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	ctx := c.Request().Context()
+	record, err := s.queries.GetDrugRegistrySync(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Drug registry sync")
+	}
+
+	rows, err := s.queries.ListDrugRegistrySyncItems(ctx, db.ListDrugRegistrySyncItemsParams{
+		SyncID:      id,
+		Outcome:     sql.NullString{String: outcome, Valid: outcome != ""},
+		LimitCount:  int32(limit),
+		OffsetCount: int32(offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch the reconciliation report.")
+	}
+
+	report := DrugRegistryReport{
+		DrugRegistrySync: drugRegistrySyncResponse(record),
+		Items:            make([]DrugRegistrySyncItem, len(rows)),
+	}
+	for i, row := range rows {
+		item := DrugRegistrySyncItem{
+			IRC:         row.Irc,
+			GenericCode: row.GenericCode.String,
+			Name:        row.Name,
+			GTIN:        row.Gtin.String,
+			Outcome:     row.Outcome,
+			Detail:      row.Detail.String,
+		}
+		if row.ProductID.Valid {
+			item.ProductID = &row.ProductID.UUID
+		}
+		report.Items[i] = item
+	}
+	return RespondSuccess(c, http.StatusOK, report)
+}
+
+func (s *Server) registrySyncError(c echo.Context, err error) error {
+	if errors.Is(err, registry.ErrSyncInProgress) {
+		return RespondError(c, http.StatusConflict, "sync_in_progress",
+			"A drug registry sync is already running. Try again when it has finished.")
+	}
+	return RespondError(c, http.StatusInternalServerError, "db_error",
+		"Failed to start the drug registry sync.")
+}
+
+func drugRegistrySyncResponse(r db.DrugRegistrySync) DrugRegistrySync {
+	resp := DrugRegistrySync{
+		ID:           r.ID,
+		Source:       r.Source,
+		Status:       r.Status,
+		StartedAt:    r.StartedAt,
+		TotalEntries: r.TotalEntries,
+		Matched:      r.Matched,
+		Created:      r.Created,
+		Ambiguous:    r.Ambiguous,
+		Conflicts:    r.Conflicts,
+		Failed:       r.Failed,
+		Error:        r.Error.String,
+	}
+	if r.TriggeredBy.Valid {
+		resp.TriggeredBy = &r.TriggeredBy.UUID
+	}
+	if r.FinishedAt.Valid {
+		resp.FinishedAt = &r.FinishedAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,274 @@
+// internal/server/erp.go - Handing fulfilled orders to the ERP
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/erp"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// ERPBatch is a batch of orders handed to the ERP
+type ERPBatch struct {
+	ID          uuid.UUID   `json:"id"`
+	Mode        string      `json:"mode"`
+	Format      string      `json:"format"`
+	OrderCount  int32       `json:"order_count"`
+	Status      string      `json:"status"`
+	Error       string      `json:"error,omitempty"`
+	CreatedBy   *uuid.UUID  `json:"created_by,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	DeliveredAt *time.Time  `json:"delivered_at,omitempty"`
+	OrderIDs    []uuid.UUID `json:"order_ids,omitempty"`
+}
+
+// ERPMapping describes the document the ERP receives
+type ERPMapping struct {
+	Format      string        `json:"format"`
+	Statuses    []string      `json:"statuses"`
+	Fields      []ERPFieldMap `json:"fields"`
+	Sources     []string      `json:"sources"`
+	PushEnabled bool          `json:"push_enabled"`
+}
+
+// ERPFieldMap is one field of the ERP document
+type ERPFieldMap struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+// newERPExporter creates the exporter, or returns nil when the field
+// mapping names an unknown source
+func newERPExporter(queries db.Querier, withTx erp.TxFunc, cfg config.ERPConfig, logger *logging.Logger) *erp.Exporter {
+	fields := make([]erp.Field, len(cfg.Fields))
+	for i, f := range cfg.Fields {
+		fields[i] = erp.Field{Name: f.Name, Source: f.Source, Value: f.Value}
+	}
+	mapping, err := erp.NewMapping(cfg.Format, fields)
+	if err != nil {
+		logger.Error("Invalid ERP mapping, ERP export disabled", err, nil)
+		return nil
+	}
+	return erp.NewExporter(queries, withTx, mapping, erp.Config{
+		Statuses:  cfg.Statuses,
+		BatchSize: cfg.BatchSize,
+		PushURL:   cfg.PushURL,
+		PushToken: cfg.PushToken,
+		Timeout:   cfg.Timeout,
+	})
+}
+
+func erpUnavailable(c echo.Context) error {
+	return RespondError(c, http.StatusServiceUnavailable, "erp_not_configured",
+		"The ERP field mapping is invalid; see the server log.")
+}
+
+// GetERPMapping handles GET /api/v1/erp/mapping
+func (s *Server) GetERPMapping(c echo.Context) error {
+	if s.erp == nil {
+		return erpUnavailable(c)
+	}
+
+	fields := make([]ERPFieldMap, len(s.config.ERP.Fields))
+	for i, f := range s.config.ERP.Fields {
+		fields[i] = ERPFieldMap{Name: f.Name, Source: f.Source, Value: f.Value}
+	}
+	return RespondSuccess(c, http.StatusOK, ERPMapping{
+		Format:      s.erp.Mapping().Format(),
+		Statuses:    s.config.ERP.Statuses,
+		Fields:      fields,
+		Sources:     erp.Sources(),
+		PushEnabled: s.erp.PushEnabled(),
+	})
+}
+
+// PullERPOrders handles GET /api/v1/erp/orders. It returns the next batch
+// of fulfilled orders as a CSV, JSON or XML document, with the batch id in
+// the X-ERP-Batch-ID header. The orders are offered again until the batch
+// is acknowledged; 204 means nothing is waiting.
+func (s *Server) PullERPOrders(c echo.Context) error {
+	if s.erp == nil {
+		return erpUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := middleware.GetUserIDFromContext(c)
+	batch, doc, err := s.erp.Pull(ctx, userID)
+	if errors.Is(err, erp.ErrNothingToExport) {
+		return c.NoContent(http.StatusNoContent)
+	}
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "export_error",
+			"Failed to build the ERP batch.")
+	}
+
+	s.logAudit(ctx, userID, "pull", "erp_batch", batch.ID.String(),
+		nil, map[string]any{"orders": batch.OrderCount, "format": batch.Format},
+		c.RealIP(), c.Request().UserAgent())
+
+	c.Response().Header().Set("X-ERP-Batch-ID", batch.ID.String())
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		`attachment; filename="orders-`+batch.ID.String()+"."+batch.Format+`"`)
+	return c.Blob(http.StatusOK, s.erp.Mapping().ContentType(), doc)
+}
+
+// AckERPBatch handles POST /api/v1/erp/batches/:id/ack, confirming that
+// the ERP imported a pulled batch
+func (s *Server) AckERPBatch(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	return s.ackERPBatch(c, id)
+}
+
+// ERPAckCallback is the body of an acknowledgement the ERP sends signed
+type ERPAckCallback struct {
+	BatchID uuid.UUID `json:"batch_id" validate:"required"`
+}
+
+// AckERPBatchCallback handles POST /api/v1/callbacks/erp/ack, the signed
+// form of AckERPBatch for an ERP importing batches without a user token.
+// The batch id is in the body, so the signature covers it.
+func (s *Server) AckERPBatchCallback(c echo.Context) error {
+	var req ERPAckCallback
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+	return s.ackERPBatch(c, req.BatchID)
+}
+
+func (s *Server) ackERPBatch(c echo.Context, id uuid.UUID) error {
+	if s.erp == nil {
+		return erpUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	batch, err := s.erp.Ack(ctx, id)
+	if errors.Is(err, erp.ErrBatchSettled) {
+		return RespondUnprocessable(c, "batch_settled",
+			"The batch has already been acknowledged or has failed.")
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "ERP batch")
+	}
+
+	// Signed callbacks have no user
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "ack", "erp_batch", batch.ID.String(),
+		nil, map[string]any{"orders": batch.OrderCount},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, erpBatchResponse(batch, nil))
+}
+
+// PushERPOrders handles POST /api/v1/erp/push, sending the next batch of
+// fulfilled orders to erp.push_url. 204 means nothing is waiting.
+func (s *Server) PushERPOrders(c echo.Context) error {
+	if s.erp == nil {
+		return erpUnavailable(c)
+	}
+	if !s.erp.PushEnabled() {
+		return RespondError(c, http.StatusServiceUnavailable, "erp_push_not_configured",
+			"No ERP push URL is configured. Set erp.push_url or ERP_PUSH_URL.")
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := middleware.GetUserIDFromContext(c)
+	batch, err := s.erp.Push(ctx, userID)
+	switch {
+	case errors.Is(err, erp.ErrNothingToExport):
+		return c.NoContent(http.StatusNoContent)
+	case err != nil && batch.Status == erp.StatusFailed:
+		s.logger.Error("ERP push failed", err, map[string]any{"batch_id": batch.ID.String()})
+		s.logAudit(ctx, userID, "push", "erp_batch", batch.ID.String(),
+			nil, map[string]any{"orders": batch.OrderCount, "status": batch.Status},
+			c.RealIP(), c.Request().UserAgent())
+		return RespondError(c, http.StatusBadGateway, "erp_push_failed",
+			"Batch "+batch.ID.String()+" was not accepted: "+err.Error())
+	case err != nil:
+		return RespondError(c, http.StatusInternalServerError, "export_error",
+			"Failed to build the ERP batch.")
+	}
+
+	s.logAudit(ctx, userID, "push", "erp_batch", batch.ID.String(),
+		nil, map[string]any{"orders": batch.OrderCount, "status": batch.Status},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, erpBatchResponse(batch, nil))
+}
+
+// ListERPBatches handles GET /api/v1/erp/batches
+func (s *Server) ListERPBatches(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, ok := parseOffsetPage(c, 20)
+	if !ok {
+		return nil
+	}
+
+	batches, err := s.queries.ListERPBatches(ctx, db.ListERPBatchesParams{
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch ERP batches.")
+	}
+
+	resp := make([]ERPBatch, len(batches))
+	for i, batch := range batches {
+		resp[i] = erpBatchResponse(batch, nil)
+	}
+	total := s.listTotal(ctx, "erp_batches", nil, s.queries.CountERPBatches)
+	return respondOffsetPage(c, page, total, len(batches), resp)
+}
+
+// GetERPBatch handles GET /api/v1/erp/batches/:id with the batch's orders
+func (s *Server) GetERPBatch(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	batch, err := s.queries.GetERPBatch(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "ERP batch")
+	}
+	orderIDs, err := s.queries.ListERPBatchOrderIDs(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "ERP batch")
+	}
+	return RespondSuccess(c, http.StatusOK, erpBatchResponse(batch, orderIDs))
+}
+
+func erpBatchResponse(b db.ErpBatch, orderIDs []uuid.UUID) ERPBatch {
+	resp := ERPBatch{
+		ID:         b.ID,
+		Mode:       b.Mode,
+		Format:     b.Format,
+		OrderCount: b.OrderCount,
+		Status:     b.Status,
+		Error:      b.Error.String,
+		CreatedAt:  b.CreatedAt,
+		OrderIDs:   orderIDs,
+	}
+	if b.CreatedBy.Valid {
+		resp.CreatedBy = &b.CreatedBy.UUID
+	}
+	if b.DeliveredAt.Valid {
+		resp.DeliveredAt = &b.DeliveredAt.Time
+	}
+	return resp
+}
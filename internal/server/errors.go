@@ -8,7 +8,7 @@ import (
 // Common error types
 var (
 	ErrInvalidRequest     = NewAPIError(http.StatusBadRequest, "invalid_request", "The request body is malformed or invalid")
-	ErrValidationFailed   = NewAPIError(http.StatusBadRequest, "validation_error", "Request validation failed")
+	ErrValidationFailed   = NewAPIError(http.StatusUnprocessableEntity, "validation_error", "Request validation failed")
 	ErrUnauthorized       = NewAPIError(http.StatusUnauthorized, "unauthorized", "Authentication required")
 	ErrForbidden          = NewAPIError(http.StatusForbidden, "forbidden", "Insufficient permissions")
 	ErrNotFound           = NewAPIError(http.StatusNotFound, "not_found", "Resource not found")
@@ -0,0 +1,109 @@
+// internal/server/events.go - Transactions and domain events
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/labstack/echo/v4"
+)
+
+// newOutboxRelay creates the relay that delivers recorded events to the
+// configured webhooks and message broker
+func newOutboxRelay(queries db.Querier, cfg config.EventsConfig, logger *logging.Logger) *outbox.Relay {
+	var publishers []outbox.Publisher
+	for _, webhook := range webhookPublishers(cfg.Webhooks) {
+		publishers = append(publishers, webhook)
+	}
+	if broker, err := newBrokerPublisher(cfg.Broker); err != nil {
+		logger.Error("Event broker publishing disabled", err, map[string]any{"broker": cfg.Broker.Type})
+	} else if broker != nil {
+		publishers = append(publishers, broker)
+	}
+
+	relayConfig := outbox.DefaultRelayConfig()
+	relayConfig.PollInterval = cfg.PollInterval
+	relayConfig.BatchSize = cfg.BatchSize
+	relayConfig.Retention = cfg.Retention
+
+	return outbox.NewRelay(queries, relayConfig, logger, publishers...)
+}
+
+// webhookPublishers returns a publisher for each configured webhook
+func webhookPublishers(cfg config.WebhooksConfig) []*outbox.WebhookPublisher {
+	webhooks := make([]*outbox.WebhookPublisher, 0, len(cfg.URLs))
+	for _, endpoint := range cfg.URLs {
+		webhooks = append(webhooks, outbox.NewWebhookPublisher(endpoint, cfg.Secret, cfg.Timeout))
+	}
+	return webhooks
+}
+
+// newBrokerPublisher returns the NATS or Kafka publisher, or nil when no
+// broker is configured
+func newBrokerPublisher(cfg config.BrokerConfig) (outbox.Publisher, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "":
+		return nil, nil
+	case "nats":
+		return outbox.NewNATSPublisher(outbox.NATSConfig{
+			URL:       cfg.URL,
+			Token:     cfg.Token,
+			Prefix:    cfg.Prefix,
+			JetStream: cfg.JetStream,
+			Timeout:   cfg.Timeout,
+		})
+	case "kafka":
+		return outbox.NewKafkaPublisher(outbox.KafkaConfig{
+			URL:      cfg.URL,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			Prefix:   cfg.Prefix,
+			Timeout:  cfg.Timeout,
+		})
+	default:
+		return nil, fmt.Errorf("unknown event broker %q", cfg.Type)
+	}
+}
+
+// withTx runs fn inside a database transaction and commits if it returns
+// nil. Without a database (e.g. against a mock Querier) fn runs directly.
+// The relay is woken after commit so events go out without waiting for the
+// next poll.
+func (s *Server) withTx(ctx context.Context, fn func(q db.Querier) error) error {
+	if s.db == nil {
+		return fn(s.queries)
+	}
+
+	tx, err := db.BeginTx(ctx, s.db, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(db.New(s.slowQueries.wrap(tx))); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.outbox.Notify()
+	return nil
+}
+
+// recordEvent stores a domain event through q, which must belong to the
+// transaction making the change
+func (s *Server) recordEvent(c echo.Context, q db.Querier, eventType, aggregateID string, data any) error {
+	var actor string
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		actor = userID.String()
+	}
+	return outbox.Record(c.Request().Context(), q, eventType, aggregateID, actor, data)
+}
@@ -0,0 +1,176 @@
+// internal/server/export_stream.go - Row limits and time limits of streamed exports
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+// exportBatchSize is how many rows an export reads from the database at a
+// time
+const exportBatchSize = 500
+
+// Why an export stopped before its last row, sent as X-Export-Truncated
+const (
+	exportTruncatedRows = "rows"
+	exportTruncatedTime = "time"
+)
+
+// Headers of a streamed export. The limits are sent before the body; the
+// number of rows written and, when it was cut short, why, follow it as
+// trailers, or as headers when the file is stored instead.
+const (
+	headerExportRowLimit  = "X-Export-Row-Limit"
+	headerExportTimeLimit = "X-Export-Time-Limit"
+	headerExportRows      = "X-Export-Rows"
+	headerExportTruncated = "X-Export-Truncated"
+)
+
+// exportSource returns the next rows of an export, one slice of cells per
+// row. It should return about limit rows; more is false once there are
+// none left.
+type exportSource func(ctx context.Context, limit int) (rows [][]any, more bool, err error)
+
+// exportResult is how much of an export was written
+type exportResult struct {
+	Rows      int
+	Truncated string // exportTruncatedRows, exportTruncatedTime or ""
+}
+
+// keysetSource reads a list batch by batch, each batch starting after the
+// cursor of the last row of the one before, and turns its rows into cells
+func keysetSource[T any](list func(context.Context, pagination.Page) ([]T, error), key func(T) pagination.Cursor, cells func(context.Context, T) []any) exportSource {
+	var after *pagination.Cursor
+	return func(ctx context.Context, limit int) ([][]any, bool, error) {
+		items, err := list(ctx, pagination.Page{Limit: limit, After: after})
+		if err != nil {
+			return nil, false, err
+		}
+		rows := make([][]any, len(items))
+		for i, item := range items {
+			rows[i] = cells(ctx, item)
+		}
+		if len(items) > 0 {
+			last := key(items[len(items)-1])
+			after = &last
+		}
+		return rows, len(items) == limit, nil
+	}
+}
+
+// sliceSource returns rows that are already in memory
+func sliceSource(rows [][]any) exportSource {
+	return func(_ context.Context, limit int) ([][]any, bool, error) {
+		batch := rows[:min(limit, len(rows))]
+		rows = rows[len(batch):]
+		return batch, len(rows) > 0, nil
+	}
+}
+
+// exportStream copies the rows of a source, at most exports.max_rows of
+// them and for at most exports.timeout
+type exportStream struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	source  exportSource
+	maxRows int
+
+	rows   [][]any // the batch read last
+	more   bool
+	result exportResult
+}
+
+// openExport starts an export of source by reading its first batch, so a
+// failure there can still be answered with an error response. The stream
+// must be copied, or closed, to release its time limit.
+func (s *Server) openExport(ctx context.Context, source exportSource) (*exportStream, error) {
+	limits := s.config.Exports
+	e := &exportStream{source: source, maxRows: limits.MaxRows}
+	e.ctx, e.cancel = context.WithTimeout(ctx, limits.Timeout)
+	if err := e.fetch(); err != nil {
+		e.cancel()
+		return nil, err
+	}
+	return e, nil
+}
+
+// fetch reads the next batch; one row past the limit is asked for, to
+// tell an export that ends at the limit from one cut short by it
+func (e *exportStream) fetch() error {
+	var err error
+	e.rows, e.more, err = e.source(e.ctx, min(exportBatchSize, e.maxRows-e.result.Rows+1))
+	return err
+}
+
+// timedOut reports whether the export ran out of time, rather than being
+// cancelled by its request
+func (e *exportStream) timedOut() bool {
+	return errors.Is(e.ctx.Err(), context.DeadlineExceeded)
+}
+
+// copy passes every row to write, calling flush after each batch, and
+// closes the stream. Reaching either limit is not an error: the rows
+// written so far stand and the result says why the export stopped.
+func (e *exportStream) copy(write func(row []any) error, flush func() error) (exportResult, error) {
+	defer e.close()
+	for {
+		for _, row := range e.rows {
+			if e.result.Rows == e.maxRows {
+				e.result.Truncated = exportTruncatedRows
+				break
+			}
+			if err := write(row); err != nil {
+				return e.result, err
+			}
+			e.result.Rows++
+		}
+		if err := flush(); err != nil {
+			return e.result, err
+		}
+		if !e.more || e.result.Truncated != "" {
+			return e.result, nil
+		}
+		if e.timedOut() {
+			e.result.Truncated = exportTruncatedTime
+			return e.result, nil
+		}
+		if err := e.fetch(); err != nil {
+			if e.timedOut() {
+				e.result.Truncated = exportTruncatedTime
+				return e.result, nil
+			}
+			return e.result, err
+		}
+	}
+}
+
+// close releases the stream without copying the rest of it
+func (e *exportStream) close() {
+	e.cancel()
+}
+
+// startExport sends the export limits as headers and moves the response's
+// write deadline past the time limit, which may be longer than the
+// server's write timeout. Writers that cannot move it keep the timeout.
+func (s *Server) startExport(c echo.Context) {
+	limits := s.config.Exports
+	h := c.Response().Header()
+	h.Set(headerExportRowLimit, strconv.Itoa(limits.MaxRows))
+	h.Set(headerExportTimeLimit, limits.Timeout.String())
+	deadline := time.Now().Add(limits.Timeout + s.config.Server.WriteTimeout)
+	http.NewResponseController(c.Response()).SetWriteDeadline(deadline)
+}
+
+// setExportResult records how much of an export was written in h
+func setExportResult(h http.Header, result exportResult) {
+	h.Set(headerExportRows, strconv.Itoa(result.Rows))
+	if result.Truncated != "" {
+		h.Set(headerExportTruncated, result.Truncated)
+	}
+}
@@ -0,0 +1,307 @@
+// internal/server/exports.go - Generated export files kept in storage
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/jamalkaksouri/DigiOrder/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// Export kinds
+const exportKindOrders = "orders"
+
+// ExportOrdersReq selects the orders to export by creation time; both
+// bounds are optional and default to the last 30 days. Calendar jalali
+// adds the Jalali times as extra columns.
+type ExportOrdersReq struct {
+	From     *time.Time `json:"from"`
+	To       *time.Time `json:"to"`
+	Calendar string     `json:"calendar,omitempty"`
+}
+
+// ExportFile is a generated file with a presigned download URL
+type ExportFile struct {
+	ID          uuid.UUID  `json:"id"`
+	Kind        string     `json:"kind"`
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"content_type"`
+	SizeBytes   int64      `json:"size_bytes"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	URL         string     `json:"url"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// ExportOrders handles POST /api/v1/exports/orders. It writes the orders
+// and their items as CSV, one row per item, and stores the file so the
+// returned link can be shared until it expires. Orders are read in
+// batches and the rows spooled to a temporary file, so memory use does not
+// grow with the export; the rows written and whether the export hit a
+// limit are reported in headers.
+func (s *Server) ExportOrders(c echo.Context) error {
+	var req ExportOrdersReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if req.Calendar != "" && !jalali.IsCalendar(req.Calendar) {
+		return invalidCalendar(c)
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	to := time.Now().UTC()
+	if req.To != nil {
+		to = req.To.UTC()
+	}
+	from := to.AddDate(0, 0, -30)
+	if req.From != nil {
+		from = req.From.UTC()
+	}
+	if !from.Before(to) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be before to.")
+	}
+
+	ctx := c.Request().Context()
+	s.startExport(c)
+	export, err := s.openExport(ctx, s.orderExportSource(from, to, req.Calendar == jalali.Jalali))
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch orders for export.")
+	}
+
+	file, err := os.CreateTemp("", "digiorder-export-*.csv")
+	if err != nil {
+		export.close()
+		s.logger.Error("Failed to create export file", err, map[string]any{"kind": exportKindOrders})
+		return RespondError(c, http.StatusInternalServerError, "export_error",
+			"Failed to write the export.")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	result, size, err := writeOrdersCSV(file, export, req.Calendar == jalali.Jalali)
+	if err != nil {
+		s.logger.Error("Failed to write export", err, map[string]any{"kind": exportKindOrders, "rows": result.Rows})
+		return RespondError(c, http.StatusInternalServerError, "export_error",
+			"Failed to write the export.")
+	}
+	setExportResult(c.Response().Header(), result)
+	if result.Truncated != "" {
+		s.logger.Warn("CSV export truncated", map[string]any{"kind": exportKindOrders, "rows": result.Rows, "limit": result.Truncated})
+	}
+
+	id := uuid.New()
+	filename := "orders-" + from.Format("20060102") + "-" + to.Format("20060102") + ".csv"
+	key := storage.Join(storage.PrefixExports, id.String(), filename)
+	if err := s.store.Put(ctx, key, file, size, "text/csv"); err != nil {
+		s.logger.Error("Failed to store export", err, map[string]any{"kind": exportKindOrders})
+		return RespondError(c, http.StatusBadGateway, "storage_error", "Failed to store the export.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	record, err := s.queries.CreateExportFile(ctx, db.CreateExportFileParams{
+		Kind:        exportKindOrders,
+		ObjectKey:   key,
+		Filename:    filename,
+		ContentType: "text/csv",
+		SizeBytes:   size,
+		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
+	if err != nil {
+		s.deleteObject(key)
+		return HandleDatabaseError(c, err, "Export")
+	}
+
+	s.logAudit(ctx, userID, "create", "export", record.ID.String(),
+		nil, map[string]any{"kind": record.Kind, "from": from, "to": to, "rows": result.Rows, "truncated": result.Truncated},
+		c.RealIP(), c.Request().UserAgent())
+
+	resp, err := s.exportFileResponse(c, record)
+	if err != nil {
+		return presignFailed(c)
+	}
+	return RespondSuccess(c, http.StatusCreated, resp)
+}
+
+// ListExports handles GET /api/v1/exports
+func (s *Server) ListExports(c echo.Context) error {
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	ctx := c.Request().Context()
+	page, ok := parseOffsetPage(c, 20)
+	if !ok {
+		return nil
+	}
+
+	records, err := s.queries.ListExportFiles(ctx, db.ListExportFilesParams{
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch exports.")
+	}
+
+	exports := make([]ExportFile, len(records))
+	for i, record := range records {
+		if exports[i], err = s.exportFileResponse(c, record); err != nil {
+			return presignFailed(c)
+		}
+	}
+	total := s.listTotal(ctx, "export_files", nil, s.queries.CountExportFiles)
+	return respondOffsetPage(c, page, total, len(records), exports)
+}
+
+// GetExport handles GET /api/v1/exports/:id with a fresh download URL
+func (s *Server) GetExport(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if s.store == nil {
+		return storageUnavailable(c)
+	}
+
+	record, err := s.queries.GetExportFile(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Export")
+	}
+
+	resp, err := s.exportFileResponse(c, record)
+	if err != nil {
+		return presignFailed(c)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// orderExportSource reads the orders created in [from, to), oldest first,
+// a batch of orders at a time, as one row of CSV cells per order item;
+// withJalali appends the order times in the Jalali calendar
+func (s *Server) orderExportSource(from, to time.Time, withJalali bool) exportSource {
+	var after *pagination.Cursor
+	return func(ctx context.Context, limit int) ([][]any, bool, error) {
+		page := pagination.Page{After: after}
+		items, err := s.queries.ListOrderExportRows(ctx, db.ListOrderExportRowsParams{
+			FromTime:   from,
+			ToTime:     to,
+			AfterTime:  page.AfterTime(),
+			AfterID:    page.AfterID(),
+			OrderLimit: int32(limit),
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		rows := make([][]any, len(items))
+		orders := 0
+		for i, r := range items {
+			if i == 0 || r.OrderID != items[i-1].OrderID {
+				orders++
+			}
+			var itemID, qty string
+			if r.ItemID.Valid {
+				itemID = r.ItemID.UUID.String()
+			}
+			if r.RequestedQty.Valid {
+				qty = strconv.Itoa(int(r.RequestedQty.Int32))
+			}
+			rows[i] = []any{
+				r.OrderID.String(), r.Status, r.Priority,
+				formatNullTime(r.CreatedAt),
+				formatNullTime(r.SubmittedAt),
+				r.Username.String, r.RequesterKind.String, r.RequesterName.String, itemID, r.ProductName.String, r.Strength.String, qty, r.Unit.String, r.Note.String,
+			}
+			if withJalali {
+				rows[i] = append(rows[i], s.jalaliTime(r.CreatedAt), s.jalaliTime(r.SubmittedAt))
+			}
+		}
+		if len(items) > 0 {
+			last := items[len(items)-1]
+			after = &pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.OrderID}
+		}
+		return rows, orders == limit, nil
+	}
+}
+
+// writeOrdersCSV copies the rows of export to f as CSV under the order
+// header, returning the size of the file; withJalali adds the Jalali
+// columns to the header
+func writeOrdersCSV(f *os.File, export *exportStream, withJalali bool) (exportResult, int64, error) {
+	w := csv.NewWriter(f)
+	header := []string{
+		"order_id", "status", "priority", "created_at", "submitted_at", "created_by",
+		"requester_kind", "requester", "item_id", "product", "strength", "requested_qty", "unit", "note",
+	}
+	if withJalali {
+		header = append(header, "created_at_jalali", "submitted_at_jalali")
+	}
+	if err := w.Write(header); err != nil {
+		export.close()
+		return exportResult{}, 0, err
+	}
+
+	record := make([]string, len(header))
+	result, err := export.copy(func(row []any) error {
+		for i, cell := range row {
+			record[i], _ = cell.(string)
+		}
+		return w.Write(record)
+	}, func() error {
+		w.Flush()
+		return w.Error()
+	})
+	if err != nil {
+		return result, 0, err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	return result, size, err
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+func (s *Server) exportFileResponse(c echo.Context, f db.ExportFile) (ExportFile, error) {
+	link, expires, err := s.downloadURL(c, f.ObjectKey, f.Filename)
+	if err != nil {
+		return ExportFile{}, err
+	}
+	resp := ExportFile{
+		ID:          f.ID,
+		Kind:        f.Kind,
+		Filename:    f.Filename,
+		ContentType: f.ContentType,
+		SizeBytes:   f.SizeBytes,
+		CreatedAt:   f.CreatedAt,
+		URL:         link,
+		ExpiresAt:   expires,
+	}
+	if f.CreatedBy.Valid {
+		resp.CreatedBy = &f.CreatedBy.UUID
+	}
+	return resp, nil
+}
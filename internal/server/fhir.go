@@ -0,0 +1,327 @@
+// internal/server/fhir.go - FHIR export of products and orders
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/fhir"
+	"github.com/labstack/echo/v4"
+)
+
+// errUnknownFHIRID is returned for FHIR IDs that were never assigned
+var errUnknownFHIRID = errors.New("unknown FHIR ID")
+
+// ListFHIRMedications handles GET /api/v1/fhir/Medication
+func (s *Server) ListFHIRMedications(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := strconv.Atoi(c.QueryParam("_count"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.QueryParam("_offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	products, err := s.queries.ListProducts(ctx, db.ListProductsParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return respondFHIRError(c, http.StatusInternalServerError, "db_error", "Failed to fetch products.")
+	}
+
+	localIDs := make([]string, len(products))
+	for i, p := range products {
+		localIDs[i] = p.ID.String()
+	}
+	ids, err := s.fhirIDs(ctx, fhir.ResourceMedication, localIDs)
+	if err != nil {
+		return respondFHIRError(c, http.StatusInternalServerError, "db_error", "Failed to assign FHIR IDs.")
+	}
+	forms := s.dosageFormNames(ctx)
+
+	base := fhirBaseURL(c)
+	bundle := fhir.NewBundle("searchset")
+	bundle.Link = []fhir.BundleLink{{
+		Relation: "self",
+		URL:      fmt.Sprintf("%s/Medication?_count=%d&_offset=%d", base, limit, offset),
+	}}
+	if len(products) == limit {
+		bundle.Link = append(bundle.Link, fhir.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("%s/Medication?_count=%d&_offset=%d", base, limit, offset+limit),
+		})
+	}
+	for _, p := range products {
+		med := s.fhirMapper().Medication(p, ids[p.ID.String()], forms[p.DosageFormID.Int32])
+		bundle.Entry = append(bundle.Entry, fhir.BundleEntry{
+			FullURL:  base + "/Medication/" + med.ID,
+			Resource: med,
+			Search:   &fhir.EntrySearch{Mode: "match"},
+		})
+	}
+
+	return respondFHIR(c, http.StatusOK, bundle)
+}
+
+// GetFHIRMedication handles GET /api/v1/fhir/Medication/:id
+func (s *Server) GetFHIRMedication(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	productID, err := s.fhirLocalID(ctx, fhir.ResourceMedication, c.Param("id"))
+	if err != nil {
+		return fhirLookupError(c, err, "Medication")
+	}
+	product, err := s.queries.GetProduct(ctx, productID)
+	if err != nil {
+		return fhirLookupError(c, err, "Medication")
+	}
+
+	med := s.fhirMapper().Medication(product, c.Param("id"), s.dosageFormNames(ctx)[product.DosageFormID.Int32])
+	return respondFHIR(c, http.StatusOK, med)
+}
+
+// GetFHIRMedicationRequest handles GET /api/v1/fhir/MedicationRequest/:id
+func (s *Server) GetFHIRMedicationRequest(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	itemID, err := s.fhirLocalID(ctx, fhir.ResourceMedicationRequest, c.Param("id"))
+	if err != nil {
+		return fhirLookupError(c, err, "MedicationRequest")
+	}
+	item, err := s.queries.GetOrderItem(ctx, itemID)
+	if err != nil {
+		return fhirLookupError(c, err, "MedicationRequest")
+	}
+	order, err := s.queries.GetOrder(ctx, item.OrderID.UUID)
+	if err != nil {
+		return fhirLookupError(c, err, "MedicationRequest")
+	}
+
+	bundle, err := s.orderBundle(c, order, []db.OrderItem{item})
+	if err != nil {
+		return respondFHIRError(c, http.StatusInternalServerError, "db_error", "Failed to export order item.")
+	}
+	return respondFHIR(c, http.StatusOK, bundle.Entry[0].Resource)
+}
+
+// GetFHIROrderBundle handles GET /api/v1/fhir/orders/:id, where :id is the
+// DigiOrder order ID
+func (s *Server) GetFHIROrderBundle(c echo.Context) error {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return respondFHIRError(c, http.StatusBadRequest, "invalid_id", "The provided ID is not a valid UUID.")
+	}
+	return s.respondOrderBundle(c, orderID)
+}
+
+// GetFHIRBundle handles GET /api/v1/fhir/Bundle/:id, the order bundle by
+// its FHIR ID
+func (s *Server) GetFHIRBundle(c echo.Context) error {
+	orderID, err := s.fhirLocalID(c.Request().Context(), fhir.ResourceBundle, c.Param("id"))
+	if err != nil {
+		return fhirLookupError(c, err, "Bundle")
+	}
+	return s.respondOrderBundle(c, orderID)
+}
+
+func (s *Server) respondOrderBundle(c echo.Context, orderID uuid.UUID) error {
+	ctx := c.Request().Context()
+
+	order, err := s.queries.GetOrder(ctx, orderID)
+	if err != nil {
+		return fhirLookupError(c, err, "Order")
+	}
+	items, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: order.ID, Valid: true})
+	if err != nil {
+		return respondFHIRError(c, http.StatusInternalServerError, "db_error", "Failed to fetch order items.")
+	}
+
+	bundle, err := s.orderBundle(c, order, items)
+	if err != nil {
+		return respondFHIRError(c, http.StatusInternalServerError, "db_error", "Failed to export order.")
+	}
+	return respondFHIR(c, http.StatusOK, bundle)
+}
+
+// orderBundle builds a collection bundle with one MedicationRequest per
+// item followed by the Medications they reference
+func (s *Server) orderBundle(c echo.Context, order db.Order, items []db.OrderItem) (fhir.Bundle, error) {
+	ctx := c.Request().Context()
+	mapper := s.fhirMapper()
+	base := fhirBaseURL(c)
+
+	bundleIDs, err := s.fhirIDs(ctx, fhir.ResourceBundle, []string{order.ID.String()})
+	if err != nil {
+		return fhir.Bundle{}, err
+	}
+
+	var itemIDs, productIDs []string
+	products := map[string]db.Product{}
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID.String())
+		if !item.ProductID.Valid {
+			continue
+		}
+		key := item.ProductID.UUID.String()
+		if _, seen := products[key]; seen {
+			continue
+		}
+		product, err := s.queries.GetProduct(ctx, item.ProductID.UUID)
+		if err != nil {
+			return fhir.Bundle{}, err
+		}
+		products[key] = product
+		productIDs = append(productIDs, key)
+	}
+
+	requestIDs, err := s.fhirIDs(ctx, fhir.ResourceMedicationRequest, itemIDs)
+	if err != nil {
+		return fhir.Bundle{}, err
+	}
+	medicationIDs, err := s.fhirIDs(ctx, fhir.ResourceMedication, productIDs)
+	if err != nil {
+		return fhir.Bundle{}, err
+	}
+
+	var requester *fhir.Reference
+	if order.CreatedBy.Valid {
+		if user, err := s.queries.GetUser(ctx, order.CreatedBy.UUID); err == nil {
+			requester = mapper.UserReference(user)
+		}
+	}
+
+	bundle := fhir.NewBundle("collection")
+	bundle.ID = bundleIDs[order.ID.String()]
+	bundle.Identifier = &fhir.Identifier{System: mapper.System("order"), Value: order.ID.String()}
+
+	for _, item := range items {
+		var medication *fhir.Reference
+		if product, ok := products[item.ProductID.UUID.String()]; ok && item.ProductID.Valid {
+			medication = &fhir.Reference{
+				Reference: "Medication/" + medicationIDs[product.ID.String()],
+				Display:   product.Name,
+			}
+		}
+		request := mapper.MedicationRequest(order, item, requestIDs[item.ID.String()], medication, requester)
+		bundle.Entry = append(bundle.Entry, fhir.BundleEntry{
+			FullURL:  base + "/MedicationRequest/" + request.ID,
+			Resource: request,
+		})
+	}
+
+	forms := s.dosageFormNames(ctx)
+	for _, key := range productIDs {
+		product := products[key]
+		med := mapper.Medication(product, medicationIDs[key], forms[product.DosageFormID.Int32])
+		bundle.Entry = append(bundle.Entry, fhir.BundleEntry{
+			FullURL:  base + "/Medication/" + med.ID,
+			Resource: med,
+		})
+	}
+
+	return bundle, nil
+}
+
+// fhirIDs returns the persisted FHIR ID of each local ID, assigning new
+// ones on first export
+func (s *Server) fhirIDs(ctx context.Context, resourceType string, localIDs []string) (map[string]string, error) {
+	ids := make(map[string]string, len(localIDs))
+	if len(localIDs) == 0 {
+		return ids, nil
+	}
+
+	rows, err := s.queries.EnsureFHIRResourceIDs(ctx, db.EnsureFHIRResourceIDsParams{
+		ResourceType: resourceType,
+		LocalIds:     localIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		ids[row.LocalID] = row.FhirID.String()
+	}
+	return ids, nil
+}
+
+// fhirLocalID resolves a FHIR ID back to the local row ID
+func (s *Server) fhirLocalID(ctx context.Context, resourceType, fhirID string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(fhirID)
+	if err != nil {
+		return uuid.Nil, errUnknownFHIRID
+	}
+
+	localID, err := s.queries.GetFHIRResourceLocalID(ctx, db.GetFHIRResourceLocalIDParams{
+		ResourceType: resourceType,
+		FhirID:       parsed,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, errUnknownFHIRID
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(localID)
+}
+
+// dosageFormNames maps dosage form IDs to names; an empty map on error
+// just leaves Medication.form out
+func (s *Server) dosageFormNames(ctx context.Context) map[int32]string {
+	names := map[int32]string{}
+	forms, err := s.queries.ListDosageForms(ctx)
+	if err != nil {
+		return names
+	}
+	for _, form := range forms {
+		names[form.ID] = form.Name
+	}
+	return names
+}
+
+func (s *Server) fhirMapper() fhir.Mapper {
+	return fhir.Mapper{SystemBase: s.config.FHIR.SystemBase}
+}
+
+// fhirBaseURL is the absolute base used for Bundle.entry.fullUrl, e.g.
+// https://host/api/v1/fhir
+func fhirBaseURL(c echo.Context) string {
+	prefix, _, _ := strings.Cut(c.Path(), "/fhir/")
+	return c.Scheme() + "://" + c.Request().Host + prefix + "/fhir"
+}
+
+// fhirLookupError answers 404 for unknown IDs and 500 otherwise
+func fhirLookupError(c echo.Context, err error, resource string) error {
+	if errors.Is(err, errUnknownFHIRID) || errors.Is(err, sql.ErrNoRows) {
+		return respondFHIRError(c, http.StatusNotFound, "not_found", resource+" not found.")
+	}
+	return respondFHIRError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve "+resource+".")
+}
+
+// respondFHIR writes a bare FHIR resource; FHIR clients do not expect the
+// {data} envelope used by the rest of the API
+func respondFHIR(c echo.Context, code int, resource any) error {
+	body, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, fhir.ContentType, body)
+}
+
+// respondFHIRError reports an error as an OperationOutcome
+func respondFHIRError(c echo.Context, code int, errCode, details string) error {
+	return respondFHIR(c, code, fhir.NewOperationOutcome(code, errCode, details))
+}
@@ -0,0 +1,219 @@
+// internal/server/files.go - Object storage wiring, uploads and downloads
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	"github.com/jamalkaksouri/DigiOrder/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// filesPath is the route serving presigned downloads from local storage
+const filesPath = "/api/v1/files"
+
+// upload is a validated multipart file, read into memory
+type upload struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// newStore creates the configured storage backend
+func newStore(cfg config.StorageConfig, jwtSecret string) (storage.Store, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:       cfg.S3.Endpoint,
+			PublicEndpoint: cfg.S3.PublicEndpoint,
+			Region:         cfg.S3.Region,
+			Bucket:         cfg.S3.Bucket,
+			AccessKey:      cfg.S3.AccessKey,
+			SecretKey:      cfg.S3.SecretKey,
+			PathStyle:      cfg.S3.PathStyle,
+		})
+	default:
+		key := cfg.SigningKey
+		if key == "" {
+			key = jwtSecret
+		}
+		return storage.NewLocalStore(cfg.Local.Path, filesPath, []byte(key))
+	}
+}
+
+// ServeFile handles GET /api/v1/files/*, the presigned download route of
+// local storage. The signature stands in for authentication.
+func (s *Server) ServeFile(c echo.Context) error {
+	local, ok := s.store.(*storage.LocalStore)
+	if !ok {
+		return RespondError(c, http.StatusNotFound, "not_found", "File not found.")
+	}
+
+	key, err := url.PathUnescape(c.Param("*"))
+	if err != nil || storage.ValidKey(key) != nil {
+		return RespondError(c, http.StatusNotFound, "not_found", "File not found.")
+	}
+	filename := c.QueryParam("filename")
+	if !local.Verify(key, c.QueryParam("expires"), filename, c.QueryParam("signature")) {
+		return RespondError(c, http.StatusForbidden, "invalid_signature",
+			"The download link is invalid or has expired.")
+	}
+
+	body, info, err := local.Get(c.Request().Context(), key)
+	if err == storage.ErrNotFound {
+		return RespondError(c, http.StatusNotFound, "not_found", "File not found.")
+	}
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "storage_error", "Failed to read the file.")
+	}
+	defer body.Close()
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentLength, strconv.FormatInt(info.Size, 10))
+	h.Set("Cache-Control", "private, max-age=300")
+	h.Set("X-Content-Type-Options", "nosniff")
+	if filename != "" {
+		h.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	return c.Stream(http.StatusOK, info.ContentType, body)
+}
+
+// downloadURL presigns key, making local storage URLs absolute
+func (s *Server) downloadURL(c echo.Context, key, filename string) (string, time.Time, error) {
+	expiry := s.config.Storage.URLExpiry
+	link, err := s.store.PresignGet(c.Request().Context(), key, expiry, filename)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if strings.HasPrefix(link, "/") {
+		link = c.Scheme() + "://" + c.Request().Host + link
+	}
+	return link, time.Now().Add(expiry).UTC(), nil
+}
+
+func storageUnavailable(c echo.Context) error {
+	return RespondError(c, http.StatusServiceUnavailable, "storage_unavailable",
+		"File storage is not available.")
+}
+
+func presignFailed(c echo.Context) error {
+	return RespondError(c, http.StatusInternalServerError, "storage_error",
+		"Failed to create a download URL.")
+}
+
+// readUpload reads the multipart field into memory, enforcing the upload
+// size limit. allowed lists the accepted sniffed content types; nil allows
+// any. A nil upload means the error response has been written.
+func (s *Server) readUpload(c echo.Context, field string, allowed []string) (*upload, error) {
+	if s.store == nil {
+		return nil, storageUnavailable(c)
+	}
+
+	header, err := c.FormFile(field)
+	if err != nil {
+		return nil, RespondError(c, http.StatusBadRequest, "missing_file",
+			fmt.Sprintf("Upload the file as multipart form field %q.", field))
+	}
+	limit := int64(s.config.Storage.MaxUploadMB) << 20
+	if header.Size > limit {
+		return nil, RespondError(c, http.StatusRequestEntityTooLarge, "file_too_large",
+			fmt.Sprintf("Files may be at most %d MB.", s.config.Storage.MaxUploadMB))
+	}
+
+	data, err := readFormFile(header, limit)
+	if err != nil || len(data) == 0 {
+		return nil, RespondError(c, http.StatusBadRequest, "invalid_file", "The uploaded file could not be read.")
+	}
+
+	contentType := http.DetectContentType(data)
+	if base, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = base
+	}
+	if allowed != nil && !slices.Contains(allowed, contentType) {
+		return nil, RespondError(c, http.StatusUnsupportedMediaType, "unsupported_file_type",
+			fmt.Sprintf("Files of type %s are not accepted here; use %s.", contentType, strings.Join(allowed, ", ")))
+	}
+
+	return &upload{
+		Filename:    sanitizeFilename(header.Filename),
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+func readFormFile(header *multipart.FileHeader, limit int64) ([]byte, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("file exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// deleteObject removes a stored object that is no longer referenced. A
+// failure only leaves an orphaned object behind, so it is logged.
+func (s *Server) deleteObject(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.store.Delete(ctx, key); err != nil {
+		s.logger.Warn("Failed to delete stored object", map[string]any{"key": key, "error": err.Error()})
+	}
+}
+
+// extensionFor picks the file extension stored objects get, so local
+// storage serves them with a matching content type
+func extensionFor(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "application/pdf":
+		return ".pdf"
+	case "text/plain":
+		return ".txt"
+	case "text/csv":
+		return ".csv"
+	}
+	return ".bin"
+}
+
+// sanitizeFilename keeps the base name of an uploaded file without control
+// characters
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
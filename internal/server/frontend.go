@@ -0,0 +1,131 @@
+// internal/server/frontend.go - The single-page app served next to the API
+package server
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jamalkaksouri/DigiOrder/frontend"
+	"github.com/labstack/echo/v4"
+)
+
+// frontendReserved are the path prefixes the app never answers, so an
+// unknown API path still gets the API's JSON 404
+var frontendReserved = []string{"/api", "/health", "/livez", "/readyz", "/metrics"}
+
+// The build tool fingerprints everything under assets/, so those files can
+// be cached for good; index.html and the rest are revalidated every time
+// so a deploy shows up on the next load.
+const (
+	frontendAssetsDir    = "assets/"
+	frontendCacheAssets  = "public, max-age=31536000, immutable"
+	frontendCacheDefault = "no-cache"
+)
+
+// registerFrontend serves the app on every path the API leaves free when
+// the frontend is enabled. Requests for pages the app routes itself fall
+// back to index.html.
+func (s *Server) registerFrontend() {
+	cfg := s.config.Frontend
+	if !cfg.Enabled {
+		return
+	}
+
+	var files fs.FS
+	source := cfg.Dir
+	if cfg.Dir != "" {
+		files = os.DirFS(cfg.Dir)
+	} else {
+		source = "embedded"
+		sub, err := fs.Sub(frontend.FS, "dist")
+		if err != nil {
+			s.logger.Error("Failed to open the embedded frontend", err, nil)
+			return
+		}
+		files = sub
+	}
+	if _, err := fs.Stat(files, "index.html"); err != nil {
+		s.logger.Error("Frontend has no index.html; not serving it", err, map[string]any{"source": source})
+		return
+	}
+
+	// Every method: were only GET registered, Echo would answer unknown
+	// API paths with 405 instead of the API's 404
+	s.router.Any("/*", s.serveFrontend(files, cfg.CSP))
+	s.logger.Info("Serving the frontend", map[string]any{"source": source})
+}
+
+// serveFrontend returns the handler for the app's files
+func (s *Server) serveFrontend(files fs.FS, csp string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if frontendReservedPath(req.URL.Path) {
+			return echo.ErrNotFound
+		}
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return echo.ErrMethodNotAllowed
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+		if name == "" {
+			name = "index.html"
+		}
+		info, err := fs.Stat(files, name)
+		switch {
+		case err == nil && !info.IsDir():
+		case path.Ext(name) != "":
+			// A missing file, not a page: index.html would only confuse
+			// the browser
+			return echo.ErrNotFound
+		default:
+			name = "index.html"
+		}
+
+		header := c.Response().Header()
+		header.Set(echo.HeaderContentSecurityPolicy, csp)
+		if strings.HasPrefix(name, frontendAssetsDir) {
+			header.Set("Cache-Control", frontendCacheAssets)
+		} else {
+			header.Set("Cache-Control", frontendCacheDefault)
+		}
+		return serveFrontendFile(c, files, name)
+	}
+}
+
+func serveFrontendFile(c echo.Context, files fs.FS, name string) error {
+	f, err := files.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return echo.ErrNotFound
+		}
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		return errors.New("frontend file " + name + " is not seekable")
+	}
+	// The content type follows the extension; Range and If-Modified-Since
+	// are handled too
+	http.ServeContent(c.Response(), c.Request(), name, info.ModTime(), content)
+	return nil
+}
+
+func frontendReservedPath(p string) bool {
+	for _, prefix := range frontendReserved {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
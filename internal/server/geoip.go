@@ -0,0 +1,104 @@
+// internal/server/geoip.go - Login attempts placed with GeoIP
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/anomaly"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/geoip"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/labstack/echo/v4"
+)
+
+// locate returns where ip is, or nil when it is unknown or GeoIP is not
+// configured
+func (s *Server) locate(ip string) *geoip.Location {
+	loc := s.geo.Lookup(ip)
+	if !loc.Known() {
+		return nil
+	}
+	return &loc
+}
+
+// describeLocation renders a location for alerts, e.g. "Tehran, IR (AS58224
+// Iran Telecommunication Company PJS)"
+func describeLocation(loc geoip.Location) string {
+	var parts []string
+	if loc.City != "" {
+		parts = append(parts, loc.City)
+	}
+	if loc.Country != "" {
+		parts = append(parts, loc.Country)
+	}
+	place := strings.Join(parts, ", ")
+	if loc.ASN != 0 {
+		network := strings.TrimSpace(fmt.Sprintf("AS%d %s", loc.ASN, loc.ASOrg))
+		if place == "" {
+			return network
+		}
+		place += " (" + network + ")"
+	}
+	return place
+}
+
+// recordLoginAttempt logs a login attempt with where it came from. It runs
+// in the background so logins do not wait for it; a successful login from
+// a country the user has not logged in from before raises the
+// "login.new_country" alert rule.
+func (s *Server) recordLoginAttempt(c echo.Context, username string, success bool, failureReason string) {
+	ip, userAgent := c.RealIP(), c.Request().UserAgent()
+	acceptLanguage := c.Request().Header.Get("Accept-Language")
+	fingerprint := anomaly.Fingerprint(userAgent, acceptLanguage)
+	device := anomaly.DeviceInfo(userAgent, acceptLanguage)
+	loc := s.geo.Lookup(ip)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		country := sql.NullString{String: loc.Country, Valid: loc.Country != ""}
+		newCountry := false
+		if success && country.Valid && auditRuleMatches(s.config.Notify.Chat.AuditRules, "login", "new_country") {
+			counts, err := s.queries.GetLoginCountryCounts(ctx, db.GetLoginCountryCountsParams{
+				Country:  country,
+				Username: username,
+			})
+			// The first located login has nothing to compare with
+			newCountry = err == nil && counts.LocatedLogins > 0 && counts.CountryLogins == 0
+		}
+
+		_, err := s.queries.LogLoginAttempt(ctx, db.LogLoginAttemptParams{
+			Username:          username,
+			IpAddress:         ip,
+			UserAgent:         sql.NullString{String: userAgent, Valid: userAgent != ""},
+			DeviceInfo:        device,
+			Success:           success,
+			FailureReason:     sql.NullString{String: failureReason, Valid: failureReason != ""},
+			RateLimited:       sql.NullBool{Bool: false, Valid: true},
+			Country:           country,
+			City:              sql.NullString{String: loc.City, Valid: loc.City != ""},
+			Asn:               sql.NullInt64{Int64: int64(loc.ASN), Valid: loc.ASN != 0},
+			AsOrg:             sql.NullString{String: loc.ASOrg, Valid: loc.ASOrg != ""},
+			Latitude:          sql.NullFloat64{Float64: loc.Latitude, Valid: loc.Positioned()},
+			Longitude:         sql.NullFloat64{Float64: loc.Longitude, Valid: loc.Positioned()},
+			DeviceFingerprint: sql.NullString{String: fingerprint, Valid: fingerprint != ""},
+		})
+		if err != nil {
+			s.logger.Warn("Failed to log login attempt", map[string]any{"username": username, "error": err.Error()})
+		}
+
+		if newCountry {
+			s.postAlert("Login from a new country",
+				fmt.Sprintf("%s logged in from %s for the first time", username, loc.Country),
+				notify.Fact{Name: "User", Value: username},
+				notify.Fact{Name: "Location", Value: describeLocation(loc)},
+				notify.Fact{Name: "IP address", Value: ip},
+			)
+		}
+	}()
+}
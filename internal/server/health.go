@@ -0,0 +1,226 @@
+// internal/server/health.go - Liveness, readiness and legacy health checks
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// readinessTimeout bounds each readiness sub-check
+const readinessTimeout = 2 * time.Second
+
+// CheckResult is the outcome of one readiness sub-check
+type CheckResult struct {
+	Status    string  `json:"status"` // ok or fail
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Details   any     `json:"details,omitempty"`
+}
+
+// readinessCheck is a named sub-check returning optional details
+type readinessCheck struct {
+	name string
+	run  func(ctx context.Context) (any, error)
+}
+
+// livenessCheck handles GET /livez. It only reports that the process is
+// up and serving HTTP; dependencies are covered by /readyz.
+func (s *Server) livenessCheck(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{
+		"status":         "alive",
+		"uptime_seconds": int(time.Since(s.startedAt).Seconds()),
+	})
+}
+
+// readinessCheck handles GET /readyz. It returns 503 when any sub-check
+// fails or the server is shutting down, so traffic is routed elsewhere.
+func (s *Server) readinessCheck(c echo.Context) error {
+	ready, checks := s.runReadinessChecks(c.Request().Context())
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	if s.stopping.Load() {
+		status, code = "shutting_down", http.StatusServiceUnavailable
+	}
+
+	return c.JSON(code, map[string]any{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// runReadinessChecks executes every sub-check with its own timeout
+func (s *Server) runReadinessChecks(ctx context.Context) (bool, map[string]CheckResult) {
+	checks := []readinessCheck{
+		{"database", s.checkDatabase},
+		{"schema", s.checkSchema},
+		{"cache", s.checkCache},
+		{"workers", s.checkWorkers},
+	}
+
+	ready := true
+	results := make(map[string]CheckResult, len(checks))
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		start := time.Now()
+		details, err := check.run(checkCtx)
+		cancel()
+
+		result := CheckResult{
+			Status:    "ok",
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			Details:   details,
+		}
+		if err != nil {
+			ready = false
+			result.Status = "fail"
+			result.Error = err.Error()
+		}
+		results[check.name] = result
+	}
+
+	return ready, results
+}
+
+// checkDatabase pings PostgreSQL and reports pool usage
+func (s *Server) checkDatabase(ctx context.Context) (any, error) {
+	if s.db == nil {
+		return nil, errors.New("database not configured")
+	}
+	if err := s.db.Ping(ctx); err != nil {
+		return nil, err
+	}
+
+	stats := s.db.Stat()
+	return map[string]any{
+		"open_connections": stats.TotalConns(),
+		"in_use":           stats.AcquiredConns(),
+		"idle":             stats.IdleConns(),
+	}, nil
+}
+
+// checkSchema fails while migrations are pending or a migration is dirty
+func (s *Server) checkSchema(ctx context.Context) (any, error) {
+	if s.migrator == nil {
+		return nil, errors.New("migrations not loaded")
+	}
+
+	status, err := s.migrator.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Dirty {
+		return status, fmt.Errorf("migration %d is dirty", status.Version)
+	}
+	if status.Version != status.Latest {
+		return status, fmt.Errorf("schema at version %d, expected %d", status.Version, status.Latest)
+	}
+	return status, nil
+}
+
+// checkCache reports the response cache backend and how full it is. The
+// cache is in-process, so it is reachable whenever the server is.
+func (s *Server) checkCache(ctx context.Context) (any, error) {
+	return map[string]any{
+		"backend": "memory",
+		"enabled": s.config.Features.ResponseCache,
+		"usage":   s.responses.Stats(),
+	}, nil
+}
+
+// checkWorkers fails when a background cleanup loop has stopped ticking
+func (s *Server) checkWorkers(ctx context.Context) (any, error) {
+	workers := []*middleware.Heartbeat{
+		s.rateLimiter.Heartbeat(),
+		s.ipLimiter.Heartbeat(),
+		s.ipLimiter.IPRules().Heartbeat(),
+	}
+	if s.outbox != nil {
+		workers = append(workers, s.outbox.Heartbeat())
+	}
+	if hb := s.registry.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.reports.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.scheduler.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.usage.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.quotas.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.audit.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.anomalies.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+	if hb := s.rekey.Heartbeat(); hb != nil {
+		workers = append(workers, hb)
+	}
+
+	details := make(map[string]any, len(workers))
+	var stalled []string
+	for _, w := range workers {
+		details[w.Name()] = map[string]any{
+			"alive":     w.Alive(),
+			"last_beat": w.LastBeat().Format(time.RFC3339),
+		}
+		if !w.Alive() {
+			stalled = append(stalled, w.Name())
+		}
+	}
+
+	if len(stalled) > 0 {
+		return details, fmt.Errorf("stalled workers: %v", stalled)
+	}
+	return details, nil
+}
+
+// healthCheck handles GET /health, kept for existing monitors and scripts
+func (s *Server) healthCheck(c echo.Context) error {
+	// Check database connection
+	err := s.db.Ping(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]any{
+			"status":   "unhealthy",
+			"service":  "DigiOrder API",
+			"database": "disconnected",
+			"error":    err.Error(),
+		})
+	}
+
+	response := map[string]any{
+		"status":   "healthy",
+		"service":  "DigiOrder API",
+		"database": "connected",
+		"version":  "3.0.1",
+	}
+
+	// Report schema drift: a pending or dirty migration is unhealthy
+	if s.migrator != nil {
+		schema, err := s.migrator.Status(c.Request().Context())
+		if err != nil {
+			response["schema"] = map[string]any{"error": err.Error()}
+		} else {
+			response["schema"] = schema
+			if schema.Dirty || schema.Version != schema.Latest {
+				response["status"] = "degraded"
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,175 @@
+// internal/server/includes.go - Related resources embedded with ?include=
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// Related resources each endpoint can embed
+var (
+	orderIncludes   = []string{"items", "creator", "requester"}
+	productIncludes = []string{"category", "barcodes"}
+)
+
+// OrderCreator is the user who created an included order
+type OrderCreator struct {
+	ID       uuid.UUID
+	Username string
+	FullName string `json:",omitempty"`
+}
+
+// IncludedOrder is an order with the related resources ?include= asked
+// for; those not asked for are left out. The Jalali dates are only set
+// with calendar=jalali.
+type IncludedOrder struct {
+	JalaliOrder
+	Items     []db.OrderItem `json:",omitzero"`
+	Creator   *OrderCreator  `json:",omitempty"`
+	Requester *Requester     `json:",omitempty"`
+}
+
+// IncludedProduct is a product with the related resources ?include=
+// asked for; those not asked for are left out
+type IncludedProduct struct {
+	db.Product
+	Category *db.Category        `json:",omitempty"`
+	Barcodes []db.ProductBarcode `json:",omitzero"`
+}
+
+// parseInclude reads the include query parameter, a comma-separated list
+// of the allowed resources. The error response is written here when it
+// names anything else.
+func parseInclude(c echo.Context, allowed []string) (map[string]bool, bool) {
+	raw := strings.TrimSpace(c.QueryParam("include"))
+	if raw == "" {
+		return nil, true
+	}
+	include := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(allowed, name) {
+			RespondError(c, http.StatusBadRequest, "invalid_include",
+				fmt.Sprintf("Cannot include %q; include takes %s.", name, strings.Join(allowed, ", ")))
+			return nil, false
+		}
+		include[name] = true
+	}
+	return include, true
+}
+
+// includeOrders loads what include asks for of orders with one query per
+// resource, whatever the number of orders
+func (s *Server) includeOrders(ctx context.Context, orders []JalaliOrder, include map[string]bool) ([]IncludedOrder, error) {
+	out := make([]IncludedOrder, len(orders))
+	ids := make([]uuid.UUID, len(orders))
+	byID := make(map[uuid.UUID]*IncludedOrder, len(orders))
+	for i, o := range orders {
+		out[i].JalaliOrder = o
+		ids[i] = o.ID
+		byID[o.ID] = &out[i]
+	}
+	if len(orders) == 0 {
+		return out, nil
+	}
+
+	if include["items"] {
+		for i := range out {
+			out[i].Items = []db.OrderItem{}
+		}
+		items, err := s.queries.ListOrderItemsByOrders(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if o, ok := byID[item.OrderID.UUID]; ok {
+				o.Items = append(o.Items, item)
+			}
+		}
+	}
+	if include["creator"] {
+		creators, err := s.queries.ListOrderCreators(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range creators {
+			if o, ok := byID[row.OrderID]; ok {
+				o.Creator = &OrderCreator{ID: row.UserID, Username: row.Username, FullName: row.FullName.String}
+			}
+		}
+	}
+	if include["requester"] {
+		var requesterIDs []uuid.UUID
+		for _, o := range orders {
+			if o.RequesterID.Valid && !slices.Contains(requesterIDs, o.RequesterID.UUID) {
+				requesterIDs = append(requesterIDs, o.RequesterID.UUID)
+			}
+		}
+		if len(requesterIDs) > 0 {
+			requesters, err := s.queries.ListRequestersByIDs(ctx, requesterIDs)
+			if err != nil {
+				return nil, err
+			}
+			byRequester := make(map[uuid.UUID]Requester, len(requesters))
+			for _, r := range requesters {
+				byRequester[r.ID] = requesterResponse(r)
+			}
+			for i := range out {
+				if r, ok := byRequester[out[i].RequesterID.UUID]; ok && out[i].RequesterID.Valid {
+					out[i].Requester = &r
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// includeProducts loads what include asks for of products with one query
+// per resource, whatever the number of products
+func (s *Server) includeProducts(ctx context.Context, products []db.Product, include map[string]bool) ([]IncludedProduct, error) {
+	out := make([]IncludedProduct, len(products))
+	ids := make([]uuid.UUID, len(products))
+	byID := make(map[uuid.UUID]*IncludedProduct, len(products))
+	for i, p := range products {
+		out[i].Product = p
+		ids[i] = p.ID
+		byID[p.ID] = &out[i]
+	}
+	if len(products) == 0 {
+		return out, nil
+	}
+
+	if include["category"] {
+		categories, err := s.queries.ListProductCategories(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range categories {
+			if p, ok := byID[row.ProductID]; ok {
+				p.Category = &db.Category{ID: row.ID, Name: row.Name}
+			}
+		}
+	}
+	if include["barcodes"] {
+		for i := range out {
+			out[i].Barcodes = []db.ProductBarcode{}
+		}
+		barcodes, err := s.queries.ListBarcodesByProducts(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range barcodes {
+			if p, ok := byID[b.ProductID.UUID]; ok {
+				p.Barcodes = append(p.Barcodes, b)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,347 @@
+// internal/server/interactions.go - Drug interaction and duplicate-therapy warnings on order items
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/interactions"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// atcCode matches an ATC code from the first level (C) to the fifth
+// (C09AA05)
+var atcCode = regexp.MustCompile(`^[A-Z]([0-9]{2}([A-Z]([A-Z]([0-9]{2})?)?)?)?$`)
+
+// OrderItemWithWarnings is an added order item with the warnings adding it
+// raised. The warnings never stop an item from being added.
+type OrderItemWithWarnings struct {
+	db.OrderItem
+	Warnings []db.ListOrderWarningsRow `json:",omitempty"`
+}
+
+// newInteractionProvider creates the configured provider, or nil when the
+// warnings are turned off
+func newInteractionProvider(queries db.Querier, cfg config.DrugChecksConfig) interactions.Provider {
+	switch cfg.Provider {
+	case "database":
+		return interactions.Database{Queries: queries}
+	case "http":
+		return interactions.NewHTTP(cfg.URL, cfg.Token, cfg.Timeout)
+	default:
+		return nil
+	}
+}
+
+// checkInteractions checks the items just added to an order against each
+// other and the rest of the order, stores the warnings on the order and
+// returns them by added item. A failed check is logged and the items are
+// kept without warnings.
+func (s *Server) checkInteractions(ctx context.Context, orderID uuid.UUID, added []db.OrderItem) map[uuid.UUID][]db.ListOrderWarningsRow {
+	if s.drugData == nil || len(added) == 0 {
+		return nil
+	}
+	fields := map[string]any{"order_id": orderID.String()}
+
+	products, err := s.queries.ListOrderItemProducts(ctx, uuid.NullUUID{UUID: orderID, Valid: true})
+	if err != nil {
+		s.logger.Error("Failed to load order items for interaction checks", err, fields)
+		return nil
+	}
+	isAdded := make(map[uuid.UUID]bool, len(added))
+	for _, item := range added {
+		isAdded[item.ID] = true
+	}
+	var existing, checked []interactions.Item
+	for _, p := range products {
+		name := p.Name
+		if p.Strength.Valid {
+			name += " " + p.Strength.String
+		}
+		item := interactions.Item{ID: p.ID, Name: name, GenericCode: p.GenericCode.String}
+		if isAdded[p.ID] {
+			checked = append(checked, item)
+		} else {
+			existing = append(existing, item)
+		}
+	}
+
+	warnings, err := interactions.Check(ctx, s.drugData, existing, checked)
+	if err != nil {
+		s.logger.Warn("Interaction check failed; items added without warnings", map[string]any{
+			"order_id": orderID.String(),
+			"error":    err.Error(),
+		})
+		return nil
+	}
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	err = s.withTx(ctx, func(q db.Querier) error {
+		for _, w := range warnings {
+			if err := q.CreateOrderWarning(ctx, db.CreateOrderWarningParams{
+				OrderID:          orderID,
+				OrderItemID:      w.ItemID,
+				OtherOrderItemID: w.OtherItemID,
+				Kind:             w.Kind,
+				Severity:         w.Severity,
+				Message:          w.Message,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to store order warnings", err, fields)
+		return nil
+	}
+
+	stored, err := s.queries.ListOrderWarnings(ctx, orderID)
+	if err != nil {
+		s.logger.Error("Failed to load order warnings", err, fields)
+		return nil
+	}
+	byItem := make(map[uuid.UUID][]db.ListOrderWarningsRow)
+	for _, w := range stored {
+		if isAdded[w.OrderItemID] {
+			byItem[w.OrderItemID] = append(byItem[w.OrderItemID], w)
+		}
+	}
+	return byItem
+}
+
+// ListOrderWarnings handles GET /api/v1/orders/:id/warnings, the
+// interaction and duplicate-therapy warnings on the order's items, most
+// severe first
+func (s *Server) ListOrderWarnings(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.queries.GetOrder(ctx, id); err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+	warnings, err := s.queries.ListOrderWarnings(ctx, id)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order warnings.")
+	}
+	if warnings == nil {
+		warnings = []db.ListOrderWarningsRow{}
+	}
+	return RespondSuccess(c, http.StatusOK, warnings)
+}
+
+// ImportDrugInteractions handles PUT /api/v1/admin/drug-interactions. The
+// "file" form field holds a CSV with the header generic_code_a,
+// generic_code_b, severity, description, which replaces the interaction
+// dataset of the database provider.
+func (s *Server) ImportDrugInteractions(c echo.Context) error {
+	rows, ok := s.readDatasetCSV(c, "generic_code_a", "generic_code_b", "severity", "description")
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[[2]string]int, len(rows))
+	dataset := make([]interactions.Interaction, len(rows))
+	for i, row := range rows {
+		in := interactions.Interaction{
+			GenericCodeA: row[0],
+			GenericCodeB: row[1],
+			Severity:     strings.ToLower(row[2]),
+			Description:  row[3],
+		}.Pair()
+		line := i + 2
+		switch {
+		case in.GenericCodeA == "" || in.Description == "":
+			return invalidDataset(c, line, "generic_code_a, generic_code_b and description are required.")
+		case in.GenericCodeA == in.GenericCodeB:
+			return invalidDataset(c, line, "a generic cannot interact with itself.")
+		case !slices.Contains(interactions.Severities, in.Severity):
+			return invalidDataset(c, line, "severity must be one of "+strings.Join(interactions.Severities, ", ")+".")
+		}
+		key := [2]string{in.GenericCodeA, in.GenericCodeB}
+		if first, ok := seen[key]; ok {
+			return invalidDataset(c, line, fmt.Sprintf("the pair is already on row %d.", first))
+		}
+		seen[key] = line
+		dataset[i] = in
+	}
+
+	ctx := c.Request().Context()
+	err := s.withTx(ctx, func(q db.Querier) error {
+		if err := q.DeleteDrugInteractions(ctx); err != nil {
+			return err
+		}
+		for first := 0; first < len(dataset); first += db.BulkBatchSize {
+			batch := dataset[first:min(first+db.BulkBatchSize, len(dataset))]
+			arg := db.CreateDrugInteractionsParams{
+				GenericCodesA: make([]string, len(batch)),
+				GenericCodesB: make([]string, len(batch)),
+				Severities:    make([]string, len(batch)),
+				Descriptions:  make([]string, len(batch)),
+			}
+			for i, in := range batch {
+				arg.GenericCodesA[i] = in.GenericCodeA
+				arg.GenericCodesB[i] = in.GenericCodeB
+				arg.Severities[i] = in.Severity
+				arg.Descriptions[i] = in.Description
+			}
+			if err := q.CreateDrugInteractions(ctx, arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to replace the interaction dataset.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "import", "drug_interactions", "",
+		nil, map[string]any{"interactions": len(dataset)}, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, map[string]int{"interactions": len(dataset)})
+}
+
+// ImportDrugClasses handles PUT /api/v1/admin/drug-classes. The "file"
+// form field holds a CSV with the header generic_code, atc_code, which
+// replaces the ATC codes the database provider checks for duplicate
+// therapy.
+func (s *Server) ImportDrugClasses(c echo.Context) error {
+	rows, ok := s.readDatasetCSV(c, "generic_code", "atc_code")
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]int, len(rows))
+	arg := db.CreateDrugClassesParams{
+		GenericCodes: make([]string, len(rows)),
+		AtcCodes:     make([]string, len(rows)),
+	}
+	for i, row := range rows {
+		code, atc := row[0], strings.ToUpper(row[1])
+		line := i + 2
+		if code == "" || !atcCode.MatchString(atc) {
+			return invalidDataset(c, line, "generic_code is required and atc_code must be an ATC code such as C09AA05.")
+		}
+		if first, ok := seen[code]; ok {
+			return invalidDataset(c, line, fmt.Sprintf("the generic is already on row %d.", first))
+		}
+		seen[code] = line
+		arg.GenericCodes[i] = code
+		arg.AtcCodes[i] = atc
+	}
+
+	ctx := c.Request().Context()
+	err := s.withTx(ctx, func(q db.Querier) error {
+		if err := q.DeleteDrugClasses(ctx); err != nil {
+			return err
+		}
+		for first := 0; first < len(rows); first += db.BulkBatchSize {
+			last := min(first+db.BulkBatchSize, len(rows))
+			if err := q.CreateDrugClasses(ctx, db.CreateDrugClassesParams{
+				GenericCodes: arg.GenericCodes[first:last],
+				AtcCodes:     arg.AtcCodes[first:last],
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to replace the drug classes.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "import", "drug_classes", "",
+		nil, map[string]any{"classes": len(rows)}, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, map[string]int{"classes": len(rows)})
+}
+
+// readDatasetCSV reads the uploaded "file" CSV, whose header must name
+// columns, and returns its rows with the fields trimmed, in the order of
+// columns. The error response is written here when it cannot.
+func (s *Server) readDatasetCSV(c echo.Context, columns ...string) ([][]string, bool) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "missing_file",
+			"Upload the CSV as multipart form field \"file\".")
+		return nil, false
+	}
+	limit := int64(s.config.Storage.MaxUploadMB) << 20
+	if header.Size > limit {
+		RespondError(c, http.StatusRequestEntityTooLarge, "file_too_large",
+			fmt.Sprintf("Files may be at most %d MB.", s.config.Storage.MaxUploadMB))
+		return nil, false
+	}
+	data, err := readFormFile(header, limit)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_file", "The uploaded file could not be read.")
+		return nil, false
+	}
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	first, err := r.Read()
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_file", "The file is empty or not a CSV.")
+		return nil, false
+	}
+	index := make([]int, len(columns))
+	for i, column := range columns {
+		index[i] = slices.IndexFunc(first, func(name string) bool {
+			return strings.EqualFold(strings.TrimSpace(name), column)
+		})
+		if index[i] < 0 {
+			RespondError(c, http.StatusBadRequest, "invalid_columns",
+				"The header row must name the columns "+strings.Join(columns, ", ")+".")
+			return nil, false
+		}
+	}
+
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid_file", err.Error())
+			return nil, false
+		}
+		row := make([]string, len(columns))
+		for i, at := range index {
+			if at < len(record) {
+				row[i] = strings.TrimSpace(record[at])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, true
+}
+
+// invalidDataset rejects a dataset for one of its rows, numbered from the
+// header as 1
+func invalidDataset(c echo.Context, row int, details string) error {
+	return RespondUnprocessable(c, "invalid_dataset", fmt.Sprintf("Row %d: %s", row, details))
+}
@@ -0,0 +1,216 @@
+// internal/server/ip_rules.go - Admin management of IP allow and deny rules
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// IPRuleReq defines the request body for creating or replacing an IP rule.
+// CIDR is an address or a network; without ExpiresAt the rule holds until
+// it is deleted.
+type IPRuleReq struct {
+	CIDR      string     `json:"cidr" validate:"required"`
+	Action    string     `json:"action" validate:"required,oneof=allow deny"`
+	Reason    string     `json:"reason" validate:"max=500"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// IPRule is an IP allow or deny rule. Automatic rules are the bans the
+// rate limiter imposed.
+type IPRule struct {
+	ID        uuid.UUID  `json:"id"`
+	CIDR      string     `json:"cidr"`
+	Action    string     `json:"action"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Expired   bool       `json:"expired"`
+	Automatic bool       `json:"automatic"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func ipRuleResponse(r db.IpRule) IPRule {
+	resp := IPRule{
+		ID:        r.ID,
+		CIDR:      r.Cidr.IPNet.String(),
+		Action:    r.Action,
+		Reason:    r.Reason.String,
+		Automatic: !r.CreatedBy.Valid,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+	if r.ExpiresAt.Valid {
+		resp.ExpiresAt = &r.ExpiresAt.Time
+		resp.Expired = !time.Now().Before(r.ExpiresAt.Time)
+	}
+	if r.CreatedBy.Valid {
+		resp.CreatedBy = &r.CreatedBy.UUID
+	}
+	return resp
+}
+
+// bindIPRule reads and checks an IP rule request, writing the error
+// response when it is invalid
+func (s *Server) bindIPRule(c echo.Context) (IPRuleReq, pqtype.CIDR, bool, error) {
+	var req IPRuleReq
+	if err := c.Bind(&req); err != nil {
+		return req, pqtype.CIDR{}, false, respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return req, pqtype.CIDR{}, false, respondValidationError(c, err)
+	}
+	network, err := middleware.ParseIPNetwork(req.CIDR)
+	if err != nil {
+		return req, pqtype.CIDR{}, false, respondFieldError(c, "invalid_cidr", "cidr", err.Error())
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return req, pqtype.CIDR{}, false, respondFieldError(c, "validation_error", "expires_at",
+			"Field 'expires_at' must be in the future")
+	}
+	return req, pqtype.CIDR{IPNet: *network, Valid: true}, true, nil
+}
+
+// reloadIPRules applies a change to the rules on this node at once; other
+// nodes pick it up within half a minute
+func (s *Server) reloadIPRules(ctx context.Context) {
+	if err := s.ipLimiter.IPRules().Reload(ctx); err != nil {
+		s.logger.Warn("Failed to reload IP rules", map[string]any{"error": err.Error()})
+	}
+}
+
+// ListIPRules handles GET /api/v1/security/ip-rules. Expired rules are
+// listed for a week before they are deleted.
+func (s *Server) ListIPRules(c echo.Context) error {
+	rows, err := s.queries.ListIPRules(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve IP rules.")
+	}
+
+	rules := make([]IPRule, len(rows))
+	for i, r := range rows {
+		rules[i] = ipRuleResponse(r)
+	}
+	return RespondSuccess(c, http.StatusOK, rules)
+}
+
+// GetIPRule handles GET /api/v1/security/ip-rules/:id
+func (s *Server) GetIPRule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	rule, err := s.queries.GetIPRule(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "IP rule")
+	}
+	return RespondSuccess(c, http.StatusOK, ipRuleResponse(rule))
+}
+
+// CreateIPRule handles POST /api/v1/security/ip-rules
+func (s *Server) CreateIPRule(c echo.Context) error {
+	req, cidr, ok, err := s.bindIPRule(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	rule, err := s.queries.CreateIPRule(ctx, db.CreateIPRuleParams{
+		Cidr:      cidr,
+		Action:    req.Action,
+		Reason:    sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		ExpiresAt: expiresAt,
+		CreatedBy: uuid.NullUUID{UUID: currentUserID, Valid: currentUserID != uuid.Nil},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "IP rule")
+	}
+	s.reloadIPRules(ctx)
+
+	s.logAudit(ctx, currentUserID, "create", "ip_rule", rule.ID.String(),
+		nil, map[string]any{"cidr": cidr.IPNet.String(), "action": rule.Action, "reason": req.Reason},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, ipRuleResponse(rule))
+}
+
+// UpdateIPRule handles PUT /api/v1/security/ip-rules/:id, replacing the
+// rule's network, action, reason and expiry
+func (s *Server) UpdateIPRule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	req, cidr, ok, err := s.bindIPRule(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetIPRule(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "IP rule")
+	}
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	rule, err := s.queries.UpdateIPRule(ctx, db.UpdateIPRuleParams{
+		ID:        id,
+		Cidr:      cidr,
+		Action:    req.Action,
+		Reason:    sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "IP rule")
+	}
+	s.reloadIPRules(ctx)
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "update", "ip_rule", id.String(),
+		map[string]any{"cidr": old.Cidr.IPNet.String(), "action": old.Action, "reason": old.Reason.String},
+		map[string]any{"cidr": rule.Cidr.IPNet.String(), "action": rule.Action, "reason": rule.Reason.String},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, ipRuleResponse(rule))
+}
+
+// DeleteIPRule handles DELETE /api/v1/security/ip-rules/:id
+func (s *Server) DeleteIPRule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	deleted, err := s.queries.DeleteIPRule(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "IP rule")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "IP rule not found.")
+	}
+	s.reloadIPRules(ctx)
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "delete", "ip_rule", id.String(),
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
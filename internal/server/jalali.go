@@ -0,0 +1,89 @@
+// internal/server/jalali.go - Jalali calendar dates in filters and responses
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/labstack/echo/v4"
+)
+
+// JalaliOrder is an order with its dates in the Jalali calendar as well;
+// the added fields are named like the order's own
+type JalaliOrder struct {
+	db.Order
+	CreatedAtJalali   string `json:",omitempty"`
+	SubmittedAtJalali string `json:",omitempty"`
+	NeededByJalali    string `json:",omitempty"`
+}
+
+// requestCalendar reads the calendar query parameter, gregorian when unset
+func requestCalendar(c echo.Context) (string, bool) {
+	calendar := c.QueryParam("calendar")
+	if calendar == "" {
+		return jalali.Gregorian, true
+	}
+	return calendar, jalali.IsCalendar(calendar)
+}
+
+// invalidCalendar rejects an unknown calendar query parameter
+func invalidCalendar(c echo.Context) error {
+	return RespondError(c, http.StatusBadRequest, "invalid_calendar",
+		"calendar must be gregorian or jalali.")
+}
+
+// parseDateParam accepts an RFC 3339 time, or a date of calendar meaning
+// its start in loc, or with end set the start of the next day. Jalali
+// dates may be written YYYY-MM-DD or YYYY/MM/DD.
+func parseDateParam(raw, calendar string, loc *time.Location, end bool) (time.Time, bool) {
+	var t time.Time
+	var err error
+	if calendar == jalali.Jalali {
+		t, err = jalali.Parse(raw, loc)
+	} else {
+		t, err = time.ParseInLocation(time.DateOnly, raw, loc)
+	}
+	if err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, true
+	}
+	t, err = time.Parse(time.RFC3339, raw)
+	return t, err == nil
+}
+
+// jalaliTime formats a timestamp column as a Jalali date and time in the
+// reporting time zone, or "" when unset
+func (s *Server) jalaliTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return jalali.FormatDateTime(t.Time.In(s.reports.Calendar().Location))
+}
+
+// jalaliDate formats a DATE column, which has no time zone, as a Jalali
+// date, or "" when unset
+func jalaliDate(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return jalali.FormatDate(t.Time)
+}
+
+// jalaliOrders adds the Jalali dates to orders
+func (s *Server) jalaliOrders(orders []db.Order) []JalaliOrder {
+	out := make([]JalaliOrder, len(orders))
+	for i, o := range orders {
+		out[i] = JalaliOrder{
+			Order:             o,
+			CreatedAtJalali:   s.jalaliTime(o.CreatedAt),
+			SubmittedAtJalali: s.jalaliTime(o.SubmittedAt),
+			NeededByJalali:    jalaliDate(o.NeededBy),
+		}
+	}
+	return out
+}
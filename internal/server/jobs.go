@@ -0,0 +1,190 @@
+// internal/server/jobs.go - Periodic maintenance jobs
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/jamalkaksouri/DigiOrder/internal/scheduler"
+	"github.com/labstack/echo/v4"
+)
+
+// stockCheckWindow is how far back the stock check sums requested
+// quantities
+const stockCheckWindow = 30 * 24 * time.Hour
+
+// ScheduledJob describes a periodic job and its last run, which may have
+// happened on another instance
+type ScheduledJob struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	Running        bool       `json:"running"`
+	LastStatus     string     `json:"last_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastDurationMs *int64     `json:"last_duration_ms,omitempty"`
+	RunCount       int64      `json:"run_count"`
+	FailureCount   int64      `json:"failure_count"`
+}
+
+// newScheduler creates the scheduler with every periodic job. Audit
+// retention only runs with a retention set. An invalid time zone or
+// expression has already been rejected by config validation.
+func (s *Server) newScheduler(cfg config.SchedulerConfig) *scheduler.Scheduler {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		s.logger.Error("Invalid scheduler time zone, using UTC", err, nil)
+		loc = time.UTC
+	}
+	sched := scheduler.NewScheduler(s.queries, scheduler.Config{
+		Enabled:  cfg.Enabled,
+		Location: loc,
+		Timeout:  cfg.Timeout,
+	}, s.logger)
+
+	auditSpec := cfg.Jobs.AuditRetention
+	if cfg.AuditRetention == 0 {
+		auditSpec = ""
+	}
+	jobs := []struct {
+		name, spec string
+		run        scheduler.Func
+	}{
+		{"rate_limit_archive", cfg.Jobs.RateLimitArchive, s.queries.ArchiveOldRateLimits},
+		{"login_attempt_cleanup", cfg.Jobs.LoginAttemptCleanup, s.queries.CleanupOldLoginAttempts},
+		{"refresh_token_cleanup", cfg.Jobs.RefreshTokenCleanup, s.pruneRefreshTokens},
+		{"password_reset_cleanup", cfg.Jobs.PasswordResetCleanup, s.prunePasswordResets},
+		{"audit_retention", auditSpec, s.pruneAuditLogs},
+		{"stock_check", cfg.Jobs.StockCheck, s.checkStock},
+		{"recurring_orders", cfg.Jobs.RecurringOrders, s.recurring.RunDue},
+	}
+	for _, job := range jobs {
+		if err := sched.Add(job.name, job.spec, job.run); err != nil {
+			s.logger.Error("Failed to add scheduled job", err, map[string]any{"job": job.name})
+		}
+	}
+	return sched
+}
+
+// pruneAuditLogs deletes audit entries older than the retention
+func (s *Server) pruneAuditLogs(ctx context.Context) error {
+	retention := s.config.Scheduler.AuditRetention
+	if retention <= 0 {
+		return nil
+	}
+	deleted, err := s.queries.DeleteAuditLogsBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Info("Pruned audit log", map[string]any{"deleted": deleted, "retention": retention.String()})
+	}
+	return nil
+}
+
+// checkStock records a stock.low event for every tracked product at or
+// below its reorder level, so subscribers hear about it once a run
+func (s *Server) checkStock(ctx context.Context) error {
+	now := time.Now()
+	return s.withTx(ctx, func(q db.Querier) error {
+		rows, err := q.ReportLowStock(ctx, db.ReportLowStockParams{
+			FromTime: now.Add(-stockCheckWindow),
+			ToTime:   now,
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := outbox.Record(ctx, q, outbox.StockLow, row.ProductID.String(), "", outbox.StockData{
+				ProductID:    row.ProductID.String(),
+				Name:         row.Name,
+				OnHand:       row.OnHand,
+				ReorderLevel: row.ReorderLevel,
+				RequestedQty: row.RequestedQty,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListScheduledJobs handles GET /api/v1/admin/jobs
+func (s *Server) ListScheduledJobs(c echo.Context) error {
+	records, err := s.queries.ListScheduledJobs(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch scheduled jobs.")
+	}
+	lastRuns := make(map[string]db.ScheduledJob, len(records))
+	for _, record := range records {
+		lastRuns[record.Name] = record
+	}
+
+	jobs := s.scheduler.Jobs()
+	resp := make([]ScheduledJob, len(jobs))
+	for i, job := range jobs {
+		resp[i] = scheduledJobResponse(job, lastRuns[job.Name])
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// RunScheduledJob handles POST /api/v1/admin/jobs/:name/run, starting a
+// run now. The job runs in the background; poll the job list for it.
+func (s *Server) RunScheduledJob(c echo.Context) error {
+	name := c.Param("name")
+	err := s.scheduler.RunNow(c.Request().Context(), name)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		return RespondError(c, http.StatusNotFound, "not_found",
+			"Scheduled job not found.")
+	case errors.Is(err, scheduler.ErrRunning):
+		return RespondError(c, http.StatusConflict, "job_running",
+			"The job is already running. Try again when it has finished.")
+	case err != nil:
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to start the job.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(c.Request().Context(), userID, "run", "scheduled_job", name,
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusAccepted, map[string]string{
+		"message": "Job started",
+		"name":    name,
+	})
+}
+
+func scheduledJobResponse(job scheduler.Job, last db.ScheduledJob) ScheduledJob {
+	resp := ScheduledJob{
+		Name:         job.Name,
+		Schedule:     job.Schedule,
+		Running:      job.Running || last.LastStatus.String == scheduler.StatusRunning,
+		LastStatus:   last.LastStatus.String,
+		LastError:    last.LastError.String,
+		RunCount:     last.RunCount,
+		FailureCount: last.FailureCount,
+	}
+	if !job.NextRun.IsZero() {
+		resp.NextRunAt = &job.NextRun
+	}
+	if last.LastStartedAt.Valid {
+		resp.LastStartedAt = &last.LastStartedAt.Time
+	}
+	if last.LastFinishedAt.Valid {
+		resp.LastFinishedAt = &last.LastFinishedAt.Time
+	}
+	if last.LastDurationMs.Valid {
+		resp.LastDurationMs = &last.LastDurationMs.Int64
+	}
+	return resp
+}
@@ -0,0 +1,19 @@
+// internal/server/jwks.go - Public keys other services verify access tokens with
+package server
+
+import (
+	"net/http"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// GetJWKS handles GET /.well-known/jwks.json, the JSON Web Key Set of the
+// keys access tokens are signed with: the current key first, then the
+// previous keys still accepted. The set is empty while tokens are signed
+// with the HS256 secret, which cannot be published.
+func (s *Server) GetJWKS(c echo.Context) error {
+	// Verifiers refetch on an unknown kid, so a short cache is enough
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, middleware.JWKS())
+}
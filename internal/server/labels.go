@@ -0,0 +1,215 @@
+// internal/server/labels.go - Printing shelf and order item labels
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/labels"
+	"github.com/labstack/echo/v4"
+)
+
+// LabelPrinter is a configured label printer
+type LabelPrinter struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Width    int    `json:"width,omitempty"`
+	Default  bool   `json:"default"`
+}
+
+// PrintLabelsReq defines the optional request body of the print endpoints
+type PrintLabelsReq struct {
+	Printer string `json:"printer"`
+	Copies  int    `json:"copies" validate:"omitempty,min=1,max=100"`
+}
+
+// PrintJob is a label job accepted by a printer
+type PrintJob struct {
+	Printer  string `json:"printer"`
+	Language string `json:"language"`
+	Labels   int    `json:"labels"`
+	Copies   int    `json:"copies"`
+}
+
+func newLabelPrinters(cfg config.LabelsConfig) *labels.Printers {
+	printers := make([]labels.Printer, len(cfg.Printers))
+	for i, p := range cfg.Printers {
+		printers[i] = labels.Printer{Name: p.Name, Address: p.Address, Language: p.Language, Width: p.Width}
+	}
+	return labels.NewPrinters(printers, cfg.DefaultPrinter, cfg.Timeout)
+}
+
+// ListLabelPrinters handles GET /api/v1/printers
+func (s *Server) ListLabelPrinters(c echo.Context) error {
+	printers := []LabelPrinter{}
+	for _, p := range s.printers.List() {
+		printers = append(printers, LabelPrinter{
+			Name:     p.Name,
+			Language: p.Language,
+			Width:    p.Width,
+			Default:  p.Name == s.printers.Default(),
+		})
+	}
+	return RespondSuccess(c, http.StatusOK, printers)
+}
+
+// PrintProductLabel handles POST /api/v1/products/:id/labels, printing
+// the product's shelf label
+func (s *Server) PrintProductLabel(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	product, err := s.queries.GetProduct(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product")
+	}
+	if product.DeletedAt.Valid {
+		return ErrNotFound.WithDetails("Product has been deleted").Send(c)
+	}
+
+	label, err := s.productLabel(ctx, product)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product barcodes")
+	}
+	if product.Brand.Valid {
+		label.Lines = append(label.Lines, product.Brand.String)
+	}
+	if product.Irc.Valid {
+		label.Lines = append(label.Lines, "IRC "+product.Irc.String)
+	}
+	return s.printLabels(c, []labels.Label{label})
+}
+
+// PrintOrderItemLabel handles POST /api/v1/order_items/:id/labels,
+// printing or reprinting the label of one order item
+func (s *Server) PrintOrderItemLabel(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	item, err := s.queries.GetOrderItem(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order item")
+	}
+	label, err := s.orderItemLabel(ctx, item)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product")
+	}
+	return s.printLabels(c, []labels.Label{label})
+}
+
+// PrintOrderLabels handles POST /api/v1/orders/:id/labels, printing a
+// label for every item of the order
+func (s *Server) PrintOrderLabels(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireOrder(c, id); !ok {
+		return err
+	}
+	items, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: id, Valid: true})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order items")
+	}
+	if len(items) == 0 {
+		return RespondUnprocessable(c, "empty_order", "The order has no items to label.")
+	}
+
+	batch := make([]labels.Label, 0, len(items))
+	for _, item := range items {
+		label, err := s.orderItemLabel(ctx, item)
+		if err != nil {
+			return HandleDatabaseError(c, err, "Product")
+		}
+		batch = append(batch, label)
+	}
+	return s.printLabels(c, batch)
+}
+
+// printLabels sends batch to the printer named in the request body
+func (s *Server) printLabels(c echo.Context, batch []labels.Label) error {
+	var req PrintLabelsReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+	if req.Copies == 0 {
+		req.Copies = 1
+	}
+
+	printer, err := s.printers.Lookup(req.Printer)
+	switch {
+	case errors.Is(err, labels.ErrNoPrinters):
+		return RespondError(c, http.StatusServiceUnavailable, "printing_not_configured",
+			"No label printer is configured. Set labels.printers or LABEL_PRINTERS.")
+	case err != nil:
+		return RespondError(c, http.StatusBadRequest, "unknown_printer",
+			fmt.Sprintf("Printer %q is not configured.", req.Printer))
+	}
+
+	if err := s.printers.Print(c.Request().Context(), printer, batch, req.Copies); err != nil {
+		s.logger.Error("Label printing failed", err, map[string]any{"printer": printer.Name})
+		return RespondError(c, http.StatusBadGateway, "printer_error", err.Error())
+	}
+
+	return RespondSuccess(c, http.StatusOK, PrintJob{
+		Printer:  printer.Name,
+		Language: printer.Language,
+		Labels:   len(batch),
+		Copies:   req.Copies,
+	})
+}
+
+// productLabel is the name, strength and first registered barcode of a
+// product
+func (s *Server) productLabel(ctx context.Context, product db.Product) (labels.Label, error) {
+	label := labels.Label{Name: product.Name, Strength: product.Strength.String}
+	barcodes, err := s.queries.GetBarcodesByProduct(ctx, uuid.NullUUID{UUID: product.ID, Valid: true})
+	if err != nil {
+		return label, err
+	}
+	if len(barcodes) > 0 {
+		label.Barcode = barcodes[len(barcodes)-1].Barcode // newest first
+	}
+	return label, nil
+}
+
+// orderItemLabel is the product label with the order, quantity and note
+func (s *Server) orderItemLabel(ctx context.Context, item db.OrderItem) (labels.Label, error) {
+	product, err := s.queries.GetProduct(ctx, item.ProductID.UUID)
+	if err != nil {
+		return labels.Label{}, err
+	}
+	label, err := s.productLabel(ctx, product)
+	if err != nil {
+		return label, err
+	}
+
+	qty := fmt.Sprintf("Qty %d", item.RequestedQty)
+	if unit := item.Unit.String; unit != "" {
+		qty += " " + unit
+	} else if product.Unit.Valid {
+		qty += " " + product.Unit.String
+	}
+	label.Lines = append(label.Lines, qty, "Order "+item.OrderID.UUID.String()[:8])
+	if item.Note.Valid {
+		label.Lines = append(label.Lines, item.Note.String)
+	}
+	return label, nil
+}
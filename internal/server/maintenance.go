@@ -0,0 +1,57 @@
+// internal/server/maintenance.go - Maintenance mode administration
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// UpdateMaintenanceReq defines the request for toggling maintenance mode
+type UpdateMaintenanceReq struct {
+	Enabled    *bool  `json:"enabled" validate:"required"`
+	Message    string `json:"message,omitempty" validate:"omitempty,max=500"`
+	RetryAfter string `json:"retry_after,omitempty"` // Go duration, e.g. "15m"
+}
+
+// GetMaintenanceStatus handles GET /api/v1/system/maintenance
+func (s *Server) GetMaintenanceStatus(c echo.Context) error {
+	return RespondSuccess(c, http.StatusOK, s.maintenance.Status())
+}
+
+// UpdateMaintenanceMode handles PUT /api/v1/system/maintenance
+func (s *Server) UpdateMaintenanceMode(c echo.Context) error {
+	var req UpdateMaintenanceReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	var retryAfter time.Duration
+	if req.RetryAfter != "" {
+		parsed, err := time.ParseDuration(req.RetryAfter)
+		if err != nil || parsed <= 0 {
+			return RespondError(c, http.StatusBadRequest, "invalid_retry_after",
+				"Field 'retry_after' must be a positive duration such as '15m'.")
+		}
+		retryAfter = parsed
+	}
+
+	oldStatus := s.maintenance.Status()
+	if *req.Enabled {
+		s.maintenance.Enable(req.Message, retryAfter)
+	} else {
+		s.maintenance.Disable()
+	}
+	newStatus := s.maintenance.Status()
+
+	// Log audit
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(c.Request().Context(), currentUserID, "update", "maintenance_mode", "system",
+		map[string]any{"enabled": oldStatus.Enabled, "message": oldStatus.Message},
+		map[string]any{"enabled": newStatus.Enabled, "message": newStatus.Message},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, newStatus)
+}
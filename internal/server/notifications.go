@@ -0,0 +1,448 @@
+// internal/server/notifications.go - Notification delivery and preferences
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/labstack/echo/v4"
+)
+
+// NotificationPreferenceItem is one event/channel toggle
+type NotificationPreferenceItem struct {
+	EventType string `json:"event_type" validate:"required"`
+	Channel   string `json:"channel" validate:"required"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// NotificationPreferencesResponse is the current user's notification setup
+type NotificationPreferencesResponse struct {
+	Email       string                       `json:"email"`
+	Phone       string                       `json:"phone"`
+	Preferences []NotificationPreferenceItem `json:"preferences"`
+}
+
+// UpdateNotificationPreferencesReq updates contact details and toggles.
+// Omitted events keep their current setting.
+type UpdateNotificationPreferencesReq struct {
+	Email       *string                      `json:"email,omitempty" validate:"omitempty,max=254"`
+	Phone       *string                      `json:"phone,omitempty" validate:"omitempty,max=20"`
+	Preferences []NotificationPreferenceItem `json:"preferences" validate:"dive"`
+}
+
+// newNotifier starts the delivery workers and registers configured channels
+func newNotifier(cfg config.NotifyConfig, queries db.Querier, logger *logging.Logger) *notify.Dispatcher {
+	dispatcherConfig := notify.DefaultDispatcherConfig()
+	dispatcherConfig.Workers = cfg.Workers
+	dispatcherConfig.QueueSize = cfg.QueueSize
+	dispatcherConfig.MaxAttempts = cfg.MaxAttempts
+
+	dispatcher := notify.NewDispatcher(dispatcherConfig, logger)
+	if cfg.SMTP.Host != "" {
+		dispatcher.Register(notify.ChannelEmail, notify.NewSMTPSender(notify.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}))
+	}
+	if cfg.SMS.Provider != "" {
+		sender, err := notify.NewSMSSender(notify.SMSConfig{
+			Provider:   cfg.SMS.Provider,
+			APIKey:     cfg.SMS.APIKey,
+			AccountSID: cfg.SMS.AccountSID,
+			AuthToken:  cfg.SMS.AuthToken,
+			From:       cfg.SMS.From,
+		})
+		if err != nil {
+			logger.Error("SMS notifications disabled", err, map[string]any{"provider": cfg.SMS.Provider})
+		} else {
+			dispatcher.Register(notify.ChannelSMS, sender)
+		}
+	}
+	if cfg.Push.CredentialsFile != "" {
+		sender, err := notify.NewFCMSender(notify.FCMConfig{
+			CredentialsFile: cfg.Push.CredentialsFile,
+			ProjectID:       cfg.Push.ProjectID,
+			OnUnregistered: func(token string) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := queries.DeleteDeviceTokenByValue(ctx, token); err != nil {
+					logger.Error("Failed to remove unregistered device token", err, nil)
+				}
+			},
+		})
+		if err != nil {
+			logger.Error("Push notifications disabled", err, nil)
+		} else {
+			dispatcher.Register(notify.ChannelPush, sender)
+		}
+	}
+	registerChatSenders(dispatcher, cfg.Chat)
+	return dispatcher
+}
+
+// registerChatSenders sets the Slack and Teams senders for the configured
+// webhook URLs, removing a channel whose URL is empty
+func registerChatSenders(dispatcher *notify.Dispatcher, cfg config.ChatConfig) {
+	var slack, teams notify.Sender
+	if cfg.SlackWebhookURL != "" {
+		slack = notify.NewSlackSender(cfg.SlackWebhookURL)
+	}
+	if cfg.TeamsWebhookURL != "" {
+		teams = notify.NewTeamsSender(cfg.TeamsWebhookURL)
+	}
+	dispatcher.Register(notify.ChannelSlack, slack)
+	dispatcher.Register(notify.ChannelTeams, teams)
+}
+
+// sendEmail renders an event for one recipient and queues it
+func (s *Server) sendEmail(event notify.EventType, to, name string, data map[string]any) {
+	payload := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		payload[k] = v
+	}
+	payload["Name"] = name
+
+	subject, text, html, err := notify.Render(event, payload)
+	if err != nil {
+		s.logger.Error("Failed to render notification", err, map[string]any{"event": event})
+		return
+	}
+
+	s.notifier.Enqueue(notify.Message{
+		Channel: notify.ChannelEmail,
+		Event:   event,
+		To:      to,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+}
+
+// sendSMS renders the short form of an event and queues it for one number
+func (s *Server) sendSMS(event notify.EventType, to string, data map[string]any) {
+	text, err := notify.RenderSMS(event, data)
+	if err != nil {
+		s.logger.Error("Failed to render SMS notification", err, map[string]any{"event": event})
+		return
+	}
+
+	s.notifier.Enqueue(notify.Message{
+		Channel: notify.ChannelSMS,
+		Event:   event,
+		To:      to,
+		Text:    text,
+	})
+}
+
+// pageRoles texts every user with one of roleNames who has a phone number
+// and has not opted out of event
+func (s *Server) pageRoles(ctx context.Context, event notify.EventType, roleNames []string, data map[string]any) {
+	if !s.notifier.Enabled(notify.ChannelSMS) {
+		return
+	}
+
+	recipients, err := s.queries.ListSMSRecipientsByRole(ctx, db.ListSMSRecipientsByRoleParams{
+		RoleNames: roleNames,
+		EventType: string(event),
+	})
+	if err != nil {
+		s.logger.Error("Failed to load SMS recipients", err, map[string]any{"event": event})
+		return
+	}
+
+	for _, r := range recipients {
+		s.sendSMS(event, r.Phone.String, data)
+	}
+}
+
+// sendPush renders the push form of an event and queues it for each
+// device token
+func (s *Server) sendPush(event notify.EventType, tokens []string, data map[string]any) {
+	if len(tokens) == 0 {
+		return
+	}
+	title, body, err := notify.RenderPush(event, data)
+	if err != nil {
+		s.logger.Error("Failed to render push notification", err, map[string]any{"event": event})
+		return
+	}
+
+	// The app opens the order the notification is about
+	payload := map[string]string{"event": string(event)}
+	if orderID, ok := data["OrderID"].(string); ok {
+		payload["order_id"] = orderID
+	}
+	for _, token := range tokens {
+		s.notifier.Enqueue(notify.Message{
+			Channel: notify.ChannelPush,
+			Event:   event,
+			To:      token,
+			Subject: title,
+			Text:    body,
+			Data:    payload,
+		})
+	}
+}
+
+// pushRoles pushes event to the devices of every user with one of
+// roleNames who has not opted out of it
+func (s *Server) pushRoles(ctx context.Context, event notify.EventType, roleNames []string, data map[string]any) {
+	if !s.notifier.Enabled(notify.ChannelPush) {
+		return
+	}
+
+	tokens, err := s.queries.ListPushTokensByRole(ctx, db.ListPushTokensByRoleParams{
+		RoleNames: roleNames,
+		EventType: string(event),
+	})
+	if err != nil {
+		s.logger.Error("Failed to load push recipients", err, map[string]any{"event": event})
+		return
+	}
+	s.sendPush(event, tokens, data)
+}
+
+// pushUser pushes event to a single user's devices unless they opted out
+func (s *Server) pushUser(ctx context.Context, event notify.EventType, userID uuid.UUID, data map[string]any) {
+	if !s.notifier.Enabled(notify.ChannelPush) {
+		return
+	}
+
+	tokens, err := s.queries.ListPushTokensForUser(ctx, db.ListPushTokensForUserParams{
+		UserID:    userID,
+		EventType: string(event),
+	})
+	if err != nil {
+		s.logger.Error("Failed to load push recipient", err, map[string]any{"event": event})
+		return
+	}
+	s.sendPush(event, tokens, data)
+}
+
+// notifyRoles emails every user with one of roleNames who has an address
+// and has not opted out of event
+func (s *Server) notifyRoles(ctx context.Context, event notify.EventType, roleNames []string, data map[string]any) {
+	if !s.notifier.Enabled(notify.ChannelEmail) {
+		return
+	}
+
+	recipients, err := s.queries.ListEmailRecipientsByRole(ctx, db.ListEmailRecipientsByRoleParams{
+		RoleNames: roleNames,
+		EventType: string(event),
+	})
+	if err != nil {
+		s.logger.Error("Failed to load notification recipients", err, map[string]any{"event": event})
+		return
+	}
+
+	for _, r := range recipients {
+		s.sendEmail(event, r.Email.String, displayName(r.FullName, r.Username), data)
+	}
+}
+
+// notifyUser emails a single user unless they opted out of event
+func (s *Server) notifyUser(ctx context.Context, event notify.EventType, userID uuid.UUID, data map[string]any) {
+	if !s.notifier.Enabled(notify.ChannelEmail) {
+		return
+	}
+
+	r, err := s.queries.GetEmailRecipient(ctx, db.GetEmailRecipientParams{
+		UserID:    userID,
+		EventType: string(event),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return // no address or opted out
+	}
+	if err != nil {
+		s.logger.Error("Failed to load notification recipient", err, map[string]any{"event": event})
+		return
+	}
+
+	s.sendEmail(event, r.Email.String, displayName(r.FullName, r.Username), data)
+}
+
+// notifyIPBanned alerts administrators when the rate limiter bans an IP
+func (s *Server) notifyIPBanned(ip, reason string, duration time.Duration, attempts int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	summary := fmt.Sprintf("IP %s banned for %s after %d failed attempts", ip, duration, attempts)
+	data := map[string]any{
+		"Summary": summary,
+		"IP":      ip,
+		"Reason":  reason,
+		"Time":    time.Now().Format(time.RFC1123),
+	}
+	s.notifyRoles(ctx, notify.EventSecurityAlert, []string{"admin"}, data)
+	s.pageRoles(ctx, notify.EventSecurityAlert, []string{"admin"}, data)
+	facts := []notify.Fact{
+		{Name: "IP address", Value: ip},
+		{Name: "Reason", Value: reason},
+	}
+	if loc := s.locate(ip); loc != nil {
+		facts = append(facts, notify.Fact{Name: "Location", Value: describeLocation(*loc)})
+	}
+	s.postAlert("IP address banned", summary, facts...)
+}
+
+func displayName(fullName db.EncryptedString, username string) string {
+	if fullName.Valid && fullName.String != "" {
+		return fullName.String
+	}
+	return username
+}
+
+// GetNotificationPreferences handles GET /api/v1/notifications/preferences
+func (s *Server) GetNotificationPreferences(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	response, err := s.loadNotificationPreferences(c.Request().Context(), userID)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Notification preferences")
+	}
+
+	return RespondSuccess(c, http.StatusOK, response)
+}
+
+// UpdateNotificationPreferences handles PUT /api/v1/notifications/preferences
+func (s *Server) UpdateNotificationPreferences(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateNotificationPreferencesReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	if req.Email != nil && *req.Email != "" {
+		if err := s.validator.Var(*req.Email, "email"); err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_email",
+				"Field 'email' must be a valid email address.")
+		}
+	}
+	if req.Phone != nil && *req.Phone != "" {
+		if err := s.validator.Var(*req.Phone, "e164"); err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_phone",
+				"Field 'phone' must be in international format, e.g. +989121234567.")
+		}
+	}
+	for _, p := range req.Preferences {
+		if !notify.ValidEvent(p.EventType) {
+			return RespondError(c, http.StatusBadRequest, "invalid_event_type",
+				fmt.Sprintf("Unknown event type '%s'.", p.EventType))
+		}
+		if !notify.ValidChannel(p.Channel) {
+			return RespondError(c, http.StatusBadRequest, "invalid_channel",
+				fmt.Sprintf("Unknown channel '%s'.", p.Channel))
+		}
+		if !notify.Supports(notify.Channel(p.Channel), notify.EventType(p.EventType)) {
+			return RespondError(c, http.StatusBadRequest, "unsupported_preference",
+				fmt.Sprintf("Event '%s' is not delivered by %s.", p.EventType, p.Channel))
+		}
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.loadNotificationPreferences(ctx, userID)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Notification preferences")
+	}
+
+	if req.Email != nil || req.Phone != nil {
+		email, phone := old.Email, old.Phone
+		if req.Email != nil {
+			email = *req.Email
+		}
+		if req.Phone != nil {
+			phone = *req.Phone
+		}
+		_, err := s.queries.UpsertNotificationSettings(ctx, db.UpsertNotificationSettingsParams{
+			UserID: userID,
+			Email:  sql.NullString{String: email, Valid: email != ""},
+			Phone:  sql.NullString{String: phone, Valid: phone != ""},
+		})
+		if err != nil {
+			return HandleDatabaseError(c, err, "Notification settings")
+		}
+	}
+
+	for _, p := range req.Preferences {
+		err := s.queries.UpsertNotificationPreference(ctx, db.UpsertNotificationPreferenceParams{
+			UserID:    userID,
+			EventType: p.EventType,
+			Channel:   p.Channel,
+			Enabled:   p.Enabled,
+		})
+		if err != nil {
+			return HandleDatabaseError(c, err, "Notification preference")
+		}
+	}
+
+	updated, err := s.loadNotificationPreferences(ctx, userID)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Notification preferences")
+	}
+
+	// Log audit
+	s.logAudit(ctx, userID, "update", "notification_preferences", userID.String(),
+		map[string]any{"preferences": old.Preferences},
+		map[string]any{"preferences": updated.Preferences},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, updated)
+}
+
+// loadNotificationPreferences returns every supported event/channel pair
+// with stored opt-outs applied; pairs without a row are enabled
+func (s *Server) loadNotificationPreferences(ctx context.Context, userID uuid.UUID) (*NotificationPreferencesResponse, error) {
+	response := &NotificationPreferencesResponse{}
+
+	settings, err := s.queries.GetNotificationSettings(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	response.Email = settings.Email.String
+	response.Phone = settings.Phone.String
+
+	stored, err := s.queries.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool, len(stored))
+	for _, p := range stored {
+		enabled[p.EventType+"/"+p.Channel] = p.Enabled
+	}
+
+	for _, event := range notify.Events {
+		for _, channel := range notify.Channels {
+			if !notify.Supports(channel, event) {
+				continue
+			}
+			on, ok := enabled[string(event)+"/"+string(channel)]
+			response.Preferences = append(response.Preferences, NotificationPreferenceItem{
+				EventType: string(event),
+				Channel:   string(channel),
+				Enabled:   !ok || on,
+			})
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,1116 @@
+// internal/server/openapi.go - OpenAPI 3 document for /api/v1 and Swagger UI
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/demo"
+	"github.com/jamalkaksouri/DigiOrder/internal/fhir"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/jamalkaksouri/DigiOrder/internal/quota"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/labstack/echo/v4"
+)
+
+const openAPIPrefix = "/api/v1"
+
+//go:embed static/swagger.html
+var swaggerPage []byte
+
+// swaggerAssets holds the Swagger UI files the docs page loads: the
+// swagger-ui-dist release named in VERSION (fetched with make swagger-ui)
+// and the page's own script
+//
+//go:embed static/swagger-ui
+var swaggerAssets embed.FS
+
+// swaggerBundle is the Swagger UI file the docs page cannot work without
+const swaggerBundle = "swagger-ui-bundle.js"
+
+// swaggerCSP lets the docs page load Swagger UI from this server only;
+// every other response keeps the strict default policy
+const swaggerCSP = "default-src 'none'; script-src 'self'; " +
+	"style-src 'self' 'unsafe-inline'; img-src 'self' data:; " +
+	"connect-src 'self'; frame-ancestors 'none'"
+
+// apiParam documents a query parameter
+type apiParam struct {
+	Name        string
+	Type        string // string, integer or boolean
+	Description string
+}
+
+// apiOperation documents one route. Routes are read from the router, so a
+// route missing here is still published, just without schemas.
+type apiOperation struct {
+	Summary  string
+	Tag      string
+	Request  any // request body, nil when the route takes none
+	Response any // "data" of the success envelope, nil for a free-form object
+	Status   int // success status, 200 when zero
+	Query    []apiParam
+	Roles    []string // role restriction applied in routes.go
+	Public   bool     // no bearer token required
+	Bare     string   // media type of a response sent without the envelope
+	Upload   string   // multipart field of an optional file upload
+	Paged    bool     // a paged list, with "meta" in the envelope
+}
+
+var (
+	pageParams = []apiParam{
+		{Name: "limit", Type: "integer", Description: "Maximum number of items to return"},
+		{Name: "offset", Type: "integer", Description: "Number of items to skip"},
+	}
+	// keysetParams page the lists that hand out a next_cursor
+	keysetParams = append(pageParams[:len(pageParams):len(pageParams)],
+		apiParam{Name: "cursor", Type: "string", Description: "meta.next_cursor of the previous page; replaces offset"})
+	// xlsxParam is accepted by the lists that can be downloaded as Excel
+	xlsxParam       = apiParam{Name: "format", Type: "string", Description: "xlsx downloads every matching row as an Excel sheet, up to the export limits, ignoring limit, offset and cursor"}
+	adminOnly       = []string{"admin"}
+	adminPharmacist = []string{"admin", "pharmacist"}
+	// sortParam takes the fields a list can be sorted by, e.g. ?sort=-created_at,name
+	sortParam = func(columns pagination.SortColumns) apiParam {
+		return apiParam{Name: "sort", Type: "string", Description: "Comma-separated fields, - before one for descending order: " +
+			strings.Join(columns.Names(), ", ") + ". A sorted list is paged by offset."}
+	}
+	// includeParam takes the related resources a route can embed
+	includeParam = func(names []string) apiParam {
+		return apiParam{Name: "include", Type: "string", Description: "Comma-separated related resources to embed: " + strings.Join(names, ", ")}
+	}
+	// calendarParam picks the calendar of date filters and adds Jalali dates
+	calendarParam = apiParam{Name: "calendar", Type: "string", Description: "gregorian (default) or jalali; jalali reads dates as Jalali and adds Jalali dates to the response"}
+)
+
+// apiOperations is keyed by "METHOD /path" as registered on the router
+var apiOperations = map[string]apiOperation{
+	// Auth
+	"POST /api/v1/auth/login": {Summary: "Log in and obtain a JWT", Tag: "Auth",
+		Request: LoginRequest{}, Response: LoginResponse{}, Public: true},
+	"POST /api/v1/auth/refresh": {Summary: "Exchange a refresh token for a new access token and refresh token", Tag: "Auth",
+		Request: RefreshTokenRequest{}, Response: RefreshTokenResponse{}, Public: true},
+	"POST /api/v1/auth/logout": {Summary: "Revoke a refresh token and every token of the same login", Tag: "Auth",
+		Request: RefreshTokenRequest{}, Public: true},
+	"POST /api/v1/auth/password-reset/request": {Summary: "Email a one-time password reset code to the user, if they have an address", Tag: "Auth",
+		Request: PasswordResetRequestReq{}, Status: http.StatusAccepted, Public: true},
+	"POST /api/v1/auth/password-reset/confirm": {Summary: "Set a new password with an emailed reset code", Tag: "Auth",
+		Request: PasswordResetConfirmReq{}, Public: true},
+	"GET /api/v1/auth/profile": {Summary: "Current user's profile", Tag: "Auth", Response: UserInfo{}},
+	"PUT /api/v1/auth/password": {Summary: "Change the current user's password", Tag: "Auth",
+		Request: struct {
+			OldPassword string `json:"old_password" validate:"required"`
+			NewPassword string `json:"new_password" validate:"required,min=6"`
+		}{}},
+	"PUT /api/v1/auth/language": {Summary: "Choose the language of the current user's error messages", Tag: "Auth",
+		Request: SetLanguageReq{}, Response: SetLanguageResponse{}},
+	"GET /api/v1/auth/check-permission": {Summary: "Check whether the current user holds a permission", Tag: "Auth",
+		Query: []apiParam{{Name: "resource", Type: "string"}, {Name: "action", Type: "string"}}},
+	"GET /api/v1/auth/permissions": {Summary: "Every permission the current login, token or API key holds", Tag: "Auth",
+		Response: EffectivePermissions{}},
+	"GET /api/v1/auth/tokens": {Summary: "Current user's personal access tokens", Tag: "Auth",
+		Response: []AccessToken{}},
+	"POST /api/v1/auth/tokens": {Summary: "Create a personal access token; the token is shown only once", Tag: "Auth",
+		Request: CreateAccessTokenReq{}, Response: AccessToken{}, Status: http.StatusCreated},
+	"DELETE /api/v1/auth/tokens/{id}": {Summary: "Revoke a personal access token", Tag: "Auth",
+		Status: http.StatusNoContent},
+
+	// Setup
+	"GET /api/v1/setup/status": {Summary: "Whether the initial admin has been created", Tag: "Setup", Public: true},
+	"POST /api/v1/setup/initialize": {Summary: "Create the first admin user (one time only)", Tag: "Setup",
+		Request: InitialSetupRequest{}, Status: http.StatusCreated, Public: true},
+
+	// Notifications
+	"GET /api/v1/notifications/preferences": {Summary: "Current user's notification preferences", Tag: "Notifications",
+		Response: NotificationPreferencesResponse{}},
+	"PUT /api/v1/notifications/preferences": {Summary: "Update contact details and notification toggles", Tag: "Notifications",
+		Request: UpdateNotificationPreferencesReq{}, Response: NotificationPreferencesResponse{}},
+	"GET /api/v1/notifications/devices": {Summary: "Current user's devices registered for push notifications", Tag: "Notifications",
+		Response: []Device{}},
+	"POST /api/v1/notifications/devices": {Summary: "Register an FCM device token for push notifications", Tag: "Notifications",
+		Request: RegisterDeviceReq{}, Response: Device{}, Status: http.StatusCreated},
+	"DELETE /api/v1/notifications/devices/{id}": {Summary: "Stop push notifications to a device", Tag: "Notifications",
+		Status: http.StatusNoContent},
+
+	// Security
+	"GET /api/v1/security/overview": {Summary: "Login attempts, blocks, bans, releases and permission changes in one payload", Tag: "Security",
+		Response: SecurityOverview{}, Roles: adminOnly, Query: []apiParam{
+			{Name: "hours", Type: "integer", Description: "Window in hours (default 24, at most 720)"},
+			{Name: "limit", Type: "integer", Description: "Entries per list (default 20, at most 100)"},
+		}},
+	"GET /api/v1/security/login-attempts": {Summary: "Rate-limited login attempts", Tag: "Security",
+		Response: []db.LoginAttemptsLog{}, Query: pageParams, Paged: true, Roles: adminOnly},
+	"GET /api/v1/security/login-attempts/report": {Summary: "Login security report by IP", Tag: "Security",
+		Response: []LoginSecurityReportEntry{}, Query: pageParams[:1], Roles: adminOnly},
+	"GET /api/v1/security/blocked-ips": {Summary: "Clients currently blocked by the rate limiter", Tag: "Security",
+		Response: []CurrentlyBlockedIPEntry{}, Roles: adminOnly},
+	"GET /api/v1/security/banned-ips": {Summary: "Active IP bans", Tag: "Security",
+		Bare: echo.MIMEApplicationJSON, Roles: adminOnly},
+	"POST /api/v1/security/unban-ip": {Summary: "Lift an IP ban", Tag: "Security",
+		Request: struct {
+			IPAddress string `json:"ip_address" validate:"required"`
+		}{}, Bare: echo.MIMEApplicationJSON, Roles: adminOnly},
+	"GET /api/v1/security/ip-rules": {Summary: "IP allow and deny rules, bans included", Tag: "Security",
+		Response: []IPRule{}, Roles: adminOnly},
+	"POST /api/v1/security/ip-rules": {Summary: "Allow or deny an address or network", Tag: "Security",
+		Request: IPRuleReq{}, Response: IPRule{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/security/ip-rules/{id}": {Summary: "Get an IP rule", Tag: "Security",
+		Response: IPRule{}, Roles: adminOnly},
+	"PUT /api/v1/security/ip-rules/{id}": {Summary: "Replace an IP rule", Tag: "Security",
+		Request: IPRuleReq{}, Response: IPRule{}, Roles: adminOnly},
+	"DELETE /api/v1/security/ip-rules/{id}": {Summary: "Delete an IP rule", Tag: "Security",
+		Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/security/events": {Summary: "Impossible travel, new devices and unusual hours found in logins", Tag: "Security",
+		Response: []SecurityEvent{}, Roles: adminOnly, Query: append([]apiParam{
+			{Name: "kind", Type: "string", Description: "impossible_travel, new_device or unusual_hour"},
+			{Name: "username", Type: "string"},
+			{Name: "acknowledged", Type: "boolean", Description: "Only acknowledged (true) or unacknowledged (false) events"},
+		}, keysetParams...), Paged: true},
+	"GET /api/v1/security/events/{id}": {Summary: "Get a security event", Tag: "Security",
+		Response: SecurityEvent{}, Roles: adminOnly},
+	"POST /api/v1/security/events/{id}/acknowledge": {Summary: "Mark a security event as reviewed", Tag: "Security",
+		Response: SecurityEvent{}, Roles: adminOnly},
+	"POST /api/v1/security/release-ip": {Summary: "Release an IP from login rate limiting", Tag: "Security",
+		Request: struct {
+			IPAddress string `json:"ip_address" validate:"required"`
+		}{}, Roles: adminOnly},
+	"POST /api/v1/security/cleanup": {Summary: "Purge old login attempts and archive rate limits", Tag: "Security", Roles: adminOnly},
+	"GET /api/v1/security/user/{username}/login-history": {Summary: "A user's login history", Tag: "Security",
+		Response: []db.GetUserLoginHistoryRow{}, Query: pageParams[:1], Roles: adminOnly},
+	"POST /api/v1/security/alerts/test": {Summary: "Post a test alert to the configured Slack and Teams webhooks", Tag: "Security",
+		Response: []AlertDelivery{}, Roles: adminOnly},
+
+	// System
+	"GET /api/v1/system/maintenance": {Summary: "Maintenance mode status", Tag: "System",
+		Response: middleware.MaintenanceStatus{}, Roles: adminOnly},
+	"PUT /api/v1/system/maintenance": {Summary: "Enable or disable maintenance mode", Tag: "System",
+		Request: UpdateMaintenanceReq{}, Response: middleware.MaintenanceStatus{}, Roles: adminOnly},
+	"POST /api/v1/system/config/reload": {Summary: "Reload configuration from file and environment", Tag: "System",
+		Response: ReloadResult{}, Roles: adminOnly},
+	"GET /api/v1/system/self-test": {Summary: "Last startup self-test report", Tag: "System",
+		Response: SelfTestReport{}, Roles: adminOnly},
+	"POST /api/v1/system/demo-data": {Summary: "Add demo products, users and orders (development only)", Tag: "System",
+		Request: SeedDemoDataReq{}, Response: demo.Result{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/admin/slow-queries": {Summary: "Statements slower than the slow query threshold", Tag: "System",
+		Response: SlowQueryReport{}, Roles: adminOnly, Query: []apiParam{
+			{Name: "hours", Type: "integer", Description: "Period to cover in hours (default 24, max 720)"},
+			{Name: "query", Type: "string", Description: "Only this statement, by sqlc query name"},
+			{Name: "limit", Type: "integer", Description: "Slow queries to list (default 50, max 200)"},
+			{Name: "offset", Type: "integer", Description: "Number of slow queries to skip"},
+		}},
+	"GET /api/v1/admin/api-usage": {Summary: "Authenticated requests per consumer, route and day", Tag: "System",
+		Response: APIUsageReport{}, Roles: adminOnly, Query: []apiParam{
+			{Name: "from", Type: "string", Description: "First day (YYYY-MM-DD, default 29 days before to)"},
+			{Name: "to", Type: "string", Description: "Last day (YYYY-MM-DD, default today)"},
+			{Name: "user_id", Type: "string", Description: "Only this user's requests"},
+			{Name: "token_id", Type: "string", Description: "Only requests made with this access token"},
+			{Name: "route", Type: "string", Description: "Only this route template, e.g. /api/v1/products/search"},
+			{Name: "group_by", Type: "string", Description: "Comma-separated day, consumer and route (default consumer)"},
+			{Name: "limit", Type: "integer", Description: "Groups to list, busiest first (default 100, max 1000)"},
+		}},
+	"GET /api/v1/admin/quotas": {Summary: "Quotas of each pharmacy and what it used today", Tag: "Tenants",
+		Response: []quota.Usage{}, Roles: adminOnly},
+	"POST /api/v1/admin/backup": {Summary: "Start a database backup into file storage", Tag: "System",
+		Response: Backup{}, Status: http.StatusAccepted, Roles: adminOnly},
+	"GET /api/v1/admin/backups": {Summary: "List database backups, newest first", Tag: "System",
+		Response: []Backup{}, Query: pageParams, Paged: true, Roles: adminOnly},
+	"GET /api/v1/admin/backups/{id}": {Summary: "Database backup with its restores and a download URL", Tag: "System",
+		Response: BackupDetail{}, Roles: adminOnly},
+	"POST /api/v1/admin/backups/{id}/verify": {Summary: "Check that a backup's file is intact and readable", Tag: "System",
+		Response: Backup{}, Status: http.StatusAccepted, Roles: adminOnly},
+	"POST /api/v1/admin/backups/{id}/restore": {Summary: "Restore the database from a backup (maintenance mode only)", Tag: "System",
+		Request: RestoreBackupReq{}, Response: BackupRestore{}, Status: http.StatusAccepted, Roles: adminOnly},
+	"GET /api/v1/admin/jobs": {Summary: "Periodic jobs with their schedules and last runs", Tag: "System",
+		Response: []ScheduledJob{}, Roles: adminOnly},
+	"POST /api/v1/admin/jobs/{name}/run": {Summary: "Run a periodic job now", Tag: "System",
+		Status: http.StatusAccepted, Roles: adminOnly},
+	"PUT /api/v1/admin/drug-interactions": {Summary: "Replace the drug interaction dataset from a CSV", Tag: "Drug Registry",
+		Upload: "file", Response: map[string]int{"interactions": 0}, Roles: adminOnly},
+	"PUT /api/v1/admin/drug-classes": {Summary: "Replace the ATC codes of generics from a CSV", Tag: "Drug Registry",
+		Upload: "file", Response: map[string]int{"classes": 0}, Roles: adminOnly},
+	"GET /api/v1/admin/api-keys": {Summary: "API keys for machine integrations that have not been revoked", Tag: "Auth",
+		Response: []APIKey{}, Roles: adminOnly},
+	"POST /api/v1/admin/api-keys": {Summary: "Create an API key with a role and scopes; the key is shown only once", Tag: "Auth",
+		Request: APIKeyReq{}, Response: APIKey{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/admin/api-keys/{id}": {Summary: "API key", Tag: "Auth",
+		Response: APIKey{}, Roles: adminOnly},
+	"PUT /api/v1/admin/api-keys/{id}": {Summary: "Replace the name, role, department, scopes and expiry of an API key", Tag: "Auth",
+		Request: APIKeyReq{}, Response: APIKey{}, Roles: adminOnly},
+	"DELETE /api/v1/admin/api-keys/{id}": {Summary: "Revoke an API key", Tag: "Auth",
+		Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/admin/service-accounts": {Summary: "Service accounts for scanners and reporting jobs", Tag: "Auth",
+		Response: []ServiceAccount{}, Roles: adminOnly},
+	"POST /api/v1/admin/service-accounts": {Summary: "Create a service account; it cannot sign in and calls the API with tokens", Tag: "Auth",
+		Request: CreateServiceAccountReq{}, Response: ServiceAccount{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/admin/service-accounts/{id}": {Summary: "Service account", Tag: "Auth",
+		Response: ServiceAccount{}, Roles: adminOnly},
+	"DELETE /api/v1/admin/service-accounts/{id}": {Summary: "Delete a service account and revoke its tokens", Tag: "Auth",
+		Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/admin/service-accounts/{id}/tokens": {Summary: "Access tokens of a service account that have not been revoked", Tag: "Auth",
+		Response: []AccessToken{}, Roles: adminOnly},
+	"POST /api/v1/admin/service-accounts/{id}/tokens": {Summary: "Create a scoped access token for a service account; the token is shown only once", Tag: "Auth",
+		Request: CreateAccessTokenReq{}, Response: AccessToken{}, Status: http.StatusCreated, Roles: adminOnly},
+	"DELETE /api/v1/admin/service-accounts/{id}/tokens/{token_id}": {Summary: "Revoke an access token of a service account", Tag: "Auth",
+		Status: http.StatusNoContent, Roles: adminOnly},
+
+	// Tenants
+	"GET /api/v1/tenants": {Summary: "List the pharmacies sharing the deployment", Tag: "Tenants",
+		Response: []db.Tenant{}, Roles: adminOnly},
+	"POST /api/v1/tenants": {Summary: "Create a pharmacy with its first admin", Tag: "Tenants",
+		Request: CreateTenantReq{}, Response: CreateTenantResponse{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/tenants/{id}": {Summary: "Get a pharmacy", Tag: "Tenants", Response: db.Tenant{}, Roles: adminOnly},
+	"PUT /api/v1/tenants/{id}": {Summary: "Rename a pharmacy", Tag: "Tenants",
+		Request: UpdateTenantReq{}, Response: db.Tenant{}, Roles: adminOnly},
+	"PUT /api/v1/tenants/{id}/quotas": {Summary: "Override a pharmacy's default quotas", Tag: "Tenants",
+		Request: SetTenantQuotasReq{}, Response: db.Tenant{}, Roles: adminOnly},
+
+	// Products
+	"POST /api/v1/products": {Summary: "Create a product", Tag: "Products",
+		Request: CreateProductReq{}, Response: db.Product{}, Status: http.StatusCreated, Roles: adminPharmacist},
+	"GET /api/v1/products": {Summary: "List products", Tag: "Products",
+		Response: []db.Product{}, Query: append([]apiParam{xlsxParam, sortParam(productSortColumns), includeParam(productIncludes)}, keysetParams...), Paged: true},
+	"GET /api/v1/products/search": {Summary: "Search products by name or brand", Tag: "Products",
+		Response: []db.Product{}, Query: append([]apiParam{{Name: "q", Type: "string", Description: "Search text; Arabic and Persian spellings, digits and ZWNJ match alike"},
+			sortParam(productSortColumns), includeParam(productIncludes)}, keysetParams...), Paged: true},
+	"GET /api/v1/products/barcode/{barcode}": {Summary: "Find a product by barcode", Tag: "Products", Response: db.Product{}},
+	"GET /api/v1/products/{id}": {Summary: "Get a product", Tag: "Products", Response: db.Product{},
+		Query: []apiParam{includeParam(productIncludes)}},
+	"PUT /api/v1/products/{id}": {Summary: "Update a product", Tag: "Products",
+		Request: UpdateProductReq{}, Response: db.Product{}, Roles: adminPharmacist},
+	"DELETE /api/v1/products/{id}": {Summary: "Delete a product", Tag: "Products", Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/products/{product_id}/barcodes": {Summary: "List a product's barcodes", Tag: "Barcodes",
+		Response: []db.ProductBarcode{}},
+	"PUT /api/v1/products/{id}/image": {Summary: "Upload or replace a product's image (JPEG, PNG or WebP)", Tag: "Products",
+		Upload: "image", Response: ProductImage{}, Roles: adminPharmacist},
+	"GET /api/v1/products/{id}/image": {Summary: "A presigned download URL for a product's image", Tag: "Products",
+		Response: ProductImage{}},
+	"DELETE /api/v1/products/{id}/image": {Summary: "Remove a product's image", Tag: "Products",
+		Status: http.StatusNoContent, Roles: adminPharmacist},
+	"GET /api/v1/products/{id}/stock": {Summary: "A product's stock on hand and reorder level", Tag: "Products",
+		Response: ProductStock{}},
+	"PUT /api/v1/products/{id}/stock": {Summary: "Record a product's stock on hand or reorder level", Tag: "Products",
+		Request: UpdateProductStockReq{}, Response: ProductStock{}, Roles: adminPharmacist},
+	"GET /api/v1/products/{id}/forecast": {Summary: "Projected demand and suggested reorder quantity", Tag: "Products",
+		Response: reports.Forecast{}, Roles: adminPharmacist, Query: []apiParam{
+			{Name: "granularity", Type: "string", Description: "day, week (default) or month"},
+			{Name: "history", Type: "integer", Description: "Past buckets to learn from (default 12)"},
+			{Name: "window", Type: "integer", Description: "Buckets in the moving average (default 4)"},
+			{Name: "horizon", Type: "integer", Description: "Future buckets to project (default 4)"},
+		}},
+
+	// Labels
+	"GET /api/v1/printers": {Summary: "List the configured label printers", Tag: "Labels", Response: []LabelPrinter{}},
+	"POST /api/v1/products/{id}/labels": {Summary: "Print a product's shelf label", Tag: "Labels",
+		Request: PrintLabelsReq{}, Response: PrintJob{}},
+	"POST /api/v1/orders/{id}/labels": {Summary: "Print a label for every item of an order", Tag: "Labels",
+		Request: PrintLabelsReq{}, Response: PrintJob{}},
+	"POST /api/v1/order_items/{id}/labels": {Summary: "Print or reprint an order item's label", Tag: "Labels",
+		Request: PrintLabelsReq{}, Response: PrintJob{}},
+
+	// Drug registry
+	"POST /api/v1/drug-registry/syncs": {Summary: "Start a national drug registry sync from an upload or the registry URL", Tag: "Drug Registry",
+		Upload: "file", Response: DrugRegistrySync{}, Status: http.StatusAccepted, Roles: adminOnly},
+	"GET /api/v1/drug-registry/syncs": {Summary: "List drug registry syncs", Tag: "Drug Registry",
+		Response: []DrugRegistrySync{}, Query: pageParams, Paged: true, Roles: adminPharmacist},
+	"GET /api/v1/drug-registry/syncs/{id}": {Summary: "Reconciliation report of a drug registry sync", Tag: "Drug Registry",
+		Response: DrugRegistryReport{}, Roles: adminPharmacist, Query: append([]apiParam{
+			{Name: "outcome", Type: "string", Description: "matched_irc, matched_barcode, matched_name, created, ambiguous, conflict or failed"},
+		}, pageParams...)},
+
+	// Catalog
+	"POST /api/v1/categories": {Summary: "Create a category", Tag: "Catalog",
+		Request: CreateCategoryReq{}, Response: db.Category{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/categories":      {Summary: "List categories", Tag: "Catalog", Response: []db.Category{}},
+	"GET /api/v1/categories/{id}": {Summary: "Get a category", Tag: "Catalog", Response: db.Category{}},
+	"POST /api/v1/dosage_forms": {Summary: "Create a dosage form", Tag: "Catalog",
+		Request: CreateDosageFormReq{}, Response: db.DosageForm{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/dosage_forms":      {Summary: "List dosage forms", Tag: "Catalog", Response: []db.DosageForm{}},
+	"GET /api/v1/dosage_forms/{id}": {Summary: "Get a dosage form", Tag: "Catalog", Response: db.DosageForm{}},
+
+	// Orders
+	"POST /api/v1/orders": {Summary: "Create an order", Tag: "Orders",
+		Request: CreateOrderReq{}, Response: db.Order{}, Status: http.StatusCreated},
+	"POST /api/v1/orders/import": {Summary: "Create a draft order from a CSV or Excel requirement list", Tag: "Orders",
+		Upload: "file", Response: OrderImportResult{}, Status: http.StatusCreated,
+		Query: []apiParam{
+			{Name: "dry_run", Type: "boolean", Description: "Match the rows without creating the order"},
+			{Name: "priority", Type: "string", Description: "routine, urgent or stat"},
+			{Name: "notes", Type: "string"},
+			{Name: "columns", Type: "string", Description: `JSON mapping of fields to headers, e.g. {"barcode": "EAN"}`},
+		}},
+	"GET /api/v1/orders": {Summary: "List orders", Tag: "Orders", Response: []db.Order{},
+		Query: append([]apiParam{
+			{Name: "created_by", Type: "string", Description: "Only orders created by this user"},
+			{Name: "user_id", Type: "string", Description: "Same as created_by"},
+			{Name: "requester_id", Type: "string", Description: "Only orders for this patient or department"},
+			{Name: "status", Type: "string", Description: "Comma-separated statuses; only orders in one of them"},
+			{Name: "product_id", Type: "string", Description: "Only orders with an item for this product"},
+			{Name: "q", Type: "string", Description: "Search the notes and the item products and notes, folding Persian spelling"},
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time; only orders created since"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time; only orders created before"},
+			calendarParam, xlsxParam, sortParam(orderSortColumns), includeParam(orderIncludes),
+		}, keysetParams...), Paged: true},
+	"GET /api/v1/orders/export": {Summary: "Download the orders ListOrders would list, with their items, as CSV", Tag: "Orders",
+		Response: "", Bare: "text/csv", Query: []apiParam{
+			{Name: "format", Type: "string", Description: "csv, the only format"},
+			{Name: "created_by", Type: "string", Description: "Only orders created by this user"},
+			{Name: "requester_id", Type: "string", Description: "Only orders for this patient or department"},
+			{Name: "status", Type: "string", Description: "Comma-separated statuses; only orders in one of them"},
+			{Name: "product_id", Type: "string", Description: "Only orders with an item for this product"},
+			{Name: "q", Type: "string", Description: "Search the notes and the item products and notes, folding Persian spelling"},
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time; only orders created since"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time; only orders created before"},
+			calendarParam,
+		}},
+	"GET /api/v1/orders/{id}": {Summary: "Get an order", Tag: "Orders", Response: db.Order{},
+		Query: []apiParam{includeParam(orderIncludes)}},
+	"POST /api/v1/orders/{id}/duplicate": {Summary: "Copy an order and its items into a new draft order", Tag: "Orders",
+		Response: db.Order{}, Status: http.StatusCreated},
+	"PUT /api/v1/orders/{id}/status": {Summary: "Change an order's status", Tag: "Orders",
+		Request: UpdateOrderStatusReq{}, Response: db.Order{}},
+	"POST /api/v1/orders/{id}/cancel": {Summary: "Cancel an order with a reason code and note, and notify its creator", Tag: "Orders",
+		Request: CancelOrderReq{}, Response: CancelledOrder{}},
+	"GET /api/v1/orders/{id}/history": {Summary: "Status changes of an order, oldest first", Tag: "Orders",
+		Response: []OrderStatusChange{}},
+	"GET /api/v1/orders/{id}/assignee": {Summary: "Staff member handling an order", Tag: "Orders",
+		Response: OrderAssignee{}},
+	"PUT /api/v1/orders/{id}/assignee": {Summary: "Assign an order and notify the assignee", Tag: "Orders",
+		Request: AssignOrderReq{}, Response: OrderAssignee{}, Roles: adminPharmacist},
+	"DELETE /api/v1/orders/{id}/assignee": {Summary: "Unassign an order", Tag: "Orders",
+		Status: http.StatusNoContent, Roles: adminPharmacist},
+	"PUT /api/v1/orders/{id}/needed-by": {Summary: "Set or clear the date an order is needed by", Tag: "Orders",
+		Request: UpdateOrderNeededByReq{}, Response: db.Order{}},
+	"PUT /api/v1/orders/{id}/requester": {Summary: "Link an order to the patient or department it is for, or unlink it", Tag: "Orders",
+		Request: SetOrderRequesterReq{}, Response: db.Order{}},
+	"DELETE /api/v1/orders/{id}": {Summary: "Delete an order", Tag: "Orders", Status: http.StatusNoContent, Roles: adminOnly},
+	"POST /api/v1/orders/{order_id}/items": {Summary: "Add an item to an order, with any interaction warnings it raises", Tag: "Orders",
+		Request: CreateOrderItemReq{}, Response: OrderItemWithWarnings{}, Status: http.StatusCreated},
+	"POST /api/v1/orders/{order_id}/items/bulk": {Summary: "Add many items to an order at once, with any interaction warnings they raise", Tag: "Orders",
+		Request: CreateOrderItemsReq{}, Response: []OrderItemWithWarnings{}, Status: http.StatusCreated},
+	"GET /api/v1/orders/{order_id}/items": {Summary: "List an order's items", Tag: "Orders", Response: []db.OrderItem{},
+		Query: pageParams, Paged: true},
+	"PUT /api/v1/order_items/{id}": {Summary: "Update an order item", Tag: "Orders",
+		Request: UpdateOrderItemReq{}, Response: db.OrderItem{}},
+	"DELETE /api/v1/order_items/{id}": {Summary: "Remove an order item", Tag: "Orders", Status: http.StatusNoContent},
+	"GET /api/v1/orders/{id}/warnings": {Summary: "Interaction and duplicate-therapy warnings on an order's items", Tag: "Orders",
+		Response: []db.ListOrderWarningsRow{}},
+	"GET /api/v1/orders/{id}/picking-slip": {Summary: "Picking slip PDF of an order's items and warnings", Tag: "Orders",
+		Response: "", Bare: reports.ContentTypePDF, Query: []apiParam{calendarParam}},
+	"GET /api/v1/orders/{id}/suppliers": {Summary: "An order split by the supplier each item is routed to, with subtotals", Tag: "Orders",
+		Response: []SupplierShare{}},
+	"PUT /api/v1/orders/{id}/suppliers": {Summary: "Route items of an order to suppliers, or unroute them", Tag: "Orders",
+		Request: RouteOrderItemsReq{}, Response: []SupplierShare{}, Roles: adminPharmacist},
+	"POST /api/v1/orders/{id}/attachments": {Summary: "Attach a file (PDF, image or text) to an order", Tag: "Orders",
+		Upload: "file", Response: OrderAttachment{}, Status: http.StatusCreated},
+	"GET /api/v1/orders/{id}/attachments": {Summary: "List an order's attachments with download URLs", Tag: "Orders",
+		Response: []OrderAttachment{}},
+	"DELETE /api/v1/orders/{id}/attachments/{attachment_id}": {Summary: "Delete an attachment (uploader or admin)", Tag: "Orders",
+		Status: http.StatusNoContent},
+
+	// Order calendar
+	"POST /api/v1/recurring-orders": {Summary: "Place a copy of an order on a schedule", Tag: "Calendar",
+		Request: CreateRecurringOrderReq{}, Response: RecurringOrder{}, Status: http.StatusCreated, Roles: adminPharmacist},
+	"GET /api/v1/recurring-orders": {Summary: "List recurring orders, soonest run first", Tag: "Calendar",
+		Response: []RecurringOrder{}},
+	"GET /api/v1/recurring-orders/{id}": {Summary: "Get a recurring order", Tag: "Calendar",
+		Response: RecurringOrder{}},
+	"PUT /api/v1/recurring-orders/{id}": {Summary: "Update a recurring order", Tag: "Calendar",
+		Request: UpdateRecurringOrderReq{}, Response: RecurringOrder{}, Roles: adminPharmacist},
+	"DELETE /api/v1/recurring-orders/{id}": {Summary: "Stop a recurring order", Tag: "Calendar",
+		Status: http.StatusNoContent, Roles: adminPharmacist},
+	"GET /api/v1/calendar/feed": {Summary: "Current user's calendar feed", Tag: "Calendar",
+		Response: CalendarFeed{}},
+	"POST /api/v1/calendar/feed": {Summary: "Create a private calendar feed URL, replacing the previous one", Tag: "Calendar",
+		Response: CalendarFeed{}, Status: http.StatusCreated},
+	"DELETE /api/v1/calendar/feed": {Summary: "Turn the current user's calendar feed off", Tag: "Calendar",
+		Status: http.StatusNoContent},
+	"GET /api/v1/calendar/feed.ics": {Summary: "iCalendar feed of recurring orders and order deadlines", Tag: "Calendar",
+		Response: "", Bare: "text/calendar", Public: true, Query: []apiParam{
+			{Name: "token", Type: "string", Description: "Feed token from POST /calendar/feed"},
+		}},
+
+	// Exports
+	"POST /api/v1/exports/orders": {Summary: "Export orders and their items as CSV", Tag: "Exports",
+		Request: ExportOrdersReq{}, Response: ExportFile{}, Status: http.StatusCreated, Roles: adminPharmacist},
+	"GET /api/v1/exports": {Summary: "List generated exports", Tag: "Exports",
+		Response: []ExportFile{}, Query: pageParams, Paged: true, Roles: adminPharmacist},
+	"GET /api/v1/exports/{id}": {Summary: "An export with a fresh download URL", Tag: "Exports",
+		Response: ExportFile{}, Roles: adminPharmacist},
+
+	// Scheduled reports
+	// ERP export
+	"GET /api/v1/erp/mapping": {Summary: "The ERP document format and field mapping", Tag: "ERP",
+		Response: ERPMapping{}, Roles: adminOnly},
+	"GET /api/v1/erp/orders": {Summary: "Pull the next batch of fulfilled orders as a CSV, JSON or XML document", Tag: "ERP",
+		Response: "", Bare: "text/csv", Roles: adminOnly},
+	"POST /api/v1/erp/push": {Summary: "Push the next batch of fulfilled orders to the ERP", Tag: "ERP",
+		Response: ERPBatch{}, Roles: adminOnly},
+	"GET /api/v1/erp/batches": {Summary: "List ERP batches", Tag: "ERP",
+		Response: []ERPBatch{}, Query: pageParams, Paged: true, Roles: adminOnly},
+	"GET /api/v1/erp/batches/{id}": {Summary: "An ERP batch with its orders", Tag: "ERP",
+		Response: ERPBatch{}, Roles: adminOnly},
+	"POST /api/v1/erp/batches/{id}/ack": {Summary: "Acknowledge a pulled ERP batch", Tag: "ERP",
+		Response: ERPBatch{}, Roles: adminOnly},
+	"POST /api/v1/callbacks/erp/ack": {Summary: "Acknowledge a pulled ERP batch with a signed request instead of a token", Tag: "ERP",
+		Request: ERPAckCallback{}, Response: ERPBatch{}, Public: true},
+
+	"GET /api/v1/reports/orders/timeseries": {Summary: "Orders, items and quantity per day, week or month for charts", Tag: "Reports",
+		Response: reports.TimeSeries{}, Roles: adminPharmacist, Query: []apiParam{
+			{Name: "granularity", Type: "string", Description: "day (default), week or month"},
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time; defaults to 30 days, 12 weeks or 12 months before to"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time; defaults to now"},
+			{Name: "group_by", Type: "string", Description: "Split the series by status, category or department"},
+			calendarParam,
+		}},
+	"GET /api/v1/reports/users/activity": {Summary: "Orders created, items added, approvals and logins per user", Tag: "Reports",
+		Response: UserActivityReport{}, Roles: adminOnly, Query: []apiParam{
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time; defaults to 30 days before to"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time; defaults to now"},
+			{Name: "user_id", Type: "string", Description: "Report on one user only"},
+			{Name: "active_only", Type: "boolean", Description: "Leave out users without activity"},
+			calendarParam,
+		}},
+	"GET /api/v1/reports/controlled-register": {Summary: "Controlled items of approved orders, with reasons and approvers", Tag: "Reports",
+		Response: ControlledRegister{}, Roles: adminPharmacist, Query: []apiParam{
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time of approval; defaults to 30 days before to"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time of approval; defaults to now"},
+			{Name: "format", Type: "string", Description: "xlsx downloads the register as an Excel sheet"},
+			calendarParam,
+		}},
+	"GET /api/v1/reports/requesters": {Summary: "Orders, items and quantity per requester", Tag: "Reports",
+		Response: RequesterReport{}, Roles: adminPharmacist, Query: []apiParam{
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time; defaults to 30 days before to"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time; defaults to now"},
+			{Name: "format", Type: "string", Description: "xlsx downloads the report as an Excel sheet"},
+			calendarParam,
+		}},
+	"GET /api/v1/reports/changes": {Summary: "How often each entity type changes, and by whom, from the audit log", Tag: "Reports",
+		Response: reports.ChangeReport{}, Roles: adminOnly, Query: []apiParam{
+			{Name: "granularity", Type: "string", Description: "day, week (default) or month"},
+			{Name: "from", Type: "string", Description: "Date or RFC 3339 time; defaults to 30 days, 12 weeks or 12 months before to"},
+			{Name: "to", Type: "string", Description: "Date (included) or RFC 3339 time; defaults to now"},
+			{Name: "entity_type", Type: "string", Description: "Comma-separated entity types, e.g. product,role_permission"},
+			{Name: "user_id", Type: "string", Description: "Only changes made by this user"},
+			{Name: "most_changed", Type: "integer", Description: "Entities of each type to list, most changed first (default 10, max 50)"},
+			calendarParam,
+		}},
+	"GET /api/v1/reports/entities": {Summary: "Entities and fields saved reports can use", Tag: "Reports",
+		Response: []reports.SavedEntity{}, Roles: adminOnly},
+	"POST /api/v1/reports": {Summary: "Save a report definition", Tag: "Reports",
+		Request: SavedReportReq{}, Response: SavedReport{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/reports": {Summary: "List saved reports", Tag: "Reports",
+		Response: []SavedReport{}, Roles: adminOnly},
+	"GET /api/v1/reports/{id}": {Summary: "Get a saved report", Tag: "Reports",
+		Response: SavedReport{}, Roles: adminOnly},
+	"PUT /api/v1/reports/{id}": {Summary: "Replace a saved report definition", Tag: "Reports",
+		Request: SavedReportReq{}, Response: SavedReport{}, Roles: adminOnly},
+	"DELETE /api/v1/reports/{id}": {Summary: "Delete a saved report and its schedules", Tag: "Reports",
+		Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/reports/{id}/run": {Summary: "Run a saved report", Tag: "Reports",
+		Response: SavedReportRun{}, Roles: adminOnly, Query: []apiParam{xlsxParam}},
+	"POST /api/v1/report-schedules": {Summary: "Email a report to a user on a schedule", Tag: "Reports",
+		Request: CreateReportScheduleReq{}, Response: ReportSchedule{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/report-schedules": {Summary: "List report schedules", Tag: "Reports",
+		Response: []ReportSchedule{}, Roles: adminOnly, Query: append([]apiParam{
+			{Name: "recipient_id", Type: "string", Description: "Only schedules emailed to this user"},
+		}, pageParams...), Paged: true},
+	"GET /api/v1/report-schedules/{id}": {Summary: "Get a report schedule", Tag: "Reports",
+		Response: ReportSchedule{}, Roles: adminOnly},
+	"PUT /api/v1/report-schedules/{id}": {Summary: "Update a report schedule", Tag: "Reports",
+		Request: UpdateReportScheduleReq{}, Response: ReportSchedule{}, Roles: adminOnly},
+	"DELETE /api/v1/report-schedules/{id}": {Summary: "Delete a report schedule", Tag: "Reports",
+		Status: http.StatusNoContent, Roles: adminOnly},
+	"POST /api/v1/report-schedules/{id}/run": {Summary: "Email a scheduled report now", Tag: "Reports",
+		Response: ReportRun{}, Roles: adminOnly},
+
+	// FHIR export
+	"GET /api/v1/fhir/Medication": {Summary: "Products as a searchset Bundle of Medication", Tag: "FHIR",
+		Response: fhir.Bundle{}, Bare: fhir.ContentType, Query: []apiParam{
+			{Name: "_count", Type: "integer", Description: "Page size (max 100)"},
+			{Name: "_offset", Type: "integer", Description: "Number of products to skip"},
+		}},
+	"GET /api/v1/fhir/Medication/{id}": {Summary: "A Medication by FHIR ID", Tag: "FHIR",
+		Response: fhir.Medication{}, Bare: fhir.ContentType},
+	"GET /api/v1/fhir/MedicationRequest/{id}": {Summary: "An order item as MedicationRequest by FHIR ID", Tag: "FHIR",
+		Response: fhir.MedicationRequest{}, Bare: fhir.ContentType},
+	"GET /api/v1/fhir/Bundle/{id}": {Summary: "An order Bundle by FHIR ID", Tag: "FHIR",
+		Response: fhir.Bundle{}, Bare: fhir.ContentType},
+	"GET /api/v1/fhir/orders/{id}": {Summary: "An order as a Bundle of MedicationRequest and Medication", Tag: "FHIR",
+		Response: fhir.Bundle{}, Bare: fhir.ContentType},
+
+	// Barcodes
+	"POST /api/v1/barcodes": {Summary: "Attach a barcode to a product", Tag: "Barcodes",
+		Request: CreateBarcodeReq{}, Response: db.ProductBarcode{}, Status: http.StatusCreated, Roles: adminPharmacist},
+	"PUT /api/v1/barcodes/{id}": {Summary: "Update a barcode", Tag: "Barcodes",
+		Request: UpdateBarcodeReq{}, Response: db.ProductBarcode{}, Roles: adminPharmacist},
+	"DELETE /api/v1/barcodes/{id}": {Summary: "Delete a barcode", Tag: "Barcodes", Status: http.StatusNoContent, Roles: adminOnly},
+
+	// Users
+	"POST /api/v1/users": {Summary: "Create a user", Tag: "Users",
+		Request: CreateUserReq{}, Response: db.User{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/users": {Summary: "List users", Tag: "Users", Query: append([]apiParam{xlsxParam, sortParam(userSortColumns)}, keysetParams...),
+		Paged: true, Roles: adminOnly},
+	"GET /api/v1/users/{id}": {Summary: "Get a user", Tag: "Users", Roles: adminOnly},
+	"PUT /api/v1/users/{id}": {Summary: "Update a user", Tag: "Users",
+		Request: UpdateUserReq{}, Response: db.User{}, Roles: adminOnly},
+	"DELETE /api/v1/users/{id}":            {Summary: "Delete a user (soft delete)", Tag: "Users", Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/users/{user_id}/activity": {Summary: "A user's audit trail", Tag: "Users", Query: pageParams, Paged: true, Roles: adminOnly},
+
+	// Order statuses
+	"GET /api/v1/order-statuses": {Summary: "List the statuses an order may be in", Tag: "Orders",
+		Response: []db.OrderStatus{}},
+	"POST /api/v1/order-statuses": {Summary: "Add an order status", Tag: "Orders",
+		Request: CreateOrderStatusReq{}, Response: db.OrderStatus{}, Status: http.StatusCreated, Roles: adminOnly},
+	"PUT /api/v1/order-statuses/{code}": {Summary: "Relabel or reorder an order status", Tag: "Orders",
+		Request: UpdateOrderStatusEntryReq{}, Response: db.OrderStatus{}, Roles: adminOnly},
+	"DELETE /api/v1/order-statuses/{code}": {Summary: "Delete an order status no order is in", Tag: "Orders",
+		Status: http.StatusNoContent, Roles: adminOnly},
+
+	// Departments
+	"POST /api/v1/departments": {Summary: "Create a department", Tag: "Users",
+		Request: CreateDepartmentReq{}, Response: db.Department{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/departments":      {Summary: "List departments", Tag: "Users", Response: []db.Department{}},
+	"GET /api/v1/departments/{id}": {Summary: "Get a department", Tag: "Users", Response: db.Department{}},
+	"PUT /api/v1/departments/{id}": {Summary: "Rename a department", Tag: "Users",
+		Request: UpdateDepartmentReq{}, Response: db.Department{}, Roles: adminOnly},
+	"DELETE /api/v1/departments/{id}": {Summary: "Delete a department; its users and orders keep no department", Tag: "Users",
+		Status: http.StatusNoContent, Roles: adminOnly},
+
+	// Suppliers
+	"POST /api/v1/suppliers": {Summary: "Add a supplier order items can be routed to", Tag: "Orders",
+		Request: SupplierReq{}, Response: Supplier{}, Status: http.StatusCreated, Roles: adminPharmacist},
+	"GET /api/v1/suppliers":      {Summary: "List suppliers by name", Tag: "Orders", Response: []Supplier{}},
+	"GET /api/v1/suppliers/{id}": {Summary: "Get a supplier", Tag: "Orders", Response: Supplier{}},
+	"PUT /api/v1/suppliers/{id}": {Summary: "Replace a supplier's name and contact details", Tag: "Orders",
+		Request: SupplierReq{}, Response: Supplier{}, Roles: adminPharmacist},
+	"DELETE /api/v1/suppliers/{id}": {Summary: "Delete a supplier; its items are left unrouted", Tag: "Orders",
+		Status: http.StatusNoContent, Roles: adminPharmacist},
+
+	// Requesters
+	"GET /api/v1/requesters": {Summary: "List the patients and departments orders are for", Tag: "Orders", Response: []Requester{},
+		Query: append([]apiParam{
+			{Name: "kind", Type: "string", Description: "patient or department"},
+			{Name: "department_id", Type: "integer", Description: "Only requesters of this department"},
+		}, keysetParams...), Paged: true},
+	"POST /api/v1/requesters": {Summary: "Add a patient or department orders are for", Tag: "Orders",
+		Request: RequesterReq{}, Response: Requester{}, Status: http.StatusCreated},
+	"GET /api/v1/requesters/{id}": {Summary: "Get a requester", Tag: "Orders", Response: Requester{}},
+	"PUT /api/v1/requesters/{id}": {Summary: "Replace a requester", Tag: "Orders",
+		Request: RequesterReq{}, Response: Requester{}},
+	"DELETE /api/v1/requesters/{id}": {Summary: "Delete a requester; its orders keep no requester", Tag: "Orders",
+		Status: http.StatusNoContent, Roles: adminOnly},
+
+	// Roles and permissions
+	"POST /api/v1/roles": {Summary: "Create a role", Tag: "Roles",
+		Request: CreateRoleReq{}, Response: db.Role{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/roles":      {Summary: "List roles", Tag: "Roles", Response: []db.Role{}, Roles: adminOnly},
+	"GET /api/v1/roles/{id}": {Summary: "Get a role", Tag: "Roles", Response: db.Role{}, Roles: adminOnly},
+	"PUT /api/v1/roles/{id}": {Summary: "Rename a role", Tag: "Roles",
+		Request: UpdateRoleReq{}, Response: db.Role{}, Roles: adminOnly},
+	"DELETE /api/v1/roles/{id}": {Summary: "Delete a role", Tag: "Roles", Status: http.StatusNoContent, Roles: adminOnly},
+	"POST /api/v1/roles/{role_id}/permissions": {Summary: "Grant a permission to a role", Tag: "Roles",
+		Request: struct {
+			PermissionID int32 `json:"permission_id" validate:"required"`
+		}{}, Response: db.RolePermission{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/roles/{role_id}/permissions": {Summary: "List a role's permissions", Tag: "Roles",
+		Response: []db.Permission{}, Roles: adminOnly},
+	"DELETE /api/v1/roles/{role_id}/permissions/{permission_id}": {Summary: "Revoke a permission from a role", Tag: "Roles",
+		Status: http.StatusNoContent, Roles: adminOnly},
+	"POST /api/v1/permissions": {Summary: "Create a permission", Tag: "Permissions",
+		Request: CreatePermissionReq{}, Response: db.Permission{}, Status: http.StatusCreated, Roles: adminOnly},
+	"GET /api/v1/permissions": {Summary: "List permissions", Tag: "Permissions", Response: []db.Permission{},
+		Query: append([]apiParam{{Name: "resource", Type: "string", Description: "Only permissions for this resource"},
+			sortParam(permissionSortColumns)}, pageParams...), Paged: true, Roles: adminOnly},
+	"GET /api/v1/permissions/{id}": {Summary: "Get a permission", Tag: "Permissions", Response: db.Permission{}, Roles: adminOnly},
+	"PUT /api/v1/permissions/{id}": {Summary: "Update a permission", Tag: "Permissions",
+		Request: UpdatePermissionReq{}, Response: db.Permission{}, Roles: adminOnly},
+	"DELETE /api/v1/permissions/{id}": {Summary: "Delete a permission", Tag: "Permissions", Status: http.StatusNoContent, Roles: adminOnly},
+	"GET /api/v1/rbac": {Summary: "Download every role, permission and grant as one document", Tag: "Permissions",
+		Response: RBACDocument{}, Bare: echo.MIMEApplicationJSON, Roles: adminOnly, Query: []apiParam{
+			{Name: "format", Type: "string", Description: "json (default) or yaml"},
+		}},
+	"PUT /api/v1/rbac": {Summary: "Make roles, permissions and grants match a document, in JSON or YAML", Tag: "Permissions",
+		Request: RBACDocument{}, Response: RBACImportResult{}, Roles: adminOnly, Query: []apiParam{
+			{Name: "format", Type: "string", Description: "json or yaml; defaults to the Content-Type of the body"},
+			{Name: "prune", Type: "boolean", Description: "Also delete the roles and permissions the document leaves out; the admin role is kept"},
+			{Name: "dry_run", Type: "boolean", Description: "List the changes without making them"},
+		}},
+
+	// Audit logs
+	"GET /api/v1/audit-logs": {Summary: "Search audit logs", Tag: "Audit", Roles: adminOnly,
+		Query: append([]apiParam{
+			{Name: "user_id", Type: "string"},
+			{Name: "entity_type", Type: "string"},
+			{Name: "entity_id", Type: "string"},
+			{Name: "action", Type: "string"},
+			{Name: "start_date", Type: "string", Description: "Date or RFC 3339 time"},
+			{Name: "end_date", Type: "string", Description: "Date (included) or RFC 3339 time"},
+			calendarParam,
+			xlsxParam,
+			sortParam(auditLogSortColumns),
+		}, keysetParams...), Paged: true},
+	"GET /api/v1/audit-logs/{id}": {Summary: "Get an audit log entry", Tag: "Audit", Roles: adminOnly},
+	"GET /api/v1/audit-logs/entity/{type}/{id}": {Summary: "Change history of one entity", Tag: "Audit",
+		Query: pageParams, Paged: true, Roles: adminOnly},
+	"GET /api/v1/audit-logs/stats": {Summary: "Audit log statistics", Tag: "Audit",
+		Response: db.GetAuditLogStatsRow{}, Roles: adminOnly},
+}
+
+// apiErrorCodes lists the "error" values each status can carry
+var apiErrorCodes = map[int][]string{
+	http.StatusBadRequest: {"invalid_request", "unknown_field", "invalid_id", "invalid_format", "invalid_limit",
+		"invalid_offset", "invalid_cursor", "invalid_sort", "invalid_include", "invalid_user_id", "invalid_order_id", "invalid_product_id",
+		"invalid_role_id", "invalid_permission_id", "invalid_attachment_id", "invalid_token_id", "validation_error", "invalid_email", "invalid_phone", "invalid_channel", "invalid_event_type",
+		"invalid_retry_after", "missing_parameters", "missing_query",
+		"missing_barcode", "missing_username", "query_too_short",
+		"unsupported_preference", "unsupported_api_version",
+		"invalid_registry_file", "registry_not_configured", "invalid_outcome",
+		"missing_file", "invalid_file", "invalid_range", "invalid_report", "invalid_frequency",
+		"invalid_recipient", "invalid_columns", "unknown_printer", "invalid_scope",
+		"invalid_granularity", "invalid_group_by", "invalid_definition", "invalid_saved_report",
+		"invalid_slug", "unsupported_language", "invalid_calendar", "invalid_reset_token"},
+	http.StatusUnauthorized:          {"unauthorized", "invalid_token", "refresh_token_reused", "invalid_credentials", "invalid_password", "invalid_setup_token", "bad_signature", "request_expired"},
+	http.StatusForbidden:             {"insufficient_permissions", "protected_user", "last_admin", "already_setup", "forbidden", "invalid_signature", "token_not_allowed", "insufficient_scope", "ip_denied", "self_approval", "policy_denied"},
+	http.StatusNotFound:              {"not_found", "product_not_found", "role_not_found", "permission_not_found", "quotas_disabled"},
+	http.StatusConflict:              {"duplicate_entry", "duplicate_username", "duplicate_barcode", "duplicate_slug", "duplicate_permission", "duplicate_permission_name", "permission_already_assigned", "permission_in_use", "role_in_use", "product_already_in_order", "sync_in_progress", "status_in_use", "request_replayed", "backup_in_progress", "backup_not_restorable", "maintenance_required", "demo_data_exists", "job_running", "controlled_order_approved", "approval_required", "order_not_cancellable"},
+	http.StatusRequestEntityTooLarge: {"file_too_large"},
+	http.StatusUnsupportedMediaType:  {"unsupported_file_type"},
+	http.StatusUnprocessableEntity: {"validation_error", "invalid_product", "invalid_role", "invalid_category",
+		"invalid_dosage_form", "invalid_department", "invalid_requester", "invalid_supplier", "invalid_order_item", "cancel_required", "invalid_status", "missing_required_field", "password_mismatch",
+		"weak_password", "foreign_key_violation", "constraint_violation", "product_in_staging", "empty_order",
+		"batch_settled", "invalid_cidr", "config_reload_failed", "nothing_to_import", "invalid_definition", "invalid_rbac_document", "confirmation_mismatch", "invalid_dataset", "reason_required"},
+	http.StatusTooManyRequests:     {"rate_limited", "ip_banned", "ip_temporarily_banned", "tenant_quota_exceeded", "order_quota_exceeded"},
+	http.StatusInternalServerError: {"db_error", "database_error", "internal_error", "hash_error", "token_error", "storage_error", "export_error"},
+	http.StatusBadGateway:          {"storage_error", "printer_error", "erp_push_failed"},
+	http.StatusServiceUnavailable:  {"maintenance", "database_unavailable", "storage_unavailable", "email_not_configured", "alerts_not_configured", "printing_not_configured", "erp_not_configured", "erp_push_not_configured"},
+	http.StatusGatewayTimeout:      {"database_timeout"},
+}
+
+// Path parameters that hold integer IDs; everything else is a string
+var (
+	integerIDPath   = regexp.MustCompile(`^/api/v1/(categories|dosage_forms|roles|permissions)/`)
+	integerIDParams = map[string]bool{"role_id": true, "permission_id": true}
+	echoPathParam   = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+	closureSuffix   = regexp.MustCompile(`\.func\d+$`)
+)
+
+// GetOpenAPISpec handles GET /api/v1/openapi.json
+func (s *Server) GetOpenAPISpec(c echo.Context) error {
+	spec, err := s.openAPIDocument()
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "internal_error",
+			"Failed to build the API specification.")
+	}
+	return c.JSONBlob(http.StatusOK, spec)
+}
+
+// GetAPIDocs handles GET /api/v1/docs. The page holds no API data; it signs
+// in and fetches the spec with the resulting token.
+func (s *Server) GetAPIDocs(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentSecurityPolicy, swaggerCSP)
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.HTMLBlob(http.StatusOK, swaggerPage)
+}
+
+// swaggerFiles returns the Swagger UI files served under
+// /api/v1/docs/assets. A build without the release still serves the
+// page's script, which says so.
+func (s *Server) swaggerFiles() fs.FS {
+	files, _ := fs.Sub(swaggerAssets, "static/swagger-ui") // a valid, embedded directory
+	if _, err := fs.Stat(files, swaggerBundle); err != nil {
+		s.logger.Error("Swagger UI is missing from the build; run make swagger-ui", err, nil)
+	}
+	return files
+}
+
+// serveAPIDocsAssets returns the handler for GET /api/v1/docs/assets/*,
+// the Swagger UI files the docs page loads
+func (s *Server) serveAPIDocsAssets(files fs.FS) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name := path.Clean(c.Param("*"))
+		if name == "VERSION" || !fs.ValidPath(name) {
+			return echo.ErrNotFound
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, frontendCacheDefault)
+		return serveFrontendFile(c, files, name)
+	}
+}
+
+// openAPIDocument builds the spec once; routes do not change after startup
+func (s *Server) openAPIDocument() ([]byte, error) {
+	s.openAPIOnce.Do(func() {
+		s.openAPISpec, s.openAPIErr = json.Marshal(buildOpenAPI(s.router.Routes()))
+	})
+	return s.openAPISpec, s.openAPIErr
+}
+
+// buildOpenAPI describes every /api/v1 route in routes
+func buildOpenAPI(routes []*echo.Route) map[string]any {
+	schemas := map[string]any{
+		"ErrorResponse": map[string]any{
+			"type":     "object",
+			"required": []string{"error"},
+			"properties": map[string]any{
+				"error":   map[string]any{"type": "string", "description": "Machine-readable error code"},
+				"message": map[string]any{"type": "string", "description": "The error in the user's language (Accept-Language or PUT /auth/language)"},
+				"details": map[string]any{"description": "Human-readable explanation"},
+				"errors": map[string]any{
+					"type":        "array",
+					"description": "Every rule the request breaks, on 422 responses",
+					"items": map[string]any{
+						"type":     "object",
+						"required": []string{"code", "message"},
+						"properties": map[string]any{
+							"field":   map[string]any{"type": "string", "description": "The field at fault, as sent"},
+							"code":    map[string]any{"type": "string"},
+							"message": map[string]any{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+	b := &schemaBuilder{schemas: schemas}
+
+	paths := map[string]map[string]any{}
+	for _, route := range routes {
+		if route.Method == echo.RouteNotFound || strings.Contains(route.Path, "*") ||
+			!strings.HasPrefix(route.Path, openAPIPrefix+"/") {
+			continue
+		}
+		path := echoPathParam.ReplaceAllString(route.Path, "{$1}")
+		if path == openAPIPrefix+"/openapi.json" || path == openAPIPrefix+"/docs" {
+			continue
+		}
+
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(route.Method)] = b.operation(route, path)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "DigiOrder API",
+			"version": "1",
+			"description": "Pharmacy ordering API. Successful responses are wrapped as " +
+				"`{\"data\": ..., \"warning\": ...}`, errors as `{\"error\": \"<code>\", \"details\": ...}`. " +
+				"Send `Accept-Version` or use the /api/v1 prefix to pin this version. " +
+				"Version 2 adds `meta` (request_id, duration_ms and, for lists, pagination) and " +
+				"`links` (self, next, prev) to every successful response.",
+		},
+		"servers": []map[string]any{{"url": "/"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas":   schemas,
+			"responses": errorResponses(),
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT",
+					"description": "A login JWT or a personal access token (dgo_pat_...) from /api/v1/auth/tokens"},
+				"apiKeyAuth": map[string]any{"type": "apiKey", "in": "header", "name": middleware.HeaderAPIKey,
+					"description": "An API key (dgo_key_...) from /api/v1/admin/api-keys"},
+			},
+		},
+		"security": []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}},
+	}
+}
+
+// errorResponses documents each error status once, listing its codes
+func errorResponses() map[string]any {
+	responses := map[string]any{}
+	for code, codes := range apiErrorCodes {
+		responses[errorResponseName(code)] = map[string]any{
+			"description": http.StatusText(code) + ". Error codes: " + strings.Join(codes, ", "),
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/ErrorResponse"}},
+			},
+		}
+	}
+	return responses
+}
+
+// errorResponseName turns 404 into "NotFound"
+func errorResponseName(code int) string {
+	return strings.ReplaceAll(http.StatusText(code), " ", "")
+}
+
+// schemaBuilder turns Go types into JSON schemas, registering named structs
+// as components
+type schemaBuilder struct {
+	schemas map[string]any
+}
+
+func (b *schemaBuilder) operation(route *echo.Route, path string) map[string]any {
+	meta, documented := apiOperations[route.Method+" "+path]
+	if meta.Tag == "" {
+		meta.Tag = "Other"
+	}
+
+	op := map[string]any{
+		"operationId": operationID(route),
+		"tags":        []string{meta.Tag},
+		"summary":     meta.Summary,
+	}
+	if !documented {
+		op["summary"] = route.Method + " " + path
+	}
+	if len(meta.Roles) > 0 {
+		op["description"] = "Requires role: " + strings.Join(meta.Roles, " or ") + "."
+	}
+	if meta.Public {
+		op["security"] = []any{}
+	}
+
+	var params []map[string]any
+	for _, match := range echoPathParam.FindAllStringSubmatch(route.Path, -1) {
+		schema := map[string]any{"type": "string"}
+		if integerIDParams[match[1]] || (match[1] == "id" && integerIDPath.MatchString(path)) {
+			schema = map[string]any{"type": "integer", "format": "int32"}
+		}
+		params = append(params, map[string]any{"name": match[1], "in": "path", "required": true, "schema": schema})
+	}
+	for _, q := range meta.Query {
+		param := map[string]any{"name": q.Name, "in": "query", "schema": map[string]any{"type": q.Type}}
+		if q.Description != "" {
+			param["description"] = q.Description
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if meta.Request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(meta.Request))},
+			},
+		}
+	}
+	if meta.Upload != "" {
+		op["requestBody"] = map[string]any{
+			"content": map[string]any{
+				"multipart/form-data": map[string]any{"schema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						meta.Upload: map[string]any{"type": "string", "format": "binary"},
+					},
+				}},
+			},
+		}
+	}
+
+	status := meta.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := map[string]any{}
+	if status == http.StatusNoContent {
+		responses[strconv.Itoa(status)] = map[string]any{"description": "Deleted"}
+	} else if meta.Bare != "" {
+		body := map[string]any{"type": "object", "additionalProperties": true}
+		if meta.Response != nil {
+			body = b.schema(reflect.TypeOf(meta.Response))
+		}
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content":     map[string]any{meta.Bare: map[string]any{"schema": body}},
+		}
+	} else {
+		data := map[string]any{"type": "object", "additionalProperties": true}
+		if meta.Response != nil {
+			data = b.schema(reflect.TypeOf(meta.Response))
+		}
+		properties := map[string]any{
+			"data":    data,
+			"warning": map[string]any{"type": "string", "description": "Deprecation notice, if any"},
+		}
+		if meta.Paged {
+			properties["meta"] = b.schema(reflect.TypeOf(PageMeta{}))
+		}
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{
+					"type":       "object",
+					"required":   []string{"data"},
+					"properties": properties,
+				}},
+			},
+		}
+	}
+	for code := range apiErrorCodes {
+		if meta.Public && code == http.StatusForbidden {
+			continue
+		}
+		responses[strconv.Itoa(code)] = map[string]any{"$ref": "#/components/responses/" + errorResponseName(code)}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// operationID derives a stable ID from the handler name, e.g.
+// ".../server.(*Server).CreateProduct-fm" -> "CreateProduct"
+func operationID(route *echo.Route) string {
+	name := strings.TrimSuffix(route.Name, "-fm")
+	name = closureSuffix.ReplaceAllString(name, "")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Implements(optionalFieldType) {
+		prop := b.schema(reflect.Zero(t).Interface().(optionalField).elemType())
+		prop["nullable"] = true
+		return prop
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case uuidType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case rawType:
+		return map[string]any{"description": "Arbitrary JSON"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, seen := b.schemas[t.Name()]; !seen {
+			b.schemas[t.Name()] = nil // guards against recursive types
+			b.schemas[t.Name()] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema follows encoding/json: untagged exported fields keep their
+// Go name, which is how the sqlc models are serialized
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// Embedded structs are flattened into the outer object
+			embedded := b.structSchema(field.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			if r, ok := embedded["required"].([]string); ok {
+				required = append(required, r...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := b.schema(field.Type)
+		if applyValidateTag(prop, field.Tag.Get("validate")) && !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+		properties[name] = prop
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyValidateTag maps validator rules onto prop and reports whether the
+// field is required
+func applyValidateTag(prop map[string]any, tag string) bool {
+	if _, isRef := prop["$ref"]; isRef || tag == "" {
+		return strings.Contains(","+tag+",", ",required,")
+	}
+
+	isString := prop["type"] == "string"
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		n, numErr := strconv.Atoi(value)
+
+		switch {
+		case key == "required":
+			required = true
+		case key == "oneof":
+			prop["enum"] = strings.Fields(value)
+		case key == "email":
+			prop["format"] = "email"
+		case key == "uuid":
+			prop["format"] = "uuid"
+		case key == "e164":
+			prop["pattern"] = `^\+[1-9][0-9]{1,14}$`
+		case numErr != nil:
+		case key == "min" && isString:
+			prop["minLength"] = n
+		case key == "max" && isString:
+			prop["maxLength"] = n
+		case key == "min", key == "gte":
+			prop["minimum"] = n
+		case key == "max", key == "lte":
+			prop["maximum"] = n
+		case key == "gt":
+			prop["minimum"] = n + 1
+		}
+	}
+	return required
+}
@@ -0,0 +1,170 @@
+// internal/server/order_assignments.go - Assigning orders to staff
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/labstack/echo/v4"
+)
+
+// AssignOrderReq defines the request body for assigning an order
+type AssignOrderReq struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
+
+// OrderAssignee is the staff member handling an order
+type OrderAssignee struct {
+	OrderID    uuid.UUID  `json:"order_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Username   string     `json:"username"`
+	AssignedBy *uuid.UUID `json:"assigned_by,omitempty"`
+	AssignedAt time.Time  `json:"assigned_at"`
+}
+
+// AssignOrder handles PUT /api/v1/orders/:id/assignee. The assignee is
+// notified by email and push unless they assigned themselves.
+func (s *Server) AssignOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req AssignOrderReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	order, err := s.queries.GetOrder(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+	if order.DeletedAt.Valid {
+		return ErrNotFound.WithDetails("Order has been deleted").Send(c)
+	}
+	assignee, err := s.queries.GetUser(ctx, req.UserID)
+	if err != nil || assignee.DeletedAt.Valid {
+		return RespondError(c, http.StatusBadRequest, "invalid_user_id",
+			"The specified user does not exist.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var old, assignment db.OrderAssignment
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		old, _ = q.GetOrderAssignment(ctx, id)
+		var err error
+		assignment, err = q.AssignOrder(ctx, db.AssignOrderParams{
+			OrderID:    id,
+			UserID:     assignee.ID,
+			AssignedBy: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		return err
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order assignment")
+	}
+
+	var oldValues map[string]any
+	if old.UserID != uuid.Nil {
+		oldValues = map[string]any{"assignee": old.UserID.String()}
+	}
+	s.logAudit(ctx, userID, "assign", "order", id.String(),
+		oldValues, map[string]any{"assignee": assignee.ID.String()},
+		c.RealIP(), c.Request().UserAgent())
+
+	if assignee.ID != userID {
+		actor, _ := middleware.GetUsernameFromContext(c)
+		data := map[string]any{
+			"OrderID":    id.String(),
+			"AssignedBy": actor,
+			"Priority":   order.Priority,
+			"Notes":      order.Notes.String,
+		}
+		s.notifyUser(ctx, notify.EventOrderAssigned, assignee.ID, data)
+		s.pushUser(ctx, notify.EventOrderAssigned, assignee.ID, data)
+	}
+
+	return RespondSuccess(c, http.StatusOK, orderAssigneeResponse(assignment, assignee.Username))
+}
+
+// GetOrderAssignee handles GET /api/v1/orders/:id/assignee
+func (s *Server) GetOrderAssignee(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireOrder(c, id); !ok {
+		return err
+	}
+	assignment, err := s.queries.GetOrderAssignment(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order assignment")
+	}
+	assignee, err := s.queries.GetUser(ctx, assignment.UserID)
+	if err != nil {
+		return HandleDatabaseError(c, err, "User")
+	}
+
+	return RespondSuccess(c, http.StatusOK, orderAssigneeResponse(assignment, assignee.Username))
+}
+
+// UnassignOrder handles DELETE /api/v1/orders/:id/assignee
+func (s *Server) UnassignOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	var assignment db.OrderAssignment
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		assignment, err = q.UnassignOrder(ctx, id)
+		return err
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order assignment")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "unassign", "order", id.String(),
+		map[string]any{"assignee": assignment.UserID.String()}, nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func orderAssigneeResponse(a db.OrderAssignment, username string) OrderAssignee {
+	resp := OrderAssignee{
+		OrderID:    a.OrderID,
+		UserID:     a.UserID,
+		Username:   username,
+		AssignedAt: a.AssignedAt,
+	}
+	if a.AssignedBy.Valid {
+		resp.AssignedBy = &a.AssignedBy.UUID
+	}
+	return resp
+}
@@ -0,0 +1,144 @@
+// internal/server/order_cancel.go - Cancelling orders with a reason
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/labstack/echo/v4"
+)
+
+// cancelledStatus is the status CancelOrder puts an order in; other status
+// changes cannot
+const cancelledStatus = "cancelled"
+
+// CancelOrderReq defines the request body for cancelling an order. The
+// reason codes match the order_cancellations check constraint.
+type CancelOrderReq struct {
+	Reason string `json:"reason" validate:"required,oneof=no_longer_needed duplicate entered_in_error out_of_stock supplier_unavailable other"`
+	Note   string `json:"note" validate:"required,max=1000"`
+}
+
+// CancelledOrder is an order with why it was cancelled
+type CancelledOrder struct {
+	db.Order
+	Cancellation db.OrderCancellation `json:"cancellation"`
+}
+
+// errNotCancellable is returned in the cancel transaction for an order in
+// a status that cannot be cancelled
+var errNotCancellable = errors.New("order cannot be cancelled")
+
+// CancelOrder handles POST /api/v1/orders/:id/cancel. It cancels the order
+// with a reason code and a note, which are kept with the order and in its
+// status history, and notifies the user who created it. Fulfilled,
+// delivered and completed orders, and orders already cancelled or
+// rejected, cannot be cancelled. Orders do not reserve stock, so there is
+// none to release.
+func (s *Server) CancelOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req CancelOrderReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var old, order db.Order
+	var cancellation db.OrderCancellation
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		// Locked, so a concurrent cancel or status change waits and then
+		// sees this one
+		var err error
+		if old, err = q.GetOrderForUpdate(ctx, id); err != nil {
+			return err
+		}
+		if old.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+		// Closed orders were fulfilled, or already cancelled or rejected
+		if slices.Contains(closedOrderStatuses, old.Status) {
+			return errNotCancellable
+		}
+		order, err = q.UpdateOrderStatus(ctx, db.UpdateOrderStatusParams{
+			ID:     id,
+			Status: cancelledStatus,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.CreateOrderStatusChange(ctx, db.CreateOrderStatusChangeParams{
+			OrderID:   id,
+			OldStatus: old.Status,
+			NewStatus: order.Status,
+			ChangedBy: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+			Note:      sql.NullString{String: req.Reason + ": " + req.Note, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		cancellation, err = q.CreateOrderCancellation(ctx, db.CreateOrderCancellationParams{
+			OrderID:     id,
+			Reason:      req.Reason,
+			Note:        req.Note,
+			CancelledBy: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		if err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.OrderStatusChanged, order.ID.String(), outbox.OrderPayload(order))
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return RespondError(c, http.StatusNotFound, "not_found",
+			"Order with the specified ID was not found.")
+	case errors.Is(err, errNotCancellable):
+		return RespondError(c, http.StatusConflict, "order_not_cancellable",
+			fmt.Sprintf("The order is %s and can no longer be cancelled.", old.Status))
+	case err != nil:
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to cancel order.")
+	}
+
+	s.logAudit(ctx, userID, "cancel", "order", id.String(),
+		map[string]any{"status": old.Status},
+		map[string]any{"status": order.Status, "reason": req.Reason, "note": req.Note},
+		c.RealIP(), c.Request().UserAgent())
+
+	if order.CreatedBy.Valid && order.CreatedBy.UUID != userID {
+		actor, _ := middleware.GetUsernameFromContext(c)
+		data := map[string]any{
+			"OrderID":     order.ID.String(),
+			"CancelledBy": actor,
+			"Reason":      req.Reason,
+			"Note":        req.Note,
+		}
+		s.notifyUser(ctx, notify.EventOrderCancelled, order.CreatedBy.UUID, data)
+		s.pushUser(ctx, notify.EventOrderCancelled, order.CreatedBy.UUID, data)
+	}
+	s.notifyOrderStatus(c, order)
+
+	return RespondSuccess(c, http.StatusOK, CancelledOrder{Order: order, Cancellation: cancellation})
+}
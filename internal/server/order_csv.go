@@ -0,0 +1,190 @@
+// internal/server/order_csv.go - CSV download of orders with their items
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+// formatCSV is the ?format= value of a CSV download
+const formatCSV = "csv"
+
+// utf8BOM starts a CSV download so that spreadsheets read its Persian
+// text as UTF-8
+const utf8BOM = "\ufeff"
+
+// ExportOrdersCSV handles GET /api/v1/orders/export. It downloads the
+// orders ListOrders would list for the same filters, newest first, as
+// CSV with one row per item, or one for an order without items. Only the
+// orders the caller may see are read. With calendar=jalali from and to
+// are Jalali dates and the order times are added in the Jalali calendar.
+func (s *Server) ExportOrdersCSV(c echo.Context) error {
+	if format := c.QueryParam("format"); format != "" && format != formatCSV {
+		return RespondError(c, http.StatusBadRequest, "invalid_format", "format must be csv.")
+	}
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	filter, ok := s.parseOrderFilter(c, calendar)
+	if !ok {
+		return nil
+	}
+	withJalali := calendar == jalali.Jalali
+
+	header := []string{
+		"order_id", "status", "priority", "created_at", "submitted_at", "needed_by", "created_by",
+		"requester", "notes", "subtotal", "total",
+		"item_id", "product", "strength", "requested_qty", "unit", "unit_price", "line_total", "item_note",
+	}
+	if withJalali {
+		header = append(header, "created_at_jalali", "submitted_at_jalali")
+	}
+	return s.streamCSV(c, "orders", header, s.orderCSVSource(filter, withJalali))
+}
+
+// orderCSVSource reads the orders filter matches, newest first, a batch of
+// orders at a time, as one row of CSV cells per order item
+func (s *Server) orderCSVSource(filter orderFilter, withJalali bool) exportSource {
+	var after *pagination.Cursor
+	requester := s.requesterNames()
+	return func(ctx context.Context, limit int) ([][]any, bool, error) {
+		orders, err := filter.search(ctx, s.queries, pagination.Page{Limit: limit, After: after})
+		if err != nil || len(orders) == 0 {
+			return nil, false, err
+		}
+		ids := make([]uuid.UUID, len(orders))
+		for i, o := range orders {
+			ids[i] = o.ID
+		}
+		items, err := s.queries.ListOrderItemsWithProducts(ctx, ids)
+		if err != nil {
+			return nil, false, err
+		}
+		byOrder := make(map[uuid.UUID][]db.ListOrderItemsWithProductsRow, len(orders))
+		for _, item := range items {
+			byOrder[item.OrderID.UUID] = append(byOrder[item.OrderID.UUID], item)
+		}
+		creators, err := s.queries.ListOrderCreators(ctx, ids)
+		if err != nil {
+			return nil, false, err
+		}
+		usernames := make(map[uuid.UUID]string, len(creators))
+		for _, row := range creators {
+			usernames[row.OrderID] = row.Username
+		}
+
+		rows := make([][]any, 0, len(items)+len(orders))
+		for _, o := range orders {
+			var neededBy string
+			if o.NeededBy.Valid {
+				neededBy = o.NeededBy.Time.Format(time.DateOnly)
+			}
+			order := []any{
+				o.ID.String(), o.Status, o.Priority, formatNullTime(o.CreatedAt), formatNullTime(o.SubmittedAt),
+				neededBy, usernames[o.ID], requester(ctx, o.RequesterID), o.Notes.String, o.Subtotal, o.Total,
+			}
+			var jalaliTimes []any
+			if withJalali {
+				jalaliTimes = []any{s.jalaliTime(o.CreatedAt), s.jalaliTime(o.SubmittedAt)}
+			}
+
+			lines := byOrder[o.ID]
+			if len(lines) == 0 {
+				row := append(append([]any{}, order...), "", "", "", "", "", "", "", "")
+				rows = append(rows, append(row, jalaliTimes...))
+				continue
+			}
+			for _, item := range lines {
+				row := append(append([]any{}, order...),
+					item.ID.String(), item.ProductName.String, item.Strength.String,
+					strconv.Itoa(int(item.RequestedQty)), item.Unit.String,
+					item.UnitPrice.String, item.LineTotal.String, item.Note.String)
+				rows = append(rows, append(row, jalaliTimes...))
+			}
+		}
+		last := orders[len(orders)-1]
+		after = &pagination.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID}
+		return rows, len(orders) == limit, nil
+	}
+}
+
+// streamCSV writes the rows of source, whose cells are strings, as a CSV
+// file named after name, flushing the response after every batch. As with
+// streamXLSX, a failure reading the first batch is still reported as
+// JSON, and the rows written are sent as trailers.
+func (s *Server) streamCSV(c echo.Context, name string, header []string, source exportSource) error {
+	export, err := s.openExport(c.Request().Context(), source)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch "+name+" for export.")
+	}
+
+	filename := name + "-" + time.Now().In(s.reports.Calendar().Location).Format("20060102") + ".csv"
+	resp := c.Response()
+	s.startExport(c)
+	resp.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	resp.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	resp.Header().Set("Trailer", headerExportRows+", "+headerExportTruncated)
+	resp.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(resp)
+	_, err = resp.Write([]byte(utf8BOM))
+	if err == nil {
+		err = w.Write(header)
+	}
+	if err != nil {
+		export.close()
+		return s.csvFailed(name, err)
+	}
+	record := make([]string, len(header))
+	result, err := export.copy(func(row []any) error {
+		for i, cell := range row {
+			text, _ := cell.(string)
+			record[i] = csvCell(text)
+		}
+		return w.Write(record)
+	}, func() error {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		return http.NewResponseController(resp).Flush()
+	})
+	setExportResult(resp.Header(), result)
+	if result.Truncated != "" {
+		s.logger.Warn("CSV export truncated", map[string]any{"list": name, "rows": result.Rows, "limit": result.Truncated})
+	}
+	return s.csvFailed(name, err)
+}
+
+// csvFormulaPrefixes start cells spreadsheets would run as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell quotes a cell that starts like a formula with a leading ' so that
+// notes and names typed by users open as text, not as formulas
+func csvCell(text string) string {
+	if text != "" && strings.ContainsRune(csvFormulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// csvFailed logs an error that happened after the download started; the
+// response cannot be changed any more
+func (s *Server) csvFailed(name string, err error) error {
+	if err != nil {
+		s.logger.Error("CSV export failed", err, map[string]any{"list": name})
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// internal/server/order_duplicate.go - New draft orders copied from old ones
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/labstack/echo/v4"
+)
+
+// DuplicateOrder handles POST /api/v1/orders/:id/duplicate. It creates a
+// draft order for the caller with the priority, notes, requester and items
+// of the order, the items priced at the products' current prices and
+// routed to the same suppliers. Items of deleted products are left out.
+// Controlled items take the same permission as when they are added.
+func (s *Server) DuplicateOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	source, err := s.queries.GetOrder(ctx, id)
+	if err == nil && source.DeletedAt.Valid {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+	items, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: id, Valid: true})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order items.")
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID.UUID)
+	}
+	products, err := s.queries.GetProductsByIDs(ctx, productIDs)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch the products of the order.")
+	}
+	byID := make(map[uuid.UUID]db.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	var checks []controlledItem
+	for i, item := range items {
+		product, ok := byID[item.ProductID.UUID]
+		if !ok || product.DeletedAt.Valid {
+			continue
+		}
+		checks = append(checks, controlledItem{
+			product: product,
+			note:    item.Note.String,
+			field:   fmt.Sprintf("items[%d].note", i),
+			label:   fmt.Sprintf("Item %d: ", i+1),
+		})
+	}
+	if ok, err := s.requireControlledItems(c, uuid.Nil, checks); !ok {
+		return err
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var order db.Order
+	var copied int64
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		order, err = q.CreateOrder(ctx, db.CreateOrderParams{
+			CreatedBy:   uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+			Status:      "draft",
+			Notes:       source.Notes,
+			Priority:    source.Priority,
+			RequesterID: source.RequesterID,
+		})
+		if err != nil {
+			return err
+		}
+		copied, err = q.CopyOrderItems(ctx, db.CopyOrderItemsParams{
+			TargetOrderID: order.ID,
+			SourceOrderID: source.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if order, err = s.recalculateOrder(ctx, q, order.ID); err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.OrderCreated, order.ID.String(), outbox.OrderPayload(order))
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to duplicate order.")
+	}
+
+	if copied > 0 {
+		added, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: order.ID, Valid: true})
+		if err != nil {
+			s.logger.Error("Failed to load duplicated order items", err, map[string]any{"order_id": order.ID.String()})
+		} else {
+			s.checkInteractions(ctx, order.ID, added)
+		}
+	}
+
+	s.logAudit(ctx, userID, "duplicate", "order", order.ID.String(),
+		nil, map[string]any{
+			"source_order_id": source.ID.String(),
+			"items":           copied,
+			"left_out":        int64(len(items)) - copied,
+		},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, order)
+}
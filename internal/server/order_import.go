@@ -0,0 +1,226 @@
+// internal/server/order_import.go - Draft orders from supplier spreadsheets
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/orderimport"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/labstack/echo/v4"
+)
+
+// OrderImportItem is a product the imported order requests
+type OrderImportItem struct {
+	ProductID    uuid.UUID `json:"product_id"`
+	ProductName  string    `json:"product_name"`
+	Strength     string    `json:"strength,omitempty"`
+	RequestedQty int32     `json:"requested_qty"`
+	Unit         string    `json:"unit,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	MatchedBy    string    `json:"matched_by"`
+	Rows         []int     `json:"rows"`
+}
+
+// OrderImportRow is a sheet row that was left out of the order
+type OrderImportRow struct {
+	Row      int    `json:"row"`
+	Barcode  string `json:"barcode,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Quantity string `json:"quantity,omitempty"`
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail"`
+}
+
+// OrderImportResult is the draft order created from a sheet, or in dry-run
+// mode the order that would be created
+type OrderImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Order     *db.Order         `json:"order,omitempty"`
+	TotalRows int               `json:"total_rows"`
+	Items     []OrderImportItem `json:"items"`
+	Unmatched []OrderImportRow  `json:"unmatched"`
+}
+
+// ImportOrder handles POST /api/v1/orders/import. The "file" form field
+// holds a CSV or Excel (.xlsx) sheet with a header row; each row is matched
+// to a product by barcode, or by name and strength, and the matches become
+// a draft order. Rows that match nothing are listed in "unmatched". With
+// dry_run=true nothing is created. The optional "columns" field maps
+// fields to headers, e.g. {"barcode": "EAN", "quantity": "Order qty"}.
+func (s *Server) ImportOrder(c echo.Context) error {
+	dryRun, _ := strconv.ParseBool(c.FormValue("dry_run"))
+	priority := c.FormValue("priority")
+	if priority == "" {
+		priority = "routine"
+	}
+	if err := s.validator.Var(priority, "oneof=routine urgent stat"); err != nil {
+		return respondFieldError(c, "validation_error", "priority",
+			"Field 'priority' must be one of routine, urgent, stat.")
+	}
+
+	var columns orderimport.Columns
+	if raw := c.FormValue("columns"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &columns); err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_request",
+				"Field 'columns' must be a JSON object such as {\"barcode\": \"EAN\", \"quantity\": \"Qty\"}.")
+		}
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return RespondError(c, http.StatusBadRequest, "missing_file",
+			"Upload the sheet as multipart form field \"file\".")
+	}
+	limit := int64(s.config.Storage.MaxUploadMB) << 20
+	if header.Size > limit {
+		return RespondError(c, http.StatusRequestEntityTooLarge, "file_too_large",
+			fmt.Sprintf("Files may be at most %d MB.", s.config.Storage.MaxUploadMB))
+	}
+	data, err := readFormFile(header, limit)
+	if err != nil || len(data) == 0 {
+		return RespondError(c, http.StatusBadRequest, "invalid_file", "The uploaded file could not be read.")
+	}
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) && !strings.HasPrefix(http.DetectContentType(data), "text/plain") {
+		return RespondError(c, http.StatusUnsupportedMediaType, "unsupported_file_type",
+			"Upload a CSV or Excel (.xlsx) file; save older .xls workbooks as .xlsx first.")
+	}
+
+	records, err := orderimport.ReadSheet(data)
+	if err != nil {
+		return RespondError(c, http.StatusBadRequest, "invalid_file", err.Error())
+	}
+	rows, err := orderimport.ParseRows(records, columns)
+	if err != nil {
+		return RespondError(c, http.StatusBadRequest, "invalid_columns", err.Error())
+	}
+
+	ctx := c.Request().Context()
+	matched, err := orderimport.Match(ctx, s.queries, rows)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to match the sheet to products.")
+	}
+
+	result := orderImportResponse(matched, dryRun)
+	if dryRun {
+		return RespondSuccess(c, http.StatusOK, result)
+	}
+	if len(matched.Items) == 0 {
+		return RespondError(c, http.StatusUnprocessableEntity, "nothing_to_import",
+			fmt.Sprintf("None of the %d rows matched an orderable product; try dry_run=true to see why.", matched.Rows))
+	}
+	checks := make([]controlledItem, len(matched.Items))
+	for i, item := range matched.Items {
+		checks[i] = controlledItem{
+			product: item.Product,
+			note:    item.Note,
+			field:   "file",
+			label:   fmt.Sprintf("Row %d: ", item.Lines[0]),
+		}
+	}
+	if ok, err := s.requireControlledItems(c, uuid.Nil, checks); !ok {
+		return err
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	notes := c.FormValue("notes")
+	if notes == "" {
+		notes = "Imported from " + sanitizeFilename(header.Filename)
+	}
+
+	var order db.Order
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		order, err = q.CreateOrder(ctx, db.CreateOrderParams{
+			CreatedBy: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+			Status:    "draft",
+			Notes:     sql.NullString{String: notes, Valid: true},
+			Priority:  priority,
+		})
+		if err != nil {
+			return err
+		}
+		items := make([]db.NewOrderItem, len(matched.Items))
+		for i, item := range matched.Items {
+			items[i] = db.NewOrderItem{
+				ProductID:    item.Product.ID,
+				RequestedQty: item.Quantity,
+				Unit:         item.Unit,
+				Note:         item.Note,
+				UnitPrice:    item.Product.Price.String,
+			}
+		}
+		if _, err := db.BulkCreateOrderItems(ctx, q, order.ID, items, 0); err != nil {
+			return err
+		}
+		if order, err = s.recalculateOrder(ctx, q, order.ID); err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.OrderCreated, order.ID.String(), outbox.OrderPayload(order))
+	})
+	var batchErr *db.BatchError
+	if errors.As(err, &batchErr) {
+		first, last := matched.Items[batchErr.First].Lines[0], matched.Items[batchErr.Last].Lines[0]
+		s.logger.Error("Failed to add imported order items", err, map[string]any{"first_row": first, "last_row": last})
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			fmt.Sprintf("Failed to add the items of rows %d to %d; nothing was imported.", first, last))
+	}
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to create the imported order.")
+	}
+
+	s.logAudit(ctx, userID, "import", "order", order.ID.String(),
+		nil, map[string]any{
+			"file":      sanitizeFilename(header.Filename),
+			"rows":      matched.Rows,
+			"items":     len(matched.Items),
+			"unmatched": len(matched.Unmatched),
+		},
+		c.RealIP(), c.Request().UserAgent())
+
+	result.Order = &order
+	return RespondSuccess(c, http.StatusCreated, result)
+}
+
+func orderImportResponse(r *orderimport.Result, dryRun bool) OrderImportResult {
+	resp := OrderImportResult{
+		DryRun:    dryRun,
+		TotalRows: r.Rows,
+		Items:     make([]OrderImportItem, len(r.Items)),
+		Unmatched: make([]OrderImportRow, len(r.Unmatched)),
+	}
+	for i, item := range r.Items {
+		resp.Items[i] = OrderImportItem{
+			ProductID:    item.Product.ID,
+			ProductName:  item.Product.Name,
+			Strength:     item.Product.Strength.String,
+			RequestedQty: item.Quantity,
+			Unit:         item.Unit,
+			Note:         item.Note,
+			MatchedBy:    item.MatchedBy,
+			Rows:         item.Lines,
+		}
+	}
+	for i, row := range r.Unmatched {
+		resp.Unmatched[i] = OrderImportRow{
+			Row:      row.Line,
+			Barcode:  row.Barcode,
+			Name:     row.Name,
+			Quantity: row.Quantity,
+			Reason:   row.Reason,
+			Detail:   row.Detail,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateOrderStatusReq defines the request body for adding an order status.
+// Codes are lowercase letters, digits and underscores, starting with a
+// letter; the label is what people see.
+type CreateOrderStatusReq struct {
+	Code      string `json:"code" validate:"required,status_code"`
+	Label     string `json:"label" validate:"required,max=100"`
+	SortOrder int32  `json:"sort_order"`
+}
+
+// UpdateOrderStatusEntryReq defines the request body for relabelling an
+// order status. The code cannot change, as orders refer to it.
+type UpdateOrderStatusEntryReq struct {
+	Label     string `json:"label" validate:"required,max=100"`
+	SortOrder int32  `json:"sort_order"`
+}
+
+// InvalidStatusResponse is the error for an order status that is not in
+// the catalog, listing the ones that are
+type InvalidStatusResponse struct {
+	ErrorResponse
+	AllowedStatuses []string `json:"allowed_statuses"`
+}
+
+// ListOrderStatuses handles GET /api/v1/order-statuses
+func (s *Server) ListOrderStatuses(c echo.Context) error {
+	statuses, err := s.queries.ListOrderStatuses(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve order statuses.")
+	}
+
+	if statuses == nil {
+		statuses = []db.OrderStatus{}
+	}
+
+	return RespondSuccess(c, http.StatusOK, statuses)
+}
+
+// CreateOrderStatus handles POST /api/v1/order-statuses
+func (s *Server) CreateOrderStatus(c echo.Context) error {
+	var req CreateOrderStatusReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	status, err := s.queries.CreateOrderStatus(ctx, db.CreateOrderStatusParams{
+		Code:      req.Code,
+		Label:     req.Label,
+		SortOrder: req.SortOrder,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order status")
+	}
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "create", "order_status", status.Code,
+		nil, map[string]any{"label": status.Label, "sort_order": status.SortOrder},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, status)
+}
+
+// UpdateOrderStatusEntry handles PUT /api/v1/order-statuses/:code
+func (s *Server) UpdateOrderStatusEntry(c echo.Context) error {
+	var req UpdateOrderStatusEntryReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	code := c.Param("code")
+	old, err := s.queries.GetOrderStatus(ctx, code)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order status")
+	}
+	status, err := s.queries.RelabelOrderStatus(ctx, db.RelabelOrderStatusParams{
+		Code:      code,
+		Label:     req.Label,
+		SortOrder: req.SortOrder,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order status")
+	}
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "update", "order_status", code,
+		map[string]any{"label": old.Label, "sort_order": old.SortOrder},
+		map[string]any{"label": status.Label, "sort_order": status.SortOrder},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, status)
+}
+
+// DeleteOrderStatus handles DELETE /api/v1/order-statuses/:code. A status
+// that orders are in cannot be deleted.
+func (s *Server) DeleteOrderStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	code := c.Param("code")
+	deleted, err := s.queries.DeleteOrderStatus(ctx, code)
+	if pqErr, ok := db.AsPgError(err); ok && pqErr.Code == "23503" {
+		return RespondError(c, http.StatusConflict, "status_in_use",
+			fmt.Sprintf("Orders are in status %q; move them to another status first.", code))
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order status")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "Order status not found.")
+	}
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "delete", "order_status", code,
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// requireOrderStatus responds with invalid_status, and the statuses that
+// may be used, unless status is in the catalog
+func (s *Server) requireOrderStatus(c echo.Context, status string) (bool, error) {
+	ctx := c.Request().Context()
+	_, err := s.queries.GetOrderStatus(ctx, status)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, HandleDatabaseError(c, err, "Order status")
+	}
+
+	statuses, err := s.queries.ListOrderStatuses(ctx)
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Order status")
+	}
+	allowed := make([]string, len(statuses))
+	for i, st := range statuses {
+		allowed[i] = st.Code
+	}
+	details := fmt.Sprintf("Status %q is not one of: %s.", status, strings.Join(allowed, ", "))
+	return false, c.JSON(http.StatusUnprocessableEntity, InvalidStatusResponse{
+		ErrorResponse: ErrorResponse{
+			Error:   "invalid_status",
+			Message: localizedMessage(c, "invalid_status"),
+			Details: details,
+			Errors:  []FieldError{{Field: "status", Code: "invalid_status", Message: details}},
+		},
+		AllowedStatuses: allowed,
+	})
+}
@@ -2,60 +2,114 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
-	"strconv"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
 	"github.com/labstack/echo/v4"
 )
 
 // CreateOrderReq defines the request body for creating an order
 type CreateOrderReq struct {
-	CreatedBy string `json:"created_by,omitempty"`
-	Status    string `json:"status" validate:"required"`
-	Notes     string `json:"notes,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	Status      string     `json:"status" validate:"required"`
+	Notes       string     `json:"notes,omitempty"`
+	Priority    string     `json:"priority,omitempty" validate:"omitempty,oneof=routine urgent stat"`
+	NeededBy    string     `json:"needed_by,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	RequesterID *uuid.UUID `json:"requester_id,omitempty"` // the patient or department the order is for
 }
 
-// UpdateOrderStatusReq defines the request for updating order status
+// UpdateOrderNeededByReq sets the delivery deadline of an order; null
+// clears it
+type UpdateOrderNeededByReq struct {
+	NeededBy *string `json:"needed_by" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// UpdateOrderStatusReq defines the request for updating order status;
+// note is kept in the order's status history and with the approval of an
+// order with controlled substances
 type UpdateOrderStatusReq struct {
 	Status string `json:"status" validate:"required"`
+	Note   string `json:"note,omitempty" validate:"omitempty,max=1000"`
+}
+
+// OrderStatusChange is one entry in an order's status history
+type OrderStatusChange struct {
+	ID                int64      `json:"id"`
+	OldStatus         string     `json:"old_status"`
+	NewStatus         string     `json:"new_status"`
+	ChangedBy         *uuid.UUID `json:"changed_by,omitempty"`
+	ChangedByUsername string     `json:"changed_by_username,omitempty"`
+	Note              string     `json:"note,omitempty"`
+	ChangedAt         time.Time  `json:"changed_at"`
 }
 
 // CreateOrderItemReq defines the request for creating an order item
 // FIXED: Unit is now optional - will auto-populate from product
 type CreateOrderItemReq struct {
-	ProductID    string `json:"product_id" validate:"required"`
-	RequestedQty int32  `json:"requested_qty" validate:"required,gt=0"`
-	Unit         string `json:"unit,omitempty"` // Optional - auto-filled from product
-	Note         string `json:"note,omitempty"`
+	ProductID    string   `json:"product_id" validate:"required"`
+	RequestedQty int32    `json:"requested_qty" validate:"required,gt=0"`
+	Unit         string   `json:"unit,omitempty"` // Optional - auto-filled from product
+	Note         string   `json:"note,omitempty"`
+	UnitPrice    *float64 `json:"unit_price,omitempty" validate:"omitempty,gte=0,lt=1000000000000"` // the product's price when left out
 }
 
-// UpdateOrderItemReq defines the request for updating an order item
+// CreateOrderItemsReq is the body of a bulk item request
+type CreateOrderItemsReq struct {
+	Items []CreateOrderItemReq `json:"items" validate:"required,min=1,max=5000,dive"`
+}
+
+// UpdateOrderItemReq defines the request for updating an order item.
+// Fields left out keep their value; null clears the unit or note.
 type UpdateOrderItemReq struct {
-	RequestedQty int32  `json:"requested_qty" validate:"required,gt=0"`
-	Unit         string `json:"unit,omitempty"`
-	Note         string `json:"note,omitempty"`
+	RequestedQty *int32            `json:"requested_qty,omitempty" validate:"omitempty,gt=0"`
+	Unit         Optional[string]  `json:"unit,omitempty"`
+	Note         Optional[string]  `json:"note,omitempty"`
+	UnitPrice    Optional[float64] `json:"unit_price,omitempty" validate:"omitempty,gte=0,lt=1000000000000"`
 }
 
 // CreateOrder handles POST /api/v1/orders
 func (s *Server) CreateOrder(c echo.Context) error {
 	var req CreateOrderReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
+	}
+
+	if ok, err := s.requireOrderStatus(c, req.Status); !ok {
+		return err
 	}
 
 	ctx := c.Request().Context()
 
 	params := db.CreateOrderParams{
-		Status: req.Status,
-		Notes:  sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		Status:   req.Status,
+		Notes:    sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		Priority: req.Priority,
+	}
+	if params.Priority == "" {
+		params.Priority = "routine"
+	}
+	if req.NeededBy != "" {
+		neededBy, _ := time.Parse(time.DateOnly, req.NeededBy)
+		params.NeededBy = sql.NullTime{Time: neededBy, Valid: true}
 	}
 
 	if req.CreatedBy != "" {
@@ -65,9 +119,30 @@ func (s *Server) CreateOrder(c echo.Context) error {
 				"Created by user ID is not a valid UUID.")
 		}
 		params.CreatedBy = uuid.NullUUID{UUID: createdByUUID, Valid: true}
+	} else if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		params.CreatedBy = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	// A caller who only sees their own orders could not see the order
+	if scope, ok := db.TenantFromContext(ctx); ok && scope.OwnerID != uuid.Nil && params.CreatedBy.UUID != scope.OwnerID {
+		return RespondError(c, http.StatusForbidden, "forbidden",
+			"You can only create orders of your own.")
+	}
+	if req.RequesterID != nil {
+		if ok, err := s.requireRequester(c, *req.RequesterID); !ok {
+			return err
+		}
+		params.RequesterID = uuid.NullUUID{UUID: *req.RequesterID, Valid: true}
 	}
 
-	order, err := s.queries.CreateOrder(ctx, params)
+	var order db.Order
+	err := s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		order, err = q.CreateOrder(ctx, params)
+		if err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.OrderCreated, order.ID.String(), outbox.OrderPayload(order))
+	})
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to create order.")
@@ -76,19 +151,23 @@ func (s *Server) CreateOrder(c echo.Context) error {
 	return RespondSuccess(c, http.StatusCreated, order)
 }
 
-// GetOrder handles GET /api/v1/orders/:id
+// GetOrder handles GET /api/v1/orders/:id. include=items,creator,requester
+// embeds the order's items, the user who created it and the patient or
+// department it is for.
 func (s *Server) GetOrder(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid UUID.")
+		return err
+	}
+	include, ok := parseInclude(c, orderIncludes)
+	if !ok {
+		return nil
 	}
 
 	ctx := c.Request().Context()
 	order, err := s.queries.GetOrder(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Order with the specified ID was not found.")
 		}
@@ -96,88 +175,319 @@ func (s *Server) GetOrder(c echo.Context) error {
 			"Failed to retrieve order.")
 	}
 
+	if include != nil {
+		included, err := s.includeOrders(ctx, []JalaliOrder{{Order: order}}, include)
+		if err != nil {
+			return RespondError(c, http.StatusInternalServerError, "db_error",
+				"Failed to retrieve the included resources.")
+		}
+		return RespondSuccess(c, http.StatusOK, included[0])
+	}
+
 	return RespondSuccess(c, http.StatusOK, order)
 }
 
-// ListOrders handles GET /api/v1/orders
+// ListOrders handles GET /api/v1/orders. With format=xlsx every matching
+// order is downloaded as an Excel sheet, newest first; otherwise sort
+// picks the order of the page. q finds orders by their notes or the
+// products and notes of their items, folding Persian spelling. from and to
+// (included) narrow the orders by creation and are dates or RFC 3339
+// times; with calendar=jalali the dates are Jalali and each order also has
+// its dates in the Jalali calendar. requester_id narrows them to the
+// orders for a requester, created_by (or user_id) to those a user created,
+// status to those in any of a comma-separated list of statuses, and
+// product_id to those holding an item for a product. Filters combine, and
+// include embeds related resources as in GetOrder.
 func (s *Server) ListOrders(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	limitStr := c.QueryParam("limit")
-	offsetStr := c.QueryParam("offset")
-	userID := c.QueryParam("user_id")
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
+	}
+	sortBy, ok := parseSort(c, &page, orderSortColumns)
+	if !ok {
+		return nil
+	}
+	include, ok := parseInclude(c, orderIncludes)
+	if !ok {
+		return nil
+	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 50
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
 	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	withJalali := calendar == jalali.Jalali
+	filter, ok := s.parseOrderFilter(c, calendar)
+	if !ok {
+		return nil
 	}
 
-	var orders []db.Order
+	// Only the search query takes a cursor, so a page after one, and an
+	// Excel download, which reads by cursor, go through it even without
+	// other filters
+	list := func(ctx context.Context, page pagination.Page) ([]db.Order, error) {
+		return s.queries.ListOrders(ctx, db.ListOrdersParams{
+			Limit:  int32(page.Limit),
+			Offset: int32(page.Offset),
+		})
+	}
+	if filter.searched() || page.After != nil || wantsXLSX(c) {
+		list = func(ctx context.Context, page pagination.Page) ([]db.Order, error) {
+			return filter.search(ctx, s.queries, page)
+		}
+	} else if filter.CreatedBy.Valid {
+		list = func(ctx context.Context, page pagination.Page) ([]db.Order, error) {
+			return s.queries.ListOrdersByUser(ctx, db.ListOrdersByUserParams{
+				CreatedBy: filter.CreatedBy,
+				Limit:     int32(page.Limit),
+				Offset:    int32(page.Offset),
+			})
+		}
+	}
 
+	if wantsXLSX(c) {
+		header := []string{"ID", "Status", "Priority", "Created By", "Requester", "Created At", "Submitted At", "Needed By", "Notes"}
+		if withJalali {
+			header = append(header, "Created At (Jalali)", "Submitted At (Jalali)", "Needed By (Jalali)")
+		}
+		requester := s.requesterNames()
+		return s.streamXLSX(c, "orders", header, keysetSource(list, orderCursor, func(ctx context.Context, o db.Order) []any {
+			var createdBy any
+			if o.CreatedBy.Valid {
+				createdBy = o.CreatedBy.UUID
+			}
+			row := []any{o.ID, o.Status, o.Priority, createdBy, requester(ctx, o.RequesterID),
+				s.xlsxTime(o.CreatedAt), s.xlsxTime(o.SubmittedAt), xlsxDate(o.NeededBy), o.Notes.String}
+			if withJalali {
+				row = append(row, s.jalaliTime(o.CreatedAt), s.jalaliTime(o.SubmittedAt), jalaliDate(o.NeededBy))
+			}
+			return row
+		}))
+	}
+
+	orders, err := list(withSort(ctx, sortBy, "id"), page)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch orders.")
+	}
+
+	if orders == nil {
+		orders = []db.Order{}
+	}
+	count := db.CountSearchOrdersParams{FromTime: filter.From, ToTime: filter.To, CreatedBy: filter.CreatedBy,
+		RequesterID: filter.RequesterID, Statuses: filter.Statuses, ProductID: filter.ProductID, Query: filter.Query}
+	var signature any
+	if filter.searched() || filter.CreatedBy.Valid {
+		signature = count
+	}
+	total := s.listTotal(ctx, "orders", signature, func(ctx context.Context) (int64, error) {
+		return s.queries.CountSearchOrders(ctx, count)
+	})
+	if include != nil {
+		rows := make([]JalaliOrder, len(orders))
+		if withJalali {
+			rows = s.jalaliOrders(orders)
+		} else {
+			for i, o := range orders {
+				rows[i].Order = o
+			}
+		}
+		included, err := s.includeOrders(ctx, rows, include)
+		if err != nil {
+			return RespondError(c, http.StatusInternalServerError, "db_error",
+				"Failed to retrieve the included resources.")
+		}
+		return respondPage(c, page, total, orders, orderCursor, included)
+	}
+	if withJalali {
+		return respondPage(c, page, total, orders, orderCursor, s.jalaliOrders(orders))
+	}
+
+	return respondPage(c, page, total, orders, orderCursor, orders)
+}
+
+// orderFilter narrows a list of orders; the fields that are set combine
+type orderFilter struct {
+	From        sql.NullTime
+	To          sql.NullTime
+	CreatedBy   uuid.NullUUID
+	RequesterID uuid.NullUUID
+	Statuses    []string
+	ProductID   uuid.NullUUID
+	Query       string
+}
+
+// parseOrderFilter reads the filters ListOrders takes, with from and to in
+// calendar. The error response is written here when a filter is not
+// valid.
+func (s *Server) parseOrderFilter(c echo.Context, calendar string) (orderFilter, bool) {
+	f := orderFilter{Query: strings.TrimSpace(c.QueryParam("q"))}
+	loc := s.reports.Calendar().Location
+	if raw := c.QueryParam("from"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, loc, false)
+		if !ok {
+			RespondError(c, http.StatusBadRequest, "invalid_range",
+				"from must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+			return f, false
+		}
+		f.From = sql.NullTime{Time: t, Valid: true}
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, loc, true)
+		if !ok {
+			RespondError(c, http.StatusBadRequest, "invalid_range",
+				"to must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+			return f, false
+		}
+		f.To = sql.NullTime{Time: t, Valid: true}
+	}
+
+	userID := c.QueryParam("created_by")
+	if userID == "" {
+		userID = c.QueryParam("user_id")
+	}
 	if userID != "" {
-		userUUID, err := uuid.Parse(userID)
+		id, err := uuid.Parse(userID)
 		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_user_id",
+			RespondError(c, http.StatusBadRequest, "invalid_user_id",
 				"The provided user ID is not a valid UUID.")
+			return f, false
 		}
-
-		orders, err = s.queries.ListOrdersByUser(ctx, db.ListOrdersByUserParams{
-			CreatedBy: uuid.NullUUID{UUID: userUUID, Valid: true},
-			Limit:     int32(limit),
-			Offset:    int32(offset),
-		})
+		f.CreatedBy = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if raw := c.QueryParam("requester_id"); raw != "" {
+		id, err := uuid.Parse(raw)
 		if err != nil {
-			return RespondError(c, http.StatusInternalServerError, "db_error",
-				"Failed to fetch orders.")
+			respondFieldError(c, "invalid_requester", "requester_id",
+				"requester_id is not a valid UUID.")
+			return f, false
 		}
-	} else {
-		orders, err = s.queries.ListOrders(ctx, db.ListOrdersParams{
-			Limit:  int32(limit),
-			Offset: int32(offset),
-		})
+		f.RequesterID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if raw := strings.TrimSpace(c.QueryParam("status")); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if ok, _ := s.requireOrderStatus(c, status); !ok {
+				return f, false
+			}
+			if !slices.Contains(f.Statuses, status) {
+				f.Statuses = append(f.Statuses, status)
+			}
+		}
+	}
+	if raw := c.QueryParam("product_id"); raw != "" {
+		id, err := uuid.Parse(raw)
 		if err != nil {
-			return RespondError(c, http.StatusInternalServerError, "db_error",
-				"Failed to fetch orders.")
+			RespondError(c, http.StatusBadRequest, "invalid_product_id",
+				"The provided product ID is not a valid UUID.")
+			return f, false
 		}
+		f.ProductID = uuid.NullUUID{UUID: id, Valid: true}
 	}
+	return f, true
+}
 
-	if orders == nil {
-		orders = []db.Order{}
-	}
+// searched reports whether the filter needs SearchOrders, which is
+// everything but created_by alone
+func (f orderFilter) searched() bool {
+	return f.From.Valid || f.To.Valid || f.RequesterID.Valid || f.Statuses != nil || f.ProductID.Valid || f.Query != ""
+}
 
-	return RespondSuccess(c, http.StatusOK, orders)
+// search lists a page of the orders the filter matches
+func (f orderFilter) search(ctx context.Context, q db.Querier, page pagination.Page) ([]db.Order, error) {
+	return q.SearchOrders(ctx, db.SearchOrdersParams{
+		FromTime:    f.From,
+		ToTime:      f.To,
+		CreatedBy:   f.CreatedBy,
+		RequesterID: f.RequesterID,
+		Statuses:    f.Statuses,
+		ProductID:   f.ProductID,
+		Query:       f.Query,
+		AfterTime:   page.AfterTime(),
+		AfterID:     page.AfterID(),
+		Limit:       int32(page.Limit),
+		Offset:      int32(page.Offset),
+	})
 }
 
 // UpdateOrderStatus handles PUT /api/v1/orders/:id/status
 func (s *Server) UpdateOrderStatus(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid UUID.")
+		return err
 	}
 
 	var req UpdateOrderStatusReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
+	}
+	if req.Status == cancelledStatus {
+		return respondFieldError(c, "cancel_required", "status",
+			"Cancel an order through POST /api/v1/orders/:id/cancel with a reason.")
+	}
+	if ok, err := s.requireOrderStatus(c, req.Status); !ok {
+		return err
+	}
+	controlled, ok, err := s.controlledStatusChange(c, id, req.Status)
+	if !ok {
+		return err
 	}
 
 	ctx := c.Request().Context()
-	order, err := s.queries.UpdateOrderStatus(ctx, db.UpdateOrderStatusParams{
-		ID:     id,
-		Status: req.Status,
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var old, order db.Order
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		// Locked, so the old status stays current until the change and a
+		// concurrent cancel cannot slip in between
+		var err error
+		if old, err = q.GetOrderForUpdate(ctx, id); err != nil {
+			return err
+		}
+		order, err = q.UpdateOrderStatus(ctx, db.UpdateOrderStatusParams{
+			ID:     id,
+			Status: req.Status,
+		})
+		if err != nil {
+			return err
+		}
+		if old.Status != order.Status {
+			err = q.CreateOrderStatusChange(ctx, db.CreateOrderStatusChangeParams{
+				OrderID:   id,
+				OldStatus: old.Status,
+				NewStatus: order.Status,
+				ChangedBy: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+				Note:      sql.NullString{String: req.Note, Valid: req.Note != ""},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := controlled.apply(c, q, id, req.Note); err != nil {
+			return err
+		}
+		// A reopened order is no longer cancelled
+		if old.Status == cancelledStatus {
+			if err := q.DeleteOrderCancellation(ctx, id); err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(c, q, outbox.OrderStatusChanged, order.ID.String(), outbox.OrderPayload(order))
 	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Order with the specified ID was not found.")
 		}
@@ -185,20 +495,140 @@ func (s *Server) UpdateOrderStatus(c echo.Context) error {
 			"Failed to update order status.")
 	}
 
+	// Approvals in the user activity report are counted from these entries
+	s.logAudit(ctx, userID, "update_status", "order", id.String(),
+		map[string]any{"status": old.Status},
+		map[string]any{"status": order.Status},
+		c.RealIP(), c.Request().UserAgent())
+	if controlled.approve {
+		s.logAudit(ctx, userID, "approve_controlled", "order", id.String(),
+			nil, map[string]any{"note": req.Note},
+			c.RealIP(), c.Request().UserAgent())
+	}
+
+	s.notifyOrderStatus(c, order)
+
 	return RespondSuccess(c, http.StatusOK, order)
 }
 
+// GetOrderStatusHistory handles GET /api/v1/orders/:id/history, every
+// status change of the order with who made it and when, oldest first
+func (s *Server) GetOrderStatusHistory(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.queries.GetOrder(ctx, id); err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+	rows, err := s.queries.ListOrderStatusChanges(ctx, id)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch the order's status history.")
+	}
+
+	history := make([]OrderStatusChange, len(rows))
+	for i, row := range rows {
+		history[i] = OrderStatusChange{
+			ID:                row.ID,
+			OldStatus:         row.OldStatus,
+			NewStatus:         row.NewStatus,
+			ChangedByUsername: row.ChangedByUsername.String,
+			Note:              row.Note.String,
+			ChangedAt:         row.ChangedAt,
+		}
+		if row.ChangedBy.Valid {
+			history[i].ChangedBy = &row.ChangedBy.UUID
+		}
+	}
+	return RespondSuccess(c, http.StatusOK, history)
+}
+
+// UpdateOrderNeededBy handles PUT /api/v1/orders/:id/needed-by. Open
+// orders with a deadline appear in the calendar feed.
+func (s *Server) UpdateOrderNeededBy(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateOrderNeededByReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	var neededBy sql.NullTime
+	if req.NeededBy != nil && *req.NeededBy != "" {
+		date, _ := time.Parse(time.DateOnly, *req.NeededBy)
+		neededBy = sql.NullTime{Time: date, Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	var old, order db.Order
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		if old, err = q.GetOrder(ctx, id); err != nil {
+			return err
+		}
+		order, err = q.UpdateOrderNeededBy(ctx, db.UpdateOrderNeededByParams{
+			ID:       id,
+			NeededBy: neededBy,
+		})
+		return err
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "order", id.String(),
+		map[string]any{"needed_by": dateValue(old.NeededBy)},
+		map[string]any{"needed_by": dateValue(order.NeededBy)},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, order)
+}
+
+// dateValue formats a DATE column for audit entries, nil when unset
+func dateValue(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.Format(time.DateOnly)
+}
+
 // DeleteOrder handles DELETE /api/v1/orders/:id
 func (s *Server) DeleteOrder(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid UUID.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	err = s.queries.DeleteOrder(ctx, id)
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		if err := q.DeleteOrder(ctx, id); err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.OrderDeleted, id.String(), outbox.DeletedData{ID: id.String()})
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to delete order.")
@@ -209,22 +639,21 @@ func (s *Server) DeleteOrder(c echo.Context) error {
 
 // CreateOrderItem handles POST /api/v1/orders/:order_id/items
 // FIXED: Auto-populates unit from product, prevents duplicates
+// The item comes back with any interaction warnings it raised.
 func (s *Server) CreateOrderItem(c echo.Context) error {
-	orderIDStr := c.Param("order_id")
-	orderID, err := uuid.Parse(orderIDStr)
+	orderID, err := ParseUUID(c, "order_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_order_id",
-			"The provided order ID is not a valid UUID.")
+		return err
 	}
 
 	var req CreateOrderItemReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	productID, err := uuid.Parse(req.ProductID)
@@ -238,26 +667,34 @@ func (s *Server) CreateOrderItem(c echo.Context) error {
 	// FIXED: Get product to auto-populate unit
 	product, err := s.queries.GetProduct(ctx, productID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "product_not_found",
 				"Product with the specified ID was not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to retrieve product.")
 	}
+	if product.Status == "staging" {
+		return respondFieldError(c, "product_in_staging", "product_id",
+			"This product was imported from the drug registry and must be approved before it can be ordered.")
+	}
+	ok, err := s.requireControlledItems(c, orderID, []controlledItem{{product: product, note: req.Note, field: "note"}})
+	if !ok {
+		return err
+	}
 
-	// FIXED: Check if product already exists in this order
-	existingItems, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: orderID, Valid: true})
+	// Check if product already exists in this order
+	exists, err := s.queries.OrderHasProduct(ctx, db.OrderHasProductParams{
+		OrderID:   uuid.NullUUID{UUID: orderID, Valid: true},
+		ProductID: uuid.NullUUID{UUID: productID, Valid: true},
+	})
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to check existing order items.")
 	}
-
-	for _, item := range existingItems {
-		if item.ProductID.UUID == productID {
-			return RespondError(c, http.StatusConflict, "product_already_in_order",
-				"This product already exists in the order. Please update its quantity instead of adding it again.")
-		}
+	if exists {
+		return RespondError(c, http.StatusConflict, "product_already_in_order",
+			"This product already exists in the order. Please update its quantity instead of adding it again.")
 	}
 
 	// FIXED: Use product unit if not provided
@@ -266,32 +703,221 @@ func (s *Server) CreateOrderItem(c echo.Context) error {
 		unit = product.Unit.String
 	}
 
-	orderItem, err := s.queries.CreateOrderItem(ctx, db.CreateOrderItemParams{
-		OrderID:      uuid.NullUUID{UUID: orderID, Valid: true},
-		ProductID:    uuid.NullUUID{UUID: productID, Valid: true},
-		RequestedQty: req.RequestedQty,
-		Unit:         sql.NullString{String: unit, Valid: unit != ""},
-		Note:         sql.NullString{String: req.Note, Valid: req.Note != ""},
+	var orderItem db.OrderItem
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		orderItem, err = q.CreateOrderItem(ctx, db.CreateOrderItemParams{
+			OrderID:      uuid.NullUUID{UUID: orderID, Valid: true},
+			ProductID:    uuid.NullUUID{UUID: productID, Valid: true},
+			RequestedQty: req.RequestedQty,
+			Unit:         sql.NullString{String: unit, Valid: unit != ""},
+			Note:         sql.NullString{String: req.Note, Valid: req.Note != ""},
+			UnitPrice:    itemPrice(req.UnitPrice, product),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = s.recalculateOrder(ctx, q, orderID)
+		return err
 	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to create order item.")
 	}
 
-	return RespondSuccess(c, http.StatusCreated, orderItem)
+	warnings := s.checkInteractions(ctx, orderID, []db.OrderItem{orderItem})
+	return RespondSuccess(c, http.StatusCreated, OrderItemWithWarnings{
+		OrderItem: orderItem,
+		Warnings:  warnings[orderItem.ID],
+	})
+}
+
+// CreateOrderItems handles POST /api/v1/orders/:order_id/items/bulk. Every
+// item is checked as by CreateOrderItem before any is added, and then all
+// are added in batches in one transaction, so either all or none are.
+// Repeats of a product in the list are merged into one item. Each item
+// comes back with any interaction warnings it raised.
+func (s *Server) CreateOrderItems(c echo.Context) error {
+	orderID, err := ParseUUID(c, "order_id")
+	if err != nil {
+		return err
+	}
+
+	var req CreateOrderItemsReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.queries.GetOrder(ctx, orderID); err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+
+	existing, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: orderID, Valid: true})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to check existing order items.")
+	}
+	seen := make(map[uuid.UUID]bool, len(existing)+len(req.Items))
+	for _, item := range existing {
+		seen[item.ProductID.UUID] = true
+	}
+
+	// A product listed more than once becomes one line with the quantities
+	// added up; positions are those of its first listing
+	var (
+		ids    []uuid.UUID
+		lines  []CreateOrderItemReq
+		pos    []int
+		lineOf = make(map[uuid.UUID]int, len(req.Items))
+	)
+	for i, item := range req.Items {
+		id, err := uuid.Parse(item.ProductID)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_product_id",
+				fmt.Sprintf("Item %d: the product ID is not a valid UUID.", i+1))
+		}
+		if j, ok := lineOf[id]; ok {
+			line := &lines[j]
+			if item.Unit != line.Unit || (item.UnitPrice != nil && line.UnitPrice != nil && *item.UnitPrice != *line.UnitPrice) {
+				return RespondError(c, http.StatusConflict, "product_already_in_order",
+					fmt.Sprintf("Item %d: this product is earlier in the list with another unit or price.", i+1))
+			}
+			if line.UnitPrice == nil {
+				line.UnitPrice = item.UnitPrice
+			}
+			if int64(line.RequestedQty)+int64(item.RequestedQty) > math.MaxInt32 {
+				return respondFieldError(c, "validation_error", fmt.Sprintf("items[%d].requested_qty", i),
+					fmt.Sprintf("Item %d: the total quantity of this product is too large.", i+1))
+			}
+			line.RequestedQty += item.RequestedQty
+			if item.Note != "" && item.Note != line.Note {
+				if line.Note != "" {
+					line.Note += "; "
+				}
+				line.Note += item.Note
+			}
+			continue
+		}
+		if seen[id] {
+			return RespondError(c, http.StatusConflict, "product_already_in_order",
+				fmt.Sprintf("Item %d: this product is already in the order.", i+1))
+		}
+		seen[id] = true
+		lineOf[id] = len(lines)
+		ids = append(ids, id)
+		lines = append(lines, item)
+		pos = append(pos, i)
+	}
+
+	found, err := s.queries.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to retrieve products.")
+	}
+	products := make(map[uuid.UUID]db.Product, len(found))
+	for _, product := range found {
+		products[product.ID] = product
+	}
+
+	items := make([]db.NewOrderItem, len(lines))
+	checks := make([]controlledItem, len(lines))
+	for i, item := range lines {
+		product, ok := products[ids[i]]
+		if !ok {
+			return RespondError(c, http.StatusNotFound, "product_not_found",
+				fmt.Sprintf("Item %d: product with the specified ID was not found.", pos[i]+1))
+		}
+		if product.Status == "staging" {
+			return respondFieldError(c, "product_in_staging", fmt.Sprintf("items[%d].product_id", pos[i]),
+				fmt.Sprintf("Item %d: this product was imported from the drug registry and must be approved before it can be ordered.", pos[i]+1))
+		}
+		unit := item.Unit
+		if unit == "" && product.Unit.Valid {
+			unit = product.Unit.String
+		}
+		items[i] = db.NewOrderItem{
+			ProductID:    ids[i],
+			RequestedQty: item.RequestedQty,
+			Unit:         unit,
+			Note:         item.Note,
+			UnitPrice:    itemPrice(item.UnitPrice, product).String,
+		}
+		checks[i] = controlledItem{
+			product: product,
+			note:    item.Note,
+			field:   fmt.Sprintf("items[%d].note", pos[i]),
+			label:   fmt.Sprintf("Item %d: ", pos[i]+1),
+		}
+	}
+	if ok, err := s.requireControlledItems(c, orderID, checks); !ok {
+		return err
+	}
+
+	var created []db.OrderItem
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		created, err = db.BulkCreateOrderItems(ctx, q, orderID, items, 0)
+		if err != nil {
+			return err
+		}
+		_, err = s.recalculateOrder(ctx, q, orderID)
+		return err
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	var batchErr *db.BatchError
+	if errors.As(err, &batchErr) {
+		s.logger.Error("Failed to add order items", err, map[string]any{"order_id": orderID.String()})
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			fmt.Sprintf("Failed to add items %d to %d; no item was added.", pos[batchErr.First]+1, pos[batchErr.Last]+1))
+	}
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to add order items.")
+	}
+
+	warnings := s.checkInteractions(ctx, orderID, created)
+	resp := make([]OrderItemWithWarnings, len(created))
+	for i, item := range created {
+		resp[i] = OrderItemWithWarnings{OrderItem: item, Warnings: warnings[item.ID]}
+	}
+	return RespondSuccess(c, http.StatusCreated, resp)
 }
 
 // GetOrderItems handles GET /api/v1/orders/:order_id/items
 func (s *Server) GetOrderItems(c echo.Context) error {
-	orderIDStr := c.Param("order_id")
-	orderID, err := uuid.Parse(orderIDStr)
+	orderID, err := ParseUUID(c, "order_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_order_id",
-			"The provided order ID is not a valid UUID.")
+		return err
+	}
+
+	page, ok := parseOffsetPage(c, pagination.MaxLimit)
+	if !ok {
+		return nil
 	}
 
 	ctx := c.Request().Context()
-	items, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: orderID, Valid: true})
+	order := uuid.NullUUID{UUID: orderID, Valid: true}
+	items, err := s.queries.ListOrderItems(ctx, db.ListOrderItemsParams{
+		OrderID: order,
+		Limit:   int32(page.Limit),
+		Offset:  int32(page.Offset),
+	})
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to fetch order items.")
@@ -301,37 +927,68 @@ func (s *Server) GetOrderItems(c echo.Context) error {
 		items = []db.OrderItem{}
 	}
 
-	return RespondSuccess(c, http.StatusOK, items)
+	total := s.listTotal(ctx, "order_items", orderID, func(ctx context.Context) (int64, error) {
+		return s.queries.CountOrderItems(ctx, order)
+	})
+	return respondOffsetPage(c, page, total, len(items), items)
 }
 
 // UpdateOrderItem handles PUT /api/v1/order_items/:id
 func (s *Server) UpdateOrderItem(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid UUID.")
+		return err
 	}
 
 	var req UpdateOrderItemReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
-	orderItem, err := s.queries.UpdateOrderItem(ctx, db.UpdateOrderItemParams{
-		ID:           id,
-		RequestedQty: req.RequestedQty,
-		Unit:         sql.NullString{String: req.Unit, Valid: req.Unit != ""},
-		Note:         sql.NullString{String: req.Note, Valid: req.Note != ""},
+	item, product, ok, err := s.orderItemProduct(c, id)
+	if !ok {
+		return err
+	}
+	note := item.Note.String
+	if req.Note.Set {
+		note = req.Note.Value
+	}
+	ok, err = s.requireControlledItems(c, item.OrderID.UUID, []controlledItem{{product: product, note: note, field: "note"}})
+	if !ok {
+		return err
+	}
+
+	params := db.UpdateOrderItemParams{ID: id}
+	if req.RequestedQty != nil {
+		params.RequestedQty = sql.NullInt32{Int32: *req.RequestedQty, Valid: true}
+	}
+	params.Unit, params.SetUnit = nullString(req.Unit)
+	params.Note, params.SetNote = nullString(req.Note)
+	params.UnitPrice, params.SetUnitPrice = nullPrice(req.UnitPrice)
+
+	var orderItem db.OrderItem
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		if orderItem, err = q.UpdateOrderItem(ctx, params); err != nil || !item.OrderID.Valid {
+			return err
+		}
+		_, err = s.recalculateOrder(ctx, q, item.OrderID.UUID)
+		return err
 	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Order item with the specified ID was not found.")
 		}
@@ -344,15 +1001,35 @@ func (s *Server) UpdateOrderItem(c echo.Context) error {
 
 // DeleteOrderItem handles DELETE /api/v1/order_items/:id
 func (s *Server) DeleteOrderItem(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	id, err := ParseUUID(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid UUID.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	err = s.queries.DeleteOrderItem(ctx, id)
+	item, product, ok, err := s.orderItemProduct(c, id)
+	if !ok {
+		return err
+	}
+	if product.IsControlled {
+		if ok, err := s.requireUnapproved(c, item.OrderID.UUID); !ok {
+			return err
+		}
+	}
+
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		if err := q.DeleteOrderItem(ctx, id); err != nil || !item.OrderID.Valid {
+			return err
+		}
+		_, err := s.recalculateOrder(ctx, q, item.OrderID.UUID)
+		return err
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to delete order item.")
@@ -360,3 +1037,65 @@ func (s *Server) DeleteOrderItem(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// orderItemProduct returns an order item and its product. Like the
+// require helpers, false means the response was written.
+func (s *Server) orderItemProduct(c echo.Context, id uuid.UUID) (db.OrderItem, db.Product, bool, error) {
+	ctx := c.Request().Context()
+	item, err := s.queries.GetOrderItem(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item, db.Product{}, false, RespondError(c, http.StatusNotFound, "not_found",
+				"Order item with the specified ID was not found.")
+		}
+		return item, db.Product{}, false, RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to retrieve order item.")
+	}
+	product, err := s.queries.GetProduct(ctx, item.ProductID.UUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return item, product, false, RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to retrieve product.")
+	}
+	return item, product, true, nil
+}
+
+// notifyOrderStatus emails and pushes to reviewers when an order is
+// submitted and emails the creator when it is approved. Any status change
+// on an urgent or stat order is pushed to staff; stat orders also page
+// on-call staff by SMS.
+func (s *Server) notifyOrderStatus(c echo.Context, order db.Order) {
+	actor, _ := middleware.GetUsernameFromContext(c)
+	ctx := c.Request().Context()
+
+	switch order.Status {
+	case "submitted":
+		data := map[string]any{
+			"OrderID":     order.ID.String(),
+			"SubmittedBy": actor,
+			"Notes":       order.Notes.String,
+		}
+		s.notifyRoles(ctx, notify.EventOrderSubmitted, []string{"admin", "pharmacist"}, data)
+		s.pushRoles(ctx, notify.EventOrderSubmitted, []string{"admin", "pharmacist"}, data)
+	case "approved":
+		if order.CreatedBy.Valid {
+			s.notifyUser(ctx, notify.EventOrderApproved, order.CreatedBy.UUID, map[string]any{
+				"OrderID":    order.ID.String(),
+				"ApprovedBy": actor,
+			})
+		}
+	}
+
+	if order.Priority == "urgent" || order.Priority == "stat" {
+		data := map[string]any{
+			"OrderID":  order.ID.String(),
+			"Status":   order.Status,
+			"Priority": order.Priority,
+			"Actor":    actor,
+			"Notes":    order.Notes.String,
+		}
+		s.pushRoles(ctx, notify.EventUrgentOrder, []string{"admin", "pharmacist"}, data)
+		if order.Priority == "stat" {
+			s.pageRoles(ctx, notify.EventUrgentOrder, []string{"admin", "pharmacist"}, data)
+		}
+	}
+}
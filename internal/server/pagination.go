@@ -0,0 +1,251 @@
+// internal/server/pagination.go - Keyset pagination of list endpoints
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+// PageMeta describes the page a list response holds: Offset rows were
+// skipped before it, none for a page reached by cursor, and HasMore tells
+// whether it was filled, so that another may follow. NextCursor, passed
+// back as cursor, fetches the page after it. Total counts the rows of
+// every page, unless it could not be counted; TotalEstimated marks a
+// total taken from table statistics.
+type PageMeta struct {
+	Limit          int    `json:"limit"`
+	Offset         int    `json:"offset"`
+	HasMore        bool   `json:"has_more"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	Total          *int64 `json:"total,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+}
+
+// parsePage reads the limit, offset and cursor query parameters. Limits
+// above the maximum are clamped, and a cursor replaces the offset. The
+// error response is written here when a parameter is not valid.
+func parsePage(c echo.Context) (pagination.Page, bool) {
+	page := pagination.Page{Limit: pagination.DefaultLimit}
+	if raw := c.QueryParam("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			RespondError(c, http.StatusBadRequest, "invalid_limit",
+				"limit must be a positive whole number.")
+			return page, false
+		}
+		page.Limit = pagination.ClampLimit(limit)
+	}
+	if raw := c.QueryParam("cursor"); raw != "" {
+		after, err := pagination.Decode(raw)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid_cursor",
+				"cursor must be the next_cursor of an earlier page.")
+			return page, false
+		}
+		page.After = &after
+		return page, true
+	}
+	if raw := c.QueryParam("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			RespondError(c, http.StatusBadRequest, "invalid_offset",
+				"offset must be a whole number, not negative.")
+			return page, false
+		}
+		page.Offset = offset
+	}
+	return page, true
+}
+
+// parseOffsetPage reads the limit, defaultLimit when not given, and the
+// offset of a list that has no cursor, refusing one. The error response
+// is written here when a parameter is not valid.
+func parseOffsetPage(c echo.Context, defaultLimit int) (pagination.Page, bool) {
+	if c.QueryParam("cursor") != "" {
+		RespondError(c, http.StatusBadRequest, "invalid_cursor",
+			"This list is paged with offset; it takes no cursor.")
+		return pagination.Page{}, false
+	}
+	page, ok := parsePage(c)
+	if ok && c.QueryParam("limit") == "" {
+		page.Limit = defaultLimit
+	}
+	return page, ok
+}
+
+// parseSort reads the sort query parameter against the columns a list
+// allows. A sorted page is paged by offset, so a cursor is refused with
+// it. The error response is written here when the sort is not valid.
+func parseSort(c echo.Context, page *pagination.Page, columns pagination.SortColumns) (pagination.Sort, bool) {
+	sort, err := pagination.ParseSort(c.QueryParam("sort"), columns)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_sort",
+			fmt.Sprintf("%s; sort by %s, with - before a field for descending order.",
+				strings.TrimPrefix(err.Error(), "invalid sort: "), strings.Join(columns.Names(), ", ")))
+		return nil, false
+	}
+	if sort != nil {
+		if page.After != nil {
+			RespondError(c, http.StatusBadRequest, "invalid_cursor",
+				"cursor cannot be combined with sort; page a sorted list with offset.")
+			return nil, false
+		}
+		page.Sorted = true
+	}
+	return sort, true
+}
+
+// withSort orders the list queries issued with the returned context by
+// sort, then by tieBreaker; without a sort they keep their default order
+func withSort(ctx context.Context, sort pagination.Sort, tieBreaker string) context.Context {
+	if sort == nil {
+		return ctx
+	}
+	return db.WithSort(ctx, sort.OrderBy(tieBreaker))
+}
+
+// Columns each list can be sorted by with ?sort=
+var (
+	orderSortColumns = pagination.SortColumns{
+		"created_at":   "created_at",
+		"submitted_at": "submitted_at",
+		"needed_by":    "needed_by",
+		"status":       "status",
+		"priority":     "priority",
+	}
+	productSortColumns = pagination.SortColumns{
+		"name":       "name",
+		"brand":      "brand",
+		"status":     "status",
+		"created_at": "created_at",
+	}
+	userSortColumns = pagination.SortColumns{
+		"username":   "username",
+		"full_name":  "full_name",
+		"created_at": "created_at",
+	}
+	permissionSortColumns = pagination.SortColumns{
+		"name":       "name",
+		"resource":   "resource",
+		"action":     "action",
+		"created_at": "created_at",
+	}
+	auditLogSortColumns = pagination.SortColumns{
+		"created_at":  "created_at",
+		"action":      "action",
+		"entity_type": "entity_type",
+		"user_id":     "user_id",
+	}
+)
+
+// listTotal counts the rows of a list of table through the server's
+// cached counter; filter is nil for the unfiltered list and otherwise the
+// parameters that set it apart. A failed count is logged and leaves the
+// total out rather than failing the page.
+func (s *Server) listTotal(ctx context.Context, table string, filter any, count func(context.Context) (int64, error)) *pagination.Total {
+	signature := ""
+	if filter != nil {
+		signature = fmt.Sprintf("%+v", filter)
+	}
+	total, err := s.counts.Count(ctx, table, signature, count)
+	if err != nil {
+		s.logger.Warn("Failed to count list total", map[string]any{"table": table, "error": err.Error()})
+		return nil
+	}
+	return &total
+}
+
+// respondPage writes data, built from rows, with the cursor of the page
+// after rows and the total of the list when known
+func respondPage[T any](c echo.Context, page pagination.Page, total *pagination.Total, rows []T, key func(T) pagination.Cursor, data any) error {
+	return respondPageOf(c, page, total, len(rows), pagination.Next(page, rows, key), data)
+}
+
+// respondOffsetPage writes data, a page of n rows of a list that has no
+// cursor and is paged by offset alone, with the total of the list when
+// known
+func respondOffsetPage(c echo.Context, page pagination.Page, total *pagination.Total, n int, data any) error {
+	// Like a sorted list, the next page is linked by offset
+	page.Sorted = true
+	return respondPageOf(c, page, total, n, "", data)
+}
+
+// respondPageOf writes data, a page of n rows, in the list envelope; next
+// is the cursor of the page after it, "" when there is none. A page reached
+// by offset has more after it when the exact total says so; otherwise, as
+// after a cursor, with an estimated total or with a cached total the page
+// already went past, when it is full.
+func respondPageOf(c echo.Context, page pagination.Page, total *pagination.Total, n int, next string, data any) error {
+	meta := PageMeta{
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		HasMore:    n == page.Limit,
+		NextCursor: next,
+	}
+	if total != nil {
+		meta.Total, meta.TotalEstimated = &total.Count, total.Estimated
+		if end := int64(page.Offset + n); !total.Estimated && page.After == nil && end <= total.Count {
+			meta.HasMore = end < total.Count
+		}
+	}
+	if !meta.HasMore {
+		meta.NextCursor = ""
+	}
+	return RespondList(c, data, meta, pageLinks(c, page, meta.NextCursor, meta.HasMore))
+}
+
+// pageLinks returns the requests for the pages after and before the one
+// asked for; full tells whether the page was filled, so a sorted list may
+// go on. Cursors only lead forward, so there is a page before only when
+// it was reached by offset.
+func pageLinks(c echo.Context, page pagination.Page, next string, full bool) Links {
+	var links Links
+	u := *c.Request().URL
+	if next != "" {
+		q := u.Query()
+		q.Del("offset")
+		q.Set("cursor", next)
+		u.RawQuery = q.Encode()
+		links.Next = u.RequestURI()
+	} else if page.Sorted && full {
+		q := u.Query()
+		q.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+		u.RawQuery = q.Encode()
+		links.Next = u.RequestURI()
+	}
+	if page.After == nil && page.Offset > 0 {
+		q := c.Request().URL.Query()
+		if prev := page.Offset - page.Limit; prev > 0 {
+			q.Set("offset", strconv.Itoa(prev))
+		} else {
+			q.Del("offset")
+		}
+		u.RawQuery = q.Encode()
+		links.Prev = u.RequestURI()
+	}
+	return links
+}
+
+// Positions of rows in the lists paginated by keyset
+func orderCursor(o db.Order) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: o.CreatedAt.Time, ID: o.ID}
+}
+
+func productCursor(p db.Product) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: p.CreatedAt.Time, ID: p.ID}
+}
+
+func userCursor(u db.User) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: u.CreatedAt.Time, ID: u.ID}
+}
+
+func auditLogCursor(l db.AuditLog) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: l.CreatedAt.Time, ID: l.ID}
+}
@@ -0,0 +1,90 @@
+// internal/server/params.go - Path parameters checked before handlers run
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// uuidParams declares the named path parameters of the routes it guards
+// as UUIDs; see pathParams
+func uuidParams(names ...string) echo.MiddlewareFunc {
+	return pathParams("UUID", names, func(raw string) (any, bool) {
+		id, err := uuid.Parse(raw)
+		return id, err == nil
+	})
+}
+
+// intParams declares the named path parameters of the routes it guards as
+// 32-bit integers; see pathParams
+func intParams(names ...string) echo.MiddlewareFunc {
+	return pathParams("number", names, func(raw string) (any, bool) {
+		id, err := strconv.ParseInt(raw, 10, 32)
+		return int32(id), err == nil
+	})
+}
+
+// pathParams parses each of the named parameters a route has, answering
+// 400 invalid_<name> for one that does not parse, so the handler never
+// runs. Parameters a route does not have are skipped, which lets a group
+// declare them for all its routes. ParseUUID and ParseInt return the
+// parsed values.
+func pathParams(kind string, names []string, parse func(string) (any, bool)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			for _, name := range c.ParamNames() {
+				if !slices.Contains(names, name) {
+					continue
+				}
+				value, ok := parse(c.Param(name))
+				if !ok {
+					return respondInvalidParam(c, name, kind)
+				}
+				c.Set(paramKey(name), value)
+			}
+			return next(c)
+		}
+	}
+}
+
+// paramKey is where pathParams keeps the parsed value of a parameter
+func paramKey(name string) string {
+	return "param:" + name
+}
+
+// respondInvalidParam answers a path parameter that is not a valid kind
+func respondInvalidParam(c echo.Context, name, kind string) error {
+	return RespondError(c, http.StatusBadRequest, "invalid_"+name,
+		fmt.Sprintf("The provided %s is not a valid %s.", name, kind))
+}
+
+// ParseUUID returns the UUID path parameter paramName, as parsed by
+// uuidParams or, on a route without it, parsed here
+func ParseUUID(c echo.Context, paramName string) (uuid.UUID, error) {
+	if id, ok := c.Get(paramKey(paramName)).(uuid.UUID); ok {
+		return id, nil
+	}
+	id, err := uuid.Parse(c.Param(paramName))
+	if err != nil {
+		return uuid.Nil, respondInvalidParam(c, paramName, "UUID")
+	}
+	return id, nil
+}
+
+// ParseInt returns the integer path parameter paramName, as parsed by
+// intParams or, on a route without it, parsed here
+func ParseInt(c echo.Context, paramName string) (int32, error) {
+	if id, ok := c.Get(paramKey(paramName)).(int32); ok {
+		return id, nil
+	}
+	id, err := strconv.ParseInt(c.Param(paramName), 10, 32)
+	if err != nil {
+		return 0, respondInvalidParam(c, paramName, "number")
+	}
+	return int32(id), nil
+}
@@ -0,0 +1,215 @@
+// internal/server/password_reset.go - Self-service password reset by emailed one-time tokens
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/security"
+	"github.com/labstack/echo/v4"
+)
+
+// passwordResetTTL is how long an emailed reset token works
+const passwordResetTTL = 30 * time.Minute
+
+// passwordResetTokenPrefix starts every password reset token
+const passwordResetTokenPrefix = "dgo_pr_"
+
+// PasswordResetRequestReq asks for a reset token for a username
+type PasswordResetRequestReq struct {
+	Username string `json:"username" validate:"required,max=100"`
+}
+
+// PasswordResetConfirmReq sets a new password with an emailed token
+type PasswordResetConfirmReq struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// RequestPasswordReset handles POST /api/v1/auth/password-reset/request.
+// A user with an email address is sent a one-time token; the response is
+// the same whether or not the username exists, so it cannot be used to
+// find accounts. Requests are limited per IP and per username.
+func (s *Server) RequestPasswordReset(c echo.Context) error {
+	var req PasswordResetRequestReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+	if !s.notifier.Enabled(notify.ChannelEmail) {
+		return RespondError(c, http.StatusServiceUnavailable, "email_not_configured",
+			"Password reset by email is not configured; ask an administrator to reset your password.")
+	}
+
+	ctx := c.Request().Context()
+	ip := c.RealIP()
+	limits := s.config.RateLimit
+	counts, err := s.queries.CountPasswordResetRequests(ctx, db.CountPasswordResetRequestsParams{
+		IpAddress: ip,
+		Username:  req.Username,
+		Since:     time.Now().Add(-limits.PasswordResetWindow),
+	})
+	if err != nil {
+		s.logger.Error("Failed to count password reset requests", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to process the request.")
+	}
+	if counts.ByIp >= int64(limits.PasswordResetPerIP) || counts.ByUsername >= int64(limits.PasswordResetPerUser) {
+		middleware.RecordRateLimitExceeded("/api/v1/auth/password-reset/request")
+		c.Response().Header().Set(middleware.HeaderRetryAfter, strconv.Itoa(int(limits.PasswordResetWindow.Seconds())))
+		return RespondError(c, http.StatusTooManyRequests, "rate_limited",
+			"Too many password reset requests. Please try again later.")
+	}
+	if err := s.queries.CreatePasswordResetRequest(ctx, db.CreatePasswordResetRequestParams{
+		Username:  req.Username,
+		IpAddress: ip,
+	}); err != nil {
+		s.logger.Error("Failed to record password reset request", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to process the request.")
+	}
+
+	accepted := func() error {
+		return RespondSuccess(c, http.StatusAccepted, map[string]string{
+			"message": "If the account exists and has an email address, a reset code was sent to it.",
+		})
+	}
+
+	user, err := s.queries.GetUserByUsername(ctx, req.Username)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (user.DeletedAt.Valid || user.ServiceAccount)) {
+		return accepted()
+	}
+	if err != nil {
+		s.logger.Error("Failed to look up user for password reset", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to process the request.")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to generate the reset token.")
+	}
+	token := passwordResetTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	expiresAt := time.Now().Add(passwordResetTTL)
+
+	// A new token supersedes any the user was sent before
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := q.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		_, err := q.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+			UserID:      user.ID,
+			TokenHash:   hashAccessToken(token),
+			RequestedIp: ip,
+			ExpiresAt:   expiresAt,
+		})
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to create password reset token", err, map[string]any{"user_id": user.ID})
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to process the request.")
+	}
+
+	s.logAudit(ctx, user.ID, "password_reset_request", "user", user.ID.String(),
+		nil, map[string]any{"expires_at": expiresAt}, ip, c.Request().UserAgent())
+
+	// Users without an email address get nothing; notifyUser skips them
+	s.notifyUser(ctx, notify.EventPasswordResetRequested, user.ID, map[string]any{
+		"Username":  user.Username,
+		"Token":     token,
+		"ExpiresAt": expiresAt.Format(time.RFC1123),
+		"IP":        ip,
+		"Time":      time.Now().Format(time.RFC1123),
+	})
+
+	return accepted()
+}
+
+// ConfirmPasswordReset handles POST /api/v1/auth/password-reset/confirm,
+// setting a new password with a token from RequestPasswordReset. The token
+// works once; the user's other reset tokens and refresh tokens are revoked
+// with it.
+func (s *Server) ConfirmPasswordReset(c echo.Context) error {
+	var req PasswordResetConfirmReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+	if err := security.ValidatePassword(req.NewPassword,
+		security.DefaultPasswordRequirements()); err != nil {
+		return respondWeakPassword(c, "new_password", req.NewPassword, err, nil)
+	}
+	hashedPassword, err := security.HashPassword(req.NewPassword)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "hash_error", "Failed to process password.")
+	}
+
+	ctx := c.Request().Context()
+	var user db.User
+	err = s.withTx(ctx, func(q db.Querier) error {
+		reset, err := q.UsePasswordResetToken(ctx, hashAccessToken(req.Token))
+		if err != nil {
+			return err
+		}
+		user, err = q.GetUser(ctx, reset.UserID)
+		if err != nil {
+			return err
+		}
+		if user.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+		if err := q.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hashedPassword,
+		}); err != nil {
+			return err
+		}
+		if err := q.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		_, err = q.RevokeUserRefreshTokens(ctx, user.ID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return RespondError(c, http.StatusBadRequest, "invalid_reset_token",
+			"The reset code is not valid, was already used or has expired.")
+	}
+	if err != nil {
+		s.logger.Error("Failed to reset password", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to update password.")
+	}
+
+	s.logAudit(ctx, user.ID, "password_reset", "user", user.ID.String(),
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+	s.notifyUser(ctx, notify.EventPasswordReset, user.ID, map[string]any{
+		"Username": user.Username,
+		"IP":       c.RealIP(),
+		"Time":     time.Now().Format(time.RFC1123),
+	})
+
+	return RespondSuccess(c, http.StatusOK, map[string]string{
+		"message": "Password updated successfully",
+	})
+}
+
+// prunePasswordResets deletes expired reset tokens, and reset requests
+// past the rate limit window and at least a day old
+func (s *Server) prunePasswordResets(ctx context.Context) error {
+	now := time.Now()
+	if _, err := s.queries.DeleteExpiredPasswordResetTokens(ctx, now.Add(-24*time.Hour)); err != nil {
+		return err
+	}
+	keep := max(s.config.RateLimit.PasswordResetWindow, 24*time.Hour)
+	_, err := s.queries.DeletePasswordResetRequestsBefore(ctx, now.Add(-keep))
+	return err
+}
@@ -0,0 +1,85 @@
+// internal/server/patch.go - Update request fields that can be cleared
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+)
+
+// Optional is an update request field that tells a field left out of the
+// body, which keeps its stored value, from one given as null, which clears
+// it. A plain or pointer field cannot tell the two apart.
+type Optional[T any] struct {
+	Set   bool // the field was in the body
+	Null  bool // the field was null
+	Value T
+}
+
+// UnmarshalJSON is only called for fields present in the body
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// MarshalJSON writes the value, or null when it is unset or null
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set || o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// validated returns the value the field's validate tag checks, nil when
+// there is none. It is a pointer so that omitempty still checks zero.
+func (o Optional[T]) validated() any {
+	if !o.Set || o.Null {
+		return nil
+	}
+	return &o.Value
+}
+
+// elemType is the type the API documents for the field
+func (o Optional[T]) elemType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// optionalField is implemented by every Optional
+type optionalField interface {
+	validated() any
+	elemType() reflect.Type
+}
+
+var optionalFieldType = reflect.TypeFor[optionalField]()
+
+// validateOptional lets validate tags on Optional fields check the value;
+// with omitempty, unset and null fields pass
+func validateOptional(field reflect.Value) any {
+	if o, ok := field.Interface().(optionalField); ok {
+		return o.validated()
+	}
+	return nil
+}
+
+// nullString returns the value to store for a string field and whether it
+// is to be stored at all. Null and the empty string both clear the field.
+func nullString(o Optional[string]) (sql.NullString, bool) {
+	return sql.NullString{String: o.Value, Valid: !o.Null && o.Value != ""}, o.Set
+}
+
+// nullInt32 returns the value to store for an integer field and whether
+// it is to be stored at all
+func nullInt32(o Optional[int32]) (sql.NullInt32, bool) {
+	return sql.NullInt32{Int32: o.Value, Valid: !o.Null}, o.Set
+}
+
+// nullPrice returns the value to store for a price field and whether it
+// is to be stored at all
+func nullPrice(o Optional[float64]) (sql.NullString, bool) {
+	return sql.NullString{String: priceString(o.Value), Valid: !o.Null}, o.Set
+}
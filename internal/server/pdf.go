@@ -0,0 +1,21 @@
+// internal/server/pdf.go - Fonts for generated PDFs
+package server
+
+import (
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/pdf"
+)
+
+// newPDFFonts loads the configured PDF fonts, falling back to the
+// standard fonts when they cannot be read
+func newPDFFonts(cfg config.PDFConfig, logger *logging.Logger) pdf.Fonts {
+	fonts, err := pdf.LoadFonts(cfg.Font, cfg.BoldFont)
+	if err != nil {
+		logger.Error("Failed to load PDF fonts, using Helvetica", err, map[string]any{
+			"font":      cfg.Font,
+			"bold_font": cfg.BoldFont,
+		})
+	}
+	return fonts
+}
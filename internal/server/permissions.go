@@ -3,13 +3,16 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
 	"github.com/labstack/echo/v4"
 )
 
@@ -33,12 +36,12 @@ type UpdatePermissionReq struct {
 func (s *Server) CreatePermission(c echo.Context) error {
 	var req CreatePermissionReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -84,30 +87,30 @@ func (s *Server) CreatePermission(c echo.Context) error {
 func (s *Server) ListPermissions(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	limitStr := c.QueryParam("limit")
-	offsetStr := c.QueryParam("offset")
 	resource := c.QueryParam("resource")
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 100
+	page, ok := parseOffsetPage(c, pagination.MaxLimit)
+	if !ok {
+		return nil
 	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	sortBy, ok := parseSort(c, &page, permissionSortColumns)
+	if !ok {
+		return nil
 	}
+	listCtx := withSort(ctx, sortBy, "id")
 
 	var permissions []db.Permission
+	var err error
 	if resource != "" {
-		permissions, err = s.queries.ListPermissionsByResource(ctx, db.ListPermissionsByResourceParams{
+		permissions, err = s.queries.ListPermissionsByResource(listCtx, db.ListPermissionsByResourceParams{
 			Resource: resource,
-			Limit:    int32(limit),
-			Offset:   int32(offset),
+			Limit:    int32(page.Limit),
+			Offset:   int32(page.Offset),
 		})
 	} else {
-		permissions, err = s.queries.ListPermissions(ctx, db.ListPermissionsParams{
-			Limit:  int32(limit),
-			Offset: int32(offset),
+		permissions, err = s.queries.ListPermissions(listCtx, db.ListPermissionsParams{
+			Limit:  int32(page.Limit),
+			Offset: int32(page.Offset),
 		})
 	}
 
@@ -120,22 +123,27 @@ func (s *Server) ListPermissions(c echo.Context) error {
 		permissions = []db.Permission{}
 	}
 
-	return RespondSuccess(c, http.StatusOK, permissions)
+	var filter any
+	if resource != "" {
+		filter = resource
+	}
+	total := s.listTotal(ctx, "permissions", filter, func(ctx context.Context) (int64, error) {
+		return s.queries.CountPermissions(ctx, resource)
+	})
+	return respondOffsetPage(c, page, total, len(permissions), permissions)
 }
 
 // GetPermission handles GET /api/v1/permissions/:id
 func (s *Server) GetPermission(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	permission, err := s.queries.GetPermission(ctx, int32(id))
+	permission, err := s.queries.GetPermission(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Permission with the specified ID was not found.")
 		}
@@ -148,25 +156,23 @@ func (s *Server) GetPermission(c echo.Context) error {
 
 // UpdatePermission handles PUT /api/v1/permissions/:id
 func (s *Server) UpdatePermission(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid number.")
+		return err
 	}
 
 	var req UpdatePermissionReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	ctx := c.Request().Context()
 
 	// Get old values for audit
-	oldPermission, err := s.queries.GetPermission(ctx, int32(id))
+	oldPermission, err := s.queries.GetPermission(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Permission with the specified ID was not found.")
 		}
@@ -175,7 +181,7 @@ func (s *Server) UpdatePermission(c echo.Context) error {
 	}
 
 	params := db.UpdatePermissionParams{
-		ID: int32(id),
+		ID: id,
 	}
 
 	if req.Name != "" {
@@ -193,7 +199,7 @@ func (s *Server) UpdatePermission(c echo.Context) error {
 
 	permission, err := s.queries.UpdatePermission(ctx, params)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Permission with the specified ID was not found.")
 		}
@@ -214,6 +220,7 @@ func (s *Server) UpdatePermission(c echo.Context) error {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to update permission.")
 	}
+	s.roles.invalidate()
 
 	// Log audit
 	currentUserID, _ := middleware.GetUserIDFromContext(c)
@@ -234,19 +241,17 @@ func (s *Server) UpdatePermission(c echo.Context) error {
 
 // DeletePermission handles DELETE /api/v1/permissions/:id
 func (s *Server) DeletePermission(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id",
-			"The provided ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
 
 	// Get permission for audit before deletion
-	permission, err := s.queries.GetPermission(ctx, int32(id))
+	permission, err := s.queries.GetPermission(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found",
 				"Permission with the specified ID was not found.")
 		}
@@ -254,7 +259,7 @@ func (s *Server) DeletePermission(c echo.Context) error {
 			"Failed to retrieve permission.")
 	}
 
-	err = s.queries.DeletePermission(ctx, int32(id))
+	err = s.queries.DeletePermission(ctx, id)
 	if err != nil {
 		// Check if permission is in use
 		if strings.Contains(err.Error(), "foreign key") ||
@@ -265,6 +270,7 @@ func (s *Server) DeletePermission(c echo.Context) error {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to delete permission.")
 	}
+	s.roles.invalidate()
 
 	// Log audit
 	currentUserID, _ := middleware.GetUserIDFromContext(c)
@@ -280,11 +286,9 @@ func (s *Server) DeletePermission(c echo.Context) error {
 
 // AssignPermissionToRole handles POST /api/v1/roles/:role_id/permissions
 func (s *Server) AssignPermissionToRole(c echo.Context) error {
-	roleIDStr := c.Param("role_id")
-	roleID, err := strconv.ParseInt(roleIDStr, 10, 32)
+	roleID, err := ParseInt(c, "role_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_role_id",
-			"The provided role ID is not a valid number.")
+		return err
 	}
 
 	var req struct {
@@ -292,20 +296,20 @@ func (s *Server) AssignPermissionToRole(c echo.Context) error {
 	}
 
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
 
 	// Verify role exists
-	_, err = s.queries.GetRole(ctx, int32(roleID))
+	_, err = s.roles.role(ctx, roleID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "role_not_found",
 				"Role with the specified ID was not found.")
 		}
@@ -316,7 +320,7 @@ func (s *Server) AssignPermissionToRole(c echo.Context) error {
 	// Verify permission exists
 	permission, err := s.queries.GetPermission(ctx, req.PermissionID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "permission_not_found",
 				"Permission with the specified ID was not found.")
 		}
@@ -326,7 +330,7 @@ func (s *Server) AssignPermissionToRole(c echo.Context) error {
 
 	// Assign permission to role
 	rolePermission, err := s.queries.AssignPermissionToRole(ctx, db.AssignPermissionToRoleParams{
-		RoleID:       int32(roleID),
+		RoleID:       roleID,
 		PermissionID: req.PermissionID,
 	})
 	if err != nil {
@@ -339,6 +343,7 @@ func (s *Server) AssignPermissionToRole(c echo.Context) error {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to assign permission to role.")
 	}
+	s.roles.invalidate()
 
 	// Log audit
 	currentUserID, _ := middleware.GetUserIDFromContext(c)
@@ -354,30 +359,27 @@ func (s *Server) AssignPermissionToRole(c echo.Context) error {
 
 // RevokePermissionFromRole handles DELETE /api/v1/roles/:role_id/permissions/:permission_id
 func (s *Server) RevokePermissionFromRole(c echo.Context) error {
-	roleIDStr := c.Param("role_id")
-	roleID, err := strconv.ParseInt(roleIDStr, 10, 32)
+	roleID, err := ParseInt(c, "role_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_role_id",
-			"The provided role ID is not a valid number.")
+		return err
 	}
 
-	permissionIDStr := c.Param("permission_id")
-	permissionID, err := strconv.ParseInt(permissionIDStr, 10, 32)
+	permissionID, err := ParseInt(c, "permission_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_permission_id",
-			"The provided permission ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
 
 	err = s.queries.RevokePermissionFromRole(ctx, db.RevokePermissionFromRoleParams{
-		RoleID:       int32(roleID),
-		PermissionID: int32(permissionID),
+		RoleID:       roleID,
+		PermissionID: permissionID,
 	})
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to revoke permission from role.")
 	}
+	s.roles.invalidate()
 
 	// Log audit
 	currentUserID, _ := middleware.GetUserIDFromContext(c)
@@ -392,15 +394,13 @@ func (s *Server) RevokePermissionFromRole(c echo.Context) error {
 
 // GetRolePermissions handles GET /api/v1/roles/:role_id/permissions
 func (s *Server) GetRolePermissions(c echo.Context) error {
-	roleIDStr := c.Param("role_id")
-	roleID, err := strconv.ParseInt(roleIDStr, 10, 32)
+	roleID, err := ParseInt(c, "role_id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_role_id",
-			"The provided role ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	permissions, err := s.queries.GetRolePermissions(ctx, int32(roleID))
+	permissions, err := s.roles.permissionsOf(ctx, roleID)
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to retrieve role permissions.")
@@ -439,11 +439,7 @@ func (s *Server) CheckUserPermission(c echo.Context) error {
 	}
 
 	// Check if user has permission - works with ANY action
-	hasPermission, err := s.queries.CheckRolePermission(ctx, db.CheckRolePermissionParams{
-		RoleID:   user.RoleID.Int32,
-		Resource: resource,
-		Action:   action, // Any custom action like "tst" will work
-	})
+	hasPermission, err := s.roles.hasPermission(ctx, user.RoleID.Int32, resource, action)
 	if err != nil {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to check permission.")
@@ -455,3 +451,56 @@ func (s *Server) CheckUserPermission(c echo.Context) error {
 		"action":         action,
 	})
 }
+
+// EffectivePermissions is what a request may do: the permissions it
+// passes RequirePermission with, as "resource:action". The admin role
+// passes every check, so it gets every permission there is. A token or API
+// key only gets those its scopes reach a route of, and Scopes lists them.
+// AllPermissions is set when nothing is left out.
+type EffectivePermissions struct {
+	RoleID         int32    `json:"role_id"`
+	RoleName       string   `json:"role_name"`
+	AllPermissions bool     `json:"all_permissions"`
+	Permissions    []string `json:"permissions"`
+	Scopes         []string `json:"scopes,omitempty"`
+}
+
+// GetEffectivePermissions handles GET /api/v1/auth/permissions, listing
+// the permissions of the role the request was made with, limited to the
+// scopes of its token or API key, so clients can decide what to show
+// without a check-permission call each. An API key reports its own role.
+func (s *Server) GetEffectivePermissions(c echo.Context) error {
+	ctx := c.Request().Context()
+	roleID, err := middleware.GetRoleIDFromContext(c)
+	if err != nil {
+		return RespondError(c, http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+	roleName, _ := middleware.GetRoleNameFromContext(c)
+
+	var permissions []db.Permission
+	if roleName == "admin" {
+		permissions, err = s.queries.ListAllPermissions(ctx)
+	} else {
+		permissions, err = s.roles.permissionsOf(ctx, roleID)
+	}
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to retrieve role permissions.")
+	}
+
+	scopes, scoped := middleware.GetScopesFromContext(c)
+	resp := EffectivePermissions{
+		RoleID:      roleID,
+		RoleName:    roleName,
+		Permissions: make([]string, 0, len(permissions)),
+		Scopes:      scopes,
+	}
+	for _, p := range permissions {
+		if scoped && !middleware.HasScope(scopes, middleware.PermissionScope(p.Resource, p.Action)) {
+			continue
+		}
+		resp.Permissions = append(resp.Permissions, p.Resource+":"+p.Action)
+	}
+	resp.AllPermissions = roleName == "admin" && len(resp.Permissions) == len(permissions)
+	return RespondSuccess(c, http.StatusOK, resp)
+}
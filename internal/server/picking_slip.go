@@ -0,0 +1,164 @@
+// internal/server/picking_slip.go - Printable picking slips for orders
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/pdf"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/labstack/echo/v4"
+)
+
+// Layout of picking slips
+const (
+	slipRowHeight = 16.0
+	slipBodySize  = 9.0
+)
+
+// slipColumns are the item table columns, with their widths in points
+var (
+	slipColumns = []string{"#", "Product", "Qty", "Unit", "Note", "Picked"}
+	slipWidths  = []float64{24, 215, 45, 60, 127, 44}
+)
+
+// GetPickingSlip handles GET /api/v1/orders/:id/picking-slip, a PDF of
+// the order's items to pick and whom they are for, followed by the
+// interaction and duplicate-therapy warnings on them. Dates follow the reports time zone;
+// calendar=jalali adds Jalali dates.
+func (s *Server) GetPickingSlip(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+
+	ctx := c.Request().Context()
+	order, err := s.queries.GetOrder(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+	items, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: id, Valid: true})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order items.")
+	}
+	products, err := s.queries.ListOrderItemProducts(ctx, uuid.NullUUID{UUID: id, Valid: true})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order items.")
+	}
+	warnings, err := s.queries.ListOrderWarnings(ctx, id)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order warnings.")
+	}
+	names := make(map[uuid.UUID]string, len(products))
+	for _, p := range products {
+		name := p.Name
+		if p.Strength.Valid {
+			name += " " + p.Strength.String
+		}
+		names[p.ID] = name
+	}
+
+	cal := s.reports.Calendar()
+	doc := pdf.New(pdf.Options{
+		Fonts:    s.fonts,
+		Footer:   "DigiOrder picking slip " + order.ID.String(),
+		Jalali:   calendar == jalali.Jalali,
+		Location: cal.Location,
+	})
+	doc.Paragraph(doc.Y, 16, true, "Picking slip")
+	doc.Y -= 20
+	doc.Text(pdf.Margin, doc.Y, slipBodySize, false, "Order "+order.ID.String()+
+		"    Status: "+order.Status+"    Priority: "+order.Priority)
+	doc.Y -= 14
+	var header []string
+	if order.CreatedAt.Valid {
+		header = append(header, "Created: "+doc.DateTime(order.CreatedAt.Time))
+	}
+	if order.NeededBy.Valid {
+		header = append(header, "Needed by: "+doc.DateTime(order.NeededBy.Time))
+	}
+	if len(header) > 0 {
+		doc.Text(pdf.Margin, doc.Y, slipBodySize, false, strings.Join(header, "    "))
+		doc.Y -= 14
+	}
+	if order.RequesterID.Valid {
+		requester, err := s.queries.GetRequester(ctx, order.RequesterID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return RespondError(c, http.StatusInternalServerError, "db_error",
+				"Failed to fetch the requester.")
+		}
+		if err == nil {
+			line := "For: " + requester.Name.String + " (" + requester.Kind
+			if requester.Reference.Valid {
+				line += " " + requester.Reference.String
+			}
+			doc.Text(pdf.Margin, doc.Y, slipBodySize, false, line+")")
+			doc.Y -= 14
+		}
+	}
+	if order.Notes.Valid && order.Notes.String != "" {
+		doc.Paragraph(doc.Y, slipBodySize, false, doc.Fit("Notes: "+order.Notes.String, pdf.PageWidth-2*pdf.Margin, slipBodySize, false))
+		doc.Y -= 14
+	}
+	if len(warnings) > 0 {
+		doc.Text(pdf.Margin, doc.Y, slipBodySize, true,
+			strconv.Itoa(len(warnings))+" warning(s) on this order; see the end of the slip before dispensing.")
+		doc.Y -= 14
+	}
+
+	doc.Y -= 10
+	tableHeader := func() {
+		doc.Row(pdf.Margin, slipWidths, slipBodySize, true, slipColumns)
+		doc.Y -= slipRowHeight
+		doc.Line(pdf.Margin, doc.Y+slipRowHeight-4, pdf.PageWidth-pdf.Margin, doc.Y+slipRowHeight-4)
+	}
+	tableHeader()
+	if len(items) == 0 {
+		doc.Text(pdf.Margin, doc.Y, slipBodySize, false, "No items")
+		doc.Y -= slipRowHeight
+	}
+	for i, item := range items {
+		if doc.Ensure(slipRowHeight) {
+			tableHeader()
+		}
+		doc.Row(pdf.Margin, slipWidths, slipBodySize, false, []string{
+			strconv.Itoa(i + 1),
+			names[item.ID],
+			strconv.Itoa(int(item.RequestedQty)),
+			item.Unit.String,
+			item.Note.String,
+			"[   ]",
+		})
+		doc.Y -= slipRowHeight
+	}
+
+	if len(warnings) > 0 {
+		doc.Y -= 12
+		doc.Ensure(3 * slipRowHeight)
+		doc.Paragraph(doc.Y, 12, true, "Warnings")
+		doc.Y -= 18
+		width := pdf.PageWidth - 2*pdf.Margin - 90
+		for _, w := range warnings {
+			doc.Ensure(slipRowHeight)
+			doc.Text(pdf.Margin, doc.Y, slipBodySize, true, strings.ToUpper(w.Severity))
+			doc.Text(pdf.Margin+90, doc.Y, slipBodySize, false, doc.Fit(w.Message, width, slipBodySize, false))
+			doc.Y -= slipRowHeight
+		}
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		`inline; filename="picking-slip-`+order.ID.String()+`.pdf"`)
+	return c.Blob(http.StatusOK, reports.ContentTypePDF, doc.Bytes())
+}
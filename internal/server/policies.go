@@ -0,0 +1,185 @@
+// internal/server/policies.go - Attribute-based rules on order changes
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/policy"
+	"github.com/labstack/echo/v4"
+)
+
+// policyRules parses the configured rules; Validate has rejected the ones
+// that do not parse
+func policyRules(rules []config.PolicyRule) []policy.Rule {
+	parsed := make([]policy.Rule, 0, len(rules))
+	for _, r := range rules {
+		rule, err := policy.NewRule(r.Name, r.Action, r.Roles, r.Require, r.Message)
+		if err == nil {
+			parsed = append(parsed, rule)
+		}
+	}
+	return parsed
+}
+
+// orderParam returns the order of the route parameter name
+func orderParam(name string) func(echo.Context) (uuid.UUID, error) {
+	return func(c echo.Context) (uuid.UUID, error) {
+		return uuid.Parse(c.Param(name))
+	}
+}
+
+// orderOfItem returns the order of the order item of the id route
+// parameter
+func (s *Server) orderOfItem(c echo.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	item, err := s.queries.GetOrderItem(c.Request().Context(), id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !item.OrderID.Valid {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return item.OrderID.UUID, nil
+}
+
+// orderPolicyKey is the echo context key of the policy check orderPolicy
+// leaves to the handler
+const orderPolicyKey = "order_policy"
+
+// orderPolicyCheck is the action a request takes on an order, to be
+// checked against the configured policies in the handler's transaction
+type orderPolicyCheck struct {
+	action  string
+	orderID uuid.UUID
+}
+
+// policyDeniedError is returned by checkOrderPolicy with the rule that
+// denied a change
+type policyDeniedError struct {
+	rule *policy.Rule
+}
+
+func (e *policyDeniedError) Error() string {
+	return "policy " + e.rule.Name + " denied the change"
+}
+
+// orderPolicy lets a request take action on the order orderOf finds only
+// when the configured policies allow it, evaluated with the caller's
+// attributes and the order's. Admins pass, as they do RequirePermission.
+// When a rule applies to the caller's role, the handler evaluates it with
+// checkOrderPolicy in the transaction that changes the order; an order the
+// caller cannot see is left to the handler to answer 404.
+func (s *Server) orderPolicy(action string, orderOf func(echo.Context) (uuid.UUID, error)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := middleware.GetRoleNameFromContext(c)
+			if role == "admin" || !s.policies.Applies("orders", action, role) {
+				return next(c)
+			}
+
+			id, err := orderOf(c)
+			if errors.Is(err, sql.ErrNoRows) {
+				return next(c)
+			}
+			if err != nil {
+				return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to check policies.")
+			}
+			c.Set(orderPolicyKey, orderPolicyCheck{action: action, orderID: id})
+			return next(c)
+		}
+	}
+}
+
+// checkOrderPolicy evaluates the policy check orderPolicy left for the
+// request on the order, read FOR UPDATE in the handler's transaction q, so
+// that its status or owner cannot change between the check and the write.
+// It returns a *policyDeniedError when a rule denies the change, and nil
+// for requests no rule applies to and for orders the caller cannot see.
+func (s *Server) checkOrderPolicy(c echo.Context, q db.Querier) error {
+	check, ok := c.Get(orderPolicyKey).(orderPolicyCheck)
+	if !ok {
+		return nil
+	}
+	order, err := q.GetOrderForUpdate(c.Request().Context(), check.orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	attrs := subjectAttributes(c)
+	for k, v := range orderAttributes(order) {
+		attrs[k] = v
+	}
+	if rule := s.policies.Evaluate("orders", check.action, attrs); rule != nil {
+		s.logger.Info("Policy denied request", map[string]any{
+			"policy":   rule.Name,
+			"action":   "orders:" + check.action,
+			"order_id": check.orderID.String(),
+			"role":     attrs["subject.role"],
+		})
+		return &policyDeniedError{rule: rule}
+	}
+	return nil
+}
+
+// respondPolicyDenied writes the 403 of an error checkOrderPolicy returned
+// for a denied change; it reports false and writes nothing for any other
+// error
+func respondPolicyDenied(c echo.Context, err error) (bool, error) {
+	var denied *policyDeniedError
+	if !errors.As(err, &denied) {
+		return false, nil
+	}
+	message := denied.rule.Message
+	if message == "" {
+		message = "A policy does not allow this change to the order."
+	}
+	return true, RespondError(c, http.StatusForbidden, "policy_denied", message)
+}
+
+// subjectAttributes are the caller's policy attributes
+func subjectAttributes(c echo.Context) policy.Attributes {
+	attrs := policy.Attributes{}
+	if userID, err := middleware.GetUserIDFromContext(c); err == nil {
+		attrs["subject.user_id"] = userID.String()
+	}
+	attrs["subject.role"], _ = middleware.GetRoleNameFromContext(c)
+	if department := middleware.GetDepartmentIDFromContext(c); department != 0 {
+		attrs["subject.department_id"] = strconv.Itoa(int(department))
+	}
+	if tenantID, err := middleware.GetTenantIDFromContext(c); err == nil {
+		attrs["subject.tenant_id"] = tenantID.String()
+	}
+	return attrs
+}
+
+// orderAttributes are an order's policy attributes
+func orderAttributes(o db.Order) policy.Attributes {
+	attrs := policy.Attributes{
+		"resource.id":       o.ID.String(),
+		"resource.status":   o.Status,
+		"resource.priority": o.Priority,
+	}
+	if o.CreatedBy.Valid {
+		attrs["resource.created_by"] = o.CreatedBy.UUID.String()
+	}
+	if o.DepartmentID.Valid {
+		attrs["resource.department_id"] = strconv.Itoa(int(o.DepartmentID.Int32))
+	}
+	if o.RequesterID.Valid {
+		attrs["resource.requester_id"] = o.RequesterID.UUID.String()
+	}
+	return attrs
+}
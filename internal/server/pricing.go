@@ -0,0 +1,42 @@
+// internal/server/pricing.go - Prices of order items and order totals
+package server
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+)
+
+// priceString formats a price for a NUMERIC column
+func priceString(price float64) string {
+	return strconv.FormatFloat(price, 'f', 2, 64)
+}
+
+// requestPrice is the value to store for a price a request may leave out
+func requestPrice(price *float64) sql.NullString {
+	if price == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: priceString(*price), Valid: true}
+}
+
+// itemPrice is the unit price an item is stored with: the one requested,
+// or else the product's, which may be unset
+func itemPrice(requested *float64, product db.Product) sql.NullString {
+	if requested != nil {
+		return requestPrice(requested)
+	}
+	return product.Price
+}
+
+// recalculateOrder brings an order's subtotal and total up to date with
+// its items. It is called in the transaction that changed them.
+func (s *Server) recalculateOrder(ctx context.Context, q db.Querier, orderID uuid.UUID) (db.Order, error) {
+	return q.RecalculateOrderTotals(ctx, db.RecalculateOrderTotalsParams{
+		TaxPercent: strconv.FormatFloat(s.config.Pricing.TaxPercent, 'f', -1, 64),
+		OrderID:    orderID,
+	})
+}
@@ -4,33 +4,47 @@ package server
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
 	"github.com/labstack/echo/v4"
 )
 
 type CreateProductReq struct {
-	Name         string `json:"name" validate:"required,min=1,max=255"`
-	Brand        string `json:"brand,omitempty"`
-	DosageFormID int32  `json:"dosage_form_id" validate:"required,gt=0"`
-	Strength     string `json:"strength,omitempty"`
-	Unit         string `json:"unit,omitempty"`
-	CategoryID   int32  `json:"category_id" validate:"required,gt=0"`
-	Description  string `json:"description,omitempty"`
+	Name         string   `json:"name" validate:"required,min=1,max=255"`
+	Brand        string   `json:"brand,omitempty"`
+	DosageFormID int32    `json:"dosage_form_id" validate:"required,gt=0"`
+	Strength     string   `json:"strength,omitempty"`
+	Unit         string   `json:"unit,omitempty"`
+	CategoryID   int32    `json:"category_id" validate:"required,gt=0"`
+	Description  string   `json:"description,omitempty"`
+	Controlled   bool     `json:"is_controlled,omitempty"`
+	Schedule     string   `json:"schedule_class,omitempty" validate:"omitempty,max=50"`
+	Price        *float64 `json:"price,omitempty" validate:"omitempty,gte=0,lt=1000000000000"` // per unit
 }
 
+// UpdateProductReq defines the request for updating a product. Fields left
+// out keep their value; null clears the ones a product may be without.
 type UpdateProductReq struct {
-	Name         string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
-	Brand        string `json:"brand,omitempty"`
-	DosageFormID *int32 `json:"dosage_form_id,omitempty" validate:"omitempty,gt=0"`
-	Strength     string `json:"strength,omitempty"`
-	Unit         string `json:"unit,omitempty"`
-	CategoryID   *int32 `json:"category_id,omitempty" validate:"omitempty,gt=0"`
-	Description  string `json:"description,omitempty"`
+	Name         string            `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Brand        Optional[string]  `json:"brand,omitempty"`
+	DosageFormID Optional[int32]   `json:"dosage_form_id,omitempty" validate:"omitempty,gt=0"`
+	Strength     Optional[string]  `json:"strength,omitempty"`
+	Unit         Optional[string]  `json:"unit,omitempty"`
+	CategoryID   Optional[int32]   `json:"category_id,omitempty" validate:"omitempty,gt=0"`
+	Description  Optional[string]  `json:"description,omitempty"`
+	Status       string            `json:"status,omitempty" validate:"omitempty,oneof=active staging"`
+	Controlled   *bool             `json:"is_controlled,omitempty"`
+	Schedule     Optional[string]  `json:"schedule_class,omitempty" validate:"omitempty,max=50"`
+	Price        Optional[float64] `json:"price,omitempty" validate:"omitempty,gte=0,lt=1000000000000"`
 }
 
 // CreateProduct handles POST /api/v1/products
@@ -45,8 +59,8 @@ func (s *Server) CreateProduct(c echo.Context) error {
 	// Verify dosage form exists
 	_, err := s.queries.GetDosageForm(ctx, req.DosageFormID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return RespondError(c, http.StatusBadRequest, "invalid_dosage_form",
+		if errors.Is(err, sql.ErrNoRows) {
+			return respondFieldError(c, "invalid_dosage_form", "dosage_form_id",
 				fmt.Sprintf("Dosage form with ID %d does not exist.", req.DosageFormID))
 		}
 		return HandleDatabaseError(c, err, "Dosage Form")
@@ -55,25 +69,49 @@ func (s *Server) CreateProduct(c echo.Context) error {
 	// Verify category exists
 	_, err = s.queries.GetCategory(ctx, req.CategoryID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return RespondError(c, http.StatusBadRequest, "invalid_category",
+		if errors.Is(err, sql.ErrNoRows) {
+			return respondFieldError(c, "invalid_category", "category_id",
 				fmt.Sprintf("Category with ID %d does not exist.", req.CategoryID))
 		}
 		return HandleDatabaseError(c, err, "Category")
 	}
 
+	if req.Schedule != "" && !req.Controlled {
+		return respondFieldError(c, "validation_error", "schedule_class",
+			"Only controlled substances have a schedule class.")
+	}
+
 	// Create timeout context for DB operations
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
 	defer cancel()
 
-	product, err := s.queries.CreateProduct(ctx, db.CreateProductParams{
-		Name:         req.Name,
-		Brand:        sql.NullString{String: req.Brand, Valid: req.Brand != ""},
-		DosageFormID: sql.NullInt32{Int32: req.DosageFormID, Valid: true},
-		Strength:     sql.NullString{String: req.Strength, Valid: req.Strength != ""},
-		Unit:         sql.NullString{String: req.Unit, Valid: req.Unit != ""},
-		CategoryID:   sql.NullInt32{Int32: req.CategoryID, Valid: true},
-		Description:  sql.NullString{String: req.Description, Valid: req.Description != ""},
+	var product db.Product
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		product, err = q.CreateProduct(ctx, db.CreateProductParams{
+			Name:         req.Name,
+			Brand:        sql.NullString{String: req.Brand, Valid: req.Brand != ""},
+			DosageFormID: sql.NullInt32{Int32: req.DosageFormID, Valid: true},
+			Strength:     sql.NullString{String: req.Strength, Valid: req.Strength != ""},
+			Unit:         sql.NullString{String: req.Unit, Valid: req.Unit != ""},
+			CategoryID:   sql.NullInt32{Int32: req.CategoryID, Valid: true},
+			Description:  sql.NullString{String: req.Description, Valid: req.Description != ""},
+			Price:        requestPrice(req.Price),
+		})
+		if err != nil {
+			return err
+		}
+		if req.Controlled {
+			product, err = q.SetProductControlled(ctx, db.SetProductControlledParams{
+				IsControlled:  true,
+				ScheduleClass: sql.NullString{String: req.Schedule, Valid: req.Schedule != ""},
+				ID:            product.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(c, q, outbox.ProductCreated, product.ID.String(), outbox.ProductPayload(product))
 	})
 	if err != nil {
 		// Check if timeout
@@ -87,46 +125,34 @@ func (s *Server) CreateProduct(c echo.Context) error {
 	return RespondSuccess(c, http.StatusCreated, product)
 }
 
-// ListProducts handles GET /api/v1/products
+// ListProducts handles GET /api/v1/products. With format=xlsx every
+// product is downloaded as an Excel sheet. include embeds related
+// resources as in GetProduct.
 func (s *Server) ListProducts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	limit := 50
-	offset := 0
-
-	if limitStr := c.QueryParam("limit"); limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit parameter must be a valid number.")
-		}
-		if parsedLimit <= 0 {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit must be greater than 0.")
-		}
-		if parsedLimit > 100 {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit cannot exceed 100.")
-		}
-		limit = parsedLimit
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
+	}
+	sortBy, ok := parseSort(c, &page, productSortColumns)
+	if !ok {
+		return nil
+	}
+	include, ok := parseInclude(c, productIncludes)
+	if !ok {
+		return nil
 	}
 
-	if offsetStr := c.QueryParam("offset"); offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_offset",
-				"Offset parameter must be a valid number.")
-		}
-		if parsedOffset < 0 {
-			return RespondError(c, http.StatusBadRequest, "invalid_offset",
-				"Offset cannot be negative.")
-		}
-		offset = parsedOffset
+	if wantsXLSX(c) {
+		return s.exportProductsXLSX(c)
 	}
 
-	products, err := s.queries.ListProducts(ctx, db.ListProductsParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+	products, err := s.queries.ListProducts(withSort(ctx, sortBy, "id"), db.ListProductsParams{
+		AfterTime: page.AfterTime(),
+		AfterID:   page.AfterID(),
+		Limit:     int32(page.Limit),
+		Offset:    int32(page.Offset),
 	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "Products")
@@ -136,15 +162,58 @@ func (s *Server) ListProducts(c echo.Context) error {
 		products = []db.Product{}
 	}
 
-	return RespondSuccess(c, http.StatusOK, products)
+	total := s.listTotal(ctx, "products", nil, s.queries.CountProducts)
+	return s.respondProductPage(c, page, total, products, include)
 }
 
-// GetProduct handles GET /api/v1/products/:id
+// exportProductsXLSX streams the products with their category and dosage
+// form names
+func (s *Server) exportProductsXLSX(c echo.Context) error {
+	ctx := c.Request().Context()
+	categories, err := s.queries.ListCategories(ctx)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Categories")
+	}
+	forms, err := s.queries.ListDosageForms(ctx)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Dosage forms")
+	}
+	categoryNames := make(map[int32]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+	formNames := make(map[int32]string, len(forms))
+	for _, f := range forms {
+		formNames[f.ID] = f.Name
+	}
+
+	header := []string{"ID", "Name", "Brand", "Dosage Form", "Strength", "Unit", "Category",
+		"Status", "IRC", "Generic Code", "Controlled", "Schedule Class", "Description", "Created At"}
+	list := func(ctx context.Context, page pagination.Page) ([]db.Product, error) {
+		return s.queries.ListProducts(ctx, db.ListProductsParams{
+			AfterTime: page.AfterTime(),
+			AfterID:   page.AfterID(),
+			Limit:     int32(page.Limit),
+		})
+	}
+	return s.streamXLSX(c, "products", header, keysetSource(list, productCursor, func(_ context.Context, p db.Product) []any {
+		return []any{p.ID, p.Name, p.Brand.String, formNames[p.DosageFormID.Int32], p.Strength.String,
+			p.Unit.String, categoryNames[p.CategoryID.Int32], p.Status, p.Irc.String, p.GenericCode.String,
+			p.IsControlled, p.ScheduleClass.String, p.Description.String, s.xlsxTime(p.CreatedAt)}
+	}))
+}
+
+// GetProduct handles GET /api/v1/products/:id. include=category,barcodes
+// embeds the product's category and barcodes.
 func (s *Server) GetProduct(c echo.Context) error {
 	id, err := ParseUUID(c, "id")
 	if err != nil {
 		return err // Already formatted
 	}
+	include, ok := parseInclude(c, productIncludes)
+	if !ok {
+		return nil
+	}
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
 	defer cancel()
@@ -161,6 +230,15 @@ func (s *Server) GetProduct(c echo.Context) error {
 		return ErrNotFound.WithDetails("Product has been deleted").Send(c)
 	}
 
+	if include != nil {
+		included, err := s.includeProducts(ctx, []db.Product{product}, include)
+		if err != nil {
+			return RespondError(c, http.StatusInternalServerError, "db_error",
+				"Failed to retrieve the included resources.")
+		}
+		return RespondSuccess(c, http.StatusOK, included[0])
+	}
+
 	return RespondSuccess(c, http.StatusOK, product)
 }
 
@@ -190,24 +268,24 @@ func (s *Server) UpdateProduct(c echo.Context) error {
 	}
 
 	// Verify dosage form if provided
-	if req.DosageFormID != nil {
-		_, err := s.queries.GetDosageForm(ctx, *req.DosageFormID)
+	if req.DosageFormID.Set && !req.DosageFormID.Null {
+		_, err := s.queries.GetDosageForm(ctx, req.DosageFormID.Value)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return RespondError(c, http.StatusBadRequest, "invalid_dosage_form",
-					fmt.Sprintf("Dosage form with ID %d does not exist.", *req.DosageFormID))
+			if errors.Is(err, sql.ErrNoRows) {
+				return respondFieldError(c, "invalid_dosage_form", "dosage_form_id",
+					fmt.Sprintf("Dosage form with ID %d does not exist.", req.DosageFormID.Value))
 			}
 			return HandleDatabaseError(c, err, "Dosage Form")
 		}
 	}
 
 	// Verify category if provided
-	if req.CategoryID != nil {
-		_, err := s.queries.GetCategory(ctx, *req.CategoryID)
+	if req.CategoryID.Set && !req.CategoryID.Null {
+		_, err := s.queries.GetCategory(ctx, req.CategoryID.Value)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return RespondError(c, http.StatusBadRequest, "invalid_category",
-					fmt.Sprintf("Category with ID %d does not exist.", *req.CategoryID))
+			if errors.Is(err, sql.ErrNoRows) {
+				return respondFieldError(c, "invalid_category", "category_id",
+					fmt.Sprintf("Category with ID %d does not exist.", req.CategoryID.Value))
 			}
 			return HandleDatabaseError(c, err, "Category")
 		}
@@ -215,39 +293,67 @@ func (s *Server) UpdateProduct(c echo.Context) error {
 
 	// Build update params
 	params := db.UpdateProductParams{
-		ID: id,
-	}
-
-	if req.Name != "" {
-		params.Name = req.Name
-	} else {
-		params.Name = existingProduct.Name
-	}
-
-	if req.Brand != "" {
-		params.Brand = sql.NullString{String: req.Brand, Valid: true}
-	}
-	if req.DosageFormID != nil {
-		params.DosageFormID = sql.NullInt32{Int32: *req.DosageFormID, Valid: true}
-	}
-	if req.Strength != "" {
-		params.Strength = sql.NullString{String: req.Strength, Valid: true}
-	}
-	if req.Unit != "" {
-		params.Unit = sql.NullString{String: req.Unit, Valid: true}
-	}
-	if req.CategoryID != nil {
-		params.CategoryID = sql.NullInt32{Int32: *req.CategoryID, Valid: true}
-	}
-	if req.Description != "" {
-		params.Description = sql.NullString{String: req.Description, Valid: true}
+		ID:     id,
+		Name:   sql.NullString{String: req.Name, Valid: req.Name != ""},
+		Status: sql.NullString{String: req.Status, Valid: req.Status != ""},
+	}
+	params.Brand, params.SetBrand = nullString(req.Brand)
+	params.DosageFormID, params.SetDosageFormID = nullInt32(req.DosageFormID)
+	params.Strength, params.SetStrength = nullString(req.Strength)
+	params.Unit, params.SetUnit = nullString(req.Unit)
+	params.CategoryID, params.SetCategoryID = nullInt32(req.CategoryID)
+	params.Description, params.SetDescription = nullString(req.Description)
+	params.Price, params.SetPrice = nullPrice(req.Price)
+
+	// A product that stops being controlled loses its schedule class
+	controlled := existingProduct.IsControlled
+	if req.Controlled != nil {
+		controlled = *req.Controlled
+	}
+	schedule := existingProduct.ScheduleClass
+	if req.Schedule.Set {
+		schedule, _ = nullString(req.Schedule)
+	}
+	if !controlled {
+		if req.Schedule.Set && schedule.Valid {
+			return respondFieldError(c, "validation_error", "schedule_class",
+				"Only controlled substances have a schedule class.")
+		}
+		schedule = sql.NullString{}
 	}
+	setControlled := controlled != existingProduct.IsControlled || schedule != existingProduct.ScheduleClass
 
-	product, err := s.queries.UpdateProduct(ctx, params)
+	var product db.Product
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		product, err = q.UpdateProduct(ctx, params)
+		if err != nil {
+			return err
+		}
+		if setControlled {
+			product, err = q.SetProductControlled(ctx, db.SetProductControlledParams{
+				IsControlled:  controlled,
+				ScheduleClass: schedule,
+				ID:            id,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(c, q, outbox.ProductUpdated, product.ID.String(), outbox.ProductPayload(product))
+	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "Product")
 	}
 
+	if setControlled {
+		userID, _ := middleware.GetUserIDFromContext(c)
+		s.logAudit(ctx, userID, "update_controlled", "product", id.String(),
+			map[string]any{"is_controlled": existingProduct.IsControlled, "schedule_class": existingProduct.ScheduleClass.String},
+			map[string]any{"is_controlled": product.IsControlled, "schedule_class": product.ScheduleClass.String},
+			c.RealIP(), c.Request().UserAgent())
+	}
+
 	return RespondSuccess(c, http.StatusOK, product)
 }
 
@@ -271,7 +377,12 @@ func (s *Server) DeleteProduct(c echo.Context) error {
 			"Product has already been deleted.")
 	}
 
-	err = s.queries.DeleteProduct(ctx, id)
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := q.DeleteProduct(ctx, id); err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.ProductDeleted, id.String(), outbox.DeletedData{ID: id.String()})
+	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "Product")
 	}
@@ -279,53 +390,41 @@ func (s *Server) DeleteProduct(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-// SearchProducts handles GET /api/v1/products/search
+// SearchProducts handles GET /api/v1/products/search. Names and brands are
+// matched after folding Arabic letters, digits, diacritics and ZWNJ, so
+// "كپسول" finds "کپسول".
 func (s *Server) SearchProducts(c echo.Context) error {
-	query := c.QueryParam("q")
+	query := strings.TrimSpace(c.QueryParam("q"))
 	if query == "" {
 		return RespondError(c, http.StatusBadRequest, "missing_query",
 			"Search query parameter 'q' is required.")
 	}
 
-	if len(query) < 2 {
+	if utf8.RuneCountInString(query) < 2 {
 		return RespondError(c, http.StatusBadRequest, "query_too_short",
 			"Search query must be at least 2 characters long.")
 	}
 
-	limit := 50
-	offset := 0
-
-	if limitStr := c.QueryParam("limit"); limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit parameter must be a valid number.")
-		}
-		if parsedLimit <= 0 || parsedLimit > 100 {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit must be between 1 and 100.")
-		}
-		limit = parsedLimit
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
 	}
-
-	if offsetStr := c.QueryParam("offset"); offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_offset",
-				"Offset parameter must be a valid number.")
-		}
-		if parsedOffset < 0 {
-			return RespondError(c, http.StatusBadRequest, "invalid_offset",
-				"Offset cannot be negative.")
-		}
-		offset = parsedOffset
+	sortBy, ok := parseSort(c, &page, productSortColumns)
+	if !ok {
+		return nil
+	}
+	include, ok := parseInclude(c, productIncludes)
+	if !ok {
+		return nil
 	}
 
 	ctx := c.Request().Context()
-	products, err := s.queries.SearchProducts(ctx, db.SearchProductsParams{
-		Column1: sql.NullString{String: query, Valid: true},
-		Limit:   int32(limit),
-		Offset:  int32(offset),
+	products, err := s.queries.SearchProducts(withSort(ctx, sortBy, "id"), db.SearchProductsParams{
+		Query:     query,
+		AfterTime: page.AfterTime(),
+		AfterID:   page.AfterID(),
+		Limit:     int32(page.Limit),
+		Offset:    int32(page.Offset),
 	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "Products")
@@ -335,5 +434,22 @@ func (s *Server) SearchProducts(c echo.Context) error {
 		products = []db.Product{}
 	}
 
-	return RespondSuccess(c, http.StatusOK, products)
+	total := s.listTotal(ctx, "products", query, func(ctx context.Context) (int64, error) {
+		return s.queries.CountSearchProducts(ctx, query)
+	})
+	return s.respondProductPage(c, page, total, products, include)
+}
+
+// respondProductPage writes a page of products with the related
+// resources include asks for
+func (s *Server) respondProductPage(c echo.Context, page pagination.Page, total *pagination.Total, products []db.Product, include map[string]bool) error {
+	if include == nil {
+		return respondPage(c, page, total, products, productCursor, products)
+	}
+	included, err := s.includeProducts(c.Request().Context(), products, include)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to retrieve the included resources.")
+	}
+	return respondPage(c, page, total, products, productCursor, included)
 }
@@ -0,0 +1,131 @@
+// internal/server/quotas.go - Per-tenant request and order quotas
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/quota"
+	"github.com/labstack/echo/v4"
+)
+
+// SetTenantQuotasReq overrides the default quotas of a tenant. A null or
+// missing limit keeps the tenancy.quotas default; 0 lifts the limit.
+type SetTenantQuotasReq struct {
+	RequestsPerMinute *int32 `json:"requests_per_minute" validate:"omitempty,gte=0"`
+	RequestsPerDay    *int32 `json:"requests_per_day" validate:"omitempty,gte=0"`
+	OrdersPerDay      *int32 `json:"orders_per_day" validate:"omitempty,gte=0"`
+}
+
+// newQuotaLimiter creates the tenant quota limiter. Quotas only apply
+// with tenancy enabled and a database to share the counts.
+func newQuotaLimiter(hasDB bool, queries db.Querier, cfg config.TenancyConfig,
+	loc *time.Location, logger *logging.Logger) *quota.Limiter {
+	return quota.NewLimiter(queries, quota.Config{
+		Enabled:      hasDB && cfg.Enabled,
+		Defaults:     quotaLimits(cfg.Quotas),
+		SyncInterval: cfg.Quotas.SyncInterval,
+		Location:     loc,
+	}, logger)
+}
+
+// quotaLimits are the default limits of the quota settings
+func quotaLimits(cfg config.TenantQuotaConfig) quota.Limits {
+	return quota.Limits{
+		RequestsPerMinute: cfg.RequestsPerMinute,
+		RequestsPerDay:    cfg.RequestsPerDay,
+		OrdersPerDay:      cfg.OrdersPerDay,
+	}
+}
+
+// GetQuotas handles GET /api/v1/admin/quotas. Admins of the main tenant see
+// every tenant, other admins their own.
+func (s *Server) GetQuotas(c echo.Context) error {
+	if !s.quotas.Enabled() {
+		return RespondError(c, http.StatusNotFound, "quotas_disabled", "Tenant quotas are not enabled.")
+	}
+	tenantID, err := middleware.GetTenantIDFromContext(c)
+	if err != nil {
+		return RespondError(c, http.StatusUnauthorized, "unauthorized", "Authentication required.")
+	}
+
+	usage, err := s.quotas.Usage()
+	if err != nil {
+		s.logger.Error("Failed to read tenant quotas", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch quotas.")
+	}
+	if tenantID != db.DefaultTenantID {
+		own := []quota.Usage{}
+		for _, u := range usage {
+			if u.TenantID == tenantID {
+				own = append(own, u)
+			}
+		}
+		usage = own
+	}
+	return RespondSuccess(c, http.StatusOK, usage)
+}
+
+// SetTenantQuotas handles PUT /api/v1/tenants/:id/quotas
+func (s *Server) SetTenantQuotas(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	var req SetTenantQuotasReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetTenant(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Tenant")
+	}
+	tenant, err := s.queries.SetTenantQuotas(ctx, db.SetTenantQuotasParams{
+		ID:                id,
+		RequestsPerMinute: quotaOverride(req.RequestsPerMinute),
+		RequestsPerDay:    quotaOverride(req.RequestsPerDay),
+		OrdersPerDay:      quotaOverride(req.OrdersPerDay),
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Tenant")
+	}
+	s.quotas.SetOverrides(tenant)
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "update", "tenant", id.String(),
+		quotaOverrides(old), quotaOverrides(tenant), c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, tenant)
+}
+
+// quotaOverride is the column value of an optional limit
+func quotaOverride(v *int32) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *v, Valid: true}
+}
+
+// quotaOverrides is the audit view of a tenant's quota columns; nil keeps
+// the default
+func quotaOverrides(t db.Tenant) map[string]any {
+	out := map[string]any{}
+	for name, v := range map[string]sql.NullInt32{
+		quota.RequestsPerMinute: t.RequestsPerMinute,
+		quota.RequestsPerDay:    t.RequestsPerDay,
+		quota.OrdersPerDay:      t.OrdersPerDay,
+	} {
+		out[name] = nil
+		if v.Valid {
+			out[name] = v.Int32
+		}
+	}
+	return out
+}
@@ -0,0 +1,446 @@
+// internal/server/rbac.go - Export and import of roles, permissions and grants as one document
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+	"go.yaml.in/yaml/v2"
+)
+
+// maxRBACDocument is the largest RBAC document ImportRBAC reads
+const maxRBACDocument = 1 << 20
+
+// RBACDocument is the whole access control setup: every permission, and
+// every role with the permissions granted to it as "resource:action".
+// Exported and imported as JSON or YAML, it can be kept in version control
+// and applied to another deployment.
+type RBACDocument struct {
+	Permissions []RBACPermission `json:"permissions" yaml:"permissions"`
+	Roles       []RBACRole       `json:"roles" yaml:"roles"`
+}
+
+// RBACPermission is a permission of an RBACDocument, identified by its
+// resource and action
+type RBACPermission struct {
+	Name        string `json:"name" yaml:"name"`
+	Resource    string `json:"resource" yaml:"resource"`
+	Action      string `json:"action" yaml:"action"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// RBACRole is a role of an RBACDocument, identified by its name
+type RBACRole struct {
+	Name        string   `json:"name" yaml:"name"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// RBACImportResult lists what an import changed, or with dry_run would
+// change
+type RBACImportResult struct {
+	DryRun             bool     `json:"dry_run"`
+	CreatedPermissions []string `json:"created_permissions"`
+	UpdatedPermissions []string `json:"updated_permissions"`
+	DeletedPermissions []string `json:"deleted_permissions"`
+	CreatedRoles       []string `json:"created_roles"`
+	DeletedRoles       []string `json:"deleted_roles"`
+	Granted            []string `json:"granted"` // "role resource:action"
+	Revoked            []string `json:"revoked"`
+}
+
+func (p RBACPermission) key() string {
+	return p.Resource + ":" + p.Action
+}
+
+// rbacFormat returns the format of the format query parameter, or of the
+// request's content type when it has none
+func rbacFormat(c echo.Context) (string, bool) {
+	format := c.QueryParam("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+		format = "json"
+		if strings.HasSuffix(mediaType, "yaml") {
+			format = "yaml"
+		}
+	}
+	return format, format == "json" || format == "yaml"
+}
+
+// loadRBAC reads the current setup as a document
+func loadRBAC(ctx context.Context, q db.Querier) (RBACDocument, error) {
+	permissions, err := q.ListAllPermissions(ctx)
+	if err != nil {
+		return RBACDocument{}, err
+	}
+	roles, err := q.ListRoles(ctx)
+	if err != nil {
+		return RBACDocument{}, err
+	}
+	grants, err := q.ListRolePermissionGrants(ctx)
+	if err != nil {
+		return RBACDocument{}, err
+	}
+
+	doc := RBACDocument{
+		Permissions: make([]RBACPermission, len(permissions)),
+		Roles:       make([]RBACRole, len(roles)),
+	}
+	keys := make(map[int32]string, len(permissions))
+	for i, p := range permissions {
+		doc.Permissions[i] = RBACPermission{
+			Name:        p.Name,
+			Resource:    p.Resource,
+			Action:      p.Action,
+			Description: p.Description.String,
+		}
+		keys[p.ID] = doc.Permissions[i].key()
+	}
+	granted := make(map[int32][]string, len(roles))
+	for _, g := range grants {
+		granted[g.RoleID] = append(granted[g.RoleID], keys[g.PermissionID])
+	}
+	for i, r := range roles {
+		list := granted[r.ID]
+		slices.Sort(list)
+		if list == nil {
+			list = []string{}
+		}
+		doc.Roles[i] = RBACRole{Name: r.Name, Permissions: list}
+	}
+	return doc, nil
+}
+
+// validate checks that names and keys are set and unique and that roles
+// are only granted permissions of the document
+func (d RBACDocument) validate() error {
+	names := make(map[string]bool, len(d.Permissions))
+	keys := make(map[string]bool, len(d.Permissions))
+	for i, p := range d.Permissions {
+		switch {
+		case p.Name == "" || p.Resource == "" || p.Action == "":
+			return fmt.Errorf("permission %d: name, resource and action are required", i+1)
+		case len(p.Name) > 100 || len(p.Resource) > 100 || len(p.Action) > 50:
+			return fmt.Errorf("permission %q: name and resource are limited to 100 characters, action to 50", p.Name)
+		case names[p.Name]:
+			return fmt.Errorf("permission %q is listed twice", p.Name)
+		case keys[p.key()]:
+			return fmt.Errorf("permission %s is listed twice", p.key())
+		}
+		names[p.Name] = true
+		keys[p.key()] = true
+	}
+
+	roles := make(map[string]bool, len(d.Roles))
+	for i, r := range d.Roles {
+		switch {
+		case r.Name == "":
+			return fmt.Errorf("role %d: name is required", i+1)
+		case roles[r.Name]:
+			return fmt.Errorf("role %q is listed twice", r.Name)
+		}
+		roles[r.Name] = true
+		for _, key := range r.Permissions {
+			if !keys[key] {
+				return fmt.Errorf("role %q: permission %s is not in the document", r.Name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// ExportRBAC handles GET /api/v1/rbac, downloading every role, permission
+// and grant as JSON or, with format=yaml, YAML. The document is not
+// wrapped in the usual response envelope, so it can be imported as it is.
+func (s *Server) ExportRBAC(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "yaml" {
+		return RespondError(c, http.StatusBadRequest, "invalid_format", "format must be json or yaml.")
+	}
+
+	doc, err := loadRBAC(c.Request().Context(), s.queries)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to read roles and permissions.")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="rbac.`+format+`"`)
+	if format == "yaml" {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return RespondError(c, http.StatusInternalServerError, "export_error", "Failed to write the document.")
+		}
+		return c.Blob(http.StatusOK, "application/yaml", data)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "export_error", "Failed to write the document.")
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, data)
+}
+
+// ImportRBAC handles PUT /api/v1/rbac, making roles, permissions and
+// grants match a document from ExportRBAC, in JSON or YAML. Missing
+// permissions and roles are created, names and descriptions of
+// permissions updated, and each role of the document is granted exactly
+// its permissions. Permissions and roles the document leaves out are kept
+// unless prune=true; the admin role is never deleted. With dry_run=true
+// the changes are only listed. All changes are made in one transaction.
+func (s *Server) ImportRBAC(c echo.Context) error {
+	format, ok := rbacFormat(c)
+	if !ok {
+		return RespondError(c, http.StatusBadRequest, "invalid_format", "format must be json or yaml.")
+	}
+	prune, _ := strconv.ParseBool(c.QueryParam("prune"))
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxRBACDocument+1))
+	if err != nil {
+		return RespondError(c, http.StatusBadRequest, "invalid_request", "Failed to read the request body.")
+	}
+	if len(data) > maxRBACDocument {
+		return RespondError(c, http.StatusRequestEntityTooLarge, "file_too_large",
+			fmt.Sprintf("The document is larger than %d bytes.", maxRBACDocument))
+	}
+	var doc RBACDocument
+	if format == "yaml" {
+		err = yaml.UnmarshalStrict(data, &doc)
+	} else {
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&doc)
+	}
+	if err != nil {
+		return RespondUnprocessable(c, "invalid_rbac_document", "The document could not be read: "+err.Error())
+	}
+	if err := doc.validate(); err != nil {
+		return RespondUnprocessable(c, "invalid_rbac_document", err.Error())
+	}
+	if len(doc.Permissions) == 0 && len(doc.Roles) == 0 {
+		return RespondUnprocessable(c, "nothing_to_import", "The document has no permissions or roles.")
+	}
+
+	ctx := c.Request().Context()
+	var result RBACImportResult
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		result, err = applyRBAC(ctx, q, doc, prune, dryRun)
+		return err
+	})
+	var inUse errRoleInUse
+	if errors.As(err, &inUse) {
+		return RespondError(c, http.StatusConflict, "role_in_use",
+			fmt.Sprintf("Role %q cannot be deleted while users or API keys have it.", inUse.role))
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Role or permission")
+	}
+
+	if !dryRun {
+		s.roles.invalidate()
+		userID, _ := middleware.GetUserIDFromContext(c)
+		s.logAudit(ctx, userID, "import", "rbac", "", nil, map[string]any{
+			"created_permissions": result.CreatedPermissions,
+			"updated_permissions": result.UpdatedPermissions,
+			"deleted_permissions": result.DeletedPermissions,
+			"created_roles":       result.CreatedRoles,
+			"deleted_roles":       result.DeletedRoles,
+			"granted":             result.Granted,
+			"revoked":             result.Revoked,
+		}, c.RealIP(), c.Request().UserAgent())
+	}
+	return RespondSuccess(c, http.StatusOK, result)
+}
+
+// errRoleInUse is returned from applyRBAC for a role to prune that users
+// or API keys still have
+type errRoleInUse struct {
+	role string
+}
+
+func (e errRoleInUse) Error() string {
+	return fmt.Sprintf("role %q is in use", e.role)
+}
+
+// applyRBAC makes the database match doc, listing the changes. With
+// dryRun nothing is written.
+func applyRBAC(ctx context.Context, q db.Querier, doc RBACDocument, prune, dryRun bool) (RBACImportResult, error) {
+	result := RBACImportResult{
+		DryRun:             dryRun,
+		CreatedPermissions: []string{},
+		UpdatedPermissions: []string{},
+		DeletedPermissions: []string{},
+		CreatedRoles:       []string{},
+		DeletedRoles:       []string{},
+		Granted:            []string{},
+		Revoked:            []string{},
+	}
+
+	permissions, err := q.ListAllPermissions(ctx)
+	if err != nil {
+		return result, err
+	}
+	roles, err := q.ListRoles(ctx)
+	if err != nil {
+		return result, err
+	}
+	grants, err := q.ListRolePermissionGrants(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	// Permissions, by resource and action
+	ids := make(map[string]int32, len(permissions))
+	wanted := make(map[string]bool, len(doc.Permissions))
+	for _, p := range doc.Permissions {
+		wanted[p.key()] = true
+	}
+	for _, p := range permissions {
+		key := p.Resource + ":" + p.Action
+		ids[key] = p.ID
+		if prune && !wanted[key] {
+			result.DeletedPermissions = append(result.DeletedPermissions, key)
+			if !dryRun {
+				if err := q.DeletePermission(ctx, p.ID); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+	current := make(map[string]db.Permission, len(permissions))
+	for _, p := range permissions {
+		current[p.Resource+":"+p.Action] = p
+	}
+	for _, p := range doc.Permissions {
+		existing, ok := current[p.key()]
+		switch {
+		case !ok:
+			result.CreatedPermissions = append(result.CreatedPermissions, p.key())
+			if dryRun {
+				continue
+			}
+			created, err := q.CreatePermission(ctx, db.CreatePermissionParams{
+				Name:        p.Name,
+				Resource:    p.Resource,
+				Action:      p.Action,
+				Description: sql.NullString{String: p.Description, Valid: p.Description != ""},
+			})
+			if err != nil {
+				return result, err
+			}
+			ids[p.key()] = created.ID
+		case existing.Name != p.Name || (p.Description != "" && existing.Description.String != p.Description):
+			// An empty description keeps the current one
+			result.UpdatedPermissions = append(result.UpdatedPermissions, p.key())
+			if dryRun {
+				continue
+			}
+			_, err := q.UpdatePermission(ctx, db.UpdatePermissionParams{
+				ID:          existing.ID,
+				Name:        p.Name,
+				Description: p.Description,
+			})
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	// Roles, by name
+	roleIDs := make(map[string]int32, len(roles))
+	listed := make(map[string]bool, len(doc.Roles))
+	for _, r := range doc.Roles {
+		listed[r.Name] = true
+	}
+	for _, r := range roles {
+		roleIDs[r.Name] = r.ID
+		if prune && !listed[r.Name] && r.Name != "admin" {
+			result.DeletedRoles = append(result.DeletedRoles, r.Name)
+			if dryRun {
+				continue
+			}
+			if err := q.DeleteRole(ctx, r.ID); err != nil {
+				if pqErr, ok := db.AsPgError(err); ok && pqErr.Code == "23503" {
+					return result, errRoleInUse{role: r.Name}
+				}
+				return result, err
+			}
+		}
+	}
+
+	// Grants of the roles the document lists
+	keys := make(map[int32]string, len(permissions))
+	for _, p := range permissions {
+		keys[p.ID] = p.Resource + ":" + p.Action
+	}
+	held := make(map[int32]map[string]bool, len(roles))
+	for _, g := range grants {
+		if held[g.RoleID] == nil {
+			held[g.RoleID] = make(map[string]bool)
+		}
+		held[g.RoleID][keys[g.PermissionID]] = true
+	}
+	for _, r := range doc.Roles {
+		roleID, exists := roleIDs[r.Name]
+		if !exists {
+			result.CreatedRoles = append(result.CreatedRoles, r.Name)
+			if !dryRun {
+				created, err := q.CreateRole(ctx, r.Name)
+				if err != nil {
+					return result, err
+				}
+				roleID = created.ID
+			}
+		}
+
+		want := make(map[string]bool, len(r.Permissions))
+		for _, key := range r.Permissions {
+			want[key] = true
+			if held[roleID][key] && exists {
+				continue
+			}
+			result.Granted = append(result.Granted, r.Name+" "+key)
+			if !dryRun {
+				if _, err := q.AssignPermissionToRole(ctx, db.AssignPermissionToRoleParams{
+					RoleID:       roleID,
+					PermissionID: ids[key],
+				}); err != nil {
+					return result, err
+				}
+			}
+		}
+		if !exists {
+			continue
+		}
+		for key := range held[roleID] {
+			// Grants of pruned permissions went with them
+			if want[key] || (prune && !wanted[key]) {
+				continue
+			}
+			result.Revoked = append(result.Revoked, r.Name+" "+key)
+			if !dryRun {
+				if err := q.RevokePermissionFromRole(ctx, db.RevokePermissionFromRoleParams{
+					RoleID:       roleID,
+					PermissionID: ids[key],
+				}); err != nil {
+					return result, err
+				}
+			}
+		}
+	}
+	slices.Sort(result.Revoked)
+	return result, nil
+}
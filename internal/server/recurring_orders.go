@@ -0,0 +1,302 @@
+// internal/server/recurring_orders.go - Orders placed on a schedule
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/recurring"
+	"github.com/labstack/echo/v4"
+)
+
+// RecurringOrder places a copy of its template order on every run
+type RecurringOrder struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	TemplateOrderID uuid.UUID  `json:"template_order_id"`
+	Frequency       string     `json:"frequency"`
+	LeadDays        int32      `json:"lead_days"`
+	Priority        string     `json:"priority"`
+	Enabled         bool       `json:"enabled"`
+	NextRunAt       time.Time  `json:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	LastOrderID     *uuid.UUID `json:"last_order_id,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// CreateRecurringOrderReq defines the request body for a recurring order.
+// The first order is placed on StartDate (default today); each is needed
+// LeadDays after it is placed.
+type CreateRecurringOrderReq struct {
+	Name            string    `json:"name" validate:"required,max=200"`
+	TemplateOrderID uuid.UUID `json:"template_order_id" validate:"required"`
+	Frequency       string    `json:"frequency" validate:"required"`
+	StartDate       string    `json:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	LeadDays        int32     `json:"lead_days" validate:"min=0,max=90"`
+	Priority        string    `json:"priority" validate:"omitempty,oneof=routine urgent stat"`
+	Enabled         *bool     `json:"enabled"`
+}
+
+// UpdateRecurringOrderReq changes the fields that are set. A new
+// StartDate or Frequency moves the next run.
+type UpdateRecurringOrderReq struct {
+	Name      string `json:"name" validate:"max=200"`
+	Frequency string `json:"frequency"`
+	StartDate string `json:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	LeadDays  *int32 `json:"lead_days" validate:"omitempty,min=0,max=90"`
+	Priority  string `json:"priority" validate:"omitempty,oneof=routine urgent stat"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// newRecurringRunner creates the runner, which the scheduler's
+// recurring_orders job runs. An invalid time zone has already been
+// rejected by config validation.
+func newRecurringRunner(withTx recurring.TxFunc, cfg config.RecurringConfig, pricing config.PricingConfig, logger *logging.Logger) *recurring.Runner {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Error("Invalid recurring order time zone, using UTC", err, nil)
+		loc = time.UTC
+	}
+	return recurring.NewRunner(withTx, recurring.Config{
+		Location:   loc,
+		TaxPercent: pricing.TaxPercent,
+	}, logger)
+}
+
+// CreateRecurringOrder handles POST /api/v1/recurring-orders
+func (s *Server) CreateRecurringOrder(c echo.Context) error {
+	var req CreateRecurringOrderReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+	if req.Priority == "" {
+		req.Priority = "routine"
+	}
+
+	loc := s.recurring.Location()
+	start := time.Now().In(loc)
+	if req.StartDate != "" {
+		start, _ = time.Parse(time.DateOnly, req.StartDate)
+	}
+	if ok, err := validRecurringSchedule(c, req.Frequency, start); !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireOrder(c, req.TemplateOrderID); !ok {
+		return err
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	created, err := s.queries.CreateRecurringOrder(ctx, db.CreateRecurringOrderParams{
+		Name:            req.Name,
+		TemplateOrderID: req.TemplateOrderID,
+		Frequency:       req.Frequency,
+		LeadDays:        req.LeadDays,
+		Priority:        req.Priority,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		NextRunAt:       recurring.FirstRun(req.Frequency, start, loc, time.Now()),
+		CreatedBy:       uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Recurring order")
+	}
+
+	s.logAudit(ctx, userID, "create", "recurring_order", created.ID.String(),
+		nil, recurringOrderAudit(created),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, recurringOrderResponse(created))
+}
+
+// ListRecurringOrders handles GET /api/v1/recurring-orders, soonest run
+// first
+func (s *Server) ListRecurringOrders(c echo.Context) error {
+	rows, err := s.queries.ListRecurringOrders(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch recurring orders.")
+	}
+
+	resp := make([]RecurringOrder, len(rows))
+	for i, row := range rows {
+		resp[i] = recurringOrderResponse(row)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// GetRecurringOrder handles GET /api/v1/recurring-orders/:id
+func (s *Server) GetRecurringOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	row, err := s.queries.GetRecurringOrder(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Recurring order")
+	}
+	return RespondSuccess(c, http.StatusOK, recurringOrderResponse(row))
+}
+
+// UpdateRecurringOrder handles PUT /api/v1/recurring-orders/:id
+func (s *Server) UpdateRecurringOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateRecurringOrderReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetRecurringOrder(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Recurring order")
+	}
+
+	loc := s.recurring.Location()
+	params := db.UpdateRecurringOrderParams{
+		ID:        id,
+		Name:      old.Name,
+		Frequency: old.Frequency,
+		LeadDays:  old.LeadDays,
+		Priority:  old.Priority,
+		Enabled:   old.Enabled,
+		NextRunAt: old.NextRunAt,
+	}
+	if req.Name != "" {
+		params.Name = req.Name
+	}
+	if req.Frequency != "" {
+		params.Frequency = req.Frequency
+	}
+	if req.LeadDays != nil {
+		params.LeadDays = *req.LeadDays
+	}
+	if req.Priority != "" {
+		params.Priority = req.Priority
+	}
+	if req.Enabled != nil {
+		params.Enabled = *req.Enabled
+	}
+
+	start := old.NextRunAt.In(loc)
+	if req.StartDate != "" {
+		start, _ = time.Parse(time.DateOnly, req.StartDate)
+	}
+	if ok, err := validRecurringSchedule(c, params.Frequency, start); !ok {
+		return err
+	}
+	if req.StartDate != "" || params.Frequency != old.Frequency || (params.Enabled && !old.Enabled) {
+		params.NextRunAt = recurring.FirstRun(params.Frequency, start, loc, time.Now())
+	}
+
+	updated, err := s.queries.UpdateRecurringOrder(ctx, params)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Recurring order")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "recurring_order", id.String(),
+		recurringOrderAudit(old), recurringOrderAudit(updated),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, recurringOrderResponse(updated))
+}
+
+// DeleteRecurringOrder handles DELETE /api/v1/recurring-orders/:id. Orders
+// already placed are kept.
+func (s *Server) DeleteRecurringOrder(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetRecurringOrder(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Recurring order")
+	}
+	if err := s.queries.DeleteRecurringOrder(ctx, id); err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to delete recurring order.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "delete", "recurring_order", id.String(),
+		recurringOrderAudit(old), nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// validRecurringSchedule checks the frequency and start day, writing the
+// error response when one is invalid
+func validRecurringSchedule(c echo.Context, frequency string, start time.Time) (bool, error) {
+	if !recurring.ValidFrequency(frequency) {
+		return false, RespondError(c, http.StatusBadRequest, "invalid_frequency",
+			"frequency must be one of "+strings.Join(recurring.Frequencies, ", ")+".")
+	}
+	if frequency == recurring.FrequencyMonthly && start.Day() > recurring.MaxMonthlyDay {
+		return false, respondFieldError(c, "validation_error", "start_date",
+			fmt.Sprintf("Monthly recurring orders must start on day %d of the month or earlier.", recurring.MaxMonthlyDay))
+	}
+	return true, nil
+}
+
+func recurringOrderAudit(r db.RecurringOrder) map[string]any {
+	return map[string]any{
+		"name":              r.Name,
+		"template_order_id": r.TemplateOrderID.String(),
+		"frequency":         r.Frequency,
+		"lead_days":         r.LeadDays,
+		"priority":          r.Priority,
+		"enabled":           r.Enabled,
+		"next_run_at":       r.NextRunAt,
+	}
+}
+
+func recurringOrderResponse(r db.RecurringOrder) RecurringOrder {
+	resp := RecurringOrder{
+		ID:              r.ID,
+		Name:            r.Name,
+		TemplateOrderID: r.TemplateOrderID,
+		Frequency:       r.Frequency,
+		LeadDays:        r.LeadDays,
+		Priority:        r.Priority,
+		Enabled:         r.Enabled,
+		NextRunAt:       r.NextRunAt,
+		LastError:       r.LastError.String,
+		CreatedAt:       r.CreatedAt,
+	}
+	if r.LastRunAt.Valid {
+		resp.LastRunAt = &r.LastRunAt.Time
+	}
+	if r.LastOrderID.Valid {
+		resp.LastOrderID = &r.LastOrderID.UUID
+	}
+	if r.CreatedBy.Valid {
+		resp.CreatedBy = &r.CreatedBy.UUID
+	}
+	return resp
+}
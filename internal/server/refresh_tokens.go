@@ -0,0 +1,204 @@
+// internal/server/refresh_tokens.go - Rotating refresh tokens issued at login
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/anomaly"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// refreshTokenPrefix starts every refresh token, telling it apart from
+// access tokens
+const refreshTokenPrefix = "dgo_rt_"
+
+// errRefreshTokenUsed is returned from the rotation transaction when
+// another request rotated or revoked the token first
+var errRefreshTokenUsed = errors.New("refresh token already used")
+
+// RefreshTokenRequest defines the refresh and logout request body
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RefreshTokenResponse is a new access token and the refresh token that
+// replaces the one exchanged for it
+type RefreshTokenResponse struct {
+	Token            string `json:"token"`
+	ExpiresIn        string `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn string `json:"refresh_expires_in"`
+}
+
+// issueRefreshToken creates a refresh token of the family. Only its hash
+// is stored, so the token is returned to hand to the client.
+func (s *Server) issueRefreshToken(ctx context.Context, q db.Querier, userID, familyID uuid.UUID) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := refreshTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	_, err := q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashAccessToken(token),
+		ExpiresAt: time.Now().Add(s.config.JWT.RefreshExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RefreshToken handles POST /api/v1/auth/refresh, exchanging a refresh
+// token for a new access token and a new refresh token. The access token
+// carries the user's current role, tenant, department and language. Each
+// refresh token works once: presenting a rotated one again revokes every
+// token descended from the same login and records a refresh_token_reuse
+// security event.
+func (s *Server) RefreshToken(c echo.Context) error {
+	var req RefreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	row, err := s.queries.GetRefreshTokenByHash(ctx, hashAccessToken(req.RefreshToken))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error("Failed to look up refresh token", err, nil)
+		}
+		return invalidRefreshToken(c)
+	}
+	if row.RotatedAt.Valid {
+		return s.refreshTokenReused(c, row)
+	}
+	if row.RevokedAt.Valid || time.Now().After(row.ExpiresAt) {
+		return invalidRefreshToken(c)
+	}
+
+	// Only administrators may sign in during planned maintenance
+	if s.maintenance != nil && s.maintenance.Enabled() && row.RoleName.String != "admin" {
+		return s.maintenance.Respond(c)
+	}
+
+	var refresh string
+	err = s.withTx(ctx, func(q db.Querier) error {
+		rotated, err := q.RotateRefreshToken(ctx, row.ID)
+		if err != nil {
+			return err
+		}
+		if rotated == 0 {
+			return errRefreshTokenUsed
+		}
+		refresh, err = s.issueRefreshToken(ctx, q, row.UserID, row.FamilyID)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenUsed) {
+		return s.refreshTokenReused(c, row)
+	}
+	if err != nil {
+		s.logger.Error("Failed to rotate refresh token", err, map[string]any{"user_id": row.UserID})
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to refresh token.")
+	}
+
+	token, err := middleware.GenerateToken(row.UserID, row.Username, row.RoleID.Int32, row.RoleName.String,
+		row.TenantID, row.DepartmentID.Int32, row.Language.String)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "token_error", "Failed to refresh token.")
+	}
+
+	return RespondSuccess(c, http.StatusOK, RefreshTokenResponse{
+		Token:            token,
+		ExpiresIn:        middleware.GetJWTExpiry().String(),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: s.config.JWT.RefreshExpiry.String(),
+	})
+}
+
+// Logout handles POST /api/v1/auth/logout, revoking the refresh token and
+// every token descended from the same login. Access tokens already issued
+// stay valid until they expire. An unknown token is not an error.
+func (s *Server) Logout(c echo.Context) error {
+	var req RefreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	row, err := s.queries.GetRefreshTokenByHash(ctx, hashAccessToken(req.RefreshToken))
+	if err == nil {
+		_, err = s.queries.RevokeRefreshTokenFamily(ctx, row.FamilyID)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.logger.Error("Failed to revoke refresh token", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to sign out.")
+	}
+
+	return RespondSuccess(c, http.StatusOK, map[string]string{
+		"message": "Signed out successfully",
+	})
+}
+
+// refreshTokenReused answers a rotated refresh token presented again: the
+// token was copied, so its family is revoked, signing out both the thief
+// and the user. The security event is only recorded while the family was
+// still live, not for every later attempt.
+func (s *Server) refreshTokenReused(c echo.Context, row db.GetRefreshTokenByHashRow) error {
+	ctx := c.Request().Context()
+	revoked, err := s.queries.RevokeRefreshTokenFamily(ctx, row.FamilyID)
+	if err != nil {
+		s.logger.Error("Failed to revoke reused refresh token family", err, map[string]any{"user_id": row.UserID})
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to refresh token.")
+	}
+
+	if revoked > 0 {
+		details, _ := json.Marshal(map[string]any{
+			"token_id":   row.ID,
+			"family_id":  row.FamilyID,
+			"rotated_at": row.RotatedAt.Time,
+			"user_agent": c.Request().UserAgent(),
+		})
+		event, err := s.queries.CreateSecurityEvent(ctx, db.CreateSecurityEventParams{
+			Kind:      anomaly.KindRefreshTokenReuse,
+			Username:  row.Username,
+			IpAddress: c.RealIP(),
+			Details:   details,
+		})
+		if err != nil {
+			s.logger.Error("Failed to record refresh token reuse", err, map[string]any{"user_id": row.UserID})
+		} else {
+			go s.notifySecurityEvent(event)
+		}
+	}
+
+	return RespondError(c, http.StatusUnauthorized, "refresh_token_reused",
+		"This refresh token was already used; the sessions of its login were signed out.")
+}
+
+func invalidRefreshToken(c echo.Context) error {
+	return RespondError(c, http.StatusUnauthorized, "invalid_token", "Invalid or expired refresh token.")
+}
+
+// pruneRefreshTokens deletes refresh tokens that expired over a day ago,
+// keeping recent ones so reuse just after expiry is still recognized
+func (s *Server) pruneRefreshTokens(ctx context.Context) error {
+	_, err := s.queries.DeleteExpiredRefreshTokens(ctx, time.Now().Add(-24*time.Hour))
+	return err
+}
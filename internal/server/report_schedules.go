@@ -0,0 +1,413 @@
+// internal/server/report_schedules.go - Scheduled report emails
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/pdf"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/labstack/echo/v4"
+)
+
+// ReportSchedule is one report emailed to one recipient
+type ReportSchedule struct {
+	ID            uuid.UUID  `json:"id"`
+	Report        string     `json:"report"`
+	SavedReportID *uuid.UUID `json:"saved_report_id,omitempty"`
+	RecipientID   uuid.UUID  `json:"recipient_id"`
+	Format        string     `json:"format"`
+	Frequency     string     `json:"frequency"`
+	SendHour      int32      `json:"send_hour"`
+	Enabled       bool       `json:"enabled"`
+	NextRunAt     time.Time  `json:"next_run_at"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastStatus    string     `json:"last_status,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CreateReportScheduleReq defines the request body for scheduling a report.
+// Frequency defaults to the report's usual one: daily order summaries,
+// weekly low-stock reports and monthly audit digests. A "saved" report
+// names the saved report to send in saved_report_id.
+type CreateReportScheduleReq struct {
+	Report        string     `json:"report"`
+	SavedReportID *uuid.UUID `json:"saved_report_id"`
+	RecipientID   uuid.UUID  `json:"recipient_id"`
+	Format        string     `json:"format"`
+	Frequency     string     `json:"frequency"`
+	SendHour      *int32     `json:"send_hour"`
+	Enabled       *bool      `json:"enabled"`
+}
+
+// UpdateReportScheduleReq changes the fields that are set
+type UpdateReportScheduleReq struct {
+	Format    string `json:"format"`
+	Frequency string `json:"frequency"`
+	SendHour  *int32 `json:"send_hour"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// ReportRun is the outcome of sending a schedule on demand
+type ReportRun struct {
+	ScheduleID uuid.UUID `json:"schedule_id"`
+	Status     string    `json:"status"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// defaultSendHour is when reports go out unless the schedule says otherwise
+const defaultSendHour = 7
+
+// newReportScheduler creates the scheduler; sending starts with Start. An
+// invalid calendar has already been rejected by config validation. Saved
+// reports run on conn, which is nil with a mock querier.
+func newReportScheduler(conn db.DBTX, queries db.Querier, withTx reports.TxFunc, scope reports.ScopeFunc,
+	notifier *notify.Dispatcher, cfg config.ReportsConfig, fonts pdf.Fonts, logger *logging.Logger) *reports.Scheduler {
+	calendar, err := reports.NewCalendar(cfg.Timezone, cfg.WeekStart, cfg.Calendar)
+	if err != nil {
+		logger.Error("Invalid report calendar, using UTC", err, nil)
+	}
+	return reports.NewScheduler(queries, withTx, notifier, reports.Config{
+		Interval: cfg.CheckInterval,
+		Calendar: calendar,
+		Fonts:    fonts,
+		Conn:     conn,
+		Scope:    scope,
+	}, logger)
+}
+
+// CreateReportSchedule handles POST /api/v1/report-schedules
+func (s *Server) CreateReportSchedule(c echo.Context) error {
+	var req CreateReportScheduleReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if !reports.ValidKind(req.Report) {
+		return RespondError(c, http.StatusBadRequest, "invalid_report",
+			"report must be one of "+strings.Join(reports.Kinds, ", ")+".")
+	}
+	if (req.Report == reports.KindSaved) != (req.SavedReportID != nil) {
+		return respondFieldError(c, "validation_error", "saved_report_id",
+			"Field 'saved_report_id' is required for saved reports and not allowed otherwise.")
+	}
+	if req.RecipientID == uuid.Nil {
+		return respondFieldError(c, "validation_error", "recipient_id",
+			"Field 'recipient_id' is required.")
+	}
+	if req.Format == "" {
+		req.Format = reports.FormatPDF
+	}
+	if req.Frequency == "" {
+		req.Frequency = reports.DefaultFrequency(req.Report)
+	}
+	hour := int32(defaultSendHour)
+	if req.SendHour != nil {
+		hour = *req.SendHour
+	}
+	if ok, err := validReportSchedule(c, req.Format, req.Frequency, hour); !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.queries.GetReportRecipient(ctx, req.RecipientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RespondError(c, http.StatusBadRequest, "invalid_recipient",
+				"The specified recipient does not exist.")
+		}
+		return HandleDatabaseError(c, err, "Recipient")
+	}
+	var saved uuid.NullUUID
+	if req.SavedReportID != nil {
+		if _, err := s.queries.GetSavedReport(ctx, *req.SavedReportID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return RespondError(c, http.StatusBadRequest, "invalid_saved_report",
+					"The specified saved report does not exist.")
+			}
+			return HandleDatabaseError(c, err, "Saved report")
+		}
+		saved = uuid.NullUUID{UUID: *req.SavedReportID, Valid: true}
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	schedule, err := s.queries.CreateReportSchedule(ctx, db.CreateReportScheduleParams{
+		Report:        req.Report,
+		RecipientID:   req.RecipientID,
+		Format:        req.Format,
+		Frequency:     req.Frequency,
+		SendHour:      hour,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+		NextRunAt:     s.reports.Calendar().NextRun(req.Frequency, int(hour), time.Now()),
+		CreatedBy:     uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		SavedReportID: saved,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Report schedule")
+	}
+
+	s.logAudit(ctx, userID, "create", "report_schedule", schedule.ID.String(),
+		nil, reportScheduleAudit(schedule),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, reportScheduleResponse(schedule))
+}
+
+// ListReportSchedules handles GET /api/v1/report-schedules; ?recipient_id=
+// narrows the list to one user
+func (s *Server) ListReportSchedules(c echo.Context) error {
+	var recipient uuid.NullUUID
+	if raw := c.QueryParam("recipient_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_id",
+				"The provided recipient_id is not a valid UUID.")
+		}
+		recipient = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	page, ok := parseOffsetPage(c, 50)
+	if !ok {
+		return nil
+	}
+
+	rows, err := s.queries.ListReportSchedules(ctx, db.ListReportSchedulesParams{
+		RecipientID: recipient,
+		LimitCount:  int32(page.Limit),
+		OffsetCount: int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch report schedules.")
+	}
+
+	schedules := make([]ReportSchedule, len(rows))
+	for i, row := range rows {
+		schedules[i] = reportScheduleResponse(row)
+	}
+	var filter any
+	if recipient.Valid {
+		filter = recipient
+	}
+	total := s.listTotal(ctx, "report_schedules", filter, func(ctx context.Context) (int64, error) {
+		return s.queries.CountReportSchedules(ctx, recipient)
+	})
+	return respondOffsetPage(c, page, total, len(rows), schedules)
+}
+
+// GetReportSchedule handles GET /api/v1/report-schedules/:id
+func (s *Server) GetReportSchedule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	schedule, err := s.queries.GetReportSchedule(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Report schedule")
+	}
+	return RespondSuccess(c, http.StatusOK, reportScheduleResponse(schedule))
+}
+
+// UpdateReportSchedule handles PUT /api/v1/report-schedules/:id. Changing
+// the frequency or send hour moves the next run accordingly.
+func (s *Server) UpdateReportSchedule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateReportScheduleReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetReportSchedule(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Report schedule")
+	}
+
+	params := db.UpdateReportScheduleParams{
+		ID:        id,
+		Format:    old.Format,
+		Frequency: old.Frequency,
+		SendHour:  old.SendHour,
+		Enabled:   old.Enabled,
+		NextRunAt: old.NextRunAt,
+	}
+	if req.Format != "" {
+		params.Format = req.Format
+	}
+	if req.Frequency != "" {
+		params.Frequency = req.Frequency
+	}
+	if req.SendHour != nil {
+		params.SendHour = *req.SendHour
+	}
+	if req.Enabled != nil {
+		params.Enabled = *req.Enabled
+	}
+	if ok, err := validReportSchedule(c, params.Format, params.Frequency, params.SendHour); !ok {
+		return err
+	}
+	if params.Frequency != old.Frequency || params.SendHour != old.SendHour || (params.Enabled && !old.Enabled) {
+		params.NextRunAt = s.reports.Calendar().NextRun(params.Frequency, int(params.SendHour), time.Now())
+	}
+
+	schedule, err := s.queries.UpdateReportSchedule(ctx, params)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Report schedule")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "report_schedule", id.String(),
+		reportScheduleAudit(old), reportScheduleAudit(schedule),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, reportScheduleResponse(schedule))
+}
+
+// DeleteReportSchedule handles DELETE /api/v1/report-schedules/:id
+func (s *Server) DeleteReportSchedule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetReportSchedule(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Report schedule")
+	}
+	if err := s.queries.DeleteReportSchedule(ctx, id); err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to delete report schedule.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "delete", "report_schedule", id.String(),
+		reportScheduleAudit(old), nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RunReportSchedule handles POST /api/v1/report-schedules/:id/run. The
+// report covering the period up to today is emailed now; the next
+// scheduled run is unchanged.
+func (s *Server) RunReportSchedule(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	if !s.notifier.Enabled(notify.ChannelEmail) {
+		return RespondError(c, http.StatusServiceUnavailable, "email_not_configured",
+			"Email delivery is not configured. Set notify.smtp.host to send reports.")
+	}
+
+	ctx := c.Request().Context()
+	schedule, err := s.queries.GetReportSchedule(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Report schedule")
+	}
+
+	now := time.Now()
+	status, sendErr := s.reports.Send(ctx, schedule, now)
+	err = s.queries.MarkReportScheduleRun(ctx, db.MarkReportScheduleRunParams{
+		ID:         id,
+		LastRunAt:  sql.NullTime{Time: now, Valid: true},
+		LastStatus: sql.NullString{String: status, Valid: true},
+		LastError:  sql.NullString{String: errorDetail(sendErr), Valid: sendErr != nil},
+		NextRunAt:  schedule.NextRunAt,
+	})
+	if err != nil {
+		s.logger.Error("Failed to record report run", err, map[string]any{"schedule_id": id.String()})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "run", "report_schedule", id.String(),
+		nil, map[string]any{"status": status},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, ReportRun{ScheduleID: id, Status: status, Detail: errorDetail(sendErr)})
+}
+
+// validReportSchedule checks the format, frequency and send hour, writing
+// the error response when one is invalid
+func validReportSchedule(c echo.Context, format, frequency string, hour int32) (bool, error) {
+	if !reports.ValidFormat(format) {
+		return false, RespondError(c, http.StatusBadRequest, "invalid_format",
+			"format must be one of "+strings.Join(reports.Formats, ", ")+".")
+	}
+	if !reports.ValidFrequency(frequency) {
+		return false, RespondError(c, http.StatusBadRequest, "invalid_frequency",
+			"frequency must be one of "+strings.Join(reports.Frequencies, ", ")+".")
+	}
+	if hour < 0 || hour > 23 {
+		return false, respondFieldError(c, "validation_error", "send_hour",
+			"Field 'send_hour' must be between 0 and 23.")
+	}
+	return true, nil
+}
+
+func errorDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func reportScheduleAudit(r db.ReportSchedule) map[string]any {
+	audit := map[string]any{
+		"report":       r.Report,
+		"recipient_id": r.RecipientID.String(),
+		"format":       r.Format,
+		"frequency":    r.Frequency,
+		"send_hour":    r.SendHour,
+		"enabled":      r.Enabled,
+	}
+	if r.SavedReportID.Valid {
+		audit["saved_report_id"] = r.SavedReportID.UUID.String()
+	}
+	return audit
+}
+
+func reportScheduleResponse(r db.ReportSchedule) ReportSchedule {
+	resp := ReportSchedule{
+		ID:          r.ID,
+		Report:      r.Report,
+		RecipientID: r.RecipientID,
+		Format:      r.Format,
+		Frequency:   r.Frequency,
+		SendHour:    r.SendHour,
+		Enabled:     r.Enabled,
+		NextRunAt:   r.NextRunAt,
+		LastStatus:  r.LastStatus.String,
+		LastError:   r.LastError.String,
+		CreatedAt:   r.CreatedAt,
+	}
+	if r.LastRunAt.Valid {
+		resp.LastRunAt = &r.LastRunAt.Time
+	}
+	if r.CreatedBy.Valid {
+		resp.CreatedBy = &r.CreatedBy.UUID
+	}
+	if r.SavedReportID.Valid {
+		resp.SavedReportID = &r.SavedReportID.UUID
+	}
+	return resp
+}
@@ -0,0 +1,336 @@
+// internal/server/reports.go - Order statistics, demand forecasts, user activity and change frequency
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/jalali"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/labstack/echo/v4"
+)
+
+// GetOrderTimeSeries handles GET /api/v1/reports/orders/timeseries. It
+// counts orders, items and requested quantity per day, week or month of
+// the reporting calendar, optionally split by status, category or
+// department. from and to are dates or RFC 3339 times, a to date
+// included, and default to the last 30 days, 12 weeks or 12 months; the
+// range is widened to whole buckets. With calendar=jalali the dates are
+// Jalali and every point also has its Jalali start date; buckets stay
+// Gregorian weeks and months.
+func (s *Server) GetOrderTimeSeries(c echo.Context) error {
+	granularity := c.QueryParam("granularity")
+	if granularity == "" {
+		granularity = reports.GranularityDay
+	}
+	if !reports.ValidGranularity(granularity) {
+		return RespondError(c, http.StatusBadRequest, "invalid_granularity",
+			"granularity must be one of "+strings.Join(reports.Granularities, ", ")+".")
+	}
+	groupBy := c.QueryParam("group_by")
+	if !reports.ValidGroupBy(groupBy) {
+		return RespondError(c, http.StatusBadRequest, "invalid_group_by",
+			"group_by must be one of "+strings.Join(reports.GroupBys, ", ")+".")
+	}
+
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	cal := s.reports.Calendar()
+	cal.Jalali = calendar == jalali.Jalali
+	to := time.Now()
+	if raw := c.QueryParam("to"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, true)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"to must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		to = t
+	}
+	var from time.Time
+	switch granularity {
+	case reports.GranularityMonth:
+		from = to.AddDate(0, -12, 0)
+	case reports.GranularityWeek:
+		from = to.AddDate(0, 0, -12*7)
+	default:
+		from = to.AddDate(0, 0, -30)
+	}
+	if raw := c.QueryParam("from"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, false)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"from must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be before to.")
+	}
+
+	ts, err := reports.BuildOrderTimeSeries(c.Request().Context(), s.queries, cal, granularity, groupBy, from, to)
+	if errors.Is(err, reports.ErrTooManyBuckets) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range",
+			"The range is too long for this granularity; use a larger one or a shorter range.")
+	}
+	if err != nil {
+		s.logger.Error("Failed to build order time series", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order statistics.")
+	}
+	return RespondSuccess(c, http.StatusOK, ts)
+}
+
+// GetProductForecast handles GET /api/v1/products/:id/forecast. Past
+// demand is the quantity requested per bucket of the reporting calendar;
+// granularity (week), history (12), window (4) and horizon (4) size the
+// forecast.
+func (s *Server) GetProductForecast(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	opts := reports.ForecastOptions{
+		Granularity: c.QueryParam("granularity"),
+		History:     12,
+		Window:      4,
+		Horizon:     4,
+	}
+	if opts.Granularity == "" {
+		opts.Granularity = reports.GranularityWeek
+	}
+	params := []struct {
+		name  string
+		field *int
+	}{{"history", &opts.History}, {"window", &opts.Window}, {"horizon", &opts.Horizon}}
+	for _, p := range params {
+		raw := c.QueryParam(p.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "validation_error",
+				fmt.Sprintf("Parameter '%s' must be a whole number.", p.name))
+		}
+		*p.field = n
+	}
+	if err := reports.ValidateForecastOptions(opts); err != nil {
+		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireProduct(c, id); !ok {
+		return err
+	}
+
+	forecast, err := reports.BuildForecast(ctx, s.queries, s.reports.Calendar(), id, opts, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to build demand forecast", err, map[string]any{"product_id": id.String()})
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to forecast demand.")
+	}
+	return RespondSuccess(c, http.StatusOK, forecast)
+}
+
+// UserActivityReport sums up what each user did in [From, To)
+type UserActivityReport struct {
+	From  time.Time          `json:"from"`
+	To    time.Time          `json:"to"`
+	Total UserActivityTotals `json:"total"`
+	Users []UserActivity     `json:"users"`
+}
+
+// UserActivity is one user's line of the activity report. ItemsAdded
+// counts the items of the orders the user created; Approvals counts the
+// orders they moved to approved.
+type UserActivity struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Username      string    `json:"username"`
+	FullName      string    `json:"full_name,omitempty"`
+	Role          string    `json:"role,omitempty"`
+	OrdersCreated int64     `json:"orders_created"`
+	ItemsAdded    int64     `json:"items_added"`
+	Approvals     int64     `json:"approvals"`
+	Logins        int64     `json:"logins"`
+}
+
+// UserActivityTotals adds up the users of the report
+type UserActivityTotals struct {
+	OrdersCreated int64 `json:"orders_created"`
+	ItemsAdded    int64 `json:"items_added"`
+	Approvals     int64 `json:"approvals"`
+	Logins        int64 `json:"logins"`
+}
+
+// GetUserActivityReport handles GET /api/v1/reports/users/activity. from,
+// to and calendar work as for the order time series, the range defaulting
+// to the last 30 days; user_id limits the report to one user. Users
+// without activity are listed with zeros; with active_only=true they are
+// left out.
+func (s *Server) GetUserActivityReport(c echo.Context) error {
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	cal := s.reports.Calendar()
+	to := time.Now()
+	if raw := c.QueryParam("to"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, true)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"to must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if raw := c.QueryParam("from"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, false)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"from must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be before to.")
+	}
+
+	var userID uuid.NullUUID
+	if raw := c.QueryParam("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_user_id",
+				"The provided user ID is not a valid UUID.")
+		}
+		userID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	activeOnly := c.QueryParam("active_only") == "true"
+
+	rows, err := s.queries.ReportUserActivity(c.Request().Context(), db.ReportUserActivityParams{
+		FromTime: from,
+		ToTime:   to,
+		UserID:   userID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to build user activity report", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch user activity.")
+	}
+
+	report := UserActivityReport{From: from, To: to, Users: []UserActivity{}}
+	for _, row := range rows {
+		if activeOnly && row.OrdersCreated+row.Approvals+row.Logins == 0 {
+			continue
+		}
+		report.Users = append(report.Users, UserActivity{
+			UserID:        row.UserID,
+			Username:      row.Username,
+			FullName:      row.FullName,
+			Role:          row.Role,
+			OrdersCreated: row.OrdersCreated,
+			ItemsAdded:    row.ItemsAdded,
+			Approvals:     row.Approvals,
+			Logins:        row.Logins,
+		})
+		report.Total.OrdersCreated += row.OrdersCreated
+		report.Total.ItemsAdded += row.ItemsAdded
+		report.Total.Approvals += row.Approvals
+		report.Total.Logins += row.Logins
+	}
+	return RespondSuccess(c, http.StatusOK, report)
+}
+
+// GetChangeReport handles GET /api/v1/reports/changes: how often each
+// entity type changed per day, week or month (default week) of the
+// reporting calendar, by action and by user, from the audit log. from,
+// to and calendar work as for the order time series. entity_type (comma-separated) and
+// user_id narrow the entries counted; most_changed (default 10) entities
+// of each type are listed.
+func (s *Server) GetChangeReport(c echo.Context) error {
+	granularity := c.QueryParam("granularity")
+	if granularity == "" {
+		granularity = reports.GranularityWeek
+	}
+	if !reports.ValidGranularity(granularity) {
+		return RespondError(c, http.StatusBadRequest, "invalid_granularity",
+			"granularity must be one of "+strings.Join(reports.Granularities, ", ")+".")
+	}
+
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	cal := s.reports.Calendar()
+	cal.Jalali = calendar == jalali.Jalali
+	to := time.Now()
+	if raw := c.QueryParam("to"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, true)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"to must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		to = t
+	}
+	var from time.Time
+	switch granularity {
+	case reports.GranularityMonth:
+		from = to.AddDate(0, -12, 0)
+	case reports.GranularityWeek:
+		from = to.AddDate(0, 0, -12*7)
+	default:
+		from = to.AddDate(0, 0, -30)
+	}
+	if raw := c.QueryParam("from"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, false)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"from must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be before to.")
+	}
+
+	filter := reports.ChangeFilter{MostChanged: 10}
+	if raw := c.QueryParam("entity_type"); raw != "" {
+		filter.EntityTypes = strings.Split(raw, ",")
+	}
+	if raw := c.QueryParam("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "invalid_user_id",
+				"The provided user ID is not a valid UUID.")
+		}
+		filter.UserID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if raw := c.QueryParam("most_changed"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > reports.MaxMostChanged {
+			return RespondError(c, http.StatusBadRequest, "validation_error",
+				fmt.Sprintf("most_changed must be a whole number between 0 and %d.", reports.MaxMostChanged))
+		}
+		filter.MostChanged = n
+	}
+
+	report, err := reports.BuildChangeReport(c.Request().Context(), s.queries, cal, granularity, from, to, filter)
+	if errors.Is(err, reports.ErrTooManyBuckets) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range",
+			"The range is too long for this granularity; use a larger one or a shorter range.")
+	}
+	if err != nil {
+		s.logger.Error("Failed to build change report", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch change statistics.")
+	}
+	return RespondSuccess(c, http.StatusOK, report)
+}
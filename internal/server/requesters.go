@@ -0,0 +1,437 @@
+// internal/server/requesters.go - Patients and departments orders are placed for
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+// RequesterReq defines the request body for creating or replacing a
+// requester. Reference is an identifier such as a medical record number;
+// keep it and the name to what is needed to tell requesters apart.
+type RequesterReq struct {
+	Kind         string `json:"kind" validate:"required,oneof=patient department"`
+	Name         string `json:"name" validate:"required,max=200"`
+	Reference    string `json:"reference,omitempty" validate:"max=100"`
+	DepartmentID *int32 `json:"department_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+// SetOrderRequesterReq links an order to a requester; null unlinks it
+type SetOrderRequesterReq struct {
+	RequesterID *uuid.UUID `json:"requester_id"`
+}
+
+// Requester is a patient or department orders are placed for
+type Requester struct {
+	ID           uuid.UUID `json:"id"`
+	Kind         string    `json:"kind"`
+	Name         string    `json:"name"`
+	Reference    string    `json:"reference,omitempty"`
+	DepartmentID *int32    `json:"department_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RequesterReportRow counts the orders for a requester
+type RequesterReportRow struct {
+	Requester
+	Orders      int64     `json:"orders"`
+	Items       int64     `json:"items"`
+	Quantity    int64     `json:"quantity"`
+	LastOrderAt time.Time `json:"last_order_at"`
+}
+
+// RequesterReport lists the requesters with orders created in [From, To)
+type RequesterReport struct {
+	From       time.Time            `json:"from"`
+	To         time.Time            `json:"to"`
+	Requesters []RequesterReportRow `json:"requesters"`
+}
+
+func requesterResponse(r db.Requester) Requester {
+	resp := Requester{
+		ID:        r.ID,
+		Kind:      r.Kind,
+		Name:      r.Name.String,
+		Reference: r.Reference.String,
+		CreatedAt: r.CreatedAt,
+	}
+	if r.DepartmentID.Valid {
+		resp.DepartmentID = &r.DepartmentID.Int32
+	}
+	return resp
+}
+
+// department returns the department of the request, unset without one
+func (r RequesterReq) department() sql.NullInt32 {
+	if r.DepartmentID == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *r.DepartmentID, Valid: true}
+}
+
+func requesterCursor(r db.Requester) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// bindRequester reads and checks a requester request, writing the error
+// response when it is invalid
+func (s *Server) bindRequester(c echo.Context) (RequesterReq, bool, error) {
+	var req RequesterReq
+	if err := c.Bind(&req); err != nil {
+		return req, false, respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return req, false, respondValidationError(c, err)
+	}
+	if req.DepartmentID != nil {
+		if ok, err := s.requireDepartment(c, *req.DepartmentID); !ok {
+			return req, false, err
+		}
+	}
+	return req, true, nil
+}
+
+// requireRequester responds with invalid_requester unless the requester
+// exists
+func (s *Server) requireRequester(c echo.Context, id uuid.UUID) (bool, error) {
+	_, err := s.queries.GetRequester(c.Request().Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, respondFieldError(c, "invalid_requester", "requester_id",
+			fmt.Sprintf("Requester with ID %s does not exist.", id))
+	}
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Requester")
+	}
+	return true, nil
+}
+
+// requesterNames returns a function naming the requester of an order for
+// exports, looking each requester up once
+func (s *Server) requesterNames() func(ctx context.Context, id uuid.NullUUID) string {
+	names := make(map[uuid.UUID]string)
+	return func(ctx context.Context, id uuid.NullUUID) string {
+		if !id.Valid {
+			return ""
+		}
+		name, ok := names[id.UUID]
+		if !ok {
+			if r, err := s.queries.GetRequester(ctx, id.UUID); err == nil {
+				name = r.Name.String
+			}
+			names[id.UUID] = name
+		}
+		return name
+	}
+}
+
+// ListRequesters handles GET /api/v1/requesters, newest first. kind and
+// department_id narrow the list; names are encrypted, so there is no
+// search by name.
+func (s *Server) ListRequesters(c echo.Context) error {
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
+	}
+
+	var filter db.CountRequestersParams
+	if kind := c.QueryParam("kind"); kind != "" {
+		if kind != "patient" && kind != "department" {
+			return respondFieldError(c, "validation_error", "kind",
+				"kind must be patient or department.")
+		}
+		filter.Kind = sql.NullString{String: kind, Valid: true}
+	}
+	if raw := c.QueryParam("department_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || id <= 0 {
+			return respondFieldError(c, "invalid_department", "department_id",
+				"department_id must be a positive whole number.")
+		}
+		filter.DepartmentID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	rows, err := s.queries.ListRequesters(ctx, db.ListRequestersParams{
+		Kind:         filter.Kind,
+		DepartmentID: filter.DepartmentID,
+		AfterTime:    page.AfterTime(),
+		AfterID:      page.AfterID(),
+		Limit:        int32(page.Limit),
+		Offset:       int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve requesters.")
+	}
+
+	var signature any
+	if filter.Kind.Valid || filter.DepartmentID.Valid {
+		signature = filter
+	}
+	total := s.listTotal(ctx, "requesters", signature, func(ctx context.Context) (int64, error) {
+		return s.queries.CountRequesters(ctx, filter)
+	})
+	requesters := make([]Requester, len(rows))
+	for i, r := range rows {
+		requesters[i] = requesterResponse(r)
+	}
+	return respondPage(c, page, total, rows, requesterCursor, requesters)
+}
+
+// GetRequester handles GET /api/v1/requesters/:id
+func (s *Server) GetRequester(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	requester, err := s.queries.GetRequester(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Requester")
+	}
+	return RespondSuccess(c, http.StatusOK, requesterResponse(requester))
+}
+
+// CreateRequester handles POST /api/v1/requesters. The audit entry keeps
+// the kind and department only, since audit values are not encrypted.
+func (s *Server) CreateRequester(c echo.Context) error {
+	req, ok, err := s.bindRequester(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	requester, err := s.queries.CreateRequester(ctx, db.CreateRequesterParams{
+		Kind:         req.Kind,
+		Name:         db.EncryptedString{String: req.Name, Valid: true},
+		Reference:    db.EncryptedString{String: req.Reference, Valid: req.Reference != ""},
+		DepartmentID: req.department(),
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Requester")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "create", "requester", requester.ID.String(),
+		nil, requesterAuditValues(requester), c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, requesterResponse(requester))
+}
+
+// UpdateRequester handles PUT /api/v1/requesters/:id, replacing the
+// requester's kind, name, reference and department
+func (s *Server) UpdateRequester(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	req, ok, err := s.bindRequester(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetRequester(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Requester")
+	}
+	requester, err := s.queries.UpdateRequester(ctx, db.UpdateRequesterParams{
+		ID:           id,
+		Kind:         req.Kind,
+		Name:         db.EncryptedString{String: req.Name, Valid: true},
+		Reference:    db.EncryptedString{String: req.Reference, Valid: req.Reference != ""},
+		DepartmentID: req.department(),
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Requester")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "requester", id.String(),
+		requesterAuditValues(old), requesterAuditValues(requester),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, requesterResponse(requester))
+}
+
+// DeleteRequester handles DELETE /api/v1/requesters/:id. Its orders are
+// kept, no longer linked to a requester.
+func (s *Server) DeleteRequester(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	deleted, err := s.queries.DeleteRequester(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Requester")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "Requester with the specified ID was not found.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "delete", "requester", id.String(),
+		nil, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetOrderRequester handles PUT /api/v1/orders/:id/requester, linking the
+// order to the requester it is for, or unlinking it with null
+func (s *Server) SetOrderRequester(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req SetOrderRequesterReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	var requesterID uuid.NullUUID
+	if req.RequesterID != nil {
+		if ok, err := s.requireRequester(c, *req.RequesterID); !ok {
+			return err
+		}
+		requesterID = uuid.NullUUID{UUID: *req.RequesterID, Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	var old, order db.Order
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		var err error
+		if old, err = q.GetOrder(ctx, id); err != nil {
+			return err
+		}
+		order, err = q.SetOrderRequester(ctx, db.SetOrderRequesterParams{
+			ID:          id,
+			RequesterID: requesterID,
+		})
+		return err
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "order", id.String(),
+		map[string]any{"requester_id": nullUUIDValue(old.RequesterID)},
+		map[string]any{"requester_id": nullUUIDValue(order.RequesterID)},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, order)
+}
+
+// GetRequesterReport handles GET /api/v1/reports/requesters: per
+// requester, the orders created for them in the range and the items and
+// quantity on those orders. from, to and calendar work as for the order
+// time series, the range defaulting to the last 30 days. With format=xlsx
+// the report is downloaded as an Excel sheet.
+func (s *Server) GetRequesterReport(c echo.Context) error {
+	calendar, ok := requestCalendar(c)
+	if !ok {
+		return invalidCalendar(c)
+	}
+	cal := s.reports.Calendar()
+	to := time.Now()
+	if raw := c.QueryParam("to"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, true)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"to must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if raw := c.QueryParam("from"); raw != "" {
+		t, ok := parseDateParam(raw, calendar, cal.Location, false)
+		if !ok {
+			return RespondError(c, http.StatusBadRequest, "invalid_range",
+				"from must be a date (YYYY-MM-DD) or an RFC 3339 time.")
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return RespondError(c, http.StatusBadRequest, "invalid_range", "from must be before to.")
+	}
+
+	rows, err := s.queries.ReportOrdersByRequester(c.Request().Context(), db.ReportOrdersByRequesterParams{
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		s.logger.Error("Failed to build requester report", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch the report.")
+	}
+
+	if wantsXLSX(c) {
+		header := []string{"Requester ID", "Kind", "Name", "Reference", "Department ID",
+			"Orders", "Items", "Quantity", "Last Order At"}
+		cells := make([][]any, len(rows))
+		for i, r := range rows {
+			var department any
+			if r.DepartmentID.Valid {
+				department = r.DepartmentID.Int32
+			}
+			cells[i] = []any{r.ID, r.Kind, r.Name.String, r.Reference.String, department,
+				r.Orders, r.Items, r.Quantity, r.LastOrderAt.In(cal.Location)}
+		}
+		return s.streamXLSX(c, "requesters", header, sliceSource(cells))
+	}
+
+	report := RequesterReport{From: from, To: to, Requesters: make([]RequesterReportRow, len(rows))}
+	for i, r := range rows {
+		report.Requesters[i] = RequesterReportRow{
+			Requester: requesterResponse(db.Requester{
+				ID:           r.ID,
+				Kind:         r.Kind,
+				Name:         r.Name,
+				Reference:    r.Reference,
+				DepartmentID: r.DepartmentID,
+			}),
+			Orders:      r.Orders,
+			Items:       r.Items,
+			Quantity:    r.Quantity,
+			LastOrderAt: r.LastOrderAt,
+		}
+	}
+	return RespondSuccess(c, http.StatusOK, report)
+}
+
+// requesterAuditValues are the fields of a requester kept in audit
+// entries, leaving out the encrypted name and reference
+func requesterAuditValues(r db.Requester) map[string]any {
+	values := map[string]any{"kind": r.Kind, "department_id": nil}
+	if r.DepartmentID.Valid {
+		values["department_id"] = r.DepartmentID.Int32
+	}
+	return values
+}
+
+// nullUUIDValue formats a nullable UUID for audit entries, nil when unset
+func nullUUIDValue(id uuid.NullUUID) any {
+	if !id.Valid {
+		return nil
+	}
+	return id.UUID.String()
+}
@@ -1,31 +1,154 @@
 package server
 
 import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jamalkaksouri/DigiOrder/internal/i18n"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
 	"github.com/labstack/echo/v4"
 )
 
 // ساختار استاندارد خطا
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Error   string       `json:"error"`
+	Message string       `json:"message,omitempty"` // Error in the client's language
+	Details string       `json:"details,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"` // Every rule broken, on 422 responses
+}
+
+// FieldError is one rule a well-formed request breaks: the field at
+// fault, when there is one, what is wrong with it as a code and in words
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
-// ساختار موفقیت
+// ساختار موفقیت. Version 1 sends the page of a list as meta and nothing
+// else; from version 2 every response has meta and links.
 type SuccessResponse struct {
-	Data any `json:"data"`
+	Data    any    `json:"data"`
+	Meta    any    `json:"meta,omitempty"` // *PageMeta in v1, ResponseMeta from v2
+	Links   *Links `json:"links,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// ResponseMeta describes the request a response answers, and the page it
+// holds when it is a list
+type ResponseMeta struct {
+	RequestID  string    `json:"request_id,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	Pagination *PageMeta `json:"pagination,omitempty"`
+}
+
+// Links are the request a response answers and, for a paged list, the
+// requests for the pages around it
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
 
 // هندلر برای خطا
 func RespondError(c echo.Context, code int, err string, details string) error {
 	return c.JSON(code, ErrorResponse{
 		Error:   err,
+		Message: localizedMessage(c, err),
 		Details: details,
 	})
 }
 
+// RespondUnprocessable answers a request that is well-formed but breaks a
+// validation, state or business rule with 422 Unprocessable Entity. errs
+// lists the rules broken; without any the response lists err itself.
+// Malformed requests stay 400.
+func RespondUnprocessable(c echo.Context, err string, details string, errs ...FieldError) error {
+	if len(errs) == 0 {
+		errs = []FieldError{{Code: err, Message: details}}
+	}
+	return c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+		Error:   err,
+		Message: localizedMessage(c, err),
+		Details: details,
+		Errors:  errs,
+	})
+}
+
+// respondFieldError answers with 422 for a single field breaking a rule
+func respondFieldError(c echo.Context, err, field, details string) error {
+	return RespondUnprocessable(c, err, details, FieldError{Field: field, Code: err, Message: details})
+}
+
+// respondBindError answers a request that could not be bound. A field the
+// request type does not have is named; anything else is invalid_request
+// with details.
+func respondBindError(c echo.Context, err error, details string) error {
+	if unknown, ok := middleware.AsUnknownFieldError(err); ok {
+		return RespondError(c, http.StatusBadRequest, "unknown_field",
+			fmt.Sprintf("Unknown field %q; check its spelling against the API documentation.", unknown.Field))
+	}
+	return RespondError(c, http.StatusBadRequest, "invalid_request", details)
+}
+
+// localizedMessage is the message for an error code in the language of
+// the request, "" when the code has none
+func localizedMessage(c echo.Context, code string) string {
+	lang := requestLanguage(c)
+	msg, ok := i18n.Message(lang, code)
+	if !ok {
+		return ""
+	}
+	c.Response().Header().Set("Content-Language", lang)
+	return msg
+}
+
+// requestLanguage is the language the signed-in user chose, else the one
+// Accept-Language prefers, else English
+func requestLanguage(c echo.Context) string {
+	if lang := middleware.GetLanguageFromContext(c); i18n.IsSupported(lang) {
+		return lang
+	}
+	if lang := i18n.Negotiate(c.Request().Header.Get("Accept-Language")); lang != "" {
+		return lang
+	}
+	return i18n.Default
+}
+
 // هندلر برای موفقیت
 func RespondSuccess(c echo.Context, code int, data any) error {
-	return c.JSON(code, SuccessResponse{
-		Data: data,
-	})
+	return c.JSON(code, envelope(c, data, nil, Links{}))
+}
+
+// RespondList writes a 200 response holding a page of a list, described
+// by meta, with links to the pages around it
+func RespondList(c echo.Context, data any, meta PageMeta, links Links) error {
+	return c.JSON(http.StatusOK, envelope(c, data, &meta, links))
+}
+
+// envelope wraps data in the success response of the request's API
+// version. Version 1 clients get the shape they were built against.
+func envelope(c echo.Context, data any, page *PageMeta, links Links) SuccessResponse {
+	resp := SuccessResponse{
+		Data:    data,
+		Warning: middleware.GetDeprecationWarning(c),
+	}
+	if middleware.GetAPIVersion(c) < 2 {
+		if page != nil {
+			resp.Meta = page
+		}
+		return resp
+	}
+
+	meta := ResponseMeta{
+		RequestID:  middleware.GetRequestID(c),
+		Pagination: page,
+	}
+	if start := middleware.GetRequestStart(c); !start.IsZero() {
+		meta.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	}
+	links.Self = c.Request().URL.RequestURI()
+	resp.Meta, resp.Links = meta, &links
+	return resp
 }
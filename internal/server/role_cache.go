@@ -0,0 +1,133 @@
+// internal/server/role_cache.go - Cached role and permission lookups
+package server
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+)
+
+// roleCache keeps roles and the permissions granted to them for ttl, as
+// logins, profiles, user lists and the RequirePermission check of nearly
+// every route look them up. A role's grants are loaded together, so one
+// query answers every resource and action checked for the role until the
+// entry expires. Changes made through the API invalidate it at once;
+// changes made directly in the database or on another node show once the
+// ttl runs out. Lookups are counted in cache_hits_total and
+// cache_misses_total as the roles and permissions caches.
+type roleCache struct {
+	queries db.Querier
+	ttl     time.Duration // 0 reads through on every call
+
+	mu          sync.Mutex
+	roles       map[int32]cachedRole
+	permissions map[int32]cachedPermissions
+	generation  uint64 // bumped by invalidate
+}
+
+type cachedRole struct {
+	role    db.Role
+	expires time.Time
+}
+
+type cachedPermissions struct {
+	list    []db.Permission
+	granted map[string]bool // by resource + ":" + action
+	expires time.Time
+}
+
+func newRoleCache(queries db.Querier, ttl time.Duration) *roleCache {
+	return &roleCache{
+		queries:     queries,
+		ttl:         ttl,
+		roles:       make(map[int32]cachedRole),
+		permissions: make(map[int32]cachedPermissions),
+	}
+}
+
+// role returns the role with id. Errors, including sql.ErrNoRows for a
+// missing role, are returned as GetRole gives them and not cached.
+func (rc *roleCache) role(ctx context.Context, id int32) (db.Role, error) {
+	rc.mu.Lock()
+	entry, ok := rc.roles[id]
+	generation := rc.generation
+	rc.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		middleware.RecordCacheHit(middleware.CacheRoles)
+		return entry.role, nil
+	}
+	middleware.RecordCacheMiss(middleware.CacheRoles)
+
+	role, err := rc.queries.GetRole(ctx, id)
+	if err != nil {
+		return role, err
+	}
+	if rc.ttl > 0 {
+		rc.mu.Lock()
+		if rc.generation == generation {
+			rc.roles[id] = cachedRole{role: role, expires: time.Now().Add(rc.ttl)}
+		}
+		rc.mu.Unlock()
+	}
+	return role, nil
+}
+
+// permissionsOf returns the permissions granted to a role, ordered by
+// resource and action
+func (rc *roleCache) permissionsOf(ctx context.Context, roleID int32) ([]db.Permission, error) {
+	entry, err := rc.permissionSet(ctx, roleID)
+	return slices.Clone(entry.list), err
+}
+
+// hasPermission reports whether a role is granted action on resource
+func (rc *roleCache) hasPermission(ctx context.Context, roleID int32, resource, action string) (bool, error) {
+	entry, err := rc.permissionSet(ctx, roleID)
+	return entry.granted[resource+":"+action], err
+}
+
+func (rc *roleCache) permissionSet(ctx context.Context, roleID int32) (cachedPermissions, error) {
+	rc.mu.Lock()
+	entry, ok := rc.permissions[roleID]
+	generation := rc.generation
+	rc.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		middleware.RecordCacheHit(middleware.CachePermissions)
+		return entry, nil
+	}
+	middleware.RecordCacheMiss(middleware.CachePermissions)
+
+	list, err := rc.queries.GetRolePermissions(ctx, roleID)
+	if err != nil {
+		return cachedPermissions{}, err
+	}
+	entry = cachedPermissions{
+		list:    list,
+		granted: make(map[string]bool, len(list)),
+		expires: time.Now().Add(rc.ttl),
+	}
+	for _, p := range list {
+		entry.granted[p.Resource+":"+p.Action] = true
+	}
+	if rc.ttl > 0 {
+		rc.mu.Lock()
+		if rc.generation == generation {
+			rc.permissions[roleID] = entry
+		}
+		rc.mu.Unlock()
+	}
+	return entry, nil
+}
+
+// invalidate drops every cached role and permission set. Lookups already
+// reading from the database when it is called do not store their result.
+func (rc *roleCache) invalidate() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	clear(rc.roles)
+	clear(rc.permissions)
+	rc.generation++
+}
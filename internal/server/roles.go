@@ -2,8 +2,8 @@ package server
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
-	"strconv"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/labstack/echo/v4"
@@ -23,11 +23,11 @@ type UpdateRoleReq struct {
 func (s *Server) CreateRole(c echo.Context) error {
 	var req CreateRoleReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -56,16 +56,15 @@ func (s *Server) ListRoles(c echo.Context) error {
 
 // GetRole handles GET /api/v1/roles/:id
 func (s *Server) GetRole(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "The provided ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	role, err := s.queries.GetRole(ctx, int32(id))
+	role, err := s.queries.GetRole(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "Role with the specified ID was not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve role.")
@@ -76,50 +75,50 @@ func (s *Server) GetRole(c echo.Context) error {
 
 // UpdateRole handles PUT /api/v1/roles/:id
 func (s *Server) UpdateRole(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "The provided ID is not a valid number.")
+		return err
 	}
 
 	var req UpdateRoleReq
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request", "The request body is not valid.")
+		return respondBindError(c, err, "The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
 	role, err := s.queries.UpdateRole(ctx, db.UpdateRoleParams{
-		ID:   int32(id),
+		ID:   id,
 		Name: req.Name,
 	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondError(c, http.StatusNotFound, "not_found", "Role with the specified ID was not found.")
 		}
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to update role.")
 	}
+	s.roles.invalidate()
 
 	return RespondSuccess(c, http.StatusOK, role)
 }
 
 // DeleteRole handles DELETE /api/v1/roles/:id
 func (s *Server) DeleteRole(c echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
+	id, err := ParseInt(c, "id")
 	if err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_id", "The provided ID is not a valid number.")
+		return err
 	}
 
 	ctx := c.Request().Context()
-	err = s.queries.DeleteRole(ctx, int32(id))
+	err = s.queries.DeleteRole(ctx, id)
 	if err != nil {
 		// Check if there are users still using this role
 		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to delete role. It may be in use by existing users.")
 	}
+	s.roles.invalidate()
 
 	return c.NoContent(http.StatusNoContent)
 }
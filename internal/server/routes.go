@@ -13,23 +13,30 @@ import (
 )
 
 func (s *Server) registerRoutes() {
-	// Initialize enhanced rate limiter with IP ban tracking
-	rateLimiter := middleware.NewEnhancedRateLimiter(s.queries, 100, 200)
+	// Enhanced rate limiter with IP ban tracking (limits reload on SIGHUP)
+	rateLimiter := s.ipLimiter
 
 	// Initialize metrics collector
 	metricsCollector := middleware.NewMetricsCollector()
 
-	// Initialize request logger
-	requestLogger := middleware.NewRequestLogger(s.router.Logger)
-
 	// Global middleware
-	s.router.Use(echomiddleware.Logger())
 	s.router.Use(echomiddleware.Recover())
-	s.router.Use(echomiddleware.RequestID())
-	s.router.Use(echomiddleware.Secure())
+	s.router.Use(echomiddleware.RequestIDWithConfig(echomiddleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, requestID string) {
+			c.Set("request_id", requestID)
+			c.Set("request_start", time.Now())
+		},
+	}))
+
+	// Structured access logging (single pipeline, see internal/logging)
+	s.router.Use(logging.LoggingMiddleware(s.logger))
+
+	// Tag SQL statements with the request ID for pg_stat_activity correlation
+	s.router.Use(middleware.QueryTagMiddleware())
+
+	s.router.Use(middleware.SecurityHeadersMiddleware())
 
 	// Custom middleware
-	s.router.Use(requestLogger.Middleware())
 	s.router.Use(metricsCollector.Middleware())
 
 	// Set custom error handler
@@ -37,57 +44,147 @@ func (s *Server) registerRoutes() {
 
 	// Public endpoints (NO AUTH REQUIRED)
 	s.router.GET("/health", s.healthCheck)
-	s.router.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
-
-	// Structured logging middleware
-	if s.logger != nil {
-		s.router.Use(logging.LoggingMiddleware(s.logger))
+	s.router.GET("/livez", s.livenessCheck)
+	s.router.GET("/readyz", s.readinessCheck)
+	if s.config.Features.Metrics {
+		s.router.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 	}
+	// Keys for verifying access tokens signed with RS256 or EdDSA
+	s.router.GET(middleware.JWKSPath, s.GetJWKS)
+
+	// Secure CORS (allowed origins reload on SIGHUP)
+	s.router.Use(middleware.ReloadableCORSMiddleware(middleware.DefaultCORSConfig(), s.corsOrigins))
 
-	// Secure CORS
-	s.router.Use(middleware.SecureCORSMiddleware())
+	// Planned maintenance (503 for everything except health, login and admins)
+	s.router.Use(s.maintenance.Middleware())
 
 	// PRODUCTION RATE LIMITING - Apply to all routes
-	s.router.Use(middleware.ProductionRateLimitMiddleware(s.queries))
+	s.router.Use(rateLimiter.Middleware())
 
 	// Observability middleware
 	s.router.Use(middleware.PrometheusMiddleware())
 	s.router.Use(middleware.TracingMiddleware())
 
-	// API v1 group
-	api := s.router.Group("/api/v1")
+	// Per-consumer API usage (counted once the request has authenticated)
+	s.router.Use(s.usage.Middleware())
+
+	// API versions. Unversioned /api/... paths are mapped onto a version
+	// by Accept-Version before routing; every version shares the same
+	// handlers and branches on middleware.GetAPIVersion where shapes differ.
+	s.router.Pre(middleware.APIVersionRewrite())
+	s.router.Use(middleware.APIVersionMiddleware())
+
+	s.registerAPIRoutes(s.router.Group("/api/v1"), rateLimiter)
+	if s.config.Features.APIDocs {
+		// The spec needs a token; the Swagger UI page signs in before loading it
+		s.router.GET("/api/v1/openapi.json", s.GetOpenAPISpec, middleware.JWTMiddleware())
+		s.router.GET("/api/v1/docs", s.GetAPIDocs)
+		s.router.GET("/api/v1/docs/assets/*", s.serveAPIDocsAssets(s.swaggerFiles()))
+	}
+	if s.config.Features.APIV2 {
+		s.registerAPIRoutes(s.router.Group("/api/v2"), rateLimiter)
+	}
+
+	// The ERP may acknowledge batches with requests signed like our
+	// webhooks instead of a token. Registered once, outside the versions,
+	// so a request cannot be replayed against the other version's path.
+	if s.config.ERP.CallbackSecret != "" {
+		s.router.POST("/api/v1/callbacks/erp/ack", s.AckERPBatchCallback,
+			middleware.VerifySignature(s.config.ERP.CallbackSecret, s.config.ERP.CallbackTolerance))
+	}
+
+	// The web app on every other path, for installs without nginx
+	s.registerFrontend()
+}
+
+// responseCache returns the GET response cache for a route group, or a
+// pass-through when the response_cache feature is disabled. Every group
+// shares the server's cache and its size limits.
+func (s *Server) responseCache(ttl time.Duration) echo.MiddlewareFunc {
+	if !s.config.Features.ResponseCache || ttl <= 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	return middleware.CacheMiddleware(s.responses, ttl, http.StatusOK)
+}
 
+// registerAPIRoutes registers the versioned API surface on a group
+func (s *Server) registerAPIRoutes(api *echo.Group, rateLimiter *middleware.EnhancedRateLimiter) {
 	// ==================== PUBLIC AUTH ENDPOINTS ====================
 	auth := api.Group("/auth")
 	{
 		// Use consolidated auth handler (now includes comprehensive logging)
 		auth.POST("/login", s.Login)
 		auth.POST("/refresh", s.RefreshToken)
+		auth.POST("/logout", s.Logout)
+		auth.POST("/password-reset/request", s.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", s.ConfirmPasswordReset)
 	}
 
 	// Setup endpoints (before auth)
-	setup := api.Group("/setup")
-	{
+	if s.config.Features.SetupEndpoints {
+		setup := api.Group("/setup")
 		setup.GET("/status", s.GetSetupStatus)
 		setup.POST("/initialize", s.InitialSetup)
 	}
 
+	// Presigned downloads from local file storage; the URL signature
+	// replaces the token
+	api.GET("/files/*", s.ServeFile)
+
+	// Calendar apps fetch the order calendar with the feed token in the
+	// URL
+	api.GET("/calendar/feed.ics", s.ServeCalendarFeed)
+
 	// ==================== PROTECTED ENDPOINTS ====================
-	// JWT middleware for all protected routes
+	// JWT, personal access token or API key for all protected routes
 	protected := api.Group("")
-	protected.Use(middleware.JWTMiddleware())
+	protected.Use(middleware.AuthMiddleware(s.resolveAccessToken, s.resolveAPIKey))
+	// Per-key request limits for machine integrations
+	protected.Use(s.keyLimiter.Middleware())
+	// Row level security keeps each pharmacy, and non-admins each
+	// department or their own orders, to its own data
+	protected.Use(s.tenantMiddleware())
+	// Per-pharmacy request quotas (see Tenant Quotas in README.md)
+	protected.Use(s.quotas.Middleware())
 
 	// Auth profile endpoints (require authentication)
 	{
 		protected.GET("/auth/profile", s.GetProfile)
 		protected.PUT("/auth/password", s.ChangePassword)
+		protected.PUT("/auth/language", s.SetLanguage)
 		protected.GET("/auth/check-permission", s.CheckUserPermission)
+		protected.GET("/auth/permissions", s.GetEffectivePermissions)
 	}
 
-	// Admin security monitoring routes (admin only)
+	// Personal access tokens of the current user; tokens cannot manage
+	// tokens themselves
+	tokens := protected.Group("/auth/tokens")
+	tokens.Use(uuidParams("id"))
+	{
+		tokens.GET("", s.ListAccessTokens)
+		tokens.POST("", s.CreateAccessToken)
+		tokens.DELETE("/:id", s.RevokeAccessToken)
+	}
+
+	// Notification preferences for the current user
+	notifications := protected.Group("/notifications")
+	notifications.Use(uuidParams("id"))
+	{
+		notifications.GET("/preferences", s.GetNotificationPreferences)
+		notifications.PUT("/preferences", s.UpdateNotificationPreferences)
+		notifications.GET("/devices", s.ListDevices)
+		notifications.POST("/devices", s.RegisterDevice)
+		notifications.DELETE("/devices/:id", s.UnregisterDevice)
+	}
+
+	// Admin security monitoring routes (security:manage)
 	security := protected.Group("/security")
-	security.Use(middleware.RequireRole("admin"))
+	security.Use(middleware.RequirePermission("security", "manage"))
+	security.Use(uuidParams("id"))
 	{
+		// Everything below in one payload for the dashboard
+		security.GET("/overview", s.GetSecurityOverview)
+
 		// Login attempt monitoring
 		security.GET("/login-attempts", s.GetLoginAttempts)
 		security.GET("/login-attempts/report", s.GetLoginSecurityReport)
@@ -97,6 +194,18 @@ func (s *Server) registerRoutes() {
 		security.GET("/banned-ips", middleware.GetBannedIPsHandler(rateLimiter))
 		security.POST("/unban-ip", middleware.UnbanIPHandler(rateLimiter))
 
+		// Persistent IP allow and deny rules, enforced on every node
+		security.GET("/ip-rules", s.ListIPRules)
+		security.POST("/ip-rules", s.CreateIPRule)
+		security.GET("/ip-rules/:id", s.GetIPRule)
+		security.PUT("/ip-rules/:id", s.UpdateIPRule)
+		security.DELETE("/ip-rules/:id", s.DeleteIPRule)
+
+		// Impossible travel, new devices and unusual hours found in logins
+		security.GET("/events", s.ListSecurityEvents)
+		security.GET("/events/:id", s.GetSecurityEvent)
+		security.POST("/events/:id/acknowledge", s.AcknowledgeSecurityEvent)
+
 		// Manual rate limit management
 		security.POST("/release-ip", s.ManuallyReleaseIP)
 
@@ -105,82 +214,359 @@ func (s *Server) registerRoutes() {
 
 		// Get user login history
 		security.GET("/user/:username/login-history", s.GetUserLoginHistory)
+
+		// Slack / Teams security alerts
+		security.POST("/alerts/test", s.TestAlert)
+	}
+
+	// System administration routes (system:manage)
+	system := protected.Group("/system")
+	system.Use(middleware.RequirePermission("system", "manage"))
+	{
+		system.GET("/maintenance", s.GetMaintenanceStatus)
+		system.PUT("/maintenance", s.UpdateMaintenanceMode)
+		system.POST("/config/reload", s.ReloadConfigHandler)
+		system.GET("/self-test", s.GetSelfTestReport)
+		if s.config.IsDevelopment() {
+			system.POST("/demo-data", s.SeedDemoData)
+		}
+	}
+
+	// Diagnostics and backups (system:manage; see Slow Queries, API Usage and
+	// Backups in README.md)
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequirePermission("system", "manage"))
+	{
+		admin.GET("/slow-queries", s.GetSlowQueries)
+		admin.GET("/api-usage", s.GetAPIUsage)
+		if s.config.Tenancy.Enabled {
+			admin.GET("/quotas", s.GetQuotas)
+		}
+		admin.POST("/backup", s.StartBackup)
+		admin.GET("/backups", s.ListBackups)
+		admin.GET("/backups/:id", s.GetBackup, uuidParams("id"))
+		admin.POST("/backups/:id/verify", s.VerifyBackup, uuidParams("id"))
+		admin.POST("/backups/:id/restore", s.RestoreBackup, uuidParams("id"))
+		admin.GET("/jobs", s.ListScheduledJobs)
+		admin.POST("/jobs/:name/run", s.RunScheduledJob)
+		admin.PUT("/drug-interactions", s.ImportDrugInteractions)
+		admin.PUT("/drug-classes", s.ImportDrugClasses)
+	}
+
+	// API keys for machine integrations (see API Keys in README.md); keys
+	// and tokens cannot manage keys themselves
+	apiKeys := admin.Group("/api-keys")
+	apiKeys.Use(uuidParams("id"))
+	{
+		apiKeys.GET("", s.ListAPIKeys)
+		apiKeys.POST("", s.CreateAPIKey)
+		apiKeys.GET("/:id", s.GetAPIKey)
+		apiKeys.PUT("/:id", s.UpdateAPIKey)
+		apiKeys.DELETE("/:id", s.RevokeAPIKey)
+	}
+
+	// Service accounts for scanners and reporting jobs (see Service Accounts
+	// in README.md); like API keys they are managed with a login only
+	serviceAccounts := admin.Group("/service-accounts")
+	serviceAccounts.Use(uuidParams("id", "token_id"))
+	{
+		serviceAccounts.GET("", s.ListServiceAccounts)
+		serviceAccounts.POST("", s.CreateServiceAccount)
+		serviceAccounts.GET("/:id", s.GetServiceAccount)
+		serviceAccounts.DELETE("/:id", s.DeleteServiceAccount)
+		serviceAccounts.GET("/:id/tokens", s.ListServiceAccountTokens)
+		serviceAccounts.POST("/:id/tokens", s.CreateServiceAccountToken)
+		serviceAccounts.DELETE("/:id/tokens/:token_id", s.RevokeServiceAccountToken)
+	}
+
+	// Pharmacies sharing the deployment (tenants:manage in the main pharmacy; see
+	// Multi-Pharmacy in README.md)
+	if s.config.Tenancy.Enabled {
+		tenants := protected.Group("/tenants")
+		tenants.Use(middleware.RequirePermission("tenants", "manage"), requireMainTenant)
+		tenants.Use(uuidParams("id"))
+		{
+			tenants.GET("", s.ListTenants)
+			tenants.POST("", s.CreateTenant)
+			tenants.GET("/:id", s.GetTenant)
+			tenants.PUT("/:id", s.UpdateTenant)
+			tenants.PUT("/:id/quotas", s.SetTenantQuotas)
+		}
 	}
 
 	// Product routes (with caching for GET requests)
 	products := protected.Group("/products")
-	products.Use(middleware.CacheMiddleware(5*time.Minute, http.StatusOK))
+	products.Use(s.responseCache(s.config.Cache.ProductsTTL))
+	products.Use(uuidParams("id", "product_id"))
 	{
-		products.POST("", s.CreateProduct, middleware.RequireRole("admin", "pharmacist"))
+		products.POST("", s.CreateProduct, middleware.RequirePermission("products", "create"))
 		products.GET("", s.ListProducts)
 		products.GET("/search", s.SearchProducts)
 		products.GET("/barcode/:barcode", s.SearchProductByBarcode)
 		products.GET("/:id", s.GetProduct)
-		products.PUT("/:id", s.UpdateProduct, middleware.RequireRole("admin", "pharmacist"))
-		products.DELETE("/:id", s.DeleteProduct, middleware.RequireRole("admin"))
+		products.PUT("/:id", s.UpdateProduct, middleware.RequirePermission("products", "update"))
+		products.DELETE("/:id", s.DeleteProduct, middleware.RequirePermission("products", "delete"))
 		products.GET("/:product_id/barcodes", s.GetBarcodesByProduct)
 	}
 
+	// Product images live outside the cached group: responses carry
+	// presigned URLs that expire
+	productID := uuidParams("id")
+	{
+		protected.PUT("/products/:id/image", s.UploadProductImage, productID, middleware.RequirePermission("products", "update"))
+		protected.GET("/products/:id/image", s.GetProductImage, productID)
+		protected.DELETE("/products/:id/image", s.DeleteProductImage, productID, middleware.RequirePermission("products", "update"))
+	}
+
+	// Stock levels change too often to cache; they feed the low-stock report
+	// and demand forecasts
+	{
+		protected.GET("/products/:id/stock", s.GetProductStock, productID)
+		protected.PUT("/products/:id/stock", s.UpdateProductStock, productID, middleware.RequirePermission("stock", "update"))
+		protected.GET("/products/:id/forecast", s.GetProductForecast, productID, middleware.RequirePermission("stock", "forecast"))
+	}
+
+	// Label printing sends jobs to the network printers in labels.printers
+	{
+		protected.GET("/printers", s.ListLabelPrinters)
+		protected.POST("/products/:id/labels", s.PrintProductLabel, productID)
+	}
+
+	// National drug registry sync; new registry products land in staging
+	drugRegistry := protected.Group("/drug-registry")
+	drugRegistry.Use(middleware.RequirePermission("drug_registry", "read"))
+	drugRegistry.Use(uuidParams("id"))
+	{
+		drugRegistry.POST("/syncs", s.StartDrugRegistrySync, middleware.RequirePermission("drug_registry", "sync"))
+		drugRegistry.GET("/syncs", s.ListDrugRegistrySyncs)
+		drugRegistry.GET("/syncs/:id", s.GetDrugRegistrySync)
+	}
+
 	// Category routes
 	categories := protected.Group("/categories")
-	categories.Use(middleware.CacheMiddleware(10*time.Minute, http.StatusOK))
+	categories.Use(s.responseCache(s.config.Cache.CatalogTTL))
+	categories.Use(intParams("id"))
 	{
-		categories.POST("", s.CreateCategory, middleware.RequireRole("admin"))
+		categories.POST("", s.CreateCategory, middleware.RequirePermission("catalog", "manage"))
 		categories.GET("", s.ListCategories)
 		categories.GET("/:id", s.GetCategory)
 	}
 
 	// Dosage Form routes
 	dosageForms := protected.Group("/dosage_forms")
-	dosageForms.Use(middleware.CacheMiddleware(10*time.Minute, http.StatusOK))
+	dosageForms.Use(s.responseCache(s.config.Cache.CatalogTTL))
+	dosageForms.Use(intParams("id"))
 	{
-		dosageForms.POST("", s.CreateDosageForm, middleware.RequireRole("admin"))
+		dosageForms.POST("", s.CreateDosageForm, middleware.RequirePermission("catalog", "manage"))
 		dosageForms.GET("", s.ListDosageForms)
 		dosageForms.GET("/:id", s.GetDosageForm)
 	}
 
-	// Order routes
+	// Order status catalog
+	orderStatuses := protected.Group("/order-statuses")
+	{
+		orderStatuses.GET("", s.ListOrderStatuses)
+		orderStatuses.POST("", s.CreateOrderStatus, middleware.RequirePermission("order_statuses", "manage"))
+		orderStatuses.PUT("/:code", s.UpdateOrderStatusEntry, middleware.RequirePermission("order_statuses", "manage"))
+		orderStatuses.DELETE("/:code", s.DeleteOrderStatus, middleware.RequirePermission("order_statuses", "manage"))
+	}
+
+	// Order routes. Changes also pass the configured policies (see
+	// policies.go), which can depend on the order, e.g. its status.
 	orders := protected.Group("/orders")
+	orders.Use(uuidParams("id", "order_id", "attachment_id"))
 	{
-		orders.POST("", s.CreateOrder)
+		updateOrder := s.orderPolicy("update", orderParam("id"))
+		updateOrderItems := s.orderPolicy("update", orderParam("order_id"))
+		assignOrder := s.orderPolicy("assign", orderParam("id"))
+		orders.POST("", s.CreateOrder, s.quotas.OrderQuota)
+		orders.POST("/import", s.ImportOrder, s.quotas.OrderQuota)
 		orders.GET("", s.ListOrders)
+		orders.GET("/export", s.ExportOrdersCSV)
 		orders.GET("/:id", s.GetOrder)
-		orders.PUT("/:id/status", s.UpdateOrderStatus)
-		orders.DELETE("/:id", s.DeleteOrder, middleware.RequireRole("admin"))
-		orders.POST("/:order_id/items", s.CreateOrderItem)
+		orders.POST("/:id/duplicate", s.DuplicateOrder, s.quotas.OrderQuota)
+		orders.PUT("/:id/status", s.UpdateOrderStatus, updateOrder)
+		orders.POST("/:id/cancel", s.CancelOrder, updateOrder)
+		orders.GET("/:id/history", s.GetOrderStatusHistory)
+		orders.PUT("/:id/needed-by", s.UpdateOrderNeededBy, updateOrder)
+		orders.PUT("/:id/requester", s.SetOrderRequester, updateOrder)
+		orders.GET("/:id/assignee", s.GetOrderAssignee)
+		orders.PUT("/:id/assignee", s.AssignOrder, middleware.RequirePermission("orders", "assign"), assignOrder)
+		orders.DELETE("/:id/assignee", s.UnassignOrder, middleware.RequirePermission("orders", "assign"), assignOrder)
+		orders.DELETE("/:id", s.DeleteOrder, middleware.RequirePermission("orders", "delete"),
+			s.orderPolicy("delete", orderParam("id")))
+		orders.POST("/:order_id/items", s.CreateOrderItem, middleware.StrictJSON(), updateOrderItems)
+		orders.POST("/:order_id/items/bulk", s.CreateOrderItems, middleware.StrictJSON(), updateOrderItems)
 		orders.GET("/:order_id/items", s.GetOrderItems)
+		orders.POST("/:id/attachments", s.CreateOrderAttachment, updateOrder)
+		orders.GET("/:id/attachments", s.ListOrderAttachments)
+		orders.DELETE("/:id/attachments/:attachment_id", s.DeleteOrderAttachment, updateOrder)
+		orders.POST("/:id/labels", s.PrintOrderLabels)
+		orders.GET("/:id/warnings", s.ListOrderWarnings)
+		orders.GET("/:id/picking-slip", s.GetPickingSlip)
+		orders.GET("/:id/suppliers", s.GetOrderSuppliers)
+		orders.PUT("/:id/suppliers", s.RouteOrderItems, middleware.RequirePermission("suppliers", "assign"), updateOrder)
+	}
+
+	// Orders placed on a schedule (see Order Calendar in README.md)
+	recurringOrders := protected.Group("/recurring-orders")
+	recurringOrders.Use(uuidParams("id"))
+	{
+		recurringOrders.POST("", s.CreateRecurringOrder, middleware.RequirePermission("recurring_orders", "manage"))
+		recurringOrders.GET("", s.ListRecurringOrders)
+		recurringOrders.GET("/:id", s.GetRecurringOrder)
+		recurringOrders.PUT("/:id", s.UpdateRecurringOrder, middleware.RequirePermission("recurring_orders", "manage"))
+		recurringOrders.DELETE("/:id", s.DeleteRecurringOrder, middleware.RequirePermission("recurring_orders", "manage"))
+	}
+
+	// Private calendar feed URL of the current user
+	calendar := protected.Group("/calendar")
+	{
+		calendar.GET("/feed", s.GetCalendarFeed)
+		calendar.POST("/feed", s.CreateCalendarFeed)
+		calendar.DELETE("/feed", s.DeleteCalendarFeed)
+	}
+
+	// Generated export files (see File Storage in README.md)
+	exports := protected.Group("/exports")
+	exports.Use(middleware.RequirePermission("exports", "manage"))
+	exports.Use(uuidParams("id"))
+	{
+		exports.POST("/orders", s.ExportOrders)
+		exports.GET("", s.ListExports)
+		exports.GET("/:id", s.GetExport)
+	}
+
+	// Fulfilled orders handed to the accounting/ERP system (see ERP Export
+	// in README.md)
+	erpExport := protected.Group("/erp")
+	erpExport.Use(middleware.RequirePermission("erp", "manage"))
+	erpExport.Use(uuidParams("id"))
+	{
+		erpExport.GET("/mapping", s.GetERPMapping)
+		erpExport.GET("/orders", s.PullERPOrders)
+		erpExport.POST("/push", s.PushERPOrders)
+		erpExport.GET("/batches", s.ListERPBatches)
+		erpExport.GET("/batches/:id", s.GetERPBatch)
+		erpExport.POST("/batches/:id/ack", s.AckERPBatch)
+	}
+
+	// Order statistics for charts, orders per requester and the
+	// controlled-substance register (see Controlled Substances in
+	// README.md); per-user activity, change frequency and saved report
+	// definitions (reports:manage; see Saved Reports in README.md)
+	reportData := protected.Group("/reports")
+	reportData.Use(middleware.RequirePermission("reports", "read"))
+	reportData.Use(uuidParams("id"))
+	{
+		adminOnly := middleware.RequirePermission("reports", "manage")
+		reportData.GET("/orders/timeseries", s.GetOrderTimeSeries)
+		reportData.GET("/users/activity", s.GetUserActivityReport, adminOnly)
+		reportData.GET("/changes", s.GetChangeReport, adminOnly)
+		reportData.GET("/controlled-register", s.GetControlledRegister)
+		reportData.GET("/requesters", s.GetRequesterReport)
+		reportData.GET("/entities", s.ListSavedReportEntities, adminOnly)
+		reportData.POST("", s.CreateSavedReport, adminOnly)
+		reportData.GET("", s.ListSavedReports, adminOnly)
+		reportData.GET("/:id", s.GetSavedReport, adminOnly)
+		reportData.PUT("/:id", s.UpdateSavedReport, adminOnly)
+		reportData.DELETE("/:id", s.DeleteSavedReport, adminOnly)
+		reportData.GET("/:id/run", s.RunSavedReport, adminOnly)
+	}
+
+	// Scheduled report emails (see Scheduled Reports in README.md)
+	reportSchedules := protected.Group("/report-schedules")
+	reportSchedules.Use(middleware.RequirePermission("report_schedules", "manage"))
+	reportSchedules.Use(uuidParams("id"))
+	{
+		reportSchedules.POST("", s.CreateReportSchedule)
+		reportSchedules.GET("", s.ListReportSchedules)
+		reportSchedules.GET("/:id", s.GetReportSchedule)
+		reportSchedules.PUT("/:id", s.UpdateReportSchedule)
+		reportSchedules.DELETE("/:id", s.DeleteReportSchedule)
+		reportSchedules.POST("/:id/run", s.RunReportSchedule)
+	}
+
+	// FHIR export for the hospital information system (see FHIR.md)
+	fhirExport := protected.Group("/fhir")
+	{
+		fhirExport.GET("/Medication", s.ListFHIRMedications)
+		fhirExport.GET("/Medication/:id", s.GetFHIRMedication)
+		fhirExport.GET("/MedicationRequest/:id", s.GetFHIRMedicationRequest)
+		fhirExport.GET("/Bundle/:id", s.GetFHIRBundle)
+		fhirExport.GET("/orders/:id", s.GetFHIROrderBundle)
 	}
 
 	// Order items routes
 	orderItems := protected.Group("/order_items")
+	orderItems.Use(uuidParams("id"))
 	{
-		orderItems.PUT("/:id", s.UpdateOrderItem)
-		orderItems.DELETE("/:id", s.DeleteOrderItem)
+		orderItems.PUT("/:id", s.UpdateOrderItem, middleware.StrictJSON(), s.orderPolicy("update", s.orderOfItem))
+		orderItems.DELETE("/:id", s.DeleteOrderItem, s.orderPolicy("update", s.orderOfItem))
+		orderItems.POST("/:id/labels", s.PrintOrderItemLabel)
 	}
 
 	// Barcode routes
 	barcodes := protected.Group("/barcodes")
+	barcodes.Use(uuidParams("id"))
 	{
-		barcodes.POST("", s.CreateBarcode, middleware.RequireRole("admin", "pharmacist"))
-		barcodes.PUT("/:id", s.UpdateBarcode, middleware.RequireRole("admin", "pharmacist"))
-		barcodes.DELETE("/:id", s.DeleteBarcode, middleware.RequireRole("admin"))
+		barcodes.POST("", s.CreateBarcode, middleware.RequirePermission("barcodes", "manage"))
+		barcodes.PUT("/:id", s.UpdateBarcode, middleware.RequirePermission("barcodes", "manage"))
+		barcodes.DELETE("/:id", s.DeleteBarcode, middleware.RequirePermission("barcodes", "delete"))
 	}
 
-	// User routes (admin only)
+	// User routes (the users permissions)
 	users := protected.Group("/users")
-	users.Use(middleware.RequireRole("admin"))
+	users.Use(uuidParams("id", "user_id"))
 	{
-		users.POST("", s.CreateUser)
-		users.GET("", s.ListUsers)
-		users.GET("/:id", s.GetUser)
-		users.PUT("/:id", s.UpdateUser)
-		users.DELETE("/:id", s.DeleteUser)
-		users.GET("/:user_id/activity", s.GetUserActivity)
+		users.POST("", s.CreateUser, middleware.RequirePermission("users", "create"))
+		users.GET("", s.ListUsers, middleware.RequirePermission("users", "read"))
+		users.GET("/:id", s.GetUser, middleware.RequirePermission("users", "read"))
+		users.PUT("/:id", s.UpdateUser, middleware.RequirePermission("users", "update"))
+		users.DELETE("/:id", s.DeleteUser, middleware.RequirePermission("users", "delete"))
+		users.GET("/:user_id/activity", s.GetUserActivity, middleware.RequirePermission("users", "read"))
 	}
 
-	// Role routes (admin only)
+	// Departments; users and orders belong to one (departments:manage
+	// changes them)
+	departments := protected.Group("/departments")
+	departments.Use(intParams("id"))
+	{
+		departments.GET("", s.ListDepartments)
+		departments.GET("/:id", s.GetDepartment)
+		departments.POST("", s.CreateDepartment, middleware.RequirePermission("departments", "manage"))
+		departments.PUT("/:id", s.UpdateDepartment, middleware.RequirePermission("departments", "manage"))
+		departments.DELETE("/:id", s.DeleteDepartment, middleware.RequirePermission("departments", "manage"))
+	}
+
+	// Distributors order items are routed to (see Suppliers in README.md;
+	// suppliers:manage changes them)
+	suppliers := protected.Group("/suppliers")
+	suppliers.Use(intParams("id"))
+	{
+		suppliers.GET("", s.ListSuppliers)
+		suppliers.GET("/:id", s.GetSupplier)
+		suppliers.POST("", s.CreateSupplier, middleware.RequirePermission("suppliers", "manage"))
+		suppliers.PUT("/:id", s.UpdateSupplier, middleware.RequirePermission("suppliers", "manage"))
+		suppliers.DELETE("/:id", s.DeleteSupplier, middleware.RequirePermission("suppliers", "manage"))
+	}
+
+	// Patients and departments orders are placed for (see Requesters in
+	// README.md); requesters:delete removes them
+	requesters := protected.Group("/requesters")
+	requesters.Use(uuidParams("id"))
+	{
+		requesters.GET("", s.ListRequesters)
+		requesters.GET("/:id", s.GetRequester)
+		requesters.POST("", s.CreateRequester)
+		requesters.PUT("/:id", s.UpdateRequester)
+		requesters.DELETE("/:id", s.DeleteRequester, middleware.RequirePermission("requesters", "delete"))
+	}
+
+	// Role routes (roles:manage)
 	roles := protected.Group("/roles")
-	roles.Use(middleware.RequireRole("admin"))
+	roles.Use(middleware.RequirePermission("roles", "manage"))
+	roles.Use(intParams("id", "role_id", "permission_id"))
 	{
 		roles.POST("", s.CreateRole)
 		roles.GET("", s.ListRoles)
@@ -192,9 +578,10 @@ func (s *Server) registerRoutes() {
 		roles.DELETE("/:role_id/permissions/:permission_id", s.RevokePermissionFromRole)
 	}
 
-	// Permission routes (admin only)
+	// Permission routes (permissions:manage)
 	permissions := protected.Group("/permissions")
-	permissions.Use(middleware.RequireRole("admin"))
+	permissions.Use(middleware.RequirePermission("permissions", "manage"))
+	permissions.Use(intParams("id"))
 	{
 		permissions.POST("", s.CreatePermission)
 		permissions.GET("", s.ListPermissions)
@@ -203,43 +590,33 @@ func (s *Server) registerRoutes() {
 		permissions.DELETE("/:id", s.DeletePermission)
 	}
 
-	// Audit log routes (admin only)
+	// Roles, permissions and grants as one document (roles:manage and
+	// permissions:manage)
+	rbac := protected.Group("/rbac")
+	rbac.Use(middleware.RequirePermission("roles", "manage"))
+	rbac.Use(middleware.RequirePermission("permissions", "manage"))
+	{
+		rbac.GET("", s.ExportRBAC)
+		rbac.PUT("", s.ImportRBAC)
+	}
+
+	// Audit log routes (audit:read)
 	auditLogs := protected.Group("/audit-logs")
-	auditLogs.Use(middleware.RequireRole("admin"))
+	auditLogs.Use(middleware.RequirePermission("audit", "read"))
 	{
 		auditLogs.GET("", s.GetAuditLogs)
-		auditLogs.GET("/:id", s.GetAuditLog)
+		auditLogs.GET("/:id", s.GetAuditLog, uuidParams("id"))
 		auditLogs.GET("/entity/:type/:id", s.GetEntityHistory)
 		auditLogs.GET("/stats", s.GetAuditStats)
 	}
 }
 
-// Health check endpoint
-func (s *Server) healthCheck(c echo.Context) error {
-	// Check database connection
-	err := s.db.Ping()
-	if err != nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]any{
-			"status":   "unhealthy",
-			"service":  "DigiOrder API",
-			"database": "disconnected",
-			"error":    err.Error(),
-		})
-	}
-
-	return c.JSON(http.StatusOK, map[string]any{
-		"status":   "healthy",
-		"service":  "DigiOrder API",
-		"database": "connected",
-		"version":  "3.0.1",
-	})
-}
-
 // Custom HTTP error handler
 func (s *Server) customHTTPErrorHandler(err error, c echo.Context) {
 	code := http.StatusInternalServerError
 	msg := "internal_server_error"
 	details := ""
+	errorCode := ""
 
 	if he, ok := err.(*echo.HTTPError); ok {
 		code = he.Code
@@ -248,6 +625,7 @@ func (s *Server) customHTTPErrorHandler(err error, c echo.Context) {
 		} else {
 			msg = http.StatusText(code)
 		}
+		errorCode = httpErrorCode(he)
 		if he.Internal != nil {
 			details = he.Internal.Error()
 		}
@@ -283,10 +661,41 @@ func (s *Server) customHTTPErrorHandler(err error, c echo.Context) {
 		if c.Request().Method == http.MethodHead {
 			c.NoContent(code)
 		} else {
+			if errorCode == "" {
+				errorCode = statusErrorCodes[code]
+			}
 			c.JSON(code, ErrorResponse{
 				Error:   msg,
+				Message: localizedMessage(c, errorCode),
 				Details: details,
 			})
 		}
 	}
-}
\ No newline at end of file
+}
+
+// statusErrorCodes names the errors echo and the middleware raise without
+// a code, for their localized message
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            "invalid_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusRequestEntityTooLarge: "file_too_large",
+	http.StatusUnprocessableEntity:   "validation_error",
+	http.StatusTooManyRequests:       "rate_limited",
+	http.StatusInternalServerError:   "internal_error",
+	http.StatusServiceUnavailable:    "service_unavailable",
+}
+
+// httpErrorCode is the code a middleware put in an error's message, if any
+func httpErrorCode(he *echo.HTTPError) string {
+	switch m := he.Message.(type) {
+	case map[string]string:
+		return m["error"]
+	case map[string]any:
+		code, _ := m["error"].(string)
+		return code
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+)
+
+// routeQuerier grants the permissions listed to every role. Any other
+// query panics, so the tests only reach the middleware in front of the
+// handlers.
+type routeQuerier struct {
+	db.Querier
+	permissions []db.Permission
+}
+
+func (q routeQuerier) ListActiveIPRules(ctx context.Context) ([]db.IpRule, error) {
+	return nil, nil
+}
+
+func (q routeQuerier) GetRolePermissions(ctx context.Context, roleID int32) ([]db.Permission, error) {
+	return q.permissions, nil
+}
+
+func newRouteTestServer(t *testing.T, setup bool, permissions ...db.Permission) *Server {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Env = "test"
+	cfg.JWT.Secret = "route-test-secret-that-is-long-enough"
+	cfg.Features.SetupEndpoints = setup
+	s := NewWithQuerier(nil, routeQuerier{permissions: permissions}, cfg)
+	t.Cleanup(func() { s.logger.Close() })
+	return s
+}
+
+// guardedRoutes are the security, audit log and permission routes with the
+// permission each one requires
+var guardedRoutes = []struct {
+	method, path, pattern string
+	resource, action      string
+}{
+	{http.MethodGet, "/api/v1/security/overview", "/api/v1/security/overview", "security", "manage"},
+	{http.MethodGet, "/api/v1/security/login-attempts", "/api/v1/security/login-attempts", "security", "manage"},
+	{http.MethodGet, "/api/v1/security/login-attempts/report", "/api/v1/security/login-attempts/report", "security", "manage"},
+	{http.MethodGet, "/api/v1/security/blocked-ips", "/api/v1/security/blocked-ips", "security", "manage"},
+	{http.MethodPost, "/api/v1/security/release-ip", "/api/v1/security/release-ip", "security", "manage"},
+	{http.MethodPost, "/api/v1/security/cleanup", "/api/v1/security/cleanup", "security", "manage"},
+	{http.MethodGet, "/api/v1/security/user/alice/login-history", "/api/v1/security/user/:username/login-history", "security", "manage"},
+	{http.MethodGet, "/api/v1/audit-logs", "/api/v1/audit-logs", "audit", "read"},
+	{http.MethodGet, "/api/v1/audit-logs/" + uuid.NewString(), "/api/v1/audit-logs/:id", "audit", "read"},
+	{http.MethodGet, "/api/v1/audit-logs/entity/order/1", "/api/v1/audit-logs/entity/:type/:id", "audit", "read"},
+	{http.MethodGet, "/api/v1/audit-logs/stats", "/api/v1/audit-logs/stats", "audit", "read"},
+	{http.MethodGet, "/api/v1/permissions", "/api/v1/permissions", "permissions", "manage"},
+	{http.MethodPost, "/api/v1/permissions", "/api/v1/permissions", "permissions", "manage"},
+	{http.MethodGet, "/api/v1/permissions/1", "/api/v1/permissions/:id", "permissions", "manage"},
+	{http.MethodPut, "/api/v1/permissions/1", "/api/v1/permissions/:id", "permissions", "manage"},
+	{http.MethodDelete, "/api/v1/permissions/1", "/api/v1/permissions/:id", "permissions", "manage"},
+}
+
+func registeredRoutes(s *Server) map[string]bool {
+	routes := make(map[string]bool)
+	for _, r := range s.router.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	return routes
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	routes := registeredRoutes(newRouteTestServer(t, true))
+
+	for _, r := range guardedRoutes {
+		if !routes[r.method+" "+r.pattern] {
+			t.Errorf("%s %s is not registered", r.method, r.pattern)
+		}
+	}
+	for _, route := range []string{
+		"GET /api/v1/setup/status",
+		"POST /api/v1/setup/initialize",
+	} {
+		if !routes[route] {
+			t.Errorf("%s is not registered", route)
+		}
+	}
+}
+
+func TestSetupRoutesFollowFeature(t *testing.T) {
+	routes := registeredRoutes(newRouteTestServer(t, false))
+
+	for _, route := range []string{
+		"GET /api/v1/setup/status",
+		"POST /api/v1/setup/initialize",
+	} {
+		if routes[route] {
+			t.Errorf("%s is registered with setup_endpoints off", route)
+		}
+	}
+}
+
+func TestGuardedRoutesRequireAuthentication(t *testing.T) {
+	s := newRouteTestServer(t, true)
+
+	for _, r := range guardedRoutes {
+		req := httptest.NewRequest(r.method, r.path, nil)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: got %d, want %d", r.method, r.path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestGuardedRoutesRequirePermission(t *testing.T) {
+	// The role holds grants, just not the ones these routes need
+	s := newRouteTestServer(t, true,
+		db.Permission{Resource: "orders", Action: "read"},
+		db.Permission{Resource: "products", Action: "read"},
+	)
+	token, err := middleware.GenerateToken(uuid.New(), "clerk", 7, "clerk", uuid.Nil, 0, "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	for _, r := range guardedRoutes {
+		req := httptest.NewRequest(r.method, r.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s without %s:%s: got %d, want %d", r.method, r.path, r.resource, r.action, rec.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// internal/server/saved_reports.go - Saved report definitions
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/labstack/echo/v4"
+)
+
+// SavedReport is a named filter set over one entity that can be run on
+// demand or emailed by a report schedule
+type SavedReport struct {
+	ID          uuid.UUID        `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Entity      string           `json:"entity"`
+	Filters     []reports.Filter `json:"filters"`
+	Columns     []string         `json:"columns"`
+	Sort        []reports.Sort   `json:"sort"`
+	Limit       int              `json:"limit"`
+	CreatedBy   *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// SavedReportReq defines the request body for creating or replacing a
+// saved report. Columns default to the entity's usual ones and limit to
+// 1000 rows; GET /reports/entities lists the fields.
+type SavedReportReq struct {
+	Name        string           `json:"name" validate:"required,max=100"`
+	Description string           `json:"description" validate:"max=500"`
+	Entity      string           `json:"entity" validate:"required"`
+	Filters     []reports.Filter `json:"filters"`
+	Columns     []string         `json:"columns"`
+	Sort        []reports.Sort   `json:"sort"`
+	Limit       int              `json:"limit"`
+}
+
+// SavedReportRun is the result of running a saved report. Each row holds
+// one value per column: text, a number, a time or null.
+type SavedReportRun struct {
+	ReportID  uuid.UUID `json:"report_id"`
+	Name      string    `json:"name"`
+	Columns   []string  `json:"columns"`
+	Rows      [][]any   `json:"rows"`
+	Truncated bool      `json:"truncated"`
+	RanAt     time.Time `json:"ran_at"`
+}
+
+// ListSavedReportEntities handles GET /api/v1/reports/entities
+func (s *Server) ListSavedReportEntities(c echo.Context) error {
+	return RespondSuccess(c, http.StatusOK, reports.SavedEntities())
+}
+
+// CreateSavedReport handles POST /api/v1/reports
+func (s *Server) CreateSavedReport(c echo.Context) error {
+	def, req, ok, err := s.bindSavedReport(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	filters, sort := savedReportJSON(def)
+	userID, _ := middleware.GetUserIDFromContext(c)
+	saved, err := s.queries.CreateSavedReport(ctx, db.CreateSavedReportParams{
+		Name:        req.Name,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		Entity:      def.Entity,
+		Filters:     filters,
+		Columns:     def.Columns,
+		Sort:        sort,
+		RowLimit:    int32(def.Limit),
+		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Saved report")
+	}
+
+	s.logAudit(ctx, userID, "create", "saved_report", saved.ID.String(),
+		nil, savedReportAudit(saved),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, savedReportResponse(saved))
+}
+
+// ListSavedReports handles GET /api/v1/reports
+func (s *Server) ListSavedReports(c echo.Context) error {
+	rows, err := s.queries.ListSavedReports(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch saved reports.")
+	}
+
+	saved := make([]SavedReport, len(rows))
+	for i, row := range rows {
+		saved[i] = savedReportResponse(row)
+	}
+	return RespondSuccess(c, http.StatusOK, saved)
+}
+
+// GetSavedReport handles GET /api/v1/reports/:id
+func (s *Server) GetSavedReport(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	saved, err := s.queries.GetSavedReport(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Saved report")
+	}
+	return RespondSuccess(c, http.StatusOK, savedReportResponse(saved))
+}
+
+// UpdateSavedReport handles PUT /api/v1/reports/:id, replacing the whole
+// definition. Schedules sending the report pick up the change on their
+// next run.
+func (s *Server) UpdateSavedReport(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	def, req, ok, err := s.bindSavedReport(c)
+	if !ok {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetSavedReport(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Saved report")
+	}
+
+	filters, sort := savedReportJSON(def)
+	saved, err := s.queries.UpdateSavedReport(ctx, db.UpdateSavedReportParams{
+		ID:          id,
+		Name:        req.Name,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		Entity:      def.Entity,
+		Filters:     filters,
+		Columns:     def.Columns,
+		Sort:        sort,
+		RowLimit:    int32(def.Limit),
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Saved report")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "update", "saved_report", id.String(),
+		savedReportAudit(old), savedReportAudit(saved),
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, savedReportResponse(saved))
+}
+
+// DeleteSavedReport handles DELETE /api/v1/reports/:id. Schedules sending
+// the report are deleted with it.
+func (s *Server) DeleteSavedReport(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetSavedReport(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Saved report")
+	}
+	if _, err := s.queries.DeleteSavedReport(ctx, id); err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to delete saved report.")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "delete", "saved_report", id.String(),
+		savedReportAudit(old), nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RunSavedReport handles GET /api/v1/reports/:id/run. Relative times in
+// the filters are taken from now; ?format=xlsx downloads the rows as an
+// Excel sheet.
+func (s *Server) RunSavedReport(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	conn := s.conn()
+	if conn == nil {
+		return RespondError(c, http.StatusServiceUnavailable, "database_unavailable",
+			"Saved reports need a database connection.")
+	}
+
+	ctx := c.Request().Context()
+	saved, err := s.queries.GetSavedReport(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Saved report")
+	}
+	def, err := reports.DefinitionOf(saved)
+	if err == nil {
+		err = reports.ValidateDefinition(&def)
+	}
+	if err != nil {
+		return RespondError(c, http.StatusUnprocessableEntity, "invalid_definition",
+			"The saved report can no longer be run: "+err.Error()+".")
+	}
+
+	now := time.Now()
+	loc := s.reports.Calendar().Location
+	res, err := reports.RunSaved(ctx, conn, def, now, loc)
+	if err != nil {
+		s.logger.Error("Saved report failed", err, map[string]any{"report_id": id.String()})
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to run saved report.")
+	}
+
+	if wantsXLSX(c) {
+		return s.streamXLSX(c, fileSlug(saved.Name), res.Columns, sliceSource(res.Rows))
+	}
+	return RespondSuccess(c, http.StatusOK, SavedReportRun{
+		ReportID:  id,
+		Name:      saved.Name,
+		Columns:   res.Columns,
+		Rows:      res.Rows,
+		Truncated: res.Truncated,
+		RanAt:     now.In(loc),
+	})
+}
+
+// bindSavedReport reads and checks a saved report body, writing the error
+// response when it is invalid
+func (s *Server) bindSavedReport(c echo.Context) (reports.Definition, SavedReportReq, bool, error) {
+	var req SavedReportReq
+	if err := c.Bind(&req); err != nil {
+		return reports.Definition{}, req, false, respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return reports.Definition{}, req, false, respondValidationError(c, err)
+	}
+
+	def := reports.Definition{
+		Entity:  req.Entity,
+		Filters: req.Filters,
+		Columns: req.Columns,
+		Sort:    req.Sort,
+		Limit:   req.Limit,
+	}
+	if err := reports.ValidateDefinition(&def); err != nil {
+		return def, req, false, RespondError(c, http.StatusBadRequest, "invalid_definition", err.Error()+".")
+	}
+	return def, req, true, nil
+}
+
+// savedReportJSON encodes the filters and sort of a definition, never as
+// null
+func savedReportJSON(def reports.Definition) (filters, sort json.RawMessage) {
+	if def.Filters == nil {
+		def.Filters = []reports.Filter{}
+	}
+	if def.Sort == nil {
+		def.Sort = []reports.Sort{}
+	}
+	filters, _ = json.Marshal(def.Filters)
+	sort, _ = json.Marshal(def.Sort)
+	return filters, sort
+}
+
+// fileSlug makes a report name safe as a file and sheet name
+func fileSlug(name string) string {
+	slug := strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, name), "-")
+	if slug == "" {
+		return "report"
+	}
+	return slug
+}
+
+func savedReportAudit(r db.SavedReport) map[string]any {
+	return map[string]any{
+		"name":    r.Name,
+		"entity":  r.Entity,
+		"filters": r.Filters,
+		"columns": r.Columns,
+		"sort":    r.Sort,
+		"limit":   r.RowLimit,
+	}
+}
+
+func savedReportResponse(r db.SavedReport) SavedReport {
+	resp := SavedReport{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description.String,
+		Entity:      r.Entity,
+		Columns:     r.Columns,
+		Limit:       int(r.RowLimit),
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+	if def, err := reports.DefinitionOf(r); err == nil {
+		resp.Filters, resp.Sort = def.Filters, def.Sort
+	}
+	if r.CreatedBy.Valid {
+		resp.CreatedBy = &r.CreatedBy.UUID
+	}
+	return resp
+}
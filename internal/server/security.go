@@ -8,34 +8,40 @@ import (
 	"time"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/geoip"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
 )
 
+// LoginSecurityReportEntry is a suspicious IP of the security report, with
+// where it is when GeoIP knows
+type LoginSecurityReportEntry struct {
+	db.GetLoginSecurityReportRow
+	Location *geoip.Location `json:"location,omitempty"`
+}
+
+// CurrentlyBlockedIPEntry is a rate-limited IP, with where it is when
+// GeoIP knows
+type CurrentlyBlockedIPEntry struct {
+	db.CurrentlyBlockedIp
+	Location *geoip.Location `json:"location,omitempty"`
+}
+
 // GetLoginAttempts - Admin endpoint to view login attempts
 func (s *Server) GetLoginAttempts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	limit := 50
-	offset := 0
-
-	if limitStr := c.QueryParam("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
-	}
-
-	if offsetStr := c.QueryParam("offset"); offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	page, ok := parseOffsetPage(c, pagination.DefaultLimit)
+	if !ok {
+		return nil
 	}
 
 	// Get rate limited attempts
 	attempts, err := s.queries.GetRateLimitedAttempts(ctx, db.GetRateLimitedAttemptsParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
 	})
 
 	if err != nil {
@@ -47,7 +53,8 @@ func (s *Server) GetLoginAttempts(c echo.Context) error {
 		attempts = []db.LoginAttemptsLog{}
 	}
 
-	return RespondSuccess(c, http.StatusOK, attempts)
+	// The window moves with the clock, so the attempts are not counted
+	return respondOffsetPage(c, page, nil, len(attempts), attempts)
 }
 
 // GetLoginSecurityReport - Get security report of suspicious IPs
@@ -67,11 +74,12 @@ func (s *Server) GetLoginSecurityReport(c echo.Context) error {
 			"Failed to retrieve security report.")
 	}
 
-	if report == nil {
-		report = []db.GetLoginSecurityReportRow{}
+	entries := make([]LoginSecurityReportEntry, len(report))
+	for i, r := range report {
+		entries[i] = LoginSecurityReportEntry{GetLoginSecurityReportRow: r, Location: s.locate(r.IpAddress)}
 	}
 
-	return RespondSuccess(c, http.StatusOK, report)
+	return RespondSuccess(c, http.StatusOK, entries)
 }
 
 // GetCurrentlyBlockedIPs - View currently rate-limited IPs
@@ -84,11 +92,12 @@ func (s *Server) GetCurrentlyBlockedIPs(c echo.Context) error {
 			"Failed to retrieve blocked IPs.")
 	}
 
-	if blockedIPs == nil {
-		blockedIPs = []db.CurrentlyBlockedIp{}
+	entries := make([]CurrentlyBlockedIPEntry, len(blockedIPs))
+	for i, b := range blockedIPs {
+		entries[i] = CurrentlyBlockedIPEntry{CurrentlyBlockedIp: b, Location: s.locate(b.ClientID)}
 	}
 
-	return RespondSuccess(c, http.StatusOK, blockedIPs)
+	return RespondSuccess(c, http.StatusOK, entries)
 }
 
 // ManuallyReleaseIP - Admin manually releases an IP from rate limiting
@@ -98,12 +107,12 @@ func (s *Server) ManuallyReleaseIP(c echo.Context) error {
 	}
 
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	ctx := c.Request().Context()
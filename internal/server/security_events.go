@@ -0,0 +1,220 @@
+// internal/server/security_events.go - Login anomalies for administrators
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamalkaksouri/DigiOrder/internal/anomaly"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+// SecurityEvent is a suspicious login found by the anomaly analyzer
+type SecurityEvent struct {
+	ID             uuid.UUID       `json:"id"`
+	Kind           string          `json:"kind"`
+	Username       string          `json:"username"`
+	LoginAttemptID *uuid.UUID      `json:"login_attempt_id,omitempty"`
+	IPAddress      string          `json:"ip_address"`
+	Details        json.RawMessage `json:"details"`
+	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uuid.UUID      `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// SecurityEventFilter narrows GET /security/events
+type SecurityEventFilter struct {
+	Kind         string `query:"kind"`
+	Username     string `query:"username"`
+	Acknowledged string `query:"acknowledged"`
+}
+
+func securityEventResponse(e db.SecurityEvent) SecurityEvent {
+	resp := SecurityEvent{
+		ID:        e.ID,
+		Kind:      e.Kind,
+		Username:  e.Username,
+		IPAddress: e.IpAddress,
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.LoginAttemptID.Valid {
+		resp.LoginAttemptID = &e.LoginAttemptID.UUID
+	}
+	if e.AcknowledgedAt.Valid {
+		resp.AcknowledgedAt = &e.AcknowledgedAt.Time
+	}
+	if e.AcknowledgedBy.Valid {
+		resp.AcknowledgedBy = &e.AcknowledgedBy.UUID
+	}
+	return resp
+}
+
+func securityEventCursor(e db.SecurityEvent) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+}
+
+func newAnomalyAnalyzer(withTx anomaly.TxFunc, cfg config.AnomalyConfig, loc *time.Location, logger *logging.Logger) *anomaly.Analyzer {
+	return anomaly.NewAnalyzer(withTx, anomaly.Config{
+		Interval:          cfg.CheckInterval,
+		Location:          loc,
+		MaxTravelSpeedKmh: float64(cfg.MaxTravelSpeedKmh),
+		MinTravelKm:       float64(cfg.MinTravelKm),
+		MinLogins:         cfg.UnusualHourMinLogins,
+		History:           cfg.History,
+	}, logger)
+}
+
+// securityEventSummaries describe each kind of security event in alerts
+var securityEventSummaries = map[string]string{
+	anomaly.KindImpossibleTravel:  "%s logged in from too far away to have travelled there",
+	anomaly.KindNewDevice:         "%s logged in from a new device",
+	anomaly.KindUnusualHour:       "%s logged in at an unusual hour",
+	anomaly.KindRefreshTokenReuse: "A refresh token of %s was used twice and its sessions were signed out",
+}
+
+// notifySecurityEvent alerts administrators of a security event matching
+// the "security_event.<kind>" alert rule
+func (s *Server) notifySecurityEvent(event db.SecurityEvent) {
+	if !auditRuleMatches(s.config.Notify.Chat.AuditRules, "security_event", event.Kind) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	summary := fmt.Sprintf(securityEventSummaries[event.Kind], event.Username)
+	reason := strings.ReplaceAll(event.Kind, "_", " ")
+	data := map[string]any{
+		"Summary": summary,
+		"IP":      event.IpAddress,
+		"Reason":  reason,
+		"Time":    event.CreatedAt.Format(time.RFC1123),
+	}
+	s.notifyRoles(ctx, notify.EventSecurityAlert, []string{"admin"}, data)
+	s.pageRoles(ctx, notify.EventSecurityAlert, []string{"admin"}, data)
+
+	facts := []notify.Fact{
+		{Name: "User", Value: event.Username},
+		{Name: "IP address", Value: event.IpAddress},
+	}
+	if loc := s.locate(event.IpAddress); loc != nil {
+		facts = append(facts, notify.Fact{Name: "Location", Value: describeLocation(*loc)})
+	}
+	facts = append(facts, notify.Fact{Name: "Event", Value: event.ID.String()})
+	s.postAlert("Suspicious login: "+reason, summary, facts...)
+}
+
+// ListSecurityEvents handles GET /api/v1/security/events, newest first.
+// kind, username and acknowledged (true or false) narrow the list.
+func (s *Server) ListSecurityEvents(c echo.Context) error {
+	var filter SecurityEventFilter
+	if err := c.Bind(&filter); err != nil {
+		return respondBindError(c, err, "Invalid query parameters.")
+	}
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
+	}
+
+	var params db.CountSecurityEventsParams
+	if filter.Kind != "" {
+		if !slices.Contains(anomaly.Kinds, filter.Kind) {
+			return RespondError(c, http.StatusBadRequest, "invalid_kind",
+				"kind must be one of "+strings.Join(anomaly.Kinds, ", ")+".")
+		}
+		params.Kind = sql.NullString{String: filter.Kind, Valid: true}
+	}
+	if filter.Username != "" {
+		params.Username = sql.NullString{String: filter.Username, Valid: true}
+	}
+	if filter.Acknowledged != "" {
+		acknowledged, err := strconv.ParseBool(filter.Acknowledged)
+		if err != nil {
+			return RespondError(c, http.StatusBadRequest, "validation_error",
+				"acknowledged must be true or false.")
+		}
+		params.Acknowledged = sql.NullBool{Bool: acknowledged, Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	rows, err := s.queries.ListSecurityEvents(ctx, db.ListSecurityEventsParams{
+		Kind:         params.Kind,
+		Username:     params.Username,
+		Acknowledged: params.Acknowledged,
+		AfterTime:    page.AfterTime(),
+		AfterID:      page.AfterID(),
+		LimitCount:   int32(page.Limit),
+		OffsetCount:  int32(page.Offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve security events.")
+	}
+
+	var signature any
+	if params != (db.CountSecurityEventsParams{}) {
+		signature = params
+	}
+	total := s.listTotal(ctx, "security_events", signature, func(ctx context.Context) (int64, error) {
+		return s.queries.CountSecurityEvents(ctx, params)
+	})
+
+	events := make([]SecurityEvent, len(rows))
+	for i, e := range rows {
+		events[i] = securityEventResponse(e)
+	}
+	return respondPage(c, page, total, rows, securityEventCursor, events)
+}
+
+// GetSecurityEvent handles GET /api/v1/security/events/:id
+func (s *Server) GetSecurityEvent(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	event, err := s.queries.GetSecurityEvent(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Security event")
+	}
+	return RespondSuccess(c, http.StatusOK, securityEventResponse(event))
+}
+
+// AcknowledgeSecurityEvent handles POST
+// /api/v1/security/events/:id/acknowledge, marking the event as reviewed.
+// Acknowledging it again keeps the first acknowledgement.
+func (s *Server) AcknowledgeSecurityEvent(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	event, err := s.queries.AcknowledgeSecurityEvent(ctx, db.AcknowledgeSecurityEventParams{
+		AcknowledgedBy: uuid.NullUUID{UUID: currentUserID, Valid: currentUserID != uuid.Nil},
+		ID:             id,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Security event")
+	}
+
+	s.logAudit(ctx, currentUserID, "acknowledge", "security_event", id.String(),
+		nil, map[string]any{"kind": event.Kind, "username": event.Username},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, securityEventResponse(event))
+}
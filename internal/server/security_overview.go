@@ -0,0 +1,230 @@
+// internal/server/security_overview.go - One payload for the security dashboard
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/geoip"
+	"github.com/labstack/echo/v4"
+)
+
+// permissionEntityTypes are the audit entries shown as permission changes
+var permissionEntityTypes = []string{"permission", "role_permission"}
+
+// Security overview window and list sizes
+const (
+	defaultOverviewHours = 24
+	maxOverviewHours     = 30 * 24
+	defaultOverviewLimit = 20
+	maxOverviewLimit     = 100
+)
+
+// SecurityOverview combines what the security endpoints report separately.
+// Every list is newest first and holds at most Limit entries from Since on.
+type SecurityOverview struct {
+	Since             time.Time          `json:"since"`
+	Limit             int                `json:"limit"`
+	Logins            LoginSummary       `json:"logins"`
+	BlockedIPs        []BlockedIP        `json:"blocked_ips"`
+	ActiveBans        []IPBan            `json:"active_bans"`
+	RecentBans        []IPBan            `json:"recent_bans"`
+	Releases          []RateLimitRelease `json:"releases"`
+	PermissionChanges []PermissionChange `json:"permission_changes"`
+}
+
+// LoginSummary counts login attempts since the start of the window
+type LoginSummary struct {
+	Attempts    int64 `json:"attempts"`
+	Failed      int64 `json:"failed"`
+	RateLimited int64 `json:"rate_limited"`
+	FailedIPs   int64 `json:"failed_ips"`
+}
+
+// BlockedIP is a client over the login limit in the last five minutes
+type BlockedIP struct {
+	IP            string          `json:"ip"`
+	TotalAttempts int64           `json:"total_attempts"`
+	BlockWindows  int64           `json:"block_windows"`
+	Location      *geoip.Location `json:"location,omitempty"`
+}
+
+// IPBan is a ban by the rate limiter. BannedUntil is only known for
+// active bans, which are held in memory by this instance.
+type IPBan struct {
+	IP          string          `json:"ip"`
+	Reason      string          `json:"reason"`
+	BannedAt    *time.Time      `json:"banned_at,omitempty"`
+	BannedUntil *time.Time      `json:"banned_until,omitempty"`
+	Attempts    int             `json:"attempts,omitempty"`
+	Location    *geoip.Location `json:"location,omitempty"`
+}
+
+// RateLimitRelease is an IP released from login rate limiting
+type RateLimitRelease struct {
+	IP                 string     `json:"ip"`
+	Username           string     `json:"username,omitempty"`
+	BlockedAt          time.Time  `json:"blocked_at"`
+	ReleasedAt         *time.Time `json:"released_at,omitempty"`
+	ReleasedBy         string     `json:"released_by"`
+	ReleasedByUsername string     `json:"released_by_username,omitempty"`
+	Reason             string     `json:"reason,omitempty"`
+}
+
+// PermissionChange is an audited change to a permission or to the
+// permissions of a role
+type PermissionChange struct {
+	ID         uuid.UUID       `json:"id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id,omitempty"`
+	Username   string          `json:"username"`
+	OldValues  json.RawMessage `json:"old_values,omitempty"`
+	NewValues  json.RawMessage `json:"new_values,omitempty"`
+	CreatedAt  *time.Time      `json:"created_at,omitempty"`
+}
+
+// GetSecurityOverview handles GET /api/v1/security/overview. hours (24,
+// at most 720) sets the window and limit (20, at most 100) the length of
+// each list.
+func (s *Server) GetSecurityOverview(c echo.Context) error {
+	hours, ok := overviewParam(c, "hours", defaultOverviewHours, maxOverviewHours)
+	if !ok {
+		return RespondError(c, http.StatusBadRequest, "validation_error",
+			"hours must be a whole number between 1 and "+strconv.Itoa(maxOverviewHours)+".")
+	}
+	limit, ok := overviewParam(c, "limit", defaultOverviewLimit, maxOverviewLimit)
+	if !ok {
+		return RespondError(c, http.StatusBadRequest, "invalid_limit",
+			"limit must be a whole number between 1 and "+strconv.Itoa(maxOverviewLimit)+".")
+	}
+
+	ctx := c.Request().Context()
+	now := time.Now()
+	since := now.Add(-time.Duration(hours) * time.Hour)
+	overview := SecurityOverview{
+		Since:             since,
+		Limit:             limit,
+		BlockedIPs:        []BlockedIP{},
+		ActiveBans:        []IPBan{},
+		RecentBans:        []IPBan{},
+		Releases:          []RateLimitRelease{},
+		PermissionChanges: []PermissionChange{},
+	}
+
+	logins, err := s.queries.ReportLoginSummary(ctx, db.ReportLoginSummaryParams{FromTime: since, ToTime: now})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve login attempts.")
+	}
+	overview.Logins = LoginSummary{
+		Attempts:    logins.Attempts,
+		Failed:      logins.Failed,
+		RateLimited: logins.RateLimited,
+		FailedIPs:   logins.FailedIps,
+	}
+
+	blocked, err := s.queries.GetCurrentlyBlockedIPs(ctx)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve blocked IPs.")
+	}
+	for _, b := range blocked {
+		if len(overview.BlockedIPs) == limit {
+			break
+		}
+		overview.BlockedIPs = append(overview.BlockedIPs, BlockedIP{
+			IP:            b.ClientID,
+			TotalAttempts: b.TotalAttempts,
+			BlockWindows:  b.BlockWindows,
+			Location:      s.locate(b.ClientID),
+		})
+	}
+
+	bans := s.ipLimiter.BannedIPs()
+	for i := range bans {
+		if len(overview.ActiveBans) == limit {
+			break
+		}
+		until := bans[i].BannedUntil
+		overview.ActiveBans = append(overview.ActiveBans, IPBan{
+			IP:          bans[i].IP,
+			Reason:      bans[i].Reason,
+			BannedUntil: &until,
+			Attempts:    bans[i].Attempts,
+			Location:    s.locate(bans[i].IP),
+		})
+	}
+
+	recent, err := s.queries.ListRecentIPBans(ctx, db.ListRecentIPBansParams{Since: since, LimitCount: int32(limit)})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve recent bans.")
+	}
+	for _, b := range recent {
+		ban := IPBan{IP: b.IpAddress, Reason: b.Reason, Location: s.locate(b.IpAddress)}
+		if b.BannedAt.Valid {
+			ban.BannedAt = &b.BannedAt.Time
+		}
+		overview.RecentBans = append(overview.RecentBans, ban)
+	}
+
+	releases, err := s.queries.ListRecentRateLimitReleases(ctx, db.ListRecentRateLimitReleasesParams{Since: since, LimitCount: int32(limit)})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve rate-limit releases.")
+	}
+	for _, r := range releases {
+		release := RateLimitRelease{
+			IP:                 r.IpAddress,
+			Username:           r.Username,
+			BlockedAt:          r.BlockedAt,
+			ReleasedBy:         r.ReleasedBy,
+			ReleasedByUsername: r.ReleasedByUsername,
+			Reason:             r.ReleaseReason,
+		}
+		if r.ReleasedAt.Valid {
+			release.ReleasedAt = &r.ReleasedAt.Time
+		}
+		overview.Releases = append(overview.Releases, release)
+	}
+
+	changes, err := s.queries.ListRecentPermissionChanges(ctx, db.ListRecentPermissionChangesParams{
+		EntityTypes: permissionEntityTypes,
+		Since:       since,
+		LimitCount:  int32(limit),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve permission changes.")
+	}
+	for _, a := range changes {
+		change := PermissionChange{
+			ID:         a.ID,
+			Action:     a.Action,
+			EntityType: a.EntityType,
+			EntityID:   a.EntityID,
+			Username:   a.Username,
+			OldValues:  a.OldValues,
+			NewValues:  a.NewValues,
+		}
+		if a.CreatedAt.Valid {
+			change.CreatedAt = &a.CreatedAt.Time
+		}
+		overview.PermissionChanges = append(overview.PermissionChanges, change)
+	}
+
+	return RespondSuccess(c, http.StatusOK, overview)
+}
+
+// overviewParam reads a positive whole number up to most, def when unset
+func overviewParam(c echo.Context, name string, def, most int) (int, bool) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > most {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,270 @@
+// internal/server/selftest.go - Startup self-test for schema and required data
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// requiredSchema lists the tables and columns the queries depend on. A
+// mismatch here would otherwise only surface as 500s on the first request
+// that touches the table.
+var requiredSchema = map[string][]string{
+	"roles":              {"id", "name"},
+	"users":              {"id", "username", "full_name", "password_hash", "role_id", "created_at", "deleted_at", "tenant_id", "department_id", "language", "service_account"},
+	"categories":         {"id", "name"},
+	"dosage_forms":       {"id", "name"},
+	"products":           {"id", "name", "brand", "dosage_form_id", "strength", "unit", "category_id", "description", "created_at", "deleted_at", "status", "irc", "generic_code", "tenant_id", "is_controlled", "schedule_class", "price"},
+	"product_barcodes":   {"id", "product_id", "barcode", "barcode_type", "created_at"},
+	"orders":             {"id", "created_by", "status", "created_at", "submitted_at", "notes", "deleted_at", "priority", "needed_by", "tenant_id", "department_id", "requester_id", "subtotal", "total"},
+	"order_items":        {"id", "order_id", "product_id", "requested_qty", "unit", "note", "unit_price", "line_total", "supplier_id"},
+	"permissions":        {"id", "name", "resource", "action", "description", "created_at"},
+	"role_permissions":   {"id", "role_id", "permission_id", "created_at"},
+	"audit_logs":         {"id", "user_id", "action", "entity_type", "entity_id", "old_values", "new_values", "ip_address", "user_agent", "created_at"},
+	"system_setup":       {"id", "admin_created", "setup_completed_at", "setup_by_ip", "created_at"},
+	"api_rate_limits":    {"id", "client_id", "endpoint", "requests_count", "window_start", "created_at"},
+	"login_attempts_log": {"id", "username", "ip_address", "user_agent", "attempt_time", "success", "failure_reason", "rate_limited", "country", "city", "asn", "as_org", "latitude", "longitude", "analyzed_at", "device_fingerprint"},
+	"ip_bans":            {"id", "ip_address", "banned_at", "banned_until", "reason", "failed_attempts"},
+
+	"user_notification_settings": {"user_id", "email", "phone", "created_at", "updated_at"},
+	"notification_preferences":   {"user_id", "event_type", "channel", "enabled", "updated_at"},
+	"outbox_events":              {"id", "event_type", "aggregate_type", "aggregate_id", "payload", "created_at", "attempts", "next_attempt_at", "last_error", "published_at"},
+	"fhir_resource_ids":          {"resource_type", "local_id", "fhir_id", "created_at"},
+	"drug_registry_syncs":        {"id", "source", "status", "triggered_by", "started_at", "finished_at", "total_entries", "matched", "created", "ambiguous", "conflicts", "failed", "error"},
+	"drug_registry_sync_items":   {"id", "sync_id", "irc", "generic_code", "name", "gtin", "outcome", "product_id", "detail"},
+	"product_images":             {"product_id", "object_key", "content_type", "size_bytes", "uploaded_by", "uploaded_at"},
+	"order_attachments":          {"id", "order_id", "object_key", "filename", "content_type", "size_bytes", "uploaded_by", "created_at"},
+	"export_files":               {"id", "kind", "object_key", "filename", "content_type", "size_bytes", "created_by", "created_at"},
+	"product_stock":              {"product_id", "on_hand", "reorder_level", "updated_by", "updated_at"},
+	"report_schedules":           {"id", "report", "recipient_id", "format", "frequency", "send_hour", "enabled", "next_run_at", "last_run_at", "last_status", "last_error", "created_by", "created_at", "saved_report_id"},
+	"erp_batches":                {"id", "mode", "format", "order_count", "status", "error", "created_by", "created_at", "delivered_at"},
+	"erp_batch_orders":           {"batch_id", "order_id"},
+	"personal_access_tokens":     {"id", "user_id", "name", "token_hash", "token_prefix", "scopes", "expires_at", "last_used_at", "last_used_ip", "created_at", "revoked_at"},
+	"api_keys":                   {"id", "tenant_id", "name", "key_hash", "key_prefix", "role_id", "department_id", "scopes", "expires_at", "last_used_at", "last_used_ip", "created_by", "created_at", "updated_at", "revoked_at"},
+	"device_tokens":              {"id", "user_id", "token", "platform", "name", "created_at", "last_seen_at"},
+	"order_assignments":          {"order_id", "user_id", "assigned_by", "assigned_at"},
+	"recurring_orders":           {"id", "name", "template_order_id", "frequency", "lead_days", "priority", "enabled", "next_run_at", "last_run_at", "last_order_id", "last_error", "created_by", "created_at"},
+	"calendar_feeds":             {"user_id", "token_hash", "created_at", "last_fetched_at"},
+	"slow_queries":               {"id", "query_name", "operation", "table_name", "query", "params", "duration_ms", "error", "request_id", "route", "user_id", "created_at"},
+	"saved_reports":              {"id", "name", "description", "entity", "filters", "columns", "sort", "row_limit", "created_by", "created_at", "updated_at"},
+	"api_usage_daily":            {"day", "user_id", "token_id", "method", "route", "requests", "client_errors", "server_errors", "total_ms", "max_ms"},
+	"tenants":                    {"id", "slug", "name", "created_at", "requests_per_minute", "requests_per_day", "orders_per_day"},
+	"departments":                {"id", "tenant_id", "name", "created_at"},
+	"order_statuses":             {"code", "label", "sort_order", "created_at"},
+	"order_status_history":       {"id", "order_id", "old_status", "new_status", "changed_by", "note", "changed_at"},
+	"ip_rules":                   {"id", "cidr", "action", "reason", "expires_at", "created_by", "created_at", "updated_at"},
+	"tenant_request_counts":      {"tenant_id", "day", "requests"},
+	"security_events":            {"id", "kind", "username", "login_attempt_id", "ip_address", "details", "acknowledged_at", "acknowledged_by", "created_at"},
+	"backups":                    {"id", "status", "storage_key", "size_bytes", "checksum", "error", "triggered_by", "started_at", "finished_at", "verify_status", "verify_error", "verified_at"},
+	"backup_restores":            {"id", "backup_id", "status", "error", "triggered_by", "started_at", "finished_at"},
+	"scheduled_jobs":             {"name", "schedule", "last_status", "last_error", "last_started_at", "last_finished_at", "last_duration_ms", "run_count", "failure_count"},
+	"drug_interactions":          {"generic_code_a", "generic_code_b", "severity", "description"},
+	"drug_classes":               {"generic_code", "atc_code"},
+	"order_warnings":             {"id", "order_id", "order_item_id", "other_order_item_id", "kind", "severity", "message", "created_at"},
+	"controlled_approvals":       {"order_id", "approved_by", "note", "approved_at"},
+	"requesters":                 {"id", "tenant_id", "kind", "name", "reference", "department_id", "created_at"},
+	"refresh_tokens":             {"id", "user_id", "family_id", "token_hash", "expires_at", "rotated_at", "revoked_at", "created_at"},
+	"password_reset_requests":    {"id", "username", "ip_address", "created_at"},
+	"password_reset_tokens":      {"id", "user_id", "token_hash", "requested_ip", "expires_at", "used_at", "created_at"},
+	"suppliers":                  {"id", "tenant_id", "name", "phone", "email", "created_at"},
+	"order_cancellations":        {"order_id", "reason", "note", "cancelled_by", "cancelled_at"},
+}
+
+// SelfTestCheck is the outcome of one self-test step
+type SelfTestCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// SelfTestReport is the outcome of a full self-test run
+type SelfTestReport struct {
+	Passed     bool            `json:"passed"`
+	RanAt      time.Time       `json:"ran_at"`
+	DurationMs int64           `json:"duration_ms"`
+	Checks     []SelfTestCheck `json:"checks"`
+}
+
+// String renders the report for console output
+func (r *SelfTestReport) String() string {
+	var b strings.Builder
+	for _, check := range r.Checks {
+		mark := "ok  "
+		if !check.Passed {
+			mark = "FAIL"
+		}
+		fmt.Fprintf(&b, "  [%s] %-12s %s\n", mark, check.Name, check.Message)
+	}
+	return b.String()
+}
+
+// RunSelfTest verifies the schema, required seed rows and JWT settings, and
+// stores the report for GET /api/v1/system/self-test
+func (s *Server) RunSelfTest(ctx context.Context) *SelfTestReport {
+	start := time.Now()
+	report := &SelfTestReport{Passed: true, RanAt: start}
+
+	steps := []struct {
+		name string
+		run  func(ctx context.Context) (string, error)
+	}{
+		{"schema", s.selfTestSchema},
+		{"admin_role", s.selfTestAdminRole},
+		{"system_setup", s.selfTestSystemSetup},
+		{"jwt", s.selfTestJWT},
+		{"tenancy", s.selfTestTenancy},
+	}
+
+	for _, step := range steps {
+		message, err := step.run(ctx)
+		check := SelfTestCheck{Name: step.name, Passed: err == nil, Message: message}
+		if err != nil {
+			check.Message = err.Error()
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	s.selfTestMu.Lock()
+	s.selfTest = report
+	s.selfTestMu.Unlock()
+
+	return report
+}
+
+// selfTestSchema checks every required table and column exists
+func (s *Server) selfTestSchema(ctx context.Context) (string, error) {
+	if s.db == nil {
+		return "", errors.New("database not configured")
+	}
+
+	columns, err := db.SchemaColumns(ctx, s.db)
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var missing []string
+	for table, required := range requiredSchema {
+		existing, ok := columns[table]
+		if !ok {
+			missing = append(missing, table)
+			continue
+		}
+		for _, column := range required {
+			if !existing[column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return "", fmt.Errorf("missing tables/columns: %s", strings.Join(missing, ", "))
+	}
+	return fmt.Sprintf("%d tables verified", len(requiredSchema)), nil
+}
+
+// selfTestAdminRole checks that role ID 1 exists and is the admin role,
+// which authorization and the setup flow assume
+func (s *Server) selfTestAdminRole(ctx context.Context) (string, error) {
+	role, err := s.queries.GetRole(ctx, 1)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("role ID 1 (admin) is missing; run 'digiorder seed'")
+	}
+	if err != nil {
+		return "", err
+	}
+	if role.Name != "admin" {
+		return "", fmt.Errorf("role ID 1 is %q, expected \"admin\"", role.Name)
+	}
+	return "role ID 1 is admin", nil
+}
+
+// selfTestSystemSetup checks the system_setup row the setup flow updates
+func (s *Server) selfTestSystemSetup(ctx context.Context) (string, error) {
+	setup, err := s.queries.GetSystemSetupStatus(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("system_setup row is missing; re-run migrations")
+	}
+	if err != nil {
+		return "", err
+	}
+	if setup.AdminCreated.Bool {
+		return "setup completed", nil
+	}
+	return "setup pending (no admin yet)", nil
+}
+
+// selfTestTenancy checks that row level security applies to the database
+// user, without which tenants would see each other's data
+func (s *Server) selfTestTenancy(ctx context.Context) (string, error) {
+	if !s.config.Tenancy.Enabled {
+		return "disabled", nil
+	}
+	if s.db == nil {
+		return "skipped (no database connection)", nil
+	}
+	bypass, err := db.RoleBypassesRLS(ctx, s.db)
+	if err != nil {
+		return "", err
+	}
+	if bypass {
+		return "", errors.New("the database user is a superuser or has BYPASSRLS, so tenants are not isolated; connect as an ordinary role")
+	}
+	return "row level security applies to the database user", nil
+}
+
+// selfTestJWT checks the signing secret, the signing key when tokens are
+// signed with RS256 or EdDSA, and the token lifetime
+func (s *Server) selfTestJWT(ctx context.Context) (string, error) {
+	secret, expiry := s.config.JWT.Secret, s.config.JWT.Expiry
+
+	if len(secret) < 32 {
+		return "", fmt.Errorf("secret must be at least 32 characters, got %d", len(secret))
+	}
+	distinct := make(map[rune]struct{})
+	for _, r := range secret {
+		distinct[r] = struct{}{}
+	}
+	if len(distinct) < 10 {
+		return "", errors.New("secret has too little variety; generate a random value")
+	}
+	if expiry < time.Minute || expiry > 30*24*time.Hour {
+		return "", fmt.Errorf("expiry %s is outside the sane range 1m-720h", expiry)
+	}
+
+	if algorithm := s.config.JWT.Algorithm; algorithm != "HS256" {
+		key, previous := middleware.JWTSigningKeys()
+		if key == nil || key.Method.Alg() != algorithm {
+			return "", fmt.Errorf("no %s signing key is loaded", algorithm)
+		}
+		return fmt.Sprintf("secret ok, %s key %s (%d previous), expiry %s", algorithm, key.ID, previous, expiry), nil
+	}
+
+	return fmt.Sprintf("secret ok, expiry %s", expiry), nil
+}
+
+// GetSelfTestReport handles GET /api/v1/system/self-test
+func (s *Server) GetSelfTestReport(c echo.Context) error {
+	s.selfTestMu.RLock()
+	report := s.selfTest
+	s.selfTestMu.RUnlock()
+
+	if report == nil {
+		return RespondError(c, http.StatusNotFound, "not_found",
+			"The self-test has not been run yet.")
+	}
+	return RespondSuccess(c, http.StatusOK, report)
+}
@@ -4,52 +4,203 @@ package server
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jamalkaksouri/DigiOrder/internal/anomaly"
+	"github.com/jamalkaksouri/DigiOrder/internal/audit"
+	"github.com/jamalkaksouri/DigiOrder/internal/backup"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/erp"
+	"github.com/jamalkaksouri/DigiOrder/internal/fieldcrypt"
+	"github.com/jamalkaksouri/DigiOrder/internal/geoip"
+	"github.com/jamalkaksouri/DigiOrder/internal/interactions"
+	"github.com/jamalkaksouri/DigiOrder/internal/labels"
 	"github.com/jamalkaksouri/DigiOrder/internal/logging"
 	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
+	"github.com/jamalkaksouri/DigiOrder/internal/pdf"
+	"github.com/jamalkaksouri/DigiOrder/internal/policy"
+	"github.com/jamalkaksouri/DigiOrder/internal/quota"
+	"github.com/jamalkaksouri/DigiOrder/internal/recurring"
+	"github.com/jamalkaksouri/DigiOrder/internal/registry"
+	"github.com/jamalkaksouri/DigiOrder/internal/rekey"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/jamalkaksouri/DigiOrder/internal/scheduler"
+	"github.com/jamalkaksouri/DigiOrder/internal/storage"
+	"github.com/jamalkaksouri/DigiOrder/internal/usage"
+	"github.com/jamalkaksouri/DigiOrder/migrations"
 	"github.com/labstack/echo/v4"
-	"github.com/lib/pq"
+	"golang.org/x/time/rate"
 )
 
 // Server holds the dependencies for our application.
 type Server struct {
-	db          *sql.DB
-	queries     *db.Queries
+	config      *config.Config
+	configMu    sync.Mutex
+	db          *pgxpool.Pool
+	queries     db.Querier
 	router      *echo.Echo
 	validator   *validator.Validate
 	server      *http.Server
 	logger      *logging.Logger
 	rateLimiter *middleware.PersistentRateLimiter
+	maintenance *middleware.MaintenanceMode
+	migrator    *db.Migrator
+	ipLimiter   *middleware.EnhancedRateLimiter
+	keyLimiter  *middleware.APIKeyRateLimiter
+	corsOrigins *middleware.CORSOrigins
+	startedAt   time.Time
+	stopping    atomic.Bool
+	selfTest    *SelfTestReport
+	selfTestMu  sync.RWMutex
+	notifier    *notify.Dispatcher
+	outbox      *outbox.Relay
+	registry    *registry.Syncer
+	store       storage.Store
+	backups     *backup.Manager
+	reports     *reports.Scheduler
+	recurring   *recurring.Runner
+	scheduler   *scheduler.Scheduler
+	printers    *labels.Printers
+	fonts       pdf.Fonts
+	drugData    interactions.Provider
+	erp         *erp.Exporter
+	slowQueries *slowQueryLog
+	usage       *usage.Tracker
+	audit       *audit.Writer
+	quotas      *quota.Limiter
+	counts      *pagination.Counter
+	responses   *middleware.Cache
+	roles       *roleCache
+	policies    *policy.Engine
+	geo         *geoip.Resolver
+	anomalies   *anomaly.Analyzer
+	rekey       *rekey.Rotator
+	openAPIOnce sync.Once
+	openAPISpec []byte
+	openAPIErr  error
 }
 
 // New creates a new Server instance with all its dependencies.
-func New(database *sql.DB) *Server {
+func New(database *pgxpool.Pool, cfg *config.Config) *Server {
+	return NewWithQuerier(database, nil, cfg)
+}
+
+// NewWithQuerier creates a Server that issues all queries through the given
+// db.Querier, allowing handlers to be exercised against a mock. A nil
+// querier issues them on database, tagged and timed.
+func NewWithQuerier(database *pgxpool.Pool, queries db.Querier, cfg *config.Config) *Server {
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	middleware.ConfigureJWT(cfg.JWT.Secret, cfg.JWT.Expiry)
+
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.Binder = &middleware.StrictBinder{}
 
 	v := validator.New()
 	registerCustomValidators(v)
 
-	queries := db.New(database)
-	logger := logging.NewLogger("digiorder", getEnv("ENV", "production"))
-	rateLimiter := middleware.NewPersistentRateLimiter(queries,
-		middleware.DefaultRateLimitConfig())
+	logger := logging.NewLogger("digiorder", cfg.Env)
+	if level, err := logging.ParseLevel(cfg.Log.Level); err == nil {
+		logger.SetLevel(level)
+	}
+	e.Logger = logging.NewEchoLogger(logger)
+	slowQueries := newSlowQueryLog(database, cfg.Database, logger)
+	if queries == nil {
+		queries = db.New(slowQueries.wrap(db.NewTenantDB(database)))
+	}
+	rateLimiter := middleware.NewPersistentRateLimiter(queries, rateLimitConfig(cfg))
 
 	server := &Server{
+		config:      cfg,
 		db:          database,
 		queries:     queries,
 		router:      e,
 		validator:   v,
 		logger:      logger,
 		rateLimiter: rateLimiter,
+		maintenance: middleware.NewMaintenanceMode(cfg.Maintenance.Enabled,
+			cfg.Maintenance.Message, cfg.Maintenance.RetryAfter),
+		ipLimiter: middleware.NewEnhancedRateLimiterWithConfig(queries, rateLimitConfig(cfg)),
+		keyLimiter: middleware.NewAPIKeyRateLimiter(rate.Limit(float64(cfg.RateLimit.APIKeyRPM)/60.0),
+			cfg.RateLimit.APIKeyRPM),
+		corsOrigins: middleware.NewCORSOrigins(cfg.CORS.AllowedOrigins),
+		startedAt:   time.Now(),
+		notifier:    newNotifier(cfg.Notify, queries, logger),
+		printers:    newLabelPrinters(cfg.Labels),
+		fonts:       newPDFFonts(cfg.PDF, logger),
+		drugData:    newInteractionProvider(queries, cfg.DrugChecks),
+		slowQueries: slowQueries,
+		counts:      pagination.NewCounter(queries, cfg.Cache.CountTTL, int64(cfg.Cache.CountEstimateAbove)),
+		responses:   middleware.NewCache(cfg.Cache.MaxEntries, int64(cfg.Cache.MaxMB)<<20),
+		roles:       newRoleCache(queries, cfg.Cache.RolesTTL),
+		policies:    policy.NewEngine(policyRules(cfg.Policies)),
+	}
+	middleware.ConfigurePermissions(server.roles.hasPermission)
+	server.ipLimiter.OnBan(server.notifyIPBanned)
+	server.registry = newRegistrySyncer(queries, server.withTx, cfg.Registry, logger)
+	server.reports = newReportScheduler(server.conn(), queries, server.withTx, server.tenantScope(), server.notifier, cfg.Reports, server.fonts, logger)
+	server.recurring = newRecurringRunner(server.withTx, cfg.Recurring, cfg.Pricing, logger)
+	server.scheduler = server.newScheduler(cfg.Scheduler)
+	server.erp = newERPExporter(queries, server.withTx, cfg.ERP, logger)
+	server.usage = newUsageTracker(database != nil, queries, cfg.APIUsage, server.reports.Calendar().Location, logger)
+	server.quotas = newQuotaLimiter(database != nil, queries, cfg.Tenancy, server.reports.Calendar().Location, logger)
+	server.audit = server.newAuditWriter(database != nil, cfg.Audit)
+	server.anomalies = newAnomalyAnalyzer(server.withTx, cfg.Anomaly, server.reports.Calendar().Location, logger)
+	server.anomalies.OnFinding(server.notifySecurityEvent)
+	server.rekey = rekey.NewRotator(server.withTx, fieldcrypt.Current(), rekey.Config{
+		Interval:  cfg.Encryption.RotateInterval,
+		BatchSize: cfg.Encryption.RotateBatch,
+	}, logger)
+	if geo, err := geoip.Open(cfg.GeoIP.CityDB, cfg.GeoIP.ASNDB); err != nil {
+		logger.Error("Failed to open GeoIP databases", err, map[string]any{"city_db": cfg.GeoIP.CityDB, "asn_db": cfg.GeoIP.ASNDB})
+	} else {
+		server.geo = geo
+		server.ipLimiter.SetGeoIP(geo)
+	}
+	if store, err := newStore(cfg.Storage, cfg.JWT.Secret); err != nil {
+		logger.Error("Failed to initialise file storage", err, map[string]any{"backend": cfg.Storage.Backend})
+	} else {
+		server.store = store
+	}
+	server.backups = server.newBackupManager(cfg)
+
+	if database != nil {
+		migrator, err := db.NewMigrator(database, migrations.FS)
+		if err != nil {
+			logger.Error("Failed to load embedded migrations", err, nil)
+		}
+		server.migrator = migrator
+
+		server.outbox = newOutboxRelay(queries, cfg.Events, logger)
+		server.outbox.Start()
+		server.slowQueries.Start()
+		server.registry.Start()
+		server.reports.Start()
+		server.scheduler.Start()
+		server.usage.Start()
+		server.quotas.Start()
+		server.audit.Start()
+		server.anomalies.Start()
+		server.rekey.Start()
+		server.backups.Start()
 	}
 
 	server.registerRoutes()
@@ -61,9 +212,9 @@ func (s *Server) Start(addr string) error {
 	s.server = &http.Server{
 		Addr:           addr,
 		Handler:        s.router,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		IdleTimeout:    60 * time.Second,
+		ReadTimeout:    s.config.Server.ReadTimeout,
+		WriteTimeout:   s.config.Server.WriteTimeout,
+		IdleTimeout:    s.config.Server.IdleTimeout,
 		MaxHeaderBytes: 1 << 20,
 	}
 
@@ -72,16 +223,84 @@ func (s *Server) Start(addr string) error {
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	// Fail readiness first so load balancers stop sending new traffic
+	s.stopping.Store(true)
+
+	err := s.server.Shutdown(ctx)
+	s.registry.Stop(ctx)
+	s.reports.Stop(ctx)
+	s.scheduler.Stop(ctx)
+	s.usage.Stop(ctx)
+	s.quotas.Stop(ctx)
+	s.audit.Stop(ctx)
+	s.anomalies.Stop(ctx)
+	s.rekey.Stop(ctx)
+	s.backups.Stop(ctx)
+	if s.outbox != nil {
+		s.outbox.Stop(ctx)
+	}
+	s.slowQueries.Stop(ctx)
+	if s.notifier != nil {
+		s.notifier.Close(ctx)
+	}
+	// Last, so the components stopped above can still log; this also ends
+	// the log sampler's summary loop
+	if s.logger != nil {
+		s.logger.Close()
+	}
+	return err
+}
+
+// conn returns the database connection for statements built outside the
+// sqlc queries, tagged and timed like them, or nil without a database
+func (s *Server) conn() db.DBTX {
+	if s.db == nil {
+		return nil
+	}
+	return s.slowQueries.wrap(db.NewTenantDB(s.db))
+}
+
+// rateLimitConfig maps the application rate limit settings onto the
+// middleware configuration
+func rateLimitConfig(cfg *config.Config) middleware.RateLimitConfig {
+	limits := middleware.DefaultRateLimitConfig()
+	limits.GlobalRPS = cfg.RateLimit.GlobalRPS
+	limits.GlobalBurst = cfg.RateLimit.GlobalBurst
+	limits.AuthenticatedRPM = cfg.RateLimit.AuthenticatedRPM
+	limits.LoginMaxAttempts = cfg.RateLimit.LoginMaxAttempts
+	limits.LoginWindow = cfg.RateLimit.LoginWindow
+	limits.MaxClients = cfg.RateLimit.MaxClients
+	return limits
 }
 
 // registerCustomValidators adds custom validation rules
+// statusCodePattern is what an order status code looks like
+var statusCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
 func registerCustomValidators(v *validator.Validate) {
+	v.RegisterCustomTypeFunc(validateOptional, Optional[string]{}, Optional[int32]{})
+
+	// Name fields in errors as clients send them
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			name, _, _ = strings.Cut(f.Tag.Get("form"), ",")
+		}
+		return name
+	})
+
 	v.RegisterValidation("uuid", func(fl validator.FieldLevel) bool {
 		_, err := uuid.Parse(fl.Field().String())
 		return err == nil
 	})
 
+	v.RegisterValidation("status_code", func(fl validator.FieldLevel) bool {
+		return statusCodePattern.MatchString(fl.Field().String())
+	})
+
 	v.RegisterValidation("barcode_format", func(fl validator.FieldLevel) bool {
 		barcode := fl.Field().String()
 		if len(barcode) < 8 || len(barcode) > 128 {
@@ -98,13 +317,13 @@ func HandleDatabaseError(c echo.Context, err error, entityName string) error {
 	}
 
 	// Handle no rows found
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return RespondError(c, http.StatusNotFound, "not_found",
 			fmt.Sprintf("%s not found.", entityName))
 	}
 
 	// Handle PostgreSQL specific errors
-	if pqErr, ok := err.(*pq.Error); ok {
+	if pqErr, ok := db.AsPgError(err); ok {
 		switch pqErr.Code {
 		case "23505": // unique_violation
 			// Extract the constraint name to provide better error message
@@ -112,6 +331,10 @@ func HandleDatabaseError(c echo.Context, err error, entityName string) error {
 				return RespondError(c, http.StatusConflict, "duplicate_username",
 					"A user with this username already exists.")
 			}
+			if strings.Contains(pqErr.Message, "slug") {
+				return RespondError(c, http.StatusConflict, "duplicate_slug",
+					"A tenant with this slug already exists.")
+			}
 			if strings.Contains(pqErr.Message, "barcode") {
 				return RespondError(c, http.StatusConflict, "duplicate_barcode",
 					"This barcode is already registered to another product.")
@@ -121,37 +344,45 @@ func HandleDatabaseError(c echo.Context, err error, entityName string) error {
 
 		case "23503": // foreign_key_violation
 			if strings.Contains(pqErr.Message, "role_id") {
-				return RespondError(c, http.StatusBadRequest, "invalid_role",
+				return RespondUnprocessable(c, "invalid_role",
 					"The specified role does not exist.")
 			}
 			if strings.Contains(pqErr.Message, "product_id") {
-				return RespondError(c, http.StatusBadRequest, "invalid_product",
+				return RespondUnprocessable(c, "invalid_product",
 					"The specified product does not exist.")
 			}
 			if strings.Contains(pqErr.Message, "category_id") {
-				return RespondError(c, http.StatusBadRequest, "invalid_category",
+				return RespondUnprocessable(c, "invalid_category",
 					"The specified category does not exist.")
 			}
+			if strings.Contains(pqErr.Message, "orders_status_fkey") {
+				return RespondUnprocessable(c, "invalid_status",
+					"The specified order status does not exist.")
+			}
 			if strings.Contains(pqErr.Message, "dosage_form_id") {
-				return RespondError(c, http.StatusBadRequest, "invalid_dosage_form",
+				return RespondUnprocessable(c, "invalid_dosage_form",
 					"The specified dosage form does not exist.")
 			}
-			return RespondError(c, http.StatusBadRequest, "foreign_key_violation",
+			return RespondUnprocessable(c, "foreign_key_violation",
 				"Referenced entity does not exist.")
 
 		case "23502": // not_null_violation
 			field := pqErr.Column
-			return RespondError(c, http.StatusBadRequest, "missing_required_field",
+			return RespondUnprocessable(c, "missing_required_field",
 				fmt.Sprintf("Field '%s' is required and cannot be null.", field))
 
 		case "23514": // check_violation
-			return RespondError(c, http.StatusBadRequest, "constraint_violation",
+			return RespondUnprocessable(c, "constraint_violation",
 				"Data violates database constraint.")
 
 		case "22P02": // invalid_text_representation (bad UUID format)
 			return RespondError(c, http.StatusBadRequest, "invalid_format",
 				"Invalid data format provided.")
 
+		case "42501": // insufficient_privilege, e.g. a row level security violation
+			return RespondError(c, http.StatusForbidden, "forbidden",
+				fmt.Sprintf("%s is outside what you may access.", entityName))
+
 		case "42703": // undefined_column
 			return RespondError(c, http.StatusInternalServerError, "database_error",
 				"Database schema error. Please contact support.")
@@ -200,27 +431,42 @@ func HandleDatabaseError(c echo.Context, err error, entityName string) error {
 // ValidateRequest validates request body and returns user-friendly errors
 func (s *Server) ValidateRequest(c echo.Context, req interface{}) error {
 	if err := c.Bind(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is malformed or invalid JSON.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		// Parse validation errors to return user-friendly messages
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			var errorMessages []string
-			for _, fieldError := range validationErrors {
-				errorMessages = append(errorMessages, formatValidationError(fieldError))
-			}
-			return RespondError(c, http.StatusBadRequest, "validation_error",
-				strings.Join(errorMessages, "; "))
-		}
-		return RespondError(c, http.StatusBadRequest, "validation_error",
-			"Request validation failed.")
+		return respondValidationError(c, err)
 	}
 
 	return nil
 }
 
+// respondValidationError answers a request body that failed validation
+// with 422, listing every field at fault with user-friendly messages
+func respondValidationError(c echo.Context, err error) error {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return RespondUnprocessable(c, "validation_error", "Request validation failed.")
+	}
+	errs := make([]FieldError, len(validationErrors))
+	messages := make([]string, len(validationErrors))
+	for i, fe := range validationErrors {
+		messages[i] = formatValidationError(fe)
+		errs[i] = FieldError{Field: fieldPath(fe), Code: fe.Tag(), Message: messages[i]}
+	}
+	return RespondUnprocessable(c, "validation_error", strings.Join(messages, "; "), errs...)
+}
+
+// fieldPath is where a field sits in the request body, e.g. items[0].quantity
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
 // formatValidationError converts validator.FieldError to user-friendly message
 func formatValidationError(fe validator.FieldError) string {
 	field := fe.Field()
@@ -251,29 +497,6 @@ func formatValidationError(fe validator.FieldError) string {
 	}
 }
 
-// ParseUUID safely parses UUID from string parameter
-func ParseUUID(c echo.Context, paramName string) (uuid.UUID, error) {
-	idStr := c.Param(paramName)
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		return uuid.Nil, RespondError(c, http.StatusBadRequest, "invalid_id",
-			fmt.Sprintf("The provided %s is not a valid UUID.", paramName))
-	}
-	return id, nil
-}
-
-// ParseInt safely parses integer from string parameter
-func ParseInt(c echo.Context, paramName string) (int32, error) {
-	idStr := c.Param(paramName)
-	var id int64
-	_, err := fmt.Sscanf(idStr, "%d", &id)
-	if err != nil {
-		return 0, RespondError(c, http.StatusBadRequest, "invalid_id",
-			fmt.Sprintf("The provided %s is not a valid number.", paramName))
-	}
-	return int32(id), nil
-}
-
 // GetUserLoginHistory - Admin endpoint to view user's login history
 func (s *Server) GetUserLoginHistory(c echo.Context) error {
 	ctx := c.Request().Context()
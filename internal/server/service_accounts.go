@@ -0,0 +1,260 @@
+// internal/server/service_accounts.go - Service accounts for scanners and reporting jobs
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateServiceAccountReq defines the request body for creating a service
+// account
+type CreateServiceAccountReq struct {
+	Username     string `json:"username" validate:"required,min=3,max=50"`
+	FullName     string `json:"full_name,omitempty"`
+	RoleID       int32  `json:"role_id" validate:"required,gt=0"`
+	DepartmentID *int32 `json:"department_id,omitempty" validate:"omitempty,gt=0"`
+}
+
+// ServiceAccount is a user for a machine. It cannot sign in; it calls the
+// API with the access tokens administrators create for it.
+type ServiceAccount struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	FullName     string    `json:"full_name,omitempty"`
+	RoleID       int32     `json:"role_id"`
+	DepartmentID *int32    `json:"department_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func serviceAccountResponse(u db.User) ServiceAccount {
+	resp := ServiceAccount{
+		ID:        u.ID,
+		Username:  u.Username,
+		FullName:  u.FullName.String,
+		RoleID:    u.RoleID.Int32,
+		CreatedAt: u.CreatedAt.Time,
+	}
+	if u.DepartmentID.Valid {
+		resp.DepartmentID = &u.DepartmentID.Int32
+	}
+	return resp
+}
+
+// serviceAccount returns the service account of the id route parameter,
+// writing the error response when there is none
+func (s *Server) serviceAccount(c echo.Context) (db.User, bool, error) {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return db.User{}, false, err
+	}
+	account, err := s.queries.GetServiceAccount(c.Request().Context(), id)
+	if err != nil {
+		return db.User{}, false, HandleDatabaseError(c, err, "Service account")
+	}
+	return account, true, nil
+}
+
+// CreateServiceAccount handles POST /api/v1/admin/service-accounts
+func (s *Server) CreateServiceAccount(c echo.Context) error {
+	var req CreateServiceAccountReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	role, err := s.roles.role(ctx, req.RoleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return respondFieldError(c, "invalid_role", "role_id",
+				fmt.Sprintf("Role with ID %d does not exist.", req.RoleID))
+		}
+		return HandleDatabaseError(c, err, "Role")
+	}
+	var departmentID sql.NullInt32
+	if req.DepartmentID != nil {
+		if ok, err := s.requireDepartment(c, *req.DepartmentID); !ok {
+			return err
+		}
+		departmentID = sql.NullInt32{Int32: *req.DepartmentID, Valid: true}
+	}
+
+	var account db.User
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		account, err = q.CreateServiceAccount(ctx, db.CreateServiceAccountParams{
+			Username:     req.Username,
+			FullName:     db.EncryptedString{String: req.FullName, Valid: req.FullName != ""},
+			RoleID:       sql.NullInt32{Int32: req.RoleID, Valid: true},
+			DepartmentID: departmentID,
+		})
+		if err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.UserCreated, account.ID.String(), outbox.UserPayload(account))
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Service account")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "create", "service_account", account.ID.String(), nil, map[string]any{
+		"username":      account.Username,
+		"role":          role.Name,
+		"department_id": account.DepartmentID.Int32,
+	}, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, serviceAccountResponse(account))
+}
+
+// ListServiceAccounts handles GET /api/v1/admin/service-accounts, newest
+// first
+func (s *Server) ListServiceAccounts(c echo.Context) error {
+	accounts, err := s.queries.ListServiceAccounts(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch service accounts.")
+	}
+
+	resp := make([]ServiceAccount, len(accounts))
+	for i, a := range accounts {
+		resp[i] = serviceAccountResponse(a)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// GetServiceAccount handles GET /api/v1/admin/service-accounts/:id
+func (s *Server) GetServiceAccount(c echo.Context) error {
+	account, ok, err := s.serviceAccount(c)
+	if !ok {
+		return err
+	}
+	return RespondSuccess(c, http.StatusOK, serviceAccountResponse(account))
+}
+
+// DeleteServiceAccount handles DELETE /api/v1/admin/service-accounts/:id,
+// deleting the account and revoking its tokens
+func (s *Server) DeleteServiceAccount(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	var account db.User
+	var revoked int64
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		account, err = q.DeleteServiceAccount(ctx, id)
+		if err != nil {
+			return err
+		}
+		revoked, err = q.RevokeUserPersonalAccessTokens(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.UserDeleted, id.String(), outbox.DeletedData{ID: id.String()})
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Service account")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "delete", "service_account", account.ID.String(),
+		map[string]any{"username": account.Username},
+		map[string]any{"deleted": true, "revoked_tokens": revoked},
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateServiceAccountToken handles POST
+// /api/v1/admin/service-accounts/:id/tokens. The token is limited to its
+// scopes like a personal access token, is in the response and cannot be
+// retrieved again.
+func (s *Server) CreateServiceAccountToken(c echo.Context) error {
+	account, ok, err := s.serviceAccount(c)
+	if !ok {
+		return err
+	}
+	req, ok, err := s.bindAccessToken(c)
+	if !ok {
+		return err
+	}
+
+	resp, ok, err := s.createAccessToken(c, account.ID, req)
+	if !ok {
+		return err
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(c.Request().Context(), userID, "create", "access_token", resp.ID.String(), nil, map[string]any{
+		"name":               resp.Name,
+		"scopes":             resp.Scopes,
+		"service_account_id": account.ID,
+	}, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, resp)
+}
+
+// ListServiceAccountTokens handles GET
+// /api/v1/admin/service-accounts/:id/tokens, listing the account's tokens
+// that have not been revoked
+func (s *Server) ListServiceAccountTokens(c echo.Context) error {
+	account, ok, err := s.serviceAccount(c)
+	if !ok {
+		return err
+	}
+
+	tokens, err := s.queries.ListPersonalAccessTokens(c.Request().Context(), account.ID)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch access tokens.")
+	}
+
+	resp := make([]AccessToken, len(tokens))
+	for i, t := range tokens {
+		resp[i] = accessTokenResponse(t)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// RevokeServiceAccountToken handles DELETE
+// /api/v1/admin/service-accounts/:id/tokens/:token_id
+func (s *Server) RevokeServiceAccountToken(c echo.Context) error {
+	account, ok, err := s.serviceAccount(c)
+	if !ok {
+		return err
+	}
+	tokenID, err := ParseUUID(c, "token_id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	revoked, err := s.queries.RevokePersonalAccessToken(ctx, db.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: account.ID,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Access token")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "revoke", "access_token", revoked.ID.String(), map[string]any{
+		"name":               revoked.Name,
+		"scopes":             revoked.Scopes,
+		"service_account_id": account.ID,
+	}, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -3,6 +3,7 @@ package server
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"os"
 
@@ -28,7 +29,7 @@ func (s *Server) InitialSetup(c echo.Context) error {
 
 	// Check if setup is already complete
 	setupStatus, err := s.queries.GetSystemSetupStatus(ctx)
-	if err != nil && err != sql.ErrNoRows {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return RespondError(c, http.StatusInternalServerError, "db_error",
 			"Failed to check setup status.")
 	}
@@ -41,17 +42,17 @@ func (s *Server) InitialSetup(c echo.Context) error {
 	// Parse request
 	var req InitialSetupRequest
 	if err := c.Bind(&req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "invalid_request",
+		return respondBindError(c, err,
 			"The request body is not valid.")
 	}
 
 	if err := s.validator.Struct(req); err != nil {
-		return RespondError(c, http.StatusBadRequest, "validation_error", err.Error())
+		return respondValidationError(c, err)
 	}
 
 	// Verify passwords match
 	if req.Password != req.ConfirmPassword {
-		return RespondError(c, http.StatusBadRequest, "password_mismatch",
+		return respondFieldError(c, "password_mismatch", "confirm_password",
 			"Passwords do not match.")
 	}
 
@@ -65,12 +66,7 @@ func (s *Server) InitialSetup(c echo.Context) error {
 	// Validate password strength
 	if err := security.ValidatePassword(req.Password,
 		security.DefaultPasswordRequirements()); err != nil {
-		suggestions := security.SuggestPasswordImprovement(req.Password)
-		return c.JSON(http.StatusBadRequest, map[string]any{
-			"error":       "weak_password",
-			"details":     err.Error(),
-			"suggestions": suggestions,
-		})
+		return respondWeakPassword(c, "password", req.Password, err, nil)
 	}
 
 	// Hash password
@@ -85,7 +81,7 @@ func (s *Server) InitialSetup(c echo.Context) error {
 	user, err := s.queries.CreateAdminUser(ctx, db.CreateAdminUserParams{
 		ID:           adminID,
 		Username:     req.Username,
-		FullName:     sql.NullString{String: req.FullName, Valid: true},
+		FullName:     db.EncryptedString{String: req.FullName, Valid: true},
 		PasswordHash: hashedPassword,
 		RoleID:       sql.NullInt32{Int32: 1, Valid: true}, // Admin role
 	})
@@ -130,7 +126,7 @@ func (s *Server) GetSetupStatus(c echo.Context) error {
 
 	setupStatus, err := s.queries.GetSystemSetupStatus(ctx)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return RespondSuccess(c, http.StatusOK, map[string]any{
 				"setup_required": true,
 				"admin_exists":   false,
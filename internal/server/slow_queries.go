@@ -0,0 +1,295 @@
+// internal/server/slow_queries.go - Query latency metrics and the slow query log
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jamalkaksouri/DigiOrder/internal/config"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// Slow queries waiting to be written; more are dropped and counted
+const slowQueryQueue = 256
+
+// How often slow queries past their retention are deleted
+const slowQueryPruneInterval = time.Hour
+
+// Defaults and limits of GET /admin/slow-queries
+const (
+	defaultSlowQueryHours = 24
+	maxSlowQueryHours     = 720
+	defaultSlowQueryLimit = 50
+	maxSlowQueryLimit     = 200
+	slowQuerySummaryLimit = 50
+)
+
+// SlowQueryReport lists the slow queries of a period, per statement and
+// one by one
+type SlowQueryReport struct {
+	ThresholdMs float64            `json:"threshold_ms"`
+	Since       time.Time          `json:"since"`
+	Dropped     int64              `json:"dropped"`
+	Summary     []SlowQuerySummary `json:"summary"`
+	Queries     []SlowQuery        `json:"queries"`
+}
+
+// SlowQuerySummary aggregates the slow runs of one statement
+type SlowQuerySummary struct {
+	Name        string    `json:"name"`
+	Operation   string    `json:"operation"`
+	Table       string    `json:"table"`
+	Occurrences int64     `json:"occurrences"`
+	AvgMs       float64   `json:"avg_ms"`
+	MaxMs       float64   `json:"max_ms"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// SlowQuery is one slow statement. Params holds the type of each
+// parameter, never its value.
+type SlowQuery struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Operation  string    `json:"operation"`
+	Table      string    `json:"table"`
+	Query      string    `json:"query"`
+	Params     []string  `json:"params"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// slowQueryLog times every statement issued through the connections it
+// wraps, feeding the db_query metrics, and keeps those slower than the
+// threshold in slow_queries. They are written in the background so a
+// struggling database is not given more work on the request path.
+type slowQueryLog struct {
+	threshold time.Duration
+	retention time.Duration
+	queries   db.Querier // unwrapped, so writing the log is not itself logged
+	logger    *logging.Logger
+	entries   chan db.InsertSlowQueryParams
+	dropped   atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newSlowQueryLog creates the log; writing starts with Start. Without a
+// database (e.g. against a mock Querier) only the metrics are recorded.
+func newSlowQueryLog(database *pgxpool.Pool, cfg config.DatabaseConfig, logger *logging.Logger) *slowQueryLog {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &slowQueryLog{
+		threshold: cfg.SlowQueryThreshold,
+		retention: cfg.SlowQueryRetention,
+		logger:    logger,
+		entries:   make(chan db.InsertSlowQueryParams, slowQueryQueue),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	if database != nil {
+		l.queries = db.New(database)
+	}
+	return l
+}
+
+// wrap returns conn with every statement tagged with its request and timed
+func (l *slowQueryLog) wrap(conn db.DBTX) db.DBTX {
+	return db.NewTimedDB(db.NewTaggedDB(conn), l.observe)
+}
+
+func (l *slowQueryLog) observe(ctx context.Context, stat db.QueryStat) {
+	middleware.RecordDBQuery(stat.Operation, stat.Table, stat.Duration)
+	if l.threshold <= 0 || stat.Duration < l.threshold || l.queries == nil {
+		return
+	}
+
+	entry := db.InsertSlowQueryParams{
+		QueryName:  stat.Name,
+		Operation:  stat.Operation,
+		TableName:  stat.Table,
+		Query:      stat.Query,
+		Params:     db.RedactArgs(stat.Args),
+		DurationMs: float64(stat.Duration.Microseconds()) / 1000,
+	}
+	if stat.Err != nil {
+		entry.Error = sql.NullString{String: stat.Err.Error(), Valid: true}
+	}
+	if tag := db.QueryTagFromContext(ctx); tag != nil {
+		entry.RequestID = sql.NullString{String: tag.RequestID, Valid: tag.RequestID != ""}
+		entry.Route = sql.NullString{String: tag.Route, Valid: tag.Route != ""}
+		entry.UserID = sql.NullString{String: tag.UserID, Valid: tag.UserID != ""}
+	}
+
+	select {
+	case l.entries <- entry:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Start launches the loop that writes slow queries and prunes old ones
+func (l *slowQueryLog) Start() {
+	if l.queries == nil || l.threshold <= 0 {
+		return
+	}
+	l.wg.Add(1)
+	go l.loop()
+}
+
+// Stop ends the loop after writing the queued slow queries, or when ctx
+// expires
+func (l *slowQueryLog) Stop(ctx context.Context) {
+	l.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (l *slowQueryLog) loop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(slowQueryPruneInterval)
+	defer ticker.Stop()
+
+	l.prune()
+	for {
+		select {
+		case entry := <-l.entries:
+			l.write(entry)
+		case <-ticker.C:
+			l.prune()
+		case <-l.ctx.Done():
+			for {
+				select {
+				case entry := <-l.entries:
+					l.write(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *slowQueryLog) write(entry db.InsertSlowQueryParams) {
+	l.logger.Warn("Slow query", map[string]any{
+		"query":       entry.QueryName,
+		"table":       entry.TableName,
+		"duration_ms": entry.DurationMs,
+		"request_id":  entry.RequestID.String,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.queries.InsertSlowQuery(ctx, entry); err != nil {
+		l.logger.Error("Failed to record slow query", err, map[string]any{"query": entry.QueryName})
+	}
+}
+
+func (l *slowQueryLog) prune() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := l.queries.DeleteSlowQueriesBefore(ctx, time.Now().Add(-l.retention)); err != nil {
+		l.logger.Error("Failed to prune slow queries", err, nil)
+	}
+}
+
+// GetSlowQueries handles GET /api/v1/admin/slow-queries: statements slower
+// than database.slow_query_threshold over the last ?hours, summarized per
+// statement and listed newest first. ?query= narrows the list to one
+// statement by name.
+func (s *Server) GetSlowQueries(c echo.Context) error {
+	hours, ok := overviewParam(c, "hours", defaultSlowQueryHours, maxSlowQueryHours)
+	if !ok {
+		return RespondError(c, http.StatusBadRequest, "validation_error",
+			"hours must be a whole number between 1 and "+strconv.Itoa(maxSlowQueryHours)+".")
+	}
+	limit, ok := overviewParam(c, "limit", defaultSlowQueryLimit, maxSlowQueryLimit)
+	if !ok {
+		return RespondError(c, http.StatusBadRequest, "invalid_limit",
+			"limit must be a whole number between 1 and "+strconv.Itoa(maxSlowQueryLimit)+".")
+	}
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	name := c.QueryParam("query")
+
+	ctx := c.Request().Context()
+	report := SlowQueryReport{
+		ThresholdMs: float64(s.slowQueries.threshold.Microseconds()) / 1000,
+		Since:       time.Now().Add(-time.Duration(hours) * time.Hour),
+		Dropped:     s.slowQueries.dropped.Load(),
+		Summary:     []SlowQuerySummary{},
+		Queries:     []SlowQuery{},
+	}
+
+	summary, err := s.queries.SummarizeSlowQueries(ctx, db.SummarizeSlowQueriesParams{
+		Since:      report.Since,
+		LimitCount: slowQuerySummaryLimit,
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to summarize slow queries.")
+	}
+	for _, row := range summary {
+		report.Summary = append(report.Summary, SlowQuerySummary{
+			Name:        row.QueryName,
+			Operation:   row.Operation,
+			Table:       row.TableName,
+			Occurrences: row.Occurrences,
+			AvgMs:       row.AvgMs,
+			MaxMs:       row.MaxMs,
+			LastSeen:    row.LastSeen,
+		})
+	}
+
+	rows, err := s.queries.ListSlowQueries(ctx, db.ListSlowQueriesParams{
+		Since:       report.Since,
+		QueryName:   sql.NullString{String: name, Valid: name != ""},
+		LimitCount:  int32(limit),
+		OffsetCount: int32(offset),
+	})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve slow queries.")
+	}
+	for _, row := range rows {
+		report.Queries = append(report.Queries, SlowQuery{
+			ID:         row.ID,
+			Name:       row.QueryName,
+			Operation:  row.Operation,
+			Table:      row.TableName,
+			Query:      row.Query,
+			Params:     row.Params,
+			DurationMs: row.DurationMs,
+			Error:      row.Error.String,
+			RequestID:  row.RequestID.String,
+			Route:      row.Route.String,
+			UserID:     row.UserID.String,
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+
+	return RespondSuccess(c, http.StatusOK, report)
+}
@@ -0,0 +1,72 @@
+// The spec is served only to authenticated users, so sign in first and
+// attach the token to every request Swagger UI makes.
+const specURL = "/api/v1/openapi.json";
+const storageKey = "digiorder_docs_token";
+const form = document.getElementById("login");
+const signout = document.getElementById("signout");
+
+function showLogin(message) {
+  sessionStorage.removeItem(storageKey);
+  document.getElementById("swagger-ui").innerHTML = "";
+  document.getElementById("login-error").textContent = message || "";
+  signout.hidden = true;
+  form.hidden = false;
+}
+
+async function showDocs(token) {
+  const resp = await fetch(specURL, { headers: { Authorization: "Bearer " + token } });
+  if (resp.status === 401) {
+    showLogin("Your session has expired. Please sign in again.");
+    return;
+  }
+  if (!resp.ok) {
+    showLogin("Could not load the API specification (" + resp.status + ").");
+    return;
+  }
+
+  form.hidden = true;
+  signout.hidden = false;
+  const ui = SwaggerUIBundle({
+    spec: await resp.json(),
+    dom_id: "#swagger-ui",
+    persistAuthorization: false,
+    requestInterceptor: (req) => {
+      if (!req.headers.Authorization) {
+        req.headers.Authorization = "Bearer " + token;
+      }
+      return req;
+    },
+  });
+  ui.preauthorizeApiKey("bearerAuth", token);
+}
+
+form.addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const body = { username: form.username.value, password: form.password.value };
+  const resp = await fetch("/api/v1/auth/login", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(body),
+  });
+  const payload = await resp.json().catch(() => ({}));
+  if (!resp.ok || !payload.data) {
+    document.getElementById("login-error").textContent = payload.details || payload.error || "Sign in failed.";
+    return;
+  }
+  form.password.value = "";
+  sessionStorage.setItem(storageKey, payload.data.token);
+  showDocs(payload.data.token);
+});
+
+signout.addEventListener("click", () => showLogin());
+
+const saved = sessionStorage.getItem(storageKey);
+if (typeof SwaggerUIBundle === "undefined") {
+  // The vendored Swagger UI files are missing from the build (make swagger-ui)
+  showLogin("Swagger UI is not available in this build.");
+  form.querySelector("button").disabled = true;
+} else if (saved) {
+  showDocs(saved);
+} else {
+  showLogin();
+}
@@ -0,0 +1,151 @@
+// internal/server/stock.go - Product stock levels
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// ProductStock is the quantity on hand of a product. A product is low on
+// stock once on_hand is at or below its reorder level.
+type ProductStock struct {
+	ProductID    uuid.UUID  `json:"product_id"`
+	OnHand       int32      `json:"on_hand"`
+	ReorderLevel int32      `json:"reorder_level"`
+	Low          bool       `json:"low"`
+	UpdatedBy    *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateProductStockReq defines the request body for recording stock
+type UpdateProductStockReq struct {
+	OnHand       *int32 `json:"on_hand"`
+	ReorderLevel *int32 `json:"reorder_level"`
+}
+
+// GetProductStock handles GET /api/v1/products/:id/stock. Products whose
+// stock was never recorded report zero on hand and no reorder level.
+func (s *Server) GetProductStock(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireProduct(c, id); !ok {
+		return err
+	}
+
+	stock, err := s.queries.GetProductStock(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RespondSuccess(c, http.StatusOK, ProductStock{ProductID: id})
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product stock")
+	}
+	return RespondSuccess(c, http.StatusOK, productStockResponse(stock))
+}
+
+// UpdateProductStock handles PUT /api/v1/products/:id/stock. Omitted
+// fields keep their current value.
+func (s *Server) UpdateProductStock(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateProductStockReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if req.OnHand == nil && req.ReorderLevel == nil {
+		return RespondUnprocessable(c, "validation_error",
+			"Set on_hand, reorder_level or both.")
+	}
+	var errs []FieldError
+	if req.OnHand != nil && *req.OnHand < 0 {
+		errs = append(errs, FieldError{Field: "on_hand", Code: "gte", Message: "Field 'on_hand' must not be negative"})
+	}
+	if req.ReorderLevel != nil && *req.ReorderLevel < 0 {
+		errs = append(errs, FieldError{Field: "reorder_level", Code: "gte", Message: "Field 'reorder_level' must not be negative"})
+	}
+	if len(errs) > 0 {
+		return RespondUnprocessable(c, "validation_error",
+			"Fields 'on_hand' and 'reorder_level' must not be negative.", errs...)
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireProduct(c, id); !ok {
+		return err
+	}
+
+	old, err := s.queries.GetProductStock(ctx, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return HandleDatabaseError(c, err, "Product stock")
+	}
+	existed := err == nil
+
+	params := db.UpsertProductStockParams{
+		ProductID:    id,
+		OnHand:       old.OnHand,
+		ReorderLevel: old.ReorderLevel,
+	}
+	if req.OnHand != nil {
+		params.OnHand = *req.OnHand
+	}
+	if req.ReorderLevel != nil {
+		params.ReorderLevel = *req.ReorderLevel
+	}
+	userID, _ := middleware.GetUserIDFromContext(c)
+	params.UpdatedBy = uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil}
+
+	stock, err := s.queries.UpsertProductStock(ctx, params)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Product stock")
+	}
+
+	var oldValues map[string]any
+	if existed {
+		oldValues = map[string]any{"on_hand": old.OnHand, "reorder_level": old.ReorderLevel}
+	}
+	s.logAudit(ctx, userID, "update", "product_stock", id.String(),
+		oldValues, map[string]any{"on_hand": stock.OnHand, "reorder_level": stock.ReorderLevel},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, productStockResponse(stock))
+}
+
+// requireProduct reports whether the product exists and is not deleted,
+// writing the error response when it does not
+func (s *Server) requireProduct(c echo.Context, id uuid.UUID) (bool, error) {
+	product, err := s.queries.GetProduct(c.Request().Context(), id)
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Product")
+	}
+	if product.DeletedAt.Valid {
+		return false, ErrNotFound.WithDetails("Product has been deleted").Send(c)
+	}
+	return true, nil
+}
+
+func productStockResponse(r db.ProductStock) ProductStock {
+	resp := ProductStock{
+		ProductID:    r.ProductID,
+		OnHand:       r.OnHand,
+		ReorderLevel: r.ReorderLevel,
+		Low:          r.OnHand <= r.ReorderLevel,
+		UpdatedAt:    &r.UpdatedAt,
+	}
+	if r.UpdatedBy.Valid {
+		resp.UpdatedBy = &r.UpdatedBy.UUID
+	}
+	return resp
+}
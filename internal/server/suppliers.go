@@ -0,0 +1,332 @@
+// internal/server/suppliers.go - Distributors order items are routed to
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// SupplierReq defines the request body for creating or replacing a
+// supplier
+type SupplierReq struct {
+	Name  string `json:"name" validate:"required,max=200"`
+	Phone string `json:"phone,omitempty" validate:"max=50"`
+	Email string `json:"email,omitempty" validate:"omitempty,email,max=254"`
+}
+
+// Supplier is a distributor order items can be routed to
+type Supplier struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RouteOrderItemsReq routes items of an order to suppliers; a null
+// supplier_id unroutes the item. Items not listed keep their supplier.
+type RouteOrderItemsReq struct {
+	Items []OrderItemRoute `json:"items" validate:"required,min=1,max=500,dive"`
+}
+
+// OrderItemRoute is the supplier one item is routed to
+type OrderItemRoute struct {
+	ItemID     uuid.UUID `json:"item_id" validate:"required"`
+	SupplierID *int32    `json:"supplier_id" validate:"omitempty,gt=0"`
+}
+
+// SupplierShare is the part of an order routed to one supplier, or to
+// none when Supplier is null: its items and what they come to
+type SupplierShare struct {
+	Supplier *Supplier      `json:"supplier"`
+	Items    []db.OrderItem `json:"items"`
+	Subtotal string         `json:"subtotal"`
+}
+
+func supplierResponse(s db.Supplier) Supplier {
+	return Supplier{
+		ID:        s.ID,
+		Name:      s.Name,
+		Phone:     s.Phone.String,
+		Email:     s.Email.String,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// CreateSupplier handles POST /api/v1/suppliers
+func (s *Server) CreateSupplier(c echo.Context) error {
+	var req SupplierReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	supplier, err := s.queries.CreateSupplier(c.Request().Context(), db.CreateSupplierParams{
+		Name:  req.Name,
+		Phone: sql.NullString{String: req.Phone, Valid: req.Phone != ""},
+		Email: sql.NullString{String: req.Email, Valid: req.Email != ""},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Supplier")
+	}
+
+	return RespondSuccess(c, http.StatusCreated, supplierResponse(supplier))
+}
+
+// ListSuppliers handles GET /api/v1/suppliers, by name
+func (s *Server) ListSuppliers(c echo.Context) error {
+	suppliers, err := s.queries.ListSuppliers(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to retrieve suppliers.")
+	}
+
+	resp := make([]Supplier, len(suppliers))
+	for i, supplier := range suppliers {
+		resp[i] = supplierResponse(supplier)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// GetSupplier handles GET /api/v1/suppliers/:id
+func (s *Server) GetSupplier(c echo.Context) error {
+	id, err := ParseInt(c, "id")
+	if err != nil {
+		return err
+	}
+
+	supplier, err := s.queries.GetSupplier(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Supplier")
+	}
+
+	return RespondSuccess(c, http.StatusOK, supplierResponse(supplier))
+}
+
+// UpdateSupplier handles PUT /api/v1/suppliers/:id
+func (s *Server) UpdateSupplier(c echo.Context) error {
+	id, err := ParseInt(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req SupplierReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	supplier, err := s.queries.UpdateSupplier(c.Request().Context(), db.UpdateSupplierParams{
+		ID:    id,
+		Name:  req.Name,
+		Phone: sql.NullString{String: req.Phone, Valid: req.Phone != ""},
+		Email: sql.NullString{String: req.Email, Valid: req.Email != ""},
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Supplier")
+	}
+
+	return RespondSuccess(c, http.StatusOK, supplierResponse(supplier))
+}
+
+// DeleteSupplier handles DELETE /api/v1/suppliers/:id. The items routed
+// to it are left unrouted.
+func (s *Server) DeleteSupplier(c echo.Context) error {
+	id, err := ParseInt(c, "id")
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.queries.DeleteSupplier(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Supplier")
+	}
+	if deleted == 0 {
+		return RespondError(c, http.StatusNotFound, "not_found", "Supplier with the specified ID was not found.")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// requireSupplier responds with invalid_supplier unless the supplier
+// exists; field is the request field that named it
+func (s *Server) requireSupplier(c echo.Context, id int32, field string) (bool, error) {
+	_, err := s.queries.GetSupplier(c.Request().Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, respondFieldError(c, "invalid_supplier", field,
+			fmt.Sprintf("Supplier with ID %d does not exist.", id))
+	}
+	if err != nil {
+		return false, HandleDatabaseError(c, err, "Supplier")
+	}
+	return true, nil
+}
+
+// GetOrderSuppliers handles GET /api/v1/orders/:id/suppliers, splitting
+// the order by the supplier each item is routed to
+func (s *Server) GetOrderSuppliers(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireOrder(c, id); !ok {
+		return err
+	}
+	items, err := s.queries.GetOrderItems(ctx, uuid.NullUUID{UUID: id, Valid: true})
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order items.")
+	}
+	shares, err := s.splitBySupplier(c, items)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Supplier")
+	}
+
+	return RespondSuccess(c, http.StatusOK, shares)
+}
+
+// RouteOrderItems handles PUT /api/v1/orders/:id/suppliers, routing items
+// of the order to suppliers in one transaction. It answers with the order
+// split as GetOrderSuppliers does.
+func (s *Server) RouteOrderItems(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req RouteOrderItemsReq
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err,
+			"The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return respondValidationError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	if ok, err := s.requireOrder(c, id); !ok {
+		return err
+	}
+	orderID := uuid.NullUUID{UUID: id, Valid: true}
+	items, err := s.queries.GetOrderItems(ctx, orderID)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error",
+			"Failed to fetch order items.")
+	}
+
+	var checked []int32
+	for i, route := range req.Items {
+		if !slices.ContainsFunc(items, func(item db.OrderItem) bool { return item.ID == route.ItemID }) {
+			return respondFieldError(c, "invalid_order_item", fmt.Sprintf("items[%d].item_id", i),
+				fmt.Sprintf("Item %s is not on this order.", route.ItemID))
+		}
+		if route.SupplierID != nil && !slices.Contains(checked, *route.SupplierID) {
+			if ok, err := s.requireSupplier(c, *route.SupplierID, fmt.Sprintf("items[%d].supplier_id", i)); !ok {
+				return err
+			}
+			checked = append(checked, *route.SupplierID)
+		}
+	}
+
+	routed := make(map[uuid.UUID]*int32, len(req.Items))
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := s.checkOrderPolicy(c, q); err != nil {
+			return err
+		}
+		for _, route := range req.Items {
+			var supplierID sql.NullInt32
+			if route.SupplierID != nil {
+				supplierID = sql.NullInt32{Int32: *route.SupplierID, Valid: true}
+			}
+			item, err := q.SetOrderItemSupplier(ctx, db.SetOrderItemSupplierParams{
+				SupplierID: supplierID,
+				ID:         route.ItemID,
+				OrderID:    orderID,
+			})
+			if err != nil {
+				return err
+			}
+			for i := range items {
+				if items[i].ID == item.ID {
+					items[i] = item
+				}
+			}
+			routed[item.ID] = route.SupplierID
+		}
+		return nil
+	})
+	if denied, err := respondPolicyDenied(c, err); denied {
+		return err
+	}
+	if err != nil {
+		return HandleDatabaseError(c, err, "Order item")
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, userID, "route_items", "order", id.String(),
+		nil, map[string]any{"suppliers": routed},
+		c.RealIP(), c.Request().UserAgent())
+
+	shares, err := s.splitBySupplier(c, items)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Supplier")
+	}
+	return RespondSuccess(c, http.StatusOK, shares)
+}
+
+// splitBySupplier groups items by their supplier, in the order of the
+// suppliers' names, with the unrouted items last
+func (s *Server) splitBySupplier(c echo.Context, items []db.OrderItem) ([]SupplierShare, error) {
+	var ids []int32
+	for _, item := range items {
+		if item.SupplierID.Valid && !slices.Contains(ids, item.SupplierID.Int32) {
+			ids = append(ids, item.SupplierID.Int32)
+		}
+	}
+	shares := make([]SupplierShare, 0, len(ids)+1)
+	for _, id := range ids {
+		supplier, err := s.queries.GetSupplier(c.Request().Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		resp := supplierResponse(supplier)
+		shares = append(shares, SupplierShare{Supplier: &resp})
+	}
+	slices.SortFunc(shares, func(a, b SupplierShare) int {
+		return strings.Compare(a.Supplier.Name, b.Supplier.Name)
+	})
+	unrouted := SupplierShare{}
+
+	for _, item := range items {
+		share := &unrouted
+		if item.SupplierID.Valid {
+			i := slices.IndexFunc(shares, func(sh SupplierShare) bool { return sh.Supplier.ID == item.SupplierID.Int32 })
+			share = &shares[i]
+		}
+		share.Items = append(share.Items, item)
+	}
+	if len(unrouted.Items) > 0 {
+		shares = append(shares, unrouted)
+	}
+
+	for i := range shares {
+		sum := new(big.Rat)
+		for _, item := range shares[i].Items {
+			if total, ok := new(big.Rat).SetString(item.LineTotal.String); item.LineTotal.Valid && ok {
+				sum.Add(sum, total)
+			}
+		}
+		shares[i].Subtotal = sum.FloatString(2)
+	}
+	return shares, nil
+}
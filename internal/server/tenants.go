@@ -0,0 +1,271 @@
+// internal/server/tenants.go - Pharmacies sharing one deployment
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/reports"
+	"github.com/jamalkaksouri/DigiOrder/internal/security"
+	"github.com/labstack/echo/v4"
+)
+
+// Role every tenant's first user gets (see InitialSetup)
+const tenantAdminRoleID = 1
+
+var tenantSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CreateTenantReq creates a pharmacy together with its first admin, who
+// then adds the pharmacy's other users
+type CreateTenantReq struct {
+	Slug  string         `json:"slug" validate:"required,max=50"`
+	Name  string         `json:"name" validate:"required,max=200"`
+	Admin TenantAdminReq `json:"admin" validate:"required"`
+}
+
+// TenantAdminReq is the first admin of a new tenant
+type TenantAdminReq struct {
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	FullName string `json:"full_name,omitempty"`
+	Password string `json:"password" validate:"required,min=12"`
+}
+
+// CreateTenantResponse is the new tenant and its admin
+type CreateTenantResponse struct {
+	Tenant db.Tenant `json:"tenant"`
+	Admin  UserInfo  `json:"admin"`
+}
+
+// UpdateTenantReq renames a tenant; its slug never changes
+type UpdateTenantReq struct {
+	Name string `json:"name" validate:"required,max=200"`
+}
+
+// tenantMiddleware scopes the database session of every authenticated
+// request to the caller's tenant, so row level security hides the other
+// pharmacies' users, orders and products from every query the request
+// makes. Unless the caller is an admin, it is also scoped to the caller's
+// department, which hides other departments' orders, or, for a caller
+// without one whose role is not granted orders:read_all, to the caller,
+// which hides every order they did not create. Requests with none of
+// these run on the pool as before.
+func (s *Server) tenantMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if s.db == nil {
+			return next
+		}
+		return func(c echo.Context) error {
+			scope := db.TenantScope{SharedCatalog: s.config.Tenancy.SharedCatalog}
+			if s.config.Tenancy.Enabled {
+				tenantID, err := middleware.GetTenantIDFromContext(c)
+				if err != nil {
+					return RespondError(c, http.StatusUnauthorized, "unauthorized", "Authentication required.")
+				}
+				scope.ID = tenantID
+			}
+			if role, _ := middleware.GetRoleNameFromContext(c); role != "admin" {
+				scope.DepartmentID = middleware.GetDepartmentIDFromContext(c)
+				if scope.DepartmentID == 0 {
+					owner, err := s.orderOwner(c)
+					if err != nil {
+						return RespondError(c, http.StatusInternalServerError, "internal_error",
+							"Failed to check permissions.")
+					}
+					scope.OwnerID = owner
+				}
+			}
+			if scope.ID == uuid.Nil && scope.DepartmentID == 0 && scope.OwnerID == uuid.Nil {
+				return next(c)
+			}
+
+			ctx, release, err := db.PinTenant(c.Request().Context(), s.db, scope)
+			if err != nil {
+				s.logger.Error("Failed to scope request to tenant", err, map[string]any{
+					"tenant_id":     scope.ID.String(),
+					"department_id": scope.DepartmentID,
+					"owner_id":      scope.OwnerID.String(),
+				})
+				return RespondError(c, http.StatusServiceUnavailable, "database_unavailable",
+					"The database is temporarily unavailable.")
+			}
+			defer release()
+
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// orderOwner returns the user whose orders a request without a
+// department is limited to: the caller, unless their role is granted
+// orders:read_all, in which case uuid.Nil
+func (s *Server) orderOwner(c echo.Context) (uuid.UUID, error) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	roleID, err := middleware.GetRoleIDFromContext(c)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	readAll, err := s.roles.hasPermission(c.Request().Context(), roleID, "orders", "read_all")
+	if err != nil || readAll {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// tenantScope returns the scope for background work done on behalf of a
+// tenant, or nil when tenancy is disabled and such work sees every tenant
+func (s *Server) tenantScope() reports.ScopeFunc {
+	if !s.config.Tenancy.Enabled || s.db == nil {
+		return nil
+	}
+	return s.pinTenant
+}
+
+// pinTenant scopes the statements issued with the returned context to a
+// tenant until release is called
+func (s *Server) pinTenant(ctx context.Context, tenantID uuid.UUID) (context.Context, func(), error) {
+	return db.PinTenant(ctx, s.db, db.TenantScope{
+		ID:            tenantID,
+		SharedCatalog: s.config.Tenancy.SharedCatalog,
+	})
+}
+
+// requireMainTenant limits a route to users of the main tenant, who
+// operate the deployment
+func requireMainTenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tenantID, err := middleware.GetTenantIDFromContext(c)
+		if err != nil || tenantID != db.DefaultTenantID {
+			return RespondError(c, http.StatusForbidden, "insufficient_permissions",
+				"Only administrators of the main pharmacy can manage tenants.")
+		}
+		return next(c)
+	}
+}
+
+// ListTenants handles GET /api/v1/tenants
+func (s *Server) ListTenants(c echo.Context) error {
+	tenants, err := s.queries.ListTenants(c.Request().Context())
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch tenants.")
+	}
+	return RespondSuccess(c, http.StatusOK, tenants)
+}
+
+// GetTenant handles GET /api/v1/tenants/:id
+func (s *Server) GetTenant(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	tenant, err := s.queries.GetTenant(c.Request().Context(), id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Tenant")
+	}
+	return RespondSuccess(c, http.StatusOK, tenant)
+}
+
+// CreateTenant handles POST /api/v1/tenants. The tenant and its admin are
+// created in one transaction, the admin inside the new tenant.
+func (s *Server) CreateTenant(c echo.Context) error {
+	var req CreateTenantReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+	if !tenantSlug.MatchString(req.Slug) {
+		return RespondError(c, http.StatusBadRequest, "invalid_slug",
+			"slug must be lowercase letters and digits separated by single hyphens.")
+	}
+	if err := security.ValidatePassword(req.Admin.Password,
+		security.DefaultPasswordRequirements()); err != nil {
+		return respondWeakPassword(c, "admin.password", req.Admin.Password, err, nil)
+	}
+	hashedPassword, err := security.HashPassword(req.Admin.Password)
+	if err != nil {
+		s.logger.Error("Failed to hash password", err, nil)
+		return RespondError(c, http.StatusInternalServerError, "hash_error",
+			"Failed to process password. Please try again.")
+	}
+
+	ctx := c.Request().Context()
+	var tenant db.Tenant
+	var admin db.User
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		if tenant, err = q.CreateTenant(ctx, db.CreateTenantParams{Slug: req.Slug, Name: req.Name}); err != nil {
+			return err
+		}
+		// The admin's tenant_id defaults to the one in scope
+		err = q.ScopeToTenant(ctx, db.ScopeToTenantParams{TenantID: tenant.ID.String()})
+		if err != nil {
+			return err
+		}
+		admin, err = q.CreateUser(ctx, db.CreateUserParams{
+			Username:     req.Admin.Username,
+			FullName:     db.EncryptedString{String: req.Admin.FullName, Valid: req.Admin.FullName != ""},
+			PasswordHash: hashedPassword,
+			RoleID:       sql.NullInt32{Int32: tenantAdminRoleID, Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Tenant")
+	}
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "create", "tenant", tenant.ID.String(), nil, map[string]any{
+		"slug":           tenant.Slug,
+		"name":           tenant.Name,
+		"admin_user_id":  admin.ID,
+		"admin_username": admin.Username,
+	}, c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, CreateTenantResponse{
+		Tenant: tenant,
+		Admin: UserInfo{
+			ID:       admin.ID.String(),
+			Username: admin.Username,
+			FullName: admin.FullName.String,
+			RoleID:   admin.RoleID.Int32,
+			RoleName: "admin",
+		},
+	})
+}
+
+// UpdateTenant handles PUT /api/v1/tenants/:id
+func (s *Server) UpdateTenant(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	var req UpdateTenantReq
+	if err := s.ValidateRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	old, err := s.queries.GetTenant(ctx, id)
+	if err != nil {
+		return HandleDatabaseError(c, err, "Tenant")
+	}
+	tenant, err := s.queries.UpdateTenant(ctx, db.UpdateTenantParams{ID: id, Name: req.Name})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Tenant")
+	}
+
+	currentUserID, _ := middleware.GetUserIDFromContext(c)
+	s.logAudit(ctx, currentUserID, "update", "tenant", id.String(),
+		map[string]any{"name": old.Name}, map[string]any{"name": tenant.Name},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusOK, tenant)
+}
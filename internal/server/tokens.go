@@ -0,0 +1,230 @@
+// internal/server/tokens.go - Personal access tokens for scripts and BI tools
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateAccessTokenReq defines the request body for creating a personal
+// access token. ExpiresInDays 0 creates a token that never expires.
+type CreateAccessTokenReq struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"min=0,max=366"`
+}
+
+// AccessToken is a personal access token. Token holds the secret and is
+// only returned when the token is created.
+type AccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Expired    bool       `json:"expired"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// hashAccessToken is the stored form of a token
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveAccessToken is the middleware.TokenResolver for the API
+func (s *Server) resolveAccessToken(c echo.Context, token string) (*middleware.TokenPrincipal, error) {
+	ctx := c.Request().Context()
+	row, err := s.queries.GetPersonalAccessTokenByHash(ctx, hashAccessToken(token))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error("Failed to look up access token", err, nil)
+		}
+		return nil, middleware.ErrInvalidToken
+	}
+	if row.RevokedAt.Valid {
+		return nil, middleware.ErrInvalidToken
+	}
+	if row.ExpiresAt.Valid && time.Now().After(row.ExpiresAt.Time) {
+		return nil, middleware.ErrExpiredToken
+	}
+
+	err = s.queries.TouchPersonalAccessToken(ctx, db.TouchPersonalAccessTokenParams{
+		ID:         row.ID,
+		LastUsedIp: sql.NullString{String: c.RealIP(), Valid: c.RealIP() != ""},
+	})
+	if err != nil {
+		s.logger.Warn("Failed to record access token use", map[string]any{
+			"token_id": row.ID.String(),
+			"error":    err.Error(),
+		})
+	}
+
+	return &middleware.TokenPrincipal{
+		TokenID:      row.ID,
+		UserID:       row.UserID,
+		Username:     row.Username,
+		RoleID:       row.RoleID.Int32,
+		RoleName:     row.RoleName.String,
+		TenantID:     row.TenantID,
+		DepartmentID: row.DepartmentID.Int32,
+		Language:     row.Language.String,
+		Scopes:       row.Scopes,
+	}, nil
+}
+
+// CreateAccessToken handles POST /api/v1/auth/tokens. The token is in the
+// response and cannot be retrieved again.
+func (s *Server) CreateAccessToken(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+	req, ok, err := s.bindAccessToken(c)
+	if !ok {
+		return err
+	}
+
+	resp, ok, err := s.createAccessToken(c, userID, req)
+	if !ok {
+		return err
+	}
+
+	s.logAudit(c.Request().Context(), userID, "create", "access_token", resp.ID.String(),
+		nil, map[string]any{"name": resp.Name, "scopes": resp.Scopes},
+		c.RealIP(), c.Request().UserAgent())
+
+	return RespondSuccess(c, http.StatusCreated, resp)
+}
+
+// bindAccessToken reads and checks a token request, writing the error
+// response when it is invalid
+func (s *Server) bindAccessToken(c echo.Context) (CreateAccessTokenReq, bool, error) {
+	var req CreateAccessTokenReq
+	if err := c.Bind(&req); err != nil {
+		return req, false, respondBindError(c, err, "The request body is not valid.")
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return req, false, respondValidationError(c, err)
+	}
+	for _, scope := range req.Scopes {
+		if !middleware.ValidScope(scope) {
+			return req, false, RespondError(c, http.StatusBadRequest, "invalid_scope",
+				fmt.Sprintf("Unknown scope %q.", scope))
+		}
+	}
+	return req, true, nil
+}
+
+// createAccessToken creates a token for the user, writing the error
+// response when it fails. The response holds the token itself.
+func (s *Server) createAccessToken(c echo.Context, userID uuid.UUID, req CreateAccessTokenReq) (AccessToken, bool, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return AccessToken{}, false, RespondError(c, http.StatusInternalServerError, "token_error",
+			"Failed to generate the token.")
+	}
+	token := middleware.PATPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	var expiresAt sql.NullTime
+	if req.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresInDays), Valid: true}
+	}
+
+	created, err := s.queries.CreatePersonalAccessToken(c.Request().Context(), db.CreatePersonalAccessTokenParams{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenHash:   hashAccessToken(token),
+		TokenPrefix: token[:len(middleware.PATPrefix)+4],
+		Scopes:      req.Scopes,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return AccessToken{}, false, HandleDatabaseError(c, err, "Access token")
+	}
+
+	resp := accessTokenResponse(created)
+	resp.Token = token
+	return resp, true, nil
+}
+
+// ListAccessTokens handles GET /api/v1/auth/tokens, listing the caller's
+// tokens that have not been revoked
+func (s *Server) ListAccessTokens(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := s.queries.ListPersonalAccessTokens(c.Request().Context(), userID)
+	if err != nil {
+		return RespondError(c, http.StatusInternalServerError, "db_error", "Failed to fetch access tokens.")
+	}
+
+	resp := make([]AccessToken, len(tokens))
+	for i, t := range tokens {
+		resp[i] = accessTokenResponse(t)
+	}
+	return RespondSuccess(c, http.StatusOK, resp)
+}
+
+// RevokeAccessToken handles DELETE /api/v1/auth/tokens/:id
+func (s *Server) RevokeAccessToken(c echo.Context) error {
+	id, err := ParseUUID(c, "id")
+	if err != nil {
+		return err
+	}
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	revoked, err := s.queries.RevokePersonalAccessToken(ctx, db.RevokePersonalAccessTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return HandleDatabaseError(c, err, "Access token")
+	}
+
+	s.logAudit(ctx, userID, "revoke", "access_token", revoked.ID.String(),
+		map[string]any{"name": revoked.Name, "scopes": revoked.Scopes}, nil,
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func accessTokenResponse(t db.PersonalAccessToken) AccessToken {
+	resp := AccessToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.TokenPrefix,
+		Scopes:     t.Scopes,
+		LastUsedIP: t.LastUsedIp.String,
+		CreatedAt:  t.CreatedAt,
+	}
+	if t.ExpiresAt.Valid {
+		resp.ExpiresAt = &t.ExpiresAt.Time
+		resp.Expired = time.Now().After(t.ExpiresAt.Time)
+	}
+	if t.LastUsedAt.Valid {
+		resp.LastUsedAt = &t.LastUsedAt.Time
+	}
+	return resp
+}
@@ -2,13 +2,17 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 
 	db "github.com/jamalkaksouri/DigiOrder/internal/db"
 	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/jamalkaksouri/DigiOrder/internal/notify"
+	"github.com/jamalkaksouri/DigiOrder/internal/outbox"
+	"github.com/jamalkaksouri/DigiOrder/internal/pagination"
 	"github.com/jamalkaksouri/DigiOrder/internal/security"
 	"github.com/labstack/echo/v4"
 )
@@ -19,48 +23,76 @@ const (
 )
 
 type CreateUserReq struct {
-	Username string `json:"username" validate:"required,min=3,max=50"`
-	FullName string `json:"full_name,omitempty"`
-	Password string `json:"password" validate:"required,min=12"`
-	RoleID   int32  `json:"role_id" validate:"required,gt=0"`
+	Username     string `json:"username" validate:"required,min=3,max=50"`
+	FullName     string `json:"full_name,omitempty"`
+	Password     string `json:"password" validate:"required,min=12"`
+	RoleID       int32  `json:"role_id" validate:"required,gt=0"`
+	Email        string `json:"email,omitempty" validate:"omitempty,email,max=254"`
+	DepartmentID *int32 `json:"department_id,omitempty" validate:"omitempty,gt=0"`
 }
 
 type UpdateUserReq struct {
 	FullName string `json:"full_name,omitempty"`
 	RoleID   *int32 `json:"role_id,omitempty"`
+	// 0 takes the user out of their department
+	DepartmentID *int32 `json:"department_id,omitempty" validate:"omitempty,gte=0"`
 }
 
-// CreateUser handles POST /api/v1/users (Admin only)
-func (s *Server) CreateUser(c echo.Context) error {
-	// Verify admin role
-	roleName, err := middleware.GetRoleNameFromContext(c)
-	if err != nil {
-		return err // Already formatted error from middleware
+// WeakPasswordResponse is the error for a password that does not meet the
+// requirements, with suggestions for making it stronger
+type WeakPasswordResponse struct {
+	ErrorResponse
+	Suggestions  []string       `json:"suggestions,omitempty"`
+	Requirements map[string]any `json:"requirements,omitempty"`
+}
+
+// respondWeakPassword answers with 422 for the password in field that
+// failed validation with err
+func respondWeakPassword(c echo.Context, field, password string, err error, requirements map[string]any) error {
+	return c.JSON(http.StatusUnprocessableEntity, WeakPasswordResponse{
+		ErrorResponse: ErrorResponse{
+			Error:   "weak_password",
+			Message: localizedMessage(c, "weak_password"),
+			Details: err.Error(),
+			Errors:  []FieldError{{Field: field, Code: "weak_password", Message: err.Error()}},
+		},
+		Suggestions:  security.SuggestPasswordImprovement(password),
+		Requirements: requirements,
+	})
+}
+
+// requireAdminFor answers 403 unless the caller is an administrator when
+// roleID is the admin role, so roles granted the users permissions cannot
+// create, promote, change or delete administrators
+func requireAdminFor(c echo.Context, roleID int32) (bool, error) {
+	if roleID != RoleAdmin {
+		return true, nil
 	}
-	if roleName != "admin" {
-		return RespondError(c, http.StatusForbidden, "insufficient_permissions",
-			"Only administrators can create users.")
+	if roleName, _ := middleware.GetRoleNameFromContext(c); roleName == "admin" {
+		return true, nil
 	}
+	return false, RespondError(c, http.StatusForbidden, "insufficient_permissions",
+		"Only administrators can manage administrator accounts.")
+}
 
+// CreateUser handles POST /api/v1/users (users:create)
+func (s *Server) CreateUser(c echo.Context) error {
 	var req CreateUserReq
 	if err := s.ValidateRequest(c, &req); err != nil {
 		return err // Already formatted by ValidateRequest
 	}
+	if ok, err := requireAdminFor(c, req.RoleID); !ok {
+		return err
+	}
 
 	ctx := c.Request().Context()
 
 	// Validate password strength
 	if err := security.ValidatePassword(req.Password,
 		security.DefaultPasswordRequirements()); err != nil {
-		suggestions := security.SuggestPasswordImprovement(req.Password)
-		return c.JSON(http.StatusBadRequest, map[string]any{
-			"error":       "weak_password",
-			"details":     err.Error(),
-			"suggestions": suggestions,
-			"requirements": map[string]any{
-				"min_length": 12,
-				"requires":   []string{"uppercase", "lowercase", "digit", "special char"},
-			},
+		return respondWeakPassword(c, "password", req.Password, err, map[string]any{
+			"min_length": 12,
+			"requires":   []string{"uppercase", "lowercase", "digit", "special char"},
 		})
 	}
 
@@ -75,21 +107,43 @@ func (s *Server) CreateUser(c echo.Context) error {
 	}
 
 	// Verify role exists
-	role, err := s.queries.GetRole(ctx, req.RoleID)
+	role, err := s.roles.role(ctx, req.RoleID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return RespondError(c, http.StatusBadRequest, "invalid_role",
+		if errors.Is(err, sql.ErrNoRows) {
+			return respondFieldError(c, "invalid_role", "role_id",
 				fmt.Sprintf("Role with ID %d does not exist.", req.RoleID))
 		}
 		return HandleDatabaseError(c, err, "Role")
 	}
+	if req.DepartmentID != nil {
+		if ok, err := s.requireDepartment(c, *req.DepartmentID); !ok {
+			return err
+		}
+	}
 
 	// Create user
-	user, err := s.queries.CreateUser(ctx, db.CreateUserParams{
-		Username:     req.Username,
-		FullName:     sql.NullString{String: req.FullName, Valid: req.FullName != ""},
-		PasswordHash: hashedPassword,
-		RoleID:       sql.NullInt32{Int32: req.RoleID, Valid: true},
+	var user db.User
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		user, err = q.CreateUser(ctx, db.CreateUserParams{
+			Username:     req.Username,
+			FullName:     db.EncryptedString{String: req.FullName, Valid: req.FullName != ""},
+			PasswordHash: hashedPassword,
+			RoleID:       sql.NullInt32{Int32: req.RoleID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if req.DepartmentID != nil {
+			user, err = q.SetUserDepartment(ctx, db.SetUserDepartmentParams{
+				ID:           user.ID,
+				DepartmentID: sql.NullInt32{Int32: *req.DepartmentID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(c, q, outbox.UserCreated, user.ID.String(), outbox.UserPayload(user))
 	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "User")
@@ -98,10 +152,29 @@ func (s *Server) CreateUser(c echo.Context) error {
 	// Log audit
 	currentUserID, _ := middleware.GetUserIDFromContext(c)
 	s.logAudit(ctx, currentUserID, "create", "user", user.ID.String(), nil, map[string]any{
-		"username": user.Username,
-		"role":     role.Name,
+		"username":      user.Username,
+		"role":          role.Name,
+		"department_id": user.DepartmentID.Int32,
 	}, c.RealIP(), c.Request().UserAgent())
 
+	// Store the contact address and send the invitation
+	if req.Email != "" {
+		_, err := s.queries.UpsertNotificationSettings(ctx, db.UpsertNotificationSettingsParams{
+			UserID: user.ID,
+			Email:  sql.NullString{String: req.Email, Valid: true},
+		})
+		if err != nil {
+			s.logger.Error("Failed to store user email", err, map[string]any{"user_id": user.ID})
+		} else {
+			invitedBy, _ := middleware.GetUsernameFromContext(c)
+			s.notifyUser(ctx, notify.EventAccountInvited, user.ID, map[string]any{
+				"Username":  user.Username,
+				"Role":      role.Name,
+				"InvitedBy": invitedBy,
+			})
+		}
+	}
+
 	// Don't return password hash
 	user.PasswordHash = ""
 
@@ -130,7 +203,7 @@ func (s *Server) GetUser(c echo.Context) error {
 	// Get role name
 	var roleName string
 	if user.RoleID.Valid {
-		role, err := s.queries.GetRole(ctx, user.RoleID.Int32)
+		role, err := s.roles.role(ctx, user.RoleID.Int32)
 		if err != nil {
 			// Don't fail the entire request if role fetch fails
 			if s.logger != nil {
@@ -156,49 +229,29 @@ func (s *Server) GetUser(c echo.Context) error {
 	return RespondSuccess(c, http.StatusOK, response)
 }
 
-// ListUsers handles GET /api/v1/users
+// ListUsers handles GET /api/v1/users. With format=xlsx every active user
+// is downloaded as an Excel sheet.
 func (s *Server) ListUsers(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	limitStr := c.QueryParam("limit")
-	offsetStr := c.QueryParam("offset")
-
-	limit := 50
-	offset := 0
-
-	if limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit parameter must be a valid number.")
-		}
-		if parsedLimit <= 0 {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit must be greater than 0.")
-		}
-		if parsedLimit > 100 {
-			return RespondError(c, http.StatusBadRequest, "invalid_limit",
-				"Limit cannot exceed 100.")
-		}
-		limit = parsedLimit
+	page, ok := parsePage(c)
+	if !ok {
+		return nil
+	}
+	sortBy, ok := parseSort(c, &page, userSortColumns)
+	if !ok {
+		return nil
 	}
 
-	if offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			return RespondError(c, http.StatusBadRequest, "invalid_offset",
-				"Offset parameter must be a valid number.")
-		}
-		if parsedOffset < 0 {
-			return RespondError(c, http.StatusBadRequest, "invalid_offset",
-				"Offset cannot be negative.")
-		}
-		offset = parsedOffset
+	if wantsXLSX(c) {
+		return s.exportUsersXLSX(c)
 	}
 
-	users, err := s.queries.ListActiveUsers(ctx, db.ListActiveUsersParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+	users, err := s.queries.ListActiveUsers(withSort(ctx, sortBy, "id"), db.ListActiveUsersParams{
+		AfterTime: page.AfterTime(),
+		AfterID:   page.AfterID(),
+		Limit:     int32(page.Limit),
+		Offset:    int32(page.Offset),
 	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "Users")
@@ -213,7 +266,7 @@ func (s *Server) ListUsers(c echo.Context) error {
 	for i, user := range users {
 		var roleName string
 		if user.RoleID.Valid {
-			role, err := s.queries.GetRole(ctx, user.RoleID.Int32)
+			role, err := s.roles.role(ctx, user.RoleID.Int32)
 			if err == nil {
 				roleName = role.Name
 			}
@@ -229,7 +282,32 @@ func (s *Server) ListUsers(c echo.Context) error {
 		}
 	}
 
-	return RespondSuccess(c, http.StatusOK, result)
+	total := s.listTotal(ctx, "users", nil, s.queries.CountActiveUsers)
+	return respondPage(c, page, total, users, userCursor, result)
+}
+
+// exportUsersXLSX streams the active users with their role names
+func (s *Server) exportUsersXLSX(c echo.Context) error {
+	roles, err := s.queries.ListRoles(c.Request().Context())
+	if err != nil {
+		return HandleDatabaseError(c, err, "Roles")
+	}
+	roleNames := make(map[int32]string, len(roles))
+	for _, r := range roles {
+		roleNames[r.ID] = r.Name
+	}
+
+	header := []string{"ID", "Username", "Full Name", "Role", "Created At"}
+	list := func(ctx context.Context, page pagination.Page) ([]db.User, error) {
+		return s.queries.ListActiveUsers(ctx, db.ListActiveUsersParams{
+			AfterTime: page.AfterTime(),
+			AfterID:   page.AfterID(),
+			Limit:     int32(page.Limit),
+		})
+	}
+	return s.streamXLSX(c, "users", header, keysetSource(list, userCursor, func(_ context.Context, u db.User) []any {
+		return []any{u.ID, u.Username, u.FullName.String, roleNames[u.RoleID.Int32], s.xlsxTime(u.CreatedAt)}
+	}))
 }
 
 // UpdateUser handles PUT /api/v1/users/:id
@@ -241,6 +319,7 @@ func (s *Server) UpdateUser(c echo.Context) error {
 
 	// Check if trying to update primary admin
 	if id.String() == PrimaryAdminID {
+		s.alertProtectedAdmin(c, id, "modify the primary administrator", "the account is protected")
 		return RespondError(c, http.StatusForbidden, "protected_user",
 			"The primary administrator account cannot be modified through this endpoint.")
 	}
@@ -262,29 +341,57 @@ func (s *Server) UpdateUser(c echo.Context) error {
 		return RespondError(c, http.StatusNotFound, "not_found",
 			"User has been deleted and cannot be updated.")
 	}
+	if ok, err := requireAdminFor(c, oldUser.RoleID.Int32); !ok {
+		return err
+	}
 
 	params := db.UpdateUserParams{
 		ID: id,
 	}
 
 	if req.FullName != "" {
-		params.FullName = sql.NullString{String: req.FullName, Valid: true}
+		params.FullName = db.EncryptedString{String: req.FullName, Valid: true}
 	}
 
 	if req.RoleID != nil {
+		if ok, err := requireAdminFor(c, *req.RoleID); !ok {
+			return err
+		}
 		// Verify new role exists
-		_, err := s.queries.GetRole(ctx, *req.RoleID)
+		_, err := s.roles.role(ctx, *req.RoleID)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return RespondError(c, http.StatusBadRequest, "invalid_role",
+			if errors.Is(err, sql.ErrNoRows) {
+				return respondFieldError(c, "invalid_role", "role_id",
 					fmt.Sprintf("Role with ID %d does not exist.", *req.RoleID))
 			}
 			return HandleDatabaseError(c, err, "Role")
 		}
 		params.RoleID = sql.NullInt32{Int32: *req.RoleID, Valid: true}
 	}
+	if req.DepartmentID != nil && *req.DepartmentID != 0 {
+		if ok, err := s.requireDepartment(c, *req.DepartmentID); !ok {
+			return err
+		}
+	}
 
-	user, err := s.queries.UpdateUser(ctx, params)
+	var user db.User
+	err = s.withTx(ctx, func(q db.Querier) error {
+		var err error
+		user, err = q.UpdateUser(ctx, params)
+		if err != nil {
+			return err
+		}
+		if req.DepartmentID != nil {
+			user, err = q.SetUserDepartment(ctx, db.SetUserDepartmentParams{
+				ID:           user.ID,
+				DepartmentID: sql.NullInt32{Int32: *req.DepartmentID, Valid: *req.DepartmentID != 0},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.recordEvent(c, q, outbox.UserUpdated, user.ID.String(), outbox.UserPayload(user))
+	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "User")
 	}
@@ -293,12 +400,14 @@ func (s *Server) UpdateUser(c echo.Context) error {
 	currentUserID, _ := middleware.GetUserIDFromContext(c)
 	s.logAudit(ctx, currentUserID, "update", "user", user.ID.String(),
 		map[string]any{
-			"full_name": oldUser.FullName.String,
-			"role_id":   oldUser.RoleID.Int32,
+			"full_name":     oldUser.FullName.String,
+			"role_id":       oldUser.RoleID.Int32,
+			"department_id": oldUser.DepartmentID.Int32,
 		},
 		map[string]any{
-			"full_name": user.FullName.String,
-			"role_id":   user.RoleID.Int32,
+			"full_name":     user.FullName.String,
+			"role_id":       user.RoleID.Int32,
+			"department_id": user.DepartmentID.Int32,
 		},
 		c.RealIP(), c.Request().UserAgent())
 
@@ -315,6 +424,7 @@ func (s *Server) DeleteUser(c echo.Context) error {
 
 	// CRITICAL: Protect primary admin
 	if id.String() == PrimaryAdminID {
+		s.alertProtectedAdmin(c, id, "delete the primary administrator", "the account is protected")
 		return RespondError(c, http.StatusForbidden, "protected_user",
 			"The primary administrator account cannot be deleted. This account is essential for system administration.")
 	}
@@ -331,6 +441,9 @@ func (s *Server) DeleteUser(c echo.Context) error {
 		return RespondError(c, http.StatusNotFound, "not_found",
 			"User has already been deleted.")
 	}
+	if ok, err := requireAdminFor(c, user.RoleID.Int32); !ok {
+		return err
+	}
 
 	// Check if user is the last admin
 	if user.RoleID.Int32 == RoleAdmin {
@@ -343,13 +456,19 @@ func (s *Server) DeleteUser(c echo.Context) error {
 				"Failed to verify admin count. Please try again.")
 		}
 		if admins <= 1 {
+			s.alertProtectedAdmin(c, id, "delete the last administrator", "at least one admin must exist")
 			return RespondError(c, http.StatusForbidden, "last_admin",
 				"Cannot delete the last administrator. At least one admin must exist in the system.")
 		}
 	}
 
 	// Soft delete
-	err = s.queries.SoftDeleteUser(ctx, id)
+	err = s.withTx(ctx, func(q db.Querier) error {
+		if err := q.SoftDeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return s.recordEvent(c, q, outbox.UserDeleted, id.String(), outbox.DeletedData{ID: id.String()})
+	})
 	if err != nil {
 		return HandleDatabaseError(c, err, "User")
 	}
@@ -0,0 +1,70 @@
+// internal/storage/storage.go - Object storage for uploaded and generated files
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for keys that do not exist
+var ErrNotFound = errors.New("object not found")
+
+// Key prefixes of the files kept in storage
+const (
+	PrefixProductImages    = "products"
+	PrefixOrderAttachments = "orders"
+	PrefixExports          = "exports"
+	PrefixBackups          = "backups"
+)
+
+// Info describes a stored object
+type Info struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ModifiedAt  time.Time
+}
+
+// Store keeps files by key. Keys are slash-separated paths such as
+// products/<id>/<uuid>.png; callers never reuse a key for new content, so
+// URLs handed out for a key stay valid until it is deleted.
+type Store interface {
+	// Put stores size bytes from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete removes the object; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// PresignGet returns a URL that downloads the object without a token
+	// until expiry. filename, when set, is sent as the attachment name.
+	PresignGet(ctx context.Context, key string, expiry time.Duration, filename string) (string, error)
+	// Name identifies the backend (local or s3)
+	Name() string
+}
+
+// ValidKey rejects keys that could escape the storage root or confuse
+// object stores
+func ValidKey(key string) error {
+	switch {
+	case key == "", strings.HasPrefix(key, "/"), strings.HasSuffix(key, "/"):
+		return fmt.Errorf("invalid storage key %q", key)
+	case path.Clean(key) != key, strings.Contains(key, "\\"):
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return nil
+}
+
+// Join builds a key from its parts
+func Join(parts ...string) string {
+	return path.Join(parts...)
+}
@@ -0,0 +1,268 @@
+// internal/usage/usage.go - Per-consumer API usage counters
+package usage
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jamalkaksouri/DigiOrder/internal/db"
+	"github.com/jamalkaksouri/DigiOrder/internal/logging"
+	"github.com/jamalkaksouri/DigiOrder/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// How often usage older than the retention is deleted
+const pruneInterval = 24 * time.Hour
+
+// Config controls how often counters are written and how long they are
+// kept. Days are counted in Location.
+type Config struct {
+	FlushInterval time.Duration // 0 disables tracking
+	Retention     time.Duration
+	Location      *time.Location
+}
+
+// key is one row of api_usage_daily; tokenID is uuid.Nil for requests
+// signed in with a password
+type key struct {
+	day     string
+	userID  uuid.UUID
+	tokenID uuid.UUID
+	method  string
+	route   string
+}
+
+type counts struct {
+	requests     int64
+	clientErrors int64
+	serverErrors int64
+	totalMs      float64
+	maxMs        float64
+}
+
+// Tracker counts authenticated requests per day, user, access token and
+// route in memory and adds them to api_usage_daily every flush interval,
+// so the request path never waits on the database. Counters of several
+// instances add up in the same rows.
+type Tracker struct {
+	queries   db.Querier
+	config    Config
+	logger    *logging.Logger
+	heartbeat *middleware.Heartbeat
+
+	mu      sync.Mutex
+	pending map[key]*counts
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTracker creates a tracker. Call Start to begin writing counters.
+func NewTracker(queries db.Querier, config Config, logger *logging.Logger) *Tracker {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Tracker{
+		queries: queries,
+		config:  config,
+		logger:  logger,
+		pending: make(map[key]*counts),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if config.FlushInterval > 0 {
+		t.heartbeat = middleware.NewHeartbeat("api_usage", config.FlushInterval)
+	}
+	return t
+}
+
+// Enabled reports whether requests are being counted
+func (t *Tracker) Enabled() bool {
+	return t.heartbeat != nil
+}
+
+// Location returns the time zone days are counted in
+func (t *Tracker) Location() *time.Location {
+	return t.config.Location
+}
+
+// Middleware counts every request that authenticated, by its route
+// template. The error response is committed first so the status counted
+// is the one the client receives.
+func (t *Tracker) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !t.Enabled() {
+			return next
+		}
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			userID, err := middleware.GetUserIDFromContext(c)
+			if err != nil || c.Path() == "" {
+				return nil
+			}
+			tokenID, _ := c.Get("token_id").(uuid.UUID)
+			t.Record(start, userID, tokenID, c.Request().Method, c.Path(),
+				c.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
+
+// Record counts one request made at start
+func (t *Tracker) Record(start time.Time, userID, tokenID uuid.UUID, method, route string, status int, took time.Duration) {
+	k := key{
+		day:     start.In(t.config.Location).Format(time.DateOnly),
+		userID:  userID,
+		tokenID: tokenID,
+		method:  method,
+		route:   route,
+	}
+	ms := float64(took.Microseconds()) / 1000
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.pending[k]
+	if n == nil {
+		n = &counts{}
+		t.pending[k] = n
+	}
+	n.requests++
+	switch {
+	case status >= http.StatusInternalServerError:
+		n.serverErrors++
+	case status >= http.StatusBadRequest:
+		n.clientErrors++
+	}
+	n.totalMs += ms
+	n.maxMs = max(n.maxMs, ms)
+}
+
+// Start launches the loop that writes counters
+func (t *Tracker) Start() {
+	if t.heartbeat == nil {
+		return
+	}
+	t.wg.Add(1)
+	go t.loop()
+}
+
+// Stop ends the loop, writing the counters still in memory unless ctx
+// expires first
+func (t *Tracker) Stop(ctx context.Context) error {
+	t.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Heartbeat reports whether the loop is running, or nil when disabled
+func (t *Tracker) Heartbeat() *middleware.Heartbeat {
+	return t.heartbeat
+}
+
+func (t *Tracker) loop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-t.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.flush(ctx)
+			cancel()
+			return
+		case <-ticker.C:
+		}
+
+		t.flush(t.ctx)
+		if t.config.Retention > 0 && time.Since(pruned) >= pruneInterval {
+			t.prune(t.ctx)
+			pruned = time.Now()
+		}
+		t.heartbeat.Beat()
+	}
+}
+
+// flush adds the counters in memory to the database. Rows that fail are
+// put back to be retried on the next flush.
+func (t *Tracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = make(map[key]*counts)
+	t.mu.Unlock()
+
+	failed := 0
+	var lastErr error
+	for k, n := range batch {
+		day, _ := time.Parse(time.DateOnly, k.day)
+		err := t.queries.AddAPIUsage(ctx, db.AddAPIUsageParams{
+			Day:          day,
+			UserID:       k.userID,
+			TokenID:      uuid.NullUUID{UUID: k.tokenID, Valid: k.tokenID != uuid.Nil},
+			Method:       k.method,
+			Route:        k.route,
+			Requests:     n.requests,
+			ClientErrors: n.clientErrors,
+			ServerErrors: n.serverErrors,
+			TotalMs:      n.totalMs,
+			MaxMs:        n.maxMs,
+		})
+		if err != nil {
+			failed++
+			lastErr = err
+			// The user or token was deleted meanwhile; retrying cannot help
+			if pqErr, ok := db.AsPgError(err); !ok || pqErr.Code != "23503" {
+				t.restore(k, n)
+			}
+		}
+	}
+	if failed > 0 {
+		t.logger.Error("Failed to write API usage", lastErr, map[string]any{"rows": failed})
+	}
+}
+
+// restore merges counters that could not be written back into memory
+func (t *Tracker) restore(k key, n *counts) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cur := t.pending[k]
+	if cur == nil {
+		t.pending[k] = n
+		return
+	}
+	cur.requests += n.requests
+	cur.clientErrors += n.clientErrors
+	cur.serverErrors += n.serverErrors
+	cur.totalMs += n.totalMs
+	cur.maxMs = max(cur.maxMs, n.maxMs)
+}
+
+func (t *Tracker) prune(ctx context.Context) {
+	y, m, d := time.Now().In(t.config.Location).Add(-t.config.Retention).Date()
+	before := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if _, err := t.queries.DeleteAPIUsageBefore(ctx, before); err != nil {
+		t.logger.Error("Failed to prune API usage", err, nil)
+	}
+}
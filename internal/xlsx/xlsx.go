@@ -0,0 +1,282 @@
+// internal/xlsx/xlsx.go - Streaming Excel (.xlsx) writer
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of an .xlsx workbook
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// MaxRows is the row limit of an Excel worksheet, header included
+const MaxRows = 1048576
+
+// ErrTooManyRows is returned by WriteRow once the sheet is full
+var ErrTooManyRows = errors.New("the sheet is full")
+
+// Cell styles in styles.xml
+const (
+	styleDefault = 0
+	styleDate    = 1
+	styleHeader  = 2
+)
+
+// excelEpoch is day 0 of Excel's 1900 date system, adjusted for its
+// 1900 leap year bug so serials from March 1900 on are right
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Writer streams a workbook with a single worksheet. Rows go straight to
+// the underlying writer as they are written, so memory use does not grow
+// with the sheet; strings are written inline rather than to a shared table
+// for the same reason.
+type Writer struct {
+	zw   *zip.Writer
+	bw   *bufio.Writer
+	rows int
+	err  error
+}
+
+// NewWriter starts a workbook on w with a sheet named sheet whose first,
+// frozen and bold, row is header
+func NewWriter(w io.Writer, sheet string, header []string) (*Writer, error) {
+	x := &Writer{zw: zip.NewWriter(w)}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetName(sheet)))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+	}
+	for _, p := range parts {
+		f, err := x.zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, xml.Header+p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := x.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x.bw = bufio.NewWriter(f)
+	x.bw.WriteString(xml.Header)
+	x.bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	x.bw.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(header) > 0 {
+		x.bw.WriteString("<cols>")
+		for i, h := range header {
+			width := max(len(h)+4, 14)
+			fmt.Fprintf(x.bw, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+		}
+		x.bw.WriteString("</cols>")
+	}
+	x.bw.WriteString("<sheetData>")
+
+	cells := make([]any, len(header))
+	for i, h := range header {
+		cells[i] = h
+	}
+	if err := x.writeRow(cells, styleHeader); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// WriteRow appends a row. Numbers and bools are written as such, times as
+// dates in their own location, nil and invalid values as empty cells and
+// anything else as text, through fmt.Stringer when it has one.
+func (x *Writer) WriteRow(cells ...any) error {
+	return x.writeRow(cells, styleDefault)
+}
+
+func (x *Writer) writeRow(cells []any, style int) error {
+	if x.err != nil {
+		return x.err
+	}
+	if x.rows == MaxRows {
+		return ErrTooManyRows
+	}
+	x.rows++
+	fmt.Fprintf(x.bw, `<row r="%d">`, x.rows)
+	for i, v := range cells {
+		x.writeCell(cellRef(i, x.rows), v, style)
+	}
+	x.bw.WriteString("</row>")
+
+	// bufio keeps the first write error; report it from here on
+	if _, err := x.bw.Write(nil); err != nil {
+		x.err = err
+	}
+	return x.err
+}
+
+func (x *Writer) writeCell(ref string, v any, style int) {
+	attrs := `r="` + ref + `"`
+	if style != styleDefault {
+		attrs += ` s="` + strconv.Itoa(style) + `"`
+	}
+	number := func(s string) {
+		x.bw.WriteString("<c " + attrs + "><v>" + s + "</v></c>")
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return
+	case string:
+		x.text(attrs, v)
+	case int:
+		number(strconv.Itoa(v))
+	case int32:
+		number(strconv.FormatInt(int64(v), 10))
+	case int64:
+		number(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return
+		}
+		number(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		b := "0"
+		if v {
+			b = "1"
+		}
+		x.bw.WriteString("<c " + attrs + ` t="b"><v>` + b + "</v></c>")
+	case time.Time:
+		if v.IsZero() {
+			return
+		}
+		if style == styleDefault {
+			attrs += ` s="` + strconv.Itoa(styleDate) + `"`
+		}
+		number(strconv.FormatFloat(serial(v), 'f', -1, 64))
+	case *time.Time:
+		if v != nil {
+			x.writeCell(ref, *v, style)
+		}
+	case fmt.Stringer:
+		x.text(attrs, v.String())
+	default:
+		x.text(attrs, fmt.Sprint(v))
+	}
+}
+
+func (x *Writer) text(attrs, s string) {
+	if s == "" {
+		return
+	}
+	space := ""
+	if strings.TrimSpace(s) != s {
+		space = ` xml:space="preserve"`
+	}
+	x.bw.WriteString("<c " + attrs + ` t="inlineStr"><is><t` + space + ">" + escape(s) + "</t></is></c>")
+}
+
+// Close ends the sheet and the workbook. It does not close the underlying
+// writer.
+func (x *Writer) Close() error {
+	if x.err != nil {
+		return x.err
+	}
+	x.bw.WriteString("</sheetData></worksheet>")
+	if err := x.bw.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// serial is t's wall clock as an Excel date: days since the epoch with
+// the time of day as the fraction
+func serial(t time.Time) float64 {
+	y, m, d := t.Date()
+	wall := time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return wall.Sub(excelEpoch).Seconds() / 86400
+}
+
+// cellRef turns a zero-based column and a row into a reference like "AB12"
+func cellRef(col, row int) string {
+	var name []byte
+	for col >= 0 {
+		name = append([]byte{byte('A' + col%26)}, name...)
+		col = col/26 - 1
+	}
+	return string(name) + strconv.Itoa(row)
+}
+
+// sheetName drops the characters Excel does not allow in sheet names and
+// keeps the first 31
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+// escape makes s safe as XML text, dropping characters XML cannot hold
+func escape(s string) string {
+	var b strings.Builder
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF {
+			return r
+		}
+		return -1
+	}, s)
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const contentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const workbookRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// styles holds the default style, a date-time format and a bold header
+const styles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,25 @@
+-- ============================================================================
+-- NOTIFICATIONS
+-- ============================================================================
+
+-- Contact details used for notifications, kept out of users so the
+-- authentication queries are unaffected
+CREATE TABLE IF NOT EXISTS user_notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Per-user opt-outs. A missing row means the notification is enabled.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, event_type, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_event
+    ON notification_preferences(event_type, channel) WHERE NOT enabled;
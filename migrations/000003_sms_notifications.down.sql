@@ -0,0 +1,3 @@
+ALTER TABLE user_notification_settings DROP COLUMN IF EXISTS phone;
+DROP INDEX IF EXISTS idx_orders_priority;
+ALTER TABLE orders DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,14 @@
+-- ============================================================================
+-- SMS NOTIFICATIONS
+-- ============================================================================
+
+-- Order urgency. Status changes on 'stat' orders page on-call staff by SMS.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'routine'
+    CHECK (priority IN ('routine', 'urgent', 'stat'));
+
+CREATE INDEX IF NOT EXISTS idx_orders_priority ON orders(priority) WHERE priority <> 'routine';
+
+-- Mobile number for SMS, in international format (e.g. +989121234567)
+ALTER TABLE user_notification_settings
+    ADD COLUMN IF NOT EXISTS phone TEXT;
@@ -0,0 +1,28 @@
+-- ============================================================================
+-- TRANSACTIONAL OUTBOX
+-- ============================================================================
+
+-- Domain events written in the same transaction as the change they describe.
+-- The relay publishes pending rows and marks them, so an event is never lost
+-- once the change commits (delivery is at-least-once).
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending
+    ON outbox_events(next_attempt_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published
+    ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate
+    ON outbox_events(aggregate_type, aggregate_id, created_at);
+
+COMMENT ON TABLE outbox_events IS 'Domain events awaiting (or recently completed) delivery to webhooks and brokers.';
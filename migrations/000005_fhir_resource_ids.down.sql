@@ -0,0 +1 @@
+DROP TABLE IF EXISTS fhir_resource_ids;
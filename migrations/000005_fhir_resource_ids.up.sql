@@ -0,0 +1,17 @@
+-- ============================================================================
+-- FHIR ID MAPPING
+-- ============================================================================
+
+-- Stable FHIR logical IDs for exported resources. The mapping is kept so a
+-- FHIR reference coming back from the hospital system (e.g. Medication/<id>)
+-- resolves to the same local row, and IDs never change between exports.
+CREATE TABLE IF NOT EXISTS fhir_resource_ids (
+    resource_type TEXT NOT NULL,
+    local_id TEXT NOT NULL,
+    fhir_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (resource_type, local_id),
+    UNIQUE (resource_type, fhir_id)
+);
+
+COMMENT ON TABLE fhir_resource_ids IS 'FHIR logical IDs assigned to exported products (Medication), order items (MedicationRequest) and orders (Bundle).';
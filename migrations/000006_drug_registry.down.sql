@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS drug_registry_sync_items;
+DROP TABLE IF EXISTS drug_registry_syncs;
+
+DROP INDEX IF EXISTS idx_products_status;
+DROP INDEX IF EXISTS idx_products_lower_name;
+DROP INDEX IF EXISTS idx_products_irc;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS generic_code,
+    DROP COLUMN IF EXISTS irc,
+    DROP COLUMN IF EXISTS status;
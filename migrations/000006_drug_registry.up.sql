@@ -0,0 +1,57 @@
+-- ============================================================================
+-- NATIONAL DRUG REGISTRY SYNC
+-- ============================================================================
+
+-- Products created from the registry start in staging until a pharmacist
+-- reviews them; staging products cannot be ordered.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'staging')),
+    ADD COLUMN IF NOT EXISTS irc TEXT,
+    ADD COLUMN IF NOT EXISTS generic_code TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_irc ON products(irc) WHERE irc IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_products_lower_name ON products(lower(name));
+CREATE INDEX IF NOT EXISTS idx_products_status ON products(status);
+
+COMMENT ON COLUMN products.irc IS 'Iran Code (IRC) of the product in the national drug registry.';
+COMMENT ON COLUMN products.generic_code IS 'Generic code of the product in the national drug registry.';
+
+-- One row per import run
+CREATE TABLE IF NOT EXISTS drug_registry_syncs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'completed', 'failed')),
+    triggered_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    total_entries INTEGER NOT NULL DEFAULT 0,
+    matched INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    ambiguous INTEGER NOT NULL DEFAULT 0,
+    conflicts INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_drug_registry_syncs_started ON drug_registry_syncs(started_at DESC);
+
+-- The reconciliation report: what happened to each registry entry
+CREATE TABLE IF NOT EXISTS drug_registry_sync_items (
+    id BIGSERIAL PRIMARY KEY,
+    sync_id UUID NOT NULL REFERENCES drug_registry_syncs(id) ON DELETE CASCADE,
+    irc TEXT NOT NULL,
+    generic_code TEXT,
+    name TEXT NOT NULL,
+    gtin TEXT,
+    outcome TEXT NOT NULL
+        CHECK (outcome IN ('matched_irc', 'matched_barcode', 'matched_name', 'created', 'ambiguous', 'conflict', 'failed')),
+    product_id UUID REFERENCES products(id) ON DELETE SET NULL,
+    detail TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_drug_registry_sync_items_outcome ON drug_registry_sync_items(sync_id, outcome);
+
+COMMENT ON TABLE drug_registry_syncs IS 'National drug registry import runs and their outcome counts.';
+COMMENT ON TABLE drug_registry_sync_items IS 'Per-entry reconciliation of a registry import against the product catalog.';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS export_files;
+DROP TABLE IF EXISTS order_attachments;
+DROP TABLE IF EXISTS product_images;
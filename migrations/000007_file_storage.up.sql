@@ -0,0 +1,48 @@
+-- ============================================================================
+-- FILE STORAGE
+-- ============================================================================
+
+-- File contents live in the configured object store (local disk or S3);
+-- these tables hold the keys and metadata.
+
+-- One image per product
+CREATE TABLE IF NOT EXISTS product_images (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    uploaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Documents attached to an order, e.g. a signed requisition
+CREATE TABLE IF NOT EXISTS order_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_attachments_order ON order_attachments(order_id, created_at);
+
+-- Generated exports, downloaded through presigned URLs
+CREATE TABLE IF NOT EXISTS export_files (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_files_created ON export_files(created_at DESC);
+
+COMMENT ON TABLE product_images IS 'Storage key of each product''s image.';
+COMMENT ON TABLE order_attachments IS 'Files attached to orders; contents are in object storage.';
+COMMENT ON TABLE export_files IS 'Generated export files kept in object storage.';
@@ -0,0 +1,40 @@
+-- ============================================================================
+-- SCHEDULED REPORTS
+-- ============================================================================
+
+-- Stock on hand per product, the input of the low-stock report. Products
+-- without a row are not tracked.
+CREATE TABLE IF NOT EXISTS product_stock (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    on_hand INTEGER NOT NULL DEFAULT 0 CHECK (on_hand >= 0),
+    reorder_level INTEGER NOT NULL DEFAULT 0 CHECK (reorder_level >= 0),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_stock_low
+    ON product_stock(product_id) WHERE on_hand <= reorder_level;
+
+-- One row per report a user receives by email
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    report TEXT NOT NULL
+        CHECK (report IN ('order_summary', 'low_stock', 'audit_digest')),
+    recipient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format TEXT NOT NULL DEFAULT 'csv' CHECK (format IN ('csv', 'pdf')),
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    send_hour INTEGER NOT NULL DEFAULT 7 CHECK (send_hour BETWEEN 0 AND 23),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_status TEXT CHECK (last_status IN ('sent', 'failed', 'skipped')),
+    last_error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (report, recipient_id, format)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_due
+    ON report_schedules(next_run_at) WHERE enabled;
+
+COMMENT ON TABLE report_schedules IS 'Reports emailed to users on a schedule (see internal/reports).';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS erp_batch_orders;
+DROP TABLE IF EXISTS erp_batches;
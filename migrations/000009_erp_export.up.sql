@@ -0,0 +1,31 @@
+-- ============================================================================
+-- ERP EXPORT
+-- ============================================================================
+
+-- Each handover of fulfilled orders to the accounting/ERP system. Pulled
+-- batches stay pending until the ERP acknowledges them; pushed batches are
+-- delivered or failed once the ERP endpoint has answered.
+CREATE TABLE IF NOT EXISTS erp_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    mode TEXT NOT NULL CHECK (mode IN ('push', 'pull')),
+    format TEXT NOT NULL CHECK (format IN ('csv', 'json', 'xml')),
+    order_count INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_erp_batches_created ON erp_batches(created_at DESC);
+
+-- Orders in each batch; an order is exported once a delivered batch holds it
+CREATE TABLE IF NOT EXISTS erp_batch_orders (
+    batch_id UUID NOT NULL REFERENCES erp_batches(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    PRIMARY KEY (batch_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_erp_batch_orders_order ON erp_batch_orders(order_id);
+
+COMMENT ON TABLE erp_batches IS 'Fulfilled orders handed to the accounting/ERP system (see internal/erp).';
@@ -0,0 +1,29 @@
+-- ============================================================================
+-- PERSONAL ACCESS TOKENS
+-- ============================================================================
+
+-- Long-lived tokens users create for scripts and BI tools. Only a SHA-256
+-- hash of the token is kept; the token itself is shown once at creation.
+-- A token acts as its user, limited to its scopes, until it expires or is
+-- revoked.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens(user_id, created_at DESC);
+
+-- Names identify a user's active tokens
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_access_tokens_name
+    ON personal_access_tokens(user_id, name) WHERE revoked_at IS NULL;
+
+COMMENT ON TABLE personal_access_tokens IS 'User-generated API tokens with scopes (see internal/middleware/tokens.go).';
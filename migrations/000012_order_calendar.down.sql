@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS calendar_feeds;
+DROP TABLE IF EXISTS recurring_orders;
+DROP INDEX IF EXISTS idx_orders_needed_by;
+ALTER TABLE orders DROP COLUMN IF EXISTS needed_by;
@@ -0,0 +1,41 @@
+-- ============================================================================
+-- ORDER CALENDAR
+-- ============================================================================
+
+-- Date an order must be delivered by
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS needed_by DATE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_needed_by
+    ON orders(needed_by) WHERE needed_by IS NOT NULL AND deleted_at IS NULL;
+
+-- Orders placed again on a schedule. Each run copies the items of the
+-- template order into a new draft order needed lead_days later.
+CREATE TABLE IF NOT EXISTS recurring_orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    template_order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    lead_days INTEGER NOT NULL DEFAULT 0 CHECK (lead_days BETWEEN 0 AND 90),
+    priority TEXT NOT NULL DEFAULT 'routine' CHECK (priority IN ('routine', 'urgent', 'stat')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    last_error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_orders_due
+    ON recurring_orders(next_run_at) WHERE enabled;
+
+-- Private iCalendar feed of each user. Only a hash of the token in the
+-- feed URL is kept; creating a new one invalidates the old URL.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_fetched_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE recurring_orders IS 'Orders placed again on a schedule (see internal/recurring).';
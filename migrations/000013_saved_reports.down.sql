@@ -0,0 +1,10 @@
+DELETE FROM report_schedules WHERE report = 'saved';
+DROP INDEX IF EXISTS idx_report_schedules_unique;
+ALTER TABLE report_schedules ADD CONSTRAINT report_schedules_report_recipient_id_format_key
+    UNIQUE (report, recipient_id, format);
+ALTER TABLE report_schedules DROP CONSTRAINT IF EXISTS report_schedules_saved_report_check;
+ALTER TABLE report_schedules DROP CONSTRAINT IF EXISTS report_schedules_report_check;
+ALTER TABLE report_schedules ADD CONSTRAINT report_schedules_report_check
+    CHECK (report IN ('order_summary', 'low_stock', 'audit_digest'));
+ALTER TABLE report_schedules DROP COLUMN IF EXISTS saved_report_id;
+DROP TABLE IF EXISTS saved_reports;
@@ -0,0 +1,40 @@
+-- ============================================================================
+-- SAVED REPORTS
+-- ============================================================================
+
+-- A named query over one entity: which rows (filters), which columns and
+-- in what order. Fields are checked against the catalog in
+-- internal/reports/saved.go, never spliced into SQL as given.
+CREATE TABLE IF NOT EXISTS saved_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    entity TEXT NOT NULL,
+    filters JSONB NOT NULL DEFAULT '[]',
+    columns TEXT[] NOT NULL,
+    sort JSONB NOT NULL DEFAULT '[]',
+    row_limit INTEGER NOT NULL DEFAULT 1000 CHECK (row_limit BETWEEN 1 AND 10000),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE saved_reports IS 'Saved filter sets run on demand or emailed by report_schedules (see internal/reports).';
+
+-- A schedule either sends a built-in report or a saved one
+ALTER TABLE report_schedules
+    ADD COLUMN IF NOT EXISTS saved_report_id UUID REFERENCES saved_reports(id) ON DELETE CASCADE;
+
+ALTER TABLE report_schedules DROP CONSTRAINT IF EXISTS report_schedules_report_check;
+ALTER TABLE report_schedules ADD CONSTRAINT report_schedules_report_check
+    CHECK (report IN ('order_summary', 'low_stock', 'audit_digest', 'saved'));
+ALTER TABLE report_schedules ADD CONSTRAINT report_schedules_saved_report_check
+    CHECK ((report = 'saved') = (saved_report_id IS NOT NULL));
+
+-- One schedule per report, recipient and format, counting each saved
+-- report as a report of its own
+ALTER TABLE report_schedules DROP CONSTRAINT IF EXISTS report_schedules_report_recipient_id_format_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_report_schedules_unique ON report_schedules (
+    report, recipient_id, format,
+    COALESCE(saved_report_id, '00000000-0000-0000-0000-000000000000'::uuid)
+);
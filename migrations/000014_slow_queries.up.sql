@@ -0,0 +1,25 @@
+-- ============================================================================
+-- SLOW QUERIES
+-- ============================================================================
+
+-- Statements that took longer than database.slow_query_threshold. Parameter
+-- values are never stored, only their types (e.g. "uuid", "text(12)").
+CREATE TABLE IF NOT EXISTS slow_queries (
+    id BIGSERIAL PRIMARY KEY,
+    query_name TEXT NOT NULL DEFAULT '',
+    operation TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    params TEXT[] NOT NULL DEFAULT '{}',
+    duration_ms DOUBLE PRECISION NOT NULL,
+    error TEXT,
+    request_id TEXT,
+    route TEXT,
+    user_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_slow_queries_created_at ON slow_queries(created_at);
+CREATE INDEX IF NOT EXISTS idx_slow_queries_name ON slow_queries(query_name, created_at);
+
+COMMENT ON TABLE slow_queries IS 'Statements slower than the configured threshold, with parameters redacted (see internal/db/query_timing.go).';
@@ -0,0 +1,28 @@
+-- ============================================================================
+-- API USAGE
+-- ============================================================================
+
+-- Requests per day, consumer and route. A consumer is a user, signed in
+-- with a password (token_id NULL) or through one of their personal access
+-- tokens. Latency is kept as a sum and a maximum so days can be combined.
+CREATE TABLE IF NOT EXISTS api_usage_daily (
+    day DATE NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_id UUID REFERENCES personal_access_tokens(id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    route TEXT NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    client_errors BIGINT NOT NULL DEFAULT 0,
+    server_errors BIGINT NOT NULL DEFAULT 0,
+    total_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_ms DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_usage_daily_key ON api_usage_daily (
+    day, user_id,
+    COALESCE(token_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    method, route
+);
+CREATE INDEX IF NOT EXISTS idx_api_usage_daily_route ON api_usage_daily(route, day);
+
+COMMENT ON TABLE api_usage_daily IS 'Daily request counts and latency per user, access token and route (see internal/usage).';
@@ -0,0 +1,39 @@
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+ALTER TABLE audit_logs NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON recurring_orders;
+ALTER TABLE recurring_orders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE recurring_orders DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON order_attachments;
+ALTER TABLE order_attachments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE order_attachments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON order_assignments;
+ALTER TABLE order_assignments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE order_assignments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON order_items;
+ALTER TABLE order_items NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE order_items DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON products;
+ALTER TABLE products NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE products DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON orders;
+ALTER TABLE orders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE orders DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE products DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP FUNCTION IF EXISTS digiorder_product_tenant();
+DROP FUNCTION IF EXISTS digiorder_tenant();
+DROP TABLE IF EXISTS tenants;
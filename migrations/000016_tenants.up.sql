@@ -0,0 +1,107 @@
+-- ============================================================================
+-- TENANTS
+-- ============================================================================
+
+-- Pharmacies (branches) served by one deployment. Users and orders belong
+-- to a tenant; products belong to one or, with tenant_id NULL, to the
+-- shared catalog. Everything that existed before goes to the main tenant.
+CREATE TABLE IF NOT EXISTS tenants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE tenants IS 'Pharmacies sharing this deployment; rows are scoped by row level security (see internal/db/tenant.go).';
+
+INSERT INTO tenants (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'main', 'Main pharmacy')
+ON CONFLICT (id) DO NOTHING;
+
+-- The tenant the session acts for, or NULL for system work: background
+-- jobs, logins and deployments with tenancy disabled
+CREATE OR REPLACE FUNCTION digiorder_tenant() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('digiorder.tenant_id', true), '')::uuid
+$$;
+
+-- The tenant new products belong to; NULL puts them in the shared catalog
+CREATE OR REPLACE FUNCTION digiorder_product_tenant() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('digiorder.product_tenant_id', true), '')::uuid
+$$;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL
+    DEFAULT COALESCE(digiorder_tenant(), '00000000-0000-0000-0000-000000000001')
+    REFERENCES tenants(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL
+    DEFAULT COALESCE(digiorder_tenant(), '00000000-0000-0000-0000-000000000001')
+    REFERENCES tenants(id);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tenant_id UUID
+    DEFAULT digiorder_product_tenant()
+    REFERENCES tenants(id);
+
+CREATE INDEX IF NOT EXISTS idx_users_tenant ON users(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_orders_tenant ON orders(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_products_tenant ON products(tenant_id);
+
+-- Row level security applies to the table owner too, so the application
+-- cannot forget a filter. Superusers and BYPASSRLS roles are exempt; the
+-- self-test fails when tenancy is enabled for such a user.
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON users;
+CREATE POLICY tenant_isolation ON users
+    USING (digiorder_tenant() IS NULL OR tenant_id = digiorder_tenant());
+
+ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON orders;
+CREATE POLICY tenant_isolation ON orders
+    USING (digiorder_tenant() IS NULL OR tenant_id = digiorder_tenant());
+
+ALTER TABLE products ENABLE ROW LEVEL SECURITY;
+ALTER TABLE products FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON products;
+CREATE POLICY tenant_isolation ON products
+    USING (digiorder_tenant() IS NULL OR tenant_id IS NULL OR tenant_id = digiorder_tenant());
+
+-- Items follow their order, whose own policy applies inside the check
+ALTER TABLE order_items ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_items FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON order_items;
+CREATE POLICY tenant_isolation ON order_items
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_items.order_id));
+
+-- Data hanging off an order follows it the same way
+ALTER TABLE order_assignments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_assignments FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON order_assignments;
+CREATE POLICY tenant_isolation ON order_assignments
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_assignments.order_id));
+
+ALTER TABLE order_attachments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_attachments FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON order_attachments;
+CREATE POLICY tenant_isolation ON order_attachments
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_attachments.order_id));
+
+ALTER TABLE recurring_orders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE recurring_orders FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON recurring_orders;
+CREATE POLICY tenant_isolation ON recurring_orders
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = recurring_orders.template_order_id));
+
+-- Audit entries follow the user who made the change; system entries are
+-- shown to the main tenant only
+ALTER TABLE audit_logs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+CREATE POLICY tenant_isolation ON audit_logs
+    USING (digiorder_tenant() IS NULL
+        OR (audit_logs.user_id IS NULL AND digiorder_tenant() = '00000000-0000-0000-0000-000000000001')
+        OR EXISTS (SELECT 1 FROM users u WHERE u.id = audit_logs.user_id));
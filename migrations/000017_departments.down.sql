@@ -0,0 +1,7 @@
+DROP POLICY IF EXISTS department_isolation ON orders;
+DROP FUNCTION IF EXISTS digiorder_department();
+
+ALTER TABLE orders DROP COLUMN IF EXISTS department_id;
+ALTER TABLE users DROP COLUMN IF EXISTS department_id;
+
+DROP TABLE IF EXISTS departments;
@@ -0,0 +1,44 @@
+-- ============================================================================
+-- DEPARTMENTS
+-- ============================================================================
+
+-- Departments (wards, branches) within a pharmacy. A user may belong to
+-- one; orders belong to the department of the user who created them.
+CREATE TABLE IF NOT EXISTS departments (
+    id SERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL
+        DEFAULT COALESCE(digiorder_tenant(), '00000000-0000-0000-0000-000000000001')
+        REFERENCES tenants(id),
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+COMMENT ON TABLE departments IS 'Departments within a tenant; non-admin users only see the orders of their own department.';
+
+ALTER TABLE departments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE departments FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON departments;
+CREATE POLICY tenant_isolation ON departments
+    USING (digiorder_tenant() IS NULL OR tenant_id = digiorder_tenant());
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS department_id INT
+    REFERENCES departments(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS department_id INT
+    REFERENCES departments(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_department ON users(department_id);
+CREATE INDEX IF NOT EXISTS idx_orders_department ON orders(department_id, created_at DESC);
+
+-- The department the session is limited to, or NULL for admins and
+-- system work
+CREATE OR REPLACE FUNCTION digiorder_department() RETURNS INT
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('digiorder.department_id', true), '')::int
+$$;
+
+-- Restrictive, so it narrows the tenant policy instead of widening it.
+-- Items, assignments, attachments and recurring orders follow their order.
+DROP POLICY IF EXISTS department_isolation ON orders;
+CREATE POLICY department_isolation ON orders AS RESTRICTIVE
+    USING (digiorder_department() IS NULL OR department_id = digiorder_department());
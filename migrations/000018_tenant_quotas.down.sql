@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS tenant_request_counts;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS orders_per_day;
+ALTER TABLE tenants DROP COLUMN IF EXISTS requests_per_day;
+ALTER TABLE tenants DROP COLUMN IF EXISTS requests_per_minute;
@@ -0,0 +1,22 @@
+-- ============================================================================
+-- TENANT QUOTAS
+-- ============================================================================
+
+-- Per-tenant overrides of the tenancy.quotas defaults; NULL keeps the
+-- default and 0 lifts the limit
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS requests_per_minute INT
+    CHECK (requests_per_minute >= 0);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS requests_per_day INT
+    CHECK (requests_per_day >= 0);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS orders_per_day INT
+    CHECK (orders_per_day >= 0);
+
+-- Authenticated requests per tenant and day, added up by every instance
+CREATE TABLE IF NOT EXISTS tenant_request_counts (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, day)
+);
+
+COMMENT ON TABLE tenant_request_counts IS 'Daily request counts per tenant, checked against the tenant request quota.';
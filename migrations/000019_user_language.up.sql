@@ -0,0 +1,7 @@
+-- ============================================================================
+-- USER LANGUAGE
+-- ============================================================================
+
+-- Language of the user's error messages (en, fa); NULL follows the
+-- client's Accept-Language header
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language TEXT;
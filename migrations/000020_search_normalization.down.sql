@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_orders_search_notes;
+DROP INDEX IF EXISTS idx_products_search_brand;
+DROP INDEX IF EXISTS idx_products_search_name;
+DROP FUNCTION IF EXISTS normalize_search(TEXT);
@@ -0,0 +1,28 @@
+-- ============================================================================
+-- PERSIAN SEARCH NORMALIZATION
+-- ============================================================================
+
+-- normalize_search folds text into the form searches compare: lower case,
+-- Arabic yeh, kaf, teh marbuta and hamza forms as their Persian letters,
+-- Persian and Arabic-Indic digits as 0-9, and without diacritics, tatweel,
+-- ZWNJ and other zero-width characters or white space, so "می‌روم",
+-- "مي روم" and "میروم" all match.
+CREATE OR REPLACE FUNCTION normalize_search(t TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT regexp_replace(
+        translate(lower(t),
+            'يىكةۀأإٱآؤ٠١٢٣٤٥٦٧٨٩۰۱۲۳۴۵۶۷۸۹',
+            'ییکههااااو01234567890123456789'),
+        '[\s\u00a0\u200b-\u200f\ufeff\u0640\u064b-\u0655\u0670]+', '', 'g')
+$$;
+
+-- Trigram indexes over the normalized text, so names are folded once when
+-- a row is written and substring searches stay fast
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_name
+    ON products USING gin (normalize_search(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_search_brand
+    ON products USING gin (normalize_search(COALESCE(brand, '')) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_orders_search_notes
+    ON orders USING gin (normalize_search(COALESCE(notes, '')) gin_trgm_ops);
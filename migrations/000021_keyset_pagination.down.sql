@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at DESC);
+DROP INDEX IF EXISTS idx_audit_logs_created_at_id;
+DROP INDEX IF EXISTS idx_users_active_created_at_id;
+DROP INDEX IF EXISTS idx_products_created_at_id;
+DROP INDEX IF EXISTS idx_orders_created_at_id;
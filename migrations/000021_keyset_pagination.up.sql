@@ -0,0 +1,15 @@
+-- ============================================================================
+-- KEYSET PAGINATION
+-- ============================================================================
+
+-- Lists are ordered newest first with the ID breaking ties, and pages after
+-- the first seek past the (created_at, id) of the last row they returned.
+-- These indexes serve both the order and the seek.
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_users_active_created_at_id ON users(created_at DESC, id DESC)
+    WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at_id ON audit_logs(created_at DESC, id DESC);
+
+-- Covered by idx_audit_logs_created_at_id
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
@@ -0,0 +1,8 @@
+-- ============================================================================
+-- ORDER ITEM LOOKUP
+-- ============================================================================
+
+-- Items are read by order, and adding one checks whether the order already
+-- has the product. Not unique: orders created before the check may hold
+-- the same product twice.
+CREATE INDEX IF NOT EXISTS idx_order_items_order_product ON order_items(order_id, product_id);
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_fkey;
+
+DROP TABLE IF EXISTS order_statuses;
@@ -0,0 +1,35 @@
+-- ============================================================================
+-- ORDER STATUSES
+-- ============================================================================
+
+-- The statuses an order may be in. Admins add and relabel them; orders
+-- reference them, so a status in use cannot be deleted.
+CREATE TABLE IF NOT EXISTS order_statuses (
+    code TEXT PRIMARY KEY,
+    label TEXT NOT NULL,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE order_statuses IS 'Catalog of order statuses; orders.status must be one of them.';
+
+INSERT INTO order_statuses (code, label, sort_order) VALUES
+    ('draft', 'Draft', 10),
+    ('submitted', 'Submitted', 20),
+    ('approved', 'Approved', 30),
+    ('processing', 'Processing', 40),
+    ('fulfilled', 'Fulfilled', 50),
+    ('delivered', 'Delivered', 60),
+    ('completed', 'Completed', 70),
+    ('rejected', 'Rejected', 80),
+    ('cancelled', 'Cancelled', 90)
+ON CONFLICT (code) DO NOTHING;
+
+-- Statuses orders already have stay valid, labelled as they are written
+INSERT INTO order_statuses (code, label, sort_order)
+SELECT DISTINCT status, status, 100 FROM orders
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_fkey;
+ALTER TABLE orders ADD CONSTRAINT orders_status_fkey
+    FOREIGN KEY (status) REFERENCES order_statuses(code);
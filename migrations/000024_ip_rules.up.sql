@@ -0,0 +1,23 @@
+-- ============================================================================
+-- IP RULES
+-- ============================================================================
+
+-- Addresses and networks let through or kept out by the rate limiter on
+-- every node. An allow rule exempts its addresses from rate limits and
+-- bans; a deny rule refuses them, for good or until it expires. Bans the
+-- rate limiter imposes are kept here too, without a creator, so they
+-- survive restarts.
+CREATE TABLE IF NOT EXISTS ip_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cidr CIDR NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('allow', 'deny')),
+    reason TEXT,
+    expires_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_rules_expires ON ip_rules(expires_at);
+
+COMMENT ON TABLE ip_rules IS 'IP allow and deny rules enforced by the rate limiter (see internal/middleware/ip_rules.go).';
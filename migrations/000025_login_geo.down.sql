@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_login_attempts_log_username_country;
+ALTER TABLE login_attempts_log DROP COLUMN IF EXISTS as_org;
+ALTER TABLE login_attempts_log DROP COLUMN IF EXISTS asn;
@@ -0,0 +1,17 @@
+-- ============================================================================
+-- LOGIN GEOLOCATION
+-- ============================================================================
+
+-- Where login attempts come from, when a GeoIP database is configured.
+-- country and city already exist; these add the network the address
+-- belongs to.
+ALTER TABLE login_attempts_log ADD COLUMN IF NOT EXISTS asn BIGINT;
+ALTER TABLE login_attempts_log ADD COLUMN IF NOT EXISTS as_org TEXT;
+
+-- Looked up for every successful login to tell whether its country is new
+CREATE INDEX IF NOT EXISTS idx_login_attempts_log_username_country
+    ON login_attempts_log(username, country)
+    WHERE success AND country IS NOT NULL;
+
+COMMENT ON COLUMN login_attempts_log.country IS 'ISO 3166-1 alpha-2 country of the address, from GeoIP.';
+COMMENT ON COLUMN login_attempts_log.asn IS 'Autonomous system number of the address, from GeoIP.';
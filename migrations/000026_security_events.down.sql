@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS security_events;
+DROP INDEX IF EXISTS idx_login_attempts_log_username_time;
+DROP INDEX IF EXISTS idx_login_attempts_log_unanalyzed;
+ALTER TABLE login_attempts_log DROP COLUMN IF EXISTS analyzed_at;
+ALTER TABLE login_attempts_log DROP COLUMN IF EXISTS longitude;
+ALTER TABLE login_attempts_log DROP COLUMN IF EXISTS latitude;
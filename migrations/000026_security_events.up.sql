@@ -0,0 +1,41 @@
+-- ============================================================================
+-- SECURITY EVENTS
+-- ============================================================================
+
+-- Where and on what device a login happened, for the anomaly analyzer
+ALTER TABLE login_attempts_log ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE login_attempts_log ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE login_attempts_log ADD COLUMN IF NOT EXISTS analyzed_at TIMESTAMPTZ;
+
+-- Logins made before the analyzer existed are not analyzed
+UPDATE login_attempts_log SET analyzed_at = NOW() WHERE analyzed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_log_unanalyzed
+    ON login_attempts_log(attempt_time)
+    WHERE success AND analyzed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_login_attempts_log_username_time
+    ON login_attempts_log(username, attempt_time DESC)
+    WHERE success;
+
+-- Suspicious logins found by the anomaly analyzer: a login too far from
+-- the previous one to have travelled, from a device the user has not used
+-- before, or at an hour the user does not log in at
+CREATE TABLE IF NOT EXISTS security_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL CHECK (kind IN ('impossible_travel', 'new_device', 'unusual_hour')),
+    username TEXT NOT NULL,
+    login_attempt_id UUID REFERENCES login_attempts_log(id) ON DELETE SET NULL,
+    ip_address TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_created ON security_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_username ON security_events(username, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_unacknowledged
+    ON security_events(created_at DESC)
+    WHERE acknowledged_at IS NULL;
+
+COMMENT ON TABLE security_events IS 'Login anomalies found by the analyzer (see internal/anomaly).';
@@ -0,0 +1,6 @@
+-- Encrypted values stay encrypted; they need the keys to be read
+COMMENT ON COLUMN login_attempts_log.device_info IS NULL;
+COMMENT ON COLUMN audit_logs.user_agent IS NULL;
+COMMENT ON COLUMN audit_logs.ip_address IS NULL;
+COMMENT ON COLUMN users.full_name IS NULL;
+ALTER TABLE login_attempts_log DROP COLUMN IF EXISTS device_fingerprint;
@@ -0,0 +1,16 @@
+-- ============================================================================
+-- FIELD ENCRYPTION
+-- ============================================================================
+
+-- device_info may now be encrypted, so the device fingerprint the anomaly
+-- analyzer matches logins by gets a column of its own
+ALTER TABLE login_attempts_log ADD COLUMN IF NOT EXISTS device_fingerprint TEXT;
+
+UPDATE login_attempts_log SET device_fingerprint = device_info->>'fingerprint'
+WHERE jsonb_typeof(device_info) = 'object' AND device_info ? 'fingerprint';
+
+COMMENT ON COLUMN login_attempts_log.device_fingerprint IS 'Hash of the user agent and languages of the login, see device_info.';
+COMMENT ON COLUMN users.full_name IS 'Encrypted (enc:v1:<key id>:...) while field encryption keys are configured.';
+COMMENT ON COLUMN audit_logs.ip_address IS 'Encrypted (enc:v1:<key id>:...) while field encryption keys are configured.';
+COMMENT ON COLUMN audit_logs.user_agent IS 'Encrypted (enc:v1:<key id>:...) while field encryption keys are configured.';
+COMMENT ON COLUMN login_attempts_log.device_info IS 'Encrypted as a JSON string (enc:v1:<key id>:...) while field encryption keys are configured.';
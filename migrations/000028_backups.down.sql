@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS backup_restores;
+DROP TABLE IF EXISTS backups;
@@ -0,0 +1,42 @@
+-- ============================================================================
+-- BACKUPS
+-- ============================================================================
+
+-- Logical dumps (pg_dump custom format) kept in file storage. These tables
+-- are left out of the dumps, so restoring an older dump keeps the history
+-- of backups and restores; for the same reason they reference no other
+-- table, which a restore drops and recreates.
+CREATE TABLE IF NOT EXISTS backups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    storage_key TEXT,
+    size_bytes BIGINT,
+    checksum TEXT,
+    error TEXT,
+    triggered_by UUID,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    verify_status TEXT CHECK (verify_status IN ('running', 'passed', 'failed')),
+    verify_error TEXT,
+    verified_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_backups_started ON backups(started_at DESC);
+
+-- At most one backup runs at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_backups_one_running ON backups((true)) WHERE status = 'running';
+
+CREATE TABLE IF NOT EXISTS backup_restores (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    backup_id UUID NOT NULL REFERENCES backups(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    error TEXT,
+    triggered_by UUID,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_restores_backup ON backup_restores(backup_id, started_at DESC);
+
+COMMENT ON TABLE backups IS 'Logical database dumps in file storage (see internal/backup); not included in the dumps.';
+COMMENT ON TABLE backup_restores IS 'Restores of backups; not included in the dumps.';
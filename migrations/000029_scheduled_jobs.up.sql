@@ -0,0 +1,20 @@
+-- ============================================================================
+-- SCHEDULED JOBS
+-- ============================================================================
+
+-- The last run of each periodic job (see internal/scheduler). Every
+-- instance runs the scheduler; the instance that claims a job's row for a
+-- run is the only one to run it.
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name TEXT PRIMARY KEY,
+    schedule TEXT NOT NULL,
+    last_status TEXT CHECK (last_status IN ('running', 'succeeded', 'failed')),
+    last_error TEXT,
+    last_started_at TIMESTAMPTZ,
+    last_finished_at TIMESTAMPTZ,
+    last_duration_ms BIGINT,
+    run_count BIGINT NOT NULL DEFAULT 0,
+    failure_count BIGINT NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE scheduled_jobs IS 'Last run of each periodic job (see internal/scheduler).';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_warnings;
+DROP TABLE IF EXISTS drug_classes;
+DROP TABLE IF EXISTS drug_interactions;
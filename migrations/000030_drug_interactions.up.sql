@@ -0,0 +1,54 @@
+-- ============================================================================
+-- DRUG INTERACTIONS
+-- ============================================================================
+
+-- The interaction dataset of the database provider (see
+-- internal/interactions), keyed by the generic codes of the national drug
+-- registry (products.generic_code). Each pair is stored once, with the
+-- smaller code first.
+CREATE TABLE IF NOT EXISTS drug_interactions (
+    generic_code_a TEXT NOT NULL,
+    generic_code_b TEXT NOT NULL,
+    severity TEXT NOT NULL CHECK (severity IN ('minor', 'moderate', 'major', 'contraindicated')),
+    description TEXT NOT NULL,
+    PRIMARY KEY (generic_code_a, generic_code_b),
+    CHECK (generic_code_a < generic_code_b)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drug_interactions_b ON drug_interactions(generic_code_b);
+
+COMMENT ON TABLE drug_interactions IS 'Known interactions between two generics, for order item warnings.';
+
+-- The ATC code of each generic, for duplicate-therapy warnings
+CREATE TABLE IF NOT EXISTS drug_classes (
+    generic_code TEXT PRIMARY KEY,
+    atc_code TEXT NOT NULL
+);
+
+COMMENT ON TABLE drug_classes IS 'ATC code of each generic, for duplicate-therapy warnings.';
+
+-- Warnings raised when items were added to an order. A warning is about
+-- the added item and an item already in the order, and goes with either.
+CREATE TABLE IF NOT EXISTS order_warnings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    other_order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('interaction', 'duplicate_therapy')),
+    severity TEXT NOT NULL CHECK (severity IN ('minor', 'moderate', 'major', 'contraindicated')),
+    message TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (order_item_id, other_order_item_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_warnings_order ON order_warnings(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_warnings_other ON order_warnings(other_order_item_id);
+
+COMMENT ON TABLE order_warnings IS 'Interaction and duplicate-therapy warnings on the items of an order.';
+
+ALTER TABLE order_warnings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_warnings FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON order_warnings;
+CREATE POLICY tenant_isolation ON order_warnings
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_warnings.order_id));
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE resource = 'controlled_substances';
+
+DROP TABLE IF EXISTS controlled_approvals;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS schedule_class,
+    DROP COLUMN IF EXISTS is_controlled;
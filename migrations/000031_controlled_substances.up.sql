@@ -0,0 +1,45 @@
+-- ============================================================================
+-- CONTROLLED SUBSTANCES
+-- ============================================================================
+
+-- Controlled products can only be ordered by users granted
+-- controlled_substances/order, each item with its reason in the note, and
+-- their orders only proceed past approval once a second user has approved
+-- them (see Controlled Substances in README.md).
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS is_controlled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS schedule_class TEXT;
+
+COMMENT ON COLUMN products.is_controlled IS 'Whether the product is a controlled substance.';
+COMMENT ON COLUMN products.schedule_class IS 'Schedule or class of a controlled substance, e.g. narcotic or psychotropic.';
+
+-- The second-person approval of an order with controlled items. It is
+-- withdrawn when the order goes back to draft or submitted.
+CREATE TABLE IF NOT EXISTS controlled_approvals (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    approved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    approved_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_controlled_approvals_approved_at ON controlled_approvals(approved_at);
+
+COMMENT ON TABLE controlled_approvals IS 'Second-person approvals of orders with controlled substances.';
+
+ALTER TABLE controlled_approvals ENABLE ROW LEVEL SECURITY;
+ALTER TABLE controlled_approvals FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON controlled_approvals;
+CREATE POLICY tenant_isolation ON controlled_approvals
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = controlled_approvals.order_id));
+
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('order_controlled_substances', 'controlled_substances', 'order', 'Order controlled substances'),
+    ('approve_controlled_substances', 'controlled_substances', 'approve', 'Approve orders with controlled substances'),
+    ('view_controlled_register', 'controlled_substances', 'read', 'View the controlled-substance register')
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.id IN (1, 2) AND p.resource = 'controlled_substances'
+ON CONFLICT DO NOTHING;
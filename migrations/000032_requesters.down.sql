@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_requester;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS requester_id;
+
+DROP TABLE IF EXISTS requesters;
@@ -0,0 +1,34 @@
+-- ============================================================================
+-- REQUESTERS
+-- ============================================================================
+
+-- Whom an order is for: a patient or a hospital department. Only what is
+-- needed to tell requesters apart is kept; the name and reference (such as
+-- a medical record number) are encrypted with the field encryption keys
+-- (see Field Encryption in README.md).
+CREATE TABLE IF NOT EXISTS requesters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL
+        DEFAULT COALESCE(digiorder_tenant(), '00000000-0000-0000-0000-000000000001')
+        REFERENCES tenants(id),
+    kind TEXT NOT NULL CHECK (kind IN ('patient', 'department')),
+    name TEXT NOT NULL,
+    reference TEXT,
+    department_id INT REFERENCES departments(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_requesters_tenant ON requesters(tenant_id, created_at DESC);
+
+COMMENT ON TABLE requesters IS 'Patients and departments orders are placed for; name and reference are encrypted at rest.';
+
+ALTER TABLE requesters ENABLE ROW LEVEL SECURITY;
+ALTER TABLE requesters FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON requesters;
+CREATE POLICY tenant_isolation ON requesters
+    USING (digiorder_tenant() IS NULL OR tenant_id = digiorder_tenant());
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS requester_id UUID
+    REFERENCES requesters(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_requester ON orders(requester_id, created_at DESC);
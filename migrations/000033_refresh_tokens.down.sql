@@ -0,0 +1,6 @@
+DELETE FROM security_events WHERE kind = 'refresh_token_reuse';
+ALTER TABLE security_events DROP CONSTRAINT IF EXISTS security_events_kind_check;
+ALTER TABLE security_events ADD CONSTRAINT security_events_kind_check
+    CHECK (kind IN ('impossible_travel', 'new_device', 'unusual_hour'));
+
+DROP TABLE IF EXISTS refresh_tokens;
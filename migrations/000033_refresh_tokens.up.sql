@@ -0,0 +1,32 @@
+-- ============================================================================
+-- REFRESH TOKENS
+-- ============================================================================
+
+-- Refresh tokens issued at login, of which only the SHA-256 hash is kept.
+-- Each use rotates the token: it is marked rotated and a new token of the
+-- same family replaces it. A rotated token presented again was copied, so
+-- its whole family is revoked and a refresh_token_reuse security event
+-- recorded.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    rotated_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user
+    ON refresh_tokens(user_id)
+    WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens(expires_at);
+
+COMMENT ON TABLE refresh_tokens IS 'Rotating refresh tokens issued at login; a family is every token descended from one login.';
+
+-- Reuse of a rotated refresh token is a security event
+ALTER TABLE security_events DROP CONSTRAINT IF EXISTS security_events_kind_check;
+ALTER TABLE security_events ADD CONSTRAINT security_events_kind_check
+    CHECK (kind IN ('impossible_travel', 'new_device', 'unusual_hour', 'refresh_token_reuse'));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS password_reset_requests;
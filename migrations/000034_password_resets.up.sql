@@ -0,0 +1,37 @@
+-- ============================================================================
+-- PASSWORD RESETS
+-- ============================================================================
+
+-- Every password reset request, known username or not, so requests can be
+-- limited per IP and per username
+CREATE TABLE IF NOT EXISTS password_reset_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_requests_ip ON password_reset_requests(ip_address, created_at);
+CREATE INDEX IF NOT EXISTS idx_password_reset_requests_username ON password_reset_requests(username, created_at);
+
+COMMENT ON TABLE password_reset_requests IS 'Password reset requests, for rate limiting per IP and per username.';
+
+-- One-time tokens emailed to users who asked to reset their password. Only
+-- the SHA-256 hash is kept; a token is used once and a newer request
+-- supersedes it.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    requested_ip TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user
+    ON password_reset_tokens(user_id)
+    WHERE used_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires ON password_reset_tokens(expires_at);
+
+COMMENT ON TABLE password_reset_tokens IS 'Single-use, expiring password reset tokens; only their hash is stored.';
@@ -0,0 +1,42 @@
+-- ============================================================================
+-- API KEYS
+-- ============================================================================
+
+-- Keys administrators create for machine integrations, sent in the
+-- X-API-Key header. Unlike personal access tokens a key does not carry the
+-- role of a person: it has its own role, optional department and scopes.
+-- Requests are attributed to the administrator who created the key. Only a
+-- SHA-256 hash of the key is kept; the key itself is shown once.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL
+        DEFAULT COALESCE(digiorder_tenant(), '00000000-0000-0000-0000-000000000001')
+        REFERENCES tenants(id),
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    key_prefix TEXT NOT NULL,
+    role_id INT NOT NULL REFERENCES roles(id),
+    department_id INT REFERENCES departments(id) ON DELETE SET NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip TEXT,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant_id, created_at DESC);
+
+-- Names identify a pharmacy's active keys
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_name
+    ON api_keys(tenant_id, name) WHERE revoked_at IS NULL;
+
+COMMENT ON TABLE api_keys IS 'Role-scoped keys for machine integrations (see internal/server/api_keys.go).';
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (digiorder_tenant() IS NULL OR tenant_id = digiorder_tenant());
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_service_account;
+ALTER TABLE users DROP COLUMN IF EXISTS service_account;
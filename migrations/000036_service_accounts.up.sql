@@ -0,0 +1,11 @@
+-- ============================================================================
+-- SERVICE ACCOUNTS
+-- ============================================================================
+
+-- Service accounts are users for warehouse scanners, reporting jobs and
+-- other machines. They cannot sign in with a password; administrators
+-- create personal access tokens for them, each limited to its scopes.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS service_account BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_users_service_account
+    ON users(tenant_id, created_at DESC) WHERE service_account;
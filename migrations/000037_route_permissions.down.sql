@@ -0,0 +1,13 @@
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 3, id FROM permissions
+WHERE (resource, action) IN (('users', 'read'), ('audit', 'read'))
+ON CONFLICT DO NOTHING;
+
+DELETE FROM permissions WHERE name IN (
+    'manage_security', 'manage_system', 'manage_tenants', 'update_stock',
+    'forecast_stock', 'view_drug_registry', 'sync_drug_registry',
+    'manage_catalog', 'manage_order_statuses', 'assign_orders',
+    'manage_recurring_orders', 'manage_exports', 'manage_erp', 'view_reports',
+    'manage_reports', 'manage_report_schedules', 'manage_barcodes',
+    'delete_barcodes', 'manage_departments', 'delete_requesters'
+);
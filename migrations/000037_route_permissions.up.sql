@@ -0,0 +1,62 @@
+-- ============================================================================
+-- ROUTE PERMISSIONS
+-- ============================================================================
+
+-- Routes check the permissions of the caller's role instead of fixed role
+-- names (see RequirePermission in internal/middleware/permissions.go).
+-- These are the permissions routes need beyond those seeded with the
+-- schema, granted so the built-in roles keep the access they had.
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('manage_security', 'security', 'manage', 'Monitor logins and manage IP rules, bans and security events'),
+    ('manage_system', 'system', 'manage', 'Maintenance, configuration, diagnostics, backups, API keys and service accounts'),
+    ('manage_tenants', 'tenants', 'manage', 'Manage the pharmacies sharing the deployment'),
+    ('update_stock', 'stock', 'update', 'Change stock levels'),
+    ('forecast_stock', 'stock', 'forecast', 'View demand forecasts'),
+    ('view_drug_registry', 'drug_registry', 'read', 'View national drug registry syncs'),
+    ('sync_drug_registry', 'drug_registry', 'sync', 'Start a national drug registry sync'),
+    ('manage_catalog', 'catalog', 'manage', 'Create categories and dosage forms'),
+    ('manage_order_statuses', 'order_statuses', 'manage', 'Manage the order status catalog'),
+    ('assign_orders', 'orders', 'assign', 'Assign orders to users'),
+    ('manage_recurring_orders', 'recurring_orders', 'manage', 'Create, change and delete recurring orders'),
+    ('manage_exports', 'exports', 'manage', 'Create and download export files'),
+    ('manage_erp', 'erp', 'manage', 'Hand fulfilled orders to the ERP system'),
+    ('view_reports', 'reports', 'read', 'View order statistics and the controlled-substance register'),
+    ('manage_reports', 'reports', 'manage', 'Saved reports, user activity and change reports'),
+    ('manage_report_schedules', 'report_schedules', 'manage', 'Manage scheduled report emails'),
+    ('manage_barcodes', 'barcodes', 'manage', 'Add and change product barcodes'),
+    ('delete_barcodes', 'barcodes', 'delete', 'Delete product barcodes'),
+    ('manage_departments', 'departments', 'manage', 'Create, change and delete departments'),
+    ('delete_requesters', 'requesters', 'delete', 'Delete requesters')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 1, id FROM permissions
+WHERE name IN (
+    'manage_security', 'manage_system', 'manage_tenants', 'update_stock',
+    'forecast_stock', 'view_drug_registry', 'sync_drug_registry',
+    'manage_catalog', 'manage_order_statuses', 'assign_orders',
+    'manage_recurring_orders', 'manage_exports', 'manage_erp', 'view_reports',
+    'manage_reports', 'manage_report_schedules', 'manage_barcodes',
+    'delete_barcodes', 'manage_departments', 'delete_requesters'
+)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 2, id FROM permissions
+WHERE (resource, action) IN (
+    ('stock', 'update'), ('stock', 'forecast'), ('drug_registry', 'read'),
+    ('orders', 'assign'), ('recurring_orders', 'manage'), ('exports', 'manage'),
+    ('reports', 'read'), ('barcodes', 'manage')
+)
+ON CONFLICT DO NOTHING;
+
+-- The schema seeded clerks with every read permission, but the user and
+-- audit log routes were for administrators only. Withdraw those two so
+-- that checking permissions does not open them to clerks; grant them again
+-- to let clerks read users and audit logs.
+DELETE FROM role_permissions
+WHERE role_id = 3
+  AND permission_id IN (
+    SELECT id FROM permissions
+    WHERE (resource, action) IN (('users', 'read'), ('audit', 'read'))
+  );
@@ -0,0 +1,34 @@
+DELETE FROM permissions WHERE resource = 'orders' AND action = 'read_all';
+
+DROP POLICY IF EXISTS tenant_isolation ON order_items;
+CREATE POLICY tenant_isolation ON order_items
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_items.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON order_assignments;
+CREATE POLICY tenant_isolation ON order_assignments
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_assignments.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON order_attachments;
+CREATE POLICY tenant_isolation ON order_attachments
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_attachments.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON recurring_orders;
+CREATE POLICY tenant_isolation ON recurring_orders
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = recurring_orders.template_order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON order_warnings;
+CREATE POLICY tenant_isolation ON order_warnings
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_warnings.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON controlled_approvals;
+CREATE POLICY tenant_isolation ON controlled_approvals
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = controlled_approvals.order_id));
+
+DROP POLICY IF EXISTS owner_isolation ON orders;
+DROP FUNCTION IF EXISTS digiorder_order_owner();
@@ -0,0 +1,56 @@
+-- ============================================================================
+-- ORDER OWNERSHIP
+-- ============================================================================
+
+-- The user whose orders the session is limited to, or NULL for admins,
+-- users with a department (which limits them instead), users granted
+-- orders:read_all and system work
+CREATE OR REPLACE FUNCTION digiorder_order_owner() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('digiorder.owner_id', true), '')::uuid
+$$;
+
+-- Restrictive like the department policy, so it narrows the others
+DROP POLICY IF EXISTS owner_isolation ON orders;
+CREATE POLICY owner_isolation ON orders AS RESTRICTIVE
+    USING (digiorder_order_owner() IS NULL OR created_by = digiorder_order_owner());
+
+-- Data hanging off an order followed it only for tenants; follow it for
+-- departments and owners too, also with tenancy disabled
+DROP POLICY IF EXISTS tenant_isolation ON order_items;
+CREATE POLICY tenant_isolation ON order_items
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_items.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON order_assignments;
+CREATE POLICY tenant_isolation ON order_assignments
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_assignments.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON order_attachments;
+CREATE POLICY tenant_isolation ON order_attachments
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_attachments.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON recurring_orders;
+CREATE POLICY tenant_isolation ON recurring_orders
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = recurring_orders.template_order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON order_warnings;
+CREATE POLICY tenant_isolation ON order_warnings
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_warnings.order_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON controlled_approvals;
+CREATE POLICY tenant_isolation ON controlled_approvals
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = controlled_approvals.order_id));
+
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('view_all_orders', 'orders', 'read_all', 'See every order of the department or pharmacy, not only their own')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 1, id FROM permissions WHERE resource = 'orders' AND action = 'read_all'
+ON CONFLICT DO NOTHING;
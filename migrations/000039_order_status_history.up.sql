@@ -0,0 +1,41 @@
+-- ============================================================================
+-- ORDER STATUS HISTORY
+-- ============================================================================
+
+-- Every status an order has been moved to, by whom and when, with the note
+-- given, so disputes about when an order was approved can be settled
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    old_status TEXT NOT NULL,
+    new_status TEXT NOT NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id, changed_at);
+
+COMMENT ON TABLE order_status_history IS 'Status changes of orders, oldest first; rows are never updated.';
+
+-- Follows its order, like the other data hanging off one
+ALTER TABLE order_status_history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_status_history FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON order_status_history;
+CREATE POLICY tenant_isolation ON order_status_history
+    USING ((digiorder_tenant() IS NULL AND digiorder_department() IS NULL AND digiorder_order_owner() IS NULL)
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_status_history.order_id));
+
+-- Earlier changes were only audited; carry them over
+INSERT INTO order_status_history (order_id, old_status, new_status, changed_by, changed_at)
+SELECT o.id, a.old_values->>'status', a.new_values->>'status', a.user_id, a.created_at
+FROM audit_logs a
+JOIN orders o ON o.id::text = a.entity_id
+WHERE a.entity_type = 'order'
+  AND a.action = 'update_status'
+  AND a.old_values->>'status' IS NOT NULL
+  AND a.new_values->>'status' IS NOT NULL
+  AND a.old_values->>'status' <> a.new_values->>'status'
+  AND a.created_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_id = o.id)
+ORDER BY a.created_at;
@@ -0,0 +1,5 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS total;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal;
+ALTER TABLE order_items DROP COLUMN IF EXISTS line_total;
+ALTER TABLE order_items DROP COLUMN IF EXISTS unit_price;
+ALTER TABLE products DROP COLUMN IF EXISTS price;
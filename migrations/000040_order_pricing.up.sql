@@ -0,0 +1,19 @@
+-- ============================================================================
+-- ORDER PRICING
+-- ============================================================================
+
+-- The price of one unit of a product; NULL while it has none
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price NUMERIC(14,2)
+    CHECK (price >= 0);
+
+-- The unit price an item was ordered at, taken from the product when the
+-- item is added, and the line total it comes to
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit_price NUMERIC(14,2)
+    CHECK (unit_price >= 0);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS line_total NUMERIC(18,2)
+    GENERATED ALWAYS AS (unit_price * requested_qty) STORED;
+
+-- The sum of the line totals of an order, and that sum with tax; both are
+-- recalculated by the server whenever the order's items change
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal NUMERIC(18,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total NUMERIC(18,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+DELETE FROM permissions WHERE name IN ('manage_suppliers', 'assign_suppliers');
+
+DROP INDEX IF EXISTS idx_order_items_supplier;
+ALTER TABLE order_items DROP COLUMN IF EXISTS supplier_id;
+
+DROP TABLE IF EXISTS suppliers;
@@ -0,0 +1,46 @@
+-- ============================================================================
+-- SUPPLIERS
+-- ============================================================================
+
+-- Distributors a pharmacy buys from. Each order item may be routed to the
+-- supplier that stocks it, which splits an order across suppliers.
+CREATE TABLE IF NOT EXISTS suppliers (
+    id SERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL
+        DEFAULT COALESCE(digiorder_tenant(), '00000000-0000-0000-0000-000000000001')
+        REFERENCES tenants(id),
+    name TEXT NOT NULL,
+    phone TEXT,
+    email TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+COMMENT ON TABLE suppliers IS 'Distributors within a tenant that order items are routed to.';
+
+ALTER TABLE suppliers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE suppliers FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON suppliers;
+CREATE POLICY tenant_isolation ON suppliers
+    USING (digiorder_tenant() IS NULL OR tenant_id = digiorder_tenant());
+
+-- The supplier an item is routed to; NULL while it is not routed
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS supplier_id INT
+    REFERENCES suppliers(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_supplier ON order_items(supplier_id);
+
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('manage_suppliers', 'suppliers', 'manage', 'Create, change and delete suppliers'),
+    ('assign_suppliers', 'suppliers', 'assign', 'Route order items to suppliers')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 1, id FROM permissions
+WHERE name IN ('manage_suppliers', 'assign_suppliers')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 2, id FROM permissions
+WHERE name IN ('manage_suppliers', 'assign_suppliers')
+ON CONFLICT DO NOTHING;
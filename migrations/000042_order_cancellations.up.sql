@@ -0,0 +1,27 @@
+-- ============================================================================
+-- ORDER CANCELLATIONS
+-- ============================================================================
+
+-- Why an order was cancelled. Orders are cancelled through
+-- POST /orders/:id/cancel with a reason code and a note; orders that are
+-- fulfilled, delivered or completed can no longer be cancelled. The row is
+-- removed when a cancelled order is reopened.
+CREATE TABLE IF NOT EXISTS order_cancellations (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+        CHECK (reason IN ('no_longer_needed', 'duplicate', 'entered_in_error', 'out_of_stock', 'supplier_unavailable', 'other')),
+    note TEXT NOT NULL,
+    cancelled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    cancelled_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_cancellations_reason ON order_cancellations(reason, cancelled_at);
+
+COMMENT ON TABLE order_cancellations IS 'Reason codes and notes of cancelled orders.';
+
+ALTER TABLE order_cancellations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_cancellations FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON order_cancellations;
+CREATE POLICY tenant_isolation ON order_cancellations
+    USING (digiorder_tenant() IS NULL
+        OR EXISTS (SELECT 1 FROM orders o WHERE o.id = order_cancellations.order_id));
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations into the binary so
+// the server can bring the database schema up to date on startup.
+package migrations
+
+import "embed"
+
+// FS holds the NNNNNN_name.up.sql / NNNNNN_name.down.sql migration files
+//
+//go:embed *.sql
+var FS embed.FS
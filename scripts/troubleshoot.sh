@@ -137,12 +137,12 @@ check_item "Go modules synced" \
     "Run: go mod tidy && go mod download"
 
 echo -ne "${BLUE}▶${NC} Checking if code compiles... "
-if go build -o /tmp/digiorder_test ./cmd/main.go 2>/dev/null; then
+if go build -o /tmp/digiorder_test ./cmd 2>/dev/null; then
     echo -e "${GREEN}✓${NC}"
     rm -f /tmp/digiorder_test
 else
     echo -e "${RED}✗${NC}"
-    echo -e "  ${YELLOW}Fix:${NC} Check build errors with: go build ./cmd/main.go"
+    echo -e "  ${YELLOW}Fix:${NC} Check build errors with: go build ./cmd"
     ((ISSUES_FOUND++))
 fi
 